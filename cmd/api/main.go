@@ -6,20 +6,27 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	apigrpc "chainpulse/services/api/grpc"
 	"chainpulse/services/api/handlers"
 	"chainpulse/services/api/handlers/grpc"
 	"chainpulse/services/blockchain/services"
+	"chainpulse/shared/alert"
 	"chainpulse/shared/cache"
 	"chainpulse/shared/config"
 	"chainpulse/shared/database"
 	"chainpulse/shared/datapuller"
+	"chainpulse/shared/eventbus"
+	"chainpulse/shared/explorer"
 	"chainpulse/shared/logger"
 	"chainpulse/shared/metrics"
 	"chainpulse/shared/migrations"
+	"chainpulse/shared/mq"
 	"chainpulse/shared/service"
+	"chainpulse/shared/types"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -45,7 +52,23 @@ func main() {
 	migrator := migrations.NewMigrator(db.DB)
 	migrator.AddMigration(&migrations.InitialSchemaMigration{})
 	migrator.AddMigration(&migrations.AddIndexesMigration{})
-	
+	migrator.AddMigration(&migrations.AddLogIndexMigration{})
+	migrator.AddMigration(&migrations.AddEventDataMigration{})
+	migrator.AddMigration(&migrations.AddEventUniqueKeyMigration{})
+	migrator.AddMigration(&migrations.AddEventTopic0Migration{})
+	migrator.AddMigration(&migrations.NormalizeContractTypesMigration{})
+	migrator.AddMigration(&migrations.AddContractABIMigration{})
+
+	// Roll back the most recent migration and exit instead of starting
+	if cfg.MigrationRollback {
+		version, err := migrator.RollbackLast()
+		if err != nil {
+			appLogger.Fatal("Failed to roll back database migration: %v", err)
+		}
+		appLogger.Info("Rolled back database migration %s", version)
+		return
+	}
+
 	if err := migrator.RunMigrations(); err != nil {
 		appLogger.Fatal("Failed to run database migrations: %v", err)
 	}
@@ -66,6 +89,10 @@ func main() {
 		appLogger.Error("Failed to connect to Ethereum node: %v", err)
 		log.Fatal(err)
 	}
+	if err := bc.VerifyChainID(context.Background(), cfg.ChainID); err != nil {
+		appLogger.Error("Ethereum node does not serve the configured chain: %v", err)
+		log.Fatal(err)
+	}
 	appLogger.Info("Connected to Ethereum node successfully")
 
 	// Initialize cached database
@@ -75,21 +102,88 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Fetch the verified ABI of contracts from the block explorer when they are registered
+	if cfg.ExplorerAPIURL != "" {
+		explorerClient := explorer.NewClient(cfg.ExplorerAPIURL, cfg.ExplorerAPIKey)
+		db.ABIFetcher = explorerClient
+		cachedDB.DB.ABIFetcher = explorerClient
+	}
+
+	// Decode the logs of contracts with a stored ABI with it
+	bc.ContractABIs = services.NewContractABIs()
+	contracts, err := cachedDB.GetContracts()
+	if err != nil {
+		appLogger.Error("Failed to load contract ABIs: %v", err)
+	} else if err := bc.ContractABIs.RegisterContracts(contracts); err != nil {
+		appLogger.Error("Failed to register contract ABIs: %v", err)
+	}
+	// Contracts registered while running are decoded with their ABI right away
+	registerContractABI := func(contract *types.Contract) {
+		if err := bc.ContractABIs.RegisterContracts([]types.Contract{*contract}); err != nil {
+			appLogger.Error("Failed to register contract ABI: %v", err)
+		}
+		if err := bc.TrackProxies(context.Background(), []types.Contract{*contract}); err != nil {
+			appLogger.Error("Failed to check contract for a proxy: %v", err)
+		}
+	}
+	db.ContractHook = registerContractABI
+	cachedDB.DB.ContractHook = registerContractABI
+
 	// Initialize resume service with regular database
-	resumeService := service.NewResumeService(bc.Client, db)
+	resumeService := service.NewResumeService(bc.EthClient(), db)
+	resumeService.StartBlock, err = services.ParseStartBlock(cfg.StartBlock)
+	if err != nil {
+		appLogger.Fatal("Invalid start block: %v", err)
+	}
+	resumeService.ContractStartBlocks, err = services.ParseContractStartBlocks(cfg.ContractStartBlocks)
+	if err != nil {
+		appLogger.Fatal("Invalid contract start blocks: %v", err)
+	}
+	resumeService.ChainID = cfg.ChainID
 
 	// Initialize metrics
 	metrics := metrics.NewMetrics()
 
+	slowQueryThreshold := time.Duration(cfg.DBSlowQueryThreshold) * time.Millisecond
+	for _, d := range []*database.Database{db, cachedDB.DB} {
+		if err := d.EnableSlowQueryLogger(slowQueryThreshold, metrics); err != nil {
+			appLogger.Error("Failed to enable slow query logger: %v", err)
+		}
+		if _, err := d.MonitorConnection(context.Background(), time.Duration(cfg.DBCheckInterval)*time.Second, nil); err != nil {
+			appLogger.Error("Failed to monitor database connection: %v", err)
+		}
+	}
+
 	// Initialize batch processor with cached database
-	batchProcessor := database.NewBatchProcessor(cachedDB.DB, cfg.BatchSize, time.Duration(cfg.FlushTimeout)*time.Second)
+	batchProcessor := database.NewBatchProcessor(cachedDB.DB, cfg.BatchSize, time.Duration(cfg.FlushTimeout)*time.Second, metrics)
+	batchProcessor.SetMaxBufferedBytes(int64(cfg.BatchMaxBytes))
 
 	// Initialize reorg handler
-	reorgHandler := service.NewReorgHandler(bc.Client, db, appLogger, 10, 100) // depth: 10, maxDepth: 100
+	reorgHandler := service.NewReorgHandler(bc.EthClient(), db, appLogger, 10, 100) // depth: 10, maxDepth: 100
+	reorgHandler.Cache = cache
+
+	// Alert on deep reorgs and sustained sync problems through the configured channels
+	alerts := alert.NewConfiguredNotifier(cfg.AlertSlackWebhookURL, cfg.AlertPagerDutyKey, "chainpulse-indexer")
+	reorgHandler.Alerts = alerts
 
 	// Initialize idempotency service
 	idempotencyService := service.NewIdempotencyService(cache, db, 24*time.Hour)
 
+	// Deduplicate events by the configured key in the idempotency service and the database
+	dedupKeyer, err := types.NewDedupKeyer(cfg.DedupKeyStrategy, cfg.ChainID)
+	if err != nil {
+		appLogger.Fatal("Invalid dedup key strategy: %v", err)
+	}
+	idempotencyService.Keyer = dedupKeyer
+	cachedDB.DB.DedupKeyer = dedupKeyer
+
+	// Assign event IDs by the configured strategy, the database auto-increment by default
+	idGenerator, err := types.NewEventIDGenerator(cfg.EventIDStrategy, cfg.ChainID)
+	if err != nil {
+		appLogger.Fatal("Invalid event ID strategy: %v", err)
+	}
+	cachedDB.DB.IDGenerator = idGenerator
+
 	// Initialize blockchain data puller with plugin architecture
 	dataPuller := datapuller.NewBlockchainDataPuller()
 	
@@ -109,12 +203,23 @@ func main() {
 			"url": cfg.EthereumNodeURL, // Use the same Ethereum node URL for HTTPS JSON-RPC
 		},
 		"websocket-jsonrpc": {
-			"url": cfg.EthereumNodeWSURL, // WebSocket URL for real-time data
+			"url":                  cfg.EthereumNodeWSURL, // WebSocket URL for real-time data
+			"compression":          cfg.WSCompression,
+			"compressionThreshold": cfg.WSCompressionThreshold,
 		},
 		"grpc": {
 			"address": cfg.GRPCServerURL, // gRPC server address
 		},
 	}
+	if cfg.DataPullerRPCEndpoints != "" {
+		// Fail over between several HTTPS JSON-RPC providers instead of using the node URL alone
+		endpoints, err := datapuller.ParseEndpoints(cfg.DataPullerRPCEndpoints)
+		if err != nil {
+			appLogger.Error("Invalid data puller RPC endpoints: %v", err)
+			log.Fatal(err)
+		}
+		pluginConfigs["https-jsonrpc"]["endpoints"] = endpoints
+	}
 	
 	// Initialize the data puller with plugin configurations
 	if err := dataPuller.Initialize(pluginConfigs); err != nil {
@@ -124,6 +229,81 @@ func main() {
 
 	// Initialize service
 	indexerService := service.NewIndexerService(bc, cachedDB, batchProcessor, cache, resumeService, appLogger, metrics, reorgHandler, idempotencyService, dataPuller)
+	indexerService.Confirmations = uint64(cfg.CacheConfirmations)
+	indexerService.UnconfirmedTTL = time.Duration(cfg.UnconfirmedCacheTTL) * time.Second
+	indexerService.Alerts = alerts
+	indexerService.AlertMaxLag = int64(cfg.AlertMaxLag)
+	indexerService.AlertLagFor = time.Duration(cfg.AlertLagDuration) * time.Second
+	indexerService.BackfillProgress = cachedDB.DB
+	indexerService.ChainID = cfg.ChainID
+	indexerService.Contracts = cachedDB
+
+	// Fan stored events out to in-process consumers
+	eventBus := eventbus.NewEventBus(cfg.EventBusQueueSize)
+	defer eventBus.Close()
+	indexerService.Events = eventBus
+	batchProcessor.SetFlushHook(func(events []*types.IndexedEvent) {
+		cachedDB.InvalidateStoredEvents(events)
+		indexerService.PublishStored(events)
+	})
+
+	// Enrich and filter events before storage with the configured transformers
+	addressLabels, err := service.ParseAddressLabels(cfg.AddressLabels)
+	if err != nil {
+		appLogger.Fatal("Invalid address labels: %v", err)
+	}
+	transformers, err := service.ParseEventTransformers(cfg.EventTransformers, map[string]types.EventTransformer{
+		service.AddressLabelsTransformer: service.LabelAddresses(addressLabels),
+		service.SpamFilterTransformer: service.FilterSpam(service.SpamFilterConfig{
+			Contracts:          strings.Split(cfg.SpamContracts, ","),
+			ZeroValue:          cfg.SpamZeroValue,
+			ZeroValueContracts: strings.Split(cfg.SpamZeroContracts, ","),
+		}, metrics),
+	})
+	if err != nil {
+		appLogger.Fatal("Invalid event transformers: %v", err)
+	}
+	indexerService.Transformers = transformers
+
+	// Index the wraps and unwraps of the configured wrapped-native tokens
+	wrappedNative, err := service.ParseWrappedNativeContracts(cfg.WrappedNativeContracts)
+	if err != nil {
+		appLogger.Fatal("Invalid wrapped native contracts: %v", err)
+	}
+	indexerService.WrappedNative = wrappedNative
+
+	// The dead-letter queue publishes dead letters only
+	if err := mq.CheckCodec(cfg.MQCodec, mq.DeadLetterMessage{}); err != nil {
+		appLogger.Fatal("Invalid MQ codec: %v", err)
+	}
+
+	// Process subscribed events one at a time, dead-lettering the events that keep failing
+	if cfg.SyncProcessing {
+		deadLetterMQ := mq.NewMultiProtocolMQ("kafka")
+		deadLetterMQ.SetMetricsCollector(mq.GlobalMetricsCollector)
+		err := deadLetterMQ.Initialize(map[string]map[string]interface{}{
+			"kafka": {
+				"brokers": []string{"localhost:9092"},
+				"codec":   cfg.MQCodec,
+			},
+		})
+		if err != nil {
+			appLogger.Fatal("Failed to initialize dead-letter queue: %v", err)
+		}
+		defer deadLetterMQ.Close()
+		topics := mq.TopicConfig{
+			Prefix:        cfg.MQTopicPrefix,
+			RawEventsName: cfg.MQRawEventsTopic,
+		}
+		indexerService.SyncProcessing = true
+		indexerService.SyncRetry = mq.RetryPolicy{
+			MaxAttempts: cfg.MQRetryMaxAttempts,
+			BaseDelay:   time.Duration(cfg.MQRetryBaseDelay) * time.Millisecond,
+			MaxDelay:    time.Duration(cfg.MQRetryMaxDelay) * time.Millisecond,
+		}
+		indexerService.DeadLetters = deadLetterMQ
+		indexerService.SyncEventsTopic = topics.RawEvents()
+	}
 
 	// Initialize the API server
 	server := api.NewServer(cfg)
@@ -136,11 +316,20 @@ func main() {
 		common.HexToAddress("0x60E4d786628Fea6478F785A6d7e704777c86a7c6"), // Mutant Ape Yacht Club
 		// Add more contract addresses as needed
 	}
+	contractAddresses = append(contractAddresses, indexerService.WrappedNative...)
 
 	// Start the indexer service
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Decode the logs of registered proxies with their implementation ABI, following upgrades
+	if err := bc.TrackProxies(ctx, contracts); err != nil {
+		appLogger.Error("Failed to track proxy contracts: %v", err)
+	}
+	if cfg.ProxyRefreshInterval > 0 {
+		go bc.RefreshProxiesPeriodically(ctx, time.Duration(cfg.ProxyRefreshInterval)*time.Second)
+	}
+
 	go func() {
 		if err := indexerService.StartIndexing(ctx, contractAddresses); err != nil {
 			appLogger.Error("Failed to start indexing: %v", err)
@@ -172,9 +361,15 @@ func main() {
 		grpcPort = "9090"
 	}
 
+	// Stream every stored event to the StreamEvents subscribers; GetStats is read
+	// through the cache
+	indexerServer := apigrpc.NewCachedServer(cachedDB)
+	unsubscribeStream := eventBus.Subscribe("grpc-stream", indexerServer.PublishEvent)
+	defer unsubscribeStream()
+
 	go func() {
 		appLogger.Info("Starting chainpulse gRPC server on port %s", grpcPort)
-		if err := grpc.StartGRPCServer(indexerService, grpcPort, cfg.JWTSecret); err != nil {
+		if err := grpc.StartGRPCServer(indexerService, indexerServer, grpcPort, cfg.JWTSecret, strings.Split(cfg.GRPCLogRedactMetadata, ",")); err != nil {
 			appLogger.Error("gRPC server error: %v", err)
 		}
 	}()
@@ -195,8 +390,12 @@ func main() {
 		appLogger.Info("REST server exited gracefully")
 	}
 
+	// Stop indexing and flush pending events before the connections close
+	if err := indexerService.Stop(ctx); err != nil {
+		appLogger.Error("Indexer service did not stop cleanly: %v", err)
+	}
+
 	// Close connections
 	bc.Close()
 	cache.Close()
-	batchProcessor.Close()
 }
\ No newline at end of file
@@ -30,10 +30,22 @@ func main() {
 		appLogger.Error("Failed to connect to Ethereum node: %v", err)
 		log.Fatal(err)
 	}
+	bc.MaxAddressesPerSubscription = cfg.MaxShardAddresses
+	bc.PendingTxEnabled = cfg.PendingTxEnabled
+	bc.ApprovalsEnabled = cfg.ApprovalsEnabled
+	bc.OrderingDepth = cfg.EventOrderingDepth
+	bc.OrderingMaxHold = time.Duration(cfg.EventOrderingMaxHold) * time.Second
+	bc.MaxSubscriptionAge = time.Duration(cfg.MaxSubscriptionAge) * time.Second
+	if err := bc.VerifyChainID(context.Background(), cfg.ChainID); err != nil {
+		appLogger.Error("Ethereum node does not serve the configured chain: %v", err)
+		log.Fatal(err)
+	}
 	appLogger.Info("Connected to Ethereum node successfully")
 
 	// Initialize metrics
 	metricsClient := metrics.NewMetrics()
+	bc.RPCLimiter = services.NewRPCLimiter(cfg.NodeRPCRateLimit, metricsClient)
+	bc.Metrics = metricsClient
 
 	// Initialize the blockchain service
 	blockchainService := services.NewBlockchainService(bc, appLogger, metricsClient)
@@ -48,16 +48,44 @@ func main() {
 		appLogger.Error("Failed to connect to Ethereum node: %v", err)
 		log.Fatal(err)
 	}
+	bc.MaxAddressesPerSubscription = cfg.MaxShardAddresses
+	bc.PendingTxEnabled = cfg.PendingTxEnabled
+	bc.ApprovalsEnabled = cfg.ApprovalsEnabled
+	bc.OrderingDepth = cfg.EventOrderingDepth
+	bc.OrderingMaxHold = time.Duration(cfg.EventOrderingMaxHold) * time.Second
+	bc.MaxSubscriptionAge = time.Duration(cfg.MaxSubscriptionAge) * time.Second
+	if err := bc.VerifyChainID(context.Background(), cfg.ChainID); err != nil {
+		appLogger.Error("Ethereum node does not serve the configured chain: %v", err)
+		log.Fatal(err)
+	}
 	appLogger.Info("Connected to Ethereum node successfully")
 
 	// Initialize resume service
-	resumeService := service.NewResumeService(bc.Client, db)
+	resumeService := service.NewResumeService(bc.EthClient(), db)
+	resumeService.StartBlock, err = services.ParseStartBlock(cfg.StartBlock)
+	if err != nil {
+		appLogger.Fatal("Invalid start block: %v", err)
+	}
+	resumeService.ContractStartBlocks, err = services.ParseContractStartBlocks(cfg.ContractStartBlocks)
+	if err != nil {
+		appLogger.Fatal("Invalid contract start blocks: %v", err)
+	}
+	resumeService.ChainID = cfg.ChainID
 
 	// Initialize metrics
 	metricsClient := metrics.NewMetrics()
+	bc.RPCLimiter = services.NewRPCLimiter(cfg.NodeRPCRateLimit, metricsClient)
+	bc.Metrics = metricsClient
+	if err := db.EnableSlowQueryLogger(time.Duration(cfg.DBSlowQueryThreshold)*time.Millisecond, metricsClient); err != nil {
+		appLogger.Error("Failed to enable slow query logger: %v", err)
+	}
+	if _, err := db.MonitorConnection(context.Background(), time.Duration(cfg.DBCheckInterval)*time.Second, nil); err != nil {
+		appLogger.Error("Failed to monitor database connection: %v", err)
+	}
 
 	// Initialize batch processor with configuration
-	batchProcessor := database.NewBatchProcessor(db, cfg.BatchSize, time.Duration(cfg.FlushTimeout)*time.Second)
+	batchProcessor := database.NewBatchProcessor(db, cfg.BatchSize, time.Duration(cfg.FlushTimeout)*time.Second, metricsClient)
+	batchProcessor.SetMaxBufferedBytes(int64(cfg.BatchMaxBytes))
 
 	// Initialize event processor service
 	eventProcessorService := service.NewEventProcessorService(bc, db, batchProcessor, cacheClient, resumeService, appLogger, metricsClient)
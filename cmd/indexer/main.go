@@ -5,17 +5,24 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"chainpulse/services/blockchain/services"
+	"chainpulse/shared/alert"
+	"chainpulse/shared/api"
 	"chainpulse/shared/cache"
 	"chainpulse/shared/config"
 	"chainpulse/shared/database"
 	"chainpulse/shared/datapuller"
+	"chainpulse/shared/eventbus"
+	"chainpulse/shared/explorer"
 	"chainpulse/shared/logger"
 	"chainpulse/shared/metrics"
+	"chainpulse/shared/mq"
 	"chainpulse/shared/service"
+	"chainpulse/shared/types"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -51,6 +58,16 @@ func main() {
 		appLogger.Error("Failed to connect to Ethereum node: %v", err)
 		log.Fatal(err)
 	}
+	bc.MaxAddressesPerSubscription = cfg.MaxShardAddresses
+	bc.PendingTxEnabled = cfg.PendingTxEnabled
+	bc.ApprovalsEnabled = cfg.ApprovalsEnabled
+	bc.OrderingDepth = cfg.EventOrderingDepth
+	bc.OrderingMaxHold = time.Duration(cfg.EventOrderingMaxHold) * time.Second
+	bc.MaxSubscriptionAge = time.Duration(cfg.MaxSubscriptionAge) * time.Second
+	if err := bc.VerifyChainID(context.Background(), cfg.ChainID); err != nil {
+		appLogger.Error("Ethereum node does not serve the configured chain: %v", err)
+		log.Fatal(err)
+	}
 	appLogger.Info("Connected to Ethereum node successfully")
 
 	// Initialize cached database
@@ -60,21 +77,105 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Fetch the verified ABI of contracts from the block explorer when they are registered
+	if cfg.ExplorerAPIURL != "" {
+		explorerClient := explorer.NewClient(cfg.ExplorerAPIURL, cfg.ExplorerAPIKey)
+		db.ABIFetcher = explorerClient
+		cachedDB.DB.ABIFetcher = explorerClient
+	}
+
+	// Decode the logs of contracts with a stored ABI with it
+	bc.ContractABIs = services.NewContractABIs()
+	contracts, err := cachedDB.GetContracts()
+	if err != nil {
+		appLogger.Error("Failed to load contract ABIs: %v", err)
+	} else if err := bc.ContractABIs.RegisterContracts(contracts); err != nil {
+		appLogger.Error("Failed to register contract ABIs: %v", err)
+	}
+	// Contracts registered while running are decoded with their ABI right away
+	registerContractABI := func(contract *types.Contract) {
+		if err := bc.ContractABIs.RegisterContracts([]types.Contract{*contract}); err != nil {
+			appLogger.Error("Failed to register contract ABI: %v", err)
+		}
+		if err := bc.TrackProxies(context.Background(), []types.Contract{*contract}); err != nil {
+			appLogger.Error("Failed to check contract for a proxy: %v", err)
+		}
+	}
+	db.ContractHook = registerContractABI
+	cachedDB.DB.ContractHook = registerContractABI
+
 	// Initialize resume service with regular database
-	resumeService := service.NewResumeService(bc.Client, db)
+	resumeService := service.NewResumeService(bc.EthClient(), db)
+	resumeService.StartBlock, err = services.ParseStartBlock(cfg.StartBlock)
+	if err != nil {
+		appLogger.Fatal("Invalid start block: %v", err)
+	}
+	resumeService.ContractStartBlocks, err = services.ParseContractStartBlocks(cfg.ContractStartBlocks)
+	if err != nil {
+		appLogger.Fatal("Invalid contract start blocks: %v", err)
+	}
+	resumeService.ChainID = cfg.ChainID
 
 	// Initialize metrics
 	metricsClient := metrics.NewMetrics()
+	bc.RPCLimiter = services.NewRPCLimiter(cfg.NodeRPCRateLimit, metricsClient)
+	bc.Metrics = metricsClient
+
+	slowQueryThreshold := time.Duration(cfg.DBSlowQueryThreshold) * time.Millisecond
+	for _, d := range []*database.Database{db, cachedDB.DB} {
+		if err := d.EnableSlowQueryLogger(slowQueryThreshold, metricsClient); err != nil {
+			appLogger.Error("Failed to enable slow query logger: %v", err)
+		}
+		if _, err := d.MonitorConnection(context.Background(), time.Duration(cfg.DBCheckInterval)*time.Second, nil); err != nil {
+			appLogger.Error("Failed to monitor database connection: %v", err)
+		}
+	}
 
 	// Initialize batch processor with cached database
-	batchProcessor := database.NewBatchProcessor(cachedDB.DB, cfg.BatchSize, time.Duration(cfg.FlushTimeout)*time.Second)
+	batchProcessor := database.NewBatchProcessor(cachedDB.DB, cfg.BatchSize, time.Duration(cfg.FlushTimeout)*time.Second, metricsClient)
+	batchProcessor.SetMaxBufferedBytes(int64(cfg.BatchMaxBytes))
 
 	// Initialize reorg handler
-	reorgHandler := service.NewReorgHandler(bc.Client, db, appLogger, 10, 100) // depth: 10, maxDepth: 100
+	reorgHandler := service.NewReorgHandler(bc.EthClient(), db, appLogger, 10, 100) // depth: 10, maxDepth: 100
+	reorgHandler.Cache = cacheClient
+
+	// Alert on deep reorgs and sustained sync problems through the configured channels
+	alerts := alert.NewConfiguredNotifier(cfg.AlertSlackWebhookURL, cfg.AlertPagerDutyKey, "chainpulse-indexer")
+	reorgHandler.Alerts = alerts
 
 	// Initialize idempotency service
 	idempotencyService := service.NewIdempotencyService(cacheClient, db, 24*time.Hour)
 
+	// Deduplicate events by the configured key in the idempotency service and the database
+	dedupKeyer, err := types.NewDedupKeyer(cfg.DedupKeyStrategy, cfg.ChainID)
+	if err != nil {
+		appLogger.Fatal("Invalid dedup key strategy: %v", err)
+	}
+	idempotencyService.Keyer = dedupKeyer
+	cachedDB.DB.DedupKeyer = dedupKeyer
+
+	// Assign event IDs by the configured strategy, the database auto-increment by default
+	idGenerator, err := types.NewEventIDGenerator(cfg.EventIDStrategy, cfg.ChainID)
+	if err != nil {
+		appLogger.Fatal("Invalid event ID strategy: %v", err)
+	}
+	cachedDB.DB.IDGenerator = idGenerator
+
+	// Initialize event retention if configured
+	retentionPolicy := database.RetentionPolicy{
+		MaxAge:        time.Duration(cfg.RetentionMaxAgeDays) * 24 * time.Hour,
+		MaxBlockDepth: int64(cfg.RetentionBlockDepth),
+	}
+	var retentionManager *database.RetentionManager
+	if retentionPolicy.Enabled() {
+		archiver, err := database.NewTableArchiver(db)
+		if err != nil {
+			appLogger.Error("Failed to create event archiver: %v", err)
+			log.Fatal(err)
+		}
+		retentionManager = database.NewRetentionManager(db, archiver, retentionPolicy)
+	}
+
 	// Initialize metrics collector for data puller
 	metricsCollector := datapuller.GlobalMetricsCollector
 	
@@ -97,12 +198,23 @@ func main() {
 			"url": cfg.EthereumNodeURL, // Use the same Ethereum node URL for HTTPS JSON-RPC
 		},
 		"websocket-jsonrpc": {
-			"url": cfg.EthereumNodeWSURL, // WebSocket URL for real-time data
+			"url":                  cfg.EthereumNodeWSURL, // WebSocket URL for real-time data
+			"compression":          cfg.WSCompression,
+			"compressionThreshold": cfg.WSCompressionThreshold,
 		},
 		"grpc": {
 			"address": cfg.GRPCServerURL, // gRPC server address
 		},
 	}
+	if cfg.DataPullerRPCEndpoints != "" {
+		// Fail over between several HTTPS JSON-RPC providers instead of using the node URL alone
+		endpoints, err := datapuller.ParseEndpoints(cfg.DataPullerRPCEndpoints)
+		if err != nil {
+			appLogger.Error("Invalid data puller RPC endpoints: %v", err)
+			log.Fatal(err)
+		}
+		pluginConfigs["https-jsonrpc"]["endpoints"] = endpoints
+	}
 	
 	// Initialize the data puller with plugin configurations
 	if err := dataPuller.Initialize(pluginConfigs); err != nil {
@@ -115,6 +227,135 @@ func main() {
 
 	// Initialize indexer service
 	indexerService := service.NewIndexerService(bc, cachedDB, batchProcessor, cacheClient, resumeService, appLogger, metricsClient, reorgHandler, idempotencyService, dataPuller)
+	indexerService.Confirmations = uint64(cfg.CacheConfirmations)
+	indexerService.UnconfirmedTTL = time.Duration(cfg.UnconfirmedCacheTTL) * time.Second
+	indexerService.Alerts = alerts
+	indexerService.AlertMaxLag = int64(cfg.AlertMaxLag)
+	indexerService.AlertLagFor = time.Duration(cfg.AlertLagDuration) * time.Second
+	indexerService.BackfillProgress = cachedDB.DB
+	indexerService.ChainID = cfg.ChainID
+	indexerService.Contracts = cachedDB
+
+	// Fan stored events out to in-process consumers
+	eventBus := eventbus.NewEventBus(cfg.EventBusQueueSize)
+	defer eventBus.Close()
+	indexerService.Events = eventBus
+	batchProcessor.SetFlushHook(func(events []*types.IndexedEvent) {
+		cachedDB.InvalidateStoredEvents(events)
+		indexerService.PublishStored(events)
+	})
+
+	// Enrich and filter events before storage with the configured transformers
+	addressLabels, err := service.ParseAddressLabels(cfg.AddressLabels)
+	if err != nil {
+		appLogger.Fatal("Invalid address labels: %v", err)
+	}
+	transformers, err := service.ParseEventTransformers(cfg.EventTransformers, map[string]types.EventTransformer{
+		service.AddressLabelsTransformer: service.LabelAddresses(addressLabels),
+		service.SpamFilterTransformer: service.FilterSpam(service.SpamFilterConfig{
+			Contracts:          strings.Split(cfg.SpamContracts, ","),
+			ZeroValue:          cfg.SpamZeroValue,
+			ZeroValueContracts: strings.Split(cfg.SpamZeroContracts, ","),
+		}, metricsClient),
+	})
+	if err != nil {
+		appLogger.Fatal("Invalid event transformers: %v", err)
+	}
+	indexerService.Transformers = transformers
+
+	// Index the wraps and unwraps of the configured wrapped-native tokens
+	wrappedNative, err := service.ParseWrappedNativeContracts(cfg.WrappedNativeContracts)
+	if err != nil {
+		appLogger.Fatal("Invalid wrapped native contracts: %v", err)
+	}
+	indexerService.WrappedNative = wrappedNative
+
+	// The queues publish dead letters and, with the Kafka sink, pulled events
+	if err := mq.CheckCodec(cfg.MQCodec, mq.DeadLetterMessage{}, &types.IndexedEvent{}); err != nil {
+		appLogger.Fatal("Invalid MQ codec: %v", err)
+	}
+
+	// Process subscribed events one at a time, dead-lettering the events that keep failing
+	if cfg.SyncProcessing {
+		deadLetterMQ := mq.NewMultiProtocolMQ("kafka")
+		deadLetterMQ.SetMetricsCollector(mq.GlobalMetricsCollector)
+		err := deadLetterMQ.Initialize(map[string]map[string]interface{}{
+			"kafka": {
+				"brokers": []string{"localhost:9092"},
+				"codec":   cfg.MQCodec,
+			},
+		})
+		if err != nil {
+			appLogger.Fatal("Failed to initialize dead-letter queue: %v", err)
+		}
+		defer deadLetterMQ.Close()
+		topics := mq.TopicConfig{
+			Prefix:        cfg.MQTopicPrefix,
+			RawEventsName: cfg.MQRawEventsTopic,
+		}
+		indexerService.SyncProcessing = true
+		indexerService.SyncRetry = mq.RetryPolicy{
+			MaxAttempts: cfg.MQRetryMaxAttempts,
+			BaseDelay:   time.Duration(cfg.MQRetryBaseDelay) * time.Millisecond,
+			MaxDelay:    time.Duration(cfg.MQRetryMaxDelay) * time.Millisecond,
+		}
+		indexerService.DeadLetters = deadLetterMQ
+		indexerService.SyncEventsTopic = topics.RawEvents()
+	}
+	readiness := service.NewSyncReadiness(int64(cfg.ReadyMaxLag))
+	indexerService.Readiness = readiness
+
+	// Send pulled external events to the configured sink; by default the indexer stores them itself
+	switch cfg.DataPullerSink {
+	case "indexer":
+	case "file":
+		fileSink, err := datapuller.NewFileSink(cfg.DataPullerSinkPath)
+		if err != nil {
+			appLogger.Fatal("Failed to create data puller file sink: %v", err)
+		}
+		defer fileSink.Close()
+		indexerService.ExternalSink = fileSink
+	case "kafka":
+		sinkMQ := mq.NewMultiProtocolMQ("kafka")
+		sinkMQ.SetMetricsCollector(mq.GlobalMetricsCollector)
+		err := sinkMQ.Initialize(map[string]map[string]interface{}{
+			"kafka": {
+				"brokers":          []string{"localhost:9092"},
+				"max_message_size": cfg.MQMaxMessageSize,
+				"codec":            cfg.MQCodec,
+			},
+		})
+		if err != nil {
+			appLogger.Fatal("Failed to initialize data puller Kafka sink: %v", err)
+		}
+		defer sinkMQ.Close()
+		topics := mq.TopicConfig{
+			Prefix:        cfg.MQTopicPrefix,
+			RawEventsName: cfg.MQRawEventsTopic,
+		}
+		indexerService.ExternalSink = datapuller.NewMQSink(sinkMQ, topics.RawEvents())
+	default:
+		appLogger.Fatal("Unknown data puller sink: %s", cfg.DataPullerSink)
+	}
+
+	// Expose liveness (/health) and readiness (/ready) through the REST plugin
+	restPlugin := api.NewRESTPlugin()
+	restPlugin.SetDatabase(db)
+	restPlugin.SetReadinessProbe(readiness)
+	restPlugin.SetContractWatchlist(indexerService)
+	restPlugin.SetJWTSecret(cfg.JWTSecret)
+	routeRateLimits, err := api.ParseRouteRateLimits(cfg.RateLimitRoutes)
+	if err != nil {
+		appLogger.Fatal("Invalid route rate limits: %v", err)
+	}
+	restPlugin.SetRateLimits(api.RateLimitConfig{
+		Default: api.RateLimit{Rate: float64(cfg.RateLimit), Burst: cfg.RateLimitBurst},
+		Routes:  routeRateLimits,
+		KeyBy:   cfg.RateLimitKey,
+	})
+	if err := restPlugin.Initialize(map[string]interface{}{"port": cfg.IndexerPort}); err != nil {
+		appLogger.Fatal("Failed to initialize REST plugin: %v", err)
+	}
 
 	// Wait for interrupt signal to gracefully shutdown
 	quit := make(chan os.Signal, 1)
@@ -132,6 +373,15 @@ func main() {
 		common.HexToAddress("0x60E4d786628Fea6478F785A6d7e704777c86a7c6"), // Mutant Ape Yacht Club
 		// Add more contract addresses as needed
 	}
+	contractAddresses = append(contractAddresses, indexerService.WrappedNative...)
+
+	// Decode the logs of registered proxies with their implementation ABI, following upgrades
+	if err := bc.TrackProxies(ctx, contracts); err != nil {
+		appLogger.Error("Failed to track proxy contracts: %v", err)
+	}
+	if cfg.ProxyRefreshInterval > 0 {
+		go bc.RefreshProxiesPeriodically(ctx, time.Duration(cfg.ProxyRefreshInterval)*time.Second)
+	}
 
 	go func() {
 		if err := indexerService.StartIndexing(ctx, contractAddresses); err != nil {
@@ -139,12 +389,57 @@ func main() {
 		}
 	}()
 
+	go func() {
+		if err := restPlugin.Start(ctx); err != nil {
+			appLogger.Error("REST plugin error: %v", err)
+		}
+	}()
+
+	if retentionManager != nil {
+		go retentionManager.Start(ctx, time.Duration(cfg.RetentionInterval)*time.Minute)
+	}
+
+	// Roll back the events of reorgs reported by the blockchain listener
+	if cfg.ReorgConsumerEnabled {
+		reorgMQ := mq.NewMultiProtocolMQ("kafka")
+		reorgMQ.SetMetricsCollector(mq.GlobalMetricsCollector)
+		err := reorgMQ.Initialize(map[string]map[string]interface{}{
+			"kafka": {
+				"brokers": []string{"localhost:9092"},
+				"codec":   cfg.MQCodec,
+			},
+		})
+		if err != nil {
+			appLogger.Fatal("Failed to initialize reorg event consumer: %v", err)
+		}
+		defer reorgMQ.Close()
+		topics := mq.TopicConfig{
+			Prefix:          cfg.MQTopicPrefix,
+			ReorgEventsName: cfg.MQReorgTopic,
+		}
+		retry := mq.RetryPolicy{
+			MaxAttempts: cfg.MQRetryMaxAttempts,
+			BaseDelay:   time.Duration(cfg.MQRetryBaseDelay) * time.Millisecond,
+			MaxDelay:    time.Duration(cfg.MQRetryMaxDelay) * time.Millisecond,
+		}
+		go func() {
+			if err := reorgHandler.ConsumeReorgEvents(ctx, reorgMQ, topics.ReorgEvents(), retry); err != nil && err != context.Canceled {
+				appLogger.Error("Reorg event consumer stopped: %v", err)
+			}
+		}()
+	}
+
 	<-quit
 	appLogger.Info("Shutting down indexer service...")
 
+	// Stop indexing and flush pending events before the connections close
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer stopCancel()
+	if err := indexerService.Stop(stopCtx); err != nil {
+		appLogger.Error("Indexer service did not stop cleanly: %v", err)
+	}
+
 	// Close connections
 	bc.Close()
 	cacheClient.Close()
-	batchProcessor.Close()
-	time.Sleep(2 * time.Second) // Allow for graceful shutdown
 }
\ No newline at end of file
@@ -31,6 +31,111 @@ type IndexerServiceClient interface {
 	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
 	// Health check
 	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
+	// Stream new events, resuming after the last received sequence
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (IndexerService_StreamEventsClient, error)
+}
+
+type indexerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIndexerServiceClient(cc grpc.ClientConnInterface) IndexerServiceClient {
+	return &indexerServiceClient{cc}
+}
+
+func (c *indexerServiceClient) GetEvents(ctx context.Context, in *GetEventsRequest, opts ...grpc.CallOption) (*GetEventsResponse, error) {
+	out := new(GetEventsResponse)
+	err := c.cc.Invoke(ctx, "/indexer.IndexerService/GetEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexerServiceClient) GetEventByTxHash(ctx context.Context, in *GetEventByTxHashRequest, opts ...grpc.CallOption) (*GetEventByTxHashResponse, error) {
+	out := new(GetEventByTxHashResponse)
+	err := c.cc.Invoke(ctx, "/indexer.IndexerService/GetEventByTxHash", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexerServiceClient) GetEventsByBlockNumber(ctx context.Context, in *GetEventsByBlockNumberRequest, opts ...grpc.CallOption) (*GetEventsByBlockNumberResponse, error) {
+	out := new(GetEventsByBlockNumberResponse)
+	err := c.cc.Invoke(ctx, "/indexer.IndexerService/GetEventsByBlockNumber", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexerServiceClient) GetContracts(ctx context.Context, in *GetContractsRequest, opts ...grpc.CallOption) (*GetContractsResponse, error) {
+	out := new(GetContractsResponse)
+	err := c.cc.Invoke(ctx, "/indexer.IndexerService/GetContracts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexerServiceClient) GetContractByAddress(ctx context.Context, in *GetContractByAddressRequest, opts ...grpc.CallOption) (*GetContractByAddressResponse, error) {
+	out := new(GetContractByAddressResponse)
+	err := c.cc.Invoke(ctx, "/indexer.IndexerService/GetContractByAddress", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexerServiceClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error) {
+	out := new(GetStatsResponse)
+	err := c.cc.Invoke(ctx, "/indexer.IndexerService/GetStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexerServiceClient) Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error) {
+	out := new(HealthResponse)
+	err := c.cc.Invoke(ctx, "/indexer.IndexerService/Health", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexerServiceClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (IndexerService_StreamEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &IndexerService_ServiceDesc.Streams[0], "/indexer.IndexerService/StreamEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &indexerServiceStreamEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type IndexerService_StreamEventsClient interface {
+	Recv() (*StreamedEvent, error)
+	grpc.ClientStream
+}
+
+type indexerServiceStreamEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *indexerServiceStreamEventsClient) Recv() (*StreamedEvent, error) {
+	m := new(StreamedEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
 // IndexerServiceServer is the server API for IndexerService service.
@@ -51,6 +156,8 @@ type IndexerServiceServer interface {
 	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
 	// Health check
 	Health(context.Context, *HealthRequest) (*HealthResponse, error)
+	// Stream new events, resuming after the last received sequence
+	StreamEvents(*StreamEventsRequest, IndexerService_StreamEventsServer) error
 }
 
 // UnimplementedIndexerServiceServer should be embedded to have forward compatible implementations.
@@ -78,6 +185,9 @@ func (UnimplementedIndexerServiceServer) GetStats(context.Context, *GetStatsRequ
 func (UnimplementedIndexerServiceServer) Health(context.Context, *HealthRequest) (*HealthResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
 }
+func (UnimplementedIndexerServiceServer) StreamEvents(*StreamEventsRequest, IndexerService_StreamEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
 func (UnimplementedIndexerServiceServer) testEmbeddedByUnimplemented() {}
 
 // UnsafeIndexerServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -219,6 +329,27 @@ func _IndexerService_Health_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _IndexerService_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(IndexerServiceServer).StreamEvents(m, &indexerServiceStreamEventsServer{stream})
+}
+
+type IndexerService_StreamEventsServer interface {
+	Send(*StreamedEvent) error
+	grpc.ServerStream
+}
+
+type indexerServiceStreamEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *indexerServiceStreamEventsServer) Send(m *StreamedEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // IndexerService_ServiceDesc is the grpc.ServiceDesc for IndexerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -255,21 +386,29 @@ var IndexerService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _IndexerService_Health_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _IndexerService_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "indexer.proto",
 }
 
 // Request/Response messages for events
 type GetEventsRequest struct {
-	Page  int32 `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
-	Limit int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Page      int32  `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
+	Limit     int32  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	PageToken string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
 }
 
 type GetEventsResponse struct {
-	Events []*Event `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
-	Page   int32    `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
-	Limit  int32    `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
-	Total  int64    `protobuf:"varint,4,opt,name=total,proto3" json:"total,omitempty"`
+	Events        []*Event `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	Page          int32    `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	Limit         int32    `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Total         int64    `protobuf:"varint,4,opt,name=total,proto3" json:"total,omitempty"`
+	NextPageToken string   `protobuf:"bytes,5,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
 }
 
 type GetEventByTxHashRequest struct {
@@ -324,6 +463,37 @@ type HealthResponse struct {
 	Time    string `protobuf:"bytes,3,opt,name=time,proto3" json:"time,omitempty"`
 }
 
+// Request/Response messages for event streaming
+type StreamEventsRequest struct {
+	AfterSequence uint64 `protobuf:"varint,1,opt,name=after_sequence,json=afterSequence,proto3" json:"after_sequence,omitempty"`
+}
+
+func (x *StreamEventsRequest) GetAfterSequence() uint64 {
+	if x != nil {
+		return x.AfterSequence
+	}
+	return 0
+}
+
+type StreamedEvent struct {
+	Sequence uint64 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Event    *Event `protobuf:"bytes,2,opt,name=event,proto3" json:"event,omitempty"`
+}
+
+func (x *StreamedEvent) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *StreamedEvent) GetEvent() *Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
 // Common data types
 type Event struct {
 	Id          uint32 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
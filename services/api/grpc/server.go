@@ -8,26 +8,62 @@ import (
 	"time"
 
 	"chainpulse/shared/database"
+	"chainpulse/shared/requestid"
 	"chainpulse/shared/types"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 )
 
+// StatsStore serves the indexer statistics
+type StatsStore interface {
+	GetStats() (*types.Stats, error)
+}
+
+// EventPageStore serves the pages of events listed by GetEvents, newest first
+type EventPageStore interface {
+	GetEvents(limitNum, offset int) ([]types.IndexedEvent, error)
+	GetEventsBefore(cursor types.EventCursor, limitNum int) ([]types.IndexedEvent, error)
+}
+
 // Server implements the gRPC IndexerService
 type Server struct {
 	UnimplementedIndexerServiceServer
-	db *database.DB
+	Stats  StatsStore     // serves GetStats; NewServer uses the database, set a *database.CachedDatabase to cache them
+	Pages  EventPageStore // serves GetEvents; NewServer uses the database
+	db     *database.DB
+	events *EventStream
 }
 
 // NewServer creates a new gRPC server instance
 func NewServer(db *database.DB) *Server {
 	return &Server{
-		db: db,
+		Stats:  db,
+		Pages:  db,
+		db:     db,
+		events: NewEventStream(DefaultStreamLookback),
 	}
 }
 
-// GetEvents returns a list of events with pagination
+// NewCachedServer creates a gRPC server whose statistics are read through the cache of db,
+// so frequent GetStats calls do not count the events table each time
+func NewCachedServer(db *database.CachedDatabase) *Server {
+	s := NewServer(db.DB)
+	s.Stats = db
+	return s
+}
+
+// PublishEvent sends a newly indexed event to the StreamEvents subscribers
+func (s *Server) PublishEvent(event types.IndexedEvent) {
+	s.events.Publish(event)
+}
+
+// GetEvents returns a page of events, newest first. The page continues after page_token
+// when it is set, otherwise it is selected by page and limit. next_page_token is set
+// when more events may follow; passing it back pages through the events without
+// repeating or skipping any as new ones are indexed, however deep the page.
 func (s *Server) GetEvents(ctx context.Context, req *GetEventsRequest) (*GetEventsResponse, error) {
 	page := int(req.GetPage())
 	limit := int(req.GetLimit())
@@ -39,9 +75,18 @@ func (s *Server) GetEvents(ctx context.Context, req *GetEventsRequest) (*GetEven
 		limit = 50
 	}
 
-	offset := (page - 1) * limit
-
-	events, err := s.db.GetEvents(limit, offset)
+	var events []types.IndexedEvent
+	var err error
+	if req.PageToken != "" {
+		cursor, decodeErr := types.DecodeEventCursor(req.PageToken)
+		if decodeErr != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		events, err = s.Pages.GetEventsBefore(cursor, limit)
+	} else {
+		offset := (page - 1) * limit
+		events, err = s.Pages.GetEvents(limit, offset)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -51,12 +96,17 @@ func (s *Server) GetEvents(ctx context.Context, req *GetEventsRequest) (*GetEven
 		protoEvents[i] = convertToProtoEvent(event)
 	}
 
-	return &GetEventsResponse{
+	resp := &GetEventsResponse{
 		Events: protoEvents,
 		Page:   int32(page),
 		Limit:  int32(limit),
 		Total:  int64(len(events)),
-	}, nil
+	}
+	// Only a full page may be followed by more events
+	if len(events) == limit {
+		resp.NextPageToken = types.CursorOf(events[len(events)-1]).Encode()
+	}
+	return resp, nil
 }
 
 // GetEventByTxHash returns an event by its transaction hash
@@ -142,7 +192,7 @@ func (s *Server) GetContractByAddress(ctx context.Context, req *GetContractByAdd
 
 // GetStats returns indexer statistics
 func (s *Server) GetStats(ctx context.Context, req *GetStatsRequest) (*GetStatsResponse, error) {
-	stats, err := s.db.GetStats()
+	stats, err := s.Stats.GetStats()
 	if err != nil {
 		return nil, err
 	}
@@ -163,6 +213,33 @@ func (s *Server) Health(ctx context.Context, req *HealthRequest) (*HealthRespons
 	}, nil
 }
 
+// StreamEvents streams new events. A client reconnecting with the last sequence it
+// received first gets the events it missed, as far as they are within the lookback.
+func (s *Server) StreamEvents(req *StreamEventsRequest, stream IndexerService_StreamEventsServer) error {
+	backlog, events, unsubscribe := s.events.Subscribe(req.GetAfterSequence())
+	defer unsubscribe()
+
+	for _, event := range backlog {
+		if err := stream.Send(event); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "event stream subscriber fell behind")
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 // convertToProtoEvent converts an IndexedEvent to a protobuf Event
 func convertToProtoEvent(event types.IndexedEvent) *Event {
 	return &Event{
@@ -186,7 +263,7 @@ func convertToProtoContract(contract types.Contract) *Contract {
 		Address:   contract.Address,
 		Name:      contract.Name,
 		Symbol:    contract.Symbol,
-		Type:      contract.Type,
+		Type:      string(contract.Type),
 		CreatedAt: contract.CreatedAt.Unix(),
 		UpdatedAt: contract.UpdatedAt.Unix(),
 	}
@@ -199,7 +276,10 @@ func (s *Server) StartGRPCServer(port string) error {
 		return fmt.Errorf("failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(requestid.UnaryServerInterceptor),
+		grpc.StreamInterceptor(requestid.StreamServerInterceptor),
+	)
 	RegisterIndexerServiceServer(grpcServer, s)
 	
 	// Register reflection service for debugging
@@ -0,0 +1,99 @@
+package grpc
+
+import (
+	"sync"
+	"time"
+
+	"chainpulse/shared/types"
+)
+
+// DefaultStreamLookback is the number of recent events kept for resuming event streams
+const DefaultStreamLookback = 10000
+
+// subscriberBuffer is the number of live events a subscriber may lag behind before it is
+// dropped; the client then reconnects and resumes from the lookback
+const subscriberBuffer = 256
+
+// EventStream assigns sequences to published events and fans them out to subscribers.
+// The most recent events are kept so that a client reconnecting after a disconnect can
+// resume after the last sequence it received. Sequences increase by one per event and
+// start at the creation time in nanoseconds, so they keep increasing across restarts.
+type EventStream struct {
+	mu          sync.Mutex
+	lookback    int
+	recent      []*StreamedEvent // oldest first, at most lookback events
+	next        uint64
+	subscribers map[chan *StreamedEvent]struct{}
+}
+
+// NewEventStream creates an event stream keeping the lookback most recent events
+func NewEventStream(lookback int) *EventStream {
+	if lookback < 0 {
+		lookback = 0
+	}
+	return &EventStream{
+		lookback:    lookback,
+		next:        uint64(time.Now().UnixNano()),
+		subscribers: make(map[chan *StreamedEvent]struct{}),
+	}
+}
+
+// Publish streams event to all subscribers and returns its sequence
+func (s *EventStream) Publish(event types.IndexedEvent) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	streamed := &StreamedEvent{Sequence: s.next, Event: convertToProtoEvent(event)}
+	s.next++
+
+	if s.lookback > 0 {
+		if len(s.recent) == s.lookback {
+			s.recent[0] = nil
+			s.recent = s.recent[1:]
+		}
+		s.recent = append(s.recent, streamed)
+	}
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- streamed:
+		default:
+			// Too far behind: end the subscription, the client resumes from the lookback
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return streamed.Sequence
+}
+
+// Subscribe returns the kept events after afterSequence followed by a channel of new
+// events. An afterSequence of 0 only subscribes to new events. Events older than the
+// lookback cannot be replayed; the client sees a gap in the sequences. The channel is
+// closed if the subscriber falls behind. unsubscribe must be called when done.
+func (s *EventStream) Subscribe(afterSequence uint64) (backlog []*StreamedEvent, events <-chan *StreamedEvent, unsubscribe func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if afterSequence > 0 {
+		for _, streamed := range s.recent {
+			if streamed.Sequence > afterSequence {
+				backlog = append(backlog, streamed)
+			}
+		}
+	}
+
+	ch := make(chan *StreamedEvent, subscriberBuffer)
+	s.subscribers[ch] = struct{}{}
+
+	unsubscribe = func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return backlog, ch, unsubscribe
+}
@@ -0,0 +1,92 @@
+package grpc
+
+import (
+	"math/big"
+	"testing"
+
+	"chainpulse/shared/types"
+)
+
+func streamTestEvent(txHash string) types.IndexedEvent {
+	return types.IndexedEvent{BlockNumber: big.NewInt(100), TxHash: txHash, EventName: "Transfer"}
+}
+
+func TestEventStream_ResumeAfterSequence(t *testing.T) {
+	stream := NewEventStream(10)
+
+	first := stream.Publish(streamTestEvent("0x1"))
+	second := stream.Publish(streamTestEvent("0x2"))
+	stream.Publish(streamTestEvent("0x3"))
+
+	if second != first+1 {
+		t.Errorf("Expected sequence %d, got %d", first+1, second)
+	}
+
+	backlog, events, unsubscribe := stream.Subscribe(first)
+	defer unsubscribe()
+
+	if len(backlog) != 2 {
+		t.Fatalf("Expected 2 backlog events, got %d", len(backlog))
+	}
+	if backlog[0].Sequence != second || backlog[0].Event.TxHash != "0x2" || backlog[1].Event.TxHash != "0x3" {
+		t.Errorf("Unexpected backlog %v", backlog)
+	}
+
+	stream.Publish(streamTestEvent("0x4"))
+	select {
+	case event := <-events:
+		if event.Event.TxHash != "0x4" {
+			t.Errorf("Expected live event 0x4, got %s", event.Event.TxHash)
+		}
+	default:
+		t.Fatal("Expected a live event")
+	}
+}
+
+func TestEventStream_NewSubscriberGetsNoBacklog(t *testing.T) {
+	stream := NewEventStream(10)
+	stream.Publish(streamTestEvent("0x1"))
+
+	backlog, _, unsubscribe := stream.Subscribe(0)
+	defer unsubscribe()
+
+	if len(backlog) != 0 {
+		t.Errorf("Expected no backlog, got %d events", len(backlog))
+	}
+}
+
+func TestEventStream_LookbackIsBounded(t *testing.T) {
+	stream := NewEventStream(2)
+
+	first := stream.Publish(streamTestEvent("0x1"))
+	for _, txHash := range []string{"0x2", "0x3", "0x4"} {
+		stream.Publish(streamTestEvent(txHash))
+	}
+
+	// 0x2 is older than the lookback and cannot be replayed
+	backlog, _, unsubscribe := stream.Subscribe(first)
+	defer unsubscribe()
+
+	if len(backlog) != 2 || backlog[0].Event.TxHash != "0x3" || backlog[1].Event.TxHash != "0x4" {
+		t.Errorf("Expected backlog of 0x3 and 0x4, got %v", backlog)
+	}
+}
+
+func TestEventStream_DropsSlowSubscriber(t *testing.T) {
+	stream := NewEventStream(0)
+
+	_, events, unsubscribe := stream.Subscribe(0)
+	defer unsubscribe()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		stream.Publish(streamTestEvent("0x1"))
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("Expected %d buffered events before the channel closed, got %d", subscriberBuffer, received)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"time"
 
+	"chainpulse/shared/json"
+
 	"github.com/golang-jwt/jwt/v4"
 )
 
@@ -35,7 +37,7 @@ func (am *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 		// Extract token from Authorization header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Authorization header is required", http.StatusUnauthorized)
+			writeError(w, http.StatusUnauthorized, "unauthorized", "Authorization header is required")
 			return
 		}
 
@@ -45,7 +47,7 @@ func (am *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 			tokenString = strings.TrimPrefix(authHeader, "Token ")
 			if tokenString == authHeader {
 				// Neither prefix found, return error
-				http.Error(w, "Authorization header must be in the form 'Bearer {token}' or 'Token {token}'", http.StatusUnauthorized)
+				writeError(w, http.StatusUnauthorized, "unauthorized", "Authorization header must be in the form 'Bearer {token}' or 'Token {token}'")
 				return
 			}
 		}
@@ -53,7 +55,7 @@ func (am *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 		// Validate the token
 		claims, err := am.ValidateToken(tokenString)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Invalid token: %v", err), http.StatusUnauthorized)
+			writeError(w, http.StatusUnauthorized, "unauthorized", fmt.Sprintf("Invalid token: %v", err))
 			return
 		}
 
@@ -116,16 +118,26 @@ func (am *AuthMiddleware) RequireRole(requiredRole string) func(http.Handler) ht
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			user := GetUserFromContext(r.Context())
 			if user == nil {
-				http.Error(w, "User not authenticated", http.StatusUnauthorized)
+				writeError(w, http.StatusUnauthorized, "unauthorized", "User not authenticated")
 				return
 			}
 
-			if user.Role != requiredRole && requiredRole != "admin" && user.Role != "admin" {
-				http.Error(w, "Insufficient permissions", http.StatusForbidden)
+			if user.Role != requiredRole && user.Role != "admin" {
+				writeError(w, http.StatusForbidden, "forbidden", "Insufficient permissions")
 				return
 			}
 
 			next.ServeHTTP(w, r)
 		})
 	}
-}
\ No newline at end of file
+}
+
+// writeError writes the REST API error envelope {"error": {"code": ..., "message": ...}}.
+// It mirrors handlers.writeError, which this package cannot import.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]map[string]string{
+		"error": {"code": code, "message": message},
+	})
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/logger"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+)
+
+// Backfill job statuses
+const (
+	BackfillStatusPending   = "pending"
+	BackfillStatusRunning   = "running"
+	BackfillStatusCompleted = "completed"
+	BackfillStatusFailed    = "failed"
+)
+
+// DefaultBackfillChunkSize is the number of blocks processed per ProcessHistoricalEvents call,
+// which is also the granularity of the reported progress
+const DefaultBackfillChunkSize = 1000
+
+// BackfillRequest is the body of POST /api/v1/admin/backfill
+type BackfillRequest struct {
+	Contract  string `json:"contract"`
+	FromBlock uint64 `json:"fromBlock"`
+	ToBlock   uint64 `json:"toBlock"`
+}
+
+// BackfillJob tracks an on-demand backfill of one contract over a block range
+type BackfillJob struct {
+	ID             string    `json:"id"`
+	Contract       string    `json:"contract"`
+	FromBlock      uint64    `json:"fromBlock"`
+	ToBlock        uint64    `json:"toBlock"`
+	ProcessedBlock uint64    `json:"processedBlock"` // last block processed, 0 before the first chunk completes
+	Status         string    `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// BackfillManager runs backfill jobs in the background and keeps their progress
+type BackfillManager struct {
+	indexerService IndexerService
+	logger         logger.Logger
+	chunkSize      uint64
+	jobs           map[string]*BackfillJob
+	mu             sync.RWMutex
+}
+
+// NewBackfillManager creates a new backfill manager
+func NewBackfillManager(indexerService IndexerService, appLogger logger.Logger) *BackfillManager {
+	return &BackfillManager{
+		indexerService: indexerService,
+		logger:         appLogger,
+		chunkSize:      DefaultBackfillChunkSize,
+		jobs:           make(map[string]*BackfillJob),
+	}
+}
+
+// backfillJobID derives the job ID from the backfill scope, so the same request maps to the same job
+// while it is pending or running
+func backfillJobID(contract common.Address, fromBlock, toBlock uint64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%d", strings.ToLower(contract.Hex()), fromBlock, toBlock)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Submit starts a backfill job for the contract over [fromBlock, toBlock] and returns a snapshot of it.
+// A job with the same scope that is pending or running is returned instead of starting a duplicate
+// (created is false). A completed or failed job is run again, replacing it, so a range can be
+// backfilled again once it was indexed wrongly.
+func (m *BackfillManager) Submit(contract common.Address, fromBlock, toBlock uint64) (job BackfillJob, created bool) {
+	id := backfillJobID(contract, fromBlock, toBlock)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, exists := m.jobs[id]; exists && (existing.Status == BackfillStatusPending || existing.Status == BackfillStatusRunning) {
+		return *existing, false
+	}
+
+	now := time.Now()
+	newJob := &BackfillJob{
+		ID:        id,
+		Contract:  contract.Hex(),
+		FromBlock: fromBlock,
+		ToBlock:   toBlock,
+		Status:    BackfillStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.jobs[id] = newJob
+
+	go m.run(newJob, contract)
+
+	return *newJob, true
+}
+
+// Get returns a snapshot of the job with the given ID
+func (m *BackfillManager) Get(id string) (BackfillJob, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return BackfillJob{}, false
+	}
+	return *job, true
+}
+
+// run processes the job range chunk by chunk, recording progress after each chunk
+func (m *BackfillManager) run(job *BackfillJob, contract common.Address) {
+	// The job outlives the request that created it
+	ctx := context.Background()
+
+	m.update(job, func(j *BackfillJob) { j.Status = BackfillStatusRunning })
+	m.logger.Info("Starting backfill %s for contract %s from block %d to %d", job.ID, job.Contract, job.FromBlock, job.ToBlock)
+
+	for start := job.FromBlock; start <= job.ToBlock; start += m.chunkSize {
+		end := start + m.chunkSize - 1
+		if end > job.ToBlock || end < start {
+			end = job.ToBlock
+		}
+
+		err := m.indexerService.ProcessHistoricalEvents(ctx, []common.Address{contract}, new(big.Int).SetUint64(start), new(big.Int).SetUint64(end))
+		if err != nil {
+			m.logger.Error("Backfill %s failed at blocks %d-%d: %v", job.ID, start, end, err)
+			m.update(job, func(j *BackfillJob) {
+				j.Status = BackfillStatusFailed
+				j.Error = err.Error()
+			})
+			return
+		}
+
+		m.update(job, func(j *BackfillJob) { j.ProcessedBlock = end })
+
+		if end == job.ToBlock {
+			break
+		}
+	}
+
+	m.update(job, func(j *BackfillJob) { j.Status = BackfillStatusCompleted })
+	m.logger.Info("Completed backfill %s for contract %s", job.ID, job.Contract)
+}
+
+// update applies fn to the job under the manager lock
+func (m *BackfillManager) update(job *BackfillJob, fn func(*BackfillJob)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fn(job)
+	job.UpdatedAt = time.Now()
+}
+
+// BackfillHandler handles POST /api/v1/admin/backfill requests
+func (s *Server) BackfillHandler(w http.ResponseWriter, r *http.Request) {
+	var req BackfillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid request body")
+		return
+	}
+
+	if !common.IsHexAddress(req.Contract) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid contract address")
+		return
+	}
+
+	if req.ToBlock < req.FromBlock {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "toBlock must not be less than fromBlock")
+		return
+	}
+
+	job, created := s.backfills.Submit(common.HexToAddress(req.Contract), req.FromBlock, req.ToBlock)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/admin/backfill/"+job.ID)
+	if created {
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(job)
+}
+
+// GetBackfillJobHandler handles GET /api/v1/admin/backfill/{id} requests
+func (s *Server) GetBackfillJobHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	job, exists := s.backfills.Get(id)
+	if !exists {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "Backfill job not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"chainpulse/services/api/handlers/auth"
+	"chainpulse/shared/json"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// backfillIndexerService records the ranges passed to ProcessHistoricalEvents
+type backfillIndexerService struct {
+	MockIndexerService
+	mu        sync.Mutex
+	calls     [][2]uint64
+	addresses [][]common.Address
+}
+
+func (m *backfillIndexerService) ProcessHistoricalEvents(ctx context.Context, contractAddresses []common.Address, fromBlock, toBlock *big.Int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, [2]uint64{fromBlock.Uint64(), toBlock.Uint64()})
+	m.addresses = append(m.addresses, contractAddresses)
+	return nil
+}
+
+func adminRequest(t *testing.T, method, url string, body []byte, role string) *http.Request {
+	token, err := auth.NewAuthMiddleware("test-secret").GenerateToken("operator", role)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestBackfillHandler(t *testing.T) {
+	mockIndexerService := &backfillIndexerService{}
+	server := NewServer(mockIndexerService, "test-secret", nil)
+
+	contract := "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D"
+	body, _ := json.Marshal(BackfillRequest{Contract: contract, FromBlock: 1, ToBlock: 2500})
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "POST", "/api/v1/admin/backfill", body, "admin"))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, rr.Code, rr.Body.String())
+	}
+
+	var job BackfillJob
+	if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil {
+		t.Fatalf("Failed to decode job: %v", err)
+	}
+	if job.ID == "" {
+		t.Fatal("Expected a job ID")
+	}
+
+	// Poll until the job completes
+	deadline := time.Now().Add(time.Second)
+	for job.Status != BackfillStatusCompleted {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected job to complete, got status %s", job.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		rr = httptest.NewRecorder()
+		server.GetRouter().ServeHTTP(rr, adminRequest(t, "GET", "/api/v1/admin/backfill/"+job.ID, nil, "admin"))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil {
+			t.Fatalf("Failed to decode job: %v", err)
+		}
+	}
+
+	if job.ProcessedBlock != 2500 {
+		t.Errorf("Expected processed block 2500, got %d", job.ProcessedBlock)
+	}
+
+	mockIndexerService.mu.Lock()
+	calls := mockIndexerService.calls
+	addresses := mockIndexerService.addresses
+	mockIndexerService.mu.Unlock()
+
+	expected := [][2]uint64{{1, 1000}, {1001, 2000}, {2001, 2500}}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected %d chunks, got %d", len(expected), len(calls))
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("Expected chunk %v, got %v", expected[i], calls[i])
+		}
+		if len(addresses[i]) != 1 || addresses[i][0] != common.HexToAddress(contract) {
+			t.Errorf("Expected only contract %s, got %v", contract, addresses[i])
+		}
+	}
+
+	// Re-submitting a completed backfill runs it again under the same job ID
+	rr = httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "POST", "/api/v1/admin/backfill", body, "admin"))
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("Expected status %d, got %d", http.StatusAccepted, rr.Code)
+	}
+	var again BackfillJob
+	json.Unmarshal(rr.Body.Bytes(), &again)
+	if again.ID != job.ID {
+		t.Errorf("Expected job ID %s, got %s", job.ID, again.ID)
+	}
+
+	deadline = time.Now().Add(time.Second)
+	for {
+		mockIndexerService.mu.Lock()
+		processed := len(mockIndexerService.calls)
+		mockIndexerService.mu.Unlock()
+		if processed == 2*len(expected) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the backfill to be processed again, got %d chunks", processed)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// blockingIndexerService holds ProcessHistoricalEvents until release is closed
+type blockingIndexerService struct {
+	backfillIndexerService
+	release chan struct{}
+}
+
+func (m *blockingIndexerService) ProcessHistoricalEvents(ctx context.Context, contractAddresses []common.Address, fromBlock, toBlock *big.Int) error {
+	<-m.release
+	return m.backfillIndexerService.ProcessHistoricalEvents(ctx, contractAddresses, fromBlock, toBlock)
+}
+
+func TestBackfillHandlerReturnsRunningJob(t *testing.T) {
+	mockIndexerService := &blockingIndexerService{release: make(chan struct{})}
+	server := NewServer(mockIndexerService, "test-secret", nil)
+	body, _ := json.Marshal(BackfillRequest{Contract: "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D", FromBlock: 1, ToBlock: 10})
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "POST", "/api/v1/admin/backfill", body, "admin"))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, rr.Code, rr.Body.String())
+	}
+
+	// The same backfill submitted while the first is unfinished is not started twice
+	rr = httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "POST", "/api/v1/admin/backfill", body, "admin"))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	close(mockIndexerService.release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		mockIndexerService.mu.Lock()
+		processed := len(mockIndexerService.calls)
+		mockIndexerService.mu.Unlock()
+		if processed == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 1 chunk, got %d", processed)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	mockIndexerService.mu.Lock()
+	defer mockIndexerService.mu.Unlock()
+	if len(mockIndexerService.calls) != 1 {
+		t.Errorf("Expected the backfill to run once, got %d chunks", len(mockIndexerService.calls))
+	}
+}
+
+func TestBackfillHandlerRequiresAdmin(t *testing.T) {
+	server := NewServer(&backfillIndexerService{}, "test-secret", nil)
+	body, _ := json.Marshal(BackfillRequest{Contract: "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D", FromBlock: 1, ToBlock: 10})
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "POST", "/api/v1/admin/backfill", body, "user"))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, rr.Code)
+	}
+
+	req, _ := http.NewRequest("POST", "/api/v1/admin/backfill", bytes.NewReader(body))
+	rr = httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+}
+
+func TestBackfillHandlerInvalidRange(t *testing.T) {
+	server := NewServer(&backfillIndexerService{}, "test-secret", nil)
+	body, _ := json.Marshal(BackfillRequest{Contract: "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D", FromBlock: 10, ToBlock: 1})
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "POST", "/api/v1/admin/backfill", body, "admin"))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
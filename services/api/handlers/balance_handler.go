@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"chainpulse/shared/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+)
+
+// GetBalancesHandler handles GET /api/v1/balances/{address} requests, returning the
+// current ERC-20 and ERC-721 balances of an address derived from its indexed transfers.
+// The contract query parameter restricts them to one token contract.
+func (s *Server) GetBalancesHandler(w http.ResponseWriter, r *http.Request) {
+	addr := mux.Vars(r)["address"]
+	if !common.IsHexAddress(addr) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid address")
+		return
+	}
+	// Stored addresses are checksummed
+	address := common.HexToAddress(addr).Hex()
+
+	var contract string
+	if c := r.URL.Query().Get("contract"); c != "" {
+		if !common.IsHexAddress(c) {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid contract address")
+			return
+		}
+		contract = common.HexToAddress(c).Hex()
+	}
+
+	balances, err := s.indexerService.GetBalances(address, contract)
+	if err != nil {
+		s.logger.WithTrace(r.Context()).Error("Failed to get balances of %s: %v", address, err)
+		writeStoreError(w, err, "Failed to get balances")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"address":  address,
+		"balances": balances,
+		"total":    len(balances),
+	})
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// bulkImportBatchSize is the number of events inserted per batch during a bulk import
+	bulkImportBatchSize = 500
+	// maxBulkImportLineSize bounds the size of one NDJSON line
+	maxBulkImportLineSize = 1 << 20
+	// maxBulkImportErrors is the number of failed lines reported back in detail
+	maxBulkImportErrors = 100
+)
+
+// EventImporter stores imported events, skipping duplicates of stored events unless force
+// is set, then overwriting them, and reports how many were inserted or overwritten.
+// *database.BatchProcessor implements it.
+type EventImporter interface {
+	InsertBatch(events []*types.IndexedEvent, force bool) (int64, error)
+}
+
+// BulkImportError describes a line of a bulk import that could not be imported
+type BulkImportError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// BulkImportResult is the response of POST /api/v1/events/bulk
+type BulkImportResult struct {
+	Inserted int64             `json:"inserted"` // including overwritten events of a forced import
+	Skipped  int64             `json:"skipped"`  // duplicates of stored or earlier imported events
+	Failed   int64             `json:"failed"`
+	Errors   []BulkImportError `json:"errors,omitempty"` // the first failed lines
+}
+
+// bulkImport accumulates parsed events into batches and counts the outcome
+type bulkImport struct {
+	importer EventImporter
+	force    bool // overwrite stored duplicates instead of skipping them
+	batch    []*types.IndexedEvent
+	lines    []int // line number of each event in batch
+	result   BulkImportResult
+}
+
+func (b *bulkImport) fail(line int, message string) {
+	b.result.Failed++
+	if len(b.result.Errors) < maxBulkImportErrors {
+		b.result.Errors = append(b.result.Errors, BulkImportError{Line: line, Message: message})
+	}
+}
+
+func (b *bulkImport) add(line int, event *types.IndexedEvent) {
+	b.batch = append(b.batch, event)
+	b.lines = append(b.lines, line)
+	if len(b.batch) >= bulkImportBatchSize {
+		b.flush()
+	}
+}
+
+func (b *bulkImport) flush() {
+	if len(b.batch) == 0 {
+		return
+	}
+
+	inserted, err := b.importer.InsertBatch(b.batch, b.force)
+	if err != nil {
+		for _, line := range b.lines {
+			b.fail(line, fmt.Sprintf("failed to store event: %v", err))
+		}
+	} else {
+		b.result.Inserted += inserted
+		b.result.Skipped += int64(len(b.batch)) - inserted
+	}
+
+	b.batch = nil
+	b.lines = nil
+}
+
+// parseImportedEvent decodes and validates one NDJSON line, normalizing hashes to
+// lowercase and addresses to their checksummed form as the indexer stores them
+func parseImportedEvent(line []byte) (*types.IndexedEvent, error) {
+	var event types.IndexedEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	event.TxHash = strings.ToLower(event.TxHash)
+	if len(event.TxHash) != 66 || !strings.HasPrefix(event.TxHash, "0x") {
+		return nil, fmt.Errorf("invalid tx_hash %q", event.TxHash)
+	}
+	if event.BlockNumber == nil || event.BlockNumber.Sign() < 0 {
+		return nil, fmt.Errorf("block_number is required")
+	}
+	if event.EventName == "" {
+		return nil, fmt.Errorf("event_name is required")
+	}
+	if !common.IsHexAddress(event.Contract) {
+		return nil, fmt.Errorf("invalid contract address %q", event.Contract)
+	}
+	event.Contract = common.HexToAddress(event.Contract).Hex()
+
+	for _, addr := range []*string{&event.From, &event.To} {
+		if *addr == "" {
+			continue
+		}
+		if !common.IsHexAddress(*addr) {
+			return nil, fmt.Errorf("invalid address %q", *addr)
+		}
+		*addr = common.HexToAddress(*addr).Hex()
+	}
+
+	// IDs and bookkeeping timestamps are assigned by this store
+	event.ID = 0
+	event.CreatedAt = time.Time{}
+	event.UpdatedAt = time.Time{}
+	return &event, nil
+}
+
+// BulkImportEventsHandler handles POST /api/v1/events/bulk requests, importing events
+// exported from another indexer. The body is NDJSON, one event per line, read as a
+// stream and stored in batches, so imports of any size use bounded memory. Events
+// already stored are skipped, which makes re-running an import safe, unless force=true is
+// set to overwrite them, e.g. to re-import corrected events. Lines that fail validation
+// are counted and reported without stopping the import.
+func (s *Server) BulkImportEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if s.eventImporter == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Bulk import not available")
+		return
+	}
+
+	b := &bulkImport{importer: s.eventImporter, force: r.URL.Query().Get("force") == "true"}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBulkImportLineSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Bytes()
+		if len(bytes.TrimSpace(text)) == 0 {
+			continue
+		}
+
+		event, err := parseImportedEvent(text)
+		if err != nil {
+			b.fail(line, err.Error())
+			continue
+		}
+		b.add(line, event)
+	}
+	b.flush()
+
+	if err := scanner.Err(); err != nil {
+		// The rest of the body cannot be read; report what was imported up to here
+		b.fail(line+1, fmt.Sprintf("failed to read request body: %v", err))
+	}
+
+	s.logger.WithTrace(r.Context()).Info("Bulk import (force: %t): %d inserted, %d skipped, %d failed", b.force, b.result.Inserted, b.result.Skipped, b.result.Failed)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b.result)
+}
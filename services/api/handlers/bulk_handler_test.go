@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
+)
+
+// dedupEventImporter stores events keyed by (tx hash, log index), skipping duplicates
+// like the unique index of the events table unless forced
+type dedupEventImporter struct {
+	stored  map[string]*types.IndexedEvent
+	batches int
+	forced  bool // whether any batch was forced
+}
+
+func (d *dedupEventImporter) InsertBatch(events []*types.IndexedEvent, force bool) (int64, error) {
+	d.batches++
+	d.forced = d.forced || force
+	var inserted int64
+	for _, event := range events {
+		key := fmt.Sprintf("%s:%d", event.TxHash, event.LogIndex)
+		if _, ok := d.stored[key]; ok && !force {
+			continue
+		}
+		d.stored[key] = event
+		inserted++
+	}
+	return inserted, nil
+}
+
+func importLine(txByte byte, logIndex uint) string {
+	return fmt.Sprintf(`{"block_number":100,"tx_hash":"0x%s","log_index":%d,"event_name":"Transfer","contract":"0xbc4ca0eda7647a8ab7c2061c2e118a18a936f13d","from":"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed","to":""}`,
+		strings.Repeat(fmt.Sprintf("%02x", txByte), 32), logIndex)
+}
+
+func TestBulkImportEventsHandler(t *testing.T) {
+	importer := &dedupEventImporter{stored: map[string]*types.IndexedEvent{}}
+	server := NewServer(&MockIndexerService{}, "test-secret", nil)
+	server.eventImporter = importer
+
+	// Already stored from an earlier import
+	importer.InsertBatch([]*types.IndexedEvent{{TxHash: "0x" + strings.Repeat("aa", 32), LogIndex: 0}}, false)
+
+	body := strings.Join([]string{
+		importLine(0xaa, 0), // duplicate of a stored event
+		importLine(0xaa, 1),
+		importLine(0xbb, 0),
+		importLine(0xbb, 0), // duplicate within the import
+		"",
+		`{"tx_hash": "0x1", "event_name": "Transfer"}`,
+		`not json`,
+	}, "\n")
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "POST", "/api/v1/events/bulk", []byte(body), "admin"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var result BulkImportResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+
+	if result.Inserted != 2 || result.Skipped != 2 || result.Failed != 2 {
+		t.Errorf("Expected 2 inserted, 2 skipped and 2 failed, got %d, %d and %d", result.Inserted, result.Skipped, result.Failed)
+	}
+	if len(result.Errors) != 2 || result.Errors[0].Line != 6 || result.Errors[1].Line != 7 {
+		t.Errorf("Expected errors on lines 6 and 7, got %v", result.Errors)
+	}
+
+	// Stored in the indexer's normalized form
+	event := importer.stored["0x"+strings.Repeat("bb", 32)+":0"]
+	if event == nil {
+		t.Fatal("Expected the imported event to be stored")
+	}
+	if event.Contract != "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D" || event.From != "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed" {
+		t.Errorf("Expected checksummed addresses, got %s and %s", event.Contract, event.From)
+	}
+}
+
+func TestBulkImportEventsHandler_Batches(t *testing.T) {
+	importer := &dedupEventImporter{stored: map[string]*types.IndexedEvent{}}
+	server := NewServer(&MockIndexerService{}, "test-secret", nil)
+	server.eventImporter = importer
+
+	var lines []string
+	for i := 0; i < bulkImportBatchSize+10; i++ {
+		lines = append(lines, importLine(0xcc, uint(i)))
+	}
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "POST", "/api/v1/events/bulk", []byte(strings.Join(lines, "\n")), "admin"))
+
+	var result BulkImportResult
+	json.Unmarshal(rr.Body.Bytes(), &result)
+	if result.Inserted != int64(bulkImportBatchSize+10) {
+		t.Errorf("Expected %d inserted, got %d", bulkImportBatchSize+10, result.Inserted)
+	}
+	if importer.batches != 2 {
+		t.Errorf("Expected 2 batches, got %d", importer.batches)
+	}
+}
+
+// generatedImport lazily produces an import of n lines, counting the bytes read so far
+type generatedImport struct {
+	n, next int
+	pending []byte
+	read    int64
+}
+
+func (g *generatedImport) Read(p []byte) (int, error) {
+	if len(g.pending) == 0 {
+		if g.next == g.n {
+			return 0, io.EOF
+		}
+		g.pending = []byte(importLine(0xee, uint(g.next)) + "\n")
+		g.next++
+	}
+	n := copy(p, g.pending)
+	g.pending = g.pending[n:]
+	g.read += int64(n)
+	return n, nil
+}
+
+// progressImporter records how much of the body had been read at the first batch
+type progressImporter struct {
+	dedupEventImporter
+	body        *generatedImport
+	readAtFirst int64
+}
+
+func (p *progressImporter) InsertBatch(events []*types.IndexedEvent, force bool) (int64, error) {
+	if p.batches == 0 {
+		p.readAtFirst = p.body.read
+	}
+	return p.dedupEventImporter.InsertBatch(events, force)
+}
+
+func TestBulkImportEventsHandler_Streams(t *testing.T) {
+	body := &generatedImport{n: bulkImportBatchSize * 20}
+	importer := &progressImporter{dedupEventImporter: dedupEventImporter{stored: map[string]*types.IndexedEvent{}}, body: body}
+	server := NewServer(&MockIndexerService{}, "test-secret", nil)
+	server.eventImporter = importer
+
+	req := adminRequest(t, "POST", "/api/v1/events/bulk", nil, "admin")
+	req.Body = io.NopCloser(body)
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	// The first batch is inserted long before the whole body has been read
+	if importer.readAtFirst == 0 || importer.readAtFirst >= body.read/2 {
+		t.Errorf("Expected the first batch before half of the %d bytes were read, got %d", body.read, importer.readAtFirst)
+	}
+	if len(importer.stored) != body.n {
+		t.Errorf("Expected %d events stored, got %d", body.n, len(importer.stored))
+	}
+}
+
+func TestBulkImportEventsHandler_Force(t *testing.T) {
+	importer := &dedupEventImporter{stored: map[string]*types.IndexedEvent{}}
+	server := NewServer(&MockIndexerService{}, "test-secret", nil)
+	server.eventImporter = importer
+
+	stored := &types.IndexedEvent{TxHash: "0x" + strings.Repeat("aa", 32), LogIndex: 0, EventName: "Approval"}
+	importer.InsertBatch([]*types.IndexedEvent{stored}, false)
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "POST", "/api/v1/events/bulk?force=true", []byte(importLine(0xaa, 0)), "admin"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var result BulkImportResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if !importer.forced || result.Inserted != 1 || result.Skipped != 0 {
+		t.Errorf("Expected the stored event to be overwritten, got %+v", result)
+	}
+	if event := importer.stored[stored.TxHash+":0"]; event.EventName != "Transfer" {
+		t.Errorf("Expected the imported Transfer event, got %s", event.EventName)
+	}
+}
+
+func TestBulkImportEventsHandler_RequiresAdmin(t *testing.T) {
+	server := NewServer(&MockIndexerService{}, "test-secret", nil)
+	server.eventImporter = &dedupEventImporter{stored: map[string]*types.IndexedEvent{}}
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "POST", "/api/v1/events/bulk", []byte(importLine(0xdd, 0)), "viewer"))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, rr.Code)
+	}
+}
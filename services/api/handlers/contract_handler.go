@@ -1,21 +1,38 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
 
-	"chainpulse/shared/database"
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
 
 	"github.com/gorilla/mux"
 )
 
+// ContractStore is the data access used by the contract handler. GetContractByAddress
+// returns a nil contract without an error for an unknown address.
+type ContractStore interface {
+	GetContracts() ([]types.Contract, error)
+	GetContractByAddress(address string) (*types.Contract, error)
+}
+
+// ContractWatchlist pauses and resumes the indexing of registered contracts. Both
+// methods report whether the contract is registered.
+type ContractWatchlist interface {
+	PauseContract(ctx context.Context, address string) (bool, error)
+	ResumeContract(ctx context.Context, address string) (bool, error)
+}
+
 // ContractHandler handles contract-related API requests
 type ContractHandler struct {
-	DB *database.DB
+	DB ContractStore
+	// Watchlist serves the pause and resume endpoints, nil when nothing is indexed
+	Watchlist ContractWatchlist
 }
 
 // NewContractHandler creates a new contract handler
-func NewContractHandler(db *database.DB) *ContractHandler {
+func NewContractHandler(db ContractStore) *ContractHandler {
 	return &ContractHandler{
 		DB: db,
 	}
@@ -25,7 +42,7 @@ func NewContractHandler(db *database.DB) *ContractHandler {
 func (h *ContractHandler) GetContracts(w http.ResponseWriter, r *http.Request) {
 	contracts, err := h.DB.GetContracts()
 	if err != nil {
-		http.Error(w, "Failed to get contracts", http.StatusInternalServerError)
+		writeStoreError(w, err, "Failed to get contracts")
 		return
 	}
 
@@ -36,22 +53,58 @@ func (h *ContractHandler) GetContracts(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GetContractByAddress returns a contract by its address
+// GetContractByAddress returns a contract by its address, or 404 if it is not known
 func (h *ContractHandler) GetContractByAddress(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	address := vars["address"]
 
 	contract, err := h.DB.GetContractByAddress(address)
 	if err != nil {
-		http.Error(w, "Failed to get contract", http.StatusInternalServerError)
+		writeStoreError(w, err, "Failed to get contract")
 		return
 	}
 
 	if contract == nil {
-		http.Error(w, "Contract not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "Contract not found")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(contract)
-}
\ No newline at end of file
+}
+
+// PauseContract stops indexing a contract, keeping its events, and returns the contract
+func (h *ContractHandler) PauseContract(w http.ResponseWriter, r *http.Request) {
+	h.setContractActive(w, r, false)
+}
+
+// ResumeContract resumes indexing a paused contract and returns the contract
+func (h *ContractHandler) ResumeContract(w http.ResponseWriter, r *http.Request) {
+	h.setContractActive(w, r, true)
+}
+
+func (h *ContractHandler) setContractActive(w http.ResponseWriter, r *http.Request, active bool) {
+	if h.Watchlist == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Contract indexing is not available")
+		return
+	}
+
+	address := mux.Vars(r)["address"]
+	var found bool
+	var err error
+	if active {
+		found, err = h.Watchlist.ResumeContract(r.Context(), address)
+	} else {
+		found, err = h.Watchlist.PauseContract(r.Context(), address)
+	}
+	if err != nil {
+		writeStoreError(w, err, "Failed to update contract")
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "Contract not found")
+		return
+	}
+
+	h.GetContractByAddress(w, r)
+}
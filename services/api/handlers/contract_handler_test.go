@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
+
+	"github.com/gorilla/mux"
+)
+
+// contractStore serves the contracts it holds, failing every lookup when err is set
+type contractStore struct {
+	contracts map[string]types.Contract
+	err       error
+}
+
+func (c *contractStore) GetContracts() ([]types.Contract, error) {
+	var contracts []types.Contract
+	for _, contract := range c.contracts {
+		contracts = append(contracts, contract)
+	}
+	return contracts, c.err
+}
+
+func (c *contractStore) GetContractByAddress(address string) (*types.Contract, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	contract, ok := c.contracts[address]
+	if !ok {
+		return nil, nil
+	}
+	return &contract, nil
+}
+
+func getContract(store ContractStore, address string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/api/v1/contracts/"+address, nil)
+	req = mux.SetURLVars(req, map[string]string{"address": address})
+	rr := httptest.NewRecorder()
+	NewContractHandler(store).GetContractByAddress(rr, req)
+	return rr
+}
+
+func TestContractHandler_GetContractByAddress(t *testing.T) {
+	address := "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D"
+	store := &contractStore{contracts: map[string]types.Contract{
+		address: {ID: 1, Address: address, Name: "BoredApeYachtClub"},
+	}}
+
+	rr := getContract(store, address)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	var contract types.Contract
+	if err := json.Unmarshal(rr.Body.Bytes(), &contract); err != nil {
+		t.Fatalf("Expected a contract, got %s: %v", rr.Body.String(), err)
+	}
+	if contract.Address != address || contract.Name != "BoredApeYachtClub" {
+		t.Errorf("Expected contract %s, got %+v", address, contract)
+	}
+
+	rr = getContract(store, "0x0000000000000000000000000000000000000001")
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+	if response := decodeErrorResponse(t, rr); response.Error.Code != ErrCodeNotFound {
+		t.Errorf("Expected code %s, got %s", ErrCodeNotFound, response.Error.Code)
+	}
+
+	rr = getContract(&contractStore{err: errors.New("connection refused")}, address)
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+	}
+	if response := decodeErrorResponse(t, rr); response.Error.Code != ErrCodeInternal {
+		t.Errorf("Expected code %s, got %s", ErrCodeInternal, response.Error.Code)
+	}
+}
+
+// contractWatchlist pauses the contracts of a contractStore
+type contractWatchlist struct {
+	store *contractStore
+}
+
+func (c *contractWatchlist) PauseContract(ctx context.Context, address string) (bool, error) {
+	return c.setActive(address, false)
+}
+
+func (c *contractWatchlist) ResumeContract(ctx context.Context, address string) (bool, error) {
+	return c.setActive(address, true)
+}
+
+func (c *contractWatchlist) setActive(address string, active bool) (bool, error) {
+	contract, ok := c.store.contracts[address]
+	if !ok {
+		return false, nil
+	}
+	contract.Active = active
+	c.store.contracts[address] = contract
+	return true, nil
+}
+
+func TestContractHandler_PauseAndResumeContract(t *testing.T) {
+	address := "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D"
+	store := &contractStore{contracts: map[string]types.Contract{
+		address: {ID: 1, Address: address, Active: true},
+	}}
+	handler := NewContractHandler(store)
+	handler.Watchlist = &contractWatchlist{store: store}
+
+	post := func(action http.HandlerFunc, address string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/contracts/"+address+"/pause", nil)
+		req = mux.SetURLVars(req, map[string]string{"address": address})
+		rr := httptest.NewRecorder()
+		action(rr, req)
+		return rr
+	}
+
+	rr := post(handler.PauseContract, address)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	var contract types.Contract
+	if err := json.Unmarshal(rr.Body.Bytes(), &contract); err != nil {
+		t.Fatalf("Expected a contract, got %s: %v", rr.Body.String(), err)
+	}
+	if contract.Active {
+		t.Error("Expected the contract to be paused")
+	}
+
+	if rr = post(handler.ResumeContract, address); rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if !store.contracts[address].Active {
+		t.Error("Expected the contract to be resumed")
+	}
+
+	if rr = post(handler.PauseContract, "0x0000000000000000000000000000000000000001"); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	handler.Watchlist = nil
+	if rr = post(handler.PauseContract, address); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+}
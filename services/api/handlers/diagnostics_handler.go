@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
+)
+
+// DiagnosticsSource reports what the indexer is doing internally
+type DiagnosticsSource interface {
+	Diagnostics() types.IndexerDiagnostics
+}
+
+// SetDiagnostics sets the indexer whose subscriptions, sync progress, batch buffer and
+// event processing are reported by /api/v1/admin/diagnostics
+func (s *Server) SetDiagnostics(source DiagnosticsSource) {
+	s.diagnostics = source
+}
+
+// DiagnosticsHandler handles GET /api/v1/admin/diagnostics requests, returning a snapshot
+// of the indexer's active subscriptions, last processed and head block per chain, batch
+// buffer depth, events being processed and goroutine count
+func (s *Server) DiagnosticsHandler(w http.ResponseWriter, r *http.Request) {
+	if s.diagnostics == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Diagnostics not available")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.diagnostics.Diagnostics())
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
+)
+
+// staticDiagnostics reports a fixed snapshot
+type staticDiagnostics struct {
+	snapshot types.IndexerDiagnostics
+}
+
+func (d *staticDiagnostics) Diagnostics() types.IndexerDiagnostics {
+	return d.snapshot
+}
+
+func TestDiagnosticsHandler(t *testing.T) {
+	server := NewServer(&MockIndexerService{}, "test-secret", nil)
+	server.SetDiagnostics(&staticDiagnostics{snapshot: types.IndexerDiagnostics{
+		Subscriptions: []types.ContractSubscription{
+			{Contract: "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D", Events: []string{"nft_transfer", "token_transfer"}},
+		},
+		Chains:          []types.ChainProgress{{ChainID: "1", LastProcessedBlock: 90, HeadBlock: 100}},
+		BatchBufferSize: 7,
+		EventsInFlight:  3,
+		Goroutines:      42,
+	}})
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "GET", "/api/v1/admin/diagnostics", nil, "admin"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var diagnostics types.IndexerDiagnostics
+	if err := json.Unmarshal(rr.Body.Bytes(), &diagnostics); err != nil {
+		t.Fatalf("Failed to decode diagnostics: %v", err)
+	}
+	if len(diagnostics.Subscriptions) != 1 || len(diagnostics.Subscriptions[0].Events) != 2 {
+		t.Errorf("Expected 1 subscription to 2 events, got %+v", diagnostics.Subscriptions)
+	}
+	if len(diagnostics.Chains) != 1 || diagnostics.Chains[0].LastProcessedBlock != 90 || diagnostics.Chains[0].HeadBlock != 100 {
+		t.Errorf("Expected chain 1 at block 90 of 100, got %+v", diagnostics.Chains)
+	}
+	if diagnostics.BatchBufferSize != 7 || diagnostics.EventsInFlight != 3 || diagnostics.Goroutines != 42 {
+		t.Errorf("Expected 7 buffered events, 3 in flight and 42 goroutines, got %+v", diagnostics)
+	}
+}
+
+func TestDiagnosticsHandler_RequiresAdmin(t *testing.T) {
+	server := NewServer(&MockIndexerService{}, "test-secret", nil)
+	server.SetDiagnostics(&staticDiagnostics{})
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "GET", "/api/v1/admin/diagnostics", nil, "viewer"))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, rr.Code)
+	}
+}
+
+func TestDiagnosticsHandler_Unavailable(t *testing.T) {
+	server := NewServer(&MockIndexerService{}, "test-secret", nil)
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "GET", "/api/v1/admin/diagnostics", nil, "admin"))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+}
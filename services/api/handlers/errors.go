@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"chainpulse/shared/database"
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
+
+	"gorm.io/gorm"
+)
+
+// Error codes returned in the error envelope
+const (
+	ErrCodeInvalidArgument = "invalid_argument"
+	ErrCodeNotFound        = "not_found"
+	ErrCodeUnauthorized    = "unauthorized"
+	ErrCodeForbidden       = "forbidden"
+	ErrCodeTimeout         = "timeout"
+	ErrCodeUnavailable     = "unavailable"
+	ErrCodeInternal        = "internal"
+)
+
+// ErrorBody describes an API error
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ErrorResponse is the envelope of every REST API error:
+// {"error": {"code": "...", "message": "..."}}
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// writeError writes the error envelope with the given status
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+}
+
+// writeStoreError writes the error envelope for an error returned by the indexer or database.
+// Known sentinel errors map to their own status and code; any other error is reported as
+// an internal error with message, so storage details are not exposed to clients.
+func writeStoreError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "Resource not found")
+	case errors.Is(err, types.ErrUnknownReplayTransform):
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusGatewayTimeout, ErrCodeTimeout, "Request timed out")
+	case errors.Is(err, database.ErrDatabaseUnavailable):
+		// Retriable: the connection monitor reconnects once the database is back
+		w.Header().Set("Retry-After", "5")
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Database unavailable, retry later")
+	default:
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, message)
+	}
+}
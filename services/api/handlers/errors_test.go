@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chainpulse/shared/database"
+	"chainpulse/shared/json"
+)
+
+func decodeErrorResponse(t *testing.T, rr *httptest.ResponseRecorder) ErrorResponse {
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected content type application/json, got %s", contentType)
+	}
+
+	var response ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected error envelope, got %s: %v", rr.Body.String(), err)
+	}
+	return response
+}
+
+func TestErrorEnvelopeNotFound(t *testing.T) {
+	server := NewServer(&backfillIndexerService{}, "test-secret", nil)
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "GET", "/api/v1/admin/backfill/unknown", nil, "admin"))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	response := decodeErrorResponse(t, rr)
+	if response.Error.Code != ErrCodeNotFound {
+		t.Errorf("Expected code %s, got %s", ErrCodeNotFound, response.Error.Code)
+	}
+	if response.Error.Message != "Backfill job not found" {
+		t.Errorf("Expected message %q, got %q", "Backfill job not found", response.Error.Message)
+	}
+}
+
+func TestErrorEnvelopeValidation(t *testing.T) {
+	server := NewServer(&MockIndexerService{}, "test-secret", nil)
+
+	req, err := http.NewRequest("GET", "/api/v1/tx/not-a-hash/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	response := decodeErrorResponse(t, rr)
+	if response.Error.Code != ErrCodeInvalidArgument {
+		t.Errorf("Expected code %s, got %s", ErrCodeInvalidArgument, response.Error.Code)
+	}
+	if response.Error.Message != "Invalid transaction hash" {
+		t.Errorf("Expected message %q, got %q", "Invalid transaction hash", response.Error.Message)
+	}
+}
+
+func TestErrorEnvelopeUnauthorized(t *testing.T) {
+	server := NewServer(&MockIndexerService{}, "test-secret", nil)
+
+	req, err := http.NewRequest("GET", "/api/v1/admin/backfill/unknown", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+
+	response := decodeErrorResponse(t, rr)
+	if response.Error.Code != ErrCodeUnauthorized {
+		t.Errorf("Expected code %s, got %s", ErrCodeUnauthorized, response.Error.Code)
+	}
+}
+
+func TestErrorEnvelopeDatabaseUnavailable(t *testing.T) {
+	rr := httptest.NewRecorder()
+	writeStoreError(rr, fmt.Errorf("get events: %w", database.ErrDatabaseUnavailable), "Failed to get events")
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+	if retryAfter := rr.Header().Get("Retry-After"); retryAfter == "" {
+		t.Error("Expected a Retry-After header")
+	}
+
+	response := decodeErrorResponse(t, rr)
+	if response.Error.Code != ErrCodeUnavailable {
+		t.Errorf("Expected code %s, got %s", ErrCodeUnavailable, response.Error.Code)
+	}
+}
@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
 
 	"chainpulse/shared/database"
+	"chainpulse/shared/json"
 
 	"github.com/gorilla/mux"
 )
@@ -46,7 +46,7 @@ func (h *EventHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
 
 	events, err := h.DB.GetEvents(limitNum, offset)
 	if err != nil {
-		http.Error(w, "Failed to get events", http.StatusInternalServerError)
+		writeStoreError(w, err, "Failed to get events")
 		return
 	}
 
@@ -66,12 +66,12 @@ func (h *EventHandler) GetEventByTxHash(w http.ResponseWriter, r *http.Request)
 
 	event, err := h.DB.GetEventByTxHash(txHash)
 	if err != nil {
-		http.Error(w, "Failed to get event", http.StatusInternalServerError)
+		writeStoreError(w, err, "Failed to get event")
 		return
 	}
 
 	if event == nil {
-		http.Error(w, "Event not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "Event not found")
 		return
 	}
 
@@ -86,16 +86,26 @@ func (h *EventHandler) GetEventsByBlockNumber(w http.ResponseWriter, r *http.Req
 
 	blockNumber, err := strconv.ParseInt(blockNumberStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid block number", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid block number")
 		return
 	}
 
 	events, err := h.DB.GetEventsByBlockNumber(blockNumber)
 	if err != nil {
-		http.Error(w, "Failed to get events", http.StatusInternalServerError)
+		writeStoreError(w, err, "Failed to get events")
 		return
 	}
 
+	// Optionally include events moved to the archive table by the retention policy
+	if r.URL.Query().Get("include_archived") == "true" {
+		archived, err := h.DB.GetArchivedEventsByBlockNumber(blockNumber)
+		if err != nil {
+			writeStoreError(w, err, "Failed to get archived events")
+			return
+		}
+		events = append(events, archived...)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"events":      events,
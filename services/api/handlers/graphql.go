@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// Page size limits of GraphQL list fields, matching the REST endpoints
+const (
+	defaultGraphQLLimit = 50
+	maxGraphQLLimit     = 100
+)
+
+// graphQLSchema is served at /graphql. Block numbers are strings since they may exceed 32 bits.
+const graphQLSchema = `
+schema {
+	query: Query
+}
+
+type Query {
+	events(filter: EventFilter, pagination: Pagination): [Event!]!
+	contract(address: String!): Contract
+	nftHistory(contract: String!, tokenId: String!, pagination: Pagination): [Event!]!
+	stats: Stats!
+}
+
+input EventFilter {
+	contract: String
+	eventName: String
+	fromBlock: String
+	toBlock: String
+}
+
+input Pagination {
+	limit: Int
+	offset: Int
+}
+
+type Event {
+	id: ID!
+	blockNumber: String
+	txHash: String!
+	logIndex: Int!
+	eventName: String!
+	contractAddress: String!
+	contract: Contract
+	from: String
+	to: String
+	tokenId: String
+	value: String
+	timestamp: String!
+}
+
+type Contract {
+	address: String!
+	name: String
+	symbol: String
+	type: String
+}
+
+type Stats {
+	totalEvents: Int!
+	totalContracts: Int!
+	latestBlock: String!
+}
+`
+
+// GraphQLStore is the data access used by the GraphQL resolvers
+type GraphQLStore interface {
+	GetEvents(filter *types.EventFilter) ([]types.IndexedEvent, error)
+	GetContractByAddress(address string) (*types.Contract, error)
+	GetStats() (*types.Stats, error)
+}
+
+// GraphQLHandler handles GraphQL queries over events, contracts and stats
+type GraphQLHandler struct {
+	schema *graphql.Schema
+}
+
+// NewGraphQLHandler creates a new GraphQL handler backed by store
+func NewGraphQLHandler(store GraphQLStore) *GraphQLHandler {
+	return &GraphQLHandler{
+		schema: graphql.MustParseSchema(graphQLSchema, &graphQLResolver{store: store}),
+	}
+}
+
+// graphQLRequest is the body of POST /graphql
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// ServeHTTP handles POST /graphql requests
+func (h *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid request body")
+		return
+	}
+
+	if req.Query == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "query is required")
+		return
+	}
+
+	// Query errors are reported in the response's errors field, as GraphQL clients expect
+	response := h.schema.Exec(r.Context(), req.Query, req.OperationName, req.Variables)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+type graphQLResolver struct {
+	store GraphQLStore
+}
+
+type eventFilterInput struct {
+	Contract  *string
+	EventName *string
+	FromBlock *string
+	ToBlock   *string
+}
+
+type paginationInput struct {
+	Limit  *int32
+	Offset *int32
+}
+
+// apply sets the page of filter, defaulting and capping the limit
+func (p *paginationInput) apply(filter *types.EventFilter) error {
+	filter.Limit = defaultGraphQLLimit
+	if p == nil {
+		return nil
+	}
+
+	if p.Limit != nil {
+		if *p.Limit <= 0 || *p.Limit > maxGraphQLLimit {
+			return fmt.Errorf("limit must be between 1 and %d", maxGraphQLLimit)
+		}
+		filter.Limit = int(*p.Limit)
+	}
+	if p.Offset != nil {
+		if *p.Offset < 0 {
+			return fmt.Errorf("offset must not be negative")
+		}
+		filter.Offset = int(*p.Offset)
+	}
+	return nil
+}
+
+// parseBlockNumber parses an optional decimal block number argument
+func parseBlockNumber(name string, value *string) (*big.Int, error) {
+	if value == nil {
+		return nil, nil
+	}
+	number, ok := new(big.Int).SetString(*value, 10)
+	if !ok || number.Sign() < 0 {
+		return nil, fmt.Errorf("invalid %s: %s", name, *value)
+	}
+	return number, nil
+}
+
+func (r *graphQLResolver) Events(args struct {
+	Filter     *eventFilterInput
+	Pagination *paginationInput
+}) ([]*eventResolver, error) {
+	filter := &types.EventFilter{}
+	if args.Filter != nil {
+		if args.Filter.Contract != nil {
+			filter.Contract = *args.Filter.Contract
+		}
+		if args.Filter.EventName != nil {
+			filter.EventType = *args.Filter.EventName
+		}
+
+		var err error
+		if filter.FromBlock, err = parseBlockNumber("fromBlock", args.Filter.FromBlock); err != nil {
+			return nil, err
+		}
+		if filter.ToBlock, err = parseBlockNumber("toBlock", args.Filter.ToBlock); err != nil {
+			return nil, err
+		}
+	}
+	if err := args.Pagination.apply(filter); err != nil {
+		return nil, err
+	}
+
+	return r.events(filter)
+}
+
+func (r *graphQLResolver) NftHistory(args struct {
+	Contract   string
+	TokenID    string
+	Pagination *paginationInput
+}) ([]*eventResolver, error) {
+	filter := &types.EventFilter{Contract: args.Contract, TokenID: args.TokenID}
+	if err := args.Pagination.apply(filter); err != nil {
+		return nil, err
+	}
+	return r.events(filter)
+}
+
+func (r *graphQLResolver) events(filter *types.EventFilter) ([]*eventResolver, error) {
+	events, err := r.store.GetEvents(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %v", err)
+	}
+
+	resolvers := make([]*eventResolver, len(events))
+	for i := range events {
+		resolvers[i] = &eventResolver{event: &events[i], store: r.store}
+	}
+	return resolvers, nil
+}
+
+func (r *graphQLResolver) Contract(args struct{ Address string }) (*contractResolver, error) {
+	return resolveContract(r.store, args.Address)
+}
+
+func (r *graphQLResolver) Stats() (*statsResolver, error) {
+	stats, err := r.store.GetStats()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %v", err)
+	}
+	return &statsResolver{stats: stats}, nil
+}
+
+// resolveContract looks up a contract, resolving to null if it is not known
+func resolveContract(store GraphQLStore, address string) (*contractResolver, error) {
+	contract, err := store.GetContractByAddress(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contract: %v", err)
+	}
+	if contract == nil {
+		return nil, nil
+	}
+	return &contractResolver{contract: contract}, nil
+}
+
+// optionalString maps an empty string to null
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+type eventResolver struct {
+	event *types.IndexedEvent
+	store GraphQLStore
+}
+
+func (e *eventResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatUint(uint64(e.event.ID), 10))
+}
+
+func (e *eventResolver) BlockNumber() *string {
+	if e.event.BlockNumber == nil {
+		return nil
+	}
+	number := e.event.BlockNumber.String()
+	return &number
+}
+
+func (e *eventResolver) TxHash() string          { return e.event.TxHash }
+func (e *eventResolver) LogIndex() int32         { return int32(e.event.LogIndex) }
+func (e *eventResolver) EventName() string       { return e.event.EventName }
+func (e *eventResolver) ContractAddress() string { return e.event.Contract }
+func (e *eventResolver) From() *string           { return optionalString(e.event.From) }
+func (e *eventResolver) To() *string             { return optionalString(e.event.To) }
+func (e *eventResolver) TokenID() *string        { return optionalString(e.event.TokenID) }
+func (e *eventResolver) Value() *string          { return optionalString(e.event.Value) }
+
+func (e *eventResolver) Timestamp() string {
+	return e.event.Timestamp.Format(time.RFC3339)
+}
+
+// Contract is only looked up when a query selects it
+func (e *eventResolver) Contract() (*contractResolver, error) {
+	return resolveContract(e.store, e.event.Contract)
+}
+
+type contractResolver struct {
+	contract *types.Contract
+}
+
+func (c *contractResolver) Address() string { return c.contract.Address }
+func (c *contractResolver) Name() *string   { return optionalString(c.contract.Name) }
+func (c *contractResolver) Symbol() *string { return optionalString(c.contract.Symbol) }
+func (c *contractResolver) Type() *string   { return optionalString(string(c.contract.Type)) }
+
+type statsResolver struct {
+	stats *types.Stats
+}
+
+func (s *statsResolver) TotalEvents() int32    { return int32(s.stats.TotalEvents) }
+func (s *statsResolver) TotalContracts() int32 { return int32(s.stats.TotalContracts) }
+
+func (s *statsResolver) LatestBlock() string {
+	return strconv.FormatInt(s.stats.LatestBlock, 10)
+}
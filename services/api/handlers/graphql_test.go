@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bytes"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
+)
+
+// graphQLTestStore serves fixed events and contracts and records the lookups made
+type graphQLTestStore struct {
+	events          []types.IndexedEvent
+	contracts       map[string]*types.Contract
+	filters         []types.EventFilter
+	contractLookups int
+}
+
+func (s *graphQLTestStore) GetEvents(filter *types.EventFilter) ([]types.IndexedEvent, error) {
+	s.filters = append(s.filters, *filter)
+
+	var events []types.IndexedEvent
+	for _, event := range s.events {
+		if filter.Contract != "" && event.Contract != filter.Contract {
+			continue
+		}
+		if filter.TokenID != "" && event.TokenID != filter.TokenID {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (s *graphQLTestStore) GetContractByAddress(address string) (*types.Contract, error) {
+	s.contractLookups++
+	return s.contracts[address], nil
+}
+
+func (s *graphQLTestStore) GetStats() (*types.Stats, error) {
+	return &types.Stats{TotalEvents: int64(len(s.events)), TotalContracts: int64(len(s.contracts)), LatestBlock: 101}, nil
+}
+
+func newGraphQLTestStore() *graphQLTestStore {
+	contract := "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D"
+	return &graphQLTestStore{
+		events: []types.IndexedEvent{
+			{ID: 1, BlockNumber: big.NewInt(100), TxHash: "0x1", EventName: "Transfer", Contract: contract, From: "0xa", To: "0xb", TokenID: "7"},
+			{ID: 2, BlockNumber: big.NewInt(101), TxHash: "0x2", EventName: "Transfer", Contract: contract, From: "0xb", To: "0xc", TokenID: "8"},
+		},
+		contracts: map[string]*types.Contract{
+			contract: {Address: contract, Name: "BoredApeYachtClub", Symbol: "BAYC", Type: "ERC721"},
+		},
+	}
+}
+
+// execGraphQL posts query to the handler and decodes the response into data
+func execGraphQL(t *testing.T, handler http.Handler, query string, variables map[string]interface{}, data interface{}) []map[string]interface{} {
+	body, _ := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	req, err := http.NewRequest("POST", "/graphql", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Data   json.RawMessage          `json:"data"`
+		Errors []map[string]interface{} `json:"errors"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected valid JSON response, got error: %v", err)
+	}
+	if data != nil && len(response.Data) > 0 {
+		if err := json.Unmarshal(response.Data, data); err != nil {
+			t.Fatalf("Failed to decode data %s: %v", response.Data, err)
+		}
+	}
+	return response.Errors
+}
+
+func TestGraphQLEventsFieldSelection(t *testing.T) {
+	store := newGraphQLTestStore()
+	handler := NewGraphQLHandler(store)
+
+	var data struct {
+		Events []map[string]interface{} `json:"events"`
+	}
+	errs := execGraphQL(t, handler, `{ events(filter: {fromBlock: "100"}, pagination: {limit: 10, offset: 5}) { txHash blockNumber } }`, nil, &data)
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+
+	if len(data.Events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(data.Events))
+	}
+	for _, event := range data.Events {
+		if len(event) != 2 {
+			t.Errorf("Expected only the selected fields, got %v", event)
+		}
+	}
+	if data.Events[0]["txHash"] != "0x1" || data.Events[0]["blockNumber"] != "100" {
+		t.Errorf("Unexpected event %v", data.Events[0])
+	}
+
+	// Unselected nested fields are never resolved
+	if store.contractLookups != 0 {
+		t.Errorf("Expected no contract lookups, got %d", store.contractLookups)
+	}
+
+	filter := store.filters[0]
+	if filter.FromBlock == nil || filter.FromBlock.Int64() != 100 || filter.Limit != 10 || filter.Offset != 5 {
+		t.Errorf("Unexpected filter %+v", filter)
+	}
+}
+
+func TestGraphQLNestedContract(t *testing.T) {
+	store := newGraphQLTestStore()
+	handler := NewGraphQLHandler(store)
+
+	var data struct {
+		NftHistory []struct {
+			TokenID  string `json:"tokenId"`
+			Contract struct {
+				Symbol string `json:"symbol"`
+				Type   string `json:"type"`
+			} `json:"contract"`
+		} `json:"nftHistory"`
+		Stats struct {
+			TotalEvents int    `json:"totalEvents"`
+			LatestBlock string `json:"latestBlock"`
+		} `json:"stats"`
+	}
+	query := `query History($contract: String!, $tokenId: String!) {
+		nftHistory(contract: $contract, tokenId: $tokenId) { tokenId contract { symbol type } }
+		stats { totalEvents latestBlock }
+	}`
+	variables := map[string]interface{}{"contract": "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D", "tokenId": "8"}
+	errs := execGraphQL(t, handler, query, variables, &data)
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+
+	if len(data.NftHistory) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(data.NftHistory))
+	}
+	event := data.NftHistory[0]
+	if event.TokenID != "8" || event.Contract.Symbol != "BAYC" || event.Contract.Type != "ERC721" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	if store.contractLookups != 1 {
+		t.Errorf("Expected 1 contract lookup, got %d", store.contractLookups)
+	}
+	if store.filters[0].Limit != defaultGraphQLLimit {
+		t.Errorf("Expected default limit %d, got %d", defaultGraphQLLimit, store.filters[0].Limit)
+	}
+
+	if data.Stats.TotalEvents != 2 || data.Stats.LatestBlock != "101" {
+		t.Errorf("Unexpected stats %+v", data.Stats)
+	}
+}
+
+func TestGraphQLInvalidArguments(t *testing.T) {
+	handler := NewGraphQLHandler(newGraphQLTestStore())
+
+	errs := execGraphQL(t, handler, `{ events(pagination: {limit: 1000}) { txHash } }`, nil, nil)
+	if len(errs) != 1 || !strings.Contains(errs[0]["message"].(string), "limit must be between") {
+		t.Errorf("Expected a limit error, got %v", errs)
+	}
+
+	errs = execGraphQL(t, handler, `{ events { unknownField } }`, nil, nil)
+	if len(errs) == 0 {
+		t.Error("Expected an error for an unknown field")
+	}
+}
@@ -7,7 +7,10 @@ import (
 	"math/big"
 	"net"
 
+	pb "chainpulse/services/api/grpc"
 	"chainpulse/services/api/handlers/auth"
+	"chainpulse/shared/grpclogging"
+	"chainpulse/shared/requestid"
 	"chainpulse/shared/service"
 
 	"google.golang.org/grpc"
@@ -291,8 +294,11 @@ func (s *EventServiceServer) ReplayEvents(ctx context.Context, req *ReplayEvents
 	}, nil
 }
 
-// StartGRPCServer starts the gRPC server
-func StartGRPCServer(indexerService *service.IndexerService, port string, jwtSecret string) error {
+// StartGRPCServer starts the gRPC server. Calls are logged with the values of the
+// redactMetadata keys redacted; nil redacts grpclogging.DefaultRedactedKeys.
+// indexerServer, when set, is served alongside for StreamEvents and the other
+// IndexerService calls.
+func StartGRPCServer(indexerService *service.IndexerService, indexerServer *pb.Server, port string, jwtSecret string, redactMetadata []string) error {
 	lis, err := net.Listen("tcp", ":"+port)
 	if err != nil {
 		return fmt.Errorf("failed to listen: %v", err)
@@ -302,10 +308,14 @@ func StartGRPCServer(indexerService *service.IndexerService, port string, jwtSec
 	authMiddleware := auth.NewAuthMiddleware(jwtSecret)
 	unaryInterceptor, streamInterceptor := authMiddleware.GetGRPCAuthInterceptors()
 
-	// Create gRPC server with interceptors
+	// Log every call, including those rejected by authentication
+	callLogger := grpclogging.NewInterceptor(indexerService.Logger, redactMetadata)
+
+	// Create gRPC server with interceptors; the request ID is assigned first so
+	// authentication failures can be correlated too
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(unaryInterceptor),
-		grpc.StreamInterceptor(streamInterceptor),
+		grpc.ChainUnaryInterceptor(requestid.UnaryServerInterceptor, callLogger.Unary, unaryInterceptor),
+		grpc.ChainStreamInterceptor(requestid.StreamServerInterceptor, callLogger.Stream, streamInterceptor),
 	)
 	eventServiceServer := &EventServiceServer{
 		IndexerService: indexerService,
@@ -313,6 +323,9 @@ func StartGRPCServer(indexerService *service.IndexerService, port string, jwtSec
 		Metrics:        indexerService.Metrics,
 	}
 	RegisterEventServiceServer(grpcServer, eventServiceServer)
+	if indexerServer != nil {
+		pb.RegisterIndexerServiceServer(grpcServer, indexerServer)
+	}
 	
 	// Register reflection service for debugging tools
 	reflection.Register(grpcServer)
@@ -2,18 +2,25 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"math/big"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"chainpulse/services/api/handlers/auth"
+	"chainpulse/shared/database"
 	"chainpulse/shared/datapuller"
+	"chainpulse/shared/json"
 	"chainpulse/shared/logger"
+	"chainpulse/shared/requestid"
 	"chainpulse/shared/types"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // IndexerService interface defines the methods that the indexer service should implement
@@ -22,9 +29,16 @@ type IndexerService interface {
 	ProcessHistoricalEvents(ctx context.Context, contractAddresses []common.Address, fromBlock, toBlock *big.Int) error
 	GetEvents(filter *types.EventFilter) ([]types.IndexedEvent, error)
 	GetEventByID(id uint) (*types.IndexedEvent, error)
+	GetEventsByTxHash(txHash string) ([]types.IndexedEvent, error)
+	GetAddressActivity(address string, limitNum, offset int) ([]types.AddressActivity, error)
+	GetBalances(address, contract string) ([]types.TokenBalance, error)
+	GetLatestEvents(limitNum int) ([]types.IndexedEvent, error)
+	GetEventsAfterID(afterID uint, limitNum int) ([]types.IndexedEvent, error)
 	GetEventsByBlockRange(fromBlock, toBlock *big.Int) ([]types.IndexedEvent, error)
 	GetLastProcessedBlock() (*big.Int, error)
 	ResumeEvents(ctx context.Context, fromBlock, toBlock *big.Int) error
+	ReplayEvents(ctx context.Context, contract string, fromBlock, toBlock *big.Int, transform string) (*types.ReplayResult, error)
+	VerifyEvents(ctx context.Context, contract string, fromBlock, toBlock *big.Int, repair bool) (*types.ConsistencyReport, error)
 }
 
 // Server represents the API server
@@ -34,6 +48,10 @@ type Server struct {
 	jwtSecret      string
 	logger         logger.Logger
 	metricsCollector *datapuller.MetricsCollector
+	backfills      *BackfillManager
+	batchProcessor *database.BatchProcessor
+	eventImporter  EventImporter
+	diagnostics    DiagnosticsSource
 }
 
 // NewServer creates a new API server instance
@@ -45,6 +63,7 @@ func NewServer(indexerService IndexerService, jwtSecret string, metricsCollector
 		logger:         logger.NewLogger(),
 		metricsCollector: metricsCollector,
 	}
+	s.backfills = NewBackfillManager(indexerService, s.logger)
 
 	// Register routes
 	s.registerRoutes()
@@ -54,10 +73,48 @@ func NewServer(indexerService IndexerService, jwtSecret string, metricsCollector
 
 // registerRoutes registers all API routes
 func (s *Server) registerRoutes() {
+	s.router.Use(requestid.Middleware, s.logRequests)
+
 	s.router.HandleFunc("/events", s.GetEventsHandler).Methods("GET")
 	s.router.HandleFunc("/events/{id}", s.GetEventByIDHandler).Methods("GET")
+	s.router.HandleFunc("/api/v1/events/latest", s.GetLatestEventsHandler).Methods("GET")
+	s.router.HandleFunc("/api/v1/tx/{hash}/events", s.GetEventsByTxHashHandler).Methods("GET")
+	s.router.HandleFunc("/api/v1/address/{addr}/activity", s.GetAddressActivityHandler).Methods("GET")
+	s.router.HandleFunc("/api/v1/balances/{address}", s.GetBalancesHandler).Methods("GET")
 	s.router.HandleFunc("/health", s.HealthHandler).Methods("GET")
 	s.router.HandleFunc("/metrics", s.MetricsHandler).Methods("GET")
+	s.router.Handle("/metrics/prometheus", promhttp.Handler()).Methods("GET")
+
+	// Admin-only operations
+	authMiddleware := auth.NewAuthMiddleware(s.jwtSecret)
+	admin := s.router.PathPrefix("/api/v1/admin").Subrouter()
+	admin.Use(authMiddleware.Middleware, authMiddleware.RequireRole("admin"))
+	admin.HandleFunc("/backfill", s.BackfillHandler).Methods("POST")
+	admin.HandleFunc("/backfill/{id}", s.GetBackfillJobHandler).Methods("GET")
+	admin.HandleFunc("/replay", s.ReplayHandler).Methods("POST")
+	admin.HandleFunc("/verify", s.VerifyHandler).Methods("POST")
+	admin.HandleFunc("/puller/metrics", s.PullerMetricsHandler).Methods("GET")
+	admin.HandleFunc("/puller/metrics/reset", s.ResetPullerMetricsHandler).Methods("POST")
+	admin.HandleFunc("/diagnostics", s.DiagnosticsHandler).Methods("GET")
+
+	requireAdmin := authMiddleware.RequireRole("admin")
+	s.router.Handle("/api/v1/events/bulk", authMiddleware.Middleware(requireAdmin(http.HandlerFunc(s.BulkImportEventsHandler)))).Methods("POST")
+}
+
+// logRequests logs each request with its request ID once it completes
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		next.ServeHTTP(w, r)
+		s.logger.WithTrace(r.Context()).Info("%s %s completed in %v", r.Method, r.URL.Path, time.Since(startTime))
+	})
+}
+
+// SetBatchProcessor sets the batch processor whose buffer and flush stats are reported by
+// /metrics and which stores bulk imported events
+func (s *Server) SetBatchProcessor(bp *database.BatchProcessor) {
+	s.batchProcessor = bp
+	s.eventImporter = bp
 }
 
 // GetRouter returns the router instance
@@ -65,7 +122,8 @@ func (s *Server) GetRouter() *mux.Router {
 	return s.router
 }
 
-// GetEventsHandler handles GET /events requests
+// GetEventsHandler handles GET /events requests. The fields query parameter, a
+// comma-separated list of event fields, returns only those fields of each event.
 func (s *Server) GetEventsHandler(w http.ResponseWriter, r *http.Request) {
 	var filter types.EventFilter
 
@@ -94,6 +152,40 @@ func (s *Server) GetEventsHandler(w http.ResponseWriter, r *http.Request) {
 		filter.Contract = contract
 	}
 
+	minValue, err := parseValueParam(r, "minValue", "min_value")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, err.Error())
+		return
+	}
+	maxValue, err := parseValueParam(r, "maxValue", "max_value")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, err.Error())
+		return
+	}
+	if minValue != nil && maxValue != nil && minValue.Cmp(maxValue) > 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "minValue must not exceed maxValue")
+		return
+	}
+	filter.MinValue = minValue
+	filter.MaxValue = maxValue
+
+	fromTime, err := parseTimeParam(r, "fromTime", "from_time")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, err.Error())
+		return
+	}
+	toTime, err := parseTimeParam(r, "toTime", "to_time")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, err.Error())
+		return
+	}
+	if fromTime != nil && toTime != nil && fromTime.After(*toTime) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "fromTime must not be after toTime")
+		return
+	}
+	filter.FromTime = fromTime
+	filter.ToTime = toTime
+
 	pageStr := r.URL.Query().Get("page")
 	if pageStr != "" {
 		if page, err := strconv.Atoi(pageStr); err == nil {
@@ -108,14 +200,76 @@ func (s *Server) GetEventsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if fields := r.URL.Query().Get("fields"); fields != "" {
+		filter.Fields, err = types.ParseEventFields(fields)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, fmt.Sprintf("Invalid fields: %v", err))
+			return
+		}
+	}
+
 	events, err := s.indexerService.GetEvents(&filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.logger.WithTrace(r.Context()).Error("Failed to get events: %v", err)
+		writeStoreError(w, err, "Failed to get events")
 		return
 	}
 
+	if len(filter.Fields) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(events)
+		return
+	}
+
+	// Only the selected fields were loaded, so the others are dropped from the response
+	projected, err := types.ProjectEvents(events, filter.Fields)
+	if err != nil {
+		s.logger.WithTrace(r.Context()).Error("Failed to encode events: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to encode events")
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(events)
+	json.NewEncoder(w).Encode(projected)
+}
+
+// queryParam returns the value of the first of names given in the query of r, and the name
+// it was given by. Later names are accepted as aliases of the first.
+func queryParam(r *http.Request, names ...string) (string, string) {
+	query := r.URL.Query()
+	for _, name := range names {
+		if value := query.Get(name); value != "" {
+			return value, name
+		}
+	}
+	return "", names[0]
+}
+
+// parseValueParam parses an optional transfer value query parameter, given by the first of
+// names or an alias. Values exceed int64, so they are parsed as big integers.
+func parseValueParam(r *http.Request, names ...string) (*big.Int, error) {
+	valueStr, name := queryParam(r, names...)
+	if valueStr == "" {
+		return nil, nil
+	}
+	value, ok := new(big.Int).SetString(valueStr, 10)
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("Invalid %s", name)
+	}
+	return value, nil
+}
+
+// parseTimeParam parses an optional RFC 3339 timestamp query parameter, given by the
+// first of names or an alias
+func parseTimeParam(r *http.Request, names ...string) (*time.Time, error) {
+	timeStr, name := queryParam(r, names...)
+	if timeStr == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, timeStr)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s, expected an RFC 3339 timestamp", name)
+	}
+	return &t, nil
 }
 
 // GetEventByIDHandler handles GET /events/{id} requests
@@ -125,18 +279,19 @@ func (s *Server) GetEventByIDHandler(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid event ID")
 		return
 	}
 
 	event, err := s.indexerService.GetEventByID(uint(id))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.logger.WithTrace(r.Context()).Error("Failed to get event %d: %v", id, err)
+		writeStoreError(w, err, "Failed to get event")
 		return
 	}
 
 	if event == nil {
-		http.Error(w, "Event not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "Event not found")
 		return
 	}
 
@@ -144,6 +299,77 @@ func (s *Server) GetEventByIDHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(event)
 }
 
+// GetEventsByTxHashHandler handles GET /api/v1/tx/{hash}/events requests
+func (s *Server) GetEventsByTxHashHandler(w http.ResponseWriter, r *http.Request) {
+	// Stored hashes are lowercase hex
+	txHash := strings.ToLower(mux.Vars(r)["hash"])
+	if len(txHash) != 66 || !strings.HasPrefix(txHash, "0x") {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid transaction hash")
+		return
+	}
+
+	events, err := s.indexerService.GetEventsByTxHash(txHash)
+	if err != nil {
+		s.logger.WithTrace(r.Context()).Error("Failed to get events for tx %s: %v", txHash, err)
+		writeStoreError(w, err, "Failed to get events")
+		return
+	}
+
+	// Order by log index even if the backing store does not
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LogIndex < events[j].LogIndex
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tx_hash": txHash,
+		"events":  events,
+		"total":   len(events),
+	})
+}
+
+// GetAddressActivityHandler handles GET /api/v1/address/{addr}/activity requests, listing
+// the events sent or received by an address, newest first, with their direction
+func (s *Server) GetAddressActivityHandler(w http.ResponseWriter, r *http.Request) {
+	addr := mux.Vars(r)["addr"]
+	if !common.IsHexAddress(addr) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid address")
+		return
+	}
+	// Stored addresses are checksummed
+	address := common.HexToAddress(addr).Hex()
+
+	pageNum := 1
+	if page := r.URL.Query().Get("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil && p > 0 {
+			pageNum = p
+		}
+	}
+
+	limitNum := 50
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil && l > 0 && l <= 100 {
+			limitNum = l
+		}
+	}
+
+	activity, err := s.indexerService.GetAddressActivity(address, limitNum, (pageNum-1)*limitNum)
+	if err != nil {
+		s.logger.WithTrace(r.Context()).Error("Failed to get activity of %s: %v", address, err)
+		writeStoreError(w, err, "Failed to get address activity")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"address": address,
+		"events":  activity,
+		"page":    pageNum,
+		"limit":   limitNum,
+		"total":   len(activity),
+	})
+}
+
 // HealthHandler handles GET /health requests
 func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -154,7 +380,7 @@ func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
 // MetricsHandler handles GET /metrics requests
 func (s *Server) MetricsHandler(w http.ResponseWriter, r *http.Request) {
 	if s.metricsCollector == nil {
-		http.Error(w, "Metrics collector not available", http.StatusInternalServerError)
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Metrics collector not available")
 		return
 	}
 
@@ -189,6 +415,16 @@ func (s *Server) MetricsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if s.batchProcessor != nil {
+		stats := s.batchProcessor.Stats()
+		response["batch_processor"] = map[string]interface{}{
+			"buffer_size":         stats.BufferSize,
+			"flushes":             stats.Flushes,
+			"flushed_events":      stats.FlushedEvents,
+			"last_flush_duration": stats.LastFlushDuration.String(),
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
\ No newline at end of file
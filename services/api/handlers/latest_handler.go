@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
+)
+
+// tailPollInterval is how often a live tail checks for newly indexed events
+const tailPollInterval = time.Second
+
+// tailBatchSize is the most events a live tail reads per query
+const tailBatchSize = 100
+
+// tailLookback is how many of the last events sent a live tail re-reads past on every
+// poll. Event IDs are assigned before the inserting transaction commits, so an event can
+// become visible after events with higher IDs; re-reading behind the newest event sent
+// catches it as long as it commits within the last tailLookback events.
+const tailLookback = 100
+
+// eventCursorSource returns the events indexed after a cursor, oldest first
+type eventCursorSource interface {
+	GetEventsAfterID(afterID uint, limitNum int) ([]types.IndexedEvent, error)
+}
+
+// eventTail sends a backlog of events and then every event indexed after it. The event
+// ID is the cursor handed from the backlog to the live phase. Each poll reads again
+// from behind the last lookback events sent and skips those already sent, so no event
+// at the boundary is sent twice, and one committed late with a lower ID is still sent.
+type eventTail struct {
+	source   eventCursorSource
+	interval time.Duration
+	batch    int
+	lookback int
+
+	recent []uint            // the IDs of the last lookback events sent, ascending
+	sent   map[uint]struct{} // the IDs in recent
+}
+
+// Run sends the backlog, given newest first, oldest first, then polls for new events
+// until ctx is done or send fails. Events at or below cursor are not sent, so a client
+// resuming after the last ID it received gets only the events it missed.
+func (t *eventTail) Run(ctx context.Context, cursor uint, backlog []types.IndexedEvent, send func(types.IndexedEvent) error) error {
+	t.recent = nil
+	t.sent = make(map[uint]struct{})
+
+	for i := len(backlog) - 1; i >= 0; i-- {
+		if backlog[i].ID <= cursor {
+			continue
+		}
+		if err := t.send(backlog[i], send); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		// Drain everything indexed since the last poll before waiting again
+		after := cursor
+		if len(t.recent) > 0 {
+			after = t.recent[0]
+		}
+		for {
+			events, err := t.source.GetEventsAfterID(after, t.batch)
+			if err != nil {
+				return err
+			}
+			for _, event := range events {
+				after = event.ID
+				if _, ok := t.sent[event.ID]; ok {
+					continue
+				}
+				if err := t.send(event, send); err != nil {
+					return err
+				}
+			}
+			if len(events) < t.batch {
+				break
+			}
+		}
+	}
+}
+
+// send sends event and remembers it among the last lookback events sent
+func (t *eventTail) send(event types.IndexedEvent, send func(types.IndexedEvent) error) error {
+	if err := send(event); err != nil {
+		return err
+	}
+
+	t.sent[event.ID] = struct{}{}
+	t.recent = append(t.recent, event.ID)
+	sort.Slice(t.recent, func(i, j int) bool { return t.recent[i] < t.recent[j] })
+	lookback := t.lookback
+	if lookback < 1 {
+		lookback = 1
+	}
+	if len(t.recent) > lookback {
+		for _, id := range t.recent[:len(t.recent)-lookback] {
+			delete(t.sent, id)
+		}
+		t.recent = append(t.recent[:0], t.recent[len(t.recent)-lookback:]...)
+	}
+	return nil
+}
+
+// GetLatestEventsHandler handles GET /api/v1/events/latest requests, returning the newest
+// events, newest first. With tail=true the response is a server-sent event stream that
+// sends those events oldest first and then every event indexed after them. Each message
+// carries the highest event ID sent so far, so a reconnecting client sending
+// Last-Event-ID resumes after it instead of receiving the backlog again.
+func (s *Server) GetLatestEventsHandler(w http.ResponseWriter, r *http.Request) {
+	limitNum := 50
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil && l > 0 && l <= 100 {
+			limitNum = l
+		}
+	}
+
+	if r.URL.Query().Get("tail") != "true" {
+		events, err := s.indexerService.GetLatestEvents(limitNum)
+		if err != nil {
+			s.logger.WithTrace(r.Context()).Error("Failed to get latest events: %v", err)
+			writeStoreError(w, err, "Failed to get latest events")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"events": events,
+			"limit":  limitNum,
+			"total":  len(events),
+		})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "Streaming not supported")
+		return
+	}
+
+	var cursor uint
+	var backlog []types.IndexedEvent
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		id, err := strconv.ParseUint(lastID, 10, 32)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid Last-Event-ID")
+			return
+		}
+		cursor = uint(id)
+	} else {
+		var err error
+		backlog, err = s.indexerService.GetLatestEvents(limitNum)
+		if err != nil {
+			s.logger.WithTrace(r.Context()).Error("Failed to get latest events: %v", err)
+			writeStoreError(w, err, "Failed to get latest events")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// The message ID is the highest event ID sent so far rather than the event's own:
+	// an event committed late is sent after events with higher IDs, and resuming after
+	// its ID would send those again
+	highestID := cursor
+	tail := &eventTail{source: s.indexerService, interval: tailPollInterval, batch: tailBatchSize, lookback: tailLookback}
+	err := tail.Run(r.Context(), cursor, backlog, func(event types.IndexedEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if event.ID > highestID {
+			highestID = event.ID
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: event\ndata: %s\n\n", highestID, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	if err != nil && r.Context().Err() == nil {
+		s.logger.WithTrace(r.Context()).Error("Live tail of events ended: %v", err)
+	}
+}
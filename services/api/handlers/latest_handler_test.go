@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
+)
+
+// growingEventSource is an event store that events can be appended to while it is read
+type growingEventSource struct {
+	mu     sync.Mutex
+	events []types.IndexedEvent
+}
+
+func (g *growingEventSource) append(id uint) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.events = append(g.events, types.IndexedEvent{ID: id, BlockNumber: big.NewInt(int64(id)), EventName: "Transfer"})
+}
+
+func (g *growingEventSource) latest(limitNum int) []types.IndexedEvent {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var events []types.IndexedEvent
+	for i := len(g.events) - 1; i >= 0 && len(events) < limitNum; i-- {
+		events = append(events, g.events[i])
+	}
+	return events
+}
+
+func (g *growingEventSource) GetEventsAfterID(afterID uint, limitNum int) ([]types.IndexedEvent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var events []types.IndexedEvent
+	for _, event := range g.events {
+		if event.ID > afterID {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	if len(events) > limitNum {
+		events = events[:limitNum]
+	}
+	return events, nil
+}
+
+func TestEventTail_HandoffDeliversEachEventOnce(t *testing.T) {
+	source := &growingEventSource{}
+	for id := uint(1); id <= 10; id++ {
+		source.append(id)
+	}
+	backlog := source.latest(4)
+
+	// Indexed between the backlog query and the live phase
+	source.append(11)
+	source.append(12)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Keep indexing while the tail runs, more per poll than one batch holds
+	go func() {
+		for id := uint(13); id <= 60; id++ {
+			source.append(id)
+			if id%20 == 0 {
+				time.Sleep(2 * time.Millisecond)
+			}
+		}
+	}()
+
+	var received []uint
+	tail := &eventTail{source: source, interval: time.Millisecond, batch: 8}
+	err := tail.Run(ctx, 0, backlog, func(event types.IndexedEvent) error {
+		received = append(received, event.ID)
+		if event.ID == 60 {
+			cancel()
+		}
+		return nil
+	})
+	if err != context.Canceled {
+		t.Fatalf("Expected the tail to stop with context.Canceled, got %v", err)
+	}
+
+	// The backlog holds 7 to 10; everything after follows exactly once, in order
+	if len(received) != 54 {
+		t.Fatalf("Expected 54 events, got %d: %v", len(received), received)
+	}
+	for i, id := range received {
+		if id != uint(i+7) {
+			t.Fatalf("Expected event %d at position %d, got %v", i+7, i, received)
+		}
+	}
+}
+
+func TestEventTail_SendsEventCommittedLate(t *testing.T) {
+	source := &growingEventSource{}
+	for id := uint(1); id <= 4; id++ {
+		source.append(id)
+	}
+	backlog := source.latest(4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var received []uint
+	tail := &eventTail{source: source, interval: time.Millisecond, batch: 2, lookback: 10}
+	go func() {
+		// Event 5 commits after events 6 and 7, which took their IDs later
+		source.append(6)
+		source.append(7)
+		time.Sleep(20 * time.Millisecond)
+		source.append(5)
+		time.Sleep(20 * time.Millisecond)
+		source.append(8)
+	}()
+	err := tail.Run(ctx, 0, backlog, func(event types.IndexedEvent) error {
+		received = append(received, event.ID)
+		if event.ID == 8 {
+			cancel()
+		}
+		return nil
+	})
+	if err != context.Canceled {
+		t.Fatalf("Expected the tail to stop with context.Canceled, got %v", err)
+	}
+
+	// Every event is sent exactly once, the late one as soon as it is visible
+	expected := []uint{1, 2, 3, 4, 6, 7, 5, 8}
+	if len(received) != len(expected) {
+		t.Fatalf("Expected events %v, got %v", expected, received)
+	}
+	for i := range expected {
+		if received[i] != expected[i] {
+			t.Fatalf("Expected events %v, got %v", expected, received)
+		}
+	}
+}
+
+func TestGetLatestEventsHandler(t *testing.T) {
+	mockIndexerService := &MockIndexerService{}
+	for id := uint(1); id <= 5; id++ {
+		mockIndexerService.events = append(mockIndexerService.events, types.IndexedEvent{ID: id, BlockNumber: big.NewInt(int64(100 + id)), EventName: "Transfer"})
+	}
+	server := NewServer(mockIndexerService, "test-secret", nil)
+
+	req, err := http.NewRequest("GET", "/api/v1/events/latest?limit=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response struct {
+		Events []types.IndexedEvent `json:"events"`
+		Total  int                  `json:"total"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected valid JSON response, got error: %v", err)
+	}
+	if response.Total != 2 || len(response.Events) != 2 || response.Events[0].ID != 5 || response.Events[1].ID != 4 {
+		t.Errorf("Expected events 5 and 4, got %v", response.Events)
+	}
+}
+
+func TestGetLatestEventsHandler_TailResumesAfterLastEventID(t *testing.T) {
+	mockIndexerService := &MockIndexerService{}
+	for id := uint(1); id <= 5; id++ {
+		mockIndexerService.events = append(mockIndexerService.events, types.IndexedEvent{ID: id, BlockNumber: big.NewInt(int64(100 + id)), EventName: "Transfer"})
+	}
+	server := httptest.NewServer(NewServer(mockIndexerService, "test-secret", nil).GetRouter())
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/v1/events/latest?limit=2&tail=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Last-Event-ID", "3")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %s", contentType)
+	}
+
+	// Only the events after the last one received are sent
+	var ids []string
+	scanner := bufio.NewScanner(resp.Body)
+	for len(ids) < 2 && scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimPrefix(line, "id: "))
+		}
+	}
+	if strings.Join(ids, ",") != "4,5" {
+		t.Errorf("Expected events 4 and 5, got %v", ids)
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"chainpulse/shared/datapuller"
+	"chainpulse/shared/json"
+)
+
+// PullerGlobalMetrics are the data puller metrics across all plugins
+type PullerGlobalMetrics struct {
+	TotalRequests   int64         `json:"total_requests"`
+	TotalErrors     int64         `json:"total_errors"`
+	TotalSuccess    int64         `json:"total_success"`
+	AvgResponseTime time.Duration `json:"avg_response_time_ns"`
+}
+
+// PullerMetricsResponse is the response of the data puller metrics admin endpoints
+type PullerMetricsResponse struct {
+	Global  PullerGlobalMetrics                 `json:"global"`
+	Plugins map[string]datapuller.PluginMetrics `json:"plugins"` // by plugin name
+}
+
+// pullerMetrics returns a snapshot of the data puller metrics
+func (s *Server) pullerMetrics() PullerMetricsResponse {
+	totalRequests, totalErrors, totalSuccess, avgResponseTime := s.metricsCollector.GetGlobalMetrics()
+	return PullerMetricsResponse{
+		Global: PullerGlobalMetrics{
+			TotalRequests:   totalRequests,
+			TotalErrors:     totalErrors,
+			TotalSuccess:    totalSuccess,
+			AvgResponseTime: avgResponseTime,
+		},
+		Plugins: s.metricsCollector.SnapshotMetrics(),
+	}
+}
+
+// PullerMetricsHandler handles GET /api/v1/admin/puller/metrics requests, returning the
+// full metrics of every data puller plugin including its last error
+func (s *Server) PullerMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if s.metricsCollector == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Metrics collector not available")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.pullerMetrics())
+}
+
+// ResetPullerMetricsHandler handles POST /api/v1/admin/puller/metrics/reset requests,
+// zeroing the request counters and response times of the data puller and its plugins.
+// It returns the metrics after the reset.
+func (s *Server) ResetPullerMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if s.metricsCollector == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Metrics collector not available")
+		return
+	}
+
+	s.metricsCollector.ResetMetrics()
+	s.logger.WithTrace(r.Context()).Info("Data puller metrics reset")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.pullerMetrics())
+}
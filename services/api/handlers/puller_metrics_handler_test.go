@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chainpulse/shared/datapuller"
+	"chainpulse/shared/json"
+)
+
+func getPullerMetrics(t *testing.T, server *Server, method, url string) PullerMetricsResponse {
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, method, url, nil, "admin"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response PullerMetricsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode metrics: %v", err)
+	}
+	return response
+}
+
+func TestPullerMetricsHandlers(t *testing.T) {
+	collector := datapuller.NewMetricsCollector()
+	collector.RecordRequest("https-jsonrpc", 20*time.Millisecond, nil)
+	collector.RecordRequest("https-jsonrpc", 40*time.Millisecond, errors.New("upstream timeout"))
+	collector.RecordRequest("websocket-jsonrpc", 10*time.Millisecond, nil)
+	server := NewServer(&MockIndexerService{}, "test-secret", collector)
+
+	metrics := getPullerMetrics(t, server, "GET", "/api/v1/admin/puller/metrics")
+	if metrics.Global.TotalRequests != 3 || metrics.Global.TotalErrors != 1 {
+		t.Errorf("Expected 3 requests and 1 error, got %+v", metrics.Global)
+	}
+	rpc, ok := metrics.Plugins["https-jsonrpc"]
+	if !ok || len(metrics.Plugins) != 2 {
+		t.Fatalf("Expected metrics of both plugins, got %v", metrics.Plugins)
+	}
+	if rpc.TotalRequests != 2 || rpc.TotalErrors != 1 || rpc.AvgResponseTime != 30*time.Millisecond {
+		t.Errorf("Expected 2 requests, 1 error and 30ms average, got %+v", rpc)
+	}
+	if rpc.LastError != "upstream timeout" || rpc.LastErrorTime.IsZero() || rpc.LastRequestTime.IsZero() {
+		t.Errorf("Expected the last error with its time, got %+v", rpc)
+	}
+
+	metrics = getPullerMetrics(t, server, "POST", "/api/v1/admin/puller/metrics/reset")
+	if metrics.Global.TotalRequests != 0 || metrics.Global.AvgResponseTime != 0 {
+		t.Errorf("Expected the global counters to be zeroed, got %+v", metrics.Global)
+	}
+	for name, plugin := range getPullerMetrics(t, server, "GET", "/api/v1/admin/puller/metrics").Plugins {
+		if plugin.TotalRequests != 0 || plugin.TotalErrors != 0 || plugin.TotalSuccess != 0 || plugin.AvgResponseTime != 0 {
+			t.Errorf("Expected the counters of %s to be zeroed, got %+v", name, plugin)
+		}
+	}
+}
+
+func TestPullerMetricsHandlers_RequireAdmin(t *testing.T) {
+	collector := datapuller.NewMetricsCollector()
+	collector.RecordRequest("https-jsonrpc", time.Millisecond, nil)
+	server := NewServer(&MockIndexerService{}, "test-secret", collector)
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "POST", "/api/v1/admin/puller/metrics/reset", nil, "viewer"))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, rr.Code)
+	}
+	if requests, _, _, _ := collector.GetGlobalMetrics(); requests != 1 {
+		t.Errorf("Expected the metrics to be kept, got %d requests", requests)
+	}
+}
+
+func TestPullerMetricsHandler_Unavailable(t *testing.T) {
+	server := NewServer(&MockIndexerService{}, "test-secret", nil)
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "GET", "/api/v1/admin/puller/metrics", nil, "admin"))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+}
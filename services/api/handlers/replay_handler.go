@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ReplayRequest is the body of POST /api/v1/admin/replay
+type ReplayRequest struct {
+	Contract  string `json:"contract"`
+	FromBlock uint64 `json:"fromBlock"`
+	ToBlock   uint64 `json:"toBlock"`
+	Transform string `json:"transform"`
+}
+
+// ReplayHandler handles POST /api/v1/admin/replay requests. It re-processes the stored
+// events of a contract within a block range through a registered transform and returns
+// how many events were read and updated.
+func (s *Server) ReplayHandler(w http.ResponseWriter, r *http.Request) {
+	var req ReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid request body")
+		return
+	}
+
+	if !common.IsHexAddress(req.Contract) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid contract address")
+		return
+	}
+
+	if req.ToBlock < req.FromBlock {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "toBlock must not be less than fromBlock")
+		return
+	}
+
+	if req.Transform == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "transform is required")
+		return
+	}
+
+	contract := common.HexToAddress(req.Contract).Hex()
+	result, err := s.indexerService.ReplayEvents(r.Context(), contract, new(big.Int).SetUint64(req.FromBlock), new(big.Int).SetUint64(req.ToBlock), req.Transform)
+	if errors.Is(err, types.ErrUnknownReplayTransform) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, err.Error())
+		return
+	}
+	if err != nil {
+		s.logger.WithTrace(r.Context()).Error("Failed to replay events of contract %s: %v", contract, err)
+		writeStoreError(w, err, "Failed to replay events")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
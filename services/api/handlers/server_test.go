@@ -6,11 +6,11 @@ import (
 	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
-	json "github.com/goccy/go-json"
-
+	"chainpulse/shared/json"
 	"chainpulse/shared/types"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -19,7 +19,8 @@ import (
 
 // MockIndexerService for testing
 type MockIndexerService struct {
-	events []types.IndexedEvent
+	events     []types.IndexedEvent
+	lastFilter *types.EventFilter
 }
 
 func (m *MockIndexerService) StartIndexing(ctx context.Context, contractAddresses []common.Address) error {
@@ -31,6 +32,7 @@ func (m *MockIndexerService) ProcessHistoricalEvents(ctx context.Context, contra
 }
 
 func (m *MockIndexerService) GetEvents(filter *types.EventFilter) ([]types.IndexedEvent, error) {
+	m.lastFilter = filter
 	return m.events, nil
 }
 
@@ -41,6 +43,54 @@ func (m *MockIndexerService) GetEventByID(id uint) (*types.IndexedEvent, error)
 	return nil, nil
 }
 
+func (m *MockIndexerService) GetEventsByTxHash(txHash string) ([]types.IndexedEvent, error) {
+	var events []types.IndexedEvent
+	for _, event := range m.events {
+		if event.TxHash == txHash {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (m *MockIndexerService) GetAddressActivity(address string, limitNum, offset int) ([]types.AddressActivity, error) {
+	var activity []types.AddressActivity
+	for i := range m.events {
+		if direction := types.EventDirection(&m.events[i], address); direction != "" {
+			activity = append(activity, types.AddressActivity{IndexedEvent: m.events[i], Direction: direction})
+		}
+	}
+	return activity, nil
+}
+
+func (m *MockIndexerService) GetBalances(address, contract string) ([]types.TokenBalance, error) {
+	var transfers []types.IndexedEvent
+	for _, event := range m.events {
+		if contract == "" || event.Contract == contract {
+			transfers = append(transfers, event)
+		}
+	}
+	return types.ComputeBalances(address, transfers), nil
+}
+
+func (m *MockIndexerService) GetLatestEvents(limitNum int) ([]types.IndexedEvent, error) {
+	var events []types.IndexedEvent
+	for i := len(m.events) - 1; i >= 0 && len(events) < limitNum; i-- {
+		events = append(events, m.events[i])
+	}
+	return events, nil
+}
+
+func (m *MockIndexerService) GetEventsAfterID(afterID uint, limitNum int) ([]types.IndexedEvent, error) {
+	var events []types.IndexedEvent
+	for _, event := range m.events {
+		if event.ID > afterID && len(events) < limitNum {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
 func (m *MockIndexerService) GetEventsByBlockRange(fromBlock, toBlock *big.Int) ([]types.IndexedEvent, error) {
 	return m.events, nil
 }
@@ -53,6 +103,14 @@ func (m *MockIndexerService) ResumeEvents(ctx context.Context, fromBlock, toBloc
 	return nil
 }
 
+func (m *MockIndexerService) ReplayEvents(ctx context.Context, contract string, fromBlock, toBlock *big.Int, transform string) (*types.ReplayResult, error) {
+	return &types.ReplayResult{}, nil
+}
+
+func (m *MockIndexerService) VerifyEvents(ctx context.Context, contract string, fromBlock, toBlock *big.Int, repair bool) (*types.ConsistencyReport, error) {
+	return &types.ConsistencyReport{}, nil
+}
+
 func TestNewServer(t *testing.T) {
 	mockIndexerService := &MockIndexerService{}
 	
@@ -128,6 +186,136 @@ func TestGetEventsHandler(t *testing.T) {
 	}
 }
 
+
+func TestGetEventsHandler_ValueRange(t *testing.T) {
+	mockIndexerService := &MockIndexerService{}
+	server := NewServer(mockIndexerService, "test-secret", nil)
+
+	req, err := http.NewRequest("GET", "/events?minValue=1000&maxValue=100000000000000000000000", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetEventsHandler).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	filter := mockIndexerService.lastFilter
+	if filter == nil || filter.MinValue.String() != "1000" || filter.MaxValue.String() != "100000000000000000000000" {
+		t.Errorf("Expected the value range beyond int64 in the filter, got %+v", filter)
+	}
+
+	// The snake_case names are accepted as aliases
+	req, _ = http.NewRequest("GET", "/events?min_value=5&max_value=50", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetEventsHandler).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	filter = mockIndexerService.lastFilter
+	if filter == nil || filter.MinValue.String() != "5" || filter.MaxValue.String() != "50" {
+		t.Errorf("Expected the aliased value range in the filter, got %+v", filter)
+	}
+
+	for _, query := range []string{"minValue=abc", "maxValue=-1", "minValue=10&maxValue=5", "min_value=abc"} {
+		req, _ := http.NewRequest("GET", "/events?"+query, nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetEventsHandler).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %s, got %d", http.StatusBadRequest, query, rr.Code)
+		}
+	}
+}
+
+func TestGetEventsHandler_TimeRange(t *testing.T) {
+	mockIndexerService := &MockIndexerService{}
+	server := NewServer(mockIndexerService, "test-secret", nil)
+
+	req, err := http.NewRequest("GET", "/events?from_block=100&fromTime=2024-01-01T00:00:00Z&toTime=2024-01-02T12:00:00%2B02:00", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetEventsHandler).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	filter := mockIndexerService.lastFilter
+	if filter == nil || filter.FromTime == nil || filter.ToTime == nil {
+		t.Fatalf("Expected the time range in the filter, got %+v", filter)
+	}
+	if !filter.FromTime.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !filter.ToTime.Equal(time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 2024-01-01T00:00:00Z to 2024-01-02T10:00:00Z, got %v to %v", filter.FromTime, filter.ToTime)
+	}
+	if filter.FromBlock == nil || filter.FromBlock.Int64() != 100 {
+		t.Errorf("Expected the block range alongside the time range, got %v", filter.FromBlock)
+	}
+
+	// The snake_case names are accepted as aliases
+	req, _ = http.NewRequest("GET", "/events?from_time=2024-01-01T00:00:00Z&to_time=2024-01-02T00:00:00Z", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetEventsHandler).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	filter = mockIndexerService.lastFilter
+	if filter == nil || filter.FromTime == nil || filter.ToTime == nil || !filter.ToTime.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the aliased time range in the filter, got %+v", filter)
+	}
+
+	for _, query := range []string{"fromTime=2024-01-01", "toTime=1704067200", "fromTime=2024-01-02T00:00:00Z&toTime=2024-01-01T00:00:00Z", "from_time=2024-01-01"} {
+		req, _ := http.NewRequest("GET", "/events?"+query, nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetEventsHandler).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %s, got %d", http.StatusBadRequest, query, rr.Code)
+		}
+	}
+}
+
+func TestGetEventsHandler_Fields(t *testing.T) {
+	mockIndexerService := &MockIndexerService{
+		events: []types.IndexedEvent{
+			{ID: 1, BlockNumber: big.NewInt(100), TxHash: "0x1", EventName: "Transfer", Contract: "0xabc", Value: "1000"},
+		},
+	}
+	server := NewServer(mockIndexerService, "test-secret", nil)
+
+	req, _ := http.NewRequest("GET", "/events?fields=txHash,value,block_number", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetEventsHandler).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	expected := []string{"tx_hash", "value", "block_number"}
+	if filter := mockIndexerService.lastFilter; filter == nil || strings.Join(filter.Fields, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected the fields %v in the filter, got %+v", expected, filter)
+	}
+
+	var events []map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &events); err != nil {
+		t.Fatalf("Expected valid JSON response, got error: %v", err)
+	}
+	if len(events) != 1 || len(events[0]) != len(expected) {
+		t.Fatalf("Expected 1 event with %d fields, got %v", len(expected), events)
+	}
+	for _, field := range expected {
+		if _, ok := events[0][field]; !ok {
+			t.Errorf("Expected field %s in %v", field, events[0])
+		}
+	}
+
+	req, _ = http.NewRequest("GET", "/events?fields=tx_hash,gas_price", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetEventsHandler).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an unknown field, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
 func TestGetEventHandler(t *testing.T) {
 	mockIndexerService := &MockIndexerService{
 		events: []types.IndexedEvent{
@@ -326,4 +514,177 @@ func TestReplayEventsHandler(t *testing.T) {
 	if !successBool {
 		t.Error("Expected success to be true")
 	}
-}
\ No newline at end of file
+}
+
+func TestGetEventsByTxHashHandler(t *testing.T) {
+	txHash := "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b"
+	mockIndexerService := &MockIndexerService{
+		events: []types.IndexedEvent{
+			{ID: 1, BlockNumber: big.NewInt(100), TxHash: txHash, LogIndex: 5, EventName: "Transfer"},
+			{ID: 2, BlockNumber: big.NewInt(100), TxHash: "0x2", LogIndex: 3, EventName: "Transfer"},
+			{ID: 3, BlockNumber: big.NewInt(100), TxHash: txHash, LogIndex: 2, EventName: "Transfer"},
+			{ID: 4, BlockNumber: big.NewInt(100), TxHash: txHash, LogIndex: 9, EventName: "Transfer"},
+		},
+	}
+
+	server := NewServer(mockIndexerService, "test-secret", nil)
+
+	req, err := http.NewRequest("GET", "/api/v1/tx/"+txHash+"/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+
+	var response struct {
+		Events []types.IndexedEvent `json:"events"`
+		Total  int                  `json:"total"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected valid JSON response, got error: %v", err)
+	}
+
+	if response.Total != 3 || len(response.Events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(response.Events))
+	}
+
+	expected := []uint{2, 5, 9}
+	for i, event := range response.Events {
+		if event.TxHash != txHash {
+			t.Errorf("Expected tx hash %s, got %s", txHash, event.TxHash)
+		}
+		if event.LogIndex != expected[i] {
+			t.Errorf("Expected log index %d at position %d, got %d", expected[i], i, event.LogIndex)
+		}
+	}
+}
+
+func TestGetAddressActivityHandler(t *testing.T) {
+	wallet := common.HexToAddress("0x52908400098527886E0F7030069857D4E4169EE7").Hex()
+	other := "0x8617E340B3D01FA5F11F306F4090FD50E238070D"
+	mockIndexerService := &MockIndexerService{
+		events: []types.IndexedEvent{
+			{ID: 1, BlockNumber: big.NewInt(103), TxHash: "0x1", From: wallet, To: wallet, EventName: "Transfer"},
+			{ID: 2, BlockNumber: big.NewInt(102), TxHash: "0x2", From: wallet, To: other, EventName: "Transfer"},
+			{ID: 3, BlockNumber: big.NewInt(101), TxHash: "0x3", From: other, To: other, EventName: "Transfer"},
+			{ID: 4, BlockNumber: big.NewInt(100), TxHash: "0x4", From: other, To: wallet, EventName: "Transfer"},
+		},
+	}
+
+	server := NewServer(mockIndexerService, "test-secret", nil)
+
+	// Queried in lowercase, stored checksummed
+	req, err := http.NewRequest("GET", "/api/v1/address/0x52908400098527886e0f7030069857d4e4169ee7/activity", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+
+	var response struct {
+		Address string                  `json:"address"`
+		Events  []types.AddressActivity `json:"events"`
+		Total   int                     `json:"total"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected valid JSON response, got error: %v", err)
+	}
+
+	if response.Address != wallet {
+		t.Errorf("Expected checksummed address, got %s", response.Address)
+	}
+
+	expected := map[string]string{"0x1": types.DirectionSelf, "0x2": types.DirectionOut, "0x4": types.DirectionIn}
+	if response.Total != len(expected) || len(response.Events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(response.Events))
+	}
+	for _, event := range response.Events {
+		if event.Direction != expected[event.TxHash] {
+			t.Errorf("Expected direction %q for %s, got %q", expected[event.TxHash], event.TxHash, event.Direction)
+		}
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/address/not-an-address/activity", nil)
+	rr = httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an invalid address, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestGetBalancesHandler(t *testing.T) {
+	wallet := "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	other := "0x8617E340B3D01FA5F11F306F4090FD50E238070D"
+	token := "0x1111111111111111111111111111111111111111"
+	nft := "0x2222222222222222222222222222222222222222"
+	mockIndexerService := &MockIndexerService{
+		events: []types.IndexedEvent{
+			{ID: 1, Contract: token, EventName: types.TokenTransferEventName, From: other, To: wallet, Value: "1000"},
+			{ID: 2, Contract: token, EventName: types.TokenTransferEventName, From: wallet, To: other, Value: "250"},
+			{ID: 3, Contract: nft, EventName: types.NFTTransferEventName, From: other, To: wallet, TokenID: "1"},
+			{ID: 4, Contract: nft, EventName: types.NFTTransferEventName, From: other, To: wallet, TokenID: "2"},
+			{ID: 5, Contract: nft, EventName: types.NFTTransferEventName, From: wallet, To: other, TokenID: "1"},
+		},
+	}
+
+	server := NewServer(mockIndexerService, "test-secret", nil)
+
+	var response struct {
+		Address  string               `json:"address"`
+		Balances []types.TokenBalance `json:"balances"`
+		Total    int                  `json:"total"`
+	}
+
+	// Queried in lowercase, stored checksummed
+	req, _ := http.NewRequest("GET", "/api/v1/balances/0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", nil)
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected valid JSON response, got error: %v", err)
+	}
+	if response.Address != wallet {
+		t.Errorf("Expected checksummed address, got %s", response.Address)
+	}
+	if response.Total != 2 || len(response.Balances) != 2 {
+		t.Fatalf("Expected 2 balances, got %+v", response.Balances)
+	}
+	if balance := response.Balances[0]; balance.Contract != token || balance.Type != types.ContractTypeERC20 || balance.Balance != "750" {
+		t.Errorf("Expected an ERC20 balance of 750, got %+v", balance)
+	}
+	if balance := response.Balances[1]; balance.Contract != nft || balance.Balance != "1" || len(balance.TokenIDs) != 1 || balance.TokenIDs[0] != "2" {
+		t.Errorf("Expected the ERC721 token 2, got %+v", balance)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/balances/"+wallet+"?contract="+nft, nil)
+	rr = httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, req)
+	response.Balances = nil
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected valid JSON response, got error: %v", err)
+	}
+	if len(response.Balances) != 1 || response.Balances[0].Contract != nft {
+		t.Errorf("Expected only the balance of %s, got %+v", nft, response.Balances)
+	}
+
+	for _, path := range []string{"/api/v1/balances/not-an-address", "/api/v1/balances/" + wallet + "?contract=nope"} {
+		req, _ = http.NewRequest("GET", path, nil)
+		rr = httptest.NewRecorder()
+		server.GetRouter().ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %s, got %d", http.StatusBadRequest, path, rr.Code)
+		}
+	}
+}
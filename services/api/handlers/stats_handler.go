@@ -1,10 +1,10 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"chainpulse/shared/database"
+	"chainpulse/shared/json"
 )
 
 // StatsHandler handles stats-related API requests
@@ -23,7 +23,7 @@ func NewStatsHandler(db *database.DB) *StatsHandler {
 func (h *StatsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 	stats, err := h.DB.GetStats()
 	if err != nil {
-		http.Error(w, "Failed to get stats", http.StatusInternalServerError)
+		writeStoreError(w, err, "Failed to get stats")
 		return
 	}
 
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"math/big"
+	"net/http"
+
+	"chainpulse/shared/json"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// VerifyRequest is the body of POST /api/v1/admin/verify
+type VerifyRequest struct {
+	Contract  string `json:"contract"`
+	FromBlock uint64 `json:"fromBlock"`
+	ToBlock   uint64 `json:"toBlock"`
+	Repair    bool   `json:"repair"` // re-index the events missing from the database
+}
+
+// VerifyHandler handles POST /api/v1/admin/verify requests. It compares the stored events
+// of a contract within a block range with the logs the node reports and returns the
+// missing and extra events, re-indexing the missing ones on request.
+func (s *Server) VerifyHandler(w http.ResponseWriter, r *http.Request) {
+	var req VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid request body")
+		return
+	}
+
+	if !common.IsHexAddress(req.Contract) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "Invalid contract address")
+		return
+	}
+
+	if req.ToBlock < req.FromBlock {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidArgument, "toBlock must not be less than fromBlock")
+		return
+	}
+
+	contract := common.HexToAddress(req.Contract).Hex()
+	report, err := s.indexerService.VerifyEvents(r.Context(), contract, new(big.Int).SetUint64(req.FromBlock), new(big.Int).SetUint64(req.ToBlock), req.Repair)
+	if err != nil {
+		s.logger.WithTrace(r.Context()).Error("Failed to verify events of contract %s: %v", contract, err)
+		writeStoreError(w, err, "Failed to verify events")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
+)
+
+// verifyIndexerService records the verification scope and reports one missing event
+type verifyIndexerService struct {
+	MockIndexerService
+	contract string
+	repair   bool
+}
+
+func (m *verifyIndexerService) VerifyEvents(ctx context.Context, contract string, fromBlock, toBlock *big.Int, repair bool) (*types.ConsistencyReport, error) {
+	m.contract = contract
+	m.repair = repair
+	return &types.ConsistencyReport{
+		Contract:  contract,
+		FromBlock: fromBlock.Uint64(),
+		ToBlock:   toBlock.Uint64(),
+		Missing:   []types.EventRef{{BlockNumber: 150, TxHash: "0xabc", LogIndex: 3}},
+		Extra:     []types.EventRef{},
+		Repaired:  1,
+	}, nil
+}
+
+func TestVerifyHandler(t *testing.T) {
+	mockIndexerService := &verifyIndexerService{}
+	server := NewServer(mockIndexerService, "test-secret", nil)
+
+	body, _ := json.Marshal(VerifyRequest{
+		Contract:  "0xbc4ca0eda7647a8ab7c2061c2e118a18a936f13d",
+		FromBlock: 100,
+		ToBlock:   200,
+		Repair:    true,
+	})
+
+	rr := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rr, adminRequest(t, "POST", "/api/v1/admin/verify", body, "admin"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var report types.ConsistencyReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if len(report.Missing) != 1 || report.Missing[0].LogIndex != 3 || report.Repaired != 1 {
+		t.Errorf("Expected one missing and repaired event, got %+v", report)
+	}
+	if mockIndexerService.contract != "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D" || !mockIndexerService.repair {
+		t.Errorf("Expected checksummed contract with repair, got %s repair=%v", mockIndexerService.contract, mockIndexerService.repair)
+	}
+}
+
+func TestVerifyHandlerRejectsInvalidRequests(t *testing.T) {
+	server := NewServer(&verifyIndexerService{}, "test-secret", nil)
+	contract := "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D"
+
+	tests := []struct {
+		name   string
+		req    VerifyRequest
+		role   string
+		status int
+	}{
+		{"invalid contract", VerifyRequest{Contract: "0x123", FromBlock: 1, ToBlock: 2}, "admin", http.StatusBadRequest},
+		{"inverted range", VerifyRequest{Contract: contract, FromBlock: 2, ToBlock: 1}, "admin", http.StatusBadRequest},
+		{"not admin", VerifyRequest{Contract: contract, FromBlock: 1, ToBlock: 2}, "user", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.req)
+			rr := httptest.NewRecorder()
+			server.GetRouter().ServeHTTP(rr, adminRequest(t, "POST", "/api/v1/admin/verify", body, tt.role))
+			if rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rr.Code)
+			}
+		})
+	}
+}
@@ -2,14 +2,22 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"chainpulse/shared/api"
 	"chainpulse/shared/database"
 	"chainpulse/shared/datapuller"
 )
 
+// pluginStopTimeout bounds how long each plugin may take to finish in-flight requests on shutdown
+const pluginStopTimeout = 10 * time.Second
+
 // APIService represents the API Gateway service using plugin architecture
 type APIService struct {
 	plugins          map[string]api.APIPlugin
@@ -64,21 +72,55 @@ func (a *APIService) GetPlugin(pluginName string) (api.APIPlugin, error) {
 	return api.GlobalPluginRegistry.GetPlugin(pluginName)
 }
 
-// Start starts all API service plugins
+// Start starts all API service plugins and blocks until ctx is cancelled or a plugin fails
+// to start. Either way every plugin is stopped, and Start returns once all of them have
+// stopped, with the first start error if any.
 func (a *APIService) Start(ctx context.Context) error {
 	log.Printf("Starting API service with %d plugins", len(a.plugins))
 
+	// A plugin failing to start shuts the others down as well
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(a.plugins))
 	for name, plugin := range a.plugins {
+		wg.Add(1)
 		go func(pluginName string, p api.APIPlugin) {
-			if err := p.Start(ctx); err != nil {
+			defer wg.Done()
+			if err := p.Start(runCtx); err != nil {
 				log.Printf("Error starting API plugin %s: %v", pluginName, err)
+				errs <- fmt.Errorf("failed to start API plugin %s: %v", pluginName, err)
+				cancel()
 			}
 		}(name, plugin)
 	}
 
-	// Block until context is cancelled
-	<-ctx.Done()
-	return nil
+	<-runCtx.Done()
+	a.stopPlugins()
+	wg.Wait()
+
+	close(errs)
+	return <-errs
+}
+
+// stopPlugins stops all plugins concurrently, giving each pluginStopTimeout to finish
+// in-flight requests
+func (a *APIService) stopPlugins() {
+	var wg sync.WaitGroup
+	for name, plugin := range a.plugins {
+		wg.Add(1)
+		go func(pluginName string, p api.APIPlugin) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), pluginStopTimeout)
+			defer cancel()
+			if err := p.Stop(ctx); err != nil {
+				log.Printf("Error stopping API plugin %s: %v", pluginName, err)
+			}
+		}(name, plugin)
+	}
+	wg.Wait()
 }
 
 func main() {
@@ -120,12 +162,13 @@ func main() {
 		log.Fatalf("Failed to initialize API service: %v", err)
 	}
 
-	// Create context for the service
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Run until interrupted, then stop the plugins gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// Start the API service
 	if err := apiService.Start(ctx); err != nil {
 		log.Fatalf("Failed to start API service: %v", err)
 	}
+	log.Println("API service stopped")
 }
\ No newline at end of file
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"chainpulse/shared/api"
+)
+
+// fakePlugin serves until its context is cancelled and takes stopDelay to finish
+// in-flight requests once stopped
+type fakePlugin struct {
+	name      string
+	startErr  error
+	stopDelay time.Duration
+
+	mu      sync.Mutex
+	stopped bool
+	stopCh  chan struct{}
+	once    sync.Once
+}
+
+func newFakePlugin(name string) *fakePlugin {
+	return &fakePlugin{name: name, stopDelay: 50 * time.Millisecond, stopCh: make(chan struct{})}
+}
+
+func (p *fakePlugin) Start(ctx context.Context) error {
+	if p.startErr != nil {
+		return p.startErr
+	}
+	select {
+	case <-ctx.Done():
+	case <-p.stopCh:
+	}
+	time.Sleep(p.stopDelay)
+
+	p.mu.Lock()
+	p.stopped = true
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *fakePlugin) Stop(ctx context.Context) error {
+	p.once.Do(func() { close(p.stopCh) })
+	return nil
+}
+
+func (p *fakePlugin) isStopped() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopped
+}
+
+func (p *fakePlugin) GetName() string                                     { return p.name }
+func (p *fakePlugin) GetType() string                                     { return "rest" }
+func (p *fakePlugin) Initialize(config map[string]interface{}) error      { return nil }
+func (p *fakePlugin) SetMetricsCollector(collector *api.MetricsCollector) {}
+
+func newTestAPIService(plugins ...*fakePlugin) *APIService {
+	service := NewAPIService(nil, nil)
+	for _, plugin := range plugins {
+		service.plugins[plugin.name] = plugin
+	}
+	return service
+}
+
+func TestAPIService_StartReturnsAfterPluginsStop(t *testing.T) {
+	rest := newFakePlugin("rest-api")
+	grpc := newFakePlugin("grpc-api")
+	service := newTestAPIService(rest, grpc)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- service.Start(ctx)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Start to return after cancellation")
+	}
+
+	for _, plugin := range []*fakePlugin{rest, grpc} {
+		if !plugin.isStopped() {
+			t.Errorf("Expected plugin %s to have stopped before Start returned", plugin.name)
+		}
+	}
+}
+
+func TestAPIService_StartError(t *testing.T) {
+	failing := newFakePlugin("grpc-api")
+	failing.startErr = errors.New("address already in use")
+	rest := newFakePlugin("rest-api")
+	service := newTestAPIService(failing, rest)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- service.Start(context.Background())
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected the start error to be returned")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Start to return when a plugin fails to start")
+	}
+
+	if !rest.isStopped() {
+		t.Error("Expected the other plugins to be stopped when one fails to start")
+	}
+}
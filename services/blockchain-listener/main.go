@@ -7,9 +7,11 @@ import (
 	"math/big"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"chainpulse/shared/config"
 	"chainpulse/shared/mq"
 	"chainpulse/shared/types"
 
@@ -20,16 +22,36 @@ import (
 
 // BlockchainListenerService listens to blockchain events and publishes them to the message queue
 type BlockchainListenerService struct {
-	client *ethclient.Client
+	client ChainReader
 	mq     mq.MessageQueue
 	latestBlock *big.Int
+	blockMu     sync.RWMutex
+	topics      mq.TopicConfig
+	reorgInterval time.Duration
+	reorgDetector *ReorgDetector
+	pollInterval  time.Duration
+	// ChainID prefixes the dedup ID of every published log, so a publish that is
+	// repeated after a failure is delivered once
+	ChainID string
 }
 
-// NewBlockchainListenerService creates a new blockchain listener service
-func NewBlockchainListenerService(client *ethclient.Client, mq mq.MessageQueue) *BlockchainListenerService {
+// NewBlockchainListenerService creates a new blockchain listener service. Every reorgInterval
+// the hashes of the last reorgDepth processed blocks are compared against the canonical chain.
+// Nodes without subscription support are polled for new blocks every pollInterval.
+func NewBlockchainListenerService(client ChainReader, mq mq.MessageQueue, topics mq.TopicConfig, reorgInterval time.Duration, reorgDepth int, pollInterval time.Duration) *BlockchainListenerService {
+	if reorgInterval <= 0 {
+		reorgInterval = DefaultReorgCheckInterval
+	}
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
 	return &BlockchainListenerService{
-		client: client,
-		mq:     mq,
+		client:        client,
+		mq:            mq,
+		topics:        topics,
+		reorgInterval: reorgInterval,
+		reorgDetector: NewReorgDetector(client, reorgDepth),
+		pollInterval:  pollInterval,
 	}
 }
 
@@ -48,21 +70,36 @@ func (bls *BlockchainListenerService) Start(contractAddresses []common.Address)
 	}()
 
 	log.Println("Starting blockchain listener service...")
+	return bls.listen(ctx, contractAddresses)
+}
 
+// listen processes new blocks until ctx is cancelled, from a new head subscription or, when
+// the node does not support subscriptions, by polling
+func (bls *BlockchainListenerService) listen(ctx context.Context, contractAddresses []common.Address) error {
 	// Get the latest block number to start from
 	latestBlock, err := bls.client.BlockNumber(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get latest block number: %w", err)
 	}
-	bls.latestBlock = new(big.Int).SetUint64(latestBlock)
+	bls.setLatestBlock(new(big.Int).SetUint64(latestBlock))
 	
-	log.Printf("Starting from block: %s", bls.latestBlock.String())
+	log.Printf("Starting from block: %s", bls.LatestBlock().String())
+
+	go func() {
+		if err := bls.ListenForReorgs(ctx); err != nil && err != context.Canceled {
+			log.Printf("Reorg detection stopped: %v", err)
+		}
+	}()
 
 	// Listen for new blocks
 	headerCh := make(chan *types.Header, 10)
 	sub, err := bls.client.SubscribeNewHead(ctx, headerCh)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to new blocks: %w", err)
+		if !isSubscriptionUnsupported(err) {
+			return fmt.Errorf("failed to subscribe to new blocks: %w", err)
+		}
+		log.Printf("Node does not support subscriptions (%v), polling for new blocks every %v", err, bls.pollInterval)
+		return bls.pollBlocks(ctx, contractAddresses)
 	}
 	defer sub.Unsubscribe()
 
@@ -120,7 +157,10 @@ func (bls *BlockchainListenerService) processBlock(ctx context.Context, header *
 			rawEvent := bls.convertLogToRawEvent(logEntry, block, tx.Hash())
 			
 			// Publish the raw event to the message queue
-			if err := bls.mq.Publish("blockchain.raw.events", rawEvent); err != nil {
+			if err := bls.mq.PublishContext(bls.messageContext(ctx, logEntry), bls.topics.RawEvents(), rawEvent); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
 				log.Printf("Failed to publish raw event: %v", err)
 				continue
 			}
@@ -129,11 +169,36 @@ func (bls *BlockchainListenerService) processBlock(ctx context.Context, header *
 		}
 	}
 
-	// Update the latest block number
-	bls.latestBlock = blockNumber
+	// Update the latest block number and remember its hash for reorg detection
+	bls.setLatestBlock(blockNumber)
+	bls.reorgDetector.Record(blockNumber.Uint64(), block.Hash())
 	return nil
 }
 
+// messageContext returns ctx carrying the dedup ID of the message of logEntry
+func (bls *BlockchainListenerService) messageContext(ctx context.Context, logEntry *types.Log) context.Context {
+	return mq.WithMessageID(ctx, mq.EventMessageID(bls.ChainID, logEntry.TxHash.Hex(), logEntry.Index))
+}
+
+// LatestBlock returns a copy of the latest processed block number, or nil before the
+// listener started
+func (bls *BlockchainListenerService) LatestBlock() *big.Int {
+	bls.blockMu.RLock()
+	defer bls.blockMu.RUnlock()
+	if bls.latestBlock == nil {
+		return nil
+	}
+	return new(big.Int).Set(bls.latestBlock)
+}
+
+// setLatestBlock stores a copy of number as the latest processed block, so callers and
+// readers in other goroutines never share the stored value
+func (bls *BlockchainListenerService) setLatestBlock(number *big.Int) {
+	bls.blockMu.Lock()
+	defer bls.blockMu.Unlock()
+	bls.latestBlock = new(big.Int).Set(number)
+}
+
 // convertLogToRawEvent converts an Ethereum log to our raw event format
 func (bls *BlockchainListenerService) convertLogToRawEvent(logEntry *types.Log, block *types.Block, txHash common.Hash) types.RawEvent {
 	// Convert the log data to a more readable format
@@ -165,11 +230,10 @@ func extractEventName(logEntry *types.Log) string {
 	return "UnknownEvent"
 }
 
-// ListenForReorgs monitors for blockchain reorganizations
+// ListenForReorgs periodically checks that the recently processed blocks are still
+// canonical and publishes a reorg event when their hashes changed
 func (bls *BlockchainListenerService) ListenForReorgs(ctx context.Context) error {
-	// This is a simplified reorg detection
-	// In a production system, you would need more sophisticated reorg detection
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(bls.reorgInterval)
 	defer ticker.Stop()
 
 	for {
@@ -177,45 +241,49 @@ func (bls *BlockchainListenerService) ListenForReorgs(ctx context.Context) error
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			// Check if the current block hash matches what we expect
-			// This is a basic check - production systems need more sophisticated reorg detection
-			currentBlock, err := bls.client.BlockByNumber(ctx, bls.latestBlock)
-			if err != nil {
-				log.Printf("Error getting current block: %v", err)
-				continue
+			if err := bls.checkReorg(ctx); err != nil {
+				log.Printf("Error checking for reorganization: %v", err)
 			}
+		}
+	}
+}
 
-			// If the block number has changed significantly, there might have been a reorg
-			chainBlock, err := bls.client.BlockNumber(ctx)
-			if err != nil {
-				log.Printf("Error getting chain block number: %v", err)
-				continue
-			}
+// checkReorg runs one reorg check and publishes the reorg found, if any
+func (bls *BlockchainListenerService) checkReorg(ctx context.Context) error {
+	reorgEvent, err := bls.reorgDetector.Check(ctx)
+	if err != nil {
+		return err
+	}
+	if reorgEvent == nil {
+		return nil
+	}
 
-			// If our stored latest block is significantly behind, we might have missed blocks
-			chainBig := new(big.Int).SetUint64(chainBlock)
-			diff := new(big.Int).Sub(chainBig, bls.latestBlock)
-			if diff.Cmp(big.NewInt(5)) > 0 { // If difference is more than 5 blocks
-				log.Printf("Potential reorganization detected: current block %s, stored latest %s", 
-					chainBig.String(), bls.latestBlock.String())
-				
-				// In a real implementation, we would publish a reorg event to the message queue
-				reorgEvent := map[string]interface{}{
-					"type":          "reorg_detected",
-					"from_block":    bls.latestBlock.Add(bls.latestBlock, big.NewInt(1)),
-					"to_block":      chainBig,
-					"detection_time": time.Now(),
-				}
-				
-				if err := bls.mq.Publish("blockchain.reorg.events", reorgEvent); err != nil {
-					log.Printf("Failed to publish reorg event: %v", err)
-				}
-			}
-		}
+	log.Printf("Reorganization detected: blocks %s-%s changed, hash at %s was %s, now %s",
+		reorgEvent.FromBlock.String(), reorgEvent.ToBlock.String(), reorgEvent.FromBlock.String(), reorgEvent.OldHash, reorgEvent.NewHash)
+
+	// The check may outlive a shutdown; do not publish once ctx is cancelled
+	if err := ctx.Err(); err != nil {
+		return err
 	}
+	if err := bls.mq.PublishContext(ctx, bls.topics.ReorgEvents(), reorgEvent); err != nil {
+		return fmt.Errorf("failed to publish reorg event: %w", err)
+	}
+	return nil
 }
 
 func main() {
+	// Load configuration
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	topics := mq.TopicConfig{
+		Prefix:              cfg.MQTopicPrefix,
+		RawEventsName:       cfg.MQRawEventsTopic,
+		ProcessedEventsName: cfg.MQProcessedTopic,
+		ReorgEventsName:     cfg.MQReorgTopic,
+	}
+
 	// Connect to Ethereum node (this would come from config in real implementation)
 	client, err := ethclient.Dial("https://mainnet.infura.io/v3/YOUR_PROJECT_ID")
 	if err != nil {
@@ -238,7 +306,10 @@ func main() {
 	}
 
 	// Create and start blockchain listener service
-	service := NewBlockchainListenerService(client, mqInstance)
+	reorgInterval := time.Duration(cfg.ReorgCheckInterval) * time.Second
+	pollInterval := time.Duration(cfg.BlockPollInterval) * time.Second
+	service := NewBlockchainListenerService(client, mqInstance, topics, reorgInterval, cfg.ReorgCheckDepth, pollInterval)
+	service.ChainID = cfg.ChainID
 	
 	if err := service.Start(contractAddresses); err != nil {
 		if err != context.Canceled {
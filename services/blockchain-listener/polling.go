@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DefaultPollInterval is how often a node without subscription support is polled for new blocks
+const DefaultPollInterval = 4 * time.Second
+
+// methodNotFoundCode is the JSON-RPC error code of methods the node does not provide
+const methodNotFoundCode = -32601
+
+// ChainReader is the node API the listener reads blocks and logs from
+type ChainReader interface {
+	HeaderReader
+	BlockNumber(ctx context.Context) (uint64, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*ethtypes.Block, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*ethtypes.Receipt, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethtypes.Log, error)
+	SubscribeNewHead(ctx context.Context, ch chan<- *ethtypes.Header) (ethereum.Subscription, error)
+}
+
+// isSubscriptionUnsupported reports whether err means the node cannot serve eth_subscribe,
+// as HTTP endpoints and some providers cannot
+func isSubscriptionUnsupported(err error) bool {
+	if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+		return true
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == methodNotFoundCode {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "not supported") ||
+		strings.Contains(message, "method not found") ||
+		strings.Contains(message, "does not exist")
+}
+
+// pollBlocks processes new blocks until ctx is cancelled by polling the chain head every
+// pollInterval, for nodes that do not support new head subscriptions
+func (bls *BlockchainListenerService) pollBlocks(ctx context.Context, contractAddresses []common.Address) error {
+	ticker := time.NewTicker(bls.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := bls.pollNewBlocks(ctx, contractAddresses); err != nil {
+				log.Printf("Error polling for new blocks: %v", err)
+			}
+		}
+	}
+}
+
+// pollNewBlocks processes the blocks after the latest processed one up to the chain head.
+// A block that fails is retried on the next poll, so no block is skipped.
+func (bls *BlockchainListenerService) pollNewBlocks(ctx context.Context, contractAddresses []common.Address) error {
+	head, err := bls.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get latest block number: %w", err)
+	}
+
+	for number := bls.LatestBlock().Uint64() + 1; number <= head; number++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := bls.processPolledBlock(ctx, new(big.Int).SetUint64(number), contractAddresses); err != nil {
+			return fmt.Errorf("failed to process block %d: %w", number, err)
+		}
+	}
+	return nil
+}
+
+// processPolledBlock publishes the logs of the watched contracts in one block, read with a
+// single FilterLogs call by block hash rather than a receipt per transaction
+func (bls *BlockchainListenerService) processPolledBlock(ctx context.Context, blockNumber *big.Int, contractAddresses []common.Address) error {
+	log.Printf("Processing block: %s", blockNumber.String())
+
+	header, err := bls.client.HeaderByNumber(ctx, blockNumber)
+	if err != nil {
+		return fmt.Errorf("failed to get block header: %w", err)
+	}
+	block := ethtypes.NewBlockWithHeader(header)
+
+	if len(contractAddresses) > 0 {
+		blockHash := header.Hash()
+		logs, err := bls.client.FilterLogs(ctx, ethereum.FilterQuery{BlockHash: &blockHash, Addresses: contractAddresses})
+		if err != nil {
+			return fmt.Errorf("failed to filter logs: %w", err)
+		}
+
+		for i := range logs {
+			logEntry := &logs[i]
+			rawEvent := bls.convertLogToRawEvent(logEntry, block, logEntry.TxHash)
+			if err := bls.mq.PublishContext(bls.messageContext(ctx, logEntry), bls.topics.RawEvents(), rawEvent); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Printf("Failed to publish raw event: %v", err)
+				continue
+			}
+
+			log.Printf("Published raw event from contract %s, tx: %s", logEntry.Address.Hex(), logEntry.TxHash.Hex())
+		}
+	}
+
+	// Update the latest block number and remember its hash for reorg detection
+	bls.setLatestBlock(blockNumber)
+	bls.reorgDetector.Record(blockNumber.Uint64(), block.Hash())
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"chainpulse/shared/mq"
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// httpOnlyChain is a node without subscription support serving blocks and their logs.
+// subscribed is closed once a subscription was attempted.
+type httpOnlyChain struct {
+	*fakeChain
+	mu         sync.Mutex
+	head       uint64
+	logs       map[common.Hash][]ethtypes.Log
+	subscribed chan struct{}
+	once       sync.Once
+}
+
+func newHTTPOnlyChain(head uint64) *httpOnlyChain {
+	return &httpOnlyChain{
+		fakeChain:  newFakeChain(head, head, 0),
+		head:       head,
+		logs:       make(map[common.Hash][]ethtypes.Log),
+		subscribed: make(chan struct{}),
+	}
+}
+
+// mine adds the next block with a log of contract and moves the head to it
+func (c *httpOnlyChain) mine(contract common.Address) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.head++
+	header := fakeHeader(c.head, 0)
+	c.set(c.head, header)
+	c.logs[header.Hash()] = []ethtypes.Log{{
+		Address:     contract,
+		Topics:      []common.Hash{common.HexToHash("0xddf252ad")},
+		BlockNumber: c.head,
+		BlockHash:   header.Hash(),
+		TxHash:      common.BigToHash(new(big.Int).SetUint64(c.head)),
+	}}
+}
+
+func (c *httpOnlyChain) BlockNumber(ctx context.Context) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.head, nil
+}
+
+func (c *httpOnlyChain) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethtypes.Log, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if q.BlockHash == nil {
+		return nil, errors.New("expected a block hash query")
+	}
+	return c.logs[*q.BlockHash], nil
+}
+
+func (c *httpOnlyChain) BlockByNumber(ctx context.Context, number *big.Int) (*ethtypes.Block, error) {
+	return nil, errors.New("unexpected BlockByNumber call")
+}
+
+func (c *httpOnlyChain) TransactionReceipt(ctx context.Context, txHash common.Hash) (*ethtypes.Receipt, error) {
+	return nil, errors.New("unexpected TransactionReceipt call")
+}
+
+func (c *httpOnlyChain) SubscribeNewHead(ctx context.Context, ch chan<- *ethtypes.Header) (ethereum.Subscription, error) {
+	c.once.Do(func() { close(c.subscribed) })
+	return nil, errors.New("notifications not supported")
+}
+
+// publishedCount returns how many messages queue has published
+func (m *recordingMQ) publishedCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.published)
+}
+
+func TestBlockchainListener_PollsWhenSubscriptionsUnsupported(t *testing.T) {
+	contract := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	chain := newHTTPOnlyChain(100)
+	queue := &recordingMQ{}
+	service := NewBlockchainListenerService(chain, queue, mq.TopicConfig{}, time.Hour, 10, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() { errs <- service.listen(ctx, []common.Address{contract}) }()
+
+	// Blocks mined after the listener started are picked up by polling
+	select {
+	case <-chain.subscribed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the listener to try subscribing")
+	}
+	chain.mine(contract)
+	chain.mine(contract)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for queue.publishedCount() < 2 {
+		select {
+		case err := <-errs:
+			t.Fatalf("Expected the listener to keep polling, got %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 2 published events, got %d", queue.publishedCount())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Fatalf("Expected the listener to stop on cancel, got %v", err)
+	}
+
+	for i, expected := range []uint64{101, 102} {
+		if queue.topics[i] != "blockchain.raw.events" {
+			t.Errorf("Expected blockchain.raw.events, got %s", queue.topics[i])
+		}
+		event := queue.published[i].(types.RawEvent)
+		if event.BlockNumber.Uint64() != expected {
+			t.Errorf("Expected an event of block %d, got %s", expected, event.BlockNumber)
+		}
+		if event.BlockHash != chain.headers[expected].Hash().Hex() {
+			t.Errorf("Expected block hash %s, got %s", chain.headers[expected].Hash().Hex(), event.BlockHash)
+		}
+		if event.ContractAddr != contract.Hex() {
+			t.Errorf("Expected contract %s, got %s", contract.Hex(), event.ContractAddr)
+		}
+	}
+	if service.LatestBlock().Uint64() != 102 {
+		t.Errorf("Expected latest block 102, got %s", service.LatestBlock())
+	}
+}
+
+func TestIsSubscriptionUnsupported(t *testing.T) {
+	tests := map[error]bool{
+		rpc.ErrNotificationsUnsupported:                                        true,
+		fmt.Errorf("subscribe: %w", rpc.ErrNotificationsUnsupported):           true,
+		errors.New("the method eth_subscribe does not exist/is not available"): true,
+		errors.New("Method not found"):                                         true,
+		errors.New("connection refused"):                                       false,
+		context.DeadlineExceeded:                                               false,
+	}
+
+	for err, expected := range tests {
+		if unsupported := isSubscriptionUnsupported(err); unsupported != expected {
+			t.Errorf("Expected %v to be unsupported=%v, got %v", err, expected, unsupported)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// DefaultReorgCheckInterval is how often recently processed blocks are compared against the canonical chain
+	DefaultReorgCheckInterval = 30 * time.Second
+	// DefaultReorgCheckDepth is how many of the most recent processed blocks are re-checked
+	DefaultReorgCheckDepth = 12
+)
+
+// HeaderReader reads headers of the canonical chain
+type HeaderReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*ethtypes.Header, error)
+}
+
+// ReorgEvent is published to the reorg topic when processed blocks leave the canonical
+// chain; the indexer consumes it to roll back the affected events
+type ReorgEvent = types.ReorgEvent
+
+// ReorgDetector remembers the hashes of the most recent processed blocks and reports
+// a reorg when the canonical chain no longer has the same hash at one of those heights.
+// Blocks older than the depth window are forgotten, and hashes are replaced with the
+// canonical ones once a reorg is reported, so each reorg is reported once.
+type ReorgDetector struct {
+	client HeaderReader
+	depth  uint64
+	hashes map[uint64]common.Hash
+	mu     sync.Mutex
+}
+
+// NewReorgDetector creates a reorg detector that re-checks the last depth processed blocks
+func NewReorgDetector(client HeaderReader, depth int) *ReorgDetector {
+	if depth <= 0 {
+		depth = DefaultReorgCheckDepth
+	}
+	return &ReorgDetector{
+		client: client,
+		depth:  uint64(depth),
+		hashes: make(map[uint64]common.Hash),
+	}
+}
+
+// Record stores the hash of a processed block
+func (d *ReorgDetector) Record(number uint64, hash common.Hash) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.hashes[number] = hash
+
+	var latest uint64
+	for height := range d.hashes {
+		if height > latest {
+			latest = height
+		}
+	}
+	for height := range d.hashes {
+		if height+d.depth <= latest {
+			delete(d.hashes, height)
+		}
+	}
+}
+
+// Check compares the recorded hashes against the canonical chain and returns the reorg
+// found, or nil if every recorded block is still canonical. A chain head that is merely
+// ahead of the processed blocks is not a reorg.
+func (d *ReorgDetector) Check(ctx context.Context) (*ReorgEvent, error) {
+	d.mu.Lock()
+	heights := make([]uint64, 0, len(d.hashes))
+	for height := range d.hashes {
+		heights = append(heights, height)
+	}
+	d.mu.Unlock()
+
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	var event *ReorgEvent
+	canonical := make(map[uint64]common.Hash, len(heights))
+	for _, height := range heights {
+		hash, exists, err := d.canonicalHash(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			canonical[height] = hash
+		}
+
+		d.mu.Lock()
+		stored, recorded := d.hashes[height]
+		d.mu.Unlock()
+		if !recorded || (exists && hash == stored) {
+			continue
+		}
+
+		if event == nil {
+			event = &ReorgEvent{
+				Type:          "reorg_detected",
+				FromBlock:     new(big.Int).SetUint64(height),
+				OldHash:       stored.Hex(),
+				DetectionTime: time.Now(),
+			}
+			if exists {
+				event.NewHash = hash.Hex()
+			}
+		}
+		event.ToBlock = new(big.Int).SetUint64(height)
+	}
+
+	if event == nil {
+		return nil, nil
+	}
+
+	// Track the canonical chain from now on so the same reorg is not reported again
+	d.mu.Lock()
+	for height := event.FromBlock.Uint64(); height <= event.ToBlock.Uint64(); height++ {
+		if hash, exists := canonical[height]; exists {
+			d.hashes[height] = hash
+		} else {
+			delete(d.hashes, height)
+		}
+	}
+	d.mu.Unlock()
+
+	return event, nil
+}
+
+// canonicalHash returns the canonical hash at height, reporting whether the block exists
+func (d *ReorgDetector) canonicalHash(ctx context.Context, height uint64) (common.Hash, bool, error) {
+	header, err := d.client.HeaderByNumber(ctx, new(big.Int).SetUint64(height))
+	if errors.Is(err, ethereum.NotFound) {
+		return common.Hash{}, false, nil
+	}
+	if err != nil {
+		return common.Hash{}, false, fmt.Errorf("failed to get header for block %d: %w", height, err)
+	}
+	return header.Hash(), true, nil
+}
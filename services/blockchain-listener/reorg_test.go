@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+
+	"chainpulse/shared/mq"
+
+	"github.com/ethereum/go-ethereum"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeChain serves canonical headers by height
+type fakeChain struct {
+	mu      sync.Mutex
+	headers map[uint64]*ethtypes.Header
+}
+
+func newFakeChain(from, to uint64, fork byte) *fakeChain {
+	chain := &fakeChain{headers: make(map[uint64]*ethtypes.Header)}
+	for height := from; height <= to; height++ {
+		chain.headers[height] = fakeHeader(height, fork)
+	}
+	return chain
+}
+
+// fakeHeader returns a header whose hash differs per height and fork
+func fakeHeader(height uint64, fork byte) *ethtypes.Header {
+	return &ethtypes.Header{Number: new(big.Int).SetUint64(height), Extra: []byte{fork}}
+}
+
+func (c *fakeChain) HeaderByNumber(ctx context.Context, number *big.Int) (*ethtypes.Header, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header, exists := c.headers[number.Uint64()]
+	if !exists {
+		return nil, ethereum.NotFound
+	}
+	return header, nil
+}
+
+func (c *fakeChain) set(height uint64, header *ethtypes.Header) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.headers[height] = header
+}
+
+// recordingMQ records published messages
+type recordingMQ struct {
+	mu        sync.Mutex
+	published []interface{}
+	topics    []string
+}
+
+func (m *recordingMQ) Publish(topic string, message interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.topics = append(m.topics, topic)
+	m.published = append(m.published, message)
+	return nil
+}
+
+func (m *recordingMQ) PublishContext(ctx context.Context, topic string, message interface{}) error {
+	return m.Publish(topic, message)
+}
+
+func (m *recordingMQ) Consume(ctx context.Context, topic string, handler mq.MessageHandler) error {
+	return nil
+}
+
+func (m *recordingMQ) Close() error { return nil }
+
+func newReorgTestService(chain *fakeChain, depth int) (*BlockchainListenerService, *recordingMQ) {
+	queue := &recordingMQ{}
+	return &BlockchainListenerService{
+		mq:            queue,
+		reorgInterval: DefaultReorgCheckInterval,
+		reorgDetector: NewReorgDetector(chain, depth),
+	}, queue
+}
+
+func TestReorgDetector_HashMismatchPublishesReorg(t *testing.T) {
+	chain := newFakeChain(100, 105, 0)
+	service, queue := newReorgTestService(chain, 10)
+	for height := uint64(100); height <= 105; height++ {
+		service.reorgDetector.Record(height, chain.headers[height].Hash())
+	}
+
+	// Blocks 103-105 are replaced by a competing fork
+	oldHash := chain.headers[103].Hash()
+	for height := uint64(103); height <= 105; height++ {
+		chain.set(height, fakeHeader(height, 1))
+	}
+
+	if err := service.checkReorg(context.Background()); err != nil {
+		t.Fatalf("Failed to check for reorg: %v", err)
+	}
+
+	if len(queue.published) != 1 {
+		t.Fatalf("Expected 1 reorg event, got %d", len(queue.published))
+	}
+	if queue.topics[0] != "blockchain.reorg.events" {
+		t.Errorf("Expected blockchain.reorg.events, got %s", queue.topics[0])
+	}
+
+	event := queue.published[0].(*ReorgEvent)
+	if event.FromBlock.Uint64() != 103 || event.ToBlock.Uint64() != 105 {
+		t.Errorf("Expected reorg of blocks 103-105, got %s-%s", event.FromBlock, event.ToBlock)
+	}
+	if event.OldHash != oldHash.Hex() || event.NewHash != chain.headers[103].Hash().Hex() {
+		t.Errorf("Expected hash %s replaced by %s, got %s replaced by %s", oldHash.Hex(), chain.headers[103].Hash().Hex(), event.OldHash, event.NewHash)
+	}
+
+	// The same reorg is not reported twice
+	if err := service.checkReorg(context.Background()); err != nil {
+		t.Fatalf("Failed to check for reorg: %v", err)
+	}
+	if len(queue.published) != 1 {
+		t.Errorf("Expected reorg to be reported once, got %d events", len(queue.published))
+	}
+}
+
+func TestReorgDetector_PayloadDoesNotAlterLatestBlock(t *testing.T) {
+	chain := newFakeChain(100, 105, 0)
+	service, queue := newReorgTestService(chain, 10)
+	for height := uint64(100); height <= 105; height++ {
+		service.reorgDetector.Record(height, chain.headers[height].Hash())
+	}
+	latest := big.NewInt(105)
+	service.setLatestBlock(latest)
+	latest.SetUint64(1)
+
+	chain.set(105, fakeHeader(105, 1))
+	if err := service.checkReorg(context.Background()); err != nil {
+		t.Fatalf("Failed to check for reorg: %v", err)
+	}
+	if len(queue.published) != 1 {
+		t.Fatalf("Expected 1 reorg event, got %d", len(queue.published))
+	}
+
+	// Consumers mutating the payload must not move the stored cursor
+	event := queue.published[0].(*ReorgEvent)
+	event.FromBlock.Add(event.FromBlock, big.NewInt(10))
+	event.ToBlock.Add(event.ToBlock, big.NewInt(10))
+	service.LatestBlock().SetUint64(0)
+
+	if service.LatestBlock().Uint64() != 105 {
+		t.Errorf("Expected latest block 105, got %s", service.LatestBlock())
+	}
+}
+
+func TestReorgDetector_CancelledContextSkipsPublish(t *testing.T) {
+	chain := newFakeChain(100, 105, 0)
+	service, queue := newReorgTestService(chain, 10)
+	for height := uint64(100); height <= 105; height++ {
+		service.reorgDetector.Record(height, chain.headers[height].Hash())
+	}
+	chain.set(105, fakeHeader(105, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := service.checkReorg(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(queue.published) != 0 {
+		t.Errorf("Expected no reorg event after cancellation, got %d", len(queue.published))
+	}
+}
+
+func TestReorgDetector_LagIsNotReorg(t *testing.T) {
+	chain := newFakeChain(100, 105, 0)
+	service, queue := newReorgTestService(chain, 10)
+	for height := uint64(100); height <= 102; height++ {
+		service.reorgDetector.Record(height, chain.headers[height].Hash())
+	}
+
+	// The chain head moves far ahead of the processed blocks
+	for height := uint64(106); height <= 150; height++ {
+		chain.set(height, fakeHeader(height, 0))
+	}
+
+	if err := service.checkReorg(context.Background()); err != nil {
+		t.Fatalf("Failed to check for reorg: %v", err)
+	}
+	if len(queue.published) != 0 {
+		t.Errorf("Expected no reorg event for a lagging listener, got %d", len(queue.published))
+	}
+}
+
+func TestReorgDetector_DepthWindow(t *testing.T) {
+	chain := newFakeChain(100, 110, 0)
+	detector := NewReorgDetector(chain, 3)
+	for height := uint64(100); height <= 110; height++ {
+		detector.Record(height, chain.headers[height].Hash())
+	}
+
+	// A change below the depth window is no longer checked
+	chain.set(105, fakeHeader(105, 1))
+	event, err := detector.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to check for reorg: %v", err)
+	}
+	if event != nil {
+		t.Errorf("Expected no reorg outside the depth window, got blocks %s-%s", event.FromBlock, event.ToBlock)
+	}
+
+	// A block that disappeared from the chain is a reorg
+	chain.mu.Lock()
+	delete(chain.headers, 110)
+	chain.mu.Unlock()
+	event, err = detector.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to check for reorg: %v", err)
+	}
+	if event == nil || event.FromBlock.Uint64() != 110 || event.NewHash != "" {
+		t.Errorf("Expected reorg at block 110 with no canonical hash, got %+v", event)
+	}
+
+	if _, exists := detector.hashes[110]; exists {
+		t.Error("Expected missing block to be forgotten")
+	}
+}
@@ -0,0 +1,221 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	ApprovalEventSignature       = "Approval(address,address,uint256)"
+	ApprovalForAllEventSignature = "ApprovalForAll(address,address,bool)"
+)
+
+// The approval events are classified by their signature hash, the first topic
+var (
+	approvalTopic       = crypto.Keccak256Hash([]byte(ApprovalEventSignature))
+	approvalForAllTopic = crypto.Keccak256Hash([]byte(ApprovalForAllEventSignature))
+)
+
+// ERC-721 indexes the approved token ID, so its Approval logs carry one more topic than
+// ERC-20 ones, which keep the allowance in the data
+const (
+	tokenApprovalTopics = 3
+	nftApprovalTopics   = 4
+)
+
+// isApprovalLog reports whether vLog is an Approval or ApprovalForAll log
+func isApprovalLog(vLog ethtypes.Log) bool {
+	return len(vLog.Topics) > 0 && (vLog.Topics[0] == approvalTopic || vLog.Topics[0] == approvalForAllTopic)
+}
+
+// ConvertApprovalLog converts an Approval or ApprovalForAll log, classified by its first
+// topic, into the indexed event stored for it
+func (ep *EventProcessor) ConvertApprovalLog(vLog ethtypes.Log) (*types.IndexedEvent, error) {
+	if len(vLog.Topics) == 0 {
+		return nil, fmt.Errorf("approval log without topics in tx %s", vLog.TxHash.Hex())
+	}
+
+	switch vLog.Topics[0] {
+	case approvalTopic:
+		event, err := ep.parseApprovalEvent(vLog)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing approval event: %v", err)
+		}
+		return ep.ConvertApprovalToIndexedEvent(event), nil
+	case approvalForAllTopic:
+		event, err := ep.parseApprovalForAllEvent(vLog)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing approval for all event: %v", err)
+		}
+		return ep.ConvertApprovalForAllToIndexedEvent(event), nil
+	default:
+		return nil, fmt.Errorf("unexpected log with signature %s in tx %s", vLog.Topics[0].Hex(), vLog.TxHash.Hex())
+	}
+}
+
+func (ep *EventProcessor) parseApprovalEvent(vLog ethtypes.Log) (*types.ApprovalEvent, error) {
+	if len(vLog.Topics) != tokenApprovalTopics && len(vLog.Topics) != nftApprovalTopics {
+		return nil, fmt.Errorf("approval log has %d topics, expected %d or %d", len(vLog.Topics), tokenApprovalTopics, nftApprovalTopics)
+	}
+
+	event := &types.ApprovalEvent{
+		BlockNumber: new(big.Int).SetUint64(vLog.BlockNumber),
+		TxHash:      vLog.TxHash,
+		LogIndex:    vLog.Index,
+		Owner:       common.BytesToAddress(vLog.Topics[1].Bytes()),
+		Spender:     common.BytesToAddress(vLog.Topics[2].Bytes()),
+		Contract:    vLog.Address,
+	}
+	if len(vLog.Topics) == nftApprovalTopics {
+		event.TokenID = new(big.Int).SetBytes(vLog.Topics[3].Bytes())
+	} else {
+		if len(vLog.Data) != common.HashLength {
+			return nil, fmt.Errorf("approval log has %d bytes of data, expected %d", len(vLog.Data), common.HashLength)
+		}
+		event.Amount = new(big.Int).SetBytes(vLog.Data)
+	}
+
+	timestamp, err := ep.logTimestamp(vLog)
+	if err != nil {
+		return nil, err
+	}
+	event.Timestamp = timestamp
+	return event, nil
+}
+
+func (ep *EventProcessor) parseApprovalForAllEvent(vLog ethtypes.Log) (*types.ApprovalForAllEvent, error) {
+	if len(vLog.Topics) != 3 {
+		return nil, fmt.Errorf("approval for all log has %d topics, expected 3", len(vLog.Topics))
+	}
+	if len(vLog.Data) != common.HashLength {
+		return nil, fmt.Errorf("approval for all log has %d bytes of data, expected %d", len(vLog.Data), common.HashLength)
+	}
+
+	timestamp, err := ep.logTimestamp(vLog)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ApprovalForAllEvent{
+		BlockNumber: new(big.Int).SetUint64(vLog.BlockNumber),
+		TxHash:      vLog.TxHash,
+		LogIndex:    vLog.Index,
+		Owner:       common.BytesToAddress(vLog.Topics[1].Bytes()),
+		Operator:    common.BytesToAddress(vLog.Topics[2].Bytes()),
+		Approved:    new(big.Int).SetBytes(vLog.Data).Sign() != 0,
+		Contract:    vLog.Address,
+		Timestamp:   timestamp,
+	}, nil
+}
+
+// ConvertApprovalToIndexedEvent converts an approval into the indexed event format, with
+// the owner as From and the spender as To. Data holds the owner, the spender and the
+// amount of an ERC-20 approval or the token ID of an ERC-721 one.
+func (ep *EventProcessor) ConvertApprovalToIndexedEvent(approval *types.ApprovalEvent) *types.IndexedEvent {
+	event := &types.IndexedEvent{
+		BlockNumber: approval.BlockNumber,
+		TxHash:      approval.TxHash.Hex(),
+		LogIndex:    approval.LogIndex,
+		EventName:   "Approval",
+		Topic0:      approvalTopic.Hex(),
+		Contract:    approval.Contract.Hex(),
+		From:        approval.Owner.Hex(),
+		To:          approval.Spender.Hex(),
+		Data: map[string]interface{}{
+			"owner":   approval.Owner.Hex(),
+			"spender": approval.Spender.Hex(),
+		},
+		Timestamp: approval.Timestamp,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if approval.TokenID != nil {
+		event.TokenID = approval.TokenID.String()
+		event.Data["tokenId"] = approval.TokenID.String()
+	} else if approval.Amount != nil {
+		event.Value = approval.Amount.String()
+		event.Data["amount"] = approval.Amount.String()
+	}
+	return event
+}
+
+// ConvertApprovalForAllToIndexedEvent converts an operator approval into the indexed event
+// format, with the owner as From and the operator as To
+func (ep *EventProcessor) ConvertApprovalForAllToIndexedEvent(approval *types.ApprovalForAllEvent) *types.IndexedEvent {
+	return &types.IndexedEvent{
+		BlockNumber: approval.BlockNumber,
+		TxHash:      approval.TxHash.Hex(),
+		LogIndex:    approval.LogIndex,
+		EventName:   "ApprovalForAll",
+		Topic0:      approvalForAllTopic.Hex(),
+		Contract:    approval.Contract.Hex(),
+		From:        approval.Owner.Hex(),
+		To:          approval.Operator.Hex(),
+		Data: map[string]interface{}{
+			"owner":    approval.Owner.Hex(),
+			"operator": approval.Operator.Hex(),
+			"approved": approval.Approved,
+		},
+		Timestamp: approval.Timestamp,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// SubscribeToApprovals subscribes to real-time Approval and ApprovalForAll events, emitted
+// in (block number, log index) order up to OrderingDepth blocks below the newest block seen
+func (ep *EventProcessor) SubscribeToApprovals(ctx context.Context, contractAddresses []common.Address) (<-chan *types.IndexedEvent, <-chan error, error) {
+	query := ethereum.FilterQuery{
+		Addresses: contractAddresses,
+		Topics: [][]common.Hash{
+			{approvalTopic, approvalForAllTopic},
+		},
+	}
+
+	logs, subErrs, err := ep.subscribeLogs(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	eventChan := make(chan *types.IndexedEvent)
+	errChan := make(chan error)
+
+	go func() {
+		defer close(eventChan)
+		defer close(errChan)
+
+		for {
+			select {
+			case vLog, ok := <-logs:
+				if !ok {
+					return
+				}
+				event, err := ep.ConvertApprovalLog(vLog)
+				if err != nil {
+					errChan <- err
+					continue
+				}
+				eventChan <- event
+			case <-ctx.Done():
+				return
+			case err, ok := <-subErrs:
+				if !ok {
+					return
+				}
+				// Failed shards resubscribe on their own, so keep the stream open
+				errChan <- err
+			}
+		}
+	}()
+
+	return orderEvents(ctx, (<-chan *types.IndexedEvent)(eventChan), ep.OrderingDepth, ep.OrderingMaxHold, indexedEventPosition), errChan, nil
+}
@@ -0,0 +1,175 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+func newApprovalTestProcessor(t *testing.T) (*EventProcessor, *ethtypes.Block) {
+	block := ethtypes.NewBlockWithHeader(&ethtypes.Header{Number: big.NewInt(150), Time: 1700000000})
+	client := &mockChainClient{blocks: map[common.Hash]*ethtypes.Block{block.Hash(): block}}
+
+	ep, err := NewEventProcessorWithClient(client)
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+	return ep, block
+}
+
+func TestEventProcessor_ConvertApprovalLog(t *testing.T) {
+	ep, block := newApprovalTestProcessor(t)
+	contract := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	owner := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	spender := common.HexToAddress("0x0000000000000000000000000000000000000002")
+
+	event, err := ep.ConvertApprovalLog(ethtypes.Log{
+		Address:     contract,
+		Topics:      []common.Hash{approvalTopic, common.BytesToHash(owner.Bytes()), common.BytesToHash(spender.Bytes())},
+		Data:        common.LeftPadBytes(big.NewInt(5000).Bytes(), 32),
+		BlockNumber: 150,
+		BlockHash:   block.Hash(),
+		TxHash:      common.HexToHash("0xaa"),
+		Index:       2,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if event.EventName != "Approval" || event.Topic0 != approvalTopic.Hex() {
+		t.Errorf("Expected an Approval event, got %s with topic %s", event.EventName, event.Topic0)
+	}
+	if event.Contract != contract.Hex() || event.From != owner.Hex() || event.To != spender.Hex() || event.Value != "5000" {
+		t.Errorf("Unexpected approval fields %+v", event)
+	}
+	if event.LogIndex != 2 || event.BlockNumber.Uint64() != 150 || !event.Timestamp.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Unexpected approval position %+v", event)
+	}
+	expected := map[string]interface{}{"owner": owner.Hex(), "spender": spender.Hex(), "amount": "5000"}
+	if len(event.Data) != len(expected) {
+		t.Errorf("Expected data %v, got %v", expected, event.Data)
+	}
+	for key, value := range expected {
+		if event.Data[key] != value {
+			t.Errorf("Expected data %s to be %v, got %v", key, value, event.Data[key])
+		}
+	}
+}
+
+func TestEventProcessor_ConvertNFTApprovalLog(t *testing.T) {
+	ep, block := newApprovalTestProcessor(t)
+	owner := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	spender := common.HexToAddress("0x0000000000000000000000000000000000000002")
+
+	// ERC-721 indexes the approved token ID
+	event, err := ep.ConvertApprovalLog(ethtypes.Log{
+		Topics:    []common.Hash{approvalTopic, common.BytesToHash(owner.Bytes()), common.BytesToHash(spender.Bytes()), common.BigToHash(big.NewInt(42))},
+		BlockHash: block.Hash(),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if event.TokenID != "42" || event.Value != "" || event.Data["tokenId"] != "42" {
+		t.Errorf("Expected an approval of token 42, got %+v", event)
+	}
+	if _, ok := event.Data["amount"]; ok {
+		t.Errorf("Expected no amount for an NFT approval, got %v", event.Data["amount"])
+	}
+}
+
+func TestEventProcessor_ConvertApprovalForAllLog(t *testing.T) {
+	ep, block := newApprovalTestProcessor(t)
+	contract := common.HexToAddress("0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D")
+	owner := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	operator := common.HexToAddress("0x0000000000000000000000000000000000000003")
+
+	for _, approved := range []bool{true, false} {
+		data := make([]byte, 32)
+		if approved {
+			data[31] = 1
+		}
+		event, err := ep.ConvertApprovalLog(ethtypes.Log{
+			Address:     contract,
+			Topics:      []common.Hash{approvalForAllTopic, common.BytesToHash(owner.Bytes()), common.BytesToHash(operator.Bytes())},
+			Data:        data,
+			BlockNumber: 150,
+			BlockHash:   block.Hash(),
+			TxHash:      common.HexToHash("0xbb"),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if event.EventName != "ApprovalForAll" || event.Topic0 != approvalForAllTopic.Hex() {
+			t.Errorf("Expected an ApprovalForAll event, got %s with topic %s", event.EventName, event.Topic0)
+		}
+		if event.From != owner.Hex() || event.To != operator.Hex() || event.Contract != contract.Hex() {
+			t.Errorf("Unexpected approval fields %+v", event)
+		}
+		if event.Data["owner"] != owner.Hex() || event.Data["operator"] != operator.Hex() || event.Data["approved"] != approved {
+			t.Errorf("Expected data with approved=%v, got %v", approved, event.Data)
+		}
+	}
+}
+
+func TestEventProcessor_ConvertApprovalLogRejectsMalformed(t *testing.T) {
+	ep, block := newApprovalTestProcessor(t)
+	owner := common.BytesToHash(common.HexToAddress("0x1").Bytes())
+	spender := common.BytesToHash(common.HexToAddress("0x2").Bytes())
+
+	malformed := []ethtypes.Log{
+		{Topics: []common.Hash{approvalTopic, owner, spender}, BlockHash: block.Hash()},                         // no amount
+		{Topics: []common.Hash{approvalForAllTopic, owner}, Data: make([]byte, 32), BlockHash: block.Hash()},    // no operator
+		{Topics: []common.Hash{transferTopic, owner, spender}, Data: make([]byte, 32), BlockHash: block.Hash()}, // not an approval
+	}
+	for i, vLog := range malformed {
+		if event, err := ep.ConvertApprovalLog(vLog); err == nil {
+			t.Errorf("Expected log %d to be rejected, got %+v", i, event)
+		}
+	}
+}
+
+func TestMultiplexTransferLogs_ClassifiesApprovalsBySignature(t *testing.T) {
+	logs := make(chan ethtypes.Log, 3)
+	subErrs := make(chan error)
+	close(subErrs)
+
+	from := common.BytesToHash(common.HexToAddress("0x1").Bytes())
+	to := common.BytesToHash(common.HexToAddress("0x2").Bytes())
+	// An ERC-20 Approval has as many topics as an ERC-20 Transfer
+	logs <- ethtypes.Log{Topics: []common.Hash{approvalTopic, from, to}, Index: 0}
+	logs <- ethtypes.Log{Topics: []common.Hash{transferTopic, from, to}, Index: 1}
+	logs <- ethtypes.Log{Topics: []common.Hash{approvalForAllTopic, from, to}, Index: 2}
+	close(logs)
+
+	parsers := &countingParsers{}
+	var approvals int
+	parseApproval := func(vLog ethtypes.Log) (*types.IndexedEvent, error) {
+		approvals++
+		return &types.IndexedEvent{LogIndex: vLog.Index, EventName: "Approval"}, nil
+	}
+	events, _ := multiplexTransferLogs(context.Background(), logs, subErrs, parsers.parseNFT, parsers.parseToken, parseApproval)
+
+	var names []string
+	for event := range events {
+		names = append(names, event.EventName)
+	}
+	expected := []string{"Approval", "TokenTransfer", "Approval"}
+	if len(names) != len(expected) {
+		t.Fatalf("Expected events %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("Expected event %d to be %s, got %s", i, expected[i], names[i])
+		}
+	}
+	if approvals != 2 || parsers.token != 1 || parsers.nft != 0 {
+		t.Errorf("Expected 2 approval parses and 1 token parse, got %d, %d and %d NFT", approvals, parsers.token, parsers.nft)
+	}
+}
@@ -0,0 +1,75 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// DefaultBlockNotFoundRetries is how many times the block of a log is looked up again
+// when the node knows it neither by hash nor by number
+const DefaultBlockNotFoundRetries = 3
+
+// defaultBlockNotFoundDelay is the wait before looking up an unknown block again
+const defaultBlockNotFoundDelay = 500 * time.Millisecond
+
+// ErrBlockNotFound is returned when the node still does not know the block of a log
+// after the retries, usually because it lags behind the node that served the log
+var ErrBlockNotFound = errors.New("block not found")
+
+// ErrOrphanedLog is returned for a log whose block was replaced by a reorg. The log is
+// no longer part of the chain and should be skipped rather than retried.
+var ErrOrphanedLog = errors.New("log orphaned by a reorg")
+
+// logTimestamp returns the timestamp of the block vLog was emitted in. During a reorg
+// the node can stop serving a block it just orphaned, so a block unknown by hash is
+// resolved through the canonical block at its height: the log is orphaned when that
+// block has another hash. A block unknown at both is looked up again, as the node may
+// not have imported it yet.
+func (ep *EventProcessor) logTimestamp(vLog ethtypes.Log) (time.Time, error) {
+	ctx := context.Background()
+	for attempt := 0; ; attempt++ {
+		if err := ep.waitRPC(ctx); err != nil {
+			return time.Time{}, err
+		}
+		block, err := ep.Client.BlockByHash(ctx, vLog.BlockHash)
+		if err == nil {
+			return time.Unix(int64(block.Time()), 0), nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return time.Time{}, err
+		}
+
+		if err := ep.waitRPC(ctx); err != nil {
+			return time.Time{}, err
+		}
+		canonical, err := ep.Client.BlockByNumber(ctx, new(big.Int).SetUint64(vLog.BlockNumber))
+		switch {
+		case err == nil && canonical.Hash() == vLog.BlockHash:
+			return time.Unix(int64(canonical.Time()), 0), nil
+		case err == nil:
+			ep.recordError("orphaned_log")
+			return time.Time{}, fmt.Errorf("%w: block %d %s was replaced by %s", ErrOrphanedLog, vLog.BlockNumber, vLog.BlockHash.Hex(), canonical.Hash().Hex())
+		case !errors.Is(err, ethereum.NotFound):
+			return time.Time{}, err
+		}
+
+		if attempt >= ep.BlockNotFoundRetries {
+			ep.recordError("block_not_found")
+			return time.Time{}, fmt.Errorf("%w: block %d %s", ErrBlockNotFound, vLog.BlockNumber, vLog.BlockHash.Hex())
+		}
+		time.Sleep(ep.blockNotFoundDelay)
+	}
+}
+
+// recordError counts an error of the processor when Metrics is set
+func (ep *EventProcessor) recordError(errorType string) {
+	if ep.Metrics != nil {
+		ep.Metrics.IncrementError("blockchain", errorType)
+	}
+}
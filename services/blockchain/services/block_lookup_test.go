@@ -0,0 +1,103 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// laggingChainClient does not know any block for its first misses lookups, like a node
+// that has not imported the block of a log yet
+type laggingChainClient struct {
+	*mockChainClient
+	misses int
+}
+
+func (c *laggingChainClient) BlockByHash(ctx context.Context, hash common.Hash) (*ethtypes.Block, error) {
+	if c.misses > 0 {
+		return nil, ethereum.NotFound
+	}
+	return c.mockChainClient.BlockByHash(ctx, hash)
+}
+
+func (c *laggingChainClient) BlockByNumber(ctx context.Context, number *big.Int) (*ethtypes.Block, error) {
+	if c.misses > 0 {
+		c.misses--
+		return nil, ethereum.NotFound
+	}
+	return c.mockChainClient.BlockByNumber(ctx, number)
+}
+
+func tokenTransferLog(ep *EventProcessor, blockNumber uint64, blockHash common.Hash, value int64) ethtypes.Log {
+	return ethtypes.Log{
+		Address:     common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"),
+		Topics:      []common.Hash{ep.ABI.Events["Transfer"].ID, common.HexToHash("0x1"), common.HexToHash("0x2")},
+		Data:        common.BigToHash(big.NewInt(value)).Bytes(),
+		BlockNumber: blockNumber,
+		BlockHash:   blockHash,
+		TxHash:      common.BigToHash(big.NewInt(value)),
+	}
+}
+
+func TestEventProcessor_SkipsOrphanedLogs(t *testing.T) {
+	first := ethtypes.NewBlockWithHeader(&ethtypes.Header{Number: big.NewInt(150), Time: 1700000000})
+	canonical := ethtypes.NewBlockWithHeader(&ethtypes.Header{Number: big.NewInt(151), Time: 1700000012})
+	orphaned := ethtypes.NewBlockWithHeader(&ethtypes.Header{Number: big.NewInt(151), Time: 1700000013, Extra: []byte("orphaned")})
+	client := &mockChainClient{blocks: map[common.Hash]*ethtypes.Block{first.Hash(): first, canonical.Hash(): canonical}}
+
+	ep, err := NewEventProcessorWithClient(client)
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+	client.logs = []ethtypes.Log{
+		tokenTransferLog(ep, 150, first.Hash(), 1),
+		// The node no longer serves the block of this log, which was replaced at its height
+		tokenTransferLog(ep, 151, orphaned.Hash(), 2),
+		tokenTransferLog(ep, 151, canonical.Hash(), 3),
+	}
+
+	events, err := ep.ProcessTokenTransfers(context.Background(), client.logs[0].Address, big.NewInt(100), big.NewInt(200))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events) != 2 || events[0].Value.Int64() != 1 || events[1].Value.Int64() != 3 {
+		t.Fatalf("Expected the transfers of the canonical blocks, got %+v", events)
+	}
+
+	if _, err := ep.parseTokenTransferEvent(client.logs[1]); !errors.Is(err, ErrOrphanedLog) {
+		t.Errorf("Expected ErrOrphanedLog, got %v", err)
+	}
+}
+
+func TestEventProcessor_RetriesBlockNotFound(t *testing.T) {
+	block := ethtypes.NewBlockWithHeader(&ethtypes.Header{Number: big.NewInt(150), Time: 1700000000})
+	client := &laggingChainClient{mockChainClient: &mockChainClient{blocks: map[common.Hash]*ethtypes.Block{block.Hash(): block}}}
+
+	ep, err := NewEventProcessorWithClient(client)
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+	ep.blockNotFoundDelay = time.Millisecond
+	vLog := tokenTransferLog(ep, 150, block.Hash(), 1)
+
+	// Found once the node catches up within the retries
+	client.misses = ep.BlockNotFoundRetries
+	event, err := ep.parseTokenTransferEvent(vLog)
+	if err != nil {
+		t.Fatalf("Expected the block to be found after retrying, got %v", err)
+	}
+	if event.Timestamp.Unix() != 1700000000 {
+		t.Errorf("Expected timestamp 1700000000, got %d", event.Timestamp.Unix())
+	}
+
+	client.misses = ep.BlockNotFoundRetries + 1
+	if _, err := ep.parseTokenTransferEvent(vLog); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("Expected ErrBlockNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ChainClient is the block source the EventProcessor reads logs and blocks from.
+// *ethclient.Client satisfies it; other implementations can back the processor with a
+// non-Ethereum source or a mock in tests.
+type ChainClient interface {
+	LogSubscriber
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethtypes.Log, error)
+	BlockByHash(ctx context.Context, hash common.Hash) (*ethtypes.Block, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*ethtypes.Block, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+	NetworkID(ctx context.Context) (*big.Int, error)
+}
+
+var _ ChainClient = (*ethclient.Client)(nil)
+
+// ErrChainIDMismatch is returned when the node serves a different chain than configured
+var ErrChainIDMismatch = errors.New("chain id mismatch")
+
+// VerifyChainID checks that the node reports the network id of the configured chain
+// id, so events are never stored under the wrong chain label. It is meant to run at
+// startup, before anything is indexed.
+func (ep *EventProcessor) VerifyChainID(ctx context.Context, chainID string) error {
+	configured, ok := new(big.Int).SetString(chainID, 10)
+	if !ok {
+		return fmt.Errorf("invalid chain id %q: must be a decimal number", chainID)
+	}
+
+	if err := ep.waitRPC(ctx); err != nil {
+		return err
+	}
+	networkID, err := ep.Client.NetworkID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get network id from node: %v", err)
+	}
+
+	if networkID.Cmp(configured) != 0 {
+		return fmt.Errorf("%w: configured chain id %s but node reports network id %s", ErrChainIDMismatch, configured, networkID)
+	}
+	return nil
+}
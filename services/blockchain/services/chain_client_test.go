@@ -0,0 +1,180 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// mockChainClient serves fixed logs and blocks and records the filter queries it receives
+type mockChainClient struct {
+	logs      []ethtypes.Log
+	blocks    map[common.Hash]*ethtypes.Block
+	head      uint64
+	networkID int64
+	storage   map[common.Address]common.Hash // implementation slot of each proxy
+	queries   []ethereum.FilterQuery
+}
+
+func (m *mockChainClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethtypes.Log, error) {
+	m.queries = append(m.queries, q)
+	return m.logs, nil
+}
+
+func (m *mockChainClient) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- ethtypes.Log) (ethereum.Subscription, error) {
+	return nil, fmt.Errorf("subscriptions not supported by mock")
+}
+
+func (m *mockChainClient) BlockByHash(ctx context.Context, hash common.Hash) (*ethtypes.Block, error) {
+	block, ok := m.blocks[hash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return block, nil
+}
+
+func (m *mockChainClient) BlockByNumber(ctx context.Context, number *big.Int) (*ethtypes.Block, error) {
+	for _, block := range m.blocks {
+		if block.Number().Cmp(number) == 0 {
+			return block, nil
+		}
+	}
+	return nil, ethereum.NotFound
+}
+
+func (m *mockChainClient) BlockNumber(ctx context.Context) (uint64, error) {
+	return m.head, nil
+}
+
+func (m *mockChainClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (m *mockChainClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	if key != EIP1967ImplementationSlot {
+		return make([]byte, 32), nil
+	}
+	return m.storage[account].Bytes(), nil
+}
+
+func (m *mockChainClient) NetworkID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(m.networkID), nil
+}
+
+func TestEventProcessor_ProcessNFTTransfersWithMockClient(t *testing.T) {
+	contract := common.HexToAddress("0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D")
+	from := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	to := common.HexToAddress("0x0000000000000000000000000000000000000002")
+
+	block := ethtypes.NewBlockWithHeader(&ethtypes.Header{Number: big.NewInt(150), Time: 1700000000})
+	client := &mockChainClient{blocks: map[common.Hash]*ethtypes.Block{block.Hash(): block}, head: 200}
+
+	ep, err := NewEventProcessorWithClient(client)
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+	ep.blockNotFoundDelay = time.Millisecond
+
+	transferTopic := ep.ABI.Events["Transfer"].ID
+	client.logs = []ethtypes.Log{
+		{
+			Address:     contract,
+			Topics:      []common.Hash{transferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes()), common.BigToHash(big.NewInt(42))},
+			BlockNumber: 150,
+			BlockHash:   block.Hash(),
+			TxHash:      common.HexToHash("0xabc"),
+			Index:       3,
+		},
+		// Its block is unknown, so it is skipped
+		{
+			Address:     contract,
+			Topics:      []common.Hash{transferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes()), common.BigToHash(big.NewInt(43))},
+			BlockNumber: 151,
+			BlockHash:   common.HexToHash("0xunknown"),
+		},
+	}
+
+	events, err := ep.ProcessNFTTransfers(context.Background(), contract, big.NewInt(100), big.NewInt(200))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(client.queries) != 1 {
+		t.Fatalf("Expected 1 filter query, got %d", len(client.queries))
+	}
+	query := client.queries[0]
+	if query.FromBlock.Int64() != 100 || query.ToBlock.Int64() != 200 {
+		t.Errorf("Expected blocks 100 to 200, got %v to %v", query.FromBlock, query.ToBlock)
+	}
+	if len(query.Addresses) != 1 || query.Addresses[0] != contract {
+		t.Errorf("Expected the contract address in the query, got %v", query.Addresses)
+	}
+	if len(query.Topics) != 1 || query.Topics[0][0] != transferTopic {
+		t.Errorf("Expected the Transfer topic in the query, got %v", query.Topics)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	event := events[0]
+	if event.From != from || event.To != to {
+		t.Errorf("Expected transfer from %s to %s, got %s to %s", from.Hex(), to.Hex(), event.From.Hex(), event.To.Hex())
+	}
+	if event.TokenID.Int64() != 42 {
+		t.Errorf("Expected token ID 42, got %s", event.TokenID)
+	}
+	if event.BlockNumber.Int64() != 150 || event.LogIndex != 3 || event.Contract != contract {
+		t.Errorf("Expected block 150, log index 3 and contract %s, got %s, %d and %s", contract.Hex(), event.BlockNumber, event.LogIndex, event.Contract.Hex())
+	}
+	if event.Timestamp.Unix() != 1700000000 {
+		t.Errorf("Expected the block timestamp, got %v", event.Timestamp)
+	}
+}
+
+func TestEventProcessor_GetLatestBlockNumberWithMockClient(t *testing.T) {
+	ep, err := NewEventProcessorWithClient(&mockChainClient{head: 12345})
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+
+	head, err := ep.GetLatestBlockNumber(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if head.Int64() != 12345 {
+		t.Errorf("Expected block 12345, got %d", head)
+	}
+
+	// Mempool subscriptions need an Ethereum node
+	ep.PendingTxEnabled = true
+	if _, _, err := ep.SubscribeToPendingTransactions(context.Background(), PendingTxOptions{}); err != ErrPendingTxDisabled {
+		t.Errorf("Expected ErrPendingTxDisabled, got %v", err)
+	}
+}
+
+func TestEventProcessor_VerifyChainID(t *testing.T) {
+	ep, err := NewEventProcessorWithClient(&mockChainClient{networkID: 137})
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+
+	if err := ep.VerifyChainID(context.Background(), "137"); err != nil {
+		t.Errorf("Expected matching chain id to pass, got %v", err)
+	}
+
+	err = ep.VerifyChainID(context.Background(), "1")
+	if !errors.Is(err, ErrChainIDMismatch) {
+		t.Errorf("Expected ErrChainIDMismatch, got %v", err)
+	}
+
+	if err := ep.VerifyChainID(context.Background(), "polygon"); err == nil || errors.Is(err, ErrChainIDMismatch) {
+		t.Errorf("Expected an invalid chain id error, got %v", err)
+	}
+}
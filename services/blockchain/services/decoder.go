@@ -0,0 +1,140 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// DecodeEventParams decodes the parameters of a log emitted by event into a map keyed by the
+// ABI input names. Indexed parameters are read from the topics and non-indexed ones from the data.
+// Indexed dynamic types (string, bytes, arrays) only have their keccak256 hash in the topic,
+// so the hash is returned for them. Values are converted to JSON-friendly forms: integers wider
+// than 64 bits as decimal strings, addresses, hashes and byte values as 0x-prefixed hex.
+func DecodeEventParams(event *abi.Event, vLog ethtypes.Log) (map[string]interface{}, error) {
+	topics := vLog.Topics
+	if !event.Anonymous {
+		if len(topics) == 0 || topics[0] != event.ID {
+			return nil, fmt.Errorf("log is not a %s event", event.Name)
+		}
+		topics = topics[1:]
+	}
+
+	var indexed abi.Arguments
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+
+	params := make(map[string]interface{}, len(event.Inputs))
+	if err := abi.ParseTopicsIntoMap(params, indexed, topics); err != nil {
+		return nil, fmt.Errorf("failed to decode indexed parameters of %s: %v", event.Name, err)
+	}
+
+	if len(vLog.Data) > 0 {
+		if err := event.Inputs.NonIndexed().UnpackIntoMap(params, vLog.Data); err != nil {
+			return nil, fmt.Errorf("failed to decode parameters of %s: %v", event.Name, err)
+		}
+	}
+
+	for name, value := range params {
+		params[name] = normalizeABIValue(reflect.ValueOf(value))
+	}
+
+	return params, nil
+}
+
+// DecodeLog finds the ABI event matching the log signature and decodes its parameters
+func (ep *EventProcessor) DecodeLog(vLog ethtypes.Log) (string, map[string]interface{}, error) {
+	if len(vLog.Topics) == 0 {
+		return "", nil, fmt.Errorf("log has no topics")
+	}
+
+	event, err := ep.eventByID(vLog.Address, vLog.Topics[0])
+	if err != nil {
+		return "", nil, err
+	}
+
+	params, err := DecodeEventParams(event, vLog)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return event.Name, params, nil
+}
+
+// eventByID finds the event with the signature hash topic0 in the ABI registered for
+// contract, falling back to the processor ABI
+func (ep *EventProcessor) eventByID(contract common.Address, topic0 common.Hash) (*abi.Event, error) {
+	if ep.ContractABIs != nil {
+		if contractABI, ok := ep.ContractABIs.ABIFor(contract); ok {
+			if event, err := contractABI.EventByID(topic0); err == nil {
+				return event, nil
+			}
+		}
+	}
+	return ep.ABI.EventByID(topic0)
+}
+
+// normalizeABIValue converts a value unpacked by the abi package into a form that
+// survives a JSON round trip unchanged
+func normalizeABIValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch value := v.Interface().(type) {
+	case *big.Int:
+		if value == nil {
+			return nil
+		}
+		return value.String()
+	case common.Address:
+		return value.Hex()
+	case common.Hash:
+		return value.Hex()
+	case []byte:
+		return hexutil.Encode(value)
+	}
+
+	switch v.Kind() {
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return hexutil.Encode(b)
+		}
+		fallthrough
+	case reflect.Slice:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = normalizeABIValue(v.Index(i))
+		}
+		return items
+	case reflect.Struct:
+		// Tuples are unpacked into structs whose json tags hold the ABI component names
+		fields := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			name := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+			if name == "" {
+				name = v.Type().Field(i).Name
+			}
+			fields[name] = normalizeABIValue(v.Field(i))
+		}
+		return fields
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return normalizeABIValue(v.Elem())
+	}
+
+	return v.Interface()
+}
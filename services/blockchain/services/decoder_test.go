@@ -0,0 +1,189 @@
+package blockchain
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const decoderTestABI = `[
+	{
+		"anonymous": false,
+		"inputs": [
+			{"indexed": true, "name": "from", "type": "address"},
+			{"indexed": true, "name": "to", "type": "address"},
+			{"indexed": false, "name": "value", "type": "uint256"}
+		],
+		"name": "Transfer",
+		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{"indexed": true, "name": "seller", "type": "address"},
+			{"indexed": true, "name": "label", "type": "string"},
+			{"indexed": false, "name": "tokenIds", "type": "uint256[]"},
+			{"indexed": false, "name": "note", "type": "string"},
+			{"indexed": false, "name": "payload", "type": "bytes"},
+			{"indexed": false, "name": "active", "type": "bool"}
+		],
+		"name": "Listed",
+		"type": "event"
+	}
+]`
+
+func newDecoderTestProcessor(t *testing.T) *EventProcessor {
+	parsedABI, err := abi.JSON(strings.NewReader(decoderTestABI))
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	return &EventProcessor{ABI: parsedABI}
+}
+
+func TestDecodeLog_Transfer(t *testing.T) {
+	ep := newDecoderTestProcessor(t)
+
+	from := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	to := common.HexToAddress("0x0000000000000000000000000000000000000002")
+	value, _ := new(big.Int).SetString("1000000000000000000000", 10)
+
+	data, err := ep.ABI.Events["Transfer"].Inputs.NonIndexed().Pack(value)
+	if err != nil {
+		t.Fatalf("Failed to pack data: %v", err)
+	}
+
+	name, params, err := ep.DecodeLog(ethtypes.Log{
+		Topics: []common.Hash{
+			ep.ABI.Events["Transfer"].ID,
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+		},
+		Data: data,
+	})
+	if err != nil {
+		t.Fatalf("Failed to decode log: %v", err)
+	}
+
+	if name != "Transfer" {
+		t.Errorf("Expected Transfer, got %s", name)
+	}
+	if params["from"] != from.Hex() {
+		t.Errorf("Expected from %s, got %v", from.Hex(), params["from"])
+	}
+	if params["to"] != to.Hex() {
+		t.Errorf("Expected to %s, got %v", to.Hex(), params["to"])
+	}
+	if params["value"] != "1000000000000000000000" {
+		t.Errorf("Expected value 1000000000000000000000, got %v", params["value"])
+	}
+}
+
+func TestDecodeLog_CustomEventWithDynamicTypes(t *testing.T) {
+	ep := newDecoderTestProcessor(t)
+	event := ep.ABI.Events["Listed"]
+
+	seller := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	data, err := event.Inputs.NonIndexed().Pack(
+		[]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
+		"first listing",
+		[]byte{0xde, 0xad, 0xbe, 0xef},
+		true,
+	)
+	if err != nil {
+		t.Fatalf("Failed to pack data: %v", err)
+	}
+
+	labelHash := crypto.Keccak256Hash([]byte("featured"))
+	name, params, err := ep.DecodeLog(ethtypes.Log{
+		Topics: []common.Hash{event.ID, common.BytesToHash(seller.Bytes()), labelHash},
+		Data:   data,
+	})
+	if err != nil {
+		t.Fatalf("Failed to decode log: %v", err)
+	}
+
+	if name != "Listed" {
+		t.Errorf("Expected Listed, got %s", name)
+	}
+	if len(params) != 6 {
+		t.Errorf("Expected 6 parameters, got %d: %v", len(params), params)
+	}
+	if params["seller"] != seller.Hex() {
+		t.Errorf("Expected seller %s, got %v", seller.Hex(), params["seller"])
+	}
+	// Indexed strings only carry their hash
+	if params["label"] != labelHash.Hex() {
+		t.Errorf("Expected label hash %s, got %v", labelHash.Hex(), params["label"])
+	}
+
+	tokenIDs, ok := params["tokenIds"].([]interface{})
+	if !ok || len(tokenIDs) != 3 || tokenIDs[0] != "1" || tokenIDs[2] != "3" {
+		t.Errorf("Expected tokenIds [1 2 3], got %v", params["tokenIds"])
+	}
+	if params["note"] != "first listing" {
+		t.Errorf("Expected note 'first listing', got %v", params["note"])
+	}
+	if params["payload"] != "0xdeadbeef" {
+		t.Errorf("Expected payload 0xdeadbeef, got %v", params["payload"])
+	}
+	if params["active"] != true {
+		t.Errorf("Expected active true, got %v", params["active"])
+	}
+}
+
+func TestDecodeLog_UnknownEvent(t *testing.T) {
+	ep := newDecoderTestProcessor(t)
+
+	_, _, err := ep.DecodeLog(ethtypes.Log{Topics: []common.Hash{crypto.Keccak256Hash([]byte("Unknown()"))}})
+	if err == nil {
+		t.Error("Expected error for an event not in the ABI")
+	}
+}
+
+func TestConvertTransferLog_PopulatesData(t *testing.T) {
+	from := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	to := common.HexToAddress("0x0000000000000000000000000000000000000002")
+	block := ethtypes.NewBlockWithHeader(&ethtypes.Header{Number: big.NewInt(10), Time: 1700000000})
+
+	ep, err := NewEventProcessorWithClient(&mockChainClient{blocks: map[common.Hash]*ethtypes.Block{block.Hash(): block}})
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+	transferID := ep.ABI.Events["Transfer"].ID
+
+	// Token transfers decode with the processor ABI
+	data, err := ep.ABI.Events["Transfer"].Inputs.NonIndexed().Pack(big.NewInt(500))
+	if err != nil {
+		t.Fatalf("Failed to pack data: %v", err)
+	}
+	token, err := ep.ConvertTransferLog(ethtypes.Log{
+		Topics:      []common.Hash{transferID, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:        data,
+		BlockNumber: 10,
+		BlockHash:   block.Hash(),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if token.Data["from"] != from.Hex() || token.Data["to"] != to.Hex() || token.Data["value"] != "500" {
+		t.Errorf("Expected the decoded token transfer, got %v", token.Data)
+	}
+
+	// NFT transfers index the token ID, which the ERC-20 ABI doesn't decode
+	nft, err := ep.ConvertTransferLog(ethtypes.Log{
+		Topics:      []common.Hash{transferID, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes()), common.BigToHash(big.NewInt(7))},
+		BlockNumber: 10,
+		BlockHash:   block.Hash(),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if nft.Data["from"] != from.Hex() || nft.Data["to"] != to.Hex() || nft.Data["tokenId"] != "7" {
+		t.Errorf("Expected the decoded NFT transfer, got %v", nft.Data)
+	}
+}
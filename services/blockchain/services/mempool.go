@@ -0,0 +1,165 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+)
+
+// pendingTxBuffer absorbs bursts of pending transaction hashes from the node
+const pendingTxBuffer = 1024
+
+// ErrPendingTxDisabled is returned when subscribing to pending transactions on a processor
+// that does not have them enabled
+var ErrPendingTxDisabled = errors.New("pending transaction subscription is disabled")
+
+// PendingTxSource is the subset of the node client used for mempool subscriptions
+type PendingTxSource interface {
+	SubscribePendingTransactions(ctx context.Context, ch chan<- common.Hash) (ethereum.Subscription, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (*ethtypes.Transaction, bool, error)
+}
+
+// nodePendingTxSource subscribes through the geth-specific newPendingTransactions
+// subscription and fetches transactions through the standard client
+type nodePendingTxSource struct {
+	geth *gethclient.Client
+	eth  *ethclient.Client
+}
+
+func (s *nodePendingTxSource) SubscribePendingTransactions(ctx context.Context, ch chan<- common.Hash) (ethereum.Subscription, error) {
+	return s.geth.SubscribePendingTransactions(ctx, ch)
+}
+
+func (s *nodePendingTxSource) TransactionByHash(ctx context.Context, hash common.Hash) (*ethtypes.Transaction, bool, error) {
+	return s.eth.TransactionByHash(ctx, hash)
+}
+
+// PendingTxOptions narrows a pending transaction subscription, which can deliver
+// thousands of hashes per second on mainnet
+type PendingTxOptions struct {
+	// SampleRate is the fraction of pending transactions delivered, between 0 and 1.
+	// Sampling is by hash, so every subscriber samples the same transactions.
+	// Values <= 0 or >= 1 deliver all of them.
+	SampleRate float64
+	// FetchTransactions fetches the full transaction of each delivered hash
+	FetchTransactions bool
+	// To only delivers transactions sent to one of these addresses. It implies FetchTransactions.
+	To []common.Address
+}
+
+// sampled reports whether hash falls within the sample rate
+func (o PendingTxOptions) sampled(hash common.Hash) bool {
+	if o.SampleRate <= 0 || o.SampleRate >= 1 {
+		return true
+	}
+	return float64(binary.BigEndian.Uint64(hash[:8]))/math.MaxUint64 < o.SampleRate
+}
+
+// matches reports whether tx passes the recipient filter
+func (o PendingTxOptions) matches(tx *ethtypes.Transaction) bool {
+	if len(o.To) == 0 {
+		return true
+	}
+	if tx.To() == nil {
+		return false
+	}
+	for _, address := range o.To {
+		if *tx.To() == address {
+			return true
+		}
+	}
+	return false
+}
+
+// PendingTransaction is a transaction seen in the node's mempool
+type PendingTransaction struct {
+	Hash common.Hash
+	// Tx is the full transaction, nil unless it was fetched
+	Tx *ethtypes.Transaction
+}
+
+// SubscribeToPendingTransactions subscribes to transactions entering the node's mempool.
+// Not every node or provider supports the newPendingTransactions subscription, so it
+// fails with ErrPendingTxDisabled unless PendingTxEnabled is set. Both channels are
+// closed once ctx is cancelled or the subscription fails.
+func (ep *EventProcessor) SubscribeToPendingTransactions(ctx context.Context, opts PendingTxOptions) (<-chan *PendingTransaction, <-chan error, error) {
+	if !ep.PendingTxEnabled || ep.pendingTxSource == nil {
+		return nil, nil, ErrPendingTxDisabled
+	}
+	source := ep.pendingTxSource
+	if ep.RPCLimiter != nil {
+		source = &rateLimitedPendingTxSource{PendingTxSource: source, limiter: ep.RPCLimiter}
+	}
+	return subscribePendingTransactions(ctx, source, opts)
+}
+
+func subscribePendingTransactions(ctx context.Context, source PendingTxSource, opts PendingTxOptions) (<-chan *PendingTransaction, <-chan error, error) {
+	hashes := make(chan common.Hash, pendingTxBuffer)
+	sub, err := source.SubscribePendingTransactions(ctx, hashes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to subscribe to pending transactions: %v", err)
+	}
+
+	fetch := opts.FetchTransactions || len(opts.To) > 0
+	txChan := make(chan *PendingTransaction)
+	errChan := make(chan error)
+
+	go func() {
+		defer close(txChan)
+		defer close(errChan)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case hash := <-hashes:
+				if !opts.sampled(hash) {
+					continue
+				}
+
+				pending := &PendingTransaction{Hash: hash}
+				if fetch {
+					tx, _, err := source.TransactionByHash(ctx, hash)
+					if err != nil {
+						// Pending transactions are often replaced or mined before they can be fetched
+						select {
+						case errChan <- fmt.Errorf("error fetching pending transaction %s: %v", hash.Hex(), err):
+						case <-ctx.Done():
+							return
+						}
+						continue
+					}
+					if !opts.matches(tx) {
+						continue
+					}
+					pending.Tx = tx
+				}
+
+				select {
+				case txChan <- pending:
+				case <-ctx.Done():
+					return
+				}
+			case err := <-sub.Err():
+				if err != nil {
+					select {
+					case errChan <- fmt.Errorf("pending transaction subscription error: %v", err):
+					case <-ctx.Done():
+					}
+				}
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return txChan, errChan, nil
+}
@@ -0,0 +1,176 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// mockPendingTxSource delivers the given hashes once subscribed and serves txs by hash
+type mockPendingTxSource struct {
+	hashes []common.Hash
+	txs    map[common.Hash]*ethtypes.Transaction
+	sub    *mockSubscription
+}
+
+func (m *mockPendingTxSource) SubscribePendingTransactions(ctx context.Context, ch chan<- common.Hash) (ethereum.Subscription, error) {
+	m.sub = &mockSubscription{errCh: make(chan error, 1)}
+	go func() {
+		for _, hash := range m.hashes {
+			ch <- hash
+		}
+	}()
+	return m.sub, nil
+}
+
+func (m *mockPendingTxSource) TransactionByHash(ctx context.Context, hash common.Hash) (*ethtypes.Transaction, bool, error) {
+	tx, ok := m.txs[hash]
+	if !ok {
+		return nil, false, ethereum.NotFound
+	}
+	return tx, true, nil
+}
+
+// receivePending reads n pending transactions, failing the test if they do not arrive
+func receivePending(t *testing.T, ch <-chan *PendingTransaction, n int) []*PendingTransaction {
+	var received []*PendingTransaction
+	for len(received) < n {
+		select {
+		case pending := <-ch:
+			received = append(received, pending)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected %d pending transactions, got %d", n, len(received))
+		}
+	}
+	return received
+}
+
+// expectNoPending fails the test if another pending transaction is delivered
+func expectNoPending(t *testing.T, ch <-chan *PendingTransaction) {
+	select {
+	case pending := <-ch:
+		t.Fatalf("Expected no more pending transactions, got %s", pending.Hash.Hex())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscribePendingTransactions(t *testing.T) {
+	hashes := []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x03")}
+	source := &mockPendingTxSource{hashes: hashes}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	txs, _, err := subscribePendingTransactions(ctx, source, PendingTxOptions{})
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	for i, pending := range receivePending(t, txs, len(hashes)) {
+		if pending.Hash != hashes[i] {
+			t.Errorf("Expected hash %s, got %s", hashes[i].Hex(), pending.Hash.Hex())
+		}
+		if pending.Tx != nil {
+			t.Errorf("Expected no transaction to be fetched for %s", pending.Hash.Hex())
+		}
+	}
+
+	// Cancelling closes the channel and unsubscribes
+	cancel()
+	for range txs {
+	}
+	select {
+	case <-source.sub.Err():
+	default:
+		t.Error("Expected the subscription to be unsubscribed")
+	}
+}
+
+func TestSubscribePendingTransactionsSampling(t *testing.T) {
+	// Hashes starting with 0x00.. fall within a 50% sample, 0xff.. do not
+	sampled := common.HexToHash("0x0011000000000000000000000000000000000000000000000000000000000000")
+	skipped := common.HexToHash("0xff11000000000000000000000000000000000000000000000000000000000000")
+	source := &mockPendingTxSource{hashes: []common.Hash{skipped, sampled, skipped}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	txs, _, err := subscribePendingTransactions(ctx, source, PendingTxOptions{SampleRate: 0.5})
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	received := receivePending(t, txs, 1)
+	if received[0].Hash != sampled {
+		t.Errorf("Expected hash %s, got %s", sampled.Hex(), received[0].Hash.Hex())
+	}
+	expectNoPending(t, txs)
+}
+
+func TestSubscribePendingTransactionsFilterByRecipient(t *testing.T) {
+	watched := common.HexToAddress("0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D")
+	other := common.HexToAddress("0x60E4d786628Fea6478F785A6d7e704777c86a7c6")
+
+	toWatched := ethtypes.NewTransaction(1, watched, big.NewInt(1), 21000, big.NewInt(1), nil)
+	toOther := ethtypes.NewTransaction(2, other, big.NewInt(1), 21000, big.NewInt(1), nil)
+	creation := ethtypes.NewContractCreation(3, big.NewInt(0), 100000, big.NewInt(1), nil)
+	dropped := common.HexToHash("0xdead")
+
+	source := &mockPendingTxSource{
+		hashes: []common.Hash{toOther.Hash(), dropped, creation.Hash(), toWatched.Hash()},
+		txs: map[common.Hash]*ethtypes.Transaction{
+			toWatched.Hash(): toWatched,
+			toOther.Hash():   toOther,
+			creation.Hash():  creation,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	txs, errs, err := subscribePendingTransactions(ctx, source, PendingTxOptions{To: []common.Address{watched}})
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	// The dropped transaction cannot be fetched and is reported
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("Expected a fetch error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a fetch error for the dropped transaction")
+	}
+
+	received := receivePending(t, txs, 1)
+	if received[0].Hash != toWatched.Hash() {
+		t.Errorf("Expected hash %s, got %s", toWatched.Hash().Hex(), received[0].Hash.Hex())
+	}
+	if received[0].Tx == nil || *received[0].Tx.To() != watched {
+		t.Errorf("Expected the fetched transaction to %s", watched.Hex())
+	}
+	expectNoPending(t, txs)
+}
+
+func TestSubscribeToPendingTransactionsDisabled(t *testing.T) {
+	ep := &EventProcessor{pendingTxSource: &mockPendingTxSource{}}
+
+	_, _, err := ep.SubscribeToPendingTransactions(context.Background(), PendingTxOptions{})
+	if !errors.Is(err, ErrPendingTxDisabled) {
+		t.Fatalf("Expected ErrPendingTxDisabled, got %v", err)
+	}
+
+	ep.PendingTxEnabled = true
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, _, err := ep.SubscribeToPendingTransactions(ctx, PendingTxOptions{}); err != nil {
+		t.Fatalf("Expected the subscription to succeed once enabled, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+
+	"chainpulse/shared/types"
+
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// ERC20 and ERC721 share the Transfer(address,address,uint256) signature. ERC721 indexes
+// the token ID, so its logs carry one more topic than ERC20 logs, which keep the value in
+// the data.
+const (
+	tokenTransferTopics = 3
+	nftTransferTopics   = 4
+)
+
+// transferLogParser converts a Transfer log into an indexed event
+type transferLogParser func(vLog ethtypes.Log) (*types.IndexedEvent, error)
+
+// multiplexTransferLogs converts every Transfer log received from logs into exactly one
+// indexed event, parsing it with parseNFT or parseToken depending on its topic count.
+// When parseApproval is set, Approval and ApprovalForAll logs, classified by their first
+// topic, are parsed with it. Subscription errors are forwarded. Both returned channels
+// are closed once logs is closed or ctx is done.
+func multiplexTransferLogs(ctx context.Context, logs <-chan ethtypes.Log, subErrs <-chan error, parseNFT, parseToken, parseApproval transferLogParser) (<-chan *types.IndexedEvent, <-chan error) {
+	eventChan := make(chan *types.IndexedEvent)
+	errChan := make(chan error)
+
+	go func() {
+		defer close(eventChan)
+		defer close(errChan)
+
+		sendErr := func(err error) bool {
+			select {
+			case errChan <- err:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			select {
+			case vLog, ok := <-logs:
+				if !ok {
+					return
+				}
+
+				var event *types.IndexedEvent
+				var err error
+				switch {
+				case parseApproval != nil && isApprovalLog(vLog):
+					event, err = parseApproval(vLog)
+				case len(vLog.Topics) == nftTransferTopics:
+					if event, err = parseNFT(vLog); err != nil {
+						err = fmt.Errorf("error parsing NFT transfer event: %v", err)
+					}
+				case len(vLog.Topics) == tokenTransferTopics:
+					if event, err = parseToken(vLog); err != nil {
+						err = fmt.Errorf("error parsing token transfer event: %v", err)
+					}
+				default:
+					err = fmt.Errorf("unexpected Transfer log with %d topics in tx %s", len(vLog.Topics), vLog.TxHash.Hex())
+				}
+
+				if err != nil {
+					if !sendErr(err) {
+						return
+					}
+					continue
+				}
+
+				select {
+				case eventChan <- event:
+				case <-ctx.Done():
+					return
+				}
+			case err, ok := <-subErrs:
+				if !ok {
+					// Keep draining logs; a nil channel is never selected
+					subErrs = nil
+					continue
+				}
+				// Failed shards resubscribe on their own, so keep the stream open
+				if !sendErr(err) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return eventChan, errChan
+}
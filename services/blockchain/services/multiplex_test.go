@@ -0,0 +1,132 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var transferTopic = crypto.Keccak256Hash([]byte(TokenTransferEventSignature))
+
+// countingParsers records how often each parser is called
+type countingParsers struct {
+	nft, token int
+}
+
+func (p *countingParsers) parseNFT(vLog ethtypes.Log) (*types.IndexedEvent, error) {
+	p.nft++
+	return &types.IndexedEvent{TxHash: vLog.TxHash.Hex(), LogIndex: vLog.Index, EventName: "NFTTransfer"}, nil
+}
+
+func (p *countingParsers) parseToken(vLog ethtypes.Log) (*types.IndexedEvent, error) {
+	p.token++
+	return &types.IndexedEvent{TxHash: vLog.TxHash.Hex(), LogIndex: vLog.Index, EventName: "TokenTransfer"}, nil
+}
+
+func TestMultiplexTransferLogs_OneEventPerLog(t *testing.T) {
+	contract := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	subscriber := &mockLogSubscriber{}
+	subscription := &ShardedLogSubscription{Subscriber: subscriber, MaxAddresses: 100}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	query := ethereum.FilterQuery{Addresses: []common.Address{contract}, Topics: [][]common.Hash{{transferTopic}}}
+	logs, subErrs, err := subscription.Subscribe(ctx, query)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if len(subscriber.queries) != 1 {
+		t.Fatalf("Expected 1 subscription, got %d", len(subscriber.queries))
+	}
+
+	parsers := &countingParsers{}
+	events, _ := multiplexTransferLogs(ctx, logs, subErrs, parsers.parseNFT, parsers.parseToken, nil)
+
+	from := common.BytesToHash(common.HexToAddress("0x1").Bytes())
+	to := common.BytesToHash(common.HexToAddress("0x2").Bytes())
+	tokenLog := ethtypes.Log{
+		Address: contract,
+		Topics:  []common.Hash{transferTopic, from, to},
+		Data:    common.LeftPadBytes(big.NewInt(1000).Bytes(), 32),
+		TxHash:  common.HexToHash("0xaa"),
+		Index:   3,
+	}
+	go func() { subscriber.sinks[0] <- tokenLog }()
+
+	select {
+	case event := <-events:
+		if event.EventName != "TokenTransfer" || event.TxHash != tokenLog.TxHash.Hex() || event.LogIndex != 3 {
+			t.Errorf("Unexpected event %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an indexed event")
+	}
+
+	select {
+	case event := <-events:
+		t.Fatalf("Expected exactly one indexed event, got another: %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if parsers.token != 1 || parsers.nft != 0 {
+		t.Errorf("Expected 1 token parse and 0 NFT parses, got %d and %d", parsers.token, parsers.nft)
+	}
+}
+
+func TestMultiplexTransferLogs_ClassifiesByTopicCount(t *testing.T) {
+	logs := make(chan ethtypes.Log, 3)
+	subErrs := make(chan error)
+	close(subErrs)
+
+	from := common.BytesToHash(common.HexToAddress("0x1").Bytes())
+	to := common.BytesToHash(common.HexToAddress("0x2").Bytes())
+	logs <- ethtypes.Log{Topics: []common.Hash{transferTopic, from, to, common.BigToHash(big.NewInt(7))}, Index: 0}
+	logs <- ethtypes.Log{Topics: []common.Hash{transferTopic, from, to}, Index: 1}
+	logs <- ethtypes.Log{Topics: []common.Hash{transferTopic}, Index: 2}
+	close(logs)
+
+	parsers := &countingParsers{}
+	events, errs := multiplexTransferLogs(context.Background(), logs, subErrs, parsers.parseNFT, parsers.parseToken, nil)
+
+	var received []*types.IndexedEvent
+	var failures []error
+	timeout := time.After(time.Second)
+	// Both channels must close once logs is drained, even though subErrs closed first
+	for events != nil || errs != nil {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			received = append(received, event)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			failures = append(failures, err)
+		case <-timeout:
+			t.Fatal("Expected the output channels to close")
+		}
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(received))
+	}
+	if received[0].EventName != "NFTTransfer" || received[1].EventName != "TokenTransfer" {
+		t.Errorf("Expected NFTTransfer then TokenTransfer, got %s and %s", received[0].EventName, received[1].EventName)
+	}
+	if len(failures) != 1 {
+		t.Errorf("Expected 1 error for the malformed log, got %d", len(failures))
+	}
+}
@@ -0,0 +1,232 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"time"
+
+	"chainpulse/shared/types"
+)
+
+// DefaultOrderingDepth is how many blocks events are held back by default: the events of a
+// block are released once an event of the next block arrives
+const DefaultOrderingDepth = 1
+
+// DefaultOrderingMaxHold is how long an event is held back at most by default. Without it
+// the events of the newest block would wait for the next event, which on a quiet contract
+// may take hours.
+const DefaultOrderingMaxHold = 5 * time.Second
+
+// eventPosition returns the block number and log index an event is ordered by
+type eventPosition[T any] func(event T) (*big.Int, uint)
+
+// heldEvent is a buffered event and when it arrived
+type heldEvent[T any] struct {
+	event   T
+	arrived time.Time
+}
+
+// eventOrderingBuffer holds events and releases them in canonical (block number, log
+// index) order. The shards of a subscription deliver their logs independently, so the
+// events of one block can arrive interleaved with those of the next. Events are only
+// released up to the frontier, depth blocks below the highest block seen, since events of
+// blocks above it may still be on their way.
+type eventOrderingBuffer[T any] struct {
+	depth    int64
+	position eventPosition[T]
+	highest  *big.Int
+	pending  []heldEvent[T]
+	now      func() time.Time
+}
+
+// newEventOrderingBuffer creates an ordering buffer of indexed events holding back depth blocks
+func newEventOrderingBuffer(depth int) *eventOrderingBuffer[*types.IndexedEvent] {
+	return newOrderingBuffer(depth, indexedEventPosition)
+}
+
+// newOrderingBuffer creates an ordering buffer holding back depth blocks of events ordered
+// by position
+func newOrderingBuffer[T any](depth int, position eventPosition[T]) *eventOrderingBuffer[T] {
+	return &eventOrderingBuffer[T]{depth: int64(depth), position: position, now: time.Now}
+}
+
+// Push adds event to the buffer and returns the events that became ready, in order
+func (b *eventOrderingBuffer[T]) Push(event T) []T {
+	b.pending = append(b.pending, heldEvent[T]{event: event, arrived: b.now()})
+	if block, _ := b.position(event); block != nil && (b.highest == nil || block.Cmp(b.highest) > 0) {
+		b.highest = new(big.Int).Set(block)
+	}
+	if b.highest == nil {
+		return b.Flush()
+	}
+
+	frontier := new(big.Int).Sub(b.highest, big.NewInt(b.depth))
+	return b.releaseThrough(frontier)
+}
+
+// Expire releases, in order, the events held for maxHold or longer along with every
+// event before them and the other events of their blocks
+func (b *eventOrderingBuffer[T]) Expire(maxHold time.Duration) []T {
+	var frontier *big.Int
+	deadline := b.now().Add(-maxHold)
+	for _, held := range b.pending {
+		if held.arrived.After(deadline) {
+			continue
+		}
+		block, _ := b.position(held.event)
+		if block == nil {
+			block = big.NewInt(-1)
+		}
+		if frontier == nil || block.Cmp(frontier) > 0 {
+			frontier = block
+		}
+	}
+	if frontier == nil {
+		return nil
+	}
+	return b.releaseThrough(frontier)
+}
+
+// Oldest returns when the longest held event arrived, false if the buffer is empty
+func (b *eventOrderingBuffer[T]) Oldest() (time.Time, bool) {
+	if len(b.pending) == 0 {
+		return time.Time{}, false
+	}
+	oldest := b.pending[0].arrived
+	for _, held := range b.pending[1:] {
+		if held.arrived.Before(oldest) {
+			oldest = held.arrived
+		}
+	}
+	return oldest, true
+}
+
+// Flush returns every buffered event, in order
+func (b *eventOrderingBuffer[T]) Flush() []T {
+	b.sort()
+	return b.take(len(b.pending))
+}
+
+// Len returns the number of buffered events
+func (b *eventOrderingBuffer[T]) Len() int {
+	return len(b.pending)
+}
+
+// releaseThrough returns the buffered events up to frontier, in order
+func (b *eventOrderingBuffer[T]) releaseThrough(frontier *big.Int) []T {
+	b.sort()
+	ready := 0
+	for ready < len(b.pending) {
+		if block, _ := b.position(b.pending[ready].event); block != nil && block.Cmp(frontier) > 0 {
+			break
+		}
+		ready++
+	}
+	return b.take(ready)
+}
+
+func (b *eventOrderingBuffer[T]) sort() {
+	sort.SliceStable(b.pending, func(i, j int) bool {
+		blockI, logIndexI := b.position(b.pending[i].event)
+		blockJ, logIndexJ := b.position(b.pending[j].event)
+		return positionBefore(blockI, logIndexI, blockJ, logIndexJ)
+	})
+}
+
+func (b *eventOrderingBuffer[T]) take(n int) []T {
+	if n == 0 {
+		return nil
+	}
+	ready := make([]T, n)
+	for i := range ready {
+		ready[i] = b.pending[i].event
+	}
+	b.pending = append(b.pending[:0], b.pending[n:]...)
+	return ready
+}
+
+// positionBefore reports whether position a comes before b in canonical order. Events
+// without a block number sort first, as there is nothing to order them by.
+func positionBefore(blockA *big.Int, logIndexA uint, blockB *big.Int, logIndexB uint) bool {
+	if blockA == nil || blockB == nil {
+		return blockA == nil && blockB != nil
+	}
+	if cmp := blockA.Cmp(blockB); cmp != 0 {
+		return cmp < 0
+	}
+	return logIndexA < logIndexB
+}
+
+func indexedEventPosition(event *types.IndexedEvent) (*big.Int, uint) {
+	return event.BlockNumber, event.LogIndex
+}
+
+func nftEventPosition(event *types.NFTTransferEvent) (*big.Int, uint) {
+	return event.BlockNumber, event.LogIndex
+}
+
+func tokenEventPosition(event *types.TokenTransferEvent) (*big.Int, uint) {
+	return event.BlockNumber, event.LogIndex
+}
+
+// orderEvents releases the events received from events in canonical order, holding back
+// the last depth blocks, and no event for longer than maxHold unless maxHold <= 0.
+// Buffered events are flushed once events is closed. depth <= 0 returns events as is.
+// The returned channel is closed once events is closed or ctx is done.
+func orderEvents[T any](ctx context.Context, events <-chan T, depth int, maxHold time.Duration, position eventPosition[T]) <-chan T {
+	if depth <= 0 {
+		return events
+	}
+
+	ordered := make(chan T)
+	go func() {
+		defer close(ordered)
+
+		buffer := newOrderingBuffer(depth, position)
+		send := func(ready []T) bool {
+			for _, event := range ready {
+				select {
+				case ordered <- event:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		defer timer.Stop()
+		// armTimer fires the timer when the longest held event is due for release
+		armTimer := func() {
+			timer.Stop()
+			if oldest, ok := buffer.Oldest(); ok && maxHold > 0 {
+				timer.Reset(time.Until(oldest.Add(maxHold)))
+			}
+		}
+
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					send(buffer.Flush())
+					return
+				}
+				if !send(buffer.Push(event)) {
+					return
+				}
+				armTimer()
+			case <-timer.C:
+				if !send(buffer.Expire(maxHold)) {
+					return
+				}
+				armTimer()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ordered
+}
@@ -0,0 +1,147 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"chainpulse/shared/types"
+)
+
+func orderedEvent(block int64, logIndex uint) *types.IndexedEvent {
+	return &types.IndexedEvent{BlockNumber: big.NewInt(block), LogIndex: logIndex}
+}
+
+func eventPositions(events []*types.IndexedEvent) string {
+	positions := ""
+	for _, event := range events {
+		positions += fmt.Sprintf("%s:%d ", event.BlockNumber, event.LogIndex)
+	}
+	return positions
+}
+
+func TestEventOrderingBuffer_ReleasesUpToFrontier(t *testing.T) {
+	buffer := newEventOrderingBuffer(1)
+
+	if ready := buffer.Push(orderedEvent(10, 2)); len(ready) != 0 {
+		t.Errorf("Expected no events before the next block, got %s", eventPositions(ready))
+	}
+	if ready := buffer.Push(orderedEvent(10, 0)); len(ready) != 0 {
+		t.Errorf("Expected no events before the next block, got %s", eventPositions(ready))
+	}
+
+	// An event of block 11 moves the frontier to block 10
+	ready := buffer.Push(orderedEvent(11, 1))
+	if got := eventPositions(ready); got != "10:0 10:2 " {
+		t.Errorf("Expected 10:0 10:2 , got %s", got)
+	}
+	if buffer.Len() != 1 {
+		t.Errorf("Expected 1 buffered event, got %d", buffer.Len())
+	}
+
+	if got := eventPositions(buffer.Flush()); got != "11:1 " {
+		t.Errorf("Expected 11:1 , got %s", got)
+	}
+}
+
+func TestOrderEvents_EmitsOutOfOrderArrivalsSorted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// NFT and token shards interleave the logs of blocks 5 to 7
+	arrivals := []*types.IndexedEvent{
+		orderedEvent(6, 3),
+		orderedEvent(5, 1),
+		orderedEvent(6, 0),
+		orderedEvent(5, 0),
+		orderedEvent(7, 2),
+		orderedEvent(6, 1),
+		orderedEvent(7, 0),
+	}
+
+	events := make(chan *types.IndexedEvent)
+	ordered := orderEvents(ctx, events, 2, 0, indexedEventPosition)
+	go func() {
+		defer close(events)
+		for _, event := range arrivals {
+			events <- event
+		}
+	}()
+
+	var received []*types.IndexedEvent
+	timeout := time.After(time.Second)
+	for done := false; !done; {
+		select {
+		case event, ok := <-ordered:
+			if !ok {
+				done = true
+				continue
+			}
+			received = append(received, event)
+		case <-timeout:
+			t.Fatal("Timed out waiting for ordered events")
+		}
+	}
+
+	expected := "5:0 5:1 6:0 6:1 6:3 7:0 7:2 "
+	if got := eventPositions(received); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestOrderEvents_ZeroDepthPassesThrough(t *testing.T) {
+	events := make(chan *types.IndexedEvent)
+	if ordered := orderEvents(context.Background(), events, 0, 0, indexedEventPosition); ordered != (<-chan *types.IndexedEvent)(events) {
+		t.Error("Expected depth 0 to return the events channel as is")
+	}
+}
+
+func TestEventOrderingBuffer_ExpireReleasesHeldBlocks(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	buffer := newEventOrderingBuffer(1)
+	buffer.now = func() time.Time { return now }
+
+	buffer.Push(orderedEvent(10, 1))
+	now = now.Add(time.Second)
+	buffer.Push(orderedEvent(10, 0))
+	now = now.Add(time.Second)
+	buffer.Push(orderedEvent(11, 0))
+	if buffer.Len() != 1 {
+		t.Fatalf("Expected block 11 to be held, got %d buffered events", buffer.Len())
+	}
+
+	// No later block arrives, so block 11 is released once it was held for the max hold
+	now = now.Add(2 * time.Second)
+	if ready := buffer.Expire(3 * time.Second); len(ready) != 0 {
+		t.Errorf("Expected no events before the max hold, got %s", eventPositions(ready))
+	}
+	buffer.Push(orderedEvent(11, 2))
+	now = now.Add(time.Second)
+	if got := eventPositions(buffer.Expire(3 * time.Second)); got != "11:0 11:2 " {
+		t.Errorf("Expected 11:0 11:2 , got %s", got)
+	}
+	if buffer.Len() != 0 {
+		t.Errorf("Expected no buffered events, got %d", buffer.Len())
+	}
+}
+
+func TestOrderEvents_MaxHoldReleasesNewestBlock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan *types.NFTTransferEvent)
+	ordered := orderEvents(ctx, events, 1, 10*time.Millisecond, nftEventPosition)
+	events <- &types.NFTTransferEvent{BlockNumber: big.NewInt(20), LogIndex: 1}
+
+	// No event of a later block arrives, so the event is released after the max hold
+	select {
+	case event := <-ordered:
+		if event.BlockNumber.Int64() != 20 || event.LogIndex != 1 {
+			t.Errorf("Expected 20:1, got %s:%d", event.BlockNumber, event.LogIndex)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the held event to be released after the max hold")
+	}
+}
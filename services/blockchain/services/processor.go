@@ -2,12 +2,14 @@ package blockchain
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
 	"strings"
 	"time"
 
+	"chainpulse/shared/metrics"
 	"chainpulse/shared/types"
 
 	"github.com/ethereum/go-ethereum"
@@ -15,6 +17,8 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 const (
@@ -22,41 +26,123 @@ const (
 	TokenTransferEventSignature = "Transfer(address,address,uint256)"
 )
 
+// transferEventABI is the generic ABI of the Transfer events of tokens and NFTs
+const transferEventABI = `[
+	{
+		"anonymous": false,
+		"inputs": [
+			{"indexed": true, "name": "from", "type": "address"},
+			{"indexed": true, "name": "to", "type": "address"},
+			{"indexed": false, "name": "value", "type": "uint256"}
+		],
+		"name": "Transfer",
+		"type": "event"
+	}
+]`
+
 type EventProcessor struct {
-	Client *ethclient.Client
+	// Client is the block source logs and blocks are read from
+	Client ChainClient
 	ABI    abi.ABI
+	// MaxAddressesPerSubscription bounds the addresses per log subscription;
+	// larger address sets are split across several subscriptions
+	MaxAddressesPerSubscription int
+	// PendingTxEnabled allows mempool subscriptions; only enable it for nodes that
+	// support the newPendingTransactions subscription
+	PendingTxEnabled bool
+	// ApprovalsEnabled adds Approval and ApprovalForAll events to SubscribeToAllEvents
+	ApprovalsEnabled bool
+	// RPCLimiter caps the node RPC calls of every method below, nil for no cap
+	RPCLimiter *RPCLimiter
+	// BlockNotFoundRetries is how many more times the block of a log is looked up when
+	// the node does not know it yet
+	BlockNotFoundRetries int
+	// Metrics counts the logs skipped because their block was orphaned or not found,
+	// nil to not count them
+	Metrics *metrics.Metrics
+	// ContractABIs decodes the logs of registered contracts and of proxies with their
+	// implementation ABI, nil to decode every log with ABI
+	ContractABIs *ContractABIs
+	// OrderingDepth is how many blocks the transfer and approval subscriptions hold events
+	// back to release them in (block number, log index) order, 0 to emit them as they arrive
+	OrderingDepth int
+	// OrderingMaxHold is how long an event is held back at most, even if no event of a
+	// later block arrived, 0 to hold events until one does
+	OrderingMaxHold time.Duration
+	// MaxSubscriptionAge rotates log subscriptions once they are this old, re-fetching
+	// the logs since the last block delivered, 0 to keep them until they fail
+	MaxSubscriptionAge time.Duration
+
+	pendingTxSource    PendingTxSource
+	ethClient          *ethclient.Client
+	blockNotFoundDelay time.Duration
 }
 
 func NewEventProcessor(ethereumNodeURL string) (*EventProcessor, error) {
-	client, err := ethclient.Dial(ethereumNodeURL)
+	rpcClient, err := rpc.Dial(ethereumNodeURL)
 	if err != nil {
 		return nil, err
 	}
+	client := ethclient.NewClient(rpcClient)
 
 	// We'll define a generic ABI that can handle common transfer events
 	// In a real implementation, we would load specific ABIs for each contract
-	parsedABI, err := abi.JSON(strings.NewReader(`[
-		{
-			"anonymous": false,
-			"inputs": [
-				{"indexed": true, "name": "from", "type": "address"},
-				{"indexed": true, "name": "to", "type": "address"},
-				{"indexed": false, "name": "value", "type": "uint256"}
-			],
-			"name": "Transfer",
-			"type": "event"
-		}
-	]`))
+	parsedABI, err := abi.JSON(strings.NewReader(transferEventABI))
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventProcessor{
+		Client:                      client,
+		ABI:                         parsedABI,
+		MaxAddressesPerSubscription: DefaultMaxAddressesPerSubscription,
+		BlockNotFoundRetries:        DefaultBlockNotFoundRetries,
+		OrderingDepth:               DefaultOrderingDepth,
+		OrderingMaxHold:             DefaultOrderingMaxHold,
+		pendingTxSource:             &nodePendingTxSource{geth: gethclient.New(rpcClient), eth: client},
+		ethClient:                   client,
+		blockNotFoundDelay:          defaultBlockNotFoundDelay,
+	}, nil
+}
+
+// NewEventProcessorWithClient creates an event processor reading from client, using the
+// same Transfer ABI as NewEventProcessor. Mempool subscriptions need an Ethereum node
+// and are unavailable.
+func NewEventProcessorWithClient(client ChainClient) (*EventProcessor, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(transferEventABI))
 	if err != nil {
 		return nil, err
 	}
 
 	return &EventProcessor{
-		Client: client,
-		ABI:    parsedABI,
+		Client:                      client,
+		ABI:                         parsedABI,
+		MaxAddressesPerSubscription: DefaultMaxAddressesPerSubscription,
+		BlockNotFoundRetries:        DefaultBlockNotFoundRetries,
+		OrderingDepth:               DefaultOrderingDepth,
+		OrderingMaxHold:             DefaultOrderingMaxHold,
+		blockNotFoundDelay:          defaultBlockNotFoundDelay,
 	}, nil
 }
 
+// EthClient returns the Ethereum client of a processor created by NewEventProcessor,
+// for services that need the full client, or nil for other block sources
+func (ep *EventProcessor) EthClient() *ethclient.Client {
+	return ep.ethClient
+}
+
+// subscribeLogs subscribes to logs matching query, sharding the address set across
+// several subscriptions so one oversized or failing group does not break the rest
+func (ep *EventProcessor) subscribeLogs(ctx context.Context, query ethereum.FilterQuery) (<-chan types.Log, <-chan error, error) {
+	subscription := &ShardedLogSubscription{
+		Subscriber:   ep.Client,
+		MaxAddresses: ep.MaxAddressesPerSubscription,
+		MaxAge:       ep.MaxSubscriptionAge,
+		Filterer:     &rateLimitedLogFilterer{LogFilterer: ep.Client, limiter: ep.RPCLimiter},
+	}
+	return subscription.Subscribe(ctx, query)
+}
+
 // ProcessNFTTransfers processes NFT transfer events from a specific block range
 func (ep *EventProcessor) ProcessNFTTransfers(ctx context.Context, contractAddress common.Address, fromBlock, toBlock *big.Int) ([]*types.NFTTransferEvent, error) {
 	query := ethereum.FilterQuery{
@@ -68,6 +154,9 @@ func (ep *EventProcessor) ProcessNFTTransfers(ctx context.Context, contractAddre
 		},
 	}
 
+	if err := ep.waitRPC(ctx); err != nil {
+		return nil, err
+	}
 	logs, err := ep.Client.FilterLogs(ctx, query)
 	if err != nil {
 		return nil, err
@@ -76,6 +165,10 @@ func (ep *EventProcessor) ProcessNFTTransfers(ctx context.Context, contractAddre
 	var events []*types.NFTTransferEvent
 	for _, vLog := range logs {
 		event, err := ep.parseNFTTransferEvent(vLog)
+		if errors.Is(err, ErrOrphanedLog) {
+			log.Printf("Skipping NFT transfer event: %v", err)
+			continue
+		}
 		if err != nil {
 			log.Printf("Error parsing NFT transfer event: %v", err)
 			continue
@@ -97,6 +190,9 @@ func (ep *EventProcessor) ProcessTokenTransfers(ctx context.Context, contractAdd
 		},
 	}
 
+	if err := ep.waitRPC(ctx); err != nil {
+		return nil, err
+	}
 	logs, err := ep.Client.FilterLogs(ctx, query)
 	if err != nil {
 		return nil, err
@@ -105,6 +201,10 @@ func (ep *EventProcessor) ProcessTokenTransfers(ctx context.Context, contractAdd
 	var events []*types.TokenTransferEvent
 	for _, vLog := range logs {
 		event, err := ep.parseTokenTransferEvent(vLog)
+		if errors.Is(err, ErrOrphanedLog) {
+			log.Printf("Skipping token transfer event: %v", err)
+			continue
+		}
 		if err != nil {
 			log.Printf("Error parsing token transfer event: %v", err)
 			continue
@@ -115,7 +215,47 @@ func (ep *EventProcessor) ProcessTokenTransfers(ctx context.Context, contractAdd
 	return events, nil
 }
 
-// SubscribeToNFTTransfers subscribes to real-time NFT transfer events
+// FilterTransferLogs returns the Transfer logs of a contract within a block range as the
+// node reports them, without parsing them
+func (ep *EventProcessor) FilterTransferLogs(ctx context.Context, contractAddress common.Address, fromBlock, toBlock *big.Int) ([]types.Log, error) {
+	query := ethereum.FilterQuery{
+		FromBlock: fromBlock,
+		ToBlock:   toBlock,
+		Addresses: []common.Address{contractAddress},
+		Topics: [][]common.Hash{
+			{ep.ABI.Events["Transfer"].ID}, // Transfer event signature
+		},
+	}
+
+	if err := ep.waitRPC(ctx); err != nil {
+		return nil, err
+	}
+	return ep.Client.FilterLogs(ctx, query)
+}
+
+// ConvertTransferLog converts a Transfer log into the indexed event live indexing stores
+// for it: an NFT transfer when the token ID is indexed, a token transfer otherwise
+func (ep *EventProcessor) ConvertTransferLog(vLog types.Log) (*types.IndexedEvent, error) {
+	switch len(vLog.Topics) {
+	case nftTransferTopics:
+		event, err := ep.parseNFTTransferEvent(vLog)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing NFT transfer event: %v", err)
+		}
+		return ep.ConvertNFTToIndexedEvent(event), nil
+	case tokenTransferTopics:
+		event, err := ep.parseTokenTransferEvent(vLog)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing token transfer event: %v", err)
+		}
+		return ep.ConvertTokenToIndexedEvent(event), nil
+	default:
+		return nil, fmt.Errorf("unexpected Transfer log with %d topics in tx %s", len(vLog.Topics), vLog.TxHash.Hex())
+	}
+}
+
+// SubscribeToNFTTransfers subscribes to real-time NFT transfer events, emitted in (block
+// number, log index) order up to OrderingDepth blocks below the newest block seen
 func (ep *EventProcessor) SubscribeToNFTTransfers(ctx context.Context, contractAddresses []common.Address) (<-chan *types.NFTTransferEvent, <-chan error, error) {
 	query := ethereum.FilterQuery{
 		Addresses: contractAddresses,
@@ -124,8 +264,7 @@ func (ep *EventProcessor) SubscribeToNFTTransfers(ctx context.Context, contractA
 		},
 	}
 
-	logs := make(chan types.Log)
-	sub, err := ep.Client.SubscribeFilterLogs(ctx, query, logs)
+	logs, subErrs, err := ep.subscribeLogs(ctx, query)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -136,11 +275,13 @@ func (ep *EventProcessor) SubscribeToNFTTransfers(ctx context.Context, contractA
 	go func() {
 		defer close(eventChan)
 		defer close(errChan)
-		defer sub.Unsubscribe()
 
 		for {
 			select {
-			case vLog := <-logs:
+			case vLog, ok := <-logs:
+				if !ok {
+					return
+				}
 				event, err := ep.parseNFTTransferEvent(vLog)
 				if err != nil {
 					errChan <- fmt.Errorf("error parsing NFT transfer event: %v", err)
@@ -149,17 +290,21 @@ func (ep *EventProcessor) SubscribeToNFTTransfers(ctx context.Context, contractA
 				eventChan <- event
 			case <-ctx.Done():
 				return
-			case err := <-sub.Err():
+			case err, ok := <-subErrs:
+				if !ok {
+					return
+				}
+				// Failed shards resubscribe on their own, so keep the stream open
 				errChan <- err
-				return
 			}
 		}
 	}()
 
-	return eventChan, errChan, nil
+	return orderEvents(ctx, (<-chan *types.NFTTransferEvent)(eventChan), ep.OrderingDepth, ep.OrderingMaxHold, nftEventPosition), errChan, nil
 }
 
-// SubscribeToTokenTransfers subscribes to real-time token transfer events
+// SubscribeToTokenTransfers subscribes to real-time token transfer events, emitted in
+// (block number, log index) order up to OrderingDepth blocks below the newest block seen
 func (ep *EventProcessor) SubscribeToTokenTransfers(ctx context.Context, contractAddresses []common.Address) (<-chan *types.TokenTransferEvent, <-chan error, error) {
 	query := ethereum.FilterQuery{
 		Addresses: contractAddresses,
@@ -168,8 +313,7 @@ func (ep *EventProcessor) SubscribeToTokenTransfers(ctx context.Context, contrac
 		},
 	}
 
-	logs := make(chan types.Log)
-	sub, err := ep.Client.SubscribeFilterLogs(ctx, query, logs)
+	logs, subErrs, err := ep.subscribeLogs(ctx, query)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -180,11 +324,13 @@ func (ep *EventProcessor) SubscribeToTokenTransfers(ctx context.Context, contrac
 	go func() {
 		defer close(eventChan)
 		defer close(errChan)
-		defer sub.Unsubscribe()
 
 		for {
 			select {
-			case vLog := <-logs:
+			case vLog, ok := <-logs:
+				if !ok {
+					return
+				}
 				event, err := ep.parseTokenTransferEvent(vLog)
 				if err != nil {
 					errChan <- fmt.Errorf("error parsing token transfer event: %v", err)
@@ -193,41 +339,27 @@ func (ep *EventProcessor) SubscribeToTokenTransfers(ctx context.Context, contrac
 				eventChan <- event
 			case <-ctx.Done():
 				return
-			case err := <-sub.Err():
+			case err, ok := <-subErrs:
+				if !ok {
+					return
+				}
+				// Failed shards resubscribe on their own, so keep the stream open
 				errChan <- err
-				return
 			}
 		}
 	}()
 
-	return eventChan, errChan, nil
+	return orderEvents(ctx, (<-chan *types.TokenTransferEvent)(eventChan), ep.OrderingDepth, ep.OrderingMaxHold, tokenEventPosition), errChan, nil
 }
 
 func (ep *EventProcessor) parseNFTTransferEvent(vLog types.Log) (*types.NFTTransferEvent, error) {
-	var transferEvent struct {
-		From    common.Address
-		To      common.Address
-		TokenID *big.Int
-	}
-
-	err := ep.ABI.UnpackIntoInterface(&transferEvent, "Transfer", vLog.Data)
+	// The token ID is usually the fourth topic, but is read from data when it is not
+	transfer, err := ep.decodeTransferLog(vLog)
 	if err != nil {
 		return nil, err
 	}
 
-	// Extract indexed parameters from topics
-	if len(vLog.Topics) >= 3 {
-		transferEvent.From = common.BytesToAddress(vLog.Topics[1].Bytes())
-		transferEvent.To = common.BytesToAddress(vLog.Topics[2].Bytes())
-	}
-
-	// For NFTs, the token ID is usually in the data part or as a topic
-	if transferEvent.TokenID == nil && len(vLog.Topics) >= 3 {
-		// If not in data, try to get from the third topic (for some NFT implementations)
-		transferEvent.TokenID = new(big.Int).SetBytes(vLog.Topics[3].Bytes())
-	}
-
-	block, err := ep.Client.BlockByHash(context.Background(), vLog.BlockHash)
+	timestamp, err := ep.logTimestamp(vLog)
 	if err != nil {
 		return nil, err
 	}
@@ -235,33 +367,24 @@ func (ep *EventProcessor) parseNFTTransferEvent(vLog types.Log) (*types.NFTTrans
 	return &types.NFTTransferEvent{
 		BlockNumber: new(big.Int).SetUint64(vLog.BlockNumber),
 		TxHash:      vLog.TxHash,
-		From:        transferEvent.From,
-		To:          transferEvent.To,
-		TokenID:     transferEvent.TokenID,
+		LogIndex:    vLog.Index,
+		From:        transfer.From,
+		To:          transfer.To,
+		TokenID:     transfer.Amount,
 		Contract:    vLog.Address,
-		Timestamp:   time.Unix(int64(block.Time()), 0),
+		Data:        ep.transferData(vLog, transfer, "tokenId"),
+		Timestamp:   timestamp,
 	}, nil
 }
 
 func (ep *EventProcessor) parseTokenTransferEvent(vLog types.Log) (*types.TokenTransferEvent, error) {
-	var transferEvent struct {
-		From  common.Address
-		To    common.Address
-		Value *big.Int
-	}
-
-	err := ep.ABI.UnpackIntoInterface(&transferEvent, "Transfer", vLog.Data)
+	// Some non-standard tokens index the value as a fourth topic instead of in data
+	transfer, err := ep.decodeTransferLog(vLog)
 	if err != nil {
 		return nil, err
 	}
 
-	// Extract indexed parameters from topics
-	if len(vLog.Topics) >= 3 {
-		transferEvent.From = common.BytesToAddress(vLog.Topics[1].Bytes())
-		transferEvent.To = common.BytesToAddress(vLog.Topics[2].Bytes())
-	}
-
-	block, err := ep.Client.BlockByHash(context.Background(), vLog.BlockHash)
+	timestamp, err := ep.logTimestamp(vLog)
 	if err != nil {
 		return nil, err
 	}
@@ -269,27 +392,39 @@ func (ep *EventProcessor) parseTokenTransferEvent(vLog types.Log) (*types.TokenT
 	return &types.TokenTransferEvent{
 		BlockNumber: new(big.Int).SetUint64(vLog.BlockNumber),
 		TxHash:      vLog.TxHash,
-		From:        transferEvent.From,
-		To:          transferEvent.To,
-		Value:       transferEvent.Value,
+		LogIndex:    vLog.Index,
+		From:        transfer.From,
+		To:          transfer.To,
+		Value:       transfer.Amount,
 		Contract:    vLog.Address,
-		Timestamp:   time.Unix(int64(block.Time()), 0),
+		Data:        ep.transferData(vLog, transfer, "value"),
+		Timestamp:   timestamp,
 	}, nil
 }
 
 // GetLatestBlockNumber gets the latest block number from the blockchain
 func (ep *EventProcessor) GetLatestBlockNumber(ctx context.Context) (*big.Int, error) {
-	return ep.Client.BlockNumber(ctx)
+	if err := ep.waitRPC(ctx); err != nil {
+		return nil, err
+	}
+	number, err := ep.Client.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetUint64(number), nil
 }
 
 // GetBlockByNumber gets a specific block by its number
 func (ep *EventProcessor) GetBlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	if err := ep.waitRPC(ctx); err != nil {
+		return nil, err
+	}
 	return ep.Client.BlockByNumber(ctx, number)
 }
 
 func (ep *EventProcessor) Close() {
-	if ep.Client != nil {
-		ep.Client.Close()
+	if closer, ok := ep.Client.(interface{ Close() }); ok {
+		closer.Close()
 	}
 }
 
@@ -298,12 +433,16 @@ func (ep *EventProcessor) ConvertNFTToIndexedEvent(nftEvent *types.NFTTransferEv
 	return &types.IndexedEvent{
 		BlockNumber: nftEvent.BlockNumber,
 		TxHash:      nftEvent.TxHash.Hex(),
+		LogIndex:    nftEvent.LogIndex,
 		EventName:   "NFTTransfer",
+		Topic0:      ep.ABI.Events["Transfer"].ID.Hex(),
 		Contract:    nftEvent.Contract.Hex(),
 		From:        nftEvent.From.Hex(),
 		To:          nftEvent.To.Hex(),
 		TokenID:     nftEvent.TokenID.String(),
+		Data:        nftEvent.Data,
 		Timestamp:   nftEvent.Timestamp,
+		ReceivedAt:  nftEvent.ReceivedAt,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -314,72 +453,61 @@ func (ep *EventProcessor) ConvertTokenToIndexedEvent(tokenEvent *types.TokenTran
 	return &types.IndexedEvent{
 		BlockNumber: tokenEvent.BlockNumber,
 		TxHash:      tokenEvent.TxHash.Hex(),
+		LogIndex:    tokenEvent.LogIndex,
 		EventName:   "TokenTransfer",
+		Topic0:      ep.ABI.Events["Transfer"].ID.Hex(),
 		Contract:    tokenEvent.Contract.Hex(),
 		From:        tokenEvent.From.Hex(),
 		To:          tokenEvent.To.Hex(),
 		Value:       tokenEvent.Value.String(),
+		Data:        tokenEvent.Data,
 		Timestamp:   tokenEvent.Timestamp,
+		ReceivedAt:  tokenEvent.ReceivedAt,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 }
 
-// SubscribeToAllEvents subscribes to all types of events
+// SubscribeToAllEvents subscribes to NFT and token transfers with a single log subscription.
+// Both share the Transfer signature, so each log is classified by its topic count and
+// converted into exactly one indexed event. With ApprovalsEnabled the subscription also
+// covers approvals, which are classified by their signature. Events are emitted in
+// (block number, log index) order up to OrderingDepth blocks below the newest block seen.
 func (ep *EventProcessor) SubscribeToAllEvents(ctx context.Context, contractAddresses []common.Address) (<-chan *types.IndexedEvent, <-chan error, error) {
-	// Subscribe to NFT transfers
-	nftEventChan, nftErrChan, err := ep.SubscribeToNFTTransfers(ctx, contractAddresses)
-	if err != nil {
-		return nil, nil, err
+	signatures := []common.Hash{ep.ABI.Events["Transfer"].ID} // Transfer event signature
+	if ep.ApprovalsEnabled {
+		signatures = append(signatures, approvalTopic, approvalForAllTopic)
+	}
+	query := ethereum.FilterQuery{
+		Addresses: contractAddresses,
+		Topics:    [][]common.Hash{signatures},
 	}
 
-	// Subscribe to token transfers
-	tokenEventChan, tokenErrChan, err := ep.SubscribeToTokenTransfers(ctx, contractAddresses)
+	logs, subErrs, err := ep.subscribeLogs(ctx, query)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// Create output channels
-	outputEventChan := make(chan *types.IndexedEvent)
-	outputErrChan := make(chan error)
-
-	// Multiplex both event streams into a single channel
-	go func() {
-		defer close(outputEventChan)
-		defer close(outputErrChan)
-
-		for {
-			select {
-			case nftEvent, ok := <-nftEventChan:
-				if !ok {
-					nftEventChan = nil // Close this case
-					continue
-				}
-				outputEventChan <- ep.ConvertNFTToIndexedEvent(nftEvent)
-			case tokenEvent, ok := <-tokenEventChan:
-				if !ok {
-					tokenEventChan = nil // Close this case
-					continue
-				}
-				outputEventChan <- ep.ConvertTokenToIndexedEvent(tokenEvent)
-			case err, ok := <-nftErrChan:
-				if ok {
-					outputErrChan <- err
-				}
-			case err, ok := <-tokenErrChan:
-				if ok {
-					outputErrChan <- err
-				}
-			case <-ctx.Done():
-				return
-			}
-
-			// If both channels are closed, exit
-			if nftEventChan == nil && tokenEventChan == nil {
-				return
-			}
+	parseNFT := func(vLog types.Log) (*types.IndexedEvent, error) {
+		event, err := ep.parseNFTTransferEvent(vLog)
+		if err != nil {
+			return nil, err
 		}
-	}()
+		return ep.ConvertNFTToIndexedEvent(event), nil
+	}
+	parseToken := func(vLog types.Log) (*types.IndexedEvent, error) {
+		event, err := ep.parseTokenTransferEvent(vLog)
+		if err != nil {
+			return nil, err
+		}
+		return ep.ConvertTokenToIndexedEvent(event), nil
+	}
+
+	var parseApproval transferLogParser
+	if ep.ApprovalsEnabled {
+		parseApproval = ep.ConvertApprovalLog
+	}
 
-	return outputEventChan, outputErrChan, nil
+	eventChan, errChan := multiplexTransferLogs(ctx, logs, subErrs, parseNFT, parseToken, parseApproval)
+	return orderEvents(ctx, eventChan, ep.OrderingDepth, ep.OrderingMaxHold, indexedEventPosition), errChan, nil
 }
\ No newline at end of file
@@ -0,0 +1,203 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// EIP1967ImplementationSlot is the storage slot holding the implementation address of an
+// EIP-1967 proxy: bytes32(uint256(keccak256("eip1967.proxy.implementation")) - 1)
+var EIP1967ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920c3ca505d382bbc")
+
+// ContractABIs holds the ABIs of individual contracts and maps upgradeable proxies to
+// their implementations. Proxies emit events under their own address while the events
+// are declared in the implementation, so a proxy's logs decode with the ABI registered
+// for its current implementation.
+type ContractABIs struct {
+	mu              sync.RWMutex
+	abis            map[common.Address]abi.ABI
+	implementations map[common.Address]common.Address // proxy to implementation
+}
+
+// NewContractABIs creates an empty set of contract ABIs
+func NewContractABIs() *ContractABIs {
+	return &ContractABIs{
+		abis:            make(map[common.Address]abi.ABI),
+		implementations: make(map[common.Address]common.Address),
+	}
+}
+
+// Register sets the ABI of contract, typically an implementation contract
+func (c *ContractABIs) Register(contract common.Address, contractABI abi.ABI) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.abis[contract] = contractABI
+}
+
+// RegisterContracts registers the stored ABIs of contracts, such as the verified ABIs
+// fetched when they were registered. Contracts without an ABI are skipped, so their logs
+// decode with the processor ABI. It returns an error listing the contracts whose ABI
+// is invalid, after registering the others.
+func (c *ContractABIs) RegisterContracts(contracts []types.Contract) error {
+	var invalid []string
+	for _, contract := range contracts {
+		if contract.ABI == "" {
+			continue
+		}
+		contractABI, err := abi.JSON(strings.NewReader(contract.ABI))
+		if err != nil {
+			invalid = append(invalid, contract.Address)
+			continue
+		}
+		c.Register(common.HexToAddress(contract.Address), contractABI)
+	}
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid ABI of contracts %v", invalid)
+	}
+	return nil
+}
+
+// SetImplementation maps proxy to its current implementation
+func (c *ContractABIs) SetImplementation(proxy, implementation common.Address) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.implementations[proxy] = implementation
+}
+
+// Proxies returns the proxies with a known implementation
+func (c *ContractABIs) Proxies() []common.Address {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	proxies := make([]common.Address, 0, len(c.implementations))
+	for proxy := range c.implementations {
+		proxies = append(proxies, proxy)
+	}
+	return proxies
+}
+
+// ABIFor returns the ABI to decode the logs of contract with: the ABI of its
+// implementation when it is a proxy, otherwise its own
+func (c *ContractABIs) ABIFor(contract common.Address) (abi.ABI, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if implementation, ok := c.implementations[contract]; ok {
+		if contractABI, ok := c.abis[implementation]; ok {
+			return contractABI, true
+		}
+	}
+	contractABI, ok := c.abis[contract]
+	return contractABI, ok
+}
+
+// ImplementationOf reads the implementation of an EIP-1967 proxy from its storage. It
+// returns the zero address for contracts that are not EIP-1967 proxies.
+func (ep *EventProcessor) ImplementationOf(ctx context.Context, proxy common.Address) (common.Address, error) {
+	if err := ep.waitRPC(ctx); err != nil {
+		return common.Address{}, err
+	}
+	slot, err := ep.Client.StorageAt(ctx, proxy, EIP1967ImplementationSlot, nil)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to read implementation slot of %s: %v", proxy.Hex(), err)
+	}
+	return common.BytesToAddress(slot), nil
+}
+
+// TrackProxy discovers the implementation of proxy and maps the proxy to it, so its logs
+// decode with the implementation ABI. It returns an error if proxy has no EIP-1967
+// implementation.
+func (ep *EventProcessor) TrackProxy(ctx context.Context, proxy common.Address) error {
+	if ep.ContractABIs == nil {
+		return fmt.Errorf("no contract ABIs configured")
+	}
+
+	implementation, err := ep.ImplementationOf(ctx, proxy)
+	if err != nil {
+		return err
+	}
+	if implementation == (common.Address{}) {
+		return fmt.Errorf("%s is not an EIP-1967 proxy", proxy.Hex())
+	}
+
+	ep.ContractABIs.SetImplementation(proxy, implementation)
+	return nil
+}
+
+// TrackProxies tracks the contracts that are EIP-1967 proxies, such as the registered
+// contracts on startup, so their logs decode with the implementation ABI. Contracts that
+// are not proxies are skipped. It returns an error listing the contracts whose
+// implementation slot could not be read, after tracking the others.
+func (ep *EventProcessor) TrackProxies(ctx context.Context, contracts []types.Contract) error {
+	if ep.ContractABIs == nil {
+		return fmt.Errorf("no contract ABIs configured")
+	}
+
+	var failed []string
+	for _, contract := range contracts {
+		proxy := common.HexToAddress(contract.Address)
+		implementation, err := ep.ImplementationOf(ctx, proxy)
+		if err != nil {
+			failed = append(failed, contract.Address)
+			continue
+		}
+		if implementation != (common.Address{}) {
+			ep.ContractABIs.SetImplementation(proxy, implementation)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to read the implementation of contracts %v", failed)
+	}
+	return nil
+}
+
+// RefreshProxies re-reads the implementation of every tracked proxy, following upgrades.
+// A proxy whose implementation cannot be read keeps its previous one.
+func (ep *EventProcessor) RefreshProxies(ctx context.Context) error {
+	if ep.ContractABIs == nil {
+		return nil
+	}
+
+	var failed []common.Address
+	for _, proxy := range ep.ContractABIs.Proxies() {
+		implementation, err := ep.ImplementationOf(ctx, proxy)
+		if err != nil || implementation == (common.Address{}) {
+			failed = append(failed, proxy)
+			continue
+		}
+		ep.ContractABIs.SetImplementation(proxy, implementation)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to refresh the implementation of proxies %v", failed)
+	}
+	return nil
+}
+
+// RefreshProxiesPeriodically calls RefreshProxies every interval until ctx is done
+func (ep *EventProcessor) RefreshProxiesPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ep.RefreshProxies(ctx); err != nil {
+				log.Printf("Error refreshing proxy implementations: %v", err)
+			}
+		}
+	}
+}
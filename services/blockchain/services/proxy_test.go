@@ -0,0 +1,249 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+const vaultV1ABI = `[
+	{
+		"anonymous": false,
+		"inputs": [
+			{"indexed": true, "name": "account", "type": "address"},
+			{"indexed": false, "name": "amount", "type": "uint256"}
+		],
+		"name": "Deposited",
+		"type": "event"
+	}
+]`
+
+// vaultV2ABI renames the amount parameter of the same event
+const vaultV2ABI = `[
+	{
+		"anonymous": false,
+		"inputs": [
+			{"indexed": true, "name": "account", "type": "address"},
+			{"indexed": false, "name": "assets", "type": "uint256"}
+		],
+		"name": "Deposited",
+		"type": "event"
+	}
+]`
+
+func parseTestABI(t *testing.T, definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	return parsed
+}
+
+func TestDecodeLog_ProxyUsesImplementationABI(t *testing.T) {
+	proxy := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	implV1 := common.HexToAddress("0x00000000000000000000000000000000000000b1")
+	implV2 := common.HexToAddress("0x00000000000000000000000000000000000000b2")
+	account := common.HexToAddress("0x0000000000000000000000000000000000000001")
+
+	client := &mockChainClient{storage: map[common.Address]common.Hash{
+		proxy: common.BytesToHash(implV1.Bytes()),
+	}}
+	ep, err := NewEventProcessorWithClient(client)
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+
+	v1 := parseTestABI(t, vaultV1ABI)
+	ep.ContractABIs = NewContractABIs()
+	ep.ContractABIs.Register(implV1, v1)
+	ep.ContractABIs.Register(implV2, parseTestABI(t, vaultV2ABI))
+
+	data, err := v1.Events["Deposited"].Inputs.NonIndexed().Pack(big.NewInt(500))
+	if err != nil {
+		t.Fatalf("Failed to pack data: %v", err)
+	}
+	vLog := ethtypes.Log{
+		Address: proxy,
+		Topics:  []common.Hash{v1.Events["Deposited"].ID, common.BytesToHash(account.Bytes())},
+		Data:    data,
+	}
+
+	// The proxy's own ABI is unknown until its implementation is discovered
+	if _, _, err := ep.DecodeLog(vLog); err == nil {
+		t.Fatal("Expected the proxy event not to decode before tracking the proxy")
+	}
+
+	if err := ep.TrackProxy(context.Background(), proxy); err != nil {
+		t.Fatalf("Failed to track proxy: %v", err)
+	}
+
+	name, params, err := ep.DecodeLog(vLog)
+	if err != nil {
+		t.Fatalf("Expected the proxy event to decode, got %v", err)
+	}
+	if name != "Deposited" || params["amount"] != "500" || params["account"] != account.Hex() {
+		t.Errorf("Expected Deposited of 500 by %s, got %s %v", account.Hex(), name, params)
+	}
+
+	// After an upgrade the refreshed mapping decodes with the new implementation ABI
+	client.storage[proxy] = common.BytesToHash(implV2.Bytes())
+	if err := ep.RefreshProxies(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh proxies: %v", err)
+	}
+
+	_, params, err = ep.DecodeLog(vLog)
+	if err != nil {
+		t.Fatalf("Expected the proxy event to decode, got %v", err)
+	}
+	if params["assets"] != "500" {
+		t.Errorf("Expected the upgraded parameter name, got %v", params)
+	}
+
+	// Transfer events still decode with the processor ABI
+	transfer := ethtypes.Log{
+		Address: proxy,
+		Topics:  []common.Hash{ep.ABI.Events["Transfer"].ID, common.BytesToHash(account.Bytes()), common.BytesToHash(account.Bytes())},
+		Data:    data,
+	}
+	if name, _, err := ep.DecodeLog(transfer); err != nil || name != "Transfer" {
+		t.Errorf("Expected a Transfer event, got %s: %v", name, err)
+	}
+}
+
+func TestTrackProxy_NotAProxy(t *testing.T) {
+	ep, err := NewEventProcessorWithClient(&mockChainClient{})
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+	ep.ContractABIs = NewContractABIs()
+
+	if err := ep.TrackProxy(context.Background(), common.HexToAddress("0x00000000000000000000000000000000000000cc")); err == nil {
+		t.Error("Expected an error for a contract without an implementation slot")
+	}
+	if proxies := ep.ContractABIs.Proxies(); len(proxies) != 0 {
+		t.Errorf("Expected no tracked proxies, got %v", proxies)
+	}
+}
+
+func TestContractABIs_RegisterContracts(t *testing.T) {
+	vault := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	account := common.HexToAddress("0x0000000000000000000000000000000000000001")
+
+	ep, err := NewEventProcessorWithClient(&mockChainClient{})
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+	ep.ContractABIs = NewContractABIs()
+
+	err = ep.ContractABIs.RegisterContracts([]types.Contract{
+		{Address: vault.Hex(), ABI: vaultV1ABI},
+		{Address: "0x00000000000000000000000000000000000000bb"},
+		{Address: "0x00000000000000000000000000000000000000cc", ABI: "not an ABI"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "0x00000000000000000000000000000000000000cc") {
+		t.Errorf("Expected the invalid ABI to be reported, got %v", err)
+	}
+
+	v1 := parseTestABI(t, vaultV1ABI)
+	data, err := v1.Events["Deposited"].Inputs.NonIndexed().Pack(big.NewInt(500))
+	if err != nil {
+		t.Fatalf("Failed to pack data: %v", err)
+	}
+	name, params, err := ep.DecodeLog(ethtypes.Log{
+		Address: vault,
+		Topics:  []common.Hash{v1.Events["Deposited"].ID, common.BytesToHash(account.Bytes())},
+		Data:    data,
+	})
+	if err != nil {
+		t.Fatalf("Expected the event to decode with the stored ABI, got %v", err)
+	}
+	if name != "Deposited" || params["amount"] != "500" {
+		t.Errorf("Expected Deposited of 500, got %s %v", name, params)
+	}
+}
+
+// wethTransferABI names the Transfer parameters like WETH does
+const wethTransferABI = `[
+	{
+		"anonymous": false,
+		"inputs": [
+			{"indexed": true, "name": "src", "type": "address"},
+			{"indexed": true, "name": "dst", "type": "address"},
+			{"indexed": false, "name": "wad", "type": "uint256"}
+		],
+		"name": "Transfer",
+		"type": "event"
+	}
+]`
+
+func TestProcessTokenTransfers_DecodesWithContractABI(t *testing.T) {
+	weth := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	src := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	dst := common.HexToAddress("0x0000000000000000000000000000000000000002")
+
+	block := ethtypes.NewBlockWithHeader(&ethtypes.Header{Number: big.NewInt(10), Time: 1700000000})
+	wethABI := parseTestABI(t, wethTransferABI)
+	data, err := wethABI.Events["Transfer"].Inputs.NonIndexed().Pack(big.NewInt(500))
+	if err != nil {
+		t.Fatalf("Failed to pack data: %v", err)
+	}
+	client := &mockChainClient{
+		blocks: map[common.Hash]*ethtypes.Block{block.Hash(): block},
+		logs: []ethtypes.Log{{
+			Address:     weth,
+			Topics:      []common.Hash{wethABI.Events["Transfer"].ID, common.BytesToHash(src.Bytes()), common.BytesToHash(dst.Bytes())},
+			Data:        data,
+			BlockNumber: 10,
+			BlockHash:   block.Hash(),
+		}},
+	}
+	ep, err := NewEventProcessorWithClient(client)
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+	ep.ContractABIs = NewContractABIs()
+	if err := ep.ContractABIs.RegisterContracts([]types.Contract{{Address: weth.Hex(), ABI: wethTransferABI}}); err != nil {
+		t.Fatalf("Failed to register ABI: %v", err)
+	}
+
+	events, err := ep.ProcessTokenTransfers(context.Background(), weth, big.NewInt(10), big.NewInt(10))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 transfer, got %d", len(events))
+	}
+
+	indexed := ep.ConvertTokenToIndexedEvent(events[0])
+	if indexed.Data["wad"] != "500" || indexed.Data["src"] != src.Hex() || indexed.Data["dst"] != dst.Hex() {
+		t.Errorf("Expected the parameters named by the contract ABI, got %v", indexed.Data)
+	}
+}
+
+func TestTrackProxies_SkipsContractsThatAreNotProxies(t *testing.T) {
+	proxy := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	implementation := common.HexToAddress("0x00000000000000000000000000000000000000b1")
+	token := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+
+	ep, err := NewEventProcessorWithClient(&mockChainClient{storage: map[common.Address]common.Hash{
+		proxy: common.BytesToHash(implementation.Bytes()),
+	}})
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+	ep.ContractABIs = NewContractABIs()
+
+	if err := ep.TrackProxies(context.Background(), []types.Contract{{Address: proxy.Hex()}, {Address: token.Hex()}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if proxies := ep.ContractABIs.Proxies(); len(proxies) != 1 || proxies[0] != proxy {
+		t.Errorf("Expected only %s to be tracked, got %v", proxy.Hex(), proxies)
+	}
+}
@@ -0,0 +1,107 @@
+package blockchain
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"chainpulse/shared/metrics"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// RPCLimiter caps the rate of outbound node RPC calls across every subsystem sharing it.
+// Calls are spaced evenly, so bursts from several subsystems queue behind each other
+// instead of exceeding the provider limit together. A nil limiter does not limit.
+type RPCLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+	metrics  *metrics.Metrics
+}
+
+// NewRPCLimiter creates a limiter allowing requestsPerSecond node RPC calls. Time spent
+// waiting for a slot is recorded in m when it is not nil. A requestsPerSecond <= 0
+// returns nil, which does not limit.
+func NewRPCLimiter(requestsPerSecond int, m *metrics.Metrics) *RPCLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	return &RPCLimiter{
+		interval: time.Second / time.Duration(requestsPerSecond),
+		metrics:  m,
+	}
+}
+
+// Wait blocks until the caller may issue one RPC call or ctx is cancelled
+func (l *RPCLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	slot := l.next
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	wait := slot.Sub(now)
+	if l.metrics != nil {
+		l.metrics.RecordRPCLimiterWait(wait.Seconds())
+	}
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitRPC acquires a slot from the processor's RPC limiter, if any
+func (ep *EventProcessor) waitRPC(ctx context.Context) error {
+	return ep.RPCLimiter.Wait(ctx)
+}
+
+// rateLimitedPendingTxSource acquires a limiter slot before every transaction lookup
+type rateLimitedPendingTxSource struct {
+	PendingTxSource
+	limiter *RPCLimiter
+}
+
+func (s *rateLimitedPendingTxSource) TransactionByHash(ctx context.Context, hash common.Hash) (*ethtypes.Transaction, bool, error) {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return nil, false, err
+	}
+	return s.PendingTxSource.TransactionByHash(ctx, hash)
+}
+
+// rateLimitedLogFilterer acquires a limiter slot before every log query and head lookup
+type rateLimitedLogFilterer struct {
+	LogFilterer
+	limiter *RPCLimiter
+}
+
+func (f *rateLimitedLogFilterer) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethtypes.Log, error) {
+	if err := f.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return f.LogFilterer.FilterLogs(ctx, q)
+}
+
+func (f *rateLimitedLogFilterer) BlockNumber(ctx context.Context) (uint64, error) {
+	if err := f.limiter.Wait(ctx); err != nil {
+		return 0, err
+	}
+	return f.LogFilterer.BlockNumber(ctx)
+}
@@ -0,0 +1,105 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"chainpulse/shared/metrics"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newRPCLimiterMetrics(t *testing.T) (*metrics.Metrics, *prometheus.Registry) {
+	m := &metrics.Metrics{
+		RPCLimiterWait: prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_rpc_limiter_wait_seconds"}),
+	}
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(m.RPCLimiterWait); err != nil {
+		t.Fatal(err)
+	}
+	return m, registry
+}
+
+func TestRPCLimiter_SharedAcrossSubsystems(t *testing.T) {
+	const (
+		requestsPerSecond = 50
+		callsPerSubsystem = 10
+	)
+	m, registry := newRPCLimiterMetrics(t)
+	limiter := NewRPCLimiter(requestsPerSecond, m)
+
+	// One subsystem acquires directly like the processor methods, the other through
+	// the mempool transaction lookups
+	ep := &EventProcessor{RPCLimiter: limiter}
+	source := &rateLimitedPendingTxSource{PendingTxSource: &mockPendingTxSource{}, limiter: limiter}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < callsPerSubsystem; i++ {
+			if err := ep.waitRPC(context.Background()); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < callsPerSubsystem; i++ {
+			// The mock source has no transactions, only the limiter matters here
+			source.TransactionByHash(context.Background(), common.HexToHash("0x01"))
+		}
+	}()
+	wg.Wait()
+
+	// 20 calls at 50 per second need at least 19 intervals of 20ms
+	total := 2 * callsPerSubsystem
+	minElapsed := time.Duration(total-1) * time.Second / requestsPerSecond
+	if elapsed := time.Since(start); elapsed < minElapsed {
+		t.Errorf("Expected %d calls to take at least %v, took %v", total, minElapsed, elapsed)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := families[0].GetMetric()[0].GetHistogram().GetSampleCount(); count != uint64(total) {
+		t.Errorf("Expected %d recorded waits, got %d", total, count)
+	}
+}
+
+func TestRPCLimiter_WaitCancelled(t *testing.T) {
+	limiter := NewRPCLimiter(1, nil)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Expected the first call not to wait, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestRPCLimiter_Unlimited(t *testing.T) {
+	limiter := NewRPCLimiter(0, nil)
+	if limiter != nil {
+		t.Fatalf("Expected no limiter for a rate of 0, got %+v", limiter)
+	}
+
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected a nil limiter not to wait, took %v", elapsed)
+	}
+}
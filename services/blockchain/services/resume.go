@@ -2,33 +2,120 @@ package blockchain
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"os"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
-	json "github.com/goccy/go-json"
-
-	"chainpulse/shared/database"
+	"chainpulse/shared/json"
 	"chainpulse/shared/types"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
-// ResumeService handles breakpoint resume and event replay functionality
+// ResumeStore persists the indexing cursor and the events stored by resume and replay
+type ResumeStore interface {
+	GetLastProcessedBlock() (*big.Int, error)
+	SaveLastProcessedBlock(blockNum *big.Int) error
+	StoreEvent(event *types.Event) error
+	GetEventsByBlockRange(fromBlock, toBlock *big.Int) ([]types.IndexedEvent, error)
+}
+
+// ErrReplayInProgress is returned when a replay is requested while another one is running
+var ErrReplayInProgress = errors.New("replay already in progress")
+
+// ResumeService handles breakpoint resume and event replay functionality.
+// Live indexing owns the last processed block, which only ever moves forward; replays
+// track their progress in a separate replay cursor, so replaying old blocks during live
+// indexing never rewinds it.
 type ResumeService struct {
-	client     *ethclient.Client
-	db         *database.DB
-	mu         sync.Mutex
-	lastBlock  *big.Int
+	// StartBlock is where indexing starts when no block was processed yet; the zero
+	// value starts at block 0. Once a block is processed the cursor is resumed instead.
+	StartBlock StartBlock
+	// ContractStartBlocks overrides StartBlock for the contracts it holds
+	ContractStartBlocks map[common.Address]StartBlock
+	// ChainID is the chain the indexed ranges of a ContractRangeStore are recorded for
+	ChainID string
+
+	client      ChainClient
+	db          ResumeStore
+	mu          sync.Mutex
+	lastBlock   *big.Int
+	replayMu    sync.Mutex // held for the duration of a replay
+	replayBlock *big.Int   // last block of the running or most recent replay, guarded by mu
+}
+
+// StartLatest is the start block spec of the chain head
+const StartLatest = "latest"
+
+// StartBlock is a block indexing starts from: a block number, or the chain head at the
+// time indexing starts when Latest is set
+type StartBlock struct {
+	Number *big.Int
+	Latest bool
+}
+
+// ParseStartBlock parses a start block given as a block number or StartLatest; an empty
+// spec is block 0
+func ParseStartBlock(spec string) (StartBlock, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return StartBlock{}, nil
+	}
+	if strings.EqualFold(spec, StartLatest) {
+		return StartBlock{Latest: true}, nil
+	}
+	number, ok := new(big.Int).SetString(spec, 10)
+	if !ok || number.Sign() < 0 {
+		return StartBlock{}, fmt.Errorf("invalid start block %q: expected a block number or %q", spec, StartLatest)
+	}
+	return StartBlock{Number: number}, nil
+}
+
+// ParseContractStartBlocks parses per-contract start blocks given as comma-separated
+// "address=block" pairs, where block is a block number or StartLatest
+func ParseContractStartBlocks(spec string) (map[common.Address]StartBlock, error) {
+	startBlocks := make(map[common.Address]StartBlock)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		address, block, found := strings.Cut(pair, "=")
+		address = strings.TrimSpace(address)
+		if !found || !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("invalid contract start block %q: expected address=block", pair)
+		}
+		startBlock, err := ParseStartBlock(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid contract start block %q: %v", pair, err)
+		}
+		startBlocks[common.HexToAddress(address)] = startBlock
+	}
+	return startBlocks, nil
+}
+
+// resolve returns the block number of the start block with head as the chain head
+func (b StartBlock) resolve(head *big.Int) *big.Int {
+	switch {
+	case b.Latest:
+		return new(big.Int).Set(head)
+	case b.Number != nil:
+		return new(big.Int).Set(b.Number)
+	default:
+		return big.NewInt(0)
+	}
 }
 
 // NewResumeService creates a new resume service
-func NewResumeService(client *ethclient.Client, db *database.DB) *ResumeService {
+func NewResumeService(client ChainClient, db ResumeStore) *ResumeService {
 	return &ResumeService{
 		client: client,
 		db:     db,
@@ -69,8 +156,49 @@ func (rs *ResumeService) SaveLastProcessedBlock(blockNum *big.Int) error {
 	return nil
 }
 
+// advanceLastProcessedBlock saves blockNum as the last processed block unless the cursor
+// is already past it, so concurrent writers can never move it backward
+func (rs *ResumeService) advanceLastProcessedBlock(blockNum *big.Int) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.lastBlock == nil {
+		current, err := rs.db.GetLastProcessedBlock()
+		if err != nil {
+			return err
+		}
+		rs.lastBlock = current
+	}
+	if rs.lastBlock != nil && rs.lastBlock.Cmp(blockNum) >= 0 {
+		return nil
+	}
+
+	if err := rs.db.SaveLastProcessedBlock(blockNum); err != nil {
+		return err
+	}
+	rs.lastBlock = new(big.Int).Set(blockNum)
+	return nil
+}
+
+// GetReplayProgress returns the last block handled by the running or most recent replay,
+// or nil if nothing has been replayed
+func (rs *ResumeService) GetReplayProgress() *big.Int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.replayBlock == nil {
+		return nil
+	}
+	return new(big.Int).Set(rs.replayBlock)
+}
+
 // ReplayEvents replays events from a specific block range
 func (rs *ResumeService) ReplayEvents(ctx context.Context, fromBlock, toBlock *big.Int) error {
+	if !rs.replayMu.TryLock() {
+		return ErrReplayInProgress
+	}
+	defer rs.replayMu.Unlock()
+
 	log.Printf("Starting event replay from block %s to %s", fromBlock.String(), toBlock.String())
 
 	// Calculate the range
@@ -122,10 +250,10 @@ func (rs *ResumeService) ReplayEvents(ctx context.Context, fromBlock, toBlock *b
 			}
 		}
 		
-		// Update the last processed block after each batch
-		if err := rs.SaveLastProcessedBlock(endBlock); err != nil {
-			return fmt.Errorf("failed to save last processed block: %v", err)
-		}
+		// Record the replay progress after each batch, leaving the live cursor alone
+		rs.mu.Lock()
+		rs.replayBlock = new(big.Int).Set(endBlock)
+		rs.mu.Unlock()
 		
 		// Move to next batch
 		current = new(big.Int).Add(endBlock, big.NewInt(1))
@@ -143,29 +271,101 @@ func (rs *ResumeService) ReplayEvents(ctx context.Context, fromBlock, toBlock *b
 	return nil
 }
 
-// ResumeFromLastBlock resumes indexing from the last processed block
+// ResumeFromLastBlock resumes indexing from the last processed block. Without one, as on
+// a first run, each contract starts from its start block, see StartBlock; so do contracts
+// with a start block override added later, when the store is a ContractRangeStore. The
+// cursor is moved to the current head once every contract is caught up, so the next run
+// resumes after it. An error reading the last processed block is returned rather than
+// treated as a first run.
 func (rs *ResumeService) ResumeFromLastBlock(ctx context.Context, addresses []common.Address) error {
-	lastBlock, err := rs.GetLastProcessedBlock()
-	if err != nil {
-		log.Printf("Could not get last processed block, starting from block 0: %v", err)
-		lastBlock = big.NewInt(0)
-	} else {
-		// Start from the next block after the last processed one
-		lastBlock = new(big.Int).Add(lastBlock, big.NewInt(1))
-	}
-	
 	// Get the current latest block
 	latestBlock, err := rs.client.BlockByNumber(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to get latest block: %v", err)
 	}
-	
-	log.Printf("Resuming from block %s to latest block %s", lastBlock.String(), latestBlock.Number().String())
-	
-	// Process events from last processed block to current
+	head := latestBlock.Number()
+
+	// The store reports block 0 when no block was processed yet
+	lastBlock, err := rs.GetLastProcessedBlock()
+	if err != nil {
+		// Restarting from the start blocks would re-index the whole history
+		return fmt.Errorf("failed to get last processed block: %v", err)
+	}
+	var groups []startGroup
+	if lastBlock.Sign() == 0 {
+		groups = rs.startGroups(addresses, head)
+	} else if groups, err = rs.resumeGroups(addresses, lastBlock, head); err != nil {
+		return err
+	}
+	for _, group := range groups {
+		log.Printf("Catching up %d contracts from block %s to latest block %s", len(group.addresses), group.from.String(), head.String())
+		if err := rs.catchUp(ctx, group.from, head, group.addresses); err != nil {
+			return err
+		}
+		if err := rs.saveGroupRanges(group, head); err != nil {
+			return err
+		}
+	}
+
+	// Every log up to the head is stored. The cursor is only moved now: groups start
+	// from different blocks, so moving it after an earlier group would skip a later one
+	// that failed.
+	if err := rs.advanceLastProcessedBlock(head); err != nil {
+		return fmt.Errorf("failed to save last processed block: %v", err)
+	}
+	return nil
+}
+
+// startGroup is a set of contracts starting from the same block
+type startGroup struct {
+	from      *big.Int
+	addresses []common.Address
+}
+
+// startGroups groups addresses by the block they start from, with head as the chain
+// head, in ascending order of start block. Without addresses, which queries the logs of
+// every contract, the single group starts from StartBlock.
+func (rs *ResumeService) startGroups(addresses []common.Address, head *big.Int) []startGroup {
+	if len(addresses) == 0 {
+		return []startGroup{{from: rs.StartBlock.resolve(head)}}
+	}
+
+	var groups []startGroup
+	for _, address := range addresses {
+		startBlock, ok := rs.ContractStartBlocks[address]
+		if !ok {
+			startBlock = rs.StartBlock
+		}
+		from := startBlock.resolve(head)
+
+		found := false
+		for i := range groups {
+			if groups[i].from.Cmp(from) == 0 {
+				groups[i].addresses = append(groups[i].addresses, address)
+				found = true
+				break
+			}
+		}
+		if !found {
+			groups = append(groups, startGroup{from: from, addresses: []common.Address{address}})
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].from.Cmp(groups[j].from) < 0 })
+	return groups
+}
+
+// catchUp stores the logs of addresses from block from to block to. It leaves the last
+// processed block to the caller.
+func (rs *ResumeService) catchUp(ctx context.Context, from, to *big.Int, addresses []common.Address) error {
+	if from.Cmp(to) > 0 {
+		return nil
+	}
+
+	// Process events from the start block to current
 	query := ethereum.FilterQuery{
-		FromBlock: lastBlock,
-		ToBlock:   latestBlock.Number(),
+		FromBlock: from,
+		ToBlock:   to,
 		Addresses: addresses,
 	}
 	
@@ -195,11 +395,6 @@ func (rs *ResumeService) ResumeFromLastBlock(ctx context.Context, addresses []co
 		if err := rs.db.StoreEvent(event); err != nil {
 			return fmt.Errorf("failed to store event: %v", err)
 		}
-		
-		// Update the last processed block
-		if err := rs.SaveLastProcessedBlock(big.NewInt(int64(vLog.BlockNumber))); err != nil {
-			return fmt.Errorf("failed to save last processed block: %v", err)
-		}
 	}
 	
 	return nil
@@ -212,15 +407,23 @@ func (rs *ResumeService) ExportEvents(ctx context.Context, fromBlock, toBlock *b
 		return fmt.Errorf("failed to get events for export: %v", err)
 	}
 	
-	file, err := json.MarshalIndent(events, "", "  ")
+	file, err := os.Create(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to marshal events to JSON: %v", err)
+		return fmt.Errorf("failed to create export file: %v", err)
 	}
-	
-	// In a real implementation, you would write this to the actual file
-	// For now, we'll just return the JSON as a string for demonstration
-	log.Printf("Exported %d events to JSON format", len(events))
-	
+	defer file.Close()
+
+	// Encode straight into the file instead of building the whole document in memory
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(events); err != nil {
+		return fmt.Errorf("failed to write events to %s: %v", filePath, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close export file: %v", err)
+	}
+
+	log.Printf("Exported %d events to %s", len(events), filePath)
 	return nil
 }
 
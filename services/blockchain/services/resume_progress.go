@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ContractRangeStore records the block ranges each contract was indexed over. When the
+// ResumeStore implements it, ResumeFromLastBlock records the range every contract was
+// caught up over, so a contract with a start block override that was added after the
+// first run starts from its own start block instead of the shared cursor.
+type ContractRangeStore interface {
+	GetBackfillRanges(contract, chainID string) ([]types.BackfillRange, error)
+	SaveBackfillRange(contract, chainID string, fromBlock, toBlock uint64) error
+}
+
+// resumeGroups groups addresses by the block they resume from after the last processed
+// block lastBlock, with head as the chain head. Contracts resume after lastBlock, except
+// the contracts with a start block override that were never indexed, which start from
+// their start block.
+func (rs *ResumeService) resumeGroups(addresses []common.Address, lastBlock, head *big.Int) ([]startGroup, error) {
+	from := new(big.Int).Add(lastBlock, big.NewInt(1))
+	store, ok := rs.db.(ContractRangeStore)
+	if !ok || len(addresses) == 0 {
+		return []startGroup{{from: from, addresses: addresses}}, nil
+	}
+
+	var resumed, added []common.Address
+	for _, address := range addresses {
+		if _, override := rs.ContractStartBlocks[address]; !override {
+			resumed = append(resumed, address)
+			continue
+		}
+		ranges, err := store.GetBackfillRanges(address.Hex(), rs.ChainID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get indexed ranges of %s: %v", address.Hex(), err)
+		}
+		if len(ranges) > 0 {
+			resumed = append(resumed, address)
+		} else {
+			added = append(added, address)
+		}
+	}
+
+	var groups []startGroup
+	if len(resumed) > 0 {
+		groups = append(groups, startGroup{from: from, addresses: resumed})
+	}
+	if len(added) > 0 {
+		groups = append(groups, rs.startGroups(added, head)...)
+	}
+	return groups, nil
+}
+
+// saveGroupRanges records that the contracts of group were caught up to head, when the
+// store keeps ranges per contract
+func (rs *ResumeService) saveGroupRanges(group startGroup, head *big.Int) error {
+	store, ok := rs.db.(ContractRangeStore)
+	if !ok || group.from.Cmp(head) > 0 {
+		return nil
+	}
+	for _, address := range group.addresses {
+		if err := store.SaveBackfillRange(address.Hex(), rs.ChainID, group.from.Uint64(), head.Uint64()); err != nil {
+			return fmt.Errorf("failed to save indexed range of %s: %v", address.Hex(), err)
+		}
+	}
+	return nil
+}
@@ -2,11 +2,17 @@ package blockchain
 
 import (
 	"context"
+	"errors"
 	"math/big"
+	"strings"
+	"sync"
 	"testing"
 
 	"chainpulse/shared/types"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
@@ -26,6 +32,10 @@ func (m *MockDB) SaveEvent(event *types.IndexedEvent) error {
 	return nil
 }
 
+func (m *MockDB) StoreEvent(event *types.Event) error {
+	return nil
+}
+
 func (m *MockDB) GetEvents(filter *types.EventFilter) ([]types.IndexedEvent, error) {
 	return m.Events, nil
 }
@@ -163,4 +173,291 @@ func TestNewResumeService(t *testing.T) {
 	if resumeService.db != mockDB {
 		t.Error("Expected db to be set correctly")
 	}
-}
\ No newline at end of file
+}
+
+// cursorStore is a ResumeStore safe for concurrent use, recording every cursor saved
+type cursorStore struct {
+	MockDB
+	mu    sync.Mutex
+	saved []*big.Int
+}
+
+func (c *cursorStore) GetLastProcessedBlock() (*big.Int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.MockDB.GetLastProcessedBlock()
+}
+
+func (c *cursorStore) SaveLastProcessedBlock(blockNum *big.Int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.saved = append(c.saved, blockNum)
+	return c.MockDB.SaveLastProcessedBlock(blockNum)
+}
+
+// rangeChainClient serves one log per block of every queried range, up to its head
+type rangeChainClient struct {
+	mockChainClient
+}
+
+func (r *rangeChainClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethtypes.Log, error) {
+	var logs []ethtypes.Log
+	for block := q.FromBlock.Uint64(); block <= q.ToBlock.Uint64(); block++ {
+		logs = append(logs, ethtypes.Log{BlockNumber: block, TxHash: common.BigToHash(new(big.Int).SetUint64(block))})
+	}
+	return logs, nil
+}
+
+func (r *rangeChainClient) BlockByNumber(ctx context.Context, number *big.Int) (*ethtypes.Block, error) {
+	return ethtypes.NewBlockWithHeader(&ethtypes.Header{Number: new(big.Int).SetUint64(r.head)}), nil
+}
+
+func TestResumeService_ReplayDoesNotRewindLiveCursor(t *testing.T) {
+	store := &cursorStore{MockDB: MockDB{LastBlock: big.NewInt(500)}}
+	resumeService := NewResumeService(&rangeChainClient{mockChainClient{head: 520}}, store)
+	ctx := context.Background()
+
+	// An admin replay of old blocks runs while live indexing resumes
+	var wg sync.WaitGroup
+	var replayErr, resumeErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		replayErr = resumeService.ReplayEvents(ctx, big.NewInt(100), big.NewInt(200))
+	}()
+	go func() {
+		defer wg.Done()
+		resumeErr = resumeService.ResumeFromLastBlock(ctx, nil)
+	}()
+	wg.Wait()
+	if replayErr != nil || resumeErr != nil {
+		t.Fatalf("Expected no errors, got replay %v and resume %v", replayErr, resumeErr)
+	}
+
+	previous := big.NewInt(500)
+	for _, saved := range store.saved {
+		if saved.Cmp(previous) <= 0 {
+			t.Fatalf("Expected the live cursor to only move forward from %s, got %s", previous, saved)
+		}
+		previous = saved
+	}
+	if store.LastBlock.Cmp(big.NewInt(520)) != 0 {
+		t.Errorf("Expected the live cursor at block 520, got %s", store.LastBlock)
+	}
+	if progress := resumeService.GetReplayProgress(); progress == nil || progress.Cmp(big.NewInt(200)) != 0 {
+		t.Errorf("Expected the replay cursor at block 200, got %v", progress)
+	}
+
+	// Saving an older block through the live path does not rewind it either
+	if err := resumeService.advanceLastProcessedBlock(big.NewInt(150)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if store.LastBlock.Cmp(big.NewInt(520)) != 0 {
+		t.Errorf("Expected the live cursor to stay at block 520, got %s", store.LastBlock)
+	}
+}
+
+func TestResumeService_ConcurrentReplayRejected(t *testing.T) {
+	resumeService := NewResumeService(&rangeChainClient{}, &cursorStore{})
+	resumeService.replayMu.Lock()
+	defer resumeService.replayMu.Unlock()
+
+	err := resumeService.ReplayEvents(context.Background(), big.NewInt(1), big.NewInt(2))
+	if !errors.Is(err, ErrReplayInProgress) {
+		t.Errorf("Expected ErrReplayInProgress, got %v", err)
+	}
+}
+
+// recordingRangeClient is a rangeChainClient recording the filter queries it receives
+type recordingRangeClient struct {
+	rangeChainClient
+	queries []ethereum.FilterQuery
+}
+
+func (r *recordingRangeClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethtypes.Log, error) {
+	r.queries = append(r.queries, q)
+	return r.rangeChainClient.FilterLogs(ctx, q)
+}
+
+func TestResumeService_StartsFromLatestWithoutCursor(t *testing.T) {
+	client := &recordingRangeClient{rangeChainClient: rangeChainClient{mockChainClient{head: 1000}}}
+	store := &cursorStore{}
+	resumeService := NewResumeService(client, store)
+	resumeService.StartBlock = StartBlock{Latest: true}
+
+	if err := resumeService.ResumeFromLastBlock(context.Background(), nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(client.queries) != 1 {
+		t.Fatalf("Expected 1 filter query, got %d", len(client.queries))
+	}
+	if query := client.queries[0]; query.FromBlock.Int64() != 1000 || query.ToBlock.Int64() != 1000 {
+		t.Errorf("Expected to index from the head at block 1000, got %v to %v", query.FromBlock, query.ToBlock)
+	}
+	if store.LastBlock == nil || store.LastBlock.Int64() != 1000 {
+		t.Errorf("Expected the cursor at the head, got %v", store.LastBlock)
+	}
+}
+
+func TestResumeService_ContractStartBlocks(t *testing.T) {
+	early := common.HexToAddress("0x1")
+	late := common.HexToAddress("0x2")
+	client := &recordingRangeClient{rangeChainClient: rangeChainClient{mockChainClient{head: 1000}}}
+	resumeService := NewResumeService(client, &cursorStore{})
+	resumeService.StartBlock = StartBlock{Number: big.NewInt(900)}
+	resumeService.ContractStartBlocks = map[common.Address]StartBlock{late: {Latest: true}}
+
+	if err := resumeService.ResumeFromLastBlock(context.Background(), []common.Address{late, early}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(client.queries) != 2 {
+		t.Fatalf("Expected 2 filter queries, got %d", len(client.queries))
+	}
+	if query := client.queries[0]; query.FromBlock.Int64() != 900 || len(query.Addresses) != 1 || query.Addresses[0] != early {
+		t.Errorf("Expected %s from block 900, got %v from %v", early.Hex(), query.Addresses, query.FromBlock)
+	}
+	if query := client.queries[1]; query.FromBlock.Int64() != 1000 || len(query.Addresses) != 1 || query.Addresses[0] != late {
+		t.Errorf("Expected %s from block 1000, got %v from %v", late.Hex(), query.Addresses, query.FromBlock)
+	}
+}
+
+func TestResumeService_StartBlockIgnoredWithCursor(t *testing.T) {
+	client := &recordingRangeClient{rangeChainClient: rangeChainClient{mockChainClient{head: 1000}}}
+	resumeService := NewResumeService(client, &cursorStore{MockDB: MockDB{LastBlock: big.NewInt(950)}})
+	resumeService.StartBlock = StartBlock{Latest: true}
+
+	if err := resumeService.ResumeFromLastBlock(context.Background(), nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(client.queries) != 1 || client.queries[0].FromBlock.Int64() != 951 {
+		t.Errorf("Expected to resume after the cursor at block 951, got %+v", client.queries)
+	}
+}
+
+// rangeCursorStore is a cursorStore keeping the indexed ranges of each contract
+type rangeCursorStore struct {
+	cursorStore
+	ranges map[string][]types.BackfillRange
+}
+
+func (r *rangeCursorStore) GetBackfillRanges(contract, chainID string) ([]types.BackfillRange, error) {
+	return r.ranges[strings.ToLower(contract)], nil
+}
+
+func (r *rangeCursorStore) SaveBackfillRange(contract, chainID string, fromBlock, toBlock uint64) error {
+	contract = strings.ToLower(contract)
+	r.ranges[contract] = append(r.ranges[contract], types.BackfillRange{Contract: contract, ChainID: chainID, FromBlock: fromBlock, ToBlock: toBlock})
+	return nil
+}
+
+func TestResumeService_ContractStartBlockOfAddedContract(t *testing.T) {
+	indexed := common.HexToAddress("0x1")
+	added := common.HexToAddress("0x2")
+	client := &recordingRangeClient{rangeChainClient: rangeChainClient{mockChainClient{head: 1000}}}
+	store := &rangeCursorStore{
+		cursorStore: cursorStore{MockDB: MockDB{LastBlock: big.NewInt(950)}},
+		ranges:      map[string][]types.BackfillRange{strings.ToLower(indexed.Hex()): {{FromBlock: 800, ToBlock: 950}}},
+	}
+	resumeService := NewResumeService(client, store)
+	resumeService.ContractStartBlocks = map[common.Address]StartBlock{
+		indexed: {Number: big.NewInt(800)},
+		added:   {Number: big.NewInt(900)},
+	}
+
+	if err := resumeService.ResumeFromLastBlock(context.Background(), []common.Address{indexed, added}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(client.queries) != 2 {
+		t.Fatalf("Expected 2 filter queries, got %d", len(client.queries))
+	}
+	if query := client.queries[0]; query.FromBlock.Int64() != 951 || len(query.Addresses) != 1 || query.Addresses[0] != indexed {
+		t.Errorf("Expected %s to resume from block 951, got %v from %v", indexed.Hex(), query.Addresses, query.FromBlock)
+	}
+	if query := client.queries[1]; query.FromBlock.Int64() != 900 || len(query.Addresses) != 1 || query.Addresses[0] != added {
+		t.Errorf("Expected %s to start from block 900, got %v from %v", added.Hex(), query.Addresses, query.FromBlock)
+	}
+	if ranges := store.ranges[strings.ToLower(added.Hex())]; len(ranges) != 1 || ranges[0].FromBlock != 900 || ranges[0].ToBlock != 1000 {
+		t.Errorf("Expected the added contract indexed from block 900 to 1000, got %v", ranges)
+	}
+}
+
+// failingRangeClient is a recordingRangeClient failing the queries from failFrom
+type failingRangeClient struct {
+	recordingRangeClient
+	failFrom int64
+}
+
+func (f *failingRangeClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethtypes.Log, error) {
+	if q.FromBlock.Int64() == f.failFrom {
+		return nil, errors.New("node unavailable")
+	}
+	return f.recordingRangeClient.FilterLogs(ctx, q)
+}
+
+func TestResumeService_CursorKeptWhenAGroupFails(t *testing.T) {
+	early := common.HexToAddress("0x1")
+	late := common.HexToAddress("0x2")
+	client := &failingRangeClient{recordingRangeClient: recordingRangeClient{rangeChainClient: rangeChainClient{mockChainClient{head: 1000}}}, failFrom: 950}
+	store := &cursorStore{}
+	resumeService := NewResumeService(client, store)
+	resumeService.StartBlock = StartBlock{Number: big.NewInt(900)}
+	resumeService.ContractStartBlocks = map[common.Address]StartBlock{late: {Number: big.NewInt(950)}}
+
+	if err := resumeService.ResumeFromLastBlock(context.Background(), []common.Address{early, late}); err == nil {
+		t.Fatal("Expected the failed catch-up to be returned")
+	}
+
+	// The earlier group was caught up, but the later one still has to be on restart
+	if len(store.saved) != 0 {
+		t.Errorf("Expected the cursor not to move, got %v", store.saved)
+	}
+}
+
+// unreadableCursorStore fails to read the last processed block
+type unreadableCursorStore struct {
+	cursorStore
+}
+
+func (c *unreadableCursorStore) GetLastProcessedBlock() (*big.Int, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestResumeService_CursorReadErrorIsReturned(t *testing.T) {
+	client := &recordingRangeClient{rangeChainClient: rangeChainClient{mockChainClient{head: 1000}}}
+	store := &unreadableCursorStore{}
+	resumeService := NewResumeService(client, store)
+	resumeService.StartBlock = StartBlock{Number: big.NewInt(0)}
+
+	if err := resumeService.ResumeFromLastBlock(context.Background(), nil); err == nil {
+		t.Fatal("Expected the cursor read error to be returned")
+	}
+	if len(client.queries) != 0 {
+		t.Errorf("Expected no logs to be fetched from the start block, got %d queries", len(client.queries))
+	}
+	if store.LastBlock != nil {
+		t.Errorf("Expected the cursor not to move, got %v", store.LastBlock)
+	}
+}
+
+func TestParseContractStartBlocks(t *testing.T) {
+	startBlocks, err := ParseContractStartBlocks(" 0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D=12287507, 0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48=latest")
+	if err != nil {
+		t.Fatalf("Expected the start blocks to parse, got %v", err)
+	}
+	if block := startBlocks[common.HexToAddress("0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D")]; block.Number == nil || block.Number.Int64() != 12287507 {
+		t.Errorf("Expected start block 12287507, got %+v", block)
+	}
+	if block := startBlocks[common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")]; !block.Latest {
+		t.Errorf("Expected the latest start block, got %+v", block)
+	}
+
+	for _, spec := range []string{"0x1", "not-an-address=1", "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D=-1", "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D=head"} {
+		if _, err := ParseContractStartBlocks(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
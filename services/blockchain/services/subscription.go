@@ -0,0 +1,333 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// DefaultMaxAddressesPerSubscription keeps each log subscription within common provider limits
+	DefaultMaxAddressesPerSubscription = 100
+	// DefaultResubscribeBackoff is the initial delay before a failed shard resubscribes
+	DefaultResubscribeBackoff = time.Second
+	// maxResubscribeBackoff caps the exponential backoff between resubscription attempts
+	maxResubscribeBackoff = time.Minute
+)
+
+// LogSubscriber is the subset of the Ethereum client used for log subscriptions
+type LogSubscriber interface {
+	SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- ethtypes.Log) (ethereum.Subscription, error)
+}
+
+// LogFilterer is the subset of the Ethereum client used to re-fetch the logs a rotated
+// subscription may have missed
+type LogFilterer interface {
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethtypes.Log, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// ShardedLogSubscription splits a filter over many addresses into several subscriptions
+// of at most MaxAddresses each and multiplexes them into one channel. Each shard
+// resubscribes independently, so a failing shard does not affect the others.
+type ShardedLogSubscription struct {
+	Subscriber      LogSubscriber
+	MaxAddresses    int
+	ResubscribeWait time.Duration
+	// MaxAge rotates a shard subscription once it is this old, as some providers let
+	// long-lived subscriptions go stale without an error. 0 never rotates.
+	MaxAge time.Duration
+	// Filterer re-fetches the logs since the last block a rotated shard delivered, so
+	// none are missed if its subscription went stale. Rotation requires it.
+	Filterer LogFilterer
+}
+
+// logKey identifies a log delivered by a shard
+type logKey struct {
+	blockHash common.Hash
+	index     uint
+	removed   bool
+}
+
+// shardCursor tracks the logs a shard forwarded, to re-fetch them from the last block
+// after a rotation without forwarding any twice
+type shardCursor struct {
+	known     bool            // whether block is known, it is not when the head could not be read
+	block     uint64          // highest block forwarded from, or the head when the shard started
+	forwarded map[logKey]bool // logs forwarded from block
+	// refetched holds the logs forwarded by the last catch-up until the subscription
+	// delivers a block past refetchedTo, as it may deliver them again
+	refetched   map[logKey]bool
+	refetchedTo uint64
+}
+
+func keyOf(vLog ethtypes.Log) logKey {
+	return logKey{blockHash: vLog.BlockHash, index: vLog.Index, removed: vLog.Removed}
+}
+
+// seen reports whether vLog was already forwarded by the shard
+func (c *shardCursor) seen(vLog ethtypes.Log) bool {
+	key := keyOf(vLog)
+	if c.refetched[key] {
+		return true
+	}
+	return c.known && vLog.BlockNumber == c.block && c.forwarded[key]
+}
+
+// record remembers that vLog was forwarded
+func (c *shardCursor) record(vLog ethtypes.Log) {
+	if !c.known || vLog.BlockNumber > c.block {
+		c.known = true
+		c.block = vLog.BlockNumber
+		c.forwarded = make(map[logKey]bool)
+	}
+	if vLog.BlockNumber == c.block {
+		c.forwarded[keyOf(vLog)] = true
+	}
+	if c.refetched != nil && vLog.BlockNumber > c.refetchedTo {
+		c.refetched = nil
+	}
+}
+
+// shardAddresses splits addresses into groups of at most size addresses
+func shardAddresses(addresses []common.Address, size int) [][]common.Address {
+	if size <= 0 || len(addresses) <= size {
+		return [][]common.Address{addresses}
+	}
+
+	shards := make([][]common.Address, 0, (len(addresses)+size-1)/size)
+	for start := 0; start < len(addresses); start += size {
+		end := start + size
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+		shards = append(shards, addresses[start:end])
+	}
+	return shards
+}
+
+// Subscribe starts one subscription per address shard and returns the multiplexed
+// log and error channels. It fails only if no shard could subscribe; shards that
+// failed initially keep retrying in the background. Both channels are closed once
+// ctx is cancelled and all shards have stopped.
+func (s *ShardedLogSubscription) Subscribe(ctx context.Context, query ethereum.FilterQuery) (<-chan ethtypes.Log, <-chan error, error) {
+	shards := shardAddresses(query.Addresses, s.MaxAddresses)
+
+	type shardState struct {
+		query     ethereum.FilterQuery
+		sub       ethereum.Subscription
+		shardLogs chan ethtypes.Log
+	}
+
+	states := make([]shardState, len(shards))
+	var lastErr error
+	subscribed := 0
+
+	for i, addresses := range shards {
+		states[i].query = query
+		states[i].query.Addresses = addresses
+
+		sub, shardLogs, err := s.subscribeShard(ctx, states[i].query)
+		if err != nil {
+			log.Printf("Failed to subscribe shard %d/%d (%d addresses): %v", i+1, len(shards), len(addresses), err)
+			lastErr = err
+			continue
+		}
+		states[i].sub = sub
+		states[i].shardLogs = shardLogs
+		subscribed++
+	}
+
+	if subscribed == 0 {
+		return nil, nil, fmt.Errorf("failed to subscribe any of %d shards: %v", len(shards), lastErr)
+	}
+
+	logs := make(chan ethtypes.Log)
+	errs := make(chan error)
+
+	var wg sync.WaitGroup
+	for i, state := range states {
+		wg.Add(1)
+		go func(shard int, state shardState) {
+			defer wg.Done()
+			s.runShard(ctx, shard, state.query, state.sub, state.shardLogs, logs, errs)
+		}(i, state)
+	}
+
+	go func() {
+		wg.Wait()
+		close(logs)
+		close(errs)
+	}()
+
+	return logs, errs, nil
+}
+
+func (s *ShardedLogSubscription) subscribeShard(ctx context.Context, query ethereum.FilterQuery) (ethereum.Subscription, chan ethtypes.Log, error) {
+	shardLogs := make(chan ethtypes.Log)
+	sub, err := s.Subscriber.SubscribeFilterLogs(ctx, query, shardLogs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sub, shardLogs, nil
+}
+
+// runShard forwards logs from a shard subscription and resubscribes with backoff when it
+// fails. With MaxAge and Filterer set, the subscription is also rotated once it is MaxAge
+// old: a new subscription is opened before the old one is closed, then the logs since
+// the last block forwarded are re-fetched to fill any gap left by a stale subscription.
+func (s *ShardedLogSubscription) runShard(ctx context.Context, shard int, query ethereum.FilterQuery, sub ethereum.Subscription, shardLogs chan ethtypes.Log, out chan<- ethtypes.Log, errs chan<- error) {
+	backoff := s.ResubscribeWait
+	if backoff <= 0 {
+		backoff = DefaultResubscribeBackoff
+	}
+	wait := backoff
+
+	cursor := &shardCursor{}
+	var rotation *time.Timer
+	var rotate <-chan time.Time
+	if s.MaxAge > 0 && s.Filterer != nil {
+		if head, err := s.Filterer.BlockNumber(ctx); err != nil {
+			s.reportError(ctx, errs, fmt.Errorf("shard %d failed to get the head block: %v", shard, err))
+		} else {
+			cursor.known, cursor.block, cursor.forwarded = true, head, make(map[logKey]bool)
+		}
+		rotation = time.NewTimer(s.MaxAge)
+		defer rotation.Stop()
+		rotate = rotation.C
+	}
+	// restartRotation schedules the next rotation d from now
+	restartRotation := func(d time.Duration) {
+		if rotation == nil {
+			return
+		}
+		if !rotation.Stop() {
+			select {
+			case <-rotation.C:
+			default:
+			}
+		}
+		rotation.Reset(d)
+	}
+
+	// forward sends vLog on unless it was forwarded already, tracking the logs forwarded
+	// when the subscription is rotated
+	forward := func(vLog ethtypes.Log) bool {
+		if rotation != nil && cursor.seen(vLog) {
+			return true
+		}
+		select {
+		case out <- vLog:
+			if rotation != nil {
+				cursor.record(vLog)
+			}
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		if sub == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+
+			var err error
+			sub, shardLogs, err = s.subscribeShard(ctx, query)
+			if err != nil {
+				s.reportError(ctx, errs, fmt.Errorf("shard %d resubscribe failed: %v", shard, err))
+				wait *= 2
+				if wait > maxResubscribeBackoff {
+					wait = maxResubscribeBackoff
+				}
+				continue
+			}
+			wait = backoff
+			restartRotation(s.MaxAge)
+		}
+
+		select {
+		case vLog := <-shardLogs:
+			if !forward(vLog) {
+				sub.Unsubscribe()
+				return
+			}
+		case err := <-sub.Err():
+			sub.Unsubscribe()
+			sub = nil
+			s.reportError(ctx, errs, fmt.Errorf("shard %d subscription error: %v", shard, err))
+		case <-rotate:
+			// Open the new subscription first so no log is lost while switching
+			newSub, newLogs, err := s.subscribeShard(ctx, query)
+			if err != nil {
+				s.reportError(ctx, errs, fmt.Errorf("shard %d rotation failed: %v", shard, err))
+				rotation.Reset(backoff)
+				continue
+			}
+			sub.Unsubscribe()
+			sub, shardLogs = newSub, newLogs
+			rotation.Reset(s.MaxAge)
+
+			if err := s.catchUp(ctx, query, cursor, forward); err != nil {
+				s.reportError(ctx, errs, fmt.Errorf("shard %d failed to re-fetch logs after rotation: %v", shard, err))
+			}
+			if ctx.Err() != nil {
+				sub.Unsubscribe()
+				return
+			}
+		case <-ctx.Done():
+			sub.Unsubscribe()
+			return
+		}
+	}
+}
+
+// catchUp forwards the logs of query from the last block the shard forwarded, skipping
+// those already forwarded. The rotated subscription may deliver them again, so they
+// are remembered until it delivers a later block.
+func (s *ShardedLogSubscription) catchUp(ctx context.Context, query ethereum.FilterQuery, cursor *shardCursor, forward func(ethtypes.Log) bool) error {
+	if !cursor.known {
+		return nil
+	}
+
+	query.FromBlock = new(big.Int).SetUint64(cursor.block)
+	query.ToBlock = nil
+	logs, err := s.Filterer.FilterLogs(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	refetched := make(map[logKey]bool, len(logs))
+	var refetchedTo uint64
+	for _, vLog := range logs {
+		if !forward(vLog) {
+			return ctx.Err()
+		}
+		refetched[keyOf(vLog)] = true
+		if vLog.BlockNumber > refetchedTo {
+			refetchedTo = vLog.BlockNumber
+		}
+	}
+	if len(refetched) > 0 {
+		cursor.refetched, cursor.refetchedTo = refetched, refetchedTo
+	}
+	return nil
+}
+
+// reportError forwards a shard error without blocking past cancellation
+func (s *ShardedLogSubscription) reportError(ctx context.Context, errs chan<- error, err error) {
+	select {
+	case errs <- err:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,264 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// mockSubscription is a controllable ethereum.Subscription
+type mockSubscription struct {
+	errCh chan error
+	once  sync.Once
+}
+
+func (s *mockSubscription) Unsubscribe() {
+	s.once.Do(func() { close(s.errCh) })
+}
+
+func (s *mockSubscription) Err() <-chan error {
+	return s.errCh
+}
+
+// mockLogSubscriber records each subscription and its log channel
+type mockLogSubscriber struct {
+	mu      sync.Mutex
+	queries []ethereum.FilterQuery
+	sinks   []chan<- ethtypes.Log
+	subs    []*mockSubscription
+}
+
+func (m *mockLogSubscriber) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- ethtypes.Log) (ethereum.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queries = append(m.queries, q)
+	m.sinks = append(m.sinks, ch)
+	sub := &mockSubscription{errCh: make(chan error, 1)}
+	m.subs = append(m.subs, sub)
+	return sub, nil
+}
+
+func TestShardedLogSubscription(t *testing.T) {
+	addresses := make([]common.Address, 250)
+	for i := range addresses {
+		addresses[i] = common.BigToAddress(big.NewInt(int64(i + 1)))
+	}
+
+	subscriber := &mockLogSubscriber{}
+	subscription := &ShardedLogSubscription{Subscriber: subscriber, MaxAddresses: 100}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logs, _, err := subscription.Subscribe(ctx, ethereum.FilterQuery{Addresses: addresses})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	if len(subscriber.queries) != 3 {
+		t.Fatalf("Expected 3 shards, got %d", len(subscriber.queries))
+	}
+	expectedSizes := []int{100, 100, 50}
+	for i, query := range subscriber.queries {
+		if len(query.Addresses) != expectedSizes[i] {
+			t.Errorf("Expected shard %d to have %d addresses, got %d", i, expectedSizes[i], len(query.Addresses))
+		}
+	}
+
+	// Every shard feeds the same output channel
+	for i, sink := range subscriber.sinks {
+		go func(i int, sink chan<- ethtypes.Log) {
+			sink <- ethtypes.Log{Address: subscriber.queries[i].Addresses[0]}
+		}(i, sink)
+	}
+
+	received := make(map[common.Address]bool)
+	for len(received) < 3 {
+		select {
+		case vLog := <-logs:
+			received[vLog.Address] = true
+		case <-time.After(time.Second):
+			t.Fatalf("Expected logs from 3 shards, got %d", len(received))
+		}
+	}
+	for i, query := range subscriber.queries {
+		if !received[query.Addresses[0]] {
+			t.Errorf("Expected a log from shard %d", i)
+		}
+	}
+}
+
+func TestShardedLogSubscriptionResubscribe(t *testing.T) {
+	addresses := make([]common.Address, 150)
+	for i := range addresses {
+		addresses[i] = common.BigToAddress(big.NewInt(int64(i + 1)))
+	}
+
+	subscriber := &mockLogSubscriber{}
+	subscription := &ShardedLogSubscription{
+		Subscriber:      subscriber,
+		MaxAddresses:    100,
+		ResubscribeWait: time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, errs, err := subscription.Subscribe(ctx, ethereum.FilterQuery{Addresses: addresses})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	// Fail only the second shard
+	subscriber.mu.Lock()
+	failing := subscriber.queries[1]
+	subscriber.subs[1].errCh <- errors.New("connection lost")
+	subscriber.mu.Unlock()
+
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("Expected shard error to be reported")
+	}
+
+	deadline := time.After(time.Second)
+	for {
+		subscriber.mu.Lock()
+		count := len(subscriber.queries)
+		var last ethereum.FilterQuery
+		if count > 0 {
+			last = subscriber.queries[count-1]
+		}
+		subscriber.mu.Unlock()
+
+		if count == 3 {
+			if len(last.Addresses) != len(failing.Addresses) || last.Addresses[0] != failing.Addresses[0] {
+				t.Errorf("Expected only the failed shard to resubscribe")
+			}
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("Expected 3 subscriptions after resubscribe, got %d", count)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+// mockLogFilterer serves the logs of a chain from the requested block on
+type mockLogFilterer struct {
+	mu      sync.Mutex
+	head    uint64
+	logs    []ethtypes.Log
+	queries []ethereum.FilterQuery
+}
+
+func (m *mockLogFilterer) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethtypes.Log, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queries = append(m.queries, q)
+	var logs []ethtypes.Log
+	for _, vLog := range m.logs {
+		if vLog.BlockNumber >= q.FromBlock.Uint64() {
+			logs = append(logs, vLog)
+		}
+	}
+	return logs, nil
+}
+
+func (m *mockLogFilterer) BlockNumber(ctx context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.head, nil
+}
+
+func blockLog(block uint64, index uint) ethtypes.Log {
+	return ethtypes.Log{BlockNumber: block, BlockHash: common.BigToHash(new(big.Int).SetUint64(block)), Index: index}
+}
+
+func TestShardedLogSubscriptionRotatesWithoutGaps(t *testing.T) {
+	subscriber := &mockLogSubscriber{}
+	filterer := &mockLogFilterer{head: 9}
+	subscription := &ShardedLogSubscription{
+		Subscriber: subscriber,
+		MaxAge:     100 * time.Millisecond,
+		Filterer:   filterer,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logs, _, err := subscription.Subscribe(ctx, ethereum.FilterQuery{Addresses: []common.Address{common.HexToAddress("0x1")}})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	expectLogs := func(expected ...ethtypes.Log) {
+		t.Helper()
+		for _, want := range expected {
+			select {
+			case vLog := <-logs:
+				if vLog.BlockNumber != want.BlockNumber || vLog.Index != want.Index {
+					t.Fatalf("Expected log %d/%d, got %d/%d", want.BlockNumber, want.Index, vLog.BlockNumber, vLog.Index)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Expected log %d/%d", want.BlockNumber, want.Index)
+			}
+		}
+	}
+	send := func(sink chan<- ethtypes.Log, vLogs ...ethtypes.Log) {
+		go func() {
+			for _, vLog := range vLogs {
+				select {
+				case sink <- vLog:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	subscriber.mu.Lock()
+	first := subscriber.sinks[0]
+	subscriber.mu.Unlock()
+	send(first, blockLog(10, 0))
+	expectLogs(blockLog(10, 0))
+
+	// The subscription goes stale while the chain moves on
+	filterer.mu.Lock()
+	filterer.logs = []ethtypes.Log{blockLog(10, 0), blockLog(10, 1), blockLog(11, 0)}
+	filterer.head = 11
+	filterer.mu.Unlock()
+
+	// Once rotated, the logs missed since block 10 are re-fetched, without repeating 10/0
+	expectLogs(blockLog(10, 1), blockLog(11, 0))
+
+	subscriber.mu.Lock()
+	if len(subscriber.sinks) != 2 {
+		t.Fatalf("Expected 2 subscriptions after the rotation, got %d", len(subscriber.sinks))
+	}
+	second := subscriber.sinks[1]
+	select {
+	case <-subscriber.subs[0].errCh:
+	default:
+		t.Error("Expected the stale subscription to be unsubscribed")
+	}
+	subscriber.mu.Unlock()
+
+	filterer.mu.Lock()
+	if len(filterer.queries) != 1 || filterer.queries[0].FromBlock.Uint64() != 10 {
+		t.Errorf("Expected logs to be re-fetched from block 10, got %+v", filterer.queries)
+	}
+	filterer.mu.Unlock()
+
+	// The new subscription repeats a re-fetched log before delivering new ones
+	send(second, blockLog(11, 0), blockLog(12, 0))
+	expectLogs(blockLog(12, 0))
+}
@@ -0,0 +1,101 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// decodedTransfer holds the parameters of a Transfer log; Amount is the value of a
+// token transfer and the token ID of an NFT transfer
+type decodedTransfer struct {
+	From   common.Address
+	To     common.Address
+	Amount *big.Int
+}
+
+// decodeTransferLog decodes a Transfer(address,address,uint256) log. Standard ERC-20
+// tokens emit the amount in data, while ERC-721 and some non-standard ERC-20 tokens
+// index it as a fourth topic and leave data empty. The registered ABI's indexed flag
+// decides where to look first; the other place is tried when the first does not hold
+// the amount, so both variants decode whichever ABI is registered.
+func (ep *EventProcessor) decodeTransferLog(vLog ethtypes.Log) (*decodedTransfer, error) {
+	if len(vLog.Topics) < 3 {
+		return nil, fmt.Errorf("transfer log has %d topics, expected at least 3", len(vLog.Topics))
+	}
+
+	transfer := &decodedTransfer{
+		From: common.BytesToAddress(vLog.Topics[1].Bytes()),
+		To:   common.BytesToAddress(vLog.Topics[2].Bytes()),
+	}
+
+	first, second := ep.transferAmountFromData, transferAmountFromTopic
+	if ep.transferAmountIndexed(vLog) {
+		first, second = transferAmountFromTopic, ep.transferAmountFromData
+	}
+
+	amount, ok := first(vLog)
+	if !ok {
+		amount, ok = second(vLog)
+	}
+	if !ok {
+		return nil, fmt.Errorf("transfer log has no amount in data (%d bytes) or topics (%d)", len(vLog.Data), len(vLog.Topics))
+	}
+	transfer.Amount = amount
+
+	return transfer, nil
+}
+
+// transferAmountIndexed reports whether the amount of a Transfer log is expected in the
+// topics: as declared by the registered ABI, or by the topic count without one
+func (ep *EventProcessor) transferAmountIndexed(vLog ethtypes.Log) bool {
+	event, ok := ep.ABI.Events["Transfer"]
+	if !ok || len(event.Inputs) != 3 {
+		return len(vLog.Topics) > 3
+	}
+	return event.Inputs[2].Indexed
+}
+
+// transferAmountFromData unpacks the amount from the log data with the registered ABI,
+// falling back to reading the data as a single uint256 word
+func (ep *EventProcessor) transferAmountFromData(vLog ethtypes.Log) (*big.Int, bool) {
+	if len(vLog.Data) == 0 {
+		return nil, false
+	}
+
+	if values, err := ep.ABI.Unpack("Transfer", vLog.Data); err == nil && len(values) == 1 {
+		if amount, ok := values[0].(*big.Int); ok {
+			return amount, true
+		}
+	}
+
+	if len(vLog.Data) == common.HashLength {
+		return new(big.Int).SetBytes(vLog.Data), true
+	}
+	return nil, false
+}
+
+// transferAmountFromTopic reads the amount from the fourth topic
+func transferAmountFromTopic(vLog ethtypes.Log) (*big.Int, bool) {
+	if len(vLog.Topics) < 4 {
+		return nil, false
+	}
+	return new(big.Int).SetBytes(vLog.Topics[3].Bytes()), true
+}
+
+// transferData returns the parameters of a Transfer log decoded with the ABI of its
+// contract, such as a verified ABI fetched from the block explorer. Logs no ABI decodes,
+// such as NFT transfers under the ERC-20 processor ABI, get the decoded transfer with
+// its amount named amountName.
+func (ep *EventProcessor) transferData(vLog ethtypes.Log, transfer *decodedTransfer, amountName string) map[string]interface{} {
+	if _, params, err := ep.DecodeLog(vLog); err == nil {
+		return params
+	}
+	return map[string]interface{}{
+		"from":     transfer.From.Hex(),
+		"to":       transfer.To.Hex(),
+		amountName: transfer.Amount.String(),
+	}
+}
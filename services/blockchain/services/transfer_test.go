@@ -0,0 +1,97 @@
+package blockchain
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// transferABI returns a Transfer ABI whose value is indexed or not
+func transferABI(t *testing.T, valueIndexed bool) abi.ABI {
+	indexed := "false"
+	if valueIndexed {
+		indexed = "true"
+	}
+	parsed, err := abi.JSON(strings.NewReader(`[{
+		"anonymous": false,
+		"inputs": [
+			{"indexed": true, "name": "from", "type": "address"},
+			{"indexed": true, "name": "to", "type": "address"},
+			{"indexed": ` + indexed + `, "name": "value", "type": "uint256"}
+		],
+		"name": "Transfer",
+		"type": "event"
+	}]`))
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	return parsed
+}
+
+func TestDecodeTransferLog_Variants(t *testing.T) {
+	from := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	to := common.HexToAddress("0x0000000000000000000000000000000000000002")
+	value, _ := new(big.Int).SetString("1000000000000000000000", 10)
+	signature := transferABI(t, false).Events["Transfer"].ID
+
+	standard := ethtypes.Log{
+		Topics: []common.Hash{signature, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:   common.BigToHash(value).Bytes(),
+	}
+	valueIndexed := ethtypes.Log{
+		Topics: []common.Hash{signature, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes()), common.BigToHash(value)},
+	}
+
+	tests := []struct {
+		name string
+		ep   *EventProcessor
+		log  ethtypes.Log
+	}{
+		{"standard log, standard ABI", &EventProcessor{ABI: transferABI(t, false)}, standard},
+		{"value-indexed log, standard ABI", &EventProcessor{ABI: transferABI(t, false)}, valueIndexed},
+		{"standard log, value-indexed ABI", &EventProcessor{ABI: transferABI(t, true)}, standard},
+		{"value-indexed log, value-indexed ABI", &EventProcessor{ABI: transferABI(t, true)}, valueIndexed},
+		{"standard log, no ABI", &EventProcessor{}, standard},
+		{"value-indexed log, no ABI", &EventProcessor{}, valueIndexed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfer, err := tt.ep.decodeTransferLog(tt.log)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if transfer.From != from {
+				t.Errorf("Expected from %s, got %s", from.Hex(), transfer.From.Hex())
+			}
+			if transfer.To != to {
+				t.Errorf("Expected to %s, got %s", to.Hex(), transfer.To.Hex())
+			}
+			if transfer.Amount == nil || transfer.Amount.Cmp(value) != 0 {
+				t.Errorf("Expected value %s, got %v", value, transfer.Amount)
+			}
+		})
+	}
+}
+
+func TestDecodeTransferLog_MissingAmount(t *testing.T) {
+	ep := &EventProcessor{ABI: transferABI(t, false)}
+	signature := ep.ABI.Events["Transfer"].ID
+
+	// Neither data nor a fourth topic holds the amount
+	_, err := ep.decodeTransferLog(ethtypes.Log{
+		Topics: []common.Hash{signature, {}, {}},
+	})
+	if err == nil {
+		t.Error("Expected error for a transfer log without an amount")
+	}
+
+	_, err = ep.decodeTransferLog(ethtypes.Log{Topics: []common.Hash{signature}})
+	if err == nil {
+		t.Error("Expected error for a transfer log without from and to topics")
+	}
+}
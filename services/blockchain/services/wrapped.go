@@ -0,0 +1,201 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Wrapped-native tokens such as WETH emit these instead of a Transfer when native currency
+// is wrapped or unwrapped
+const (
+	DepositEventSignature    = "Deposit(address,uint256)"
+	WithdrawalEventSignature = "Withdrawal(address,uint256)"
+)
+
+// The wrap and unwrap events are classified by their signature hash, the first topic
+var (
+	depositTopic    = crypto.Keccak256Hash([]byte(DepositEventSignature))
+	withdrawalTopic = crypto.Keccak256Hash([]byte(WithdrawalEventSignature))
+)
+
+// wrappedNativeTopics is the topic count of Deposit and Withdrawal logs, which index the
+// account and keep the amount in the data
+const wrappedNativeTopics = 2
+
+// ConvertWrappedNativeLog converts a Deposit or Withdrawal log, classified by its first
+// topic, into the indexed event stored for it
+func (ep *EventProcessor) ConvertWrappedNativeLog(vLog ethtypes.Log) (*types.IndexedEvent, error) {
+	if len(vLog.Topics) == 0 {
+		return nil, fmt.Errorf("wrapped native log without topics in tx %s", vLog.TxHash.Hex())
+	}
+
+	switch vLog.Topics[0] {
+	case depositTopic:
+		event, err := ep.parseDepositEvent(vLog)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing deposit event: %v", err)
+		}
+		return ep.ConvertDepositToIndexedEvent(event), nil
+	case withdrawalTopic:
+		event, err := ep.parseWithdrawalEvent(vLog)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing withdrawal event: %v", err)
+		}
+		return ep.ConvertWithdrawalToIndexedEvent(event), nil
+	default:
+		return nil, fmt.Errorf("unexpected log with signature %s in tx %s", vLog.Topics[0].Hex(), vLog.TxHash.Hex())
+	}
+}
+
+// parseWrappedNativeLog returns the account and amount of a Deposit or Withdrawal log and
+// the timestamp of its block
+func (ep *EventProcessor) parseWrappedNativeLog(vLog ethtypes.Log) (common.Address, *big.Int, time.Time, error) {
+	if len(vLog.Topics) != wrappedNativeTopics {
+		return common.Address{}, nil, time.Time{}, fmt.Errorf("log has %d topics, expected %d", len(vLog.Topics), wrappedNativeTopics)
+	}
+	if len(vLog.Data) != common.HashLength {
+		return common.Address{}, nil, time.Time{}, fmt.Errorf("log has %d bytes of data, expected %d", len(vLog.Data), common.HashLength)
+	}
+
+	timestamp, err := ep.logTimestamp(vLog)
+	if err != nil {
+		return common.Address{}, nil, time.Time{}, err
+	}
+	return common.BytesToAddress(vLog.Topics[1].Bytes()), new(big.Int).SetBytes(vLog.Data), timestamp, nil
+}
+
+func (ep *EventProcessor) parseDepositEvent(vLog ethtypes.Log) (*types.DepositEvent, error) {
+	account, amount, timestamp, err := ep.parseWrappedNativeLog(vLog)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.DepositEvent{
+		BlockNumber: new(big.Int).SetUint64(vLog.BlockNumber),
+		TxHash:      vLog.TxHash,
+		LogIndex:    vLog.Index,
+		Account:     account,
+		Amount:      amount,
+		Contract:    vLog.Address,
+		Timestamp:   timestamp,
+	}, nil
+}
+
+func (ep *EventProcessor) parseWithdrawalEvent(vLog ethtypes.Log) (*types.WithdrawalEvent, error) {
+	account, amount, timestamp, err := ep.parseWrappedNativeLog(vLog)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.WithdrawalEvent{
+		BlockNumber: new(big.Int).SetUint64(vLog.BlockNumber),
+		TxHash:      vLog.TxHash,
+		LogIndex:    vLog.Index,
+		Account:     account,
+		Amount:      amount,
+		Contract:    vLog.Address,
+		Timestamp:   timestamp,
+	}, nil
+}
+
+// ConvertDepositToIndexedEvent converts a deposit into the indexed event format, with the
+// account receiving the wrapped tokens as To. Data holds the account and the amount.
+func (ep *EventProcessor) ConvertDepositToIndexedEvent(deposit *types.DepositEvent) *types.IndexedEvent {
+	return &types.IndexedEvent{
+		BlockNumber: deposit.BlockNumber,
+		TxHash:      deposit.TxHash.Hex(),
+		LogIndex:    deposit.LogIndex,
+		EventName:   "Deposit",
+		Topic0:      depositTopic.Hex(),
+		Contract:    deposit.Contract.Hex(),
+		To:          deposit.Account.Hex(),
+		Value:       deposit.Amount.String(),
+		Data: map[string]interface{}{
+			"account": deposit.Account.Hex(),
+			"amount":  deposit.Amount.String(),
+		},
+		Timestamp: deposit.Timestamp,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// ConvertWithdrawalToIndexedEvent converts a withdrawal into the indexed event format, with
+// the account unwrapping its tokens as From. Data holds the account and the amount.
+func (ep *EventProcessor) ConvertWithdrawalToIndexedEvent(withdrawal *types.WithdrawalEvent) *types.IndexedEvent {
+	return &types.IndexedEvent{
+		BlockNumber: withdrawal.BlockNumber,
+		TxHash:      withdrawal.TxHash.Hex(),
+		LogIndex:    withdrawal.LogIndex,
+		EventName:   "Withdrawal",
+		Topic0:      withdrawalTopic.Hex(),
+		Contract:    withdrawal.Contract.Hex(),
+		From:        withdrawal.Account.Hex(),
+		Value:       withdrawal.Amount.String(),
+		Data: map[string]interface{}{
+			"account": withdrawal.Account.Hex(),
+			"amount":  withdrawal.Amount.String(),
+		},
+		Timestamp: withdrawal.Timestamp,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// SubscribeToWrappedNative subscribes to real-time Deposit and Withdrawal events of the
+// wrapped-native token contracts in contractAddresses
+func (ep *EventProcessor) SubscribeToWrappedNative(ctx context.Context, contractAddresses []common.Address) (<-chan *types.IndexedEvent, <-chan error, error) {
+	query := ethereum.FilterQuery{
+		Addresses: contractAddresses,
+		Topics: [][]common.Hash{
+			{depositTopic, withdrawalTopic},
+		},
+	}
+
+	logs, subErrs, err := ep.subscribeLogs(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	eventChan := make(chan *types.IndexedEvent)
+	errChan := make(chan error)
+
+	go func() {
+		defer close(eventChan)
+		defer close(errChan)
+
+		for {
+			select {
+			case vLog, ok := <-logs:
+				if !ok {
+					return
+				}
+				event, err := ep.ConvertWrappedNativeLog(vLog)
+				if err != nil {
+					errChan <- err
+					continue
+				}
+				eventChan <- event
+			case <-ctx.Done():
+				return
+			case err, ok := <-subErrs:
+				if !ok {
+					return
+				}
+				// Failed shards resubscribe on their own, so keep the stream open
+				errChan <- err
+			}
+		}
+	}()
+
+	return eventChan, errChan, nil
+}
@@ -0,0 +1,68 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestEventProcessor_ConvertWrappedNativeLog(t *testing.T) {
+	ep, block := newApprovalTestProcessor(t)
+	weth := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	account := common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc454e4438f44e")
+	amount, _ := new(big.Int).SetString("1500000000000000000", 10)
+
+	for _, tc := range []struct {
+		topic    common.Hash
+		name     string
+		from, to string
+	}{
+		{depositTopic, "Deposit", "", account.Hex()},
+		{withdrawalTopic, "Withdrawal", account.Hex(), ""},
+	} {
+		event, err := ep.ConvertWrappedNativeLog(ethtypes.Log{
+			Address:     weth,
+			Topics:      []common.Hash{tc.topic, common.BytesToHash(account.Bytes())},
+			Data:        common.LeftPadBytes(amount.Bytes(), 32),
+			BlockNumber: 150,
+			BlockHash:   block.Hash(),
+			TxHash:      common.HexToHash("0xcc"),
+			Index:       4,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", tc.name, err)
+		}
+
+		if event.EventName != tc.name || event.Topic0 != tc.topic.Hex() {
+			t.Errorf("Expected a %s event, got %s with topic %s", tc.name, event.EventName, event.Topic0)
+		}
+		if event.Contract != weth.Hex() || event.From != tc.from || event.To != tc.to || event.Value != amount.String() {
+			t.Errorf("Unexpected %s fields %+v", tc.name, event)
+		}
+		if event.Data["account"] != account.Hex() || event.Data["amount"] != amount.String() {
+			t.Errorf("Expected data with the account and amount, got %v", event.Data)
+		}
+		if event.LogIndex != 4 || event.BlockNumber.Uint64() != 150 || !event.Timestamp.Equal(time.Unix(1700000000, 0)) {
+			t.Errorf("Unexpected %s position %+v", tc.name, event)
+		}
+	}
+}
+
+func TestEventProcessor_ConvertWrappedNativeLogRejectsMalformed(t *testing.T) {
+	ep, block := newApprovalTestProcessor(t)
+	account := common.BytesToHash(common.HexToAddress("0x1").Bytes())
+
+	malformed := []ethtypes.Log{
+		{Topics: []common.Hash{depositTopic, account}, BlockHash: block.Hash()},                                   // no amount
+		{Topics: []common.Hash{withdrawalTopic}, Data: make([]byte, 32), BlockHash: block.Hash()},                 // no account
+		{Topics: []common.Hash{transferTopic, account, account}, Data: make([]byte, 32), BlockHash: block.Hash()}, // not a wrap
+	}
+	for i, vLog := range malformed {
+		if event, err := ep.ConvertWrappedNativeLog(vLog); err == nil {
+			t.Errorf("Expected log %d to be rejected, got %+v", i, event)
+		}
+	}
+}
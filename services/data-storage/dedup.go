@@ -0,0 +1,107 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"chainpulse/shared/types"
+)
+
+// eventStore is the subset of the database used to store processed events
+type eventStore interface {
+	GetEventByTxHashAndLogIndex(txHash string, logIndex uint) (*types.IndexedEvent, error)
+	SaveEventIfNotExists(event *types.IndexedEvent) (bool, error)
+}
+
+// recentKeys is a fixed-size set of the most recently added keys
+type recentKeys struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	keys     map[string]*list.Element
+}
+
+func newRecentKeys(capacity int) *recentKeys {
+	return &recentKeys{
+		capacity: capacity,
+		order:    list.New(),
+		keys:     make(map[string]*list.Element),
+	}
+}
+
+// Contains reports whether key is among the recent keys
+func (r *recentKeys) Contains(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, exists := r.keys[key]
+	return exists
+}
+
+// Add adds key, evicting the least recently added key when full
+func (r *recentKeys) Add(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if element, exists := r.keys[key]; exists {
+		r.order.MoveToFront(element)
+		return
+	}
+
+	r.keys[key] = r.order.PushFront(key)
+	if r.order.Len() > r.capacity {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.keys, oldest.Value.(string))
+	}
+}
+
+// eventDeduplicator stores events at most once. Keys of recently stored events are kept
+// in memory so that the common case, an event never seen before, is inserted without a
+// duplicate lookup. Only events whose key was seen recently, i.e. likely redeliveries,
+// are looked up in the database. Duplicates older than the recent keys, e.g. replayed
+// after a restart, are still rejected by the unique index on (tx_hash, log_index).
+type eventDeduplicator struct {
+	store  eventStore
+	recent *recentKeys // nil looks up every event
+}
+
+func newEventDeduplicator(store eventStore, recentSize int) *eventDeduplicator {
+	d := &eventDeduplicator{store: store}
+	if recentSize > 0 {
+		d.recent = newRecentKeys(recentSize)
+	}
+	return d
+}
+
+// eventKey identifies an event by the log that emitted it
+func eventKey(event *types.IndexedEvent) string {
+	return fmt.Sprintf("%s:%d", strings.ToLower(event.TxHash), event.LogIndex)
+}
+
+// Store saves event unless it is already stored and reports whether it was saved
+func (d *eventDeduplicator) Store(event *types.IndexedEvent) (bool, error) {
+	key := eventKey(event)
+
+	if d.recent == nil || d.recent.Contains(key) {
+		existing, err := d.store.GetEventByTxHashAndLogIndex(event.TxHash, event.LogIndex)
+		if err != nil {
+			// The unique index still rejects the event if it is a duplicate
+			log.Printf("Error checking for existing event %s: %v", key, err)
+		} else if existing != nil {
+			return false, nil
+		}
+	}
+
+	saved, err := d.store.SaveEventIfNotExists(event)
+	if err != nil {
+		return false, err
+	}
+
+	if d.recent != nil {
+		d.recent.Add(key)
+	}
+	return saved, nil
+}
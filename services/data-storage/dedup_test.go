@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"chainpulse/shared/types"
+)
+
+// fakeEventStore stores events by key and enforces the unique (tx_hash, log_index) index
+type fakeEventStore struct {
+	events  map[string]*types.IndexedEvent
+	lookups int
+	saves   int
+}
+
+func newFakeEventStore() *fakeEventStore {
+	return &fakeEventStore{events: make(map[string]*types.IndexedEvent)}
+}
+
+func (s *fakeEventStore) GetEventByTxHashAndLogIndex(txHash string, logIndex uint) (*types.IndexedEvent, error) {
+	s.lookups++
+	return s.events[eventKey(&types.IndexedEvent{TxHash: txHash, LogIndex: logIndex})], nil
+}
+
+func (s *fakeEventStore) SaveEventIfNotExists(event *types.IndexedEvent) (bool, error) {
+	key := eventKey(event)
+	if _, exists := s.events[key]; exists {
+		return false, nil
+	}
+	s.saves++
+	s.events[key] = event
+	return true, nil
+}
+
+func TestEventDeduplicatorSkipsLookupForNewEvents(t *testing.T) {
+	store := newFakeEventStore()
+	dedup := newEventDeduplicator(store, 10)
+
+	for i := 0; i < 5; i++ {
+		saved, err := dedup.Store(&types.IndexedEvent{TxHash: fmt.Sprintf("0x%d", i), LogIndex: uint(i)})
+		if err != nil {
+			t.Fatalf("Failed to store event: %v", err)
+		}
+		if !saved {
+			t.Errorf("Expected event %d to be saved", i)
+		}
+	}
+
+	if store.lookups != 0 {
+		t.Errorf("Expected no duplicate lookups, got %d", store.lookups)
+	}
+	if store.saves != 5 {
+		t.Errorf("Expected 5 saves, got %d", store.saves)
+	}
+}
+
+func TestEventDeduplicatorCatchesRedelivery(t *testing.T) {
+	store := newFakeEventStore()
+	dedup := newEventDeduplicator(store, 10)
+
+	event := &types.IndexedEvent{TxHash: "0xABC", LogIndex: 3}
+	if _, err := dedup.Store(event); err != nil {
+		t.Fatalf("Failed to store event: %v", err)
+	}
+
+	// The same log redelivered, with a differently cased hash
+	saved, err := dedup.Store(&types.IndexedEvent{TxHash: "0xabc", LogIndex: 3})
+	if err != nil {
+		t.Fatalf("Failed to store event: %v", err)
+	}
+	if saved {
+		t.Error("Expected the redelivered event not to be saved")
+	}
+	if store.lookups != 1 {
+		t.Errorf("Expected 1 duplicate lookup, got %d", store.lookups)
+	}
+	if store.saves != 1 {
+		t.Errorf("Expected 1 save, got %d", store.saves)
+	}
+
+	// Another log of the same transaction is not a duplicate
+	saved, err = dedup.Store(&types.IndexedEvent{TxHash: "0xABC", LogIndex: 4})
+	if err != nil {
+		t.Fatalf("Failed to store event: %v", err)
+	}
+	if !saved {
+		t.Error("Expected another log of the transaction to be saved")
+	}
+}
+
+func TestEventDeduplicatorCatchesDuplicateOutsideRecentKeys(t *testing.T) {
+	store := newFakeEventStore()
+	// Stored before a restart, so not among the recent keys
+	store.events[eventKey(&types.IndexedEvent{TxHash: "0xold", LogIndex: 0})] = &types.IndexedEvent{TxHash: "0xold"}
+
+	dedup := newEventDeduplicator(store, 2)
+	saved, err := dedup.Store(&types.IndexedEvent{TxHash: "0xold", LogIndex: 0})
+	if err != nil {
+		t.Fatalf("Failed to store event: %v", err)
+	}
+	if saved {
+		t.Error("Expected the duplicate to be rejected by the unique index")
+	}
+	if store.saves != 0 {
+		t.Errorf("Expected no saves, got %d", store.saves)
+	}
+}
+
+func TestRecentKeysEvictsOldest(t *testing.T) {
+	recent := newRecentKeys(2)
+	recent.Add("a")
+	recent.Add("b")
+	recent.Add("c")
+
+	if recent.Contains("a") {
+		t.Error("Expected the oldest key to be evicted")
+	}
+	if !recent.Contains("b") || !recent.Contains("c") {
+		t.Error("Expected the newest keys to be kept")
+	}
+}
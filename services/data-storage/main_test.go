@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"chainpulse/shared/database"
+	"chainpulse/shared/mq"
+)
+
+func TestNewDataStorageServiceRequiresDatabase(t *testing.T) {
+	service, err := NewDataStorageService(nil, nil, mq.TopicConfig{}, 10, mq.RetryPolicy{})
+	if err != errNilDatabase {
+		t.Errorf("Expected errNilDatabase, got %v", err)
+	}
+	if service != nil {
+		t.Error("Expected no service without a database")
+	}
+}
+
+func TestNewDataStorageServiceWithDatabase(t *testing.T) {
+	service, err := NewDataStorageService(nil, &database.Database{}, mq.TopicConfig{}, 10, mq.RetryPolicy{})
+	if err != nil {
+		t.Fatalf("Expected no error with a database, got %v", err)
+	}
+	if service == nil {
+		t.Error("Expected a service with a database")
+	}
+}
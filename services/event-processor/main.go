@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"chainpulse/shared/cache"
+	"chainpulse/shared/config"
+	"chainpulse/shared/database"
 	"chainpulse/shared/mq"
 	"chainpulse/shared/types"
 )
@@ -15,7 +20,15 @@ import (
 // EventProcessorService handles blockchain event processing
 type EventProcessorService struct {
 	mq     mq.MessageQueue
-	db     *types.Database
+	db     *database.Database
+	topics mq.TopicConfig
+	retry  mq.RetryPolicy
+	// Workers is the number of raw events handled concurrently; events of the same
+	// contract are always handled in order by one worker. Values below 1 mean 1.
+	Workers int
+	// Dedup, if set, drops raw events delivered again with the same message ID, e.g.
+	// after the listener retried a publish that had succeeded
+	Dedup mq.DedupStore
 }
 
 // ProcessedEventMessage represents a message containing a processed event
@@ -23,12 +36,21 @@ type ProcessedEventMessage struct {
 	Event types.IndexedEvent `json:"event"`
 }
 
-// NewEventProcessorService creates a new event processor service
-func NewEventProcessorService(mq mq.MessageQueue, db *types.Database) *EventProcessorService {
-	return &EventProcessorService{
-		mq: mq,
-		db: db,
+// errNilDatabase is returned when the service is created without a database
+var errNilDatabase = errors.New("event processor service requires a database")
+
+// NewEventProcessorService creates a new event processor service. It fails when db is
+// nil, so a missing database is reported at startup instead of on the first message.
+func NewEventProcessorService(mq mq.MessageQueue, db *database.Database, topics mq.TopicConfig, retry mq.RetryPolicy) (*EventProcessorService, error) {
+	if db == nil {
+		return nil, errNilDatabase
 	}
+	return &EventProcessorService{
+		mq:     mq,
+		db:     db,
+		topics: topics,
+		retry:  retry,
+	}, nil
 }
 
 // Start begins processing events from the message queue
@@ -47,18 +69,31 @@ func (eps *EventProcessorService) Start() error {
 
 	log.Println("Starting event processor service...")
 	
-	// Start consuming raw blockchain events
-	if err := eps.mq.Consume(ctx, "blockchain.raw.events", eps.handleRawEvent); err != nil && err != context.Canceled {
+	// Start consuming raw blockchain events on a worker pool partitioned by contract,
+	// skipping duplicates and dead-lettering those that keep failing
+	topic := eps.topics.RawEvents()
+	handler := mq.DedupHandler(eps.Dedup, mq.RetryHandler(ctx, eps.mq, topic, eps.retry, eps.handleRawEvent))
+	if err := mq.ConsumePartitioned(ctx, eps.mq, topic, eps.Workers, rawEventContract, handler); err != nil && err != context.Canceled {
 		return err
 	}
 
 	return nil
 }
 
+// rawEventContract returns the contract of a raw event message, the key keeping events
+// of one contract in order. Undecodable messages share the empty key and fail in the handler.
+func rawEventContract(data []byte) string {
+	var rawEvent types.RawEvent
+	if err := mq.Decode(data, &rawEvent); err != nil {
+		return ""
+	}
+	return strings.ToLower(rawEvent.ContractAddr)
+}
+
 // handleRawEvent processes raw blockchain events from the queue
 func (eps *EventProcessorService) handleRawEvent(data []byte) error {
 	var rawEvent types.RawEvent
-	if err := json.Unmarshal(data, &rawEvent); err != nil {
+	if err := mq.Decode(data, &rawEvent); err != nil {
 		return err
 	}
 
@@ -78,7 +113,7 @@ func (eps *EventProcessorService) handleRawEvent(data []byte) error {
 	}
 
 	// Store the processed event
-	if err := eps.db.StoreEvent(indexedEvent); err != nil {
+	if err := eps.db.SaveEvent(&indexedEvent); err != nil {
 		return err
 	}
 
@@ -92,7 +127,9 @@ func (eps *EventProcessorService) handleRawEvent(data []byte) error {
 		Event: indexedEvent,
 	}
 
-	if err := eps.mq.Publish("blockchain.processed.events", processedMsg); err != nil {
+	// Carry the request ID of the incoming message over to the processed event
+	ctx := mq.ContextFromMessage(context.Background(), data)
+	if err := eps.mq.PublishContext(ctx, eps.topics.ProcessedEvents(), processedMsg); err != nil {
 		return err
 	}
 
@@ -137,13 +174,35 @@ func (eps *EventProcessorService) isEventAlreadyProcessed(event types.IndexedEve
 // markEventAsProcessed marks an event as processed for idempotency
 func (eps *EventProcessorService) markEventAsProcessed(event types.IndexedEvent) error {
 	// Store processed event ID or hash in a separate table for idempotency
-	return eps.db.MarkProcessed(event.TxHash)
+	return eps.db.MarkEventAsProcessed(event.TxHash)
 }
 
 func main() {
+	// Load configuration
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	topics := mq.TopicConfig{
+		Prefix:              cfg.MQTopicPrefix,
+		RawEventsName:       cfg.MQRawEventsTopic,
+		ProcessedEventsName: cfg.MQProcessedTopic,
+		ReorgEventsName:     cfg.MQReorgTopic,
+	}
+	retry := mq.RetryPolicy{
+		MaxAttempts: cfg.MQRetryMaxAttempts,
+		BaseDelay:   time.Duration(cfg.MQRetryBaseDelay) * time.Millisecond,
+		MaxDelay:    time.Duration(cfg.MQRetryMaxDelay) * time.Millisecond,
+	}
+
 	// Initialize metrics collector
 	metricsCollector := mq.GlobalMetricsCollector
 
+	// Processed events and dead letters are published with the configured codec
+	if err := mq.CheckCodec(cfg.MQCodec, ProcessedEventMessage{}, mq.DeadLetterMessage{}); err != nil {
+		log.Fatalf("Invalid MQ codec: %v", err)
+	}
+
 	// Initialize multi-protocol message queue
 	multiMQ := mq.NewMultiProtocolMQ("kafka") // Use Kafka as default
 	multiMQ.SetMetricsCollector(metricsCollector)
@@ -152,15 +211,21 @@ func main() {
 	pluginConfigs := map[string]map[string]interface{}{
 		"kafka": {
 			"brokers": []string{"localhost:9092"}, // This would come from config in real implementation
+			"max_message_size": cfg.MQMaxMessageSize,
+			"codec": cfg.MQCodec,
 		},
 		"redis": {
 			"addr": "localhost:6379",
 			"password": "",
 			"db": 0,
+			"max_message_size": cfg.MQMaxMessageSize,
+			"codec": cfg.MQCodec,
 		},
 		"zeromq": {
 			"publish_addr": "tcp://localhost:5555",
 			"subscribe_addr": "tcp://localhost:5556",
+			"max_message_size": cfg.MQMaxMessageSize,
+			"codec": cfg.MQCodec,
 		},
 	}
 
@@ -170,11 +235,27 @@ func main() {
 	}
 	defer multiMQ.Close()
 
-	// Initialize database (this would be a real database instance)
-	var db *types.Database
+	// Initialize database
+	db, err := database.NewDatabase(cfg.PostgreSQLURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
 
 	// Create and start event processor service
-	service := NewEventProcessorService(multiMQ, db)
+	service, err := NewEventProcessorService(multiMQ, db, topics, retry)
+	if err != nil {
+		log.Fatalf("Failed to create event processor service: %v", err)
+	}
+	service.Workers = cfg.EventProcessorWorkers
+
+	// Share the delivered message IDs between event processors through Redis
+	dedupCache, err := cache.NewCache(cfg.RedisURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize dedup store: %v", err)
+	}
+	defer dedupCache.Close()
+	service.Dedup = mq.NewRedisDedupStore(dedupCache.Client, mq.DefaultDedupTTL)
 	
 	if err := service.Start(); err != nil {
 		log.Fatalf("Failed to start event processor service: %v", err)
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"chainpulse/shared/database"
+	"chainpulse/shared/mq"
+)
+
+func TestNewEventProcessorServiceRequiresDatabase(t *testing.T) {
+	service, err := NewEventProcessorService(nil, nil, mq.TopicConfig{}, mq.RetryPolicy{})
+	if err != errNilDatabase {
+		t.Errorf("Expected errNilDatabase, got %v", err)
+	}
+	if service != nil {
+		t.Error("Expected no service without a database")
+	}
+
+	service, err = NewEventProcessorService(nil, &database.Database{}, mq.TopicConfig{}, mq.RetryPolicy{})
+	if err != nil {
+		t.Fatalf("Expected no error with a database, got %v", err)
+	}
+	if service == nil {
+		t.Error("Expected a service with a database")
+	}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+
+	"chainpulse/shared/types"
+)
+
+// handleIndexedEvents stores the events of a subscription delivering indexed events, such
+// as the approval or the wrapped native subscription. kind names the events in logs.
+func (s *IndexerService) handleIndexedEvents(ctx context.Context, kind string, eventChan <-chan *types.IndexedEvent, errChan <-chan error) {
+	for {
+		select {
+		case event, ok := <-eventChan:
+			if !ok {
+				s.Logger.Warn("Channel of %s events closed", kind)
+				return
+			}
+			event.ReceivedAt = s.now()
+			if s.SyncProcessing {
+				s.processInOrder(ctx, event, func() error { return s.processIndexedEvent(kind, event) })
+				continue
+			}
+			s.goProcess(func() error { return s.processIndexedEvent(kind, event) })
+		case err, ok := <-errChan:
+			if ok {
+				s.Logger.Error("Subscription error of %s events: %v", kind, err)
+			}
+		case <-ctx.Done():
+			s.Logger.Info("Handler of %s events context cancelled", kind)
+			return
+		}
+	}
+}
+
+// processIndexedEvent stores an already converted event unless it is filtered out or
+// already processed. It returns an error if the event failed and may be retried.
+func (s *IndexerService) processIndexedEvent(kind string, indexedEvent *types.IndexedEvent) error {
+	s.Logger.Info("Processing %s event: block %s, from %s, to %s", indexedEvent.EventName, indexedEvent.BlockNumber.String(), indexedEvent.From, indexedEvent.To)
+	s.observeBlock(indexedEvent.BlockNumber)
+	_, err := s.storeEvent(context.Background(), kind, indexedEvent)
+	return err
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"runtime"
+	"sync/atomic"
+
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Event kinds reported per contract by Diagnostics
+const (
+	subscribedNFTTransfers   = "nft_transfer"
+	subscribedTokenTransfers = "token_transfer"
+	subscribedApprovals      = "approval"
+	subscribedWrappedNative  = "wrapped_native"
+)
+
+// Diagnostics returns a snapshot of the indexer's subscriptions, sync progress, batch
+// buffer and event processing. Subscribed events are each processed in their own
+// goroutine, unless SyncProcessing is set, so EventsInFlight is the number of those
+// goroutines. It never fails: a last processed block that cannot be read is reported
+// as the error of the chain.
+func (s *IndexerService) Diagnostics() types.IndexerDiagnostics {
+	diagnostics := types.IndexerDiagnostics{
+		Subscriptions:  s.currentSubscriptions(),
+		EventsInFlight: atomic.LoadInt64(&s.inFlight),
+		SyncProcessing: s.SyncProcessing,
+		Goroutines:     runtime.NumGoroutine(),
+	}
+
+	if s.BatchProcessor != nil {
+		stats := s.BatchProcessor.Stats()
+		diagnostics.BatchBufferSize = stats.BufferSize
+		diagnostics.BatchBufferedBytes = stats.BufferedBytes
+	}
+
+	// The indexer follows a single chain
+	progress := types.ChainProgress{ChainID: s.ChainID, HeadBlock: atomic.LoadUint64(&s.head)}
+	if s.Resume != nil {
+		processed, err := s.Resume.GetLastProcessedBlock()
+		if err != nil {
+			progress.Error = err.Error()
+		} else if processed != nil && processed.IsUint64() {
+			progress.LastProcessedBlock = processed.Uint64()
+		}
+	}
+	diagnostics.Chains = []types.ChainProgress{progress}
+
+	return diagnostics
+}
+
+// currentSubscriptions returns the events subscribed to per contract, none once indexing
+// stopped
+func (s *IndexerService) currentSubscriptions() []types.ContractSubscription {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	if s.watchCtx == nil || s.watchCtx.Err() != nil {
+		return []types.ContractSubscription{}
+	}
+	return append([]types.ContractSubscription{}, s.subscriptions...)
+}
+
+// subscriptionsOf returns the events subscribe subscribed to for each of addresses,
+// wrapped being the wrapped-native token contracts among them
+func (s *IndexerService) subscriptionsOf(addresses, wrapped []common.Address) []types.ContractSubscription {
+	events := []string{subscribedNFTTransfers, subscribedTokenTransfers}
+	if s.Blockchain.ApprovalsEnabled {
+		events = append(events, subscribedApprovals)
+	}
+	isWrapped := make(map[common.Address]bool, len(wrapped))
+	for _, address := range wrapped {
+		isWrapped[address] = true
+	}
+
+	subscriptions := make([]types.ContractSubscription, 0, len(addresses))
+	for _, address := range addresses {
+		contractEvents := events
+		if isWrapped[address] {
+			contractEvents = append(append([]string{}, events...), subscribedWrappedNative)
+		}
+		subscriptions = append(subscriptions, types.ContractSubscription{Contract: address.Hex(), Events: contractEvents})
+	}
+	return subscriptions
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"chainpulse/services/blockchain/services"
+	sharedservice "chainpulse/shared/service"
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// headClient is a subscribingClient whose chain head is at a fixed block
+type headClient struct {
+	*subscribingClient
+	head int64
+}
+
+func (c *headClient) BlockByNumber(ctx context.Context, number *big.Int) (*ethtypes.Block, error) {
+	return ethtypes.NewBlockWithHeader(&ethtypes.Header{Number: big.NewInt(c.head)}), nil
+}
+
+func (c *headClient) BlockNumber(ctx context.Context) (uint64, error) {
+	return uint64(c.head), nil
+}
+
+// processedStore reports a fixed last processed block
+type processedStore struct {
+	lastProcessed *big.Int
+}
+
+func (s *processedStore) GetLastProcessedBlock() (*big.Int, error) { return s.lastProcessed, nil }
+
+func (s *processedStore) SaveLastProcessedBlock(blockNum *big.Int) error { return nil }
+
+func (s *processedStore) StoreEvent(event *types.Event) error { return nil }
+
+func (s *processedStore) GetEventsByBlockRange(fromBlock, toBlock *big.Int) ([]types.IndexedEvent, error) {
+	return nil, nil
+}
+
+// flakyProcessedStore fails to read the last processed block until failures runs out
+type flakyProcessedStore struct {
+	processedStore
+	mu       sync.Mutex
+	failures int
+}
+
+func (s *flakyProcessedStore) GetLastProcessedBlock() (*big.Int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.New("connection refused")
+	}
+	return s.lastProcessed, nil
+}
+
+func TestIndexerService_StartIndexingSubscribesWhenResumeFails(t *testing.T) {
+	apes := common.HexToAddress("0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D")
+
+	client := &headClient{subscribingClient: &subscribingClient{live: make(map[int][]common.Address)}, head: 100}
+	bc, err := blockchain.NewEventProcessorWithClient(client)
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+	readiness := sharedservice.NewSyncReadiness(10)
+	s := &IndexerService{
+		Blockchain: bc,
+		Resume:     blockchain.NewResumeService(client, &flakyProcessedStore{processedStore: processedStore{lastProcessed: big.NewInt(100)}, failures: 1}),
+		Logger:     &MockLogger{},
+		Readiness:  readiness,
+	}
+
+	if err := s.StartIndexing(context.Background(), []common.Address{apes}); err != nil {
+		t.Fatalf("Expected indexing to start despite the failed resume, got %v", err)
+	}
+	defer s.Stop(context.Background())
+
+	if diagnostics := s.Diagnostics(); len(diagnostics.Subscriptions) != 1 {
+		t.Fatalf("Expected 1 subscription, got %v", diagnostics.Subscriptions)
+	}
+
+	// Once the last processed block can be read again the lag decides readiness
+	deadline := time.Now().Add(time.Second)
+	for {
+		ready, reason := readiness.Ready()
+		if ready {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the indexer to become ready, got %q", reason)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestIndexerService_DiagnosticsAfterStartIndexing(t *testing.T) {
+	apes := common.HexToAddress("0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D")
+	mutants := common.HexToAddress("0x60E4d786628Fea6478F785A6d7e704777c86a7c6")
+	weth := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+
+	client := &headClient{subscribingClient: &subscribingClient{live: make(map[int][]common.Address)}, head: 100}
+	bc, err := blockchain.NewEventProcessorWithClient(client)
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+	s := &IndexerService{
+		Blockchain:    bc,
+		Resume:        blockchain.NewResumeService(client, &processedStore{lastProcessed: big.NewInt(100)}),
+		Logger:        &MockLogger{},
+		ChainID:       "1",
+		WrappedNative: []common.Address{weth},
+		Contracts:     &statusStore{contracts: []types.Contract{{Address: mutants.Hex(), Active: false}}},
+	}
+
+	if diagnostics := s.Diagnostics(); len(diagnostics.Subscriptions) != 0 {
+		t.Errorf("Expected no subscriptions before indexing starts, got %v", diagnostics.Subscriptions)
+	}
+
+	if err := s.StartIndexing(context.Background(), []common.Address{apes, mutants, weth}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The paused contract is not subscribed to
+	diagnostics := s.Diagnostics()
+	if len(diagnostics.Subscriptions) != 2 {
+		t.Fatalf("Expected 2 subscriptions, got %v", diagnostics.Subscriptions)
+	}
+	events := make(map[string][]string)
+	for _, subscription := range diagnostics.Subscriptions {
+		events[subscription.Contract] = subscription.Events
+	}
+	if len(events[apes.Hex()]) != 2 {
+		t.Errorf("Expected NFT and token transfers of %s, got %v", apes.Hex(), events[apes.Hex()])
+	}
+	if wethEvents := events[weth.Hex()]; len(wethEvents) != 3 || wethEvents[2] != subscribedWrappedNative {
+		t.Errorf("Expected the wrapped native events of %s, got %v", weth.Hex(), wethEvents)
+	}
+
+	if len(diagnostics.Chains) != 1 || diagnostics.Chains[0].ChainID != "1" || diagnostics.Chains[0].LastProcessedBlock != 100 {
+		t.Errorf("Expected chain 1 processed up to block 100, got %+v", diagnostics.Chains)
+	}
+	if diagnostics.Goroutines == 0 {
+		t.Error("Expected the goroutines to be counted")
+	}
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if diagnostics := s.Diagnostics(); len(diagnostics.Subscriptions) != 0 {
+		t.Errorf("Expected no subscriptions once stopped, got %v", diagnostics.Subscriptions)
+	}
+}
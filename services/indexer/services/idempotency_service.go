@@ -2,46 +2,74 @@ package service
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"chainpulse/shared/cache"
+	"chainpulse/shared/clock"
 	"chainpulse/shared/database"
+	"chainpulse/shared/types"
 
 	"gorm.io/gorm"
 )
 
+// defaultClaimTTL 处理中声明的过期时间，避免处理者崩溃后事件永久被占用
+const defaultClaimTTL = 5 * time.Minute
+
 // IdempotencyService 幂等性服务
 type IdempotencyService struct {
-	cache *cache.Cache
-	db    *database.Database
-	ttl   time.Duration
+	cache    *cache.Cache
+	db       *database.Database
+	ttl      time.Duration
+	claimTTL time.Duration
+
+	// 没有缓存时在进程内记录的处理权及其过期时间
+	mu          sync.Mutex
+	localClaims map[string]time.Time
+	nextPrune   time.Time
+
+	// Keyer 决定哪些事件互为重复，为 nil 时使用 types.DefaultDedupKeyer
+	Keyer types.DedupKeyer
+
+	// Clock 决定进程内处理权的过期；为 nil 时使用系统时间
+	Clock clock.Clock
 }
 
-// NewIdempotencyService 创建幂等性服务
+// NewIdempotencyService 创建幂等性服务，cache 可以为 nil，此时只依赖数据库，处理权只在进程内声明
 func NewIdempotencyService(cache *cache.Cache, db *database.Database, ttl time.Duration) *IdempotencyService {
 	return &IdempotencyService{
-		cache: cache,
-		db:    db,
-		ttl:   ttl,
+		cache:    cache,
+		db:       db,
+		ttl:      ttl,
+		claimTTL: defaultClaimTTL,
 	}
 }
 
+// EventKey 返回事件的去重键，键相同的事件只处理一次
+func (is *IdempotencyService) EventKey(event *types.IndexedEvent) string {
+	if is.Keyer == nil {
+		return types.DefaultDedupKeyer.Key(event)
+	}
+	return is.Keyer.Key(event)
+}
+
 // IsProcessed 检查事件是否已经被处理过
 func (is *IdempotencyService) IsProcessed(ctx context.Context, eventKey string) (bool, error) {
-	var exists bool
-	err := is.cache.Get(ctx, "processed:"+eventKey, &exists)
-	if err == nil {
-		return exists, nil
+	if is.cache != nil {
+		var exists bool
+		if err := is.cache.Get(ctx, "processed:"+eventKey, &exists); err == nil {
+			return exists, nil
+		}
 	}
 	
 	// 如果缓存中不存在，检查数据库
-	exists, err = is.db.EventExists(eventKey)
+	exists, err := is.db.EventExists(eventKey)
 	if err != nil {
 		return false, err
 	}
 	
 	// 如果数据库中存在，也认为已处理
-	if exists {
+	if exists && is.cache != nil {
 		// 设置缓存以提高后续检查的性能
 		is.cache.Set(ctx, "processed:"+eventKey, true, is.ttl)
 	}
@@ -49,6 +77,53 @@ func (is *IdempotencyService) IsProcessed(ctx context.Context, eventKey string)
 	return exists, nil
 }
 
+// TryClaim 使用 SET NX 原子地声明事件的处理权，只有第一个声明者返回 true
+func (is *IdempotencyService) TryClaim(ctx context.Context, eventKey string) (bool, error) {
+	// 没有缓存时无法跨实例协调，只在进程内声明
+	if is.cache == nil {
+		return is.claimLocally(eventKey), nil
+	}
+	return is.cache.SetNX(ctx, "processing:"+eventKey, true, is.claimTTL)
+}
+
+// claimLocally 在进程内声明事件的处理权，声明在 claimTTL 后过期
+func (is *IdempotencyService) claimLocally(eventKey string) bool {
+	now := clock.OrReal(is.Clock).Now()
+
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	if is.localClaims == nil {
+		is.localClaims = make(map[string]time.Time)
+	}
+
+	// 每隔 claimTTL 清理一次过期的声明
+	if !now.Before(is.nextPrune) {
+		for key, expires := range is.localClaims {
+			if !now.Before(expires) {
+				delete(is.localClaims, key)
+			}
+		}
+		is.nextPrune = now.Add(is.claimTTL)
+	}
+
+	if expires, ok := is.localClaims[eventKey]; ok && now.Before(expires) {
+		return false
+	}
+	is.localClaims[eventKey] = now.Add(is.claimTTL)
+	return true
+}
+
+// ReleaseClaim 释放事件的处理权，用于处理失败后允许重试
+func (is *IdempotencyService) ReleaseClaim(ctx context.Context, eventKey string) error {
+	if is.cache == nil {
+		is.mu.Lock()
+		delete(is.localClaims, eventKey)
+		is.mu.Unlock()
+		return nil
+	}
+	return is.cache.Delete(ctx, "processing:"+eventKey)
+}
+
 // MarkProcessed 标记事件为已处理
 func (is *IdempotencyService) MarkProcessed(ctx context.Context, eventKey string) error {
 	// 在数据库中标记事件
@@ -57,6 +132,9 @@ func (is *IdempotencyService) MarkProcessed(ctx context.Context, eventKey string
 	}
 	
 	// 在缓存中标记事件
+	if is.cache == nil {
+		return nil
+	}
 	return is.cache.Set(ctx, "processed:"+eventKey, true, is.ttl)
 }
 
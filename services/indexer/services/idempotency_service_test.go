@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"chainpulse/shared/cache"
+	"chainpulse/shared/clock"
+)
+
+func TestIdempotencyService_TryClaimConcurrent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping idempotency test in short mode")
+	}
+
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		redisURL = "redis://localhost:6379"
+	}
+
+	redisCache, err := cache.NewCache(redisURL)
+	if err != nil {
+		t.Skipf("skipping test: could not create Redis client: %v", err)
+	}
+	defer redisCache.Close()
+
+	ctx := context.Background()
+	if err := redisCache.Ping(ctx); err != nil {
+		t.Skipf("skipping test: could not connect to Redis: %v", err)
+	}
+
+	idempotency := NewIdempotencyService(redisCache, nil, time.Minute)
+	eventKey := fmt.Sprintf("test:claim:%d", time.Now().UnixNano())
+	defer idempotency.ReleaseClaim(ctx, eventKey)
+
+	var proceeded int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			claimed, err := idempotency.TryClaim(ctx, eventKey)
+			if err != nil {
+				t.Errorf("Expected no error claiming event, got %v", err)
+				return
+			}
+			if claimed {
+				atomic.AddInt32(&proceeded, 1)
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	if proceeded != 1 {
+		t.Errorf("Expected exactly 1 goroutine to proceed, got %d", proceeded)
+	}
+
+	// Once released, the event can be claimed again
+	if err := idempotency.ReleaseClaim(ctx, eventKey); err != nil {
+		t.Fatalf("Expected no error releasing claim, got %v", err)
+	}
+
+	claimed, err := idempotency.TryClaim(ctx, eventKey)
+	if err != nil {
+		t.Fatalf("Expected no error claiming event, got %v", err)
+	}
+	if !claimed {
+		t.Error("Expected claim to succeed after release")
+	}
+}
+
+func TestIdempotencyService_LocalClaimExpires(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	idempotency := NewIdempotencyService(nil, nil, time.Hour)
+	idempotency.Clock = fake
+	ctx := context.Background()
+
+	if claimed, _ := idempotency.TryClaim(ctx, "event"); !claimed {
+		t.Fatal("Expected the first claim to succeed")
+	}
+	if claimed, _ := idempotency.TryClaim(ctx, "event"); claimed {
+		t.Error("Expected a second claim to fail while the first is held")
+	}
+
+	fake.Advance(defaultClaimTTL - time.Second)
+	if claimed, _ := idempotency.TryClaim(ctx, "event"); claimed {
+		t.Error("Expected the claim to be held until its TTL")
+	}
+
+	// A claim left behind by a crashed worker expires
+	fake.Advance(time.Second)
+	if claimed, _ := idempotency.TryClaim(ctx, "event"); !claimed {
+		t.Error("Expected the claim to succeed once the previous one expired")
+	}
+
+	if err := idempotency.ReleaseClaim(ctx, "event"); err != nil {
+		t.Fatalf("Expected no error releasing claim, got %v", err)
+	}
+	if claimed, _ := idempotency.TryClaim(ctx, "event"); !claimed {
+		t.Error("Expected claim to succeed after release")
+	}
+}
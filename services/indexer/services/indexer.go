@@ -2,17 +2,25 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
-	"strconv"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"chainpulse/services/blockchain/services"
+	"chainpulse/shared/alert"
 	"chainpulse/shared/cache"
+	"chainpulse/shared/clock"
 	"chainpulse/shared/database"
 	"chainpulse/shared/datapuller"
+	"chainpulse/shared/eventbus"
 	"chainpulse/shared/metrics"
+	"chainpulse/shared/mq"
+	sharedservice "chainpulse/shared/service"
 	"chainpulse/shared/types"
 	"chainpulse/shared/utils"
 
@@ -30,7 +38,39 @@ type IndexerService struct {
 	ReorgHandler     *ReorgHandler
 	Idempotency      *IdempotencyService
 	DataPuller       *datapuller.BlockchainDataPuller
+	Readiness        *sharedservice.SyncReadiness // optional, reported by the /ready endpoint
+	ExternalSink     datapuller.Sink              // optional, receives pulled external events instead of the indexer's own storage
+	Confirmations    uint64                       // blocks an event needs on top of it before it is cached for long, 0 caches every event for long
+	UnconfirmedTTL   time.Duration                // cache TTL of events within the confirmation depth, 0 does not cache them
+	Alerts           *alert.Notifier              // optional, alerted on sustained sync lag and when the indexer cannot sync
+	AlertMaxLag      int64                        // blocks behind the chain head before the lag alert fires
+	AlertLagFor      time.Duration                // how long the lag or sync failures must last before alerting
+	VerifyChunkSize  uint64                       // blocks compared per node query by VerifyEvents, 0 uses DefaultVerifyChunkSize
+	Transformers     []types.EventTransformer     // optional, applied in order to every event before it is stored
+	BackfillProgress BackfillProgressStore        // optional, lets ProcessHistoricalEvents resume an interrupted backfill per contract
+	BackfillWindow   uint64                       // blocks of a contract processed between backfill progress records, 0 uses DefaultBackfillWindow
+	ChainID          string                       // chain the backfill progress is recorded for
+	Contracts        ContractStatusStore          // optional, paused contracts are left out of subscriptions and backfills
+	Events           *eventbus.EventBus           // optional, receives every event once it is stored, see PublishStored
+	WrappedNative    []common.Address             // optional, wrapped-native token contracts, e.g. WETH, whose Deposit and Withdrawal events are indexed too
+	SyncProcessing   bool                         // process each subscribed event before reading the next, retrying failures, instead of concurrently
+	SyncRetry        mq.RetryPolicy               // attempts per event in sync processing before it is dead-lettered
+	DeadLetters      mq.MessageQueue              // optional, receives the events sync processing gave up on, nil logs them
+	SyncEventsTopic  string                       // topic whose dead-letter topic receives the events sync processing gave up on
+	Clock            clock.Clock                  // drives the sync lag checks and alert timing, nil uses the real clock
+	head             uint64                       // highest block seen, read and written atomically
+	inFlight         int64                        // subscribed events being processed, read and written atomically
+	lagAlert         *alert.Condition
+	downAlert        *alert.Condition
+	replayTransforms map[string]types.EventTransform
+	cancel           context.CancelFunc // cancels the indexing started by StartIndexing
+	wg               sync.WaitGroup     // goroutines started for indexing, waited for by Stop
 	mu               sync.Mutex
+	watched          []common.Address             // contracts passed to StartIndexing, paused or not
+	watchCtx         context.Context              // indexing context the subscriptions are restarted in
+	subCancel        context.CancelFunc           // cancels the current subscriptions
+	subscriptions    []types.ContractSubscription // events subscribed to per contract by the current subscriptions
+	subMu            sync.Mutex                   // guards the watch and subscription state above
 }
 
 type Logger interface {
@@ -52,43 +92,261 @@ func NewIndexerService(bc *blockchain.EventProcessor, cachedDB *database.CachedD
 		ReorgHandler:   reorgHandler,
 		Idempotency:    idempotency,
 		DataPuller:     dataPuller,
+		replayTransforms: map[string]types.EventTransform{
+			SyncDecodedFieldsTransform: syncDecodedFields,
+		},
 	}
 }
 
-// StartIndexing starts the indexing process for both NFT and token transfers
+// StartIndexing starts the indexing process for both NFT and token transfers,
+// until ctx is cancelled or Stop is called
 func (s *IndexerService) StartIndexing(ctx context.Context, contractAddresses []common.Address) error {
 	s.Logger.Info("Starting indexer service...")
+	ctx = s.indexingContext(ctx)
 
-	// Resume from the last processed block
+	// Resume from the last processed block; the subscriptions below still index new
+	// events when it fails
 	if err := s.Resume.ResumeFromLastBlock(ctx, contractAddresses); err != nil {
-		s.Logger.Error("Failed to resume from last processed block: %v", err)
-		// Continue anyway, as this might be the first run
+		s.Logger.Error("Failed to resume from last processed block, indexing from the chain head: %v", err)
 	}
 
-	// Start listening for new NFT transfer events
-	nftEventChan, nftErrChan, err := s.Blockchain.SubscribeToNFTTransfers(ctx, contractAddresses)
-	if err != nil {
-		return fmt.Errorf("failed to subscribe to NFT transfers: %v", err)
+	// Subscribe to the events of the watched contracts that are not paused
+	if err := s.watchContracts(ctx, contractAddresses); err != nil {
+		return err
 	}
 
-	// Start listening for new token transfer events
-	tokenEventChan, tokenErrChan, err := s.Blockchain.SubscribeToTokenTransfers(ctx, contractAddresses)
-	if err != nil {
-		return fmt.Errorf("failed to subscribe to token transfers: %v", err)
+	// Indexing is live once subscribed. Whether it is caught up is left to the sync
+	// lag, which stays unknown, and the indexer unready, while the last processed block
+	// cannot be read.
+	if s.Readiness != nil {
+		s.Readiness.MarkResumed()
 	}
 
-	// Handle events in separate goroutines
-	go s.handleNFTEvents(ctx, nftEventChan, nftErrChan)
-	go s.handleTokenEvents(ctx, tokenEventChan, tokenErrChan)
-
 	// Start reorg detection if enabled
 	if s.ReorgHandler != nil {
-		go s.ReorgHandler.CheckReorgPeriodically(ctx, 30*time.Second) // Check every 30 seconds
+		s.goTracked(func() { s.ReorgHandler.CheckReorgPeriodically(ctx, 30*time.Second) }) // Check every 30 seconds
+	}
+
+	// Track the sync lag for readiness reporting and alerting
+	if s.Readiness != nil || s.Alerts != nil {
+		s.goTracked(func() { s.trackSyncLag(ctx, 10*time.Second) })
 	}
 
 	return nil
 }
 
+// indexingContext returns a context derived from ctx that Stop cancels
+func (s *IndexerService) indexingContext(ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if previous := s.cancel; previous != nil {
+		s.cancel = func() {
+			previous()
+			cancel()
+		}
+	} else {
+		s.cancel = cancel
+	}
+	return ctx
+}
+
+// goTracked runs fn in a goroutine that Stop waits for
+func (s *IndexerService) goTracked(fn func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		fn()
+	}()
+}
+
+// goProcess processes a subscribed event in a goroutine that Stop waits for, counting it
+// in flight until process returns
+func (s *IndexerService) goProcess(process func() error) {
+	atomic.AddInt64(&s.inFlight, 1)
+	s.goTracked(func() {
+		defer atomic.AddInt64(&s.inFlight, -1)
+		process()
+	})
+}
+
+// Stop stops indexing: it cancels the subscriptions and periodic checks started by
+// StartIndexing, waits for the events being processed, flushes and closes the batch
+// processor and closes the data puller. It returns the errors of the steps that
+// failed, including ctx's error when the goroutines did not finish in time.
+func (s *IndexerService) Stop(ctx context.Context) error {
+	s.Logger.Info("Stopping indexer service...")
+
+	s.mu.Lock()
+	cancel := s.cancel
+	s.cancel = nil
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+
+	var errs []error
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("indexing did not stop in time: %v", ctx.Err()))
+	}
+
+	// Pending events are flushed on close
+	if s.BatchProcessor != nil {
+		if err := s.BatchProcessor.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close batch processor: %v", err))
+		}
+	}
+
+	if s.DataPuller != nil {
+		if err := s.DataPuller.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close data puller: %v", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// PublishStored publishes events to Events. It is set as the flush hook of the batch
+// processor, so subscribers only see events that were stored.
+func (s *IndexerService) PublishStored(events []*types.IndexedEvent) {
+	if s.Events == nil {
+		return
+	}
+	for _, event := range events {
+		s.Events.Publish(*event)
+	}
+}
+
+// invalidateStored removes the caches made stale by events inserted outside the batch,
+// whose flush hook invalidates them otherwise
+func (s *IndexerService) invalidateStored(events []*types.IndexedEvent) {
+	if s.Database != nil {
+		s.Database.InvalidateStoredEvents(events)
+	}
+}
+
+// confirmedEventCacheTTL is the cache TTL of events past the confirmation depth
+const confirmedEventCacheTTL = 24 * time.Hour
+
+// observeBlock raises the known chain head to blockNumber
+func (s *IndexerService) observeBlock(blockNumber *big.Int) {
+	if blockNumber == nil || !blockNumber.IsUint64() {
+		return
+	}
+	block := blockNumber.Uint64()
+	for {
+		head := atomic.LoadUint64(&s.head)
+		if block <= head || atomic.CompareAndSwapUint64(&s.head, head, block) {
+			return
+		}
+	}
+}
+
+// eventCacheTTL returns how long an event of blockNumber may be cached. Events within
+// the confirmation depth may still be reorged away, so they are cached for
+// UnconfirmedTTL only. The head is the highest block seen, which never exceeds the
+// real head, so an event is never taken as more confirmed than it is.
+func (s *IndexerService) eventCacheTTL(blockNumber *big.Int) time.Duration {
+	if s.Confirmations == 0 {
+		return confirmedEventCacheTTL
+	}
+	head := atomic.LoadUint64(&s.head)
+	if blockNumber != nil && blockNumber.IsUint64() && blockNumber.Uint64()+s.Confirmations <= head {
+		return confirmedEventCacheTTL
+	}
+	return s.UnconfirmedTTL
+}
+
+// now returns the current time of the indexer's clock
+func (s *IndexerService) now() time.Time {
+	return clock.OrReal(s.Clock).Now()
+}
+
+// trackSyncLag periodically updates the readiness tracker and the sync alerts with
+// the distance between the chain head and the last processed block
+func (s *IndexerService) trackSyncLag(ctx context.Context, interval time.Duration) {
+	ticker := clock.OrReal(s.Clock).NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.updateSyncLag(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+func (s *IndexerService) updateSyncLag(ctx context.Context) {
+	head, err := s.Blockchain.GetLatestBlockNumber(ctx)
+	if err != nil {
+		s.Logger.Warn("Failed to get latest block number for readiness: %v", err)
+		s.alertSync(ctx, nil, fmt.Errorf("failed to get latest block number: %v", err), s.now())
+		return
+	}
+	s.observeBlock(head)
+
+	processed, err := s.Resume.GetLastProcessedBlock()
+	if err != nil {
+		s.Logger.Warn("Failed to get last processed block for readiness: %v", err)
+		s.alertSync(ctx, nil, fmt.Errorf("failed to get last processed block: %v", err), s.now())
+		return
+	}
+
+	if s.Readiness != nil {
+		s.Readiness.UpdateLag(head, processed)
+	}
+	s.alertSync(ctx, new(big.Int).Sub(head, processed), nil, s.now())
+}
+
+// alertSync fires or resolves the sync alerts with the result of a lag check at now:
+// the lag in blocks, or the error that prevented measuring it. Both alerts fire only
+// once their condition has lasted AlertLagFor, and only once until it clears.
+func (s *IndexerService) alertSync(ctx context.Context, lag *big.Int, checkErr error, now time.Time) {
+	if s.Alerts == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.lagAlert == nil {
+		s.lagAlert = &alert.Condition{Notifier: s.Alerts, Key: "indexer-sync-lag", Severity: alert.SeverityCritical, For: s.AlertLagFor}
+		s.downAlert = &alert.Condition{Notifier: s.Alerts, Key: "indexer-down", Severity: alert.SeverityCritical, For: s.AlertLagFor}
+	}
+	s.mu.Unlock()
+
+	if checkErr != nil {
+		if err := s.downAlert.Update(ctx, true, fmt.Sprintf("Indexer cannot sync: %v", checkErr), now); err != nil {
+			s.Logger.Error("Failed to send indexer down alert: %v", err)
+		}
+		return
+	}
+	if err := s.downAlert.Update(ctx, false, "Indexer is syncing again", now); err != nil {
+		s.Logger.Error("Failed to resolve indexer down alert: %v", err)
+	}
+
+	if lag.Sign() < 0 {
+		lag = big.NewInt(0)
+	}
+	lagging := lag.Cmp(big.NewInt(s.AlertMaxLag)) > 0
+	summary := fmt.Sprintf("Indexer is %s blocks behind the chain head (max %d)", lag.String(), s.AlertMaxLag)
+	if !lagging {
+		summary = fmt.Sprintf("Indexer caught up, %s blocks behind the chain head", lag.String())
+	}
+	if err := s.lagAlert.Update(ctx, lagging, summary, now); err != nil {
+		s.Logger.Error("Failed to send sync lag alert: %v", err)
+	}
+}
+
 func (s *IndexerService) handleNFTEvents(ctx context.Context, eventChan <-chan *types.NFTTransferEvent, errChan <-chan error) {
 	for {
 		select {
@@ -97,7 +355,12 @@ func (s *IndexerService) handleNFTEvents(ctx context.Context, eventChan <-chan *
 				s.Logger.Warn("NFT event channel closed")
 				return
 			}
-			go s.processNFTEvent(event)
+			event.ReceivedAt = s.now()
+			if s.SyncProcessing {
+				s.processInOrder(ctx, event, func() error { return s.processNFTEvent(event) })
+				continue
+			}
+			s.goProcess(func() error { return s.processNFTEvent(event) })
 		case err, ok := <-errChan:
 			if ok {
 				s.Logger.Error("NFT event subscription error: %v", err)
@@ -117,7 +380,12 @@ func (s *IndexerService) handleTokenEvents(ctx context.Context, eventChan <-chan
 				s.Logger.Warn("Token event channel closed")
 				return
 			}
-			go s.processTokenEvent(event)
+			event.ReceivedAt = s.now()
+			if s.SyncProcessing {
+				s.processInOrder(ctx, event, func() error { return s.processTokenEvent(event) })
+				continue
+			}
+			s.goProcess(func() error { return s.processTokenEvent(event) })
 		case err, ok := <-errChan:
 			if ok {
 				s.Logger.Error("Token event subscription error: %v", err)
@@ -129,114 +397,175 @@ func (s *IndexerService) handleTokenEvents(ctx context.Context, eventChan <-chan
 	}
 }
 
-func (s *IndexerService) processNFTEvent(event *types.NFTTransferEvent) {
+// processNFTEvent stores an NFT transfer unless it is filtered out or already processed.
+// It returns an error if the event failed and may be retried.
+func (s *IndexerService) processNFTEvent(event *types.NFTTransferEvent) error {
 	s.Logger.Info("Processing NFT transfer event: block %s, token %s", event.BlockNumber.String(), event.TokenID.String())
 
-	// Create a unique event key for idempotency check
-	eventKey := fmt.Sprintf("nft:%s:%s:%s", event.Contract.Hex(), event.TokenID.String(), event.TxHash.Hex())
+	indexedEvent := s.Blockchain.ConvertNFTToIndexedEvent(event)
+	s.observeBlock(event.BlockNumber)
 
-	// Check if the event has already been processed
-	ctx := context.Background()
-	processed, err := s.Idempotency.IsProcessed(ctx, eventKey)
-	if err != nil {
-		s.Logger.Error("Failed to check if NFT event is processed: %v", err)
-		// Continue processing in case of error to avoid missing events
-	} else if processed {
-		s.Logger.Debug("NFT event already processed, skipping: %s", eventKey)
-		return
+	indexedEvent, err := s.storeEvent(context.Background(), "NFT", indexedEvent)
+	if err != nil || indexedEvent == nil {
+		return err
 	}
 
-	indexedEvent := s.Blockchain.ConvertNFTToIndexedEvent(event)
-
-	// Add to batch processor
-	err = s.BatchProcessor.AddEvent(indexedEvent)
-	if err != nil {
-		s.Logger.Error("Failed to add NFT event to batch processor: %v", err)
-		if s.Metrics != nil {
-			s.Metrics.IncrementError("batch", "add_event_failed")
+	// Cache the event with retry, briefly or not at all while it may still be reorged away
+	if ttl := s.eventCacheTTL(indexedEvent.BlockNumber); s.Cache != nil && ttl > 0 {
+		cacheKey := fmt.Sprintf("event:nft:%s:%s", indexedEvent.Contract, indexedEvent.TokenID)
+		err = utils.RetryWithBackoff(func() error {
+			return s.Cache.Set(context.Background(), cacheKey, indexedEvent, ttl)
+		}, nil)
+		if err != nil {
+			s.Logger.Warn("Failed to cache NFT event after retries: %v", err)
+			if s.Metrics != nil {
+				s.Metrics.IncrementError("cache", "set_failed")
+			}
 		}
-		return
 	}
+	return nil
+}
 
-	// Mark the event as processed for idempotency
-	if err := s.Idempotency.MarkProcessed(ctx, eventKey); err != nil {
-		s.Logger.Error("Failed to mark NFT event as processed: %v", err)
-		// Continue even if marking as processed fails to avoid losing events
+// storeEvent runs the pipeline shared by every kind of event: it enriches and filters
+// indexedEvent, skips it if it was already processed or is claimed by another worker,
+// adds it to the batch processor and marks it processed. With SyncProcessing the event is
+// inserted right away instead, and only marked processed once it is stored, so a failed
+// insert is retried and dead-lettered rather than lost in a later flush. kind names the
+// event in logs and errors. It returns the stored event, nil if the event was skipped,
+// and an error if the event failed and may be retried.
+func (s *IndexerService) storeEvent(ctx context.Context, kind string, indexedEvent *types.IndexedEvent) (*types.IndexedEvent, error) {
+	indexedEvent, eventKey, err := s.claimEvent(ctx, kind, indexedEvent)
+	if err != nil || indexedEvent == nil {
+		return nil, err
 	}
 
-	// Cache the event with retry
-	cacheKey := fmt.Sprintf("event:nft:%s:%s", indexedEvent.Contract, indexedEvent.TokenID)
-	err = utils.RetryWithBackoff(func() error {
-		return s.Cache.Set(context.Background(), cacheKey, indexedEvent, 24*time.Hour)
-	}, nil)
-	if err != nil {
-		s.Logger.Warn("Failed to cache NFT event after retries: %v", err)
+	if s.SyncProcessing {
+		inserted, err := s.BatchProcessor.InsertBatch([]*types.IndexedEvent{indexedEvent}, false)
+		if err != nil {
+			s.Logger.Error("Failed to insert %s event: %v", kind, err)
+			if s.Metrics != nil {
+				s.Metrics.IncrementError("batch", "insert_event_failed")
+			}
+			// Release the claim so the event can be retried
+			s.releaseClaims(ctx, kind, eventKey)
+			return nil, fmt.Errorf("failed to insert %s event: %v", kind, err)
+		}
+		// Inserted events bypass the batch, so they are published here; a duplicate of
+		// a stored event was published when it was first stored
+		if inserted > 0 {
+			s.invalidateStored([]*types.IndexedEvent{indexedEvent})
+			s.PublishStored([]*types.IndexedEvent{indexedEvent})
+		}
+	} else if err := s.BatchProcessor.AddEvent(indexedEvent); err != nil {
+		s.Logger.Error("Failed to add %s event to batch processor: %v", kind, err)
 		if s.Metrics != nil {
-			s.Metrics.IncrementError("cache", "set_failed")
+			s.Metrics.IncrementError("batch", "add_event_failed")
 		}
+		// Release the claim so the event can be retried
+		s.releaseClaims(ctx, kind, eventKey)
+		return nil, fmt.Errorf("failed to add %s event to batch processor: %v", kind, err)
 	}
 
-	if s.Metrics != nil {
-		s.Metrics.IncrementEventsProcessed()
-		s.Metrics.IncrementEventsIndexed()
-	}
-
-	s.Logger.Info("Successfully processed NFT transfer event: %s", indexedEvent.TxHash)
+	s.markStored(ctx, kind, indexedEvent, eventKey)
+	return indexedEvent, nil
 }
 
-func (s *IndexerService) processTokenEvent(event *types.TokenTransferEvent) {
-	s.Logger.Info("Processing token transfer event: block %s, value %s", event.BlockNumber.String(), event.Value.String())
+// claimEvent enriches and filters indexedEvent and claims it unless it was already
+// processed or is claimed by another worker. It returns the event and its idempotency
+// key, nil if the event was skipped, and an error if the transformer failed.
+func (s *IndexerService) claimEvent(ctx context.Context, kind string, indexedEvent *types.IndexedEvent) (*types.IndexedEvent, string, error) {
+	txHash := indexedEvent.TxHash
+
+	// Enrich and filter the event before it is stored
+	indexedEvent, keep, err := s.transformEvent(ctx, indexedEvent)
+	if err != nil {
+		s.Logger.Error("Failed to transform %s event: %v", kind, err)
+		if s.Metrics != nil {
+			s.Metrics.IncrementError("transform", "transform_failed")
+		}
+		return nil, "", fmt.Errorf("failed to transform %s event: %v", kind, err)
+	} else if !keep {
+		s.Logger.Debug("Transformer dropped %s event: %s", kind, txHash)
+		return nil, "", nil
+	}
 
 	// Create a unique event key for idempotency check
-	eventKey := fmt.Sprintf("token:%s:%s:%s", event.Contract.Hex(), event.Value.String(), event.TxHash.Hex())
+	eventKey := s.Idempotency.EventKey(indexedEvent)
 
 	// Check if the event has already been processed
-	ctx := context.Background()
 	processed, err := s.Idempotency.IsProcessed(ctx, eventKey)
 	if err != nil {
-		s.Logger.Error("Failed to check if token event is processed: %v", err)
+		s.Logger.Error("Failed to check if %s event is processed: %v", kind, err)
 		// Continue processing in case of error to avoid missing events
 	} else if processed {
-		s.Logger.Debug("Token event already processed, skipping: %s", eventKey)
-		return
+		s.Logger.Debug("Skipping already processed %s event: %s", kind, eventKey)
+		return nil, "", nil
 	}
 
-	indexedEvent := s.Blockchain.ConvertTokenToIndexedEvent(event)
-
-	// Add to batch processor
-	err = s.BatchProcessor.AddEvent(indexedEvent)
+	// Claim the event so a concurrent worker doesn't process it as well
+	claimed, err := s.Idempotency.TryClaim(ctx, eventKey)
 	if err != nil {
-		s.Logger.Error("Failed to add token event to batch processor: %v", err)
-		if s.Metrics != nil {
-			s.Metrics.IncrementError("batch", "add_event_failed")
+		s.Logger.Error("Failed to claim %s event: %v", kind, err)
+		// Continue processing in case of error to avoid missing events
+	} else if !claimed {
+		s.Logger.Debug("Skipping %s event being processed by another worker: %s", kind, eventKey)
+		return nil, "", nil
+	}
+	return indexedEvent, eventKey, nil
+}
+
+// releaseClaims releases the claims of events that were not stored so they can be retried
+func (s *IndexerService) releaseClaims(ctx context.Context, kind string, eventKeys ...string) {
+	for _, eventKey := range eventKeys {
+		if err := s.Idempotency.ReleaseClaim(ctx, eventKey); err != nil {
+			s.Logger.Warn("Failed to release %s event claim: %v", kind, err)
 		}
-		return
 	}
+}
 
+// markStored marks a stored event as processed and records it in the metrics
+func (s *IndexerService) markStored(ctx context.Context, kind string, indexedEvent *types.IndexedEvent, eventKey string) {
 	// Mark the event as processed for idempotency
 	if err := s.Idempotency.MarkProcessed(ctx, eventKey); err != nil {
-		s.Logger.Error("Failed to mark token event as processed: %v", err)
+		s.Logger.Error("Failed to mark %s event as processed: %v", kind, err)
 		// Continue even if marking as processed fails to avoid losing events
 	}
 
-	// Cache the event with retry
-	cacheKey := fmt.Sprintf("event:token:%s:%s", indexedEvent.Contract, indexedEvent.TxHash)
-	err = utils.RetryWithBackoff(func() error {
-		return s.Cache.Set(context.Background(), cacheKey, indexedEvent, 24*time.Hour)
-	}, nil)
-	if err != nil {
-		s.Logger.Warn("Failed to cache token event after retries: %v", err)
-		if s.Metrics != nil {
-			s.Metrics.IncrementError("cache", "set_failed")
-		}
-	}
-
 	if s.Metrics != nil {
 		s.Metrics.IncrementEventsProcessed()
 		s.Metrics.IncrementEventsIndexed()
 	}
 
-	s.Logger.Info("Successfully processed token transfer event: %s", indexedEvent.TxHash)
+	s.Logger.Info("Successfully processed %s event: %s", kind, indexedEvent.TxHash)
+}
+
+// processTokenEvent stores a token transfer unless it is filtered out or already processed.
+// It returns an error if the event failed and may be retried.
+func (s *IndexerService) processTokenEvent(event *types.TokenTransferEvent) error {
+	s.Logger.Info("Processing token transfer event: block %s, value %s", event.BlockNumber.String(), event.Value.String())
+
+	indexedEvent := s.Blockchain.ConvertTokenToIndexedEvent(event)
+	s.observeBlock(event.BlockNumber)
+
+	indexedEvent, err := s.storeEvent(context.Background(), "token", indexedEvent)
+	if err != nil || indexedEvent == nil {
+		return err
+	}
+
+	// Cache the event with retry, briefly or not at all while it may still be reorged away
+	if ttl := s.eventCacheTTL(indexedEvent.BlockNumber); s.Cache != nil && ttl > 0 {
+		cacheKey := fmt.Sprintf("event:token:%s:%s", indexedEvent.Contract, indexedEvent.TxHash)
+		err = utils.RetryWithBackoff(func() error {
+			return s.Cache.Set(context.Background(), cacheKey, indexedEvent, ttl)
+		}, nil)
+		if err != nil {
+			s.Logger.Warn("Failed to cache token event after retries: %v", err)
+			if s.Metrics != nil {
+				s.Metrics.IncrementError("cache", "set_failed")
+			}
+		}
+	}
+	return nil
 }
 
 // GetEvents retrieves events based on filter criteria
@@ -244,18 +573,20 @@ func (s *IndexerService) GetEvents(filter *types.EventFilter) ([]types.IndexedEv
 	ctx := context.Background()
 	
 	// Try to get from cache first with retry
-	cacheKey := fmt.Sprintf("events:%s:%s:%s", filter.EventType, filter.Contract, filter.FromBlock)
+	cacheKey := fmt.Sprintf("events:%s:%s:%s:%s:%s:%s:%s:%s", filter.EventType, filter.Contract, filter.FromBlock, filter.MinValue, filter.MaxValue, formatFilterTime(filter.FromTime), formatFilterTime(filter.ToTime), strings.Join(filter.Fields, ","))
 	var cachedEvents []types.IndexedEvent
 	
-	err := utils.RetryWithBackoff(func() error {
-		return s.Cache.Get(ctx, cacheKey, &cachedEvents)
-	}, nil)
-	if err == nil {
-		s.Logger.Debug("Retrieved events from cache: %s", cacheKey)
-		if s.Metrics != nil {
-			s.Metrics.IncrementCacheHit()
+	if s.Cache != nil {
+		err := utils.RetryWithBackoff(func() error {
+			return s.Cache.Get(ctx, cacheKey, &cachedEvents)
+		}, nil)
+		if err == nil {
+			s.Logger.Debug("Retrieved events from cache: %s", cacheKey)
+			if s.Metrics != nil {
+				s.Metrics.IncrementCacheHit()
+			}
+			return cachedEvents, nil
 		}
-		return cachedEvents, nil
 	}
 
 	if s.Metrics != nil {
@@ -264,7 +595,7 @@ func (s *IndexerService) GetEvents(filter *types.EventFilter) ([]types.IndexedEv
 
 	// Fetch from database with retry using cached database
 	var events []types.IndexedEvent
-	err = utils.RetryWithBackoff(func() error {
+	err := utils.RetryWithBackoff(func() error {
 		var dbErr error
 		events, dbErr = s.Database.GetEvents(filter)  // Use the cached database method
 		return dbErr
@@ -277,7 +608,7 @@ func (s *IndexerService) GetEvents(filter *types.EventFilter) ([]types.IndexedEv
 	}
 
 	// Cache the results with retry
-	if len(events) > 0 {
+	if s.Cache != nil && len(events) > 0 {
 		go func() {
 			err := utils.RetryWithBackoff(func() error {
 				return s.Cache.Set(ctx, cacheKey, events, 10*time.Minute)
@@ -294,6 +625,14 @@ func (s *IndexerService) GetEvents(filter *types.EventFilter) ([]types.IndexedEv
 	return events, nil
 }
 
+// formatFilterTime formats an optional filter time for cache keys
+func formatFilterTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
 // GetNFTEvents retrieves NFT transfer events
 func (s *IndexerService) GetNFTEvents(contractAddress string, fromBlock, toBlock *big.Int, limit, offset int) ([]types.IndexedEvent, error) {
 	filter := &types.EventFilter{
@@ -322,42 +661,42 @@ func (s *IndexerService) GetTokenEvents(contractAddress string, fromBlock, toBlo
 	return s.GetEvents(filter)
 }
 
-// ProcessHistoricalEvents processes historical events from a specific block range
+// DefaultBackfillWindow is the number of blocks of a contract ProcessHistoricalEvents
+// processes between backfill progress records
+const DefaultBackfillWindow = 1000
+
+// BackfillProgressStore records up to which block the backfill of each contract completed,
+// and the block ranges it completed
+type BackfillProgressStore interface {
+	GetBackfillProgress(contract, chainID string) (uint64, bool, error)
+	SaveBackfillProgress(contract, chainID string, blockNumber uint64) error
+	GetBackfillRanges(contract, chainID string) ([]types.BackfillRange, error)
+	SaveBackfillRange(contract, chainID string, fromBlock, toBlock uint64) error
+}
+
+// ProcessHistoricalEvents processes historical events from a specific block range,
+// skipping paused contracts. With BackfillProgress set, blocks a contract already completed are skipped, so an
+// interrupted backfill resumes where it stopped rather than at fromBlock, and only the
+// missing parts of a partly indexed range are fetched.
 func (s *IndexerService) ProcessHistoricalEvents(ctx context.Context, contractAddresses []common.Address, fromBlock, toBlock *big.Int) error {
 	s.Logger.Info("Processing historical events from block %s to %s", fromBlock.String(), toBlock.String())
 
-	// Process NFT transfers in parallel
+	// Paused contracts are not backfilled
+	contractAddresses, err := s.activeContracts(contractAddresses)
+	if err != nil {
+		return fmt.Errorf("failed to load paused contracts: %v", err)
+	}
+
+	// Process contracts in parallel
 	var wg sync.WaitGroup
-	errChan := make(chan error, len(contractAddresses)*2)
+	errChan := make(chan error, len(contractAddresses))
 
 	for _, addr := range contractAddresses {
-		wg.Add(2)
-
-		// Process NFT transfers
+		wg.Add(1)
 		go func(contractAddr common.Address) {
 			defer wg.Done()
-			nftEvents, err := s.Blockchain.ProcessNFTTransfers(ctx, contractAddr, fromBlock, toBlock)
-			if err != nil {
-				errChan <- fmt.Errorf("failed to process NFT transfers for contract %s: %v", contractAddr.Hex(), err)
-				return
-			}
-
-			for _, event := range nftEvents {
-				s.processNFTEvent(event) // Process synchronously to respect idempotency
-			}
-		}(addr)
-
-		// Process token transfers
-		go func(contractAddr common.Address) {
-			defer wg.Done()
-			tokenEvents, err := s.Blockchain.ProcessTokenTransfers(ctx, contractAddr, fromBlock, toBlock)
-			if err != nil {
-				errChan <- fmt.Errorf("failed to process token transfers for contract %s: %v", contractAddr.Hex(), err)
-				return
-			}
-
-			for _, event := range tokenEvents {
-				s.processTokenEvent(event) // Process synchronously to respect idempotency
+			if err := s.backfillContract(ctx, contractAddr, fromBlock.Uint64(), toBlock.Uint64()); err != nil {
+				errChan <- err
 			}
 		}(addr)
 	}
@@ -378,6 +717,239 @@ func (s *IndexerService) ProcessHistoricalEvents(ctx context.Context, contractAd
 	return nil
 }
 
+// backfillContract processes the transfers of a contract from fromBlock to toBlock. The
+// blocks already completed are skipped, and the remaining ranges are processed window by
+// window, recording the progress after each window.
+func (s *IndexerService) backfillContract(ctx context.Context, contractAddr common.Address, fromBlock, toBlock uint64) error {
+	if fromBlock > toBlock {
+		return nil
+	}
+	contract := contractAddr.Hex()
+	ranges := []types.BackfillRange{{FromBlock: fromBlock, ToBlock: toBlock}}
+	if s.BackfillProgress != nil {
+		completed, err := s.BackfillProgress.GetBackfillRanges(contract, s.ChainID)
+		if err != nil {
+			return fmt.Errorf("failed to get backfill ranges for contract %s: %v", contract, err)
+		}
+		// Backfills recorded before completed ranges were kept only have their progress.
+		// It is saved as a range, so it is not forgotten once this backfill saves its own.
+		if len(completed) == 0 {
+			done, ok, err := s.BackfillProgress.GetBackfillProgress(contract, s.ChainID)
+			if err != nil {
+				return fmt.Errorf("failed to get backfill progress for contract %s: %v", contract, err)
+			}
+			if ok {
+				if err := s.BackfillProgress.SaveBackfillRange(contract, s.ChainID, 0, done); err != nil {
+					return fmt.Errorf("failed to save backfill range for contract %s: %v", contract, err)
+				}
+				completed = append(completed, types.BackfillRange{FromBlock: 0, ToBlock: done})
+			}
+		}
+
+		ranges = incompleteRanges(fromBlock, toBlock, completed)
+		if len(ranges) == 0 {
+			s.Logger.Debug("Backfill of contract %s already completed from block %d to %d, skipping", contract, fromBlock, toBlock)
+			return nil
+		}
+		if ranges[0].FromBlock != fromBlock || len(ranges) > 1 || ranges[0].ToBlock != toBlock {
+			s.Logger.Info("Resuming backfill of contract %s in %d ranges from block %d, skipping completed blocks", contract, len(ranges), ranges[0].FromBlock)
+		}
+	}
+
+	window := s.BackfillWindow
+	if window == 0 {
+		window = DefaultBackfillWindow
+	}
+
+	for _, r := range ranges {
+		for start := r.FromBlock; ; start += window {
+			end := r.ToBlock
+			if r.ToBlock-start >= window {
+				end = start + window - 1
+			}
+			if err := s.backfillWindow(ctx, contractAddr, start, end); err != nil {
+				return err
+			}
+
+			if s.BackfillProgress != nil {
+				if err := s.BackfillProgress.SaveBackfillRange(contract, s.ChainID, start, end); err != nil {
+					return fmt.Errorf("failed to save backfill range for contract %s: %v", contract, err)
+				}
+				if err := s.BackfillProgress.SaveBackfillProgress(contract, s.ChainID, end); err != nil {
+					return fmt.Errorf("failed to save backfill progress for contract %s: %v", contract, err)
+				}
+			}
+			if end == r.ToBlock {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// backfillWindow stores the transfers of a contract from start to end. It returns an
+// error unless every transfer of the window was stored or skipped as already processed.
+func (s *IndexerService) backfillWindow(ctx context.Context, contractAddr common.Address, start, end uint64) error {
+	contract := contractAddr.Hex()
+	from, to := new(big.Int).SetUint64(start), new(big.Int).SetUint64(end)
+
+	nftEvents, err := s.Blockchain.ProcessNFTTransfers(ctx, contractAddr, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to process NFT transfers for contract %s: %v", contract, err)
+	}
+	tokenEvents, err := s.Blockchain.ProcessTokenTransfers(ctx, contractAddr, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to process token transfers for contract %s: %v", contract, err)
+	}
+
+	indexedEvents := make([]*types.IndexedEvent, 0, len(nftEvents)+len(tokenEvents))
+	kinds := make([]string, 0, cap(indexedEvents))
+	for _, event := range nftEvents {
+		indexedEvents = append(indexedEvents, s.Blockchain.ConvertNFTToIndexedEvent(event))
+		s.observeBlock(event.BlockNumber)
+		kinds = append(kinds, "NFT")
+	}
+	for _, event := range tokenEvents {
+		indexedEvents = append(indexedEvents, s.Blockchain.ConvertTokenToIndexedEvent(event))
+		s.observeBlock(event.BlockNumber)
+		kinds = append(kinds, "token")
+	}
+
+	// Claim the events first and store the window with a single insert, so progress is
+	// only recorded once every event of the window is stored
+	var claimed []*types.IndexedEvent
+	var claimedKinds, eventKeys []string
+	for i, event := range indexedEvents {
+		event, eventKey, err := s.claimEvent(ctx, kinds[i], event)
+		if err != nil {
+			s.releaseClaims(ctx, "backfilled", eventKeys...)
+			return fmt.Errorf("failed to backfill contract %s from block %d to %d: %v", contract, start, end, err)
+		}
+		if event == nil {
+			continue
+		}
+		claimed = append(claimed, event)
+		claimedKinds = append(claimedKinds, kinds[i])
+		eventKeys = append(eventKeys, eventKey)
+	}
+	if len(claimed) == 0 {
+		return nil
+	}
+
+	if _, err := s.BatchProcessor.InsertBatch(claimed, false); err != nil {
+		s.releaseClaims(ctx, "backfilled", eventKeys...)
+		if s.Metrics != nil {
+			s.Metrics.IncrementError("batch", "insert_failed")
+		}
+		return fmt.Errorf("failed to store backfilled events of contract %s from block %d to %d: %v", contract, start, end, err)
+	}
+	for i, event := range claimed {
+		s.markStored(ctx, claimedKinds[i], event, eventKeys[i])
+	}
+	s.invalidateStored(claimed)
+	s.PublishStored(claimed)
+	return nil
+}
+
+// incompleteRanges returns the parts of fromBlock-toBlock not covered by the completed
+// ranges, in order. Adjacent missing blocks form a single range, so each gap is fetched
+// with as few log queries as the window allows.
+func incompleteRanges(fromBlock, toBlock uint64, completed []types.BackfillRange) []types.BackfillRange {
+	sorted := make([]types.BackfillRange, len(completed))
+	copy(sorted, completed)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].FromBlock < sorted[j].FromBlock })
+
+	var missing []types.BackfillRange
+	next := fromBlock
+	for _, r := range sorted {
+		if r.ToBlock < next {
+			continue
+		}
+		if r.FromBlock > toBlock {
+			break
+		}
+		if r.FromBlock > next {
+			missing = append(missing, types.BackfillRange{FromBlock: next, ToBlock: r.FromBlock - 1})
+		}
+		if r.ToBlock >= toBlock {
+			return missing
+		}
+		next = r.ToBlock + 1
+	}
+	return append(missing, types.BackfillRange{FromBlock: next, ToBlock: toBlock})
+}
+
+// GetEventsByTxHash returns every event emitted by a transaction, ordered by log index
+func (s *IndexerService) GetEventsByTxHash(txHash string) ([]types.IndexedEvent, error) {
+	var events []types.IndexedEvent
+	err := utils.RetryWithBackoff(func() error {
+		var dbErr error
+		events, dbErr = s.Database.GetEventsByTxHash(txHash)
+		return dbErr
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetAddressActivity returns the events sent or received by address, newest first
+func (s *IndexerService) GetAddressActivity(address string, limitNum, offset int) ([]types.AddressActivity, error) {
+	var activity []types.AddressActivity
+	err := utils.RetryWithBackoff(func() error {
+		var dbErr error
+		activity, dbErr = s.Database.GetAddressActivity(address, limitNum, offset)
+		return dbErr
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// GetBalances returns the current token balances of address, of contract only when it
+// is not empty
+func (s *IndexerService) GetBalances(address, contract string) ([]types.TokenBalance, error) {
+	var balances []types.TokenBalance
+	err := utils.RetryWithBackoff(func() error {
+		var dbErr error
+		balances, dbErr = s.Database.GetBalances(address, contract)
+		return dbErr
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+// GetLatestEvents returns the limitNum most recently indexed events, newest first
+func (s *IndexerService) GetLatestEvents(limitNum int) ([]types.IndexedEvent, error) {
+	var events []types.IndexedEvent
+	err := utils.RetryWithBackoff(func() error {
+		var dbErr error
+		events, dbErr = s.Database.GetLatestEvents(limitNum)
+		return dbErr
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetEventsAfterID returns up to limitNum events indexed after afterID, oldest first
+func (s *IndexerService) GetEventsAfterID(afterID uint, limitNum int) ([]types.IndexedEvent, error) {
+	var events []types.IndexedEvent
+	err := utils.RetryWithBackoff(func() error {
+		var dbErr error
+		events, dbErr = s.Database.GetEventsAfterID(afterID, limitNum)
+		return dbErr
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
 // GetLatestBlockProcessed returns the latest block number that was processed
 func (s *IndexerService) GetLatestBlockProcessed() (*big.Int, error) {
 	var event *types.IndexedEvent
@@ -441,31 +1013,42 @@ func (s *IndexerService) IntegrateExternalData(ctx context.Context) error {
 		lastProcessed = big.NewInt(0)
 	}
 	
+	sink := s.externalSink()
+
 	// Pull historical data from the last processed point
 	startTime := time.Now().Add(-24 * time.Hour) // Last 24 hours of data
 	endTime := time.Now()
 	
-	historicalData, err := s.DataPuller.PullHistorical(ctx, startTime, endTime, map[string]interface{}{
+	// Stream the data page by page, resuming from the cursor after a failed page
+	filters := map[string]interface{}{
 		"from_block": lastProcessed.String(),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to pull historical data: %v", err)
 	}
-	
-	// Process the pulled data
-	for _, data := range historicalData {
-		if err := s.processExternalData(data); err != nil {
-			s.Logger.Error("Failed to process external data: %v", err)
-			// Continue processing other data
-			continue
+	handler := datapuller.SinkHandler(ctx, sink)
+	written := 0
+	cursor := startTime
+	err = utils.RetryWithBackoff(func() error {
+		var pullErr error
+		cursor, pullErr = s.DataPuller.PullHistoricalStream(ctx, cursor, endTime, filters, func(data interface{}) error {
+			if err := handler(data); err != nil {
+				s.Logger.Warn("Failed to write historical external event: %v", err)
+				return nil
+			}
+			written++
+			return nil
+		})
+		if pullErr != nil {
+			s.Logger.Warn("Historical pull interrupted at %v: %v", cursor, pullErr)
 		}
+		return pullErr
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to pull historical data: %v", err)
 	}
+	s.Logger.Info("Wrote %d historical external events", written)
 	
 	// Start real-time pulling in a separate goroutine
 	go func() {
-		if err := s.DataPuller.PullRealTimeEvents(ctx, func(data interface{}) error {
-			return s.processExternalData(data)
-		}); err != nil {
+		if err := s.DataPuller.PullRealTimeEventsToSink(ctx, sink); err != nil {
 			s.Logger.Error("Real-time data pulling failed: %v", err)
 		}
 	}()
@@ -474,35 +1057,38 @@ func (s *IndexerService) IntegrateExternalData(ctx context.Context) error {
 	return nil
 }
 
-// processExternalData processes data from external sources
-func (s *IndexerService) processExternalData(data interface{}) error {
-	// Convert the external data to our internal format
-	// This is a simplified implementation - in a real system, you'd need to
-	// handle different data formats from different sources
-	
-	// Check if the data is already in our internal IndexedEvent format
-	var indexedEvent *types.IndexedEvent
-	
-	if event, ok := data.(*types.IndexedEvent); ok {
-		// Data is already in the correct format
-		indexedEvent = event
-	} else if eventData, ok := data.(map[string]interface{}); ok {
-		// Data is in map format from external source, need to convert
-		convertedEvent, err := convertExternalDataToIndexedEvent(eventData)
-		if err != nil {
-			s.Logger.Error("Failed to convert external data to IndexedEvent: %v", err)
-			return fmt.Errorf("failed to convert external data: %v", err)
-		}
-		indexedEvent = convertedEvent
-	} else {
-		// Try to handle other possible formats
-		s.Logger.Error("Unsupported data format for external data: %T", data)
-		return fmt.Errorf("unsupported data format: %T", data)
+// externalSink returns the sink receiving pulled external events, defaulting to
+// the indexer's own storage path
+func (s *IndexerService) externalSink() datapuller.Sink {
+	if s.ExternalSink != nil {
+		return s.ExternalSink
+	}
+	return &indexerSink{s: s}
+}
+
+// indexerSink stores external events like indexed chain events: deduplicated through
+// the idempotency service, saved by the batch processor and cached
+type indexerSink struct {
+	s *IndexerService
+}
+
+// Write stores an external event
+func (k *indexerSink) Write(ctx context.Context, indexedEvent *types.IndexedEvent) error {
+	s := k.s
+
+	// Enrich and filter the event before it is stored
+	indexedEvent, keep, err := s.transformEvent(ctx, indexedEvent)
+	if err != nil {
+		s.Logger.Error("Failed to transform external event: %v", err)
+		return err
+	} else if !keep {
+		s.Logger.Debug("External event dropped by a transformer")
+		return nil
 	}
 	
 	// Check for idempotency to avoid duplicates
-	eventKey := fmt.Sprintf("%s_%s", indexedEvent.TxHash, indexedEvent.EventName)
-	if exists, err := s.Idempotency.IsProcessed(eventKey); err != nil {
+	eventKey := s.Idempotency.EventKey(indexedEvent)
+	if exists, err := s.Idempotency.IsProcessed(ctx, eventKey); err != nil {
 		s.Logger.Error("Failed to check idempotency for event %s: %v", eventKey, err)
 		// Continue processing anyway
 	} else if exists {
@@ -517,16 +1103,18 @@ func (s *IndexerService) processExternalData(data interface{}) error {
 	}
 	
 	// Mark as processed for idempotency
-	if err := s.Idempotency.MarkProcessed(eventKey, indexedEvent.Timestamp); err != nil {
+	if err := s.Idempotency.MarkProcessed(ctx, eventKey); err != nil {
 		s.Logger.Error("Failed to mark event as processed for idempotency: %v", err)
 		// This is not a fatal error, continue processing
 	}
 	
 	// Update cache
-	cacheKey := fmt.Sprintf("event_%s", indexedEvent.TxHash)
-	if err := s.Cache.Set(cacheKey, indexedEvent, 1*time.Hour); err != nil {
-		s.Logger.Warn("Failed to cache event: %v", err)
-		// This is not a fatal error, continue processing
+	if s.Cache != nil {
+		cacheKey := fmt.Sprintf("event_%s", indexedEvent.TxHash)
+		if err := s.Cache.Set(cacheKey, indexedEvent, 1*time.Hour); err != nil {
+			s.Logger.Warn("Failed to cache event: %v", err)
+			// This is not a fatal error, continue processing
+		}
 	}
 	
 	// Update metrics
@@ -536,165 +1124,3 @@ func (s *IndexerService) processExternalData(data interface{}) error {
 	
 	return nil
 }
-
-// convertExternalDataToIndexedEvent converts external data format to our internal IndexedEvent format
-func convertExternalDataToIndexedEvent(data map[string]interface{}) (*types.IndexedEvent, error) {
-	// This function would typically be in a separate utility package
-	// but for this implementation we'll keep it in the same file
-	
-	blockNumberStr, ok := data["blockNumber"].(string)
-	if !ok {
-		// Try alternative field names
-		if val, exists := data["block_number"]; exists {
-			if str, ok := val.(string); ok {
-				blockNumberStr = str
-			} else if num, ok := val.(float64); ok {
-				blockNumberStr = fmt.Sprintf("%.0f", num)
-			} else {
-				return nil, fmt.Errorf("missing or invalid block number")
-			}
-		} else {
-			return nil, fmt.Errorf("missing block number")
-		}
-	}
-	
-	txHash, ok := data["txHash"].(string)
-	if !ok {
-		// Try alternative field names
-		if val, exists := data["transactionHash"]; exists {
-			if str, ok := val.(string); ok {
-				txHash = str
-			} else {
-				return nil, fmt.Errorf("invalid transaction hash")
-			}
-		} else {
-			return nil, fmt.Errorf("missing transaction hash")
-		}
-	}
-	
-	eventName, ok := data["eventName"].(string)
-	if !ok {
-		// Try alternative field names
-		if val, exists := data["event_name"]; exists {
-			if str, ok := val.(string); ok {
-				eventName = str
-			} else {
-				eventName = "Unknown"
-			}
-		} else {
-			eventName = "Unknown"
-		}
-	}
-	
-	contract, ok := data["contract"].(string)
-	if !ok {
-		// Try alternative field names
-		if val, exists := data["address"]; exists {
-			if str, ok := val.(string); ok {
-				contract = str
-			} else {
-				contract = ""
-			}
-		} else {
-			contract = ""
-		}
-	}
-	
-	// Parse block number string to *big.Int
-	blockNumber := new(big.Int)
-	if blockNumberStr != "" {
-		// Check if it's hex format
-		if len(blockNumberStr) >= 2 && blockNumberStr[:2] == "0x" {
-			_, ok := blockNumber.SetString(blockNumberStr[2:], 16)
-			if !ok {
-				// If hex parsing fails, try decimal
-				_, ok = blockNumber.SetString(blockNumberStr, 10)
-				if !ok {
-					return nil, fmt.Errorf("invalid block number format: %s", blockNumberStr)
-				}
-			}
-		} else {
-			_, ok = blockNumber.SetString(blockNumberStr, 10)
-			if !ok {
-				return nil, fmt.Errorf("invalid block number format: %s", blockNumberStr)
-			}
-		}
-	}
-	
-	// Parse timestamp
-	timestamp := time.Now()
-	if ts, exists := data["timestamp"]; exists {
-		if tsStr, ok := ts.(string); ok {
-			if parsedTime, err := time.Parse(time.RFC3339, tsStr); err == nil {
-				timestamp = parsedTime
-			} else if parsedTime, err := time.Parse("2006-01-02T15:04:05Z", tsStr); err == nil {
-				timestamp = parsedTime
-			} else if tsFloat, ok := ts.(float64); ok {
-				timestamp = time.Unix(int64(tsFloat), 0)
-			}
-		} else if tsFloat, ok := ts.(float64); ok {
-			timestamp = time.Unix(int64(tsFloat), 0)
-		}
-	} else if ts, exists := data["timeStamp"]; exists {
-		if tsStr, ok := ts.(string); ok {
-			if tsFloat, err := strconv.ParseFloat(tsStr, 64); err == nil {
-				timestamp = time.Unix(int64(tsFloat), 0)
-			}
-		} else if tsFloat, ok := ts.(float64); ok {
-			timestamp = time.Unix(int64(tsFloat), 0)
-		}
-	}
-	
-	// Extract optional fields
-	from := ""
-	if val, exists := data["from"]; exists {
-		if str, ok := val.(string); ok {
-			from = str
-		}
-	}
-	
-	to := ""
-	if val, exists := data["to"]; exists {
-		if str, ok := val.(string); ok {
-			to = str
-		}
-	}
-	
-	tokenID := ""
-	if val, exists := data["tokenID"]; exists {
-		if str, ok := val.(string); ok {
-			tokenID = str
-		} else if num, ok := val.(float64); ok {
-			tokenID = fmt.Sprintf("%.0f", num)
-		}
-	} else if val, exists := data["tokenId"]; exists {
-		if str, ok := val.(string); ok {
-			tokenID = str
-		} else if num, ok := val.(float64); ok {
-			tokenID = fmt.Sprintf("%.0f", num)
-		}
-	}
-	
-	value := ""
-	if val, exists := data["value"]; exists {
-		if str, ok := val.(string); ok {
-			value = str
-		} else if num, ok := val.(float64); ok {
-			value = fmt.Sprintf("%.0f", num)
-		}
-	}
-	
-	return &types.IndexedEvent{
-		BlockNumber: blockNumber,
-		TxHash:      txHash,
-		EventName:   eventName,
-		Contract:    contract,
-		From:        from,
-		To:          to,
-		TokenID:     tokenID,
-		Value:       value,
-		Timestamp:   timestamp,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}, nil
-}
\ No newline at end of file
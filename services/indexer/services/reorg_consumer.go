@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"chainpulse/shared/mq"
+	"chainpulse/shared/types"
+)
+
+// errInvalidReorgEvent 表示重组消息缺少起始区块，重试也无法处理
+var errInvalidReorgEvent = errors.New("reorg event without a from block")
+
+// ConsumeReorgEvents 消费监听服务发布到 topic 的重组消息，回滚受影响的事件。
+// 处理失败的消息按 retry 重试，仍然失败时进入死信主题
+func (rh *ReorgHandler) ConsumeReorgEvents(ctx context.Context, queue mq.MessageQueue, topic string, retry mq.RetryPolicy) error {
+	return mq.ConsumeWithRetry(ctx, queue, topic, retry, func(data []byte) error {
+		var event types.ReorgEvent
+		if err := mq.Decode(data, &event); err != nil {
+			return fmt.Errorf("failed to decode reorg event: %v", err)
+		}
+		return rh.HandleReorgEvent(mq.ContextFromMessage(ctx, data), &event)
+	})
+}
+
+// HandleReorgEvent 处理一条重组消息：删除起始区块及之后的事件，更新重组纪元使缓存失效，并发送重组告警。
+// 起始区块之后的区块都建立在孤块之上，因此回滚不止于消息中的结束区块。
+// 重复投递的消息会再次回滚，被删除的事件随后按规范链重新索引
+func (rh *ReorgHandler) HandleReorgEvent(ctx context.Context, event *types.ReorgEvent) error {
+	if event.FromBlock == nil {
+		return errInvalidReorgEvent
+	}
+
+	toBlock := event.ToBlock
+	if toBlock == nil || toBlock.Cmp(event.FromBlock) < 0 {
+		toBlock = event.FromBlock
+	}
+	rh.logger.Warn("Received reorg event for blocks %s-%s", event.FromBlock.String(), toBlock.String())
+
+	if err := rh.rollbackToBlock(ctx, event.FromBlock); err != nil {
+		return fmt.Errorf("failed to rollback reorg from block %s: %v", event.FromBlock.String(), err)
+	}
+
+	reorgDepth := toBlock.Uint64() - event.FromBlock.Uint64() + 1
+	rh.alertDeepReorg(ctx, event.FromBlock, reorgDepth, event.OldHash, event.NewHash)
+	return nil
+}
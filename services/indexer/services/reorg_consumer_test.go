@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"chainpulse/shared/alert"
+	"chainpulse/shared/mq"
+	"chainpulse/shared/types"
+)
+
+// reorgStore keeps the blocks of stored events in memory
+type reorgStore struct {
+	mu                 sync.Mutex
+	eventBlocks        []int64
+	processedFrom      *big.Int
+	lastProcessedBlock *big.Int
+}
+
+func (s *reorgStore) GetBlockHeaders(fromBlock uint64) ([]types.BlockHeader, error) {
+	return nil, nil
+}
+
+func (s *reorgStore) SaveBlockHeader(blockNumber uint64, blockHash string) error { return nil }
+
+func (s *reorgStore) PruneBlockHeaders(blockNumber uint64) error { return nil }
+
+func (s *reorgStore) DeleteBlockHeadersFromBlock(blockNumber uint64) error { return nil }
+
+func (s *reorgStore) DeleteEventsFromBlock(blockNumber *big.Int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var kept []int64
+	for _, block := range s.eventBlocks {
+		if block < blockNumber.Int64() {
+			kept = append(kept, block)
+		}
+	}
+	s.eventBlocks = kept
+	return nil
+}
+
+func (s *reorgStore) DeleteProcessedEventsFromBlock(blockNumber *big.Int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.processedFrom = blockNumber
+	return nil
+}
+
+func (s *reorgStore) SaveLastProcessedBlock(blockNum *big.Int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastProcessedBlock = blockNum
+	return nil
+}
+
+func (s *reorgStore) UpdateLastProcessedBlockWithHash(blockNum *big.Int, blockHash string) error {
+	return s.SaveLastProcessedBlock(blockNum)
+}
+
+// loopbackMQ delivers published messages to the handler consuming their topic
+type loopbackMQ struct {
+	mu       sync.Mutex
+	handlers map[string]mq.MessageHandler
+	ready    chan struct{}
+}
+
+func newLoopbackMQ() *loopbackMQ {
+	return &loopbackMQ{handlers: make(map[string]mq.MessageHandler), ready: make(chan struct{})}
+}
+
+func (m *loopbackMQ) Publish(topic string, message interface{}) error {
+	return m.PublishContext(context.Background(), topic, message)
+}
+
+func (m *loopbackMQ) PublishContext(ctx context.Context, topic string, message interface{}) error {
+	data, err := mq.Encode(mq.JSONCodec{}, message)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	handler := m.handlers[topic]
+	m.mu.Unlock()
+	if handler == nil {
+		return nil
+	}
+	return handler(data)
+}
+
+func (m *loopbackMQ) Consume(ctx context.Context, topic string, handler mq.MessageHandler) error {
+	m.mu.Lock()
+	m.handlers[topic] = handler
+	m.mu.Unlock()
+	close(m.ready)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (m *loopbackMQ) Close() error { return nil }
+
+func TestReorgHandler_ConsumeReorgEvents(t *testing.T) {
+	store := &reorgStore{eventBlocks: []int64{100, 101, 102, 103, 104, 105}}
+	alerter := &recordingAlerter{}
+	rh := &ReorgHandler{db: store, logger: &MockLogger{}, Alerts: alert.NewNotifier(alerter)}
+
+	queue := newLoopbackMQ()
+	topic := mq.TopicConfig{}.ReorgEvents()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- rh.ConsumeReorgEvents(ctx, queue, topic, mq.RetryPolicy{MaxAttempts: 1})
+	}()
+
+	select {
+	case <-queue.ready:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the consumer to subscribe to the reorg topic")
+	}
+
+	event := &types.ReorgEvent{
+		Type:      "reorg_detected",
+		FromBlock: big.NewInt(103),
+		ToBlock:   big.NewInt(104),
+		OldHash:   "0xold",
+		NewHash:   "0xnew",
+	}
+	if err := queue.Publish(topic, event); err != nil {
+		t.Fatalf("Failed to publish reorg event: %v", err)
+	}
+
+	// Events from the fork on are deleted, including those above the reorged range
+	if len(store.eventBlocks) != 3 || store.eventBlocks[2] != 102 {
+		t.Errorf("Expected the events of blocks 100-102 to remain, got %v", store.eventBlocks)
+	}
+	if store.processedFrom == nil || store.processedFrom.Int64() != 103 {
+		t.Errorf("Expected processed events from block 103 to be deleted, got %v", store.processedFrom)
+	}
+	if store.lastProcessedBlock == nil || store.lastProcessedBlock.Int64() != 102 {
+		t.Errorf("Expected the last processed block to be 102, got %v", store.lastProcessedBlock)
+	}
+
+	if len(alerter.sent) != 1 {
+		t.Fatalf("Expected 1 alert, got %d", len(alerter.sent))
+	}
+	if sent := alerter.sent[0]; sent.Details["block"] != "103" || sent.Details["depth"] != "2" || sent.Details["chain_hash"] != "0xnew" {
+		t.Errorf("Expected an alert for the 2 block reorg at 103, got %+v", sent)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Expected the consumer to stop with context.Canceled, got %v", err)
+	}
+}
+
+func TestReorgHandler_HandleReorgEventWithoutFromBlock(t *testing.T) {
+	store := &reorgStore{eventBlocks: []int64{100}}
+	rh := &ReorgHandler{db: store, logger: &MockLogger{}}
+
+	if err := rh.HandleReorgEvent(context.Background(), &types.ReorgEvent{ToBlock: big.NewInt(100)}); err != errInvalidReorgEvent {
+		t.Errorf("Expected errInvalidReorgEvent, got %v", err)
+	}
+	if len(store.eventBlocks) != 1 {
+		t.Errorf("Expected no events to be deleted, got %v", store.eventBlocks)
+	}
+}
@@ -7,20 +7,54 @@ import (
 	"math/big"
 	"time"
 
+	"chainpulse/shared/alert"
+	"chainpulse/shared/cache"
+	"chainpulse/shared/clock"
 	"chainpulse/shared/database"
+	"chainpulse/shared/types"
 
+	"github.com/ethereum/go-ethereum"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
-
-	"gorm.io/gorm"
 )
 
 // ReorgHandler 处理区块链重组
 type ReorgHandler struct {
-	client     *EthClientWrapper // Wrapper for eth client
-	db         *database.Database
+	client     ReorgChainClient
+	db         ReorgStore
 	logger     Logger
 	depth      int
 	maxDepth   int
+
+	// Cache 用于在回滚后更新重组纪元，使重组前缓存的区块数据失效；为 nil 时不更新
+	Cache *cache.Cache
+
+	// Alerts 在确认深度处检测到重组时发送告警；为 nil 时不告警
+	Alerts *alert.Notifier
+
+	// Clock 驱动定期重组检查；为 nil 时使用系统时间
+	Clock clock.Clock
+}
+
+// deepReorgAlertKey 是深度重组告警的去重键，重组未消除前不会重复告警
+const deepReorgAlertKey = "deep-reorg"
+
+// ReorgChainClient 重组检测读取规范链所需的客户端接口
+type ReorgChainClient interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*ethtypes.Header, error)
+}
+
+// ReorgStore 重组检测与回滚所需的数据库接口
+type ReorgStore interface {
+	GetBlockHeaders(fromBlock uint64) ([]types.BlockHeader, error)
+	SaveBlockHeader(blockNumber uint64, blockHash string) error
+	PruneBlockHeaders(blockNumber uint64) error
+	DeleteBlockHeadersFromBlock(blockNumber uint64) error
+	DeleteEventsFromBlock(blockNumber *big.Int) error
+	DeleteProcessedEventsFromBlock(blockNumber *big.Int) error
+	SaveLastProcessedBlock(blockNum *big.Int) error
+	UpdateLastProcessedBlockWithHash(blockNum *big.Int, blockHash string) error
 }
 
 // EthClientWrapper 包装以太坊客户端，提供更高级的功能
@@ -39,45 +73,133 @@ func NewReorgHandler(client *ethclient.Client, db *database.Database, logger Log
 	}
 }
 
-// DetectAndHandleReorg 检测并处理重组
+// DetectAndHandleReorg 检测并处理重组。将 block_headers 中最近 maxDepth 个区块的哈希与规范链对比，
+// 找到分叉点后回滚分叉点及之后的事件，然后记录到当前区块为止的新区块哈希，并清理超出深度的记录
 func (rh *ReorgHandler) DetectAndHandleReorg(ctx context.Context, currentBlock *big.Int) error {
-	// 获取确认深度之前的区块哈希
-	safeBlock := new(big.Int).Sub(currentBlock, big.NewInt(int64(rh.depth)))
-	if safeBlock.Sign() < 0 {
-		safeBlock.SetInt64(0) // 不能低于创世块
+	current := currentBlock.Uint64()
+	windowStart := uint64(0)
+	if current > uint64(rh.maxDepth) {
+		windowStart = current - uint64(rh.maxDepth)
 	}
 
-	// 获取安全区块的哈希
-	block, err := rh.client.BlockByNumber(ctx, safeBlock)
+	headers, err := rh.db.GetBlockHeaders(windowStart)
 	if err != nil {
-		return fmt.Errorf("failed to get safe block: %v", err)
+		return fmt.Errorf("failed to get stored block headers: %v", err)
 	}
-	safeBlockHash := block.Hash().Hex()
 
-	// 从数据库获取之前记录的哈希
-	storedBlock, err := rh.db.GetLastProcessedBlockByNumber(safeBlock)
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		return fmt.Errorf("failed to get stored block hash: %v", err)
+	fork, chainHash, err := findForkPoint(ctx, rh.client, headers)
+	if err != nil {
+		return fmt.Errorf("failed to find fork point: %v", err)
+	}
+
+	// 已记录哈希的最高区块，之后的区块哈希需要补充记录
+	recordFrom := windowStart
+	if len(headers) > 0 && headers[0].BlockNumber+1 > recordFrom {
+		recordFrom = headers[0].BlockNumber + 1
 	}
 
-	// 如果哈希不匹配，说明发生了重组
-	if storedBlock != nil && storedBlock.BlockHash != "" && storedBlock.BlockHash != safeBlockHash {
-		rh.logger.Warn("Blockchain reorganization detected at block %s", safeBlock.String())
-		
-		// 回滚到重组点
-		if err := rh.rollbackToBlock(ctx, safeBlock); err != nil {
+	if fork != nil {
+		forkBlock := new(big.Int).SetUint64(fork.BlockNumber)
+		rh.logger.Warn("Blockchain reorganization detected, fork point at block %s", forkBlock.String())
+
+		// 分叉点在确认深度之内，已确认的事件被回滚
+		if fork.BlockNumber+uint64(rh.depth) <= current {
+			rh.alertDeepReorg(ctx, forkBlock, current-fork.BlockNumber+1, fork.BlockHash, chainHash)
+		}
+
+		// 回滚到分叉点
+		if err := rh.rollbackToBlock(ctx, forkBlock); err != nil {
 			return fmt.Errorf("failed to rollback: %v", err)
 		}
+		recordFrom = fork.BlockNumber
+	} else if rh.Alerts != nil {
+		// 规范链与已记录的区块一致，解除之前的重组告警
+		if err := rh.Alerts.Resolve(ctx, deepReorgAlertKey, fmt.Sprintf("Chain is consistent up to block %s", currentBlock.String())); err != nil {
+			rh.logger.Error("Failed to resolve deep reorg alert: %v", err)
+		}
+	}
+
+	// 记录新区块的哈希
+	for number := recordFrom; number <= current; number++ {
+		header, err := rh.client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return fmt.Errorf("failed to get header of block %d: %v", number, err)
+		}
+		if err := rh.db.SaveBlockHeader(number, header.Hash().Hex()); err != nil {
+			return fmt.Errorf("failed to save header of block %d: %v", number, err)
+		}
+	}
+
+	// 清理超出重组深度的区块哈希
+	if err := rh.db.PruneBlockHeaders(windowStart); err != nil {
+		return fmt.Errorf("failed to prune block headers: %v", err)
 	}
 
 	// 更新安全区块信息
-	if err := rh.db.UpdateLastProcessedBlockWithHash(safeBlock, safeBlockHash); err != nil {
+	safeBlock := new(big.Int).Sub(currentBlock, big.NewInt(int64(rh.depth)))
+	if safeBlock.Sign() < 0 {
+		safeBlock.SetInt64(0) // 不能低于创世块
+	}
+	safeHeader, err := rh.client.HeaderByNumber(ctx, safeBlock)
+	if err != nil {
+		return fmt.Errorf("failed to get safe block: %v", err)
+	}
+	if err := rh.db.UpdateLastProcessedBlockWithHash(safeBlock, safeHeader.Hash().Hex()); err != nil {
 		return fmt.Errorf("failed to update safe block: %v", err)
 	}
 
 	return nil
 }
 
+// findForkPoint 将按区块号从新到旧排列的已记录区块哈希与规范链对比，返回分叉点，即哈希不再匹配的最低区块，
+// 以及规范链在该高度的哈希；所有记录都匹配时返回 nil。区块哈希包含父区块哈希，
+// 因此某个高度匹配时更低的区块也都在规范链上，对比到第一个匹配的区块即可停止。
+// 所有记录都不匹配时，重组深度超出了记录范围，返回最旧的记录。
+func findForkPoint(ctx context.Context, client ReorgChainClient, headers []types.BlockHeader) (*types.BlockHeader, string, error) {
+	var fork *types.BlockHeader
+	var chainHash string
+	for i := range headers {
+		header := &headers[i]
+		canonical, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(header.BlockNumber))
+		if err != nil && !errors.Is(err, ethereum.NotFound) {
+			return nil, "", err
+		}
+
+		// 区块不存在时规范链变短了，同样视为不匹配
+		hash := ""
+		if err == nil {
+			hash = canonical.Hash().Hex()
+		}
+		if hash == header.BlockHash {
+			break
+		}
+		fork, chainHash = header, hash
+	}
+	return fork, chainHash, nil
+}
+
+// alertDeepReorg 发送深度重组告警。分叉点在确认深度之内，已确认的事件被回滚
+func (rh *ReorgHandler) alertDeepReorg(ctx context.Context, blockNumber *big.Int, reorgDepth uint64, storedHash, chainHash string) {
+	if rh.Alerts == nil {
+		return
+	}
+
+	err := rh.Alerts.Fire(ctx, alert.Alert{
+		Key:      deepReorgAlertKey,
+		Severity: alert.SeverityCritical,
+		Summary:  fmt.Sprintf("Reorg %d blocks deep detected, fork point at block %s", reorgDepth, blockNumber.String()),
+		Details: map[string]string{
+			"block":       blockNumber.String(),
+			"depth":       fmt.Sprint(reorgDepth),
+			"stored_hash": storedHash,
+			"chain_hash":  chainHash,
+		},
+	})
+	if err != nil {
+		rh.logger.Error("Failed to send deep reorg alert: %v", err)
+	}
+}
+
 // rollbackToBlock 回滚到指定区块
 func (rh *ReorgHandler) rollbackToBlock(ctx context.Context, blockNumber *big.Int) error {
 	rh.logger.Info("Rolling back events from block %s onwards", blockNumber.String())
@@ -91,6 +213,20 @@ func (rh *ReorgHandler) rollbackToBlock(ctx context.Context, blockNumber *big.In
 	if err := rh.db.DeleteProcessedEventsFromBlock(blockNumber); err != nil {
 		return fmt.Errorf("failed to delete processed events from block %s: %v", blockNumber.String(), err)
 	}
+
+	// 删除孤块的哈希记录，之后按规范链重新记录
+	if err := rh.db.DeleteBlockHeadersFromBlock(blockNumber.Uint64()); err != nil {
+		return fmt.Errorf("failed to delete block headers from block %s: %v", blockNumber.String(), err)
+	}
+	
+	// 更新重组纪元，之后的读取不再命中孤块的缓存
+	if rh.Cache != nil {
+		epoch, err := rh.Cache.BumpReorgEpoch(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to bump reorg epoch: %v", err)
+		}
+		rh.logger.Info("Reorg epoch bumped to %d", epoch)
+	}
 	
 	// 更新最后处理的区块
 	prevBlock := new(big.Int).Sub(blockNumber, big.NewInt(1))
@@ -104,7 +240,7 @@ func (rh *ReorgHandler) rollbackToBlock(ctx context.Context, blockNumber *big.In
 
 // CheckReorgPeriodically 定期检查重组
 func (rh *ReorgHandler) CheckReorgPeriodically(ctx context.Context, interval time.Duration) {
-	ticker := time.NewTicker(interval)
+	ticker := clock.OrReal(rh.Clock).NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -112,7 +248,7 @@ func (rh *ReorgHandler) CheckReorgPeriodically(ctx context.Context, interval tim
 		case <-ctx.Done():
 			rh.logger.Info("Reorg checker stopped")
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			// 获取当前最新区块
 			currentBlock, err := rh.client.BlockNumber(ctx)
 			if err != nil {
@@ -120,7 +256,7 @@ func (rh *ReorgHandler) CheckReorgPeriodically(ctx context.Context, interval tim
 				continue
 			}
 			
-			if err := rh.DetectAndHandleReorg(ctx, currentBlock); err != nil {
+			if err := rh.DetectAndHandleReorg(ctx, new(big.Int).SetUint64(currentBlock)); err != nil {
 				rh.logger.Error("Error during reorg detection: %v", err)
 			}
 		}
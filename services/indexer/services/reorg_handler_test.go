@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"chainpulse/shared/clock"
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeChain serves headers of a canonical chain; fork is the first height whose header
+// differs from the originally processed chain, 0 for none
+type fakeChain struct {
+	head uint64
+	fork uint64
+}
+
+func (c *fakeChain) header(number uint64, forked bool) *ethtypes.Header {
+	header := &ethtypes.Header{Number: new(big.Int).SetUint64(number)}
+	if forked {
+		header.Extra = []byte("fork")
+	}
+	return header
+}
+
+func (c *fakeChain) BlockNumber(ctx context.Context) (uint64, error) {
+	return c.head, nil
+}
+
+func (c *fakeChain) HeaderByNumber(ctx context.Context, number *big.Int) (*ethtypes.Header, error) {
+	n := number.Uint64()
+	if n > c.head {
+		return nil, ethereum.NotFound
+	}
+	return c.header(n, c.fork != 0 && n >= c.fork), nil
+}
+
+// storedHeaders returns the headers of the original chain from..to, newest first
+func storedHeaders(chain *fakeChain, from, to uint64) []types.BlockHeader {
+	var headers []types.BlockHeader
+	for n := to; n >= from; n-- {
+		headers = append(headers, types.BlockHeader{BlockNumber: n, BlockHash: chain.header(n, false).Hash().Hex()})
+	}
+	return headers
+}
+
+func TestFindForkPoint_HashMismatch(t *testing.T) {
+	chain := &fakeChain{head: 120}
+	headers := storedHeaders(chain, 100, 120)
+
+	// The chain is reorged from block 115 on
+	chain.fork = 115
+	fork, chainHash, err := findForkPoint(context.Background(), chain, headers)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fork == nil {
+		t.Fatal("Expected the hash mismatch to be detected")
+	}
+	if fork.BlockNumber != 115 {
+		t.Errorf("Expected fork point 115, got %d", fork.BlockNumber)
+	}
+	if expected := chain.header(115, true).Hash().Hex(); chainHash != expected {
+		t.Errorf("Expected canonical hash %s, got %s", expected, chainHash)
+	}
+	if fork.BlockHash == chainHash {
+		t.Error("Expected the stored hash to differ from the canonical hash")
+	}
+}
+
+func TestFindForkPoint_Consistent(t *testing.T) {
+	chain := &fakeChain{head: 130}
+	headers := storedHeaders(chain, 100, 120)
+
+	fork, _, err := findForkPoint(context.Background(), chain, headers)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fork != nil {
+		t.Errorf("Expected no fork point, got %d", fork.BlockNumber)
+	}
+}
+
+func TestFindForkPoint_ShorterChainAndDeepReorg(t *testing.T) {
+	chain := &fakeChain{head: 120}
+	headers := storedHeaders(chain, 100, 120)
+
+	// Blocks that no longer exist are part of the reorg
+	chain.head = 117
+	chain.fork = 118
+	fork, chainHash, err := findForkPoint(context.Background(), chain, headers)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fork == nil || fork.BlockNumber != 118 || chainHash != "" {
+		t.Errorf("Expected fork point 118 without a canonical hash, got %v %q", fork, chainHash)
+	}
+
+	// A reorg deeper than the stored headers reports the oldest one
+	chain.head = 120
+	chain.fork = 90
+	fork, _, _ = findForkPoint(context.Background(), chain, headers)
+	if fork == nil || fork.BlockNumber != 100 {
+		t.Errorf("Expected the oldest stored header 100, got %v", fork)
+	}
+}
+
+// checkedChain reports every chain head lookup of a reorg check on checks
+type checkedChain struct {
+	fakeChain
+	checks chan struct{}
+}
+
+func (c *checkedChain) BlockNumber(ctx context.Context) (uint64, error) {
+	c.checks <- struct{}{}
+	return c.head, nil
+}
+
+func TestReorgHandler_CheckReorgPeriodicallyOnInterval(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	chain := &checkedChain{fakeChain: fakeChain{head: 10}, checks: make(chan struct{}, 10)}
+	rh := &ReorgHandler{client: chain, db: &reorgStore{}, logger: &MockLogger{}, maxDepth: 5, Clock: fake}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		rh.CheckReorgPeriodically(ctx, 30*time.Second)
+		close(done)
+	}()
+	for fake.Tickers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	fake.Advance(29 * time.Second)
+	select {
+	case <-chain.checks:
+		t.Fatal("Expected no reorg check before the interval passed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	for i := 1; i <= 2; i++ {
+		fake.Advance(time.Second)
+		select {
+		case <-chain.checks:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected reorg check %d once the interval passed", i)
+		}
+		fake.Advance(29 * time.Second)
+	}
+
+	cancel()
+	<-done
+	if tickers := fake.Tickers(); tickers != 0 {
+		t.Errorf("Expected the ticker to be stopped, got %d running", tickers)
+	}
+}
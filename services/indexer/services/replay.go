@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"chainpulse/shared/types"
+)
+
+// SyncDecodedFieldsTransform is the name of the built-in replay transform that re-derives
+// the from, to, value and token ID columns from the decoded event parameters
+const SyncDecodedFieldsTransform = "sync_decoded_fields"
+
+// RegisterReplayTransform makes transform available to replays under name,
+// replacing any transform previously registered under that name
+func (s *IndexerService) RegisterReplayTransform(name string, transform types.EventTransform) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.replayTransforms == nil {
+		s.replayTransforms = make(map[string]types.EventTransform)
+	}
+	s.replayTransforms[name] = transform
+}
+
+// ReplayEvents re-processes the stored events of a contract within a block range through
+// the named transform and saves the corrected events, without fetching anything from the node
+func (s *IndexerService) ReplayEvents(ctx context.Context, contract string, fromBlock, toBlock *big.Int, transformName string) (*types.ReplayResult, error) {
+	s.mu.Lock()
+	transform, exists := s.replayTransforms[transformName]
+	s.mu.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", types.ErrUnknownReplayTransform, transformName)
+	}
+
+	s.Logger.Info("Replaying events of contract %s from block %s to %s with %s", contract, fromBlock.String(), toBlock.String(), transformName)
+
+	result, err := s.Database.ReplayEvents(ctx, contract, fromBlock, toBlock, transform)
+	if err != nil {
+		s.Logger.Error("Replay of contract %s failed: %v", contract, err)
+		return result, err
+	}
+
+	s.Logger.Info("Replayed %d events of contract %s, updated %d", result.Scanned, contract, result.Updated)
+	return result, nil
+}
+
+// syncDecodedFields copies the decoded transfer parameters in Data into the event columns
+func syncDecodedFields(event *types.IndexedEvent) (bool, error) {
+	changed := false
+	copyField := func(field *string, keys ...string) {
+		for _, key := range keys {
+			value, ok := event.Data[key].(string)
+			if !ok {
+				continue
+			}
+			if *field != value {
+				*field = value
+				changed = true
+			}
+			return
+		}
+	}
+
+	copyField(&event.From, "from")
+	copyField(&event.To, "to")
+	copyField(&event.Value, "value")
+	copyField(&event.TokenID, "tokenId", "tokenID", "id")
+
+	return changed, nil
+}
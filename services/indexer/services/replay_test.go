@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"chainpulse/shared/types"
+)
+
+func TestSyncDecodedFields(t *testing.T) {
+	event := &types.IndexedEvent{
+		From:  "0xstale",
+		Value: "1",
+		Data: map[string]interface{}{
+			"from":    "0x0000000000000000000000000000000000000001",
+			"to":      "0x0000000000000000000000000000000000000002",
+			"tokenId": "42",
+			"value":   "1",
+		},
+	}
+
+	changed, err := syncDecodedFields(event)
+	if err != nil {
+		t.Fatalf("Failed to transform event: %v", err)
+	}
+	if !changed {
+		t.Error("Expected event to change")
+	}
+	if event.From != "0x0000000000000000000000000000000000000001" || event.To != "0x0000000000000000000000000000000000000002" {
+		t.Errorf("Expected decoded from/to, got %s/%s", event.From, event.To)
+	}
+	if event.TokenID != "42" || event.Value != "1" {
+		t.Errorf("Expected token 42 value 1, got token %s value %s", event.TokenID, event.Value)
+	}
+
+	changed, _ = syncDecodedFields(event)
+	if changed {
+		t.Error("Expected no change on a second pass")
+	}
+}
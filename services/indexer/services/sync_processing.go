@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"chainpulse/shared/mq"
+)
+
+// deadLetterRetryDelay is the wait before retrying an event whose dead-lettering failed
+const deadLetterRetryDelay = time.Second
+
+// processInOrder processes event before the next subscribed event is read. A failing
+// event is retried per SyncRetry and then dead-lettered, blocking the subscription until
+// it succeeds or is dead-lettered. It returns early only when ctx is cancelled.
+func (s *IndexerService) processInOrder(ctx context.Context, event interface{}, process func() error) {
+	atomic.AddInt64(&s.inFlight, 1)
+	defer atomic.AddInt64(&s.inFlight, -1)
+
+	message, err := mq.Encode(mq.JSONCodec{}, event)
+	if err != nil {
+		s.Logger.Error("Failed to encode event for dead-lettering: %v", err)
+	}
+
+	handler := mq.RetryHandler(ctx, s.deadLetters(), s.SyncEventsTopic, s.SyncRetry, func([]byte) error {
+		return process()
+	})
+	for {
+		err := handler(message)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		s.Logger.Error("Failed to dead-letter event, retrying: %v", err)
+
+		select {
+		case <-time.After(deadLetterRetryDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deadLetters returns the queue events are dead-lettered to, logging them when
+// DeadLetters is not set
+func (s *IndexerService) deadLetters() mq.MessageQueue {
+	if s.DeadLetters != nil {
+		return s.DeadLetters
+	}
+	return loggedDeadLetters{logger: s.Logger}
+}
+
+// loggedDeadLetters logs the messages published to it instead of queueing them
+type loggedDeadLetters struct {
+	logger Logger
+}
+
+func (l loggedDeadLetters) Publish(topic string, message interface{}) error {
+	return l.PublishContext(context.Background(), topic, message)
+}
+
+func (l loggedDeadLetters) PublishContext(ctx context.Context, topic string, message interface{}) error {
+	if deadLetter, ok := message.(mq.DeadLetterMessage); ok {
+		_, event, _ := mq.DecodeEnvelope(deadLetter.Payload)
+		l.logger.Error("Giving up on event after %d attempts: %s: %s", deadLetter.Attempts, deadLetter.Error, event)
+		return nil
+	}
+	l.logger.Error("Giving up on event: %v", message)
+	return nil
+}
+
+func (l loggedDeadLetters) Consume(ctx context.Context, topic string, handler mq.MessageHandler) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (l loggedDeadLetters) Close() error { return nil }
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"chainpulse/services/blockchain/services"
+	"chainpulse/shared/database"
+	"chainpulse/shared/mq"
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// deadLetterQueue records the messages published to it
+type deadLetterQueue struct {
+	mu        sync.Mutex
+	topics    []string
+	published []mq.DeadLetterMessage
+}
+
+func (q *deadLetterQueue) Publish(topic string, message interface{}) error {
+	return q.PublishContext(context.Background(), topic, message)
+}
+
+func (q *deadLetterQueue) PublishContext(ctx context.Context, topic string, message interface{}) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.topics = append(q.topics, topic)
+	q.published = append(q.published, message.(mq.DeadLetterMessage))
+	return nil
+}
+
+func (q *deadLetterQueue) Consume(ctx context.Context, topic string, handler mq.MessageHandler) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (q *deadLetterQueue) Close() error { return nil }
+
+// newSyncIndexer returns an indexer in sync processing mode whose events fail the given
+// number of attempts per transaction hash and are then dropped by the transformer. It
+// records every processing attempt.
+func newSyncIndexer(failures map[common.Hash]int, maxAttempts int) (*IndexerService, *[]common.Hash) {
+	var attempts []common.Hash
+	var mu sync.Mutex
+	s := &IndexerService{
+		Blockchain:     &blockchain.EventProcessor{},
+		Logger:         &MockLogger{},
+		SyncProcessing: true,
+		SyncRetry:      mq.RetryPolicy{MaxAttempts: maxAttempts, BaseDelay: time.Millisecond},
+		Transformers: []types.EventTransformer{types.EventTransformerFunc(func(ctx context.Context, event *types.IndexedEvent) (*types.IndexedEvent, bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			hash := common.HexToHash(event.TxHash)
+			attempts = append(attempts, hash)
+			if failures[hash] > 0 {
+				failures[hash]--
+				return nil, false, errors.New("price feed unavailable")
+			}
+			return event, false, nil
+		})},
+	}
+	return s, &attempts
+}
+
+// runSyncHandler feeds events to the NFT handler and waits until it has read them all
+func runSyncHandler(t *testing.T, s *IndexerService, events ...*types.NFTTransferEvent) {
+	eventChan := make(chan *types.NFTTransferEvent)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.handleNFTEvents(ctx, eventChan, nil)
+		close(done)
+	}()
+
+	for _, event := range events {
+		select {
+		case eventChan <- event:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the handler to read the next event")
+		}
+	}
+	close(eventChan)
+	<-done
+	cancel()
+}
+
+func TestIndexerService_SyncProcessingRetriesInOrder(t *testing.T) {
+	first, second := common.HexToHash("0x1"), common.HexToHash("0x2")
+	s, attempts := newSyncIndexer(map[common.Hash]int{first: 2}, 3)
+	s.DeadLetters = &deadLetterQueue{}
+
+	runSyncHandler(t, s,
+		&types.NFTTransferEvent{BlockNumber: big.NewInt(100), TxHash: first, TokenID: big.NewInt(1)},
+		&types.NFTTransferEvent{BlockNumber: big.NewInt(100), TxHash: second, TokenID: big.NewInt(2)},
+	)
+
+	// The failing event is retried until it succeeds before the next one is processed
+	expected := []common.Hash{first, first, first, second}
+	if len(*attempts) != len(expected) {
+		t.Fatalf("Expected attempts %v, got %v", expected, *attempts)
+	}
+	for i, hash := range expected {
+		if (*attempts)[i] != hash {
+			t.Errorf("Expected attempt %d to process %s, got %s", i+1, hash.Hex(), (*attempts)[i].Hex())
+		}
+	}
+	if published := s.DeadLetters.(*deadLetterQueue).published; len(published) != 0 {
+		t.Errorf("Expected no dead-lettered events, got %d", len(published))
+	}
+}
+
+func TestIndexerService_SyncProcessingDeadLetters(t *testing.T) {
+	first, second := common.HexToHash("0x1"), common.HexToHash("0x2")
+	s, attempts := newSyncIndexer(map[common.Hash]int{first: 10}, 2)
+	deadLetters := &deadLetterQueue{}
+	s.DeadLetters = deadLetters
+	s.SyncEventsTopic = mq.TopicConfig{}.RawEvents()
+
+	runSyncHandler(t, s,
+		&types.NFTTransferEvent{BlockNumber: big.NewInt(100), TxHash: first, TokenID: big.NewInt(1)},
+		&types.NFTTransferEvent{BlockNumber: big.NewInt(100), TxHash: second, TokenID: big.NewInt(2)},
+	)
+
+	// The event is dead-lettered after its attempts, then the next one is processed
+	expected := []common.Hash{first, first, second}
+	if len(*attempts) != len(expected) || (*attempts)[1] != first || (*attempts)[2] != second {
+		t.Fatalf("Expected attempts %v, got %v", expected, *attempts)
+	}
+
+	if len(deadLetters.published) != 1 {
+		t.Fatalf("Expected 1 dead-lettered event, got %d", len(deadLetters.published))
+	}
+	if topic := deadLetters.topics[0]; topic != mq.DeadLetterTopic(s.SyncEventsTopic) {
+		t.Errorf("Expected the event on %s, got %s", mq.DeadLetterTopic(s.SyncEventsTopic), topic)
+	}
+	deadLetter := deadLetters.published[0]
+	if deadLetter.Attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", deadLetter.Attempts)
+	}
+	var event types.NFTTransferEvent
+	if err := mq.Decode(deadLetter.Payload, &event); err != nil {
+		t.Fatalf("Failed to decode dead-lettered event: %v", err)
+	}
+	if event.TxHash != first {
+		t.Errorf("Expected the dead-lettered event %s, got %s", first.Hex(), event.TxHash.Hex())
+	}
+}
+
+func TestIndexerService_SyncProcessingDeadLettersUnstoredEvents(t *testing.T) {
+	// The database is unreachable, so every insert fails
+	gormDB, err := gorm.Open(postgres.Open("host=127.0.0.1 port=1 user=chainpulse dbname=chainpulse sslmode=disable connect_timeout=1"), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db := &database.Database{DB: gormDB}
+	batchProcessor := database.NewBatchProcessor(db, 10, time.Hour, nil)
+	defer batchProcessor.Close()
+
+	first := common.HexToHash("0x1")
+	s, _ := newSyncIndexer(nil, 2)
+	s.Transformers = nil
+	s.BatchProcessor = batchProcessor
+	s.Idempotency = NewIdempotencyService(nil, db, time.Hour)
+	deadLetters := &deadLetterQueue{}
+	s.DeadLetters = deadLetters
+
+	runSyncHandler(t, s, &types.NFTTransferEvent{BlockNumber: big.NewInt(100), TxHash: first, TokenID: big.NewInt(1)})
+
+	// An event that cannot be stored is retried and dead-lettered rather than buffered
+	if len(deadLetters.published) != 1 {
+		t.Fatalf("Expected the unstored event to be dead-lettered, got %d dead letters", len(deadLetters.published))
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"chainpulse/shared/metrics"
+	"chainpulse/shared/types"
+)
+
+// Names of the built-in event transformers, as listed in the transformer configuration
+const (
+	AddressLabelsTransformer = "address_labels"
+	SpamFilterTransformer    = "spam_filter"
+)
+
+// transformEvent runs an event through the transformers in order before it is stored.
+// It returns false when a transformer drops the event.
+func (s *IndexerService) transformEvent(ctx context.Context, event *types.IndexedEvent) (*types.IndexedEvent, bool, error) {
+	for i, transformer := range s.Transformers {
+		transformed, keep, err := transformer.Transform(ctx, event)
+		if err != nil {
+			return nil, false, fmt.Errorf("event transformer %d failed: %v", i+1, err)
+		}
+		if !keep {
+			return nil, false, nil
+		}
+		if transformed != nil {
+			event = transformed
+		}
+	}
+	return event, true, nil
+}
+
+// ParseEventTransformers returns the transformers named in spec, a comma-separated list
+// applied in the order given, looked up in available
+func ParseEventTransformers(spec string, available map[string]types.EventTransformer) ([]types.EventTransformer, error) {
+	var transformers []types.EventTransformer
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		transformer, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown event transformer: %s", name)
+		}
+		transformers = append(transformers, transformer)
+	}
+	return transformers, nil
+}
+
+// ParseAddressLabels parses address labels given as comma-separated "address=label" pairs
+func ParseAddressLabels(spec string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		address, label, found := strings.Cut(pair, "=")
+		if !found || address == "" || label == "" {
+			return nil, fmt.Errorf("invalid address label %q: expected address=label", pair)
+		}
+		labels[strings.ToLower(strings.TrimSpace(address))] = strings.TrimSpace(label)
+	}
+	return labels, nil
+}
+
+// LabelAddresses returns a transformer adding the labels of known from and to addresses
+// to the event data as "fromLabel" and "toLabel"
+func LabelAddresses(labels map[string]string) types.EventTransformer {
+	return types.EventTransformerFunc(func(ctx context.Context, event *types.IndexedEvent) (*types.IndexedEvent, bool, error) {
+		fromLabel, hasFrom := labels[strings.ToLower(event.From)]
+		toLabel, hasTo := labels[strings.ToLower(event.To)]
+		if !hasFrom && !hasTo {
+			return event, true, nil
+		}
+
+		// Label a copy, the event data may be shared with the caller
+		labeled := *event
+		labeled.Data = make(map[string]interface{}, len(event.Data)+2)
+		for key, value := range event.Data {
+			labeled.Data[key] = value
+		}
+		if hasFrom {
+			labeled.Data["fromLabel"] = fromLabel
+		}
+		if hasTo {
+			labeled.Data["toLabel"] = toLabel
+		}
+		return &labeled, true, nil
+	})
+}
+
+// Reasons spam events are dropped for, as recorded in the dropped spam metric
+const (
+	SpamReasonContract  = "contract"
+	SpamReasonZeroValue = "zero_value"
+)
+
+// SpamFilterConfig selects the events the spam filter drops
+type SpamFilterConfig struct {
+	// Contracts are known spam contracts, all of whose events are dropped
+	Contracts []string
+	// ZeroValue drops transfers of value zero, of ZeroValueContracts or of every contract
+	// when none are listed. Transfers without a value, such as NFT transfers, are kept.
+	ZeroValue          bool
+	ZeroValueContracts []string
+}
+
+// contractSet returns the non-empty contracts lowercased, for lookups by address
+func contractSet(contracts []string) map[string]bool {
+	set := make(map[string]bool, len(contracts))
+	for _, contract := range contracts {
+		if contract = strings.TrimSpace(contract); contract != "" {
+			set[strings.ToLower(contract)] = true
+		}
+	}
+	return set
+}
+
+// FilterSpam returns a transformer dropping the spam events selected by config and
+// counting them by reason in m, unless m is nil
+func FilterSpam(config SpamFilterConfig, m *metrics.Metrics) types.EventTransformer {
+	spam := contractSet(config.Contracts)
+	zeroValueContracts := contractSet(config.ZeroValueContracts)
+	return types.EventTransformerFunc(func(ctx context.Context, event *types.IndexedEvent) (*types.IndexedEvent, bool, error) {
+		contract := strings.ToLower(event.Contract)
+		reason := ""
+		if spam[contract] {
+			reason = SpamReasonContract
+		} else if config.ZeroValue && (len(zeroValueContracts) == 0 || zeroValueContracts[contract]) && isZeroValue(event.Value) {
+			reason = SpamReasonZeroValue
+		}
+		if reason == "" {
+			return event, true, nil
+		}
+
+		if m != nil {
+			m.IncrementSpamDropped(reason)
+		}
+		return event, false, nil
+	})
+}
+
+// isZeroValue reports whether value is a decimal value of zero, an empty value is not
+func isZeroValue(value string) bool {
+	amount, ok := new(big.Int).SetString(value, 10)
+	return ok && amount.Sign() == 0
+}
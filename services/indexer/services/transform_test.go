@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"chainpulse/shared/metrics"
+	"chainpulse/shared/types"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const (
+	exchangeAddress = "0x28C6c06298d514Db089934071355E5743bf21d60"
+	spamContract    = "0x00000000000000000000000000000000000000aa"
+)
+
+func newTransformingIndexer(t *testing.T, spec string) *IndexerService {
+	labels, err := ParseAddressLabels(exchangeAddress + "=Binance 14")
+	if err != nil {
+		t.Fatalf("Failed to parse address labels: %v", err)
+	}
+	transformers, err := ParseEventTransformers(spec, map[string]types.EventTransformer{
+		AddressLabelsTransformer: LabelAddresses(labels),
+		SpamFilterTransformer:    FilterSpam(SpamFilterConfig{Contracts: []string{spamContract}}, nil),
+	})
+	if err != nil {
+		t.Fatalf("Failed to parse event transformers: %v", err)
+	}
+	return &IndexerService{Logger: &MockLogger{}, Transformers: transformers}
+}
+
+func TestIndexerService_TransformEvent(t *testing.T) {
+	s := newTransformingIndexer(t, "spam_filter, address_labels")
+
+	data := map[string]interface{}{"value": "1"}
+	event := &types.IndexedEvent{Contract: "0xbc4ca0eda7647a8ab7c2061c2e118a18a936f13d", From: "0x28c6c06298d514db089934071355e5743bf21d60", To: "0x1", Data: data}
+	transformed, keep, err := s.transformEvent(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !keep {
+		t.Fatal("Expected the event to be kept")
+	}
+	if transformed.Data["fromLabel"] != "Binance 14" || transformed.Data["value"] != "1" {
+		t.Errorf("Expected the sender label alongside the decoded data, got %v", transformed.Data)
+	}
+	if _, ok := transformed.Data["toLabel"]; ok {
+		t.Error("Expected no label for an unknown recipient")
+	}
+	if _, ok := data["fromLabel"]; ok {
+		t.Error("Expected the original event data to be left unchanged")
+	}
+
+	// Events of spam contracts are dropped, whatever their labels
+	spam := &types.IndexedEvent{Contract: "0x00000000000000000000000000000000000000AA", From: exchangeAddress}
+	if _, keep, err := s.transformEvent(context.Background(), spam); err != nil || keep {
+		t.Errorf("Expected the spam event to be dropped, got keep=%v err=%v", keep, err)
+	}
+}
+
+func TestIndexerService_TransformEventOrder(t *testing.T) {
+	var calls []string
+	record := func(name string, keep bool) types.EventTransformer {
+		return types.EventTransformerFunc(func(ctx context.Context, event *types.IndexedEvent) (*types.IndexedEvent, bool, error) {
+			calls = append(calls, name)
+			return event, keep, nil
+		})
+	}
+	s := &IndexerService{Transformers: []types.EventTransformer{record("first", true), record("drop", false), record("last", true)}}
+
+	if _, keep, _ := s.transformEvent(context.Background(), &types.IndexedEvent{}); keep {
+		t.Error("Expected the event to be dropped")
+	}
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "drop" {
+		t.Errorf("Expected the transformers to run in order up to the drop, got %v", calls)
+	}
+
+	s.Transformers = []types.EventTransformer{types.EventTransformerFunc(func(ctx context.Context, event *types.IndexedEvent) (*types.IndexedEvent, bool, error) {
+		return nil, false, errors.New("price feed unavailable")
+	})}
+	if _, _, err := s.transformEvent(context.Background(), &types.IndexedEvent{}); err == nil {
+		t.Error("Expected a transformer error to be returned")
+	}
+}
+
+func TestFilterSpam_ZeroValueTransfers(t *testing.T) {
+	dropped := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "spam_events_dropped_total"}, []string{"reason"})
+	m := &metrics.Metrics{SpamEventsDroppedTotal: dropped}
+	airdropToken := "0x00000000000000000000000000000000000000bb"
+	s := &IndexerService{Transformers: []types.EventTransformer{FilterSpam(SpamFilterConfig{
+		Contracts:          []string{spamContract},
+		ZeroValue:          true,
+		ZeroValueContracts: []string{airdropToken},
+	}, m)}}
+
+	events := []*types.IndexedEvent{
+		{TxHash: "0x1", Contract: "0x00000000000000000000000000000000000000BB", Value: "0"},
+		{TxHash: "0x2", Contract: airdropToken, Value: "1000"},
+		{TxHash: "0x3", Contract: airdropToken, TokenID: "7"},
+		{TxHash: "0x4", Contract: spamContract, Value: "1000"},
+		// Zero-value transfers of contracts the filter is not configured for are kept
+		{TxHash: "0x5", Contract: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", Value: "0"},
+	}
+	var stored []string
+	for _, event := range events {
+		transformed, keep, err := s.transformEvent(context.Background(), event)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if keep {
+			stored = append(stored, transformed.TxHash)
+		}
+	}
+	if len(stored) != 3 || stored[0] != "0x2" || stored[1] != "0x3" || stored[2] != "0x5" {
+		t.Errorf("Expected 0x2, 0x3 and 0x5 to be stored, got %v", stored)
+	}
+	if count := testutil.ToFloat64(dropped.WithLabelValues(SpamReasonZeroValue)); count != 1 {
+		t.Errorf("Expected 1 zero-value drop, got %v", count)
+	}
+	if count := testutil.ToFloat64(dropped.WithLabelValues(SpamReasonContract)); count != 1 {
+		t.Errorf("Expected 1 spam contract drop, got %v", count)
+	}
+
+	// Without contracts listed, zero-value transfers of every contract are dropped
+	s.Transformers = []types.EventTransformer{FilterSpam(SpamFilterConfig{ZeroValue: true}, nil)}
+	if _, keep, _ := s.transformEvent(context.Background(), events[4]); keep {
+		t.Error("Expected the zero-value transfer to be dropped")
+	}
+
+	// The filter is off unless enabled
+	s.Transformers = []types.EventTransformer{FilterSpam(SpamFilterConfig{}, nil)}
+	if _, keep, _ := s.transformEvent(context.Background(), events[0]); !keep {
+		t.Error("Expected zero-value transfers to be kept without the zero-value filter")
+	}
+}
+
+func TestParseEventTransformers(t *testing.T) {
+	available := map[string]types.EventTransformer{SpamFilterTransformer: FilterSpam(SpamFilterConfig{}, nil)}
+	if transformers, err := ParseEventTransformers("", available); err != nil || len(transformers) != 0 {
+		t.Errorf("Expected no transformers, got %d and %v", len(transformers), err)
+	}
+	if _, err := ParseEventTransformers("spam_filter,price_lookup", available); err == nil {
+		t.Error("Expected an error for an unknown transformer")
+	}
+	if _, err := ParseAddressLabels("0xabc"); err == nil {
+		t.Error("Expected an error for an address without a label")
+	}
+}
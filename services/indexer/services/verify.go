@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// DefaultVerifyChunkSize is the number of blocks VerifyEvents compares per node query
+const DefaultVerifyChunkSize = 1000
+
+// VerifyEvents compares the stored Transfer events of a contract within a block range with
+// the logs the node reports, chunk by chunk, and reports the events missing from the
+// database and the stored events the node does not know. With repair, the missing events
+// are parsed from the node logs and stored.
+func (s *IndexerService) VerifyEvents(ctx context.Context, contract string, fromBlock, toBlock *big.Int, repair bool) (*types.ConsistencyReport, error) {
+	contractAddr := common.HexToAddress(contract)
+	report := &types.ConsistencyReport{
+		Contract:  contractAddr.Hex(),
+		FromBlock: fromBlock.Uint64(),
+		ToBlock:   toBlock.Uint64(),
+		Missing:   []types.EventRef{},
+		Extra:     []types.EventRef{},
+	}
+
+	chunkSize := s.VerifyChunkSize
+	if chunkSize == 0 {
+		chunkSize = DefaultVerifyChunkSize
+	}
+
+	s.Logger.Info("Verifying events of contract %s from block %s to %s", report.Contract, fromBlock.String(), toBlock.String())
+
+	for start := report.FromBlock; start <= report.ToBlock; start += chunkSize {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		end := start + chunkSize - 1
+		if end > report.ToBlock || end < start {
+			end = report.ToBlock
+		}
+		chunkFrom, chunkTo := new(big.Int).SetUint64(start), new(big.Int).SetUint64(end)
+
+		logs, err := s.Blockchain.FilterTransferLogs(ctx, contractAddr, chunkFrom, chunkTo)
+		if err != nil {
+			return report, fmt.Errorf("failed to fetch logs of blocks %d to %d: %v", start, end, err)
+		}
+		stored, err := s.Database.GetEventsByBlockRange(chunkFrom, chunkTo)
+		if err != nil {
+			return report, fmt.Errorf("failed to get stored events of blocks %d to %d: %v", start, end, err)
+		}
+
+		missing, extra, storedCount := diffEvents(logs, stored, report.Contract)
+		report.StoredEvents += storedCount
+		for _, vLog := range logs {
+			if !vLog.Removed {
+				report.NodeEvents++
+			}
+		}
+		for _, event := range extra {
+			report.Extra = append(report.Extra, eventRef(event))
+		}
+
+		for _, vLog := range missing {
+			report.Missing = append(report.Missing, types.EventRef{
+				BlockNumber: vLog.BlockNumber,
+				TxHash:      vLog.TxHash.Hex(),
+				LogIndex:    vLog.Index,
+			})
+			if repair {
+				if err := s.repairEvent(ctx, vLog); err != nil {
+					s.Logger.Error("Failed to re-index event %s:%d: %v", vLog.TxHash.Hex(), vLog.Index, err)
+					continue
+				}
+				report.Repaired++
+			}
+		}
+
+		if end == report.ToBlock {
+			break
+		}
+	}
+
+	s.Logger.Info("Verified events of contract %s: %d missing, %d extra, %d repaired", report.Contract, len(report.Missing), len(report.Extra), report.Repaired)
+	return report, nil
+}
+
+// repairEvent stores the event of a log missing from the database. The event is marked as
+// processed afterwards; it may already be, as the stored row was lost after indexing.
+func (s *IndexerService) repairEvent(ctx context.Context, vLog ethtypes.Log) error {
+	event, err := s.Blockchain.ConvertTransferLog(vLog)
+	if err != nil {
+		return err
+	}
+	if err := s.Database.SaveEvent(event); err != nil {
+		return err
+	}
+
+	if s.Idempotency != nil {
+		if err := s.Idempotency.MarkProcessed(ctx, s.Idempotency.EventKey(event)); err != nil {
+			s.Logger.Warn("Failed to mark re-indexed event as processed: %v", err)
+		}
+	}
+	return nil
+}
+
+// diffEvents matches node logs and stored events by transaction hash and log index. It
+// returns the logs without a stored event, the stored Transfer events of contract without a
+// log and how many stored events were compared. Logs removed by a reorg are ignored.
+func diffEvents(logs []ethtypes.Log, stored []types.IndexedEvent, contract string) ([]ethtypes.Log, []types.IndexedEvent, int) {
+	storedByKey := make(map[string]types.IndexedEvent)
+	for _, event := range stored {
+		if !strings.EqualFold(event.Contract, contract) || !isTransferEvent(event) {
+			continue
+		}
+		storedByKey[eventPositionKey(event.TxHash, event.LogIndex)] = event
+	}
+	storedCount := len(storedByKey)
+
+	var missing []ethtypes.Log
+	for _, vLog := range logs {
+		if vLog.Removed {
+			continue
+		}
+		key := eventPositionKey(vLog.TxHash.Hex(), vLog.Index)
+		if _, ok := storedByKey[key]; ok {
+			delete(storedByKey, key)
+			continue
+		}
+		missing = append(missing, vLog)
+	}
+
+	var extra []types.IndexedEvent
+	for _, event := range stored {
+		if _, ok := storedByKey[eventPositionKey(event.TxHash, event.LogIndex)]; ok && strings.EqualFold(event.Contract, contract) {
+			extra = append(extra, event)
+		}
+	}
+	return missing, extra, storedCount
+}
+
+// isTransferEvent reports whether event was indexed from a Transfer log, the only logs
+// the node is asked for
+func isTransferEvent(event types.IndexedEvent) bool {
+	switch event.EventName {
+	case "NFTTransfer", "TokenTransfer", "Transfer":
+		return true
+	}
+	return false
+}
+
+func eventPositionKey(txHash string, logIndex uint) string {
+	return fmt.Sprintf("%s:%d", strings.ToLower(txHash), logIndex)
+}
+
+func eventRef(event types.IndexedEvent) types.EventRef {
+	ref := types.EventRef{TxHash: event.TxHash, LogIndex: event.LogIndex}
+	if event.BlockNumber != nil {
+		ref.BlockNumber = event.BlockNumber.Uint64()
+	}
+	return ref
+}
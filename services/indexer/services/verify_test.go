@@ -0,0 +1,62 @@
+package service
+
+import (
+	"math/big"
+	"testing"
+
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestDiffEvents_DeletedEventReportedMissing(t *testing.T) {
+	contract := "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D"
+	txA := common.HexToHash("0xaaaa")
+	txB := common.HexToHash("0xbbbb")
+
+	logs := []ethtypes.Log{
+		{BlockNumber: 100, TxHash: txA, Index: 0},
+		{BlockNumber: 100, TxHash: txA, Index: 1},
+		{BlockNumber: 101, TxHash: txB, Index: 0},
+		{BlockNumber: 101, TxHash: txB, Index: 5, Removed: true},
+	}
+	stored := []types.IndexedEvent{
+		{BlockNumber: big.NewInt(100), TxHash: txA.Hex(), LogIndex: 0, EventName: "TokenTransfer", Contract: contract},
+		// The event at txA:1 was deleted from the database
+		{BlockNumber: big.NewInt(101), TxHash: txB.Hex(), LogIndex: 0, EventName: "NFTTransfer", Contract: contract},
+		// Not on the node any more
+		{BlockNumber: big.NewInt(101), TxHash: txB.Hex(), LogIndex: 7, EventName: "TokenTransfer", Contract: contract},
+		// Events of other contracts and other event types are not compared
+		{BlockNumber: big.NewInt(101), TxHash: txB.Hex(), LogIndex: 8, EventName: "TokenTransfer", Contract: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
+		{BlockNumber: big.NewInt(101), TxHash: txB.Hex(), LogIndex: 9, EventName: "Approval", Contract: contract},
+	}
+
+	missing, extra, storedCount := diffEvents(logs, stored, contract)
+
+	if len(missing) != 1 || missing[0].TxHash != txA || missing[0].Index != 1 {
+		t.Errorf("Expected the deleted event %s:1 to be missing, got %+v", txA.Hex(), missing)
+	}
+	if len(extra) != 1 || extra[0].LogIndex != 7 {
+		t.Errorf("Expected the event at log index 7 to be extra, got %+v", extra)
+	}
+	if storedCount != 3 {
+		t.Errorf("Expected 3 stored events to be compared, got %d", storedCount)
+	}
+}
+
+func TestDiffEvents_Consistent(t *testing.T) {
+	contract := "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D"
+	tx := common.HexToHash("0xcccc")
+
+	logs := []ethtypes.Log{{BlockNumber: 5, TxHash: tx, Index: 2}}
+	// Stored hashes may differ in case from the node's
+	stored := []types.IndexedEvent{
+		{BlockNumber: big.NewInt(5), TxHash: "0x000000000000000000000000000000000000000000000000000000000000CCCC", LogIndex: 2, EventName: "TokenTransfer", Contract: "0xbc4ca0eda7647a8ab7c2061c2e118a18a936f13d"},
+	}
+
+	missing, extra, _ := diffEvents(logs, stored, contract)
+	if len(missing) != 0 || len(extra) != 0 {
+		t.Errorf("Expected no discrepancies, got %d missing and %d extra", len(missing), len(extra))
+	}
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrWatchlistUnavailable is returned when contracts are paused or resumed without a
+// ContractStatusStore
+var ErrWatchlistUnavailable = errors.New("contract watchlist is not configured")
+
+// ContractStatusStore stores which registered contracts are paused
+type ContractStatusStore interface {
+	GetContracts() ([]types.Contract, error)
+	// SetContractActive pauses or resumes a contract and reports whether it is registered
+	SetContractActive(address string, active bool) (bool, error)
+}
+
+// PauseContract stops indexing a contract, keeping the events already stored. Its
+// subscriptions are restarted without it and backfills skip it until it is resumed.
+// It reports whether the contract is registered.
+func (s *IndexerService) PauseContract(ctx context.Context, address string) (bool, error) {
+	return s.setContractActive(address, false)
+}
+
+// ResumeContract resumes indexing a paused contract, restarting the subscriptions with it.
+// Events emitted while it was paused are not indexed until they are backfilled. It
+// reports whether the contract is registered.
+func (s *IndexerService) ResumeContract(ctx context.Context, address string) (bool, error) {
+	return s.setContractActive(address, true)
+}
+
+func (s *IndexerService) setContractActive(address string, active bool) (bool, error) {
+	if s.Contracts == nil {
+		return false, ErrWatchlistUnavailable
+	}
+
+	found, err := s.Contracts.SetContractActive(address, active)
+	if err != nil || !found {
+		return found, err
+	}
+
+	if active {
+		s.Logger.Info("Resumed indexing contract %s", address)
+	} else {
+		s.Logger.Info("Paused indexing contract %s", address)
+	}
+	return true, s.resubscribe()
+}
+
+// activeContracts returns addresses without the paused contracts. Contracts that are not
+// registered are active.
+func (s *IndexerService) activeContracts(addresses []common.Address) ([]common.Address, error) {
+	if s.Contracts == nil {
+		return addresses, nil
+	}
+
+	contracts, err := s.Contracts.GetContracts()
+	if err != nil {
+		return nil, err
+	}
+	paused := make(map[string]bool)
+	for _, contract := range contracts {
+		if !contract.Active {
+			paused[strings.ToLower(contract.Address)] = true
+		}
+	}
+
+	active := make([]common.Address, 0, len(addresses))
+	for _, address := range addresses {
+		if paused[strings.ToLower(address.Hex())] {
+			s.Logger.Info("Skipping paused contract %s", address.Hex())
+			continue
+		}
+		active = append(active, address)
+	}
+	return active, nil
+}
+
+// watchContracts subscribes to the events of addresses that are not paused, in ctx, and
+// remembers them so pausing or resuming a contract restarts the subscriptions
+func (s *IndexerService) watchContracts(ctx context.Context, addresses []common.Address) error {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	s.watched = addresses
+	s.watchCtx = ctx
+	return s.subscribeLocked()
+}
+
+// resubscribe restarts the subscriptions of the watched contracts, if indexing started.
+// The events emitted while the subscriptions restart are backfilled from the block after
+// the last processed one.
+func (s *IndexerService) resubscribe() error {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	if s.watchCtx == nil || s.watchCtx.Err() != nil {
+		return nil
+	}
+
+	var fromBlock *big.Int
+	if s.Resume != nil {
+		processed, err := s.Resume.GetLastProcessedBlock()
+		if err != nil {
+			s.Logger.Warn("Failed to get last processed block, not catching up after resubscribing: %v", err)
+		} else {
+			fromBlock = new(big.Int).Add(processed, big.NewInt(1))
+		}
+	}
+
+	if err := s.subscribeLocked(); err != nil {
+		return err
+	}
+	if fromBlock != nil {
+		ctx, watched := s.watchCtx, s.watched
+		s.goTracked(func() { s.catchUp(ctx, watched, fromBlock) })
+	}
+	return nil
+}
+
+// catchUp backfills the events of addresses that are not paused from fromBlock to the
+// chain head
+func (s *IndexerService) catchUp(ctx context.Context, addresses []common.Address, fromBlock *big.Int) {
+	head, err := s.Blockchain.GetLatestBlockNumber(ctx)
+	if err != nil {
+		s.Logger.Error("Failed to get latest block number to catch up after resubscribing: %v", err)
+		return
+	}
+	if fromBlock.Cmp(head) > 0 {
+		return
+	}
+	if err := s.ProcessHistoricalEvents(ctx, addresses, fromBlock, head); err != nil {
+		s.Logger.Error("Failed to catch up after resubscribing: %v", err)
+	}
+}
+
+// subscribeLocked replaces the current subscriptions with ones for the watched contracts
+// that are not paused. The caller holds subMu.
+func (s *IndexerService) subscribeLocked() error {
+	if s.subCancel != nil {
+		s.subCancel()
+		s.subCancel = nil
+		s.subscriptions = nil
+	}
+
+	addresses, err := s.activeContracts(s.watched)
+	if err != nil {
+		return fmt.Errorf("failed to load paused contracts: %v", err)
+	}
+	// A log filter without addresses matches every contract
+	if len(addresses) == 0 {
+		s.Logger.Warn("Every watched contract is paused, not subscribing to events")
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(s.watchCtx)
+	subscriptions, err := s.subscribe(ctx, addresses)
+	if err != nil {
+		cancel()
+		return err
+	}
+	s.subCancel = cancel
+	s.subscriptions = subscriptions
+	return nil
+}
+
+// subscribe subscribes to the NFT and token transfers, approvals when they are indexed
+// and the deposits and withdrawals of wrapped-native tokens, of addresses and handles
+// their events until ctx is cancelled. It returns the events subscribed to per contract.
+func (s *IndexerService) subscribe(ctx context.Context, addresses []common.Address) ([]types.ContractSubscription, error) {
+	// Start listening for new NFT transfer events
+	nftEventChan, nftErrChan, err := s.Blockchain.SubscribeToNFTTransfers(ctx, addresses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to NFT transfers: %v", err)
+	}
+
+	// Start listening for new token transfer events
+	tokenEventChan, tokenErrChan, err := s.Blockchain.SubscribeToTokenTransfers(ctx, addresses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to token transfers: %v", err)
+	}
+
+	// Start listening for token approvals when they are indexed
+	var approvalEventChan <-chan *types.IndexedEvent
+	var approvalErrChan <-chan error
+	if s.Blockchain.ApprovalsEnabled {
+		approvalEventChan, approvalErrChan, err = s.Blockchain.SubscribeToApprovals(ctx, addresses)
+		if err != nil {
+			return nil, fmt.Errorf("failed to subscribe to approvals: %v", err)
+		}
+	}
+
+	// Start listening for wraps and unwraps of the wrapped-native token contracts
+	var wrappedEventChan <-chan *types.IndexedEvent
+	var wrappedErrChan <-chan error
+	wrapped := s.wrappedNativeContracts(addresses)
+	if len(wrapped) > 0 {
+		wrappedEventChan, wrappedErrChan, err = s.Blockchain.SubscribeToWrappedNative(ctx, wrapped)
+		if err != nil {
+			return nil, fmt.Errorf("failed to subscribe to wrapped native deposits and withdrawals: %v", err)
+		}
+	}
+
+	// Handle events in separate goroutines
+	s.goTracked(func() { s.handleNFTEvents(ctx, nftEventChan, nftErrChan) })
+	s.goTracked(func() { s.handleTokenEvents(ctx, tokenEventChan, tokenErrChan) })
+	if approvalEventChan != nil {
+		s.goTracked(func() { s.handleIndexedEvents(ctx, "approval", approvalEventChan, approvalErrChan) })
+	}
+	if wrappedEventChan != nil {
+		s.goTracked(func() { s.handleIndexedEvents(ctx, "wrapped native", wrappedEventChan, wrappedErrChan) })
+	}
+	return s.subscriptionsOf(addresses, wrapped), nil
+}
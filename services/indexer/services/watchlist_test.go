@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"chainpulse/services/blockchain/services"
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// statusStore keeps the registered contracts in memory
+type statusStore struct {
+	mu        sync.Mutex
+	contracts []types.Contract
+}
+
+func (s *statusStore) GetContracts() ([]types.Contract, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]types.Contract(nil), s.contracts...), nil
+}
+
+func (s *statusStore) SetContractActive(address string, active bool) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.contracts {
+		if strings.EqualFold(s.contracts[i].Address, address) {
+			s.contracts[i].Active = active
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// liveSubscription is a log subscription that stays open until it is unsubscribed
+type liveSubscription struct {
+	client *subscribingClient
+	id     int
+	err    chan error
+}
+
+func (s *liveSubscription) Unsubscribe() {
+	s.client.mu.Lock()
+	defer s.client.mu.Unlock()
+	delete(s.client.live, s.id)
+}
+
+func (s *liveSubscription) Err() <-chan error {
+	return s.err
+}
+
+// subscribingClient tracks the addresses of its open log subscriptions
+type subscribingClient struct {
+	blockchain.ChainClient
+	mu     sync.Mutex
+	nextID int
+	live   map[int][]common.Address
+}
+
+func (c *subscribingClient) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- ethtypes.Log) (ethereum.Subscription, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	c.live[c.nextID] = q.Addresses
+	return &liveSubscription{client: c, id: c.nextID, err: make(chan error)}, nil
+}
+
+// subscribedAddresses returns the distinct addresses of the open subscriptions
+func (c *subscribingClient) subscribedAddresses() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seen := make(map[string]bool)
+	var addresses []string
+	for _, subscribed := range c.live {
+		for _, address := range subscribed {
+			if !seen[address.Hex()] {
+				seen[address.Hex()] = true
+				addresses = append(addresses, address.Hex())
+			}
+		}
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// waitForAddresses waits until the open subscriptions cover exactly expected
+func waitForAddresses(t *testing.T, client *subscribingClient, expected ...common.Address) {
+	t.Helper()
+	want := make([]string, len(expected))
+	for i, address := range expected {
+		want[i] = address.Hex()
+	}
+	sort.Strings(want)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		got := client.subscribedAddresses()
+		if strings.Join(got, ",") == strings.Join(want, ",") {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected subscriptions to %v, got %v", want, got)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestIndexerService_PauseAndResumeContract(t *testing.T) {
+	apes := common.HexToAddress("0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D")
+	mutants := common.HexToAddress("0x60E4d786628Fea6478F785A6d7e704777c86a7c6")
+
+	client := &subscribingClient{live: make(map[int][]common.Address)}
+	bc, err := blockchain.NewEventProcessorWithClient(client)
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+	s := &IndexerService{
+		Blockchain: bc,
+		Logger:     &MockLogger{},
+		Contracts: &statusStore{contracts: []types.Contract{
+			{Address: strings.ToLower(apes.Hex()), Active: true},
+			{Address: mutants.Hex(), Active: true},
+		}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer s.Stop(context.Background())
+	defer cancel()
+
+	if err := s.watchContracts(s.indexingContext(ctx), []common.Address{apes, mutants}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	waitForAddresses(t, client, apes, mutants)
+
+	found, err := s.PauseContract(ctx, apes.Hex())
+	if err != nil || !found {
+		t.Fatalf("Expected the contract to be paused, got %v, %v", found, err)
+	}
+	waitForAddresses(t, client, mutants)
+
+	found, err = s.ResumeContract(ctx, apes.Hex())
+	if err != nil || !found {
+		t.Fatalf("Expected the contract to be resumed, got %v, %v", found, err)
+	}
+	waitForAddresses(t, client, apes, mutants)
+
+	if found, err := s.PauseContract(ctx, "0x0000000000000000000000000000000000000001"); err != nil || found {
+		t.Errorf("Expected an unknown contract not to be found, got %v, %v", found, err)
+	}
+}
+
+func TestIndexerService_ProcessHistoricalEventsSkipsPausedContracts(t *testing.T) {
+	paused := common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc454e4438f44e")
+	client := &windowClient{}
+	s := &IndexerService{
+		Blockchain: &blockchain.EventProcessor{Client: client},
+		Logger:     &MockLogger{},
+		Contracts:  &statusStore{contracts: []types.Contract{{Address: paused.Hex(), Active: false}}},
+	}
+
+	if err := s.ProcessHistoricalEvents(context.Background(), []common.Address{paused}, big.NewInt(100), big.NewInt(200)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(client.queried) != 0 {
+		t.Errorf("Expected a paused contract not to be backfilled, got queries from %v", client.queried)
+	}
+}
+
+// catchUpClient is a subscribingClient that also serves the chain head and records the
+// blocks log queries start from
+type catchUpClient struct {
+	*subscribingClient
+	head    uint64
+	queried []uint64
+}
+
+func (c *catchUpClient) BlockNumber(ctx context.Context) (uint64, error) {
+	return c.head, nil
+}
+
+func (c *catchUpClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethtypes.Log, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queried = append(c.queried, q.FromBlock.Uint64())
+	return nil, nil
+}
+
+func TestIndexerService_ResumeContractCatchesUp(t *testing.T) {
+	apes := common.HexToAddress("0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D")
+
+	client := &catchUpClient{subscribingClient: &subscribingClient{live: make(map[int][]common.Address)}, head: 120}
+	bc, err := blockchain.NewEventProcessorWithClient(client)
+	if err != nil {
+		t.Fatalf("Failed to create event processor: %v", err)
+	}
+	s := &IndexerService{
+		Blockchain: bc,
+		Logger:     &MockLogger{},
+		Resume:     blockchain.NewResumeService(client, &processedStore{lastProcessed: big.NewInt(100)}),
+		Contracts:  &statusStore{contracts: []types.Contract{{Address: apes.Hex(), Active: false}}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer s.Stop(context.Background())
+	defer cancel()
+
+	if err := s.watchContracts(s.indexingContext(ctx), []common.Address{apes}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if found, err := s.ResumeContract(ctx, apes.Hex()); err != nil || !found {
+		t.Fatalf("Expected the contract to be resumed, got %v, %v", found, err)
+	}
+	waitForAddresses(t, client.subscribingClient, apes)
+
+	// The catch-up runs in the background
+	deadline := time.Now().Add(time.Second)
+	for {
+		client.mu.Lock()
+		queried := append([]uint64(nil), client.queried...)
+		client.mu.Unlock()
+		if len(queried) > 0 {
+			if queried[0] != 101 {
+				t.Errorf("Expected a catch-up from block 101, got queries from %v", queried)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the resumed contract to be caught up")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ParseWrappedNativeContracts parses the comma-separated addresses of wrapped-native
+// token contracts, such as WETH
+func ParseWrappedNativeContracts(spec string) ([]common.Address, error) {
+	var contracts []common.Address
+	for _, address := range strings.Split(spec, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("invalid wrapped native contract address: %s", address)
+		}
+		contracts = append(contracts, common.HexToAddress(address))
+	}
+	return contracts, nil
+}
+
+// wrappedNativeContracts returns the addresses that are wrapped-native token contracts,
+// whose Deposit and Withdrawal events are indexed
+func (s *IndexerService) wrappedNativeContracts(addresses []common.Address) []common.Address {
+	wrapped := make(map[common.Address]bool, len(s.WrappedNative))
+	for _, contract := range s.WrappedNative {
+		wrapped[contract] = true
+	}
+
+	var contracts []common.Address
+	for _, address := range addresses {
+		if wrapped[address] {
+			contracts = append(contracts, address)
+		}
+	}
+	return contracts
+}
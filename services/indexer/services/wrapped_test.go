@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParseWrappedNativeContracts(t *testing.T) {
+	weth := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+
+	contracts, err := ParseWrappedNativeContracts(" 0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2 ,")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(contracts) != 1 || contracts[0] != weth {
+		t.Errorf("Expected [%s], got %v", weth.Hex(), contracts)
+	}
+
+	if contracts, err := ParseWrappedNativeContracts(""); err != nil || len(contracts) != 0 {
+		t.Errorf("Expected no contracts, got %v, %v", contracts, err)
+	}
+	if _, err := ParseWrappedNativeContracts("weth"); err == nil {
+		t.Error("Expected an invalid address to be rejected")
+	}
+}
+
+func TestIndexerService_WrappedNativeContracts(t *testing.T) {
+	weth := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	apes := common.HexToAddress("0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D")
+
+	s := &IndexerService{WrappedNative: []common.Address{weth}}
+	if contracts := s.wrappedNativeContracts([]common.Address{apes, weth}); len(contracts) != 1 || contracts[0] != weth {
+		t.Errorf("Expected only %s, got %v", weth.Hex(), contracts)
+	}
+	// A paused wrapped-native contract is not subscribed to
+	if contracts := s.wrappedNativeContracts([]common.Address{apes}); len(contracts) != 0 {
+		t.Errorf("Expected no wrapped native contracts, got %v", contracts)
+	}
+}
@@ -0,0 +1,183 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Severity is how urgent an alert is
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// severityRank orders severities so an escalation can be told apart from a repeat
+var severityRank = map[Severity]int{
+	SeverityInfo:     0,
+	SeverityWarning:  1,
+	SeverityCritical: 2,
+}
+
+// Alert is a notification about a condition. Alerts with the same Key are about the
+// same condition, so a channel can group them and resolve the condition later.
+type Alert struct {
+	Key      string
+	Severity Severity
+	Summary  string
+	Details  map[string]string
+	Resolved bool // the condition has cleared
+}
+
+// Alerter delivers alerts to a notification channel such as Slack or PagerDuty
+type Alerter interface {
+	Send(ctx context.Context, alert Alert) error
+}
+
+// Multi sends every alert to all of its alerters
+type Multi []Alerter
+
+// Send sends alert to every alerter, returning the errors of those that failed
+func (m Multi) Send(ctx context.Context, alert Alert) error {
+	var failed []string
+	for _, alerter := range m {
+		if err := alerter.Send(ctx, alert); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to send alert %s: %s", alert.Key, strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Notifier deduplicates alerts before sending them. A condition is sent when it fires
+// and again only when its severity escalates, not every time it is checked, and its
+// resolution is sent once when it clears.
+type Notifier struct {
+	alerter Alerter
+	mu      sync.Mutex
+	active  map[string]Severity // firing conditions by key
+}
+
+// NewNotifier creates a notifier sending through alerter
+func NewNotifier(alerter Alerter) *Notifier {
+	return &Notifier{
+		alerter: alerter,
+		active:  make(map[string]Severity),
+	}
+}
+
+// Fire reports that the condition of alert holds. It is sent unless the condition is
+// already firing at the same or a higher severity.
+func (n *Notifier) Fire(ctx context.Context, alert Alert) error {
+	n.mu.Lock()
+	severity, firing := n.active[alert.Key]
+	if firing && severityRank[alert.Severity] <= severityRank[severity] {
+		n.mu.Unlock()
+		return nil
+	}
+	n.active[alert.Key] = alert.Severity
+	n.mu.Unlock()
+
+	alert.Resolved = false
+	if err := n.alerter.Send(ctx, alert); err != nil {
+		// Not delivered, so the next check tries again
+		n.mu.Lock()
+		if firing {
+			n.active[alert.Key] = severity
+		} else {
+			delete(n.active, alert.Key)
+		}
+		n.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Resolve reports that the condition of key has cleared. The resolution is sent only
+// if the condition was firing.
+func (n *Notifier) Resolve(ctx context.Context, key, summary string) error {
+	n.mu.Lock()
+	severity, firing := n.active[key]
+	delete(n.active, key)
+	n.mu.Unlock()
+
+	if !firing {
+		return nil
+	}
+
+	err := n.alerter.Send(ctx, Alert{Key: key, Severity: severity, Summary: summary, Resolved: true})
+	if err != nil {
+		// Still firing as far as the channel knows, so the next check tries again
+		n.mu.Lock()
+		if _, ok := n.active[key]; !ok {
+			n.active[key] = severity
+		}
+		n.mu.Unlock()
+	}
+	return err
+}
+
+// Firing reports whether the condition of key is firing
+func (n *Notifier) Firing(key string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, ok := n.active[key]
+	return ok
+}
+
+// Condition fires an alert once a condition has held for a while, so short blips do
+// not page anyone, and resolves it once the condition clears
+type Condition struct {
+	Notifier *Notifier
+	Key      string
+	Severity Severity
+	For      time.Duration // how long the condition must hold before it fires
+
+	mu    sync.Mutex
+	since time.Time // when the condition started holding, zero while it does not
+}
+
+// Update records whether the condition holds at now, firing or resolving its alert
+// as needed. summary describes the current state.
+func (c *Condition) Update(ctx context.Context, holds bool, summary string, now time.Time) error {
+	c.mu.Lock()
+	if !holds {
+		c.since = time.Time{}
+		c.mu.Unlock()
+		return c.Notifier.Resolve(ctx, c.Key, summary)
+	}
+	if c.since.IsZero() {
+		c.since = now
+	}
+	sustained := now.Sub(c.since) >= c.For
+	c.mu.Unlock()
+
+	if !sustained {
+		return nil
+	}
+	return c.Notifier.Fire(ctx, Alert{Key: c.Key, Severity: c.Severity, Summary: summary})
+}
+
+// NewConfiguredNotifier creates a notifier sending to the configured channels: Slack
+// when slackWebhookURL is set and PagerDuty when pagerDutyRoutingKey is set. It
+// returns nil when no channel is configured.
+func NewConfiguredNotifier(slackWebhookURL, pagerDutyRoutingKey, source string) *Notifier {
+	var alerters Multi
+	if slackWebhookURL != "" {
+		alerters = append(alerters, NewSlackAlerter(slackWebhookURL))
+	}
+	if pagerDutyRoutingKey != "" {
+		alerters = append(alerters, NewPagerDutyAlerter(pagerDutyRoutingKey, source))
+	}
+	if len(alerters) == 0 {
+		return nil
+	}
+	return NewNotifier(alerters)
+}
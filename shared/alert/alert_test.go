@@ -0,0 +1,132 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chainpulse/shared/json"
+)
+
+// recordingAlerter records the alerts sent through it
+type recordingAlerter struct {
+	sent []Alert
+	err  error
+}
+
+func (r *recordingAlerter) Send(ctx context.Context, alert Alert) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.sent = append(r.sent, alert)
+	return nil
+}
+
+func TestCondition_SustainedLagAlertsOnceAndResolves(t *testing.T) {
+	recorder := &recordingAlerter{}
+	lag := &Condition{Notifier: NewNotifier(recorder), Key: "sync-lag", Severity: SeverityCritical, For: time.Minute}
+	ctx := context.Background()
+	start := time.Now()
+
+	// A short spike does not alert
+	lag.Update(ctx, true, "500 blocks behind", start)
+	lag.Update(ctx, true, "500 blocks behind", start.Add(30*time.Second))
+	if len(recorder.sent) != 0 {
+		t.Fatalf("Expected no alert before the lag is sustained, got %v", recorder.sent)
+	}
+
+	// Sustained lag alerts once, however often it is checked
+	for i := 0; i < 5; i++ {
+		lag.Update(ctx, true, "500 blocks behind", start.Add(time.Minute+time.Duration(i)*10*time.Second))
+	}
+	if len(recorder.sent) != 1 || recorder.sent[0].Resolved || recorder.sent[0].Severity != SeverityCritical {
+		t.Fatalf("Expected one critical alert, got %v", recorder.sent)
+	}
+
+	// Clearing the lag resolves the alert once
+	lag.Update(ctx, false, "caught up", start.Add(3*time.Minute))
+	lag.Update(ctx, false, "caught up", start.Add(4*time.Minute))
+	if len(recorder.sent) != 2 || !recorder.sent[1].Resolved || recorder.sent[1].Key != "sync-lag" {
+		t.Fatalf("Expected one resolution, got %v", recorder.sent)
+	}
+
+	// Lag returning must be sustained again before it alerts
+	lag.Update(ctx, true, "500 blocks behind", start.Add(5*time.Minute))
+	if len(recorder.sent) != 2 {
+		t.Errorf("Expected no alert for returning lag yet, got %v", recorder.sent)
+	}
+}
+
+func TestNotifier_EscalationAndFailedDelivery(t *testing.T) {
+	recorder := &recordingAlerter{}
+	notifier := NewNotifier(recorder)
+	ctx := context.Background()
+
+	notifier.Fire(ctx, Alert{Key: "node", Severity: SeverityWarning, Summary: "slow"})
+	notifier.Fire(ctx, Alert{Key: "node", Severity: SeverityWarning, Summary: "slow"})
+	notifier.Fire(ctx, Alert{Key: "node", Severity: SeverityCritical, Summary: "down"})
+	if len(recorder.sent) != 2 || recorder.sent[1].Severity != SeverityCritical {
+		t.Fatalf("Expected a warning and an escalation, got %v", recorder.sent)
+	}
+
+	// An alert that was not delivered is sent again on the next check
+	recorder.err = errors.New("unreachable")
+	if err := notifier.Fire(ctx, Alert{Key: "reorg", Severity: SeverityCritical}); err == nil {
+		t.Fatal("Expected the delivery error")
+	}
+	if notifier.Firing("reorg") {
+		t.Error("Expected an undelivered alert not to be firing")
+	}
+}
+
+func TestSlackAlerter_Send(t *testing.T) {
+	var message map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&message)
+	}))
+	defer server.Close()
+
+	alert := Alert{Key: "sync-lag", Severity: SeverityCritical, Summary: "500 blocks behind", Details: map[string]string{"head": "1000"}}
+	if err := NewSlackAlerter(server.URL).Send(context.Background(), alert); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(message["text"], "CRITICAL") || !strings.Contains(message["text"], "500 blocks behind") || !strings.Contains(message["text"], "head: 1000") {
+		t.Errorf("Expected the alert in the message, got %q", message["text"])
+	}
+}
+
+func TestPagerDutyAlerter_TriggerAndResolve(t *testing.T) {
+	var events []pagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event pagerDutyEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	alerter := NewPagerDutyAlerter("routing-key", "indexer")
+	alerter.url = server.URL
+	ctx := context.Background()
+
+	if err := alerter.Send(ctx, Alert{Key: "sync-lag", Severity: SeverityCritical, Summary: "500 blocks behind"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := alerter.Send(ctx, Alert{Key: "sync-lag", Resolved: true}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0].EventAction != "trigger" || events[0].DedupKey != "sync-lag" || events[0].Payload.Severity != SeverityCritical {
+		t.Errorf("Expected a critical trigger for sync-lag, got %+v", events[0])
+	}
+	if events[1].EventAction != "resolve" || events[1].DedupKey != "sync-lag" || events[1].Payload != nil {
+		t.Errorf("Expected a resolve for sync-lag, got %+v", events[1])
+	}
+}
@@ -0,0 +1,90 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"chainpulse/shared/json"
+)
+
+// PagerDutyEventsURL is the endpoint of the PagerDuty Events API v2
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyAlerter triggers and resolves PagerDuty incidents through the Events API v2.
+// The alert key is the dedup key, so PagerDuty groups the alerts of one condition into
+// a single incident.
+type PagerDutyAlerter struct {
+	routingKey string
+	source     string
+	url        string
+	client     *http.Client
+}
+
+// NewPagerDutyAlerter creates an alerter sending to the service of routingKey. source
+// names the system the alerts come from.
+func NewPagerDutyAlerter(routingKey, source string) *PagerDutyAlerter {
+	return &PagerDutyAlerter{
+		routingKey: routingKey,
+		source:     source,
+		url:        PagerDutyEventsURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      Severity          `json:"severity"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"` // "trigger" or "resolve"
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"` // only for triggers
+}
+
+// Send triggers an incident for alert, or resolves it if alert is resolved
+func (p *PagerDutyAlerter) Send(ctx context.Context, alert Alert) error {
+	event := pagerDutyEvent{
+		RoutingKey:  p.routingKey,
+		EventAction: "trigger",
+		DedupKey:    alert.Key,
+	}
+	if alert.Resolved {
+		event.EventAction = "resolve"
+	} else {
+		event.Payload = &pagerDutyPayload{
+			Summary:       alert.Summary,
+			Source:        p.source,
+			Severity:      alert.Severity,
+			CustomDetails: alert.Details,
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode PagerDuty event: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create PagerDuty request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send PagerDuty event: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("PagerDuty returned status %d", resp.StatusCode)
+	}
+	return nil
+}
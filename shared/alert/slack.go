@@ -0,0 +1,72 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"chainpulse/shared/json"
+)
+
+// SlackAlerter posts alerts to a Slack incoming webhook
+type SlackAlerter struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackAlerter creates an alerter posting to the Slack incoming webhook at webhookURL
+func NewSlackAlerter(webhookURL string) *SlackAlerter {
+	return &SlackAlerter{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send posts alert as a Slack message
+func (s *SlackAlerter) Send(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(map[string]string{"text": slackText(alert)})
+	if err != nil {
+		return fmt.Errorf("failed to encode Slack message: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Slack request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post Slack message: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Slack webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// slackText formats alert as a Slack message, details sorted by name
+func slackText(alert Alert) string {
+	var b strings.Builder
+	if alert.Resolved {
+		fmt.Fprintf(&b, ":white_check_mark: *RESOLVED* %s: %s", alert.Key, alert.Summary)
+	} else {
+		fmt.Fprintf(&b, ":rotating_light: *%s* %s: %s", strings.ToUpper(string(alert.Severity)), alert.Key, alert.Summary)
+	}
+
+	names := make([]string, 0, len(alert.Details))
+	for name := range alert.Details {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n• %s: %s", name, alert.Details[name])
+	}
+	return b.String()
+}
@@ -31,6 +31,11 @@ type GRPCPlugin interface {
 	SetDatabase(db interface{})
 }
 
+// ReadinessProbe reports whether the backing service is ready to serve traffic
+type ReadinessProbe interface {
+	Ready() (bool, string)
+}
+
 // Route represents an API route
 type Route struct {
 	Path    string
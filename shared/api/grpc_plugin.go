@@ -8,6 +8,8 @@ import (
 	"sync"
 	"time"
 
+	"chainpulse/shared/requestid"
+
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -50,7 +52,10 @@ func (g *GRPCPluginImpl) Initialize(config map[string]interface{}) error {
 	}
 
 	// Create gRPC server
-	g.server = grpc.NewServer()
+	g.server = grpc.NewServer(
+		grpc.UnaryInterceptor(requestid.UnaryServerInterceptor),
+		grpc.StreamInterceptor(requestid.StreamServerInterceptor),
+	)
 
 	// Enable reflection for debugging tools
 	reflection.Register(g.server)
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"chainpulse/shared/json"
+)
+
+// Rate limit keys: what a client's requests are counted by
+const (
+	RateLimitByIP     = "ip"
+	RateLimitByAPIKey = "api_key" // the X-API-Key header, or the IP for requests without one
+)
+
+// APIKeyHeader is the header identifying API key clients for rate limiting
+const APIKeyHeader = "X-API-Key"
+
+// maxRateLimitBuckets bounds the clients tracked at once; beyond it idle clients are forgotten
+const maxRateLimitBuckets = 10000
+
+// RateLimit is a token bucket: clients may burst up to Burst requests, refilled at
+// Rate requests per second. A Rate <= 0 does not limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitConfig configures the rate limits of the REST routes
+type RateLimitConfig struct {
+	Default RateLimit
+	Routes  map[string]RateLimit // by route path, overriding Default
+	KeyBy   string               // RateLimitByIP or RateLimitByAPIKey, empty counts by IP
+}
+
+// ParseRouteRateLimits parses per-route rate limits given as comma-separated
+// "path=rate:burst" pairs, such as "/graphql=2:5"
+func ParseRouteRateLimits(spec string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		path, limit, found := strings.Cut(pair, "=")
+		rateStr, burstStr, hasBurst := strings.Cut(limit, ":")
+		if !found || !hasBurst || path == "" {
+			return nil, fmt.Errorf("invalid route rate limit %q: expected path=rate:burst", pair)
+		}
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate in route rate limit %q: %v", pair, err)
+		}
+		burst, err := strconv.Atoi(burstStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid burst in route rate limit %q: %v", pair, err)
+		}
+		limits[path] = RateLimit{Rate: rate, Burst: burst}
+	}
+	return limits, nil
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter throttles each client with its own token bucket, answering requests
+// beyond the limit with 429 Too Many Requests and a Retry-After header
+type RateLimiter struct {
+	limit   RateLimit
+	keyBy   string
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+// NewRateLimiter creates a limiter counting requests by keyBy. It returns nil,
+// which does not limit, when limit.Rate <= 0.
+func NewRateLimiter(limit RateLimit, keyBy string) *RateLimiter {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &RateLimiter{
+		limit:   limit,
+		keyBy:   keyBy,
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of key. When the bucket is empty it returns
+// false and how long until a token is available.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitBuckets {
+			l.evictFull(now)
+		}
+		bucket = &tokenBucket{tokens: float64(l.limit.Burst), last: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(float64(l.limit.Burst), bucket.tokens+now.Sub(bucket.last).Seconds()*l.limit.Rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / l.limit.Rate * float64(time.Second))
+		return false, wait
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// evictFull forgets the clients whose buckets have refilled, which behave the same as
+// new clients
+func (l *RateLimiter) evictFull(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.limit.Rate >= float64(l.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// clientKey returns the key the requests of r are counted by
+func (l *RateLimiter) clientKey(r *http.Request) string {
+	if l.keyBy == RateLimitByAPIKey {
+		if key := r.Header.Get(APIKeyHeader); key != "" {
+			return "key:" + key
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// Middleware limits the requests to next
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed, wait := l.Allow(l.clientKey(r))
+		if !allowed {
+			writeRateLimited(w, wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeRateLimited answers a request over the limit, telling the client when to retry
+func writeRateLimited(w http.ResponseWriter, wait time.Duration) {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": "rate limit exceeded",
+	})
+}
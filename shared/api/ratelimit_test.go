@@ -0,0 +1,154 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for the rate limiter
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestLimiter(limit RateLimit, keyBy string) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	limiter := NewRateLimiter(limit, keyBy)
+	limiter.now = clock.Now
+	return limiter, clock
+}
+
+func TestRateLimiter_ExceedAndRefill(t *testing.T) {
+	limiter, clock := newTestLimiter(RateLimit{Rate: 2, Burst: 3}, RateLimitByIP)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	get := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/events", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The burst is allowed, the request after it is not
+	for i := 0; i < 3; i++ {
+		if rec := get("10.0.0.1:1234"); rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d for request %d, got %d", http.StatusOK, i+1, rec.Code)
+		}
+	}
+	rec := get("10.0.0.1:1234")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+	if retryAfter := rec.Header().Get("Retry-After"); retryAfter != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", retryAfter)
+	}
+
+	// Other clients have their own bucket, whatever their port
+	if rec := get("10.0.0.2:1234"); rec.Code != http.StatusOK {
+		t.Errorf("Expected status %d for another client, got %d", http.StatusOK, rec.Code)
+	}
+	if rec := get("10.0.0.1:5678"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d for the same IP, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+
+	// Half a second refills one token at 2 per second
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	if rec := get("10.0.0.1:1234"); rec.Code != http.StatusOK {
+		t.Errorf("Expected status %d after refill, got %d", http.StatusOK, rec.Code)
+	}
+	if rec := get("10.0.0.1:1234"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d after using the refilled token, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+
+	// Refilling never exceeds the burst
+	clock.now = clock.now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		get("10.0.0.1:1234")
+	}
+	if rec := get("10.0.0.1:1234"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d beyond the burst, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+}
+
+func TestRateLimiter_ByAPIKey(t *testing.T) {
+	limiter, _ := newTestLimiter(RateLimit{Rate: 1, Burst: 1}, RateLimitByAPIKey)
+
+	request := func(apiKey string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/graphql", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		if apiKey != "" {
+			req.Header.Set(APIKeyHeader, apiKey)
+		}
+		return req
+	}
+
+	// Keys sharing an IP are counted separately, requests without a key by IP
+	for _, apiKey := range []string{"key-a", "key-b", ""} {
+		if allowed, _ := limiter.Allow(limiter.clientKey(request(apiKey))); !allowed {
+			t.Errorf("Expected the first request with key %q to be allowed", apiKey)
+		}
+	}
+	if allowed, wait := limiter.Allow(limiter.clientKey(request("key-a"))); allowed || wait != time.Second {
+		t.Errorf("Expected the second request with key-a to wait 1s, got allowed=%v wait=%v", allowed, wait)
+	}
+}
+
+func TestParseRouteRateLimits(t *testing.T) {
+	limits, err := ParseRouteRateLimits("/graphql=2:5, /api/v1/stats=0.5:1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if limits["/graphql"] != (RateLimit{Rate: 2, Burst: 5}) || limits["/api/v1/stats"] != (RateLimit{Rate: 0.5, Burst: 1}) {
+		t.Errorf("Expected the parsed limits, got %v", limits)
+	}
+
+	for _, spec := range []string{"/graphql", "/graphql=2", "/graphql=x:5", "=2:5"} {
+		if _, err := ParseRouteRateLimits(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestRESTPlugin_RouteRateLimits(t *testing.T) {
+	plugin := NewRESTPlugin()
+	plugin.SetRateLimits(RateLimitConfig{
+		Default: RateLimit{Rate: 10, Burst: 20},
+		Routes:  map[string]RateLimit{"/graphql": {Rate: 1, Burst: 2}},
+	})
+	if err := plugin.Initialize(map[string]interface{}{"port": "0"}); err != nil {
+		t.Fatalf("Failed to initialize REST plugin: %v", err)
+	}
+
+	if limiter := plugin.rateLimiter("/graphql"); limiter == nil || limiter.limit.Burst != 2 {
+		t.Errorf("Expected the GraphQL override, got %+v", limiter)
+	}
+	if limiter := plugin.rateLimiter("/api/v1/events"); limiter == nil || limiter.limit.Burst != 20 {
+		t.Errorf("Expected the default limit, got %+v", limiter)
+	}
+
+	// Health checks are never limited
+	for i := 0; i < 50; i++ {
+		rec := httptest.NewRecorder()
+		plugin.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected /health status %d, got %d", http.StatusOK, rec.Code)
+		}
+	}
+
+	// GraphQL requests beyond its burst are rejected
+	var codes []int
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		plugin.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", nil))
+		codes = append(codes, rec.Code)
+	}
+	if codes[2] != http.StatusTooManyRequests || codes[0] == http.StatusTooManyRequests {
+		t.Errorf("Expected only the third GraphQL request to be rate limited, got %v", codes)
+	}
+}
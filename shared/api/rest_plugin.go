@@ -2,7 +2,6 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,7 +9,10 @@ import (
 	"time"
 
 	"chainpulse/services/api/handlers"
+	"chainpulse/services/api/handlers/auth"
 	"chainpulse/shared/database"
+	"chainpulse/shared/json"
+	"chainpulse/shared/requestid"
 
 	"github.com/gorilla/mux"
 )
@@ -22,6 +24,11 @@ type RESTPluginImpl struct {
 	db               *database.DB
 	port             string
 	metricsCollector *MetricsCollector
+	readiness        ReadinessProbe
+	watchlist        handlers.ContractWatchlist
+	jwtSecret        string
+	rateLimits       RateLimitConfig
+	limiters         map[string]*RateLimiter // by route path, created on first use
 	config           map[string]interface{}
 	mutex            sync.RWMutex
 	name             string
@@ -68,38 +75,85 @@ func (r *RESTPluginImpl) Initialize(config map[string]interface{}) error {
 	// Create HTTP server
 	r.server = &http.Server{
 		Addr:    ":" + r.port,
-		Handler: r.router,
+		Handler: requestid.Middleware(r.router),
 	}
 
 	return nil
 }
 
-// setupRoutes configures the API routes
+// setupRoutes configures the API routes on a new router, replacing the routes set up
+// before
 func (r *RESTPluginImpl) setupRoutes() {
+	r.router = mux.NewRouter()
+
 	// Initialize handlers
 	eventHandler := handlers.NewEventHandler(r.db)
 	contractHandler := handlers.NewContractHandler(r.db)
+	contractHandler.Watchlist = r.watchlist
 	statsHandler := handlers.NewStatsHandler(r.db)
 
-	// Health check endpoint
+	// Health check endpoints: liveness and readiness
 	r.router.HandleFunc("/health", r.healthCheck).Methods("GET")
+	r.router.HandleFunc("/ready", r.readyCheck).Methods("GET")
 
 	// Event endpoints
-	r.router.HandleFunc("/api/v1/events", eventHandler.GetEvents).Methods("GET")
-	r.router.HandleFunc("/api/v1/events/{txHash}", eventHandler.GetEventByTxHash).Methods("GET")
-	r.router.HandleFunc("/api/v1/events/block/{blockNumber}", eventHandler.GetEventsByBlockNumber).Methods("GET")
+	r.router.Handle("/api/v1/events", r.limited("/api/v1/events", http.HandlerFunc(eventHandler.GetEvents))).Methods("GET")
+	r.router.Handle("/api/v1/events/{txHash}", r.limited("/api/v1/events/{txHash}", http.HandlerFunc(eventHandler.GetEventByTxHash))).Methods("GET")
+	r.router.Handle("/api/v1/events/block/{blockNumber}", r.limited("/api/v1/events/block/{blockNumber}", http.HandlerFunc(eventHandler.GetEventsByBlockNumber))).Methods("GET")
 
 	// Contract endpoints
-	r.router.HandleFunc("/api/v1/contracts", contractHandler.GetContracts).Methods("GET")
-	r.router.HandleFunc("/api/v1/contracts/{address}", contractHandler.GetContractByAddress).Methods("GET")
+	r.router.Handle("/api/v1/contracts", r.limited("/api/v1/contracts", http.HandlerFunc(contractHandler.GetContracts))).Methods("GET")
+	r.router.Handle("/api/v1/contracts/{address}", r.limited("/api/v1/contracts/{address}", http.HandlerFunc(contractHandler.GetContractByAddress))).Methods("GET")
+
+	// Pausing and resuming contracts is admin-only, and not served without a JWT secret
+	if r.jwtSecret != "" {
+		authMiddleware := auth.NewAuthMiddleware(r.jwtSecret)
+		admin := func(handler http.HandlerFunc) http.Handler {
+			return authMiddleware.Middleware(authMiddleware.RequireRole("admin")(handler))
+		}
+		r.router.Handle("/api/v1/contracts/{address}/pause", r.limited("/api/v1/contracts/{address}/pause", admin(contractHandler.PauseContract))).Methods("POST")
+		r.router.Handle("/api/v1/contracts/{address}/resume", r.limited("/api/v1/contracts/{address}/resume", admin(contractHandler.ResumeContract))).Methods("POST")
+	}
 
 	// Stats endpoints
-	r.router.HandleFunc("/api/v1/stats", statsHandler.GetStats).Methods("GET")
+	r.router.Handle("/api/v1/stats", r.limited("/api/v1/stats", http.HandlerFunc(statsHandler.GetStats))).Methods("GET")
+
+	// GraphQL endpoint for queries selecting only the fields they need
+	r.router.Handle("/graphql", r.limited("/graphql", handlers.NewGraphQLHandler(r.db))).Methods("POST")
 	
 	// Metrics endpoint
 	r.router.HandleFunc("/api/v1/metrics", r.metricsHandler).Methods("GET")
 }
 
+// limited applies the rate limit of path to handler. The limiter is looked up per
+// request, so limits set after the routes are registered apply too.
+func (r *RESTPluginImpl) limited(path string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.rateLimiter(path).Middleware(handler).ServeHTTP(w, req)
+	})
+}
+
+// rateLimiter returns the limiter of path, nil when path is not limited
+func (r *RESTPluginImpl) rateLimiter(path string) *RateLimiter {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if limiter, ok := r.limiters[path]; ok {
+		return limiter
+	}
+
+	limit, ok := r.rateLimits.Routes[path]
+	if !ok {
+		limit = r.rateLimits.Default
+	}
+	limiter := NewRateLimiter(limit, r.rateLimits.KeyBy)
+	if r.limiters == nil {
+		r.limiters = make(map[string]*RateLimiter)
+	}
+	r.limiters[path] = limiter
+	return limiter
+}
+
 // healthCheck returns the health status of the service
 func (r *RESTPluginImpl) healthCheck(w http.ResponseWriter, req *http.Request) {
 	startTime := time.Now()
@@ -117,6 +171,39 @@ func (r *RESTPluginImpl) healthCheck(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// readyCheck returns 200 once the service is ready to serve traffic and 503 otherwise
+func (r *RESTPluginImpl) readyCheck(w http.ResponseWriter, req *http.Request) {
+	startTime := time.Now()
+
+	r.mutex.RLock()
+	probe := r.readiness
+	r.mutex.RUnlock()
+
+	ready, reason := true, ""
+	if probe != nil {
+		ready, reason = probe.Ready()
+	}
+
+	response := map[string]string{
+		"status":  "ready",
+		"service": "api-gateway",
+		"time":    time.Now().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		response["status"] = "not_ready"
+		response["reason"] = reason
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	err := json.NewEncoder(w).Encode(response)
+
+	// Record metrics
+	if r.metricsCollector != nil {
+		r.metricsCollector.RecordRequest("rest", time.Since(startTime), err)
+	}
+}
+
 // metricsHandler returns the metrics of the service
 func (r *RESTPluginImpl) metricsHandler(w http.ResponseWriter, req *http.Request) {
 	startTime := time.Now()
@@ -228,7 +315,37 @@ func (r *RESTPluginImpl) SetDatabase(db interface{}) {
 	}
 }
 
+// SetContractWatchlist sets the indexer paused and resumed by the contract pause and
+// resume endpoints. It must be called before Initialize.
+func (r *RESTPluginImpl) SetContractWatchlist(watchlist handlers.ContractWatchlist) {
+	r.watchlist = watchlist
+}
+
+// SetJWTSecret sets the secret the admin endpoints validate tokens with. It must be
+// called before Initialize.
+func (r *RESTPluginImpl) SetJWTSecret(secret string) {
+	r.jwtSecret = secret
+}
+
 // SetMetricsCollector sets the metrics collector for the REST plugin
 func (r *RESTPluginImpl) SetMetricsCollector(collector *MetricsCollector) {
 	r.metricsCollector = collector
-}
\ No newline at end of file
+}
+
+// SetRateLimits sets the rate limits of the API routes, replacing the current ones
+// and their client counts. Health checks are never limited.
+func (r *RESTPluginImpl) SetRateLimits(limits RateLimitConfig) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.rateLimits = limits
+	r.limiters = nil
+}
+
+// SetReadinessProbe sets the probe consulted by the /ready endpoint
+func (r *RESTPluginImpl) SetReadinessProbe(probe ReadinessProbe) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.readiness = probe
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chainpulse/services/api/handlers/auth"
+	"chainpulse/shared/service"
+)
+
+func TestRESTPlugin_ReadyDuringBackfill(t *testing.T) {
+	readiness := service.NewSyncReadiness(10)
+
+	plugin := NewRESTPlugin()
+	plugin.SetReadinessProbe(readiness)
+	if err := plugin.Initialize(map[string]interface{}{"port": "0"}); err != nil {
+		t.Fatalf("Failed to initialize REST plugin: %v", err)
+	}
+
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		plugin.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	// Before resuming the process is alive but not ready
+	if code := get("/health"); code != http.StatusOK {
+		t.Errorf("Expected /health status %d, got %d", http.StatusOK, code)
+	}
+	if code := get("/ready"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected /ready status %d before resume, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	// Resumed but still backfilling far behind the head
+	readiness.MarkResumed()
+	readiness.UpdateLag(big.NewInt(1000), big.NewInt(100))
+	if code := get("/ready"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected /ready status %d during backfill, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	// Caught up within the lag threshold
+	readiness.UpdateLag(big.NewInt(1000), big.NewInt(995))
+	if code := get("/ready"); code != http.StatusOK {
+		t.Errorf("Expected /ready status %d after catching up, got %d", http.StatusOK, code)
+	}
+}
+
+// unknownContracts is a watchlist without registered contracts
+type unknownContracts struct{}
+
+func (unknownContracts) PauseContract(ctx context.Context, address string) (bool, error) {
+	return false, nil
+}
+
+func (unknownContracts) ResumeContract(ctx context.Context, address string) (bool, error) {
+	return false, nil
+}
+
+func TestRESTPlugin_PauseRequiresAdmin(t *testing.T) {
+	plugin := NewRESTPlugin()
+	plugin.SetContractWatchlist(unknownContracts{})
+	plugin.SetJWTSecret("secret")
+	if err := plugin.Initialize(map[string]interface{}{"port": "0"}); err != nil {
+		t.Fatalf("Failed to initialize REST plugin: %v", err)
+	}
+
+	pause := func(role string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/contracts/0x1/pause", nil)
+		if role != "" {
+			token, err := auth.NewAuthMiddleware("secret").GenerateToken("user", role)
+			if err != nil {
+				t.Fatalf("Failed to generate token: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		plugin.router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := pause(""); code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without a token, got %d", http.StatusUnauthorized, code)
+	}
+	if code := pause("user"); code != http.StatusForbidden {
+		t.Errorf("Expected status %d for a non-admin, got %d", http.StatusForbidden, code)
+	}
+	if code := pause("admin"); code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an admin pausing an unknown contract, got %d", http.StatusNotFound, code)
+	}
+}
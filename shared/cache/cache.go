@@ -2,9 +2,12 @@ package cache
 
 import (
 	"context"
-	json "github.com/goccy/go-json"
+	"fmt"
+	"math/big"
 	"time"
 
+	"chainpulse/shared/json"
+
 	"github.com/go-redis/redis/v8"
 )
 
@@ -51,6 +54,40 @@ func (c *Cache) Get(ctx context.Context, key string, dest interface{}) error {
 	return json.Unmarshal([]byte(data), dest)
 }
 
+// SetNX sets the value only if the key does not already exist and reports whether it was set
+func (c *Cache) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	return c.Client.SetNX(ctx, key, data, expiration).Result()
+}
+
+// SetBigInt stores a big.Int as a decimal string, which round-trips exactly
+// regardless of size (unlike a JSON number)
+func (c *Cache) SetBigInt(ctx context.Context, key string, value *big.Int, expiration time.Duration) error {
+	if value == nil {
+		return fmt.Errorf("cannot cache nil big.Int for key %s", key)
+	}
+
+	return c.Client.Set(ctx, key, value.String(), expiration).Err()
+}
+
+// GetBigInt reads a big.Int stored by SetBigInt
+func (c *Cache) GetBigInt(ctx context.Context, key string) (*big.Int, error) {
+	data, err := c.Client.Get(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := new(big.Int).SetString(data, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid big.Int value cached for key %s: %q", key, data)
+	}
+	return value, nil
+}
+
 func (c *Cache) Exists(ctx context.Context, key string) (bool, error) {
 	count, err := c.Client.Exists(ctx, key).Result()
 	if err != nil {
@@ -63,6 +100,24 @@ func (c *Cache) Delete(ctx context.Context, key string) error {
 	return c.Client.Del(ctx, key).Err()
 }
 
+// ReorgEpochKey holds the reorg epoch, a counter bumped each time a chain reorg is handled
+const ReorgEpochKey = "reorg:epoch"
+
+// ReorgEpoch returns the current reorg epoch, which is 0 until the first reorg
+func (c *Cache) ReorgEpoch(ctx context.Context) (int64, error) {
+	epoch, err := c.Client.Get(ctx, ReorgEpochKey).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return epoch, err
+}
+
+// BumpReorgEpoch increments the reorg epoch so that keys built with the previous
+// epoch are no longer read, and returns the new epoch
+func (c *Cache) BumpReorgEpoch(ctx context.Context) (int64, error) {
+	return c.Client.Incr(ctx, ReorgEpochKey).Result()
+}
+
 func (c *Cache) Close() error {
 	return c.Client.Close()
 }
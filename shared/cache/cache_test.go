@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"math/big"
 	"os"
 	"testing"
 	"time"
@@ -245,4 +246,44 @@ func TestCacheClose(t *testing.T) {
 	if err != nil {
 		t.Errorf("Expected no error when closing cache, got %v", err)
 	}
-}
\ No newline at end of file
+}
+
+func TestCacheBigIntRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping cache test in short mode")
+	}
+
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		redisURL = "redis://localhost:6379"
+	}
+
+	cache, err := NewCache(redisURL)
+	if err != nil {
+		t.Skipf("skipping test: could not connect to Redis: %v", err)
+	}
+	defer cache.Close()
+
+	ctx := context.Background()
+	if err := cache.Ping(ctx); err != nil {
+		t.Skipf("skipping test: could not connect to Redis: %v", err)
+	}
+
+	// Larger than 2^53, so it would lose precision as a float64 JSON number
+	value, _ := new(big.Int).SetString("9007199254740993123", 10)
+	key := "test_bigint_key"
+
+	if err := cache.SetBigInt(ctx, key, value, 10*time.Second); err != nil {
+		t.Fatalf("Expected no error when setting value, got %v", err)
+	}
+	defer cache.Delete(ctx, key)
+
+	result, err := cache.GetBigInt(ctx, key)
+	if err != nil {
+		t.Fatalf("Expected no error when getting value, got %v", err)
+	}
+
+	if result.Cmp(value) != 0 {
+		t.Errorf("Expected value %s, got %s", value.String(), result.String())
+	}
+}
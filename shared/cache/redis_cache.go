@@ -2,11 +2,12 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"time"
 
+	"chainpulse/shared/json"
+
 	"github.com/go-redis/redis/v8"
 )
 
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SecretScheme prefixes config values that are references to a secret rather than the
+// value itself, in the form secret://<provider>/<path>, e.g. secret://env/POSTGRES_PASSWORD
+// or secret://vault/chainpulse/postgres_url
+const SecretScheme = "secret://"
+
+// SecretProvider resolves secret references against a secret store
+type SecretProvider interface {
+	// Name is the provider segment of the secret references it resolves
+	Name() string
+	// GetSecret returns the secret stored at path
+	GetSecret(ctx context.Context, path string) (string, error)
+}
+
+var (
+	secretProviders   = make(map[string]SecretProvider)
+	secretProvidersMu sync.RWMutex
+)
+
+func init() {
+	RegisterSecretProvider(EnvSecretProvider{})
+	RegisterSecretProvider(&VaultSecretProvider{Addr: os.Getenv("VAULT_ADDR"), Token: os.Getenv("VAULT_TOKEN")})
+	RegisterSecretProvider(&AWSSecretsManagerProvider{Region: os.Getenv("AWS_REGION")})
+}
+
+// RegisterSecretProvider registers provider for its name, replacing any provider
+// previously registered under that name
+func RegisterSecretProvider(provider SecretProvider) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+
+	secretProviders[provider.Name()] = provider
+}
+
+// IsSecretReference reports whether value is a secret:// reference
+func IsSecretReference(value string) bool {
+	return strings.HasPrefix(value, SecretScheme)
+}
+
+// ResolveSecret returns the secret referenced by value, or value unchanged if it is not a secret reference
+func ResolveSecret(ctx context.Context, value string) (string, error) {
+	if !IsSecretReference(value) {
+		return value, nil
+	}
+
+	name, path, found := strings.Cut(strings.TrimPrefix(value, SecretScheme), "/")
+	if !found || name == "" || path == "" {
+		return "", fmt.Errorf("invalid secret reference %q: expected %s<provider>/<path>", value, SecretScheme)
+	}
+
+	secretProvidersMu.RLock()
+	provider, exists := secretProviders[name]
+	secretProvidersMu.RUnlock()
+	if !exists {
+		return "", fmt.Errorf("unknown secret provider %q", name)
+	}
+
+	secret, err := provider.GetSecret(ctx, path)
+	if err != nil {
+		// The reference is safe to log; the secret is never part of the error
+		return "", fmt.Errorf("failed to resolve secret %s: %v", value, err)
+	}
+	return secret, nil
+}
+
+// resolveSecrets replaces every secret reference among values with the referenced secret
+func resolveSecrets(ctx context.Context, values ...*string) error {
+	for _, value := range values {
+		resolved, err := ResolveSecret(ctx, *value)
+		if err != nil {
+			return err
+		}
+		*value = resolved
+	}
+	return nil
+}
+
+// EnvSecretProvider reads secrets from environment variables, so secret://env/NAME
+// resolves to the value of NAME
+type EnvSecretProvider struct{}
+
+// Name returns the provider name
+func (EnvSecretProvider) Name() string { return "env" }
+
+// GetSecret returns the value of the environment variable named path
+func (EnvSecretProvider) GetSecret(ctx context.Context, path string) (string, error) {
+	value, exists := os.LookupEnv(path)
+	if !exists {
+		return "", fmt.Errorf("environment variable %s is not set", path)
+	}
+	return value, nil
+}
+
+// VaultSecretProvider resolves secrets from HashiCorp Vault.
+// It is a placeholder until a Vault client is added.
+type VaultSecretProvider struct {
+	Addr  string
+	Token string
+}
+
+// Name returns the provider name
+func (p *VaultSecretProvider) Name() string { return "vault" }
+
+// GetSecret returns the secret stored at path in Vault
+func (p *VaultSecretProvider) GetSecret(ctx context.Context, path string) (string, error) {
+	return "", fmt.Errorf("vault secret provider is not implemented")
+}
+
+// AWSSecretsManagerProvider resolves secrets from AWS Secrets Manager.
+// It is a placeholder until an AWS SDK client is added.
+type AWSSecretsManagerProvider struct {
+	Region string
+}
+
+// Name returns the provider name
+func (p *AWSSecretsManagerProvider) Name() string { return "aws" }
+
+// GetSecret returns the secret with the name path from AWS Secrets Manager
+func (p *AWSSecretsManagerProvider) GetSecret(ctx context.Context, path string) (string, error) {
+	return "", fmt.Errorf("aws secrets manager provider is not implemented")
+}
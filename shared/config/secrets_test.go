@@ -0,0 +1,76 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockSecretProvider serves secrets from a map and records the requested paths
+type mockSecretProvider struct {
+	secrets   map[string]string
+	requested []string
+}
+
+func (p *mockSecretProvider) Name() string { return "mock" }
+
+func (p *mockSecretProvider) GetSecret(ctx context.Context, path string) (string, error) {
+	p.requested = append(p.requested, path)
+	secret, exists := p.secrets[path]
+	if !exists {
+		return "", fmt.Errorf("secret %s not found", path)
+	}
+	return secret, nil
+}
+
+func TestLoadConfigResolvesSecretReferences(t *testing.T) {
+	provider := &mockSecretProvider{secrets: map[string]string{
+		"chainpulse/postgres": "postgres://app:s3cret@db:5432/chainpulse",
+		"chainpulse/jwt":      "jwt-from-store",
+	}}
+	RegisterSecretProvider(provider)
+
+	os.Setenv("POSTGRESQL_URL", "secret://mock/chainpulse/postgres")
+	os.Setenv("JWT_SECRET", "secret://mock/chainpulse/jwt")
+	os.Setenv("REDIS_URL", "secret://env/TEST_REDIS_URL_SECRET")
+	os.Setenv("TEST_REDIS_URL_SECRET", "redis://:pass@cache:6379")
+	defer func() {
+		os.Unsetenv("POSTGRESQL_URL")
+		os.Unsetenv("JWT_SECRET")
+		os.Unsetenv("REDIS_URL")
+		os.Unsetenv("TEST_REDIS_URL_SECRET")
+	}()
+
+	config, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://app:s3cret@db:5432/chainpulse", config.PostgreSQLURL)
+	assert.Equal(t, "jwt-from-store", config.JWTSecret)
+	assert.Equal(t, "redis://:pass@cache:6379", config.RedisURL)
+	// Plain values are left as they are
+	assert.Equal(t, "https://mainnet.infura.io/v3/YOUR_PROJECT_ID", config.EthereumNodeURL)
+	assert.ElementsMatch(t, []string{"chainpulse/postgres", "chainpulse/jwt"}, provider.requested)
+}
+
+func TestLoadConfigFailsOnUnresolvableSecret(t *testing.T) {
+	RegisterSecretProvider(&mockSecretProvider{})
+
+	os.Setenv("POSTGRESQL_URL", "secret://mock/missing")
+	defer os.Unsetenv("POSTGRESQL_URL")
+
+	_, err := LoadConfig()
+	assert.Error(t, err)
+}
+
+func TestResolveSecretInvalidReferences(t *testing.T) {
+	_, err := ResolveSecret(context.Background(), "secret://unknown/path")
+	assert.Error(t, err)
+
+	_, err = ResolveSecret(context.Background(), "secret://env")
+	assert.Error(t, err)
+
+	_, err = ResolveSecret(context.Background(), "secret://vault/chainpulse/db")
+	assert.Error(t, err)
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
+)
+
+// Archiver moves events that fall outside the retention window to cold storage. The
+// retention manager deletes the events once Archive returns, and archives them again if
+// deleting them fails, so archiving the same events twice must not duplicate them.
+type Archiver interface {
+	Name() string
+	Archive(ctx context.Context, events []types.IndexedEvent) error
+}
+
+// TxArchiver is an Archiver writing to the events database, which archives events in
+// the transaction that deletes them from the hot table
+type TxArchiver interface {
+	Archiver
+	ArchiveTx(tx *gorm.DB, events []types.IndexedEvent) error
+}
+
+// TableArchiver archives events into the archived_events table of the same database
+type TableArchiver struct {
+	db *gorm.DB
+}
+
+// NewTableArchiver creates a table archiver and migrates the archive table
+func NewTableArchiver(db *Database) (*TableArchiver, error) {
+	if err := db.DB.AutoMigrate(&types.ArchivedEvent{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate archived events table: %v", err)
+	}
+
+	return &TableArchiver{db: db.DB}, nil
+}
+
+// Name returns the archiver name
+func (a *TableArchiver) Name() string {
+	return "table"
+}
+
+// Archive copies the events into the archive table, skipping events already archived
+func (a *TableArchiver) Archive(ctx context.Context, events []types.IndexedEvent) error {
+	return a.ArchiveTx(a.db.WithContext(ctx), events)
+}
+
+// ArchiveTx copies the events into the archive table within tx, skipping events already
+// archived
+func (a *TableArchiver) ArchiveTx(tx *gorm.DB, events []types.IndexedEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	archived := make([]types.ArchivedEvent, len(events))
+	for i, event := range events {
+		archived[i] = types.ArchivedEvent{
+			IndexedEvent: event,
+			ArchivedAt:   now,
+		}
+	}
+
+	return tx.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(archived, len(archived)).Error
+}
+
+// ObjectStore is the minimal interface required from an object storage backend
+// (an S3-compatible bucket, GCS, or a local directory)
+type ObjectStore interface {
+	PutObject(ctx context.Context, key string, data []byte, contentType string) error
+}
+
+// ObjectStoreArchiver exports archived events as NDJSON objects to an ObjectStore
+type ObjectStoreArchiver struct {
+	store  ObjectStore
+	prefix string
+}
+
+// NewObjectStoreArchiver creates an archiver that writes NDJSON objects under prefix
+func NewObjectStoreArchiver(store ObjectStore, prefix string) *ObjectStoreArchiver {
+	return &ObjectStoreArchiver{
+		store:  store,
+		prefix: prefix,
+	}
+}
+
+// Name returns the archiver name
+func (a *ObjectStoreArchiver) Name() string {
+	return "object-store"
+}
+
+// Archive writes the events as a single NDJSON object, one event per line. The object
+// key is derived from the event IDs, so archiving the same events again overwrites the
+// object instead of adding a duplicate.
+func (a *ObjectStoreArchiver) Archive(ctx context.Context, events []types.IndexedEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return fmt.Errorf("failed to encode event %d: %v", event.ID, err)
+		}
+	}
+
+	ids := sha256.New()
+	for _, event := range events {
+		fmt.Fprintf(ids, "%d,", event.ID)
+	}
+	key := fmt.Sprintf("%s/events-%d-%d-%x.ndjson", a.prefix, events[0].ID, events[len(events)-1].ID, ids.Sum(nil)[:8])
+	if err := a.store.PutObject(ctx, key, buf.Bytes(), "application/x-ndjson"); err != nil {
+		return fmt.Errorf("failed to upload archive %s: %v", key, err)
+	}
+
+	return nil
+}
+
+// FileObjectStore is an ObjectStore backed by a local directory
+type FileObjectStore struct {
+	Dir string
+}
+
+// NewFileObjectStore creates an object store rooted at dir
+func NewFileObjectStore(dir string) *FileObjectStore {
+	return &FileObjectStore{Dir: dir}
+}
+
+// PutObject writes the object to a file under the store directory
+func (s *FileObjectStore) PutObject(ctx context.Context, key string, data []byte, contentType string) error {
+	path := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
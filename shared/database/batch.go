@@ -3,11 +3,11 @@ package database
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"chainpulse/shared/metrics"
 	"chainpulse/shared/types"
-
-	"gorm.io/gorm/clause"
 )
 
 // BatchProcessor handles batch database operations for better performance
@@ -20,10 +20,29 @@ type BatchProcessor struct {
 	wg           sync.WaitGroup
 	ctx          context.Context
 	cancel       context.CancelFunc
+	metrics      *metrics.Metrics
+
+	flushHook         atomic.Value // func([]*types.IndexedEvent) called with every stored batch
+	maxBufferedBytes  int64 // approximate bytes of buffered events that force a flush, 0 disables
+	bufferSize        int64 // events added but not yet flushed
+	bufferedBytes     int64 // approximate size of the events buffered for the next flush
+	flushes           int64
+	flushedEvents     int64
+	lastFlushDuration int64 // nanoseconds
+}
+
+// BatchStats is a snapshot of the batch processor's buffer and flush activity
+type BatchStats struct {
+	BufferSize        int64
+	BufferedBytes     int64
+	Flushes           int64
+	FlushedEvents     int64
+	LastFlushDuration time.Duration
 }
 
-// NewBatchProcessor creates a new batch processor
-func NewBatchProcessor(db *Database, batchSize int, flushTimeout time.Duration) *BatchProcessor {
+// NewBatchProcessor creates a new batch processor. Buffer and flush metrics are recorded
+// into m when it is not nil.
+func NewBatchProcessor(db *Database, batchSize int, flushTimeout time.Duration, m *metrics.Metrics) *BatchProcessor {
 	ctx, cancel := context.WithCancel(context.Background())
 	
 	bp := &BatchProcessor{
@@ -34,6 +53,7 @@ func NewBatchProcessor(db *Database, batchSize int, flushTimeout time.Duration)
 		flushChan:    make(chan struct{}, 1),
 		ctx:          ctx,
 		cancel:       cancel,
+		metrics:      m,
 	}
 	
 	bp.startProcessing()
@@ -51,6 +71,13 @@ func (bp *BatchProcessor) processBatches() {
 	defer bp.wg.Done()
 	
 	events := make([]*types.IndexedEvent, 0, bp.batchSize)
+	var bufferedBytes int64
+	flush := func() {
+		bp.flushBatch(events)
+		events = make([]*types.IndexedEvent, 0, bp.batchSize)
+		atomic.AddInt64(&bp.bufferedBytes, -bufferedBytes)
+		bufferedBytes = 0
+	}
 	ticker := time.NewTicker(bp.flushTimeout)
 	defer ticker.Stop()
 
@@ -58,28 +85,32 @@ func (bp *BatchProcessor) processBatches() {
 		select {
 		case event := <-bp.eventsChan:
 			events = append(events, event)
+			size := approxEventSize(event)
+			bufferedBytes += size
+			atomic.AddInt64(&bp.bufferedBytes, size)
 			
-			// If we've reached batch size, flush immediately
-			if len(events) >= bp.batchSize {
-				bp.flushBatch(events)
-				events = make([]*types.IndexedEvent, 0, bp.batchSize)
+			// If we've reached batch size or the memory limit, flush immediately
+			maxBytes := atomic.LoadInt64(&bp.maxBufferedBytes)
+			if len(events) >= bp.batchSize || (maxBytes > 0 && bufferedBytes >= maxBytes) {
+				flush()
 			}
 		case <-ticker.C:
 			// Flush batch if there are any events
 			if len(events) > 0 {
-				bp.flushBatch(events)
-				events = make([]*types.IndexedEvent, 0, bp.batchSize)
+				flush()
 			}
 		case <-bp.flushChan:
 			// Force flush when requested
 			if len(events) > 0 {
-				bp.flushBatch(events)
-				events = make([]*types.IndexedEvent, 0, bp.batchSize)
+				flush()
 			}
 		case <-bp.ctx.Done():
-			// Flush remaining events when shutting down
+			// Flush remaining events when shutting down, including those still queued
+			for len(bp.eventsChan) > 0 {
+				events = append(events, <-bp.eventsChan)
+			}
 			if len(events) > 0 {
-				bp.flushBatch(events)
+				flush()
 			}
 			return
 		}
@@ -92,25 +123,123 @@ func (bp *BatchProcessor) flushBatch(events []*types.IndexedEvent) {
 		return
 	}
 
+	// The events leave the buffer whether or not they are stored
+	atomic.AddInt64(&bp.bufferSize, -int64(len(events)))
+	if bp.metrics != nil {
+		bp.metrics.AddBatchBufferSize(-float64(len(events)))
+	}
+
 	// Use GORM's clause for batch insert
-	err := bp.db.DB.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(events, bp.batchSize).Error
+	startTime := time.Now()
+	bp.db.assignIDs(events...)
+	err := bp.db.DB.Clauses(bp.db.eventConflict()).CreateInBatches(events, bp.batchSize).Error
+	duration := time.Since(startTime)
+	atomic.StoreInt64(&bp.lastFlushDuration, int64(duration))
 	if err != nil {
+		// Failed flushes are only counted as errors, the flush counters track stored events
+		if bp.metrics != nil {
+			bp.metrics.IncrementError("batch_processor", "flush")
+		}
 		// In a real implementation, you might want to handle this error differently
 		// For now, we'll just log it
 		return
 	}
+
+	atomic.AddInt64(&bp.flushes, 1)
+	atomic.AddInt64(&bp.flushedEvents, int64(len(events)))
+	if bp.metrics != nil {
+		bp.metrics.RecordBatchFlush(len(events), duration.Seconds())
+	}
+	bp.recordLatency(events, time.Now())
+
+	if hook, ok := bp.flushHook.Load().(func([]*types.IndexedEvent)); ok && hook != nil {
+		hook(events)
+	}
+}
+
+// recordLatency records how long the subscribed events among events took to be stored at
+// storedAt. Backfilled events were not received from a subscription and are skipped.
+func (bp *BatchProcessor) recordLatency(events []*types.IndexedEvent, storedAt time.Time) {
+	if bp.metrics == nil {
+		return
+	}
+	for _, event := range events {
+		if event.ReceivedAt.IsZero() || event.Timestamp.IsZero() {
+			continue
+		}
+		bp.metrics.RecordEventLatency(storedAt.Sub(event.Timestamp).Seconds(), storedAt.Sub(event.ReceivedAt).Seconds())
+	}
 }
 
 // AddEvent adds an event to the batch processor
 func (bp *BatchProcessor) AddEvent(event *types.IndexedEvent) error {
 	select {
 	case bp.eventsChan <- event:
+		atomic.AddInt64(&bp.bufferSize, 1)
+		if bp.metrics != nil {
+			bp.metrics.AddBatchBufferSize(1)
+		}
 		return nil
 	case <-bp.ctx.Done():
 		return bp.ctx.Err()
 	}
 }
 
+// InsertBatch inserts events right away rather than buffering them, skipping duplicates of
+// stored events, and returns how many were inserted. Callers that need the outcome, such
+// as bulk imports, use it instead of AddEvent. With force, duplicates overwrite the stored
+// events instead, and are counted as inserted; of duplicates within events the last wins.
+// Force is meant for operators re-importing events; buffered live events are never forced.
+func (bp *BatchProcessor) InsertBatch(events []*types.IndexedEvent, force bool) (int64, error) {
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	conflict := bp.db.eventConflict()
+	if force {
+		var err error
+		if conflict, err = bp.db.eventUpsert(); err != nil {
+			return 0, err
+		}
+		events = bp.db.lastOfEachKey(events)
+	}
+
+	bp.db.assignIDs(events...)
+	startTime := time.Now()
+	result := bp.db.DB.Clauses(conflict).CreateInBatches(events, bp.batchSize)
+	duration := time.Since(startTime)
+	atomic.StoreInt64(&bp.lastFlushDuration, int64(duration))
+
+	if result.Error != nil {
+		// Failed inserts are only counted as errors, the flush counters track stored events
+		if bp.metrics != nil {
+			bp.metrics.IncrementError("batch_processor", "insert")
+		}
+		return 0, result.Error
+	}
+
+	atomic.AddInt64(&bp.flushes, 1)
+	atomic.AddInt64(&bp.flushedEvents, result.RowsAffected)
+	if bp.metrics != nil {
+		bp.metrics.RecordBatchFlush(len(events), duration.Seconds())
+	}
+	return result.RowsAffected, nil
+}
+
+// SetMaxBufferedBytes sets the approximate size of buffered events at which they are
+// flushed without waiting for the batch to fill, so bursts of events with large Data maps
+// cannot exhaust memory. 0 disables the limit.
+func (bp *BatchProcessor) SetMaxBufferedBytes(maxBytes int64) {
+	atomic.StoreInt64(&bp.maxBufferedBytes, maxBytes)
+}
+
+// SetFlushHook sets a function called with the events of every batch once they are
+// stored, e.g. to notify subscribers of new events. It runs on the flushing goroutine,
+// so it must not block.
+func (bp *BatchProcessor) SetFlushHook(hook func(events []*types.IndexedEvent)) {
+	bp.flushHook.Store(hook)
+}
+
 // Flush forces a flush of all pending events
 func (bp *BatchProcessor) Flush() {
 	select {
@@ -120,9 +249,65 @@ func (bp *BatchProcessor) Flush() {
 	}
 }
 
+// Stats returns a snapshot of the batch processor's buffer size and flush activity
+func (bp *BatchProcessor) Stats() BatchStats {
+	return BatchStats{
+		BufferSize:        atomic.LoadInt64(&bp.bufferSize),
+		BufferedBytes:     atomic.LoadInt64(&bp.bufferedBytes),
+		Flushes:           atomic.LoadInt64(&bp.flushes),
+		FlushedEvents:     atomic.LoadInt64(&bp.flushedEvents),
+		LastFlushDuration: time.Duration(atomic.LoadInt64(&bp.lastFlushDuration)),
+	}
+}
+
 // Close shuts down the batch processor
 func (bp *BatchProcessor) Close() error {
 	bp.cancel()
 	bp.wg.Wait()
 	return nil
-}
\ No newline at end of file
+}
+
+// eventOverhead approximates the memory of an IndexedEvent besides its strings and Data
+const eventOverhead = 256
+
+// approxEventSize approximates the memory held by event, dominated by its strings and
+// decoded Data
+func approxEventSize(event *types.IndexedEvent) int64 {
+	size := int64(eventOverhead)
+	size += int64(len(event.TxHash) + len(event.EventName) + len(event.Topic0) + len(event.Contract))
+	size += int64(len(event.From) + len(event.To) + len(event.TokenID) + len(event.Value))
+	return size + approxValueSize(event.Data)
+}
+
+// approxValueSize approximates the memory held by a decoded Data value
+func approxValueSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(v)) + 16
+	case []byte:
+		return int64(len(v)) + 24
+	case map[string]interface{}:
+		size := int64(48)
+		for key, item := range v {
+			size += int64(len(key)) + 16 + approxValueSize(item)
+		}
+		return size
+	case []interface{}:
+		size := int64(24)
+		for _, item := range v {
+			size += 16 + approxValueSize(item)
+		}
+		return size
+	case []string:
+		size := int64(24)
+		for _, item := range v {
+			size += int64(len(item)) + 16
+		}
+		return size
+	default:
+		// Numbers, booleans and small values such as *big.Int
+		return 16
+	}
+}
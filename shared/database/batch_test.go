@@ -1,12 +1,18 @@
 package database
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"chainpulse/shared/metrics"
 	"chainpulse/shared/types"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
@@ -20,7 +26,7 @@ func TestNewBatchProcessor(t *testing.T) {
 	batchSize := 10
 	flushTimeout := 1 * time.Second
 
-	batchProcessor := NewBatchProcessor(db, batchSize, flushTimeout)
+	batchProcessor := NewBatchProcessor(db, batchSize, flushTimeout, nil)
 
 	assert.NotNil(t, batchProcessor)
 	assert.Equal(t, batchSize, batchProcessor.batchSize)
@@ -31,7 +37,7 @@ func TestNewBatchProcessor(t *testing.T) {
 
 func TestBatchProcessor_AddEvent(t *testing.T) {
 	db := &Database{DB: nil}
-	batchProcessor := NewBatchProcessor(db, 5, 10*time.Second)
+	batchProcessor := NewBatchProcessor(db, 5, 10*time.Second, nil)
 	defer batchProcessor.Close()
 
 	event := &types.IndexedEvent{
@@ -54,7 +60,7 @@ func TestBatchProcessor_AddEvent(t *testing.T) {
 
 func TestBatchProcessor_Flush(t *testing.T) {
 	db := &Database{DB: nil}
-	batchProcessor := NewBatchProcessor(db, 5, 10*time.Second)
+	batchProcessor := NewBatchProcessor(db, 5, 10*time.Second, nil)
 	defer batchProcessor.Close()
 
 	// Add an event
@@ -81,8 +87,247 @@ func TestBatchProcessor_Flush(t *testing.T) {
 
 func TestBatchProcessor_Close(t *testing.T) {
 	db := &Database{DB: nil}
-	batchProcessor := NewBatchProcessor(db, 5, 10*time.Second)
+	batchProcessor := NewBatchProcessor(db, 5, 10*time.Second, nil)
 
 	err := batchProcessor.Close()
 	assert.NoError(t, err)
-}
\ No newline at end of file
+}
+
+// newDryRunDatabase returns a Database whose statements are built but never executed.
+// Statements run outside a transaction, since beginning one would connect.
+func newDryRunDatabase(t *testing.T) *Database {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=chainpulse_test"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry run database: %v", err)
+	}
+	return &Database{DB: db}
+}
+
+func newBatchMetrics() *metrics.Metrics {
+	return &metrics.Metrics{
+		BatchBufferSize:         prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_batch_buffer_size"}),
+		BatchFlushesTotal:       prometheus.NewCounter(prometheus.CounterOpts{Name: "test_batch_flushes_total"}),
+		BatchFlushedEventsTotal: prometheus.NewCounter(prometheus.CounterOpts{Name: "test_batch_flushed_events_total"}),
+		BatchFlushDuration:      prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_batch_flush_duration_seconds"}),
+		ErrorsTotal:             prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_errors_total"}, []string{"component", "error_type"}),
+		EventChainLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "test_event_chain_latency_seconds",
+			Buckets: []float64{1, 2, 5, 10, 15, 30, 60, 120, 300, 600},
+		}),
+		EventProcessingLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "test_event_processing_latency_seconds",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}),
+	}
+}
+
+// cumulativeCount returns how many samples of the only histogram gathered from collector
+// fall into the bucket with upper bound le
+func cumulativeCount(t *testing.T, collector prometheus.Collector, le float64) uint64 {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+	families, err := registry.Gather()
+	if err != nil || len(families) != 1 {
+		t.Fatalf("Failed to gather histogram: %v", err)
+	}
+	for _, bucket := range families[0].GetMetric()[0].GetHistogram().GetBucket() {
+		if bucket.GetUpperBound() == le {
+			return bucket.GetCumulativeCount()
+		}
+	}
+	t.Fatalf("Expected a bucket with upper bound %v", le)
+	return 0
+}
+
+func TestBatchProcessor_BufferSizeMetric(t *testing.T) {
+	m := newBatchMetrics()
+	batchProcessor := NewBatchProcessor(newDryRunDatabase(t), 10, time.Hour, m)
+	defer batchProcessor.Close()
+
+	for i := 0; i < 3; i++ {
+		err := batchProcessor.AddEvent(&types.IndexedEvent{TxHash: "0xabcdef", LogIndex: uint(i), Timestamp: time.Now()})
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, float64(3), testutil.ToFloat64(m.BatchBufferSize))
+	assert.Equal(t, int64(3), batchProcessor.Stats().BufferSize)
+}
+
+func TestBatchProcessor_FlushMetrics(t *testing.T) {
+	m := newBatchMetrics()
+	batchProcessor := NewBatchProcessor(newDryRunDatabase(t), 10, time.Hour, m)
+	defer batchProcessor.Close()
+
+	for i := 0; i < 2; i++ {
+		err := batchProcessor.AddEvent(&types.IndexedEvent{TxHash: "0xabcdef", LogIndex: uint(i), Timestamp: time.Now()})
+		assert.NoError(t, err)
+	}
+
+	// Wait for the background goroutine to pick up both events before flushing
+	assert.Eventually(t, func() bool { return len(batchProcessor.eventsChan) == 0 }, time.Second, 5*time.Millisecond)
+	batchProcessor.Flush()
+
+	assert.Eventually(t, func() bool { return batchProcessor.Stats().Flushes == 1 }, time.Second, 5*time.Millisecond)
+
+	stats := batchProcessor.Stats()
+	assert.Equal(t, int64(0), stats.BufferSize)
+	assert.Equal(t, int64(2), stats.FlushedEvents)
+	assert.Greater(t, stats.LastFlushDuration, time.Duration(0))
+
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.BatchBufferSize))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.BatchFlushesTotal))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.BatchFlushedEventsTotal))
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(m.BatchFlushDuration)
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	if assert.Len(t, families, 1) {
+		assert.Equal(t, uint64(1), families[0].GetMetric()[0].GetHistogram().GetSampleCount())
+	}
+}
+
+func TestBatchProcessor_RecordLatency(t *testing.T) {
+	m := newBatchMetrics()
+	batchProcessor := &BatchProcessor{metrics: m}
+
+	// Mined 45s and received 2s before being stored, a backfilled event has no receipt time
+	storedAt := time.Date(2023, 11, 1, 12, 0, 0, 0, time.UTC)
+	batchProcessor.recordLatency([]*types.IndexedEvent{
+		{TxHash: "0xabcdef", LogIndex: 0, Timestamp: storedAt.Add(-45 * time.Second), ReceivedAt: storedAt.Add(-2 * time.Second)},
+		{TxHash: "0xabcdef", LogIndex: 1, Timestamp: storedAt.Add(-45 * time.Second)},
+	}, storedAt)
+
+	if count := cumulativeCount(t, m.EventChainLatency, 30); count != 0 {
+		t.Errorf("Expected no chain latency up to 30s, got %d", count)
+	}
+	if count := cumulativeCount(t, m.EventChainLatency, 60); count != 1 {
+		t.Errorf("Expected 1 chain latency up to 60s, got %d", count)
+	}
+	if count := cumulativeCount(t, m.EventProcessingLatency, 1); count != 0 {
+		t.Errorf("Expected no processing latency up to 1s, got %d", count)
+	}
+	if count := cumulativeCount(t, m.EventProcessingLatency, 2.5); count != 1 {
+		t.Errorf("Expected 1 processing latency up to 2.5s, got %d", count)
+	}
+}
+
+func TestBatchProcessor_FailedFlushMetrics(t *testing.T) {
+	db := newDryRunDatabase(t)
+	err := db.DB.Callback().Create().Before("gorm:create").Register("test:fail", func(tx *gorm.DB) {
+		tx.AddError(errors.New("insert failed"))
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	m := newBatchMetrics()
+	batchProcessor := NewBatchProcessor(db, 10, time.Hour, m)
+
+	for i := 0; i < 2; i++ {
+		err := batchProcessor.AddEvent(&types.IndexedEvent{TxHash: "0xabcdef", LogIndex: uint(i), Timestamp: time.Now()})
+		assert.NoError(t, err)
+	}
+	// Close flushes the buffered events and waits for the flush to finish
+	assert.NoError(t, batchProcessor.Close())
+
+	stats := batchProcessor.Stats()
+	assert.Equal(t, int64(0), stats.BufferSize)
+	assert.Equal(t, int64(0), stats.Flushes)
+	assert.Equal(t, int64(0), stats.FlushedEvents)
+
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.BatchBufferSize))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.BatchFlushesTotal))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.BatchFlushedEventsTotal))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.ErrorsTotal.WithLabelValues("batch_processor", "flush")))
+}
+
+func TestBatchProcessor_FailedInsertBatchMetrics(t *testing.T) {
+	db := newDryRunDatabase(t)
+	err := db.DB.Callback().Create().Before("gorm:create").Register("test:fail", func(tx *gorm.DB) {
+		tx.AddError(errors.New("insert failed"))
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	m := newBatchMetrics()
+	batchProcessor := NewBatchProcessor(db, 10, time.Hour, m)
+	defer batchProcessor.Close()
+
+	inserted, err := batchProcessor.InsertBatch([]*types.IndexedEvent{{TxHash: "0xabcdef", Timestamp: time.Now()}}, false)
+	assert.Error(t, err)
+	assert.Equal(t, int64(0), inserted)
+
+	stats := batchProcessor.Stats()
+	assert.Equal(t, int64(0), stats.Flushes)
+	assert.Equal(t, int64(0), stats.FlushedEvents)
+
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.BatchFlushesTotal))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.BatchFlushedEventsTotal))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.ErrorsTotal.WithLabelValues("batch_processor", "insert")))
+}
+
+func TestBatchProcessor_CloseFlushesQueuedEvents(t *testing.T) {
+	batchProcessor := NewBatchProcessor(newDryRunDatabase(t), 100, time.Hour, nil)
+	for i := 0; i < 25; i++ {
+		err := batchProcessor.AddEvent(&types.IndexedEvent{TxHash: "0xabcdef", LogIndex: uint(i), Timestamp: time.Now()})
+		assert.NoError(t, err)
+	}
+
+	// Events still queued when the processor is closed are flushed as well
+	assert.NoError(t, batchProcessor.Close())
+	stats := batchProcessor.Stats()
+	assert.Equal(t, int64(25), stats.FlushedEvents)
+	assert.Equal(t, int64(0), stats.BufferSize)
+}
+
+func TestBatchProcessor_FlushesOnBufferedBytes(t *testing.T) {
+	batchProcessor := NewBatchProcessor(newDryRunDatabase(t), 100, time.Hour, nil)
+	defer batchProcessor.Close()
+	batchProcessor.SetMaxBufferedBytes(64 * 1024)
+
+	// Each event carries about 16KB of decoded data, far below the count threshold
+	payload := strings.Repeat("f", 16*1024)
+	for i := 0; i < 10; i++ {
+		event := &types.IndexedEvent{
+			TxHash:    "0xabcdef",
+			LogIndex:  uint(i),
+			Data:      map[string]interface{}{"data": payload},
+			Timestamp: time.Now(),
+		}
+		assert.NoError(t, batchProcessor.AddEvent(event))
+	}
+
+	// The memory limit is reached every 4 events, flushing twice with the rest buffered
+	assert.Eventually(t, func() bool {
+		return batchProcessor.Stats().FlushedEvents == 8
+	}, 5*time.Second, 10*time.Millisecond)
+	stats := batchProcessor.Stats()
+	assert.Equal(t, int64(2), stats.Flushes)
+	assert.Equal(t, int64(2), stats.BufferSize)
+	assert.Less(t, stats.BufferedBytes, int64(64*1024))
+	assert.Greater(t, stats.BufferedBytes, int64(2*16*1024))
+}
+
+func TestBatchProcessor_NoByteLimitByDefault(t *testing.T) {
+	batchProcessor := NewBatchProcessor(newDryRunDatabase(t), 100, time.Hour, nil)
+	defer batchProcessor.Close()
+
+	payload := strings.Repeat("f", 16*1024)
+	for i := 0; i < 10; i++ {
+		event := &types.IndexedEvent{TxHash: "0xabcdef", LogIndex: uint(i), Data: map[string]interface{}{"data": payload}, Timestamp: time.Now()}
+		assert.NoError(t, batchProcessor.AddEvent(event))
+	}
+
+	// Without a limit the events wait for the batch to fill
+	assert.Eventually(t, func() bool {
+		return batchProcessor.Stats().BufferedBytes > 10*16*1024
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(0), batchProcessor.Stats().Flushes)
+}
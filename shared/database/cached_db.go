@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"time"
@@ -18,7 +19,8 @@ type CachedDatabase struct {
 	Cache *cache.Cache
 }
 
-// NewCachedDatabase creates a new database instance with caching
+// NewCachedDatabase creates a new database instance with caching.
+// A nil cache is allowed: every read then misses and goes to the database.
 func NewCachedDatabase(dsn string, cache *cache.Cache) (*CachedDatabase, error) {
 	db, err := NewDatabase(dsn)
 	if err != nil {
@@ -31,16 +33,59 @@ func NewCachedDatabase(dsn string, cache *cache.Cache) (*CachedDatabase, error)
 	}, nil
 }
 
+// errCacheDisabled is returned by cacheGet when no cache is configured, so reads fall through to the database
+var errCacheDisabled = errors.New("cache disabled")
+
+// cacheGet reads a cached value, treating a missing cache as a miss
+func (cd *CachedDatabase) cacheGet(ctx context.Context, key string, dest interface{}) error {
+	if cd.Cache == nil {
+		return errCacheDisabled
+	}
+	return cd.Cache.Get(ctx, key, dest)
+}
+
+// cacheSet caches a value, doing nothing when no cache is configured
+func (cd *CachedDatabase) cacheSet(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	if cd.Cache == nil {
+		return nil
+	}
+	return cd.Cache.Set(ctx, key, value, expiration)
+}
+
+// cacheDelete removes a cached value, doing nothing when no cache is configured
+func (cd *CachedDatabase) cacheDelete(ctx context.Context, key string) error {
+	if cd.Cache == nil {
+		return nil
+	}
+	return cd.Cache.Delete(ctx, key)
+}
+
+// epochKey prefixes the key of data a chain reorg can make stale with the current
+// reorg epoch, so entries cached before a reorg are missed after it
+func (cd *CachedDatabase) epochKey(ctx context.Context, key string) (string, error) {
+	if cd.Cache == nil {
+		return key, nil
+	}
+	epoch, err := cd.Cache.ReorgEpoch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read reorg epoch: %v", err)
+	}
+	return fmt.Sprintf("epoch:%d:%s", epoch, key), nil
+}
+
 // GetEventByTxHash retrieves an event by transaction hash with caching
 func (cd *CachedDatabase) GetEventByTxHash(txHash string) (*types.IndexedEvent, error) {
 	ctx := context.Background()
-	cacheKey := fmt.Sprintf("event:tx_hash:%s", txHash)
+	// Keyed by reorg epoch; the cache is skipped if the epoch cannot be read
+	cacheKey, keyErr := cd.epochKey(ctx, fmt.Sprintf("event:tx_hash:%s", txHash))
 
 	// Try to get from cache first
 	var event types.IndexedEvent
-	err := cd.Cache.Get(ctx, cacheKey, &event)
-	if err == nil {
-		return &event, nil
+	if keyErr == nil {
+		if err := cd.cacheGet(ctx, cacheKey, &event); err == nil {
+			event.UpgradeSchema()
+			return &event, nil
+		}
 	}
 
 	// Cache miss, get from database
@@ -49,10 +94,10 @@ func (cd *CachedDatabase) GetEventByTxHash(txHash string) (*types.IndexedEvent,
 		return nil, err
 	}
 
-	if dbEvent != nil {
+	if dbEvent != nil && keyErr == nil {
 		// Cache the result for 5 minutes
 		go func() {
-			if err := cd.Cache.Set(ctx, cacheKey, dbEvent, 5*time.Minute); err != nil {
+			if err := cd.cacheSet(ctx, cacheKey, dbEvent, 5*time.Minute); err != nil {
 				// Log error but don't fail the operation
 				fmt.Printf("Error caching event: %v\n", err)
 			}
@@ -69,7 +114,7 @@ func (cd *CachedDatabase) GetContractByAddress(address string) (*types.Contract,
 
 	// Try to get from cache first
 	var contract types.Contract
-	err := cd.Cache.Get(ctx, cacheKey, &contract)
+	err := cd.cacheGet(ctx, cacheKey, &contract)
 	if err == nil {
 		return &contract, nil
 	}
@@ -83,7 +128,7 @@ func (cd *CachedDatabase) GetContractByAddress(address string) (*types.Contract,
 	if dbContract != nil {
 		// Cache the result for 10 minutes
 		go func() {
-			if err := cd.Cache.Set(ctx, cacheKey, dbContract, 10*time.Minute); err != nil {
+			if err := cd.cacheSet(ctx, cacheKey, dbContract, 10*time.Minute); err != nil {
 				// Log error but don't fail the operation
 				fmt.Printf("Error caching contract: %v\n", err)
 			}
@@ -100,7 +145,7 @@ func (cd *CachedDatabase) GetStats() (*types.Stats, error) {
 
 	// Try to get from cache first
 	var stats types.Stats
-	err := cd.Cache.Get(ctx, cacheKey, &stats)
+	err := cd.cacheGet(ctx, cacheKey, &stats)
 	if err == nil {
 		return &stats, nil
 	}
@@ -113,7 +158,7 @@ func (cd *CachedDatabase) GetStats() (*types.Stats, error) {
 
 	// Cache the result for 1 minute (shorter for stats as they change frequently)
 	go func() {
-		if err := cd.Cache.Set(ctx, cacheKey, dbStats, 1*time.Minute); err != nil {
+		if err := cd.cacheSet(ctx, cacheKey, dbStats, 1*time.Minute); err != nil {
 			// Log error but don't fail the operation
 			fmt.Printf("Error caching stats: %v\n", err)
 		}
@@ -132,13 +177,18 @@ func (cd *CachedDatabase) GetEvents(limitNum, offset int) ([]types.IndexedEvent,
 // GetEventsByBlockNumber retrieves events by block number with caching
 func (cd *CachedDatabase) GetEventsByBlockNumber(blockNumber int64) ([]types.IndexedEvent, error) {
 	ctx := context.Background()
-	cacheKey := fmt.Sprintf("events:block_number:%d", blockNumber)
+	// Keyed by reorg epoch; the cache is skipped if the epoch cannot be read
+	cacheKey, keyErr := cd.epochKey(ctx, fmt.Sprintf("events:block_number:%d", blockNumber))
 
 	// Try to get from cache first
 	var events []types.IndexedEvent
-	err := cd.Cache.Get(ctx, cacheKey, &events)
-	if err == nil {
-		return events, nil
+	if keyErr == nil {
+		if err := cd.cacheGet(ctx, cacheKey, &events); err == nil {
+			for i := range events {
+				events[i].UpgradeSchema()
+			}
+			return events, nil
+		}
 	}
 
 	// Cache miss, get from database
@@ -147,10 +197,10 @@ func (cd *CachedDatabase) GetEventsByBlockNumber(blockNumber int64) ([]types.Ind
 		return nil, err
 	}
 
-	if len(dbEvents) > 0 {
+	if len(dbEvents) > 0 && keyErr == nil {
 		// Cache the result for 2 minutes
 		go func() {
-			if err := cd.Cache.Set(ctx, cacheKey, dbEvents, 2*time.Minute); err != nil {
+			if err := cd.cacheSet(ctx, cacheKey, dbEvents, 2*time.Minute); err != nil {
 				// Log error but don't fail the operation
 				fmt.Printf("Error caching events by block: %v\n", err)
 			}
@@ -163,13 +213,16 @@ func (cd *CachedDatabase) GetEventsByBlockNumber(blockNumber int64) ([]types.Ind
 // GetLatestBlockProcessed retrieves the latest processed block with caching
 func (cd *CachedDatabase) GetLatestBlockProcessed() (*types.IndexedEvent, error) {
 	ctx := context.Background()
-	cacheKey := "event:latest_block"
+	// Keyed by reorg epoch; the cache is skipped if the epoch cannot be read
+	cacheKey, keyErr := cd.epochKey(ctx, "event:latest_block")
 
 	// Try to get from cache first
 	var event types.IndexedEvent
-	err := cd.Cache.Get(ctx, cacheKey, &event)
-	if err == nil {
-		return &event, nil
+	if keyErr == nil {
+		if err := cd.cacheGet(ctx, cacheKey, &event); err == nil {
+			event.UpgradeSchema()
+			return &event, nil
+		}
 	}
 
 	// Cache miss, get from database
@@ -178,10 +231,10 @@ func (cd *CachedDatabase) GetLatestBlockProcessed() (*types.IndexedEvent, error)
 		return nil, err
 	}
 
-	if dbEvent != nil {
+	if dbEvent != nil && keyErr == nil {
 		// Cache for 30 seconds (since this changes frequently)
 		go func() {
-			if err := cd.Cache.Set(ctx, cacheKey, dbEvent, 30*time.Second); err != nil {
+			if err := cd.cacheSet(ctx, cacheKey, dbEvent, 30*time.Second); err != nil {
 				// Log error but don't fail the operation
 				fmt.Printf("Error caching latest block: %v\n", err)
 			}
@@ -197,10 +250,11 @@ func (cd *CachedDatabase) GetLastProcessedBlock() (*big.Int, error) {
 	cacheKey := "block:last_processed"
 
 	// Try to get from cache first
-	var blockNumber big.Int
-	err := cd.Cache.Get(ctx, cacheKey, &blockNumber)
-	if err == nil {
-		return &blockNumber, nil
+	if cd.Cache != nil {
+		blockNumber, err := cd.Cache.GetBigInt(ctx, cacheKey)
+		if err == nil {
+			return blockNumber, nil
+		}
 	}
 
 	// Cache miss, get from database
@@ -208,14 +262,19 @@ func (cd *CachedDatabase) GetLastProcessedBlock() (*big.Int, error) {
 	if err != nil {
 		return nil, err
 	}
+	if dbBlock == nil {
+		return nil, nil
+	}
 
 	// Cache for 1 minute
-	go func() {
-		if err := cd.Cache.Set(ctx, cacheKey, dbBlock, 1*time.Minute); err != nil {
-			// Log error but don't fail the operation
-			fmt.Printf("Error caching last processed block: %v\n", err)
-		}
-	}()
+	if cd.Cache != nil {
+		go func() {
+			if err := cd.Cache.SetBigInt(ctx, cacheKey, dbBlock, 1*time.Minute); err != nil {
+				// Log error but don't fail the operation
+				fmt.Printf("Error caching last processed block: %v\n", err)
+			}
+		}()
+	}
 
 	return dbBlock, nil
 }
@@ -223,31 +282,115 @@ func (cd *CachedDatabase) GetLastProcessedBlock() (*big.Int, error) {
 // InvalidateEventCache removes cached event data
 func (cd *CachedDatabase) InvalidateEventCache(txHash string) error {
 	ctx := context.Background()
-	cacheKey := fmt.Sprintf("event:tx_hash:%s", txHash)
-	return cd.Cache.Delete(ctx, cacheKey)
+	cacheKey, err := cd.epochKey(ctx, fmt.Sprintf("event:tx_hash:%s", txHash))
+	if err != nil {
+		return err
+	}
+	return cd.cacheDelete(ctx, cacheKey)
 }
 
 // InvalidateContractCache removes cached contract data
 func (cd *CachedDatabase) InvalidateContractCache(address string) error {
 	ctx := context.Background()
 	cacheKey := fmt.Sprintf("contract:address:%s", address)
-	return cd.Cache.Delete(ctx, cacheKey)
+	return cd.cacheDelete(ctx, cacheKey)
 }
 
 // InvalidateBlockCache removes cached block data
 func (cd *CachedDatabase) InvalidateBlockCache(blockNumber int64) error {
 	ctx := context.Background()
-	cacheKey := fmt.Sprintf("events:block_number:%d", blockNumber)
-	return cd.Cache.Delete(ctx, cacheKey)
+	cacheKey, err := cd.epochKey(ctx, fmt.Sprintf("events:block_number:%d", blockNumber))
+	if err != nil {
+		return err
+	}
+	return cd.cacheDelete(ctx, cacheKey)
 }
 
-// All other database methods that don't need caching just pass through to the underlying DB
+// InvalidateStatsCache removes cached statistics
+func (cd *CachedDatabase) InvalidateStatsCache() error {
+	ctx := context.Background()
+	return cd.cacheDelete(ctx, "stats:overview")
+}
+
+// SaveEvent saves an event and invalidates the caches it makes stale
 func (cd *CachedDatabase) SaveEvent(event *types.IndexedEvent) error {
-	return cd.DB.SaveEvent(event)
+	err := cd.DB.SaveEvent(event)
+	if err == nil {
+		// Invalidate asynchronously so writes stay fast
+		go func() {
+			if event.BlockNumber != nil {
+				if err := cd.InvalidateBlockCache(event.BlockNumber.Int64()); err != nil {
+					fmt.Printf("Error invalidating block cache: %v\n", err)
+				}
+			}
+			if err := cd.InvalidateStatsCache(); err != nil {
+				fmt.Printf("Error invalidating stats cache: %v\n", err)
+			}
+		}()
+	}
+	return err
+}
+
+// InvalidateStoredEvents removes the cached block events and statistics made stale by
+// storing events. It returns right away, so it can run as the batch processor's flush hook.
+func (cd *CachedDatabase) InvalidateStoredEvents(events []*types.IndexedEvent) {
+	if len(events) == 0 {
+		return
+	}
+	blocks := make(map[int64]bool)
+	for _, event := range events {
+		if event.BlockNumber != nil {
+			blocks[event.BlockNumber.Int64()] = true
+		}
+	}
+
+	go func() {
+		for blockNumber := range blocks {
+			if err := cd.InvalidateBlockCache(blockNumber); err != nil {
+				fmt.Printf("Error invalidating block cache: %v\n", err)
+			}
+		}
+		if err := cd.InvalidateStatsCache(); err != nil {
+			fmt.Printf("Error invalidating stats cache: %v\n", err)
+		}
+	}()
+}
+
+// ReplayEvents replays stored events through transform and invalidates the caches of the events it changed
+func (cd *CachedDatabase) ReplayEvents(ctx context.Context, contract string, fromBlock, toBlock *big.Int, transform types.EventTransform) (*types.ReplayResult, error) {
+	blocks := make(map[int64]bool)
+	txHashes := make(map[string]bool)
+
+	result, err := cd.DB.ReplayEvents(ctx, contract, fromBlock, toBlock, func(event *types.IndexedEvent) (bool, error) {
+		changed, err := transform(event)
+		if changed {
+			if event.BlockNumber != nil {
+				blocks[event.BlockNumber.Int64()] = true
+			}
+			txHashes[event.TxHash] = true
+		}
+		return changed, err
+	})
+
+	// Invalidate even after a partial replay, since earlier batches were saved
+	for blockNumber := range blocks {
+		if err := cd.InvalidateBlockCache(blockNumber); err != nil {
+			fmt.Printf("Error invalidating block cache: %v\n", err)
+		}
+	}
+	for txHash := range txHashes {
+		if err := cd.InvalidateEventCache(txHash); err != nil {
+			fmt.Printf("Error invalidating event cache: %v\n", err)
+		}
+	}
+
+	return result, err
 }
 
-func (cd *CachedDatabase) SaveContract(contract *types.Contract) error {
-	err := cd.DB.SaveContract(contract)
+// All other database methods that don't need caching just pass through to the underlying DB
+
+func (cd *CachedDatabase) SaveContract(ctx context.Context, contract *types.Contract) error {
+	err := cd.DB.SaveContract(ctx, contract)
 	if err == nil {
 		// Invalidate the contract cache when saving
 		go func() {
@@ -259,10 +402,41 @@ func (cd *CachedDatabase) SaveContract(contract *types.Contract) error {
 	return err
 }
 
+// SetContractActive pauses or resumes a contract and drops its cached copy
+func (cd *CachedDatabase) SetContractActive(address string, active bool) (bool, error) {
+	found, err := cd.DB.SetContractActive(address, active)
+	if err == nil && found {
+		if err := cd.InvalidateContractCache(address); err != nil {
+			fmt.Printf("Error invalidating contract cache: %v\n", err)
+		}
+	}
+	return found, err
+}
+
 func (cd *CachedDatabase) GetEvents(filter *types.EventFilter) ([]types.IndexedEvent, error) {
 	return cd.DB.GetEvents(filter)
 }
 
+func (cd *CachedDatabase) GetEventsByTxHash(txHash string) ([]types.IndexedEvent, error) {
+	return cd.DB.GetEventsByTxHash(txHash)
+}
+
+func (cd *CachedDatabase) GetAddressActivity(address string, limitNum, offset int) ([]types.AddressActivity, error) {
+	return cd.DB.GetAddressActivity(address, limitNum, offset)
+}
+
+func (cd *CachedDatabase) GetBalances(address, contract string) ([]types.TokenBalance, error) {
+	return cd.DB.GetBalances(address, contract)
+}
+
+func (cd *CachedDatabase) GetLatestEvents(limitNum int) ([]types.IndexedEvent, error) {
+	return cd.DB.GetLatestEvents(limitNum)
+}
+
+func (cd *CachedDatabase) GetEventsAfterID(afterID uint, limitNum int) ([]types.IndexedEvent, error) {
+	return cd.DB.GetEventsAfterID(afterID, limitNum)
+}
+
 func (cd *CachedDatabase) GetEventByID(id uint) (*types.IndexedEvent, error) {
 	// For GetEventByID, we could implement caching, but for now we'll just pass through
 	return cd.DB.GetEventByID(id)
@@ -282,7 +456,7 @@ func (cd *CachedDatabase) SaveLastProcessedBlock(blockNum *big.Int) error {
 	if err == nil {
 		// Invalidate the last processed block cache
 		go func() {
-			if err := cd.Cache.Delete(context.Background(), "block:last_processed"); err != nil {
+			if err := cd.cacheDelete(context.Background(), "block:last_processed"); err != nil {
 				fmt.Printf("Error invalidating last processed block cache: %v\n", err)
 			}
 		}()
@@ -295,7 +469,7 @@ func (cd *CachedDatabase) UpdateLastProcessedBlockWithHash(blockNum *big.Int, bl
 	if err == nil {
 		// Invalidate the last processed block cache
 		go func() {
-			if err := cd.Cache.Delete(context.Background(), "block:last_processed"); err != nil {
+			if err := cd.cacheDelete(context.Background(), "block:last_processed"); err != nil {
 				fmt.Printf("Error invalidating last processed block cache: %v\n", err)
 			}
 		}()
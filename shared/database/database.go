@@ -2,7 +2,9 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"math/big"
+	"strings"
 	"time"
 
 	"gorm.io/driver/postgres"
@@ -14,6 +16,24 @@ import (
 
 type Database struct {
 	DB *gorm.DB
+	// DedupKeyer selects the unique index that rejects duplicate events, nil uses
+	// types.DefaultDedupKeyer
+	DedupKeyer types.DedupKeyer
+	// IDGenerator assigns the IDs of inserted events that have none, nil leaves them to
+	// the auto-increment. Switch to generated IDs only on an empty events table, as
+	// generated IDs may collide with the sequence.
+	IDGenerator types.EventIDGenerator
+	// ABIFetcher fetches the ABI of contracts registered without one, nil registers them
+	// without an ABI
+	ABIFetcher ABIFetcher
+	// ContractHook is called with every saved contract, e.g. to register its ABI with the
+	// decoder of the running indexer, nil calls nothing
+	ContractHook func(contract *types.Contract)
+}
+
+// ABIFetcher returns the JSON ABI of a verified contract, such as explorer.Client
+type ABIFetcher interface {
+	FetchABI(ctx context.Context, address string) (string, error)
 }
 
 // DB is an alias for Database to maintain compatibility
@@ -26,7 +46,7 @@ func NewDatabase(dsn string) (*Database, error) {
 	}
 
 	// Migrate the schema
-	err = db.AutoMigrate(&types.IndexedEvent{}, &types.LastProcessedBlock{}, &types.ProcessedEvent{}, &types.Contract{})
+	err = db.AutoMigrate(&types.IndexedEvent{}, &types.LastProcessedBlock{}, &types.ProcessedEvent{}, &types.Contract{}, &types.BlockHeader{}, &types.BackfillProgress{}, &types.BackfillRange{})
 	if err != nil {
 		return nil, err
 	}
@@ -36,12 +56,137 @@ func NewDatabase(dsn string) (*Database, error) {
 	}, nil
 }
 
+// numericValue is the stored decimal value of an event as a NUMERIC, which holds
+// values beyond int64, or NULL for events without a numeric value such as NFT
+// transfers. The CASE keeps the cast from failing on those rows.
+const numericValue = "CASE WHEN value ~ '^[0-9]+$' THEN CAST(value AS NUMERIC) END"
+
+// Orderings of event queries. Events of one block follow their on-chain log order, with
+// the ID breaking ties between events that share a log index, so repeated queries
+// return a block's events in the same order.
+const (
+	eventOrderAsc  = "block_number ASC, log_index ASC, id ASC"
+	eventOrderDesc = "block_number DESC, log_index DESC, id DESC"
+)
+
 func (d *Database) SaveEvent(event *types.IndexedEvent) error {
+	d.assignIDs(event)
 	return d.DB.Create(event).Error
 }
 
-func (d *Database) SaveContract(contract *types.Contract) error {
-	return d.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(contract).Error
+// assignIDs sets the generated ID of events without one
+func (d *Database) assignIDs(events ...*types.IndexedEvent) {
+	if d.IDGenerator == nil {
+		return
+	}
+	for _, event := range events {
+		if event.ID == 0 {
+			event.ID = d.IDGenerator.ID(event)
+		}
+	}
+}
+
+// eventConflict is the insert conflict clause skipping duplicate events: the ID when IDs
+// are generated, then the unique index matching the dedup key when there is one,
+// otherwise any unique index
+func (d *Database) eventConflict() clause.OnConflict {
+	if d.IDGenerator != nil {
+		return clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoNothing: true}
+	}
+
+	keyer := d.DedupKeyer
+	if keyer == nil {
+		keyer = types.DefaultDedupKeyer
+	}
+
+	var columns []clause.Column
+	for _, name := range keyer.UniqueColumns() {
+		columns = append(columns, clause.Column{Name: name})
+	}
+	return clause.OnConflict{Columns: columns, DoNothing: true}
+}
+
+// forcedImportColumns are overwritten when a forced import replaces a stored event. The
+// stored event keeps its ID, creation time and block timestamp, like replayed events, so
+// a re-import cannot move an event in time.
+var forcedImportColumns = []string{
+	"block_number", "tx_hash", "log_index", "event_name", "topic0", "contract", "from", "to",
+	"token_id", "value", "data", "updated_at",
+}
+
+// eventUpsert is the insert conflict clause of forced imports, overwriting duplicate
+// events instead of skipping them. It needs a conflict target, so it fails for dedup keys
+// no unique index matches.
+func (d *Database) eventUpsert() (clause.OnConflict, error) {
+	conflict := d.eventConflict()
+	if len(conflict.Columns) == 0 {
+		return conflict, fmt.Errorf("forced imports need a unique index matching the dedup key")
+	}
+	conflict.DoNothing = false
+	conflict.DoUpdates = clause.AssignmentColumns(forcedImportColumns)
+	return conflict, nil
+}
+
+// lastOfEachKey drops all but the last of the events sharing a dedup key, since one
+// upsert cannot update the same row twice
+func (d *Database) lastOfEachKey(events []*types.IndexedEvent) []*types.IndexedEvent {
+	keyer := d.DedupKeyer
+	if keyer == nil {
+		keyer = types.DefaultDedupKeyer
+	}
+
+	last := make(map[string]int, len(events))
+	for i, event := range events {
+		last[keyer.Key(event)] = i
+	}
+	unique := make([]*types.IndexedEvent, 0, len(last))
+	for i, event := range events {
+		if last[keyer.Key(event)] == i {
+			unique = append(unique, event)
+		}
+	}
+	return unique
+}
+
+// SaveEventIfNotExists inserts event unless a duplicate is already stored, relying on
+// the unique index selected by the dedup keyer. It reports whether the event was inserted.
+func (d *Database) SaveEventIfNotExists(event *types.IndexedEvent) (bool, error) {
+	d.assignIDs(event)
+	result := d.DB.Clauses(d.eventConflict()).Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SaveContract registers a contract, storing its type in the canonical spelling. Contracts
+// of an unknown type are rejected; an empty type is stored as unknown. A contract without
+// an ABI gets the one ABIFetcher returns; if it has none, e.g. for an unverified contract,
+// the contract is registered without an ABI and its logs are decoded with the generic one.
+// The saved contract is passed to ContractHook.
+func (d *Database) SaveContract(ctx context.Context, contract *types.Contract) error {
+	if contract.Type != "" {
+		contractType, err := types.ParseContractType(string(contract.Type))
+		if err != nil {
+			return fmt.Errorf("invalid contract %s: %v", contract.Address, err)
+		}
+		contract.Type = contractType
+	}
+	if contract.ABI == "" && d.ABIFetcher != nil {
+		contractABI, err := d.ABIFetcher.FetchABI(ctx, contract.Address)
+		if err != nil {
+			fmt.Printf("Registering contract %s without an ABI: %v\n", contract.Address, err)
+		} else {
+			contract.ABI = contractABI
+		}
+	}
+	if err := d.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(contract).Error; err != nil {
+		return err
+	}
+	if d.ContractHook != nil {
+		d.ContractHook(contract)
+	}
+	return nil
 }
 
 func (d *Database) GetEvents(filter *types.EventFilter) ([]types.IndexedEvent, error) {
@@ -64,6 +209,27 @@ func (d *Database) GetEvents(filter *types.EventFilter) ([]types.IndexedEvent, e
 		query = query.Where("block_number <= ?", filter.ToBlock)
 	}
 
+	if filter.TokenID != "" {
+		query = query.Where("token_id = ?", filter.TokenID)
+	}
+
+	// Both bounds use idx_events_timestamp, and combine with the block range when given
+	if filter.FromTime != nil {
+		query = query.Where("timestamp >= ?", *filter.FromTime)
+	}
+
+	if filter.ToTime != nil {
+		query = query.Where("timestamp <= ?", *filter.ToTime)
+	}
+
+	if filter.MinValue != nil {
+		query = query.Where(numericValue+" >= CAST(? AS NUMERIC)", filter.MinValue.String())
+	}
+
+	if filter.MaxValue != nil {
+		query = query.Where(numericValue+" <= CAST(? AS NUMERIC)", filter.MaxValue.String())
+	}
+
 	if filter.Limit > 0 {
 		query = query.Limit(filter.Limit)
 	}
@@ -72,7 +238,12 @@ func (d *Database) GetEvents(filter *types.EventFilter) ([]types.IndexedEvent, e
 		query = query.Offset(filter.Offset)
 	}
 
-	query = query.Order("block_number DESC, created_at DESC")
+	// Only load the selected columns, the other fields are left empty
+	if len(filter.Fields) > 0 {
+		query = query.Select(types.EventColumns(filter.Fields))
+	}
+
+	query = query.Order(eventOrderDesc)
 
 	err := query.Find(&events).Error
 	return events, err
@@ -108,10 +279,107 @@ func (d *Database) GetLastProcessedBlock() (*big.Int, error) {
 
 func (d *Database) GetEvents(limitNum, offset int) ([]types.IndexedEvent, error) {
 	var events []types.IndexedEvent
-	err := d.DB.Limit(limitNum).Offset(offset).Order("block_number DESC, created_at DESC").Find(&events).Error
+	err := d.DB.Limit(limitNum).Offset(offset).Order(eventOrderDesc).Find(&events).Error
+	return events, err
+}
+
+// GetEventsBefore returns up to limitNum events listed after cursor in the order of
+// GetEvents, newest first. Paging by cursor is as fast deep into the events as on the
+// first page and is not shifted by events indexed in between, unlike paging by offset.
+func (d *Database) GetEventsBefore(cursor types.EventCursor, limitNum int) ([]types.IndexedEvent, error) {
+	var events []types.IndexedEvent
+	err := d.DB.Where("(block_number, log_index, id) < (?, ?, ?)", cursor.BlockNumber, cursor.LogIndex, cursor.ID).
+		Limit(limitNum).Order(eventOrderDesc).Find(&events).Error
+	return events, err
+}
+
+// GetEventsByTopic0 returns events of any contract whose signature hash is topic0,
+// optionally bounded by block range; a nil bound is open
+func (d *Database) GetEventsByTopic0(topic0 string, fromBlock, toBlock *big.Int, limitNum, offset int) ([]types.IndexedEvent, error) {
+	var events []types.IndexedEvent
+	query := d.DB.Where("topic0 = ?", strings.ToLower(topic0))
+
+	if fromBlock != nil {
+		query = query.Where("block_number >= ?", fromBlock)
+	}
+
+	if toBlock != nil {
+		query = query.Where("block_number <= ?", toBlock)
+	}
+
+	if limitNum > 0 {
+		query = query.Limit(limitNum)
+	}
+
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	err := query.Order(eventOrderDesc).Find(&events).Error
+	return events, err
+}
+
+// GetLatestEvents returns the limitNum most recently indexed events, newest first
+func (d *Database) GetLatestEvents(limitNum int) ([]types.IndexedEvent, error) {
+	var events []types.IndexedEvent
+	err := d.DB.Order("id DESC").Limit(limitNum).Find(&events).Error
 	return events, err
 }
 
+// GetEventsAfterID returns up to limitNum events indexed after the event with afterID,
+// oldest first, so a caller can page through new events by passing the last ID it saw
+func (d *Database) GetEventsAfterID(afterID uint, limitNum int) ([]types.IndexedEvent, error) {
+	var events []types.IndexedEvent
+	err := d.DB.Where("id > ?", afterID).Order("id ASC").Limit(limitNum).Find(&events).Error
+	return events, err
+}
+
+// GetAddressActivity returns the events sent or received by address, newest first, each
+// with its direction relative to address. The from and to indexes serve the two sides
+// of the OR.
+func (d *Database) GetAddressActivity(address string, limitNum, offset int) ([]types.AddressActivity, error) {
+	var events []types.IndexedEvent
+	query := d.DB.Where(`"from" = ? OR "to" = ?`, address, address)
+
+	if limitNum > 0 {
+		query = query.Limit(limitNum)
+	}
+
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	if err := query.Order(eventOrderDesc).Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	activity := make([]types.AddressActivity, len(events))
+	for i := range events {
+		activity[i] = types.AddressActivity{
+			IndexedEvent: events[i],
+			Direction:    types.EventDirection(&events[i], address),
+		}
+	}
+	return activity, nil
+}
+
+// GetBalances returns the non-zero token balances of address, of contract only when it
+// is not empty. They are computed on demand from the stored transfers of the address, so
+// they always agree with the stored events, including after reorg rollbacks.
+func (d *Database) GetBalances(address, contract string) ([]types.TokenBalance, error) {
+	var transfers []types.IndexedEvent
+	query := d.DB.Where(`("from" = ? OR "to" = ?) AND event_name IN ?`, address, address,
+		[]string{types.NFTTransferEventName, types.TokenTransferEventName})
+	if contract != "" {
+		query = query.Where("contract = ?", contract)
+	}
+
+	if err := query.Order(eventOrderAsc).Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+	return types.ComputeBalances(address, transfers), nil
+}
+
 func (d *Database) GetEventByTxHash(txHash string) (*types.IndexedEvent, error) {
 	var event types.IndexedEvent
 	err := d.DB.Where("tx_hash = ?", txHash).First(&event).Error
@@ -124,12 +392,47 @@ func (d *Database) GetEventByTxHash(txHash string) (*types.IndexedEvent, error)
 	return &event, nil
 }
 
+// GetEventByTxHashAndLogIndex returns the event emitted by the log at logIndex of a transaction,
+// or nil if it is not stored
+func (d *Database) GetEventByTxHashAndLogIndex(txHash string, logIndex uint) (*types.IndexedEvent, error) {
+	var event types.IndexedEvent
+	err := d.DB.Where("tx_hash = ? AND log_index = ?", txHash, logIndex).First(&event).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+// GetEventsByTxHash returns every event emitted by a transaction, ordered by log index
+func (d *Database) GetEventsByTxHash(txHash string) ([]types.IndexedEvent, error) {
+	var events []types.IndexedEvent
+	err := d.DB.Where("tx_hash = ?", txHash).Order("log_index ASC").Find(&events).Error
+	return events, err
+}
+
 func (d *Database) GetEventsByBlockNumber(blockNumber int64) ([]types.IndexedEvent, error) {
 	var events []types.IndexedEvent
-	err := d.DB.Where("block_number = ?", blockNumber).Find(&events).Error
+	err := d.DB.Where("block_number = ?", blockNumber).Order(eventOrderAsc).Find(&events).Error
 	return events, err
 }
 
+func (d *Database) GetArchivedEventsByBlockNumber(blockNumber int64) ([]types.IndexedEvent, error) {
+	var archived []types.ArchivedEvent
+	err := d.DB.Where("block_number = ?", blockNumber).Order(eventOrderAsc).Find(&archived).Error
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]types.IndexedEvent, len(archived))
+	for i, event := range archived {
+		events[i] = event.IndexedEvent
+	}
+	return events, nil
+}
+
 func (d *Database) GetContracts() ([]types.Contract, error) {
 	var contracts []types.Contract
 	err := d.DB.Find(&contracts).Error
@@ -148,6 +451,18 @@ func (d *Database) GetContractByAddress(address string) (*types.Contract, error)
 	return &contract, nil
 }
 
+// SetContractActive pauses or resumes the indexing of a contract, matching its address
+// case-insensitively. It reports whether the contract is registered.
+func (d *Database) SetContractActive(address string, active bool) (bool, error) {
+	result := d.DB.Model(&types.Contract{}).
+		Where("LOWER(address) = LOWER(?)", address).
+		Updates(map[string]interface{}{"active": active, "updated_at": time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
 func (d *Database) GetStats() (*types.Stats, error) {
 	var stats types.Stats
 	
@@ -244,10 +559,114 @@ func (d *Database) DeleteProcessedEventsFromBlock(blockNumber *big.Int) error {
 	return d.DB.Where("block_number >= ?", blockNumber).Delete(&types.ProcessedEvent{}).Error
 }
 
+// SaveBlockHeader stores the hash of a processed block, replacing the hash stored for
+// its height
+func (d *Database) SaveBlockHeader(blockNumber uint64, blockHash string) error {
+	header := types.BlockHeader{BlockNumber: blockNumber, BlockHash: blockHash, CreatedAt: time.Now()}
+	return d.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "block_number"}},
+		DoUpdates: clause.AssignmentColumns([]string{"block_hash", "created_at"}),
+	}).Create(&header).Error
+}
+
+// GetBlockHeaders returns the stored block headers from fromBlock on, newest first
+func (d *Database) GetBlockHeaders(fromBlock uint64) ([]types.BlockHeader, error) {
+	var headers []types.BlockHeader
+	err := d.DB.Where("block_number >= ?", fromBlock).
+		Order("block_number DESC").
+		Find(&headers).Error
+	return headers, err
+}
+
+// PruneBlockHeaders deletes the block headers below blockNumber
+func (d *Database) PruneBlockHeaders(blockNumber uint64) error {
+	return d.DB.Where("block_number < ?", blockNumber).Delete(&types.BlockHeader{}).Error
+}
+
+// DeleteBlockHeadersFromBlock deletes the block headers from blockNumber on, which a
+// rollback has made stale
+func (d *Database) DeleteBlockHeadersFromBlock(blockNumber uint64) error {
+	return d.DB.Where("block_number >= ?", blockNumber).Delete(&types.BlockHeader{}).Error
+}
+
+// GetBackfillProgress returns the highest block up to which the backfill of contract on
+// chainID completed, and false if its backfill has not completed any block
+func (d *Database) GetBackfillProgress(contract, chainID string) (uint64, bool, error) {
+	var progress types.BackfillProgress
+	err := d.DB.Where("contract = ? AND chain_id = ?", strings.ToLower(contract), chainID).First(&progress).Error
+	if err == gorm.ErrRecordNotFound {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return progress.BlockNumber, true, nil
+}
+
+// SaveBackfillProgress records that the backfill of contract on chainID completed up to
+// blockNumber. Progress only moves forward, so re-running an earlier range keeps it.
+func (d *Database) SaveBackfillProgress(contract, chainID string, blockNumber uint64) error {
+	progress := types.BackfillProgress{
+		Contract:    strings.ToLower(contract),
+		ChainID:     chainID,
+		BlockNumber: blockNumber,
+		UpdatedAt:   time.Now(),
+	}
+	return d.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "contract"}, {Name: "chain_id"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "block_number"}, Value: gorm.Expr("GREATEST(backfill_progress.block_number, EXCLUDED.block_number)")},
+			{Column: clause.Column{Name: "updated_at"}, Value: progress.UpdatedAt},
+		},
+	}).Create(&progress).Error
+}
+
+// GetBackfillRanges returns the completed backfill ranges of contract on chainID, ordered
+// by their first block. Saved ranges are merged, so a contract has few of them.
+func (d *Database) GetBackfillRanges(contract, chainID string) ([]types.BackfillRange, error) {
+	var ranges []types.BackfillRange
+	err := d.DB.Where("contract = ? AND chain_id = ?", strings.ToLower(contract), chainID).
+		Order("from_block ASC").
+		Find(&ranges).Error
+	return ranges, err
+}
+
+// SaveBackfillRange records that the backfill of contract on chainID completed from fromBlock
+// to toBlock, merging the range with the stored ranges it overlaps or adjoins
+func (d *Database) SaveBackfillRange(contract, chainID string, fromBlock, toBlock uint64) error {
+	contract = strings.ToLower(contract)
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		var touching []types.BackfillRange
+		err := tx.Where("contract = ? AND chain_id = ? AND from_block <= ? AND to_block + 1 >= ?", contract, chainID, toBlock+1, fromBlock).
+			Find(&touching).Error
+		if err != nil {
+			return err
+		}
+
+		merged := types.BackfillRange{Contract: contract, ChainID: chainID, FromBlock: fromBlock, ToBlock: toBlock, UpdatedAt: time.Now()}
+		ids := make([]uint, 0, len(touching))
+		for _, r := range touching {
+			if r.FromBlock < merged.FromBlock {
+				merged.FromBlock = r.FromBlock
+			}
+			if r.ToBlock > merged.ToBlock {
+				merged.ToBlock = r.ToBlock
+			}
+			ids = append(ids, r.ID)
+		}
+		if len(ids) > 0 {
+			if err := tx.Delete(&types.BackfillRange{}, ids).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&merged).Error
+	})
+}
+
 func (d *Database) GetEventsByBlockRange(fromBlock, toBlock *big.Int) ([]types.IndexedEvent, error) {
 	var events []types.IndexedEvent
 	err := d.DB.Where("block_number >= ? AND block_number <= ?", fromBlock, toBlock).
-		Order("block_number ASC").
+		Order(eventOrderAsc).
 		Find(&events).Error
 	return events, err
 }
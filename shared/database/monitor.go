@@ -0,0 +1,232 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"chainpulse/shared/clock"
+
+	"gorm.io/gorm"
+)
+
+// DefaultConnectionCheckInterval is how often the connection monitor pings the database
+const DefaultConnectionCheckInterval = 5 * time.Second
+
+// defaultMaxIdleConns is the idle connection limit of a database/sql pool, restored
+// after the monitor drops the idle connections
+const defaultMaxIdleConns = 2
+
+// ErrDatabaseUnavailable is returned for statements run while the database is down.
+// It is retriable: the connection monitor restores the pool once the database is back.
+var ErrDatabaseUnavailable = errors.New("database unavailable")
+
+// connectionPool is the part of *sql.DB the connection monitor uses
+type connectionPool interface {
+	PingContext(ctx context.Context) error
+	SetMaxIdleConns(n int)
+}
+
+var _ connectionPool = (*sql.DB)(nil)
+
+// ConnectionMonitor pings the database in the background. When a ping fails, or a
+// statement fails with a connection error, the database is marked down: statements
+// fail fast with ErrDatabaseUnavailable instead of waiting on dead connections, and the
+// idle connections are dropped so the pool reconnects. The database is marked up again
+// once a ping succeeds. The monitor is a GORM plugin; register it with Use.
+type ConnectionMonitor struct {
+	// Interval is how often the database is pinged, <= 0 uses DefaultConnectionCheckInterval
+	Interval time.Duration
+	// Logf logs losing and recovering the connection, nil uses fmt.Printf
+	Logf func(format string, args ...interface{})
+	// Clock times the pings, nil uses the system time
+	Clock clock.Clock
+
+	pool  connectionPool
+	check chan struct{}
+
+	mu        sync.Mutex
+	down      bool
+	downSince time.Time
+}
+
+// NewConnectionMonitor creates a monitor of pool pinging it every interval
+func NewConnectionMonitor(pool connectionPool, interval time.Duration) *ConnectionMonitor {
+	return &ConnectionMonitor{
+		Interval: interval,
+		pool:     pool,
+		check:    make(chan struct{}, 1),
+	}
+}
+
+// Name implements gorm.Plugin
+func (m *ConnectionMonitor) Name() string {
+	return "connection_monitor"
+}
+
+// Initialize implements gorm.Plugin, failing statements fast while the database is down
+// and reporting connection errors of the others
+func (m *ConnectionMonitor) Initialize(db *gorm.DB) error {
+	type registrar interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}
+
+	cb := db.Callback()
+	hooks := []struct {
+		operation     string
+		before, after registrar
+	}{
+		{"create", cb.Create().Before("*"), cb.Create().After("*")},
+		{"query", cb.Query().Before("*"), cb.Query().After("*")},
+		{"update", cb.Update().Before("*"), cb.Update().After("*")},
+		{"delete", cb.Delete().Before("*"), cb.Delete().After("*")},
+		{"row", cb.Row().Before("*"), cb.Row().After("*")},
+		{"raw", cb.Raw().Before("*"), cb.Raw().After("*")},
+	}
+
+	for _, hook := range hooks {
+		if err := hook.before.Register("connection_monitor:before_"+hook.operation, m.failFast); err != nil {
+			return err
+		}
+		if err := hook.after.Register("connection_monitor:after_"+hook.operation, m.reportError); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *ConnectionMonitor) failFast(db *gorm.DB) {
+	if !m.Healthy() {
+		db.AddError(ErrDatabaseUnavailable)
+	}
+}
+
+func (m *ConnectionMonitor) reportError(db *gorm.DB) {
+	if db.Error == nil || errors.Is(db.Error, ErrDatabaseUnavailable) || !isConnectionError(db.Error) {
+		return
+	}
+	db.Error = fmt.Errorf("%w: %v", ErrDatabaseUnavailable, db.Error)
+
+	// Check right away instead of waiting for the next ping
+	select {
+	case m.check <- struct{}{}:
+	default:
+	}
+}
+
+// Healthy reports whether the last ping succeeded
+func (m *ConnectionMonitor) Healthy() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.down
+}
+
+// Run pings the database every Interval, and after a statement failed with a connection
+// error, until ctx is done
+func (m *ConnectionMonitor) Run(ctx context.Context) {
+	ticker := clock.OrReal(m.Clock).NewTicker(m.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		case <-m.check:
+		}
+		m.ping(ctx)
+	}
+}
+
+// interval returns the ping interval, DefaultConnectionCheckInterval if none is set
+func (m *ConnectionMonitor) interval() time.Duration {
+	if m.Interval <= 0 {
+		return DefaultConnectionCheckInterval
+	}
+	return m.Interval
+}
+
+// ping checks the connection, marking the database down or up when it changed
+func (m *ConnectionMonitor) ping(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, m.interval())
+	err := m.pool.PingContext(pingCtx)
+	cancel()
+	if ctx.Err() != nil {
+		return
+	}
+
+	now := clock.OrReal(m.Clock).Now()
+	m.mu.Lock()
+	wasDown, downSince := m.down, m.downSince
+	if err != nil && !wasDown {
+		m.down, m.downSince = true, now
+	}
+	if err == nil {
+		m.down = false
+	}
+	m.mu.Unlock()
+
+	switch {
+	case err != nil:
+		if !wasDown {
+			m.logf("Database connection lost, reconnecting: %v\n", err)
+		}
+		// Drop the idle connections, which died with the server, so the pool dials new ones
+		m.pool.SetMaxIdleConns(0)
+		m.pool.SetMaxIdleConns(defaultMaxIdleConns)
+	case wasDown:
+		m.logf("Database connection recovered after %v\n", now.Sub(downSince))
+	}
+}
+
+func (m *ConnectionMonitor) logf(format string, args ...interface{}) {
+	if m.Logf != nil {
+		m.Logf(format, args...)
+	} else {
+		fmt.Printf(format, args...)
+	}
+}
+
+// isConnectionError reports whether err means the connection to the database was lost
+// rather than that the statement failed
+func isConnectionError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, fragment := range []string{"connection refused", "connection reset", "broken pipe", "terminating connection", "the database system is shutting down", "the database system is starting up"} {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// MonitorConnection pings d every interval until ctx is done, see ConnectionMonitor.
+// Losing and recovering the connection is logged with logf, nil for fmt.Printf.
+func (d *Database) MonitorConnection(ctx context.Context, interval time.Duration, logf func(format string, args ...interface{})) (*ConnectionMonitor, error) {
+	pool, err := d.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	monitor := NewConnectionMonitor(pool, interval)
+	monitor.Logf = logf
+	if err := d.DB.Use(monitor); err != nil {
+		return nil, err
+	}
+	go monitor.Run(ctx)
+	return monitor, nil
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"chainpulse/shared/clock"
+	"chainpulse/shared/types"
+
+	"gorm.io/gorm"
+)
+
+// fakePool fails its pings with err and counts the times its idle connections are dropped
+type fakePool struct {
+	mu      sync.Mutex
+	err     error
+	dropped int
+}
+
+func (p *fakePool) PingContext(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *fakePool) SetMaxIdleConns(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n == 0 {
+		p.dropped++
+	}
+}
+
+func (p *fakePool) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// waitFor fails the test unless cond holds within a second
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConnectionMonitor_RecoversAfterConnectionDrop(t *testing.T) {
+	db := newDryRunDatabase(t)
+	pool := &fakePool{}
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var mu sync.Mutex
+	var logged []string
+	monitor := NewConnectionMonitor(pool, time.Second)
+	monitor.Clock = fake
+	monitor.Logf = func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+	if err := db.DB.Use(monitor); err != nil {
+		t.Fatalf("Failed to register connection monitor: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.Run(ctx)
+	waitFor(t, "the monitor to start its ticker", func() bool { return fake.Tickers() == 1 })
+
+	// The server restarts: pings fail until it is back
+	pool.setErr(errors.New("dial tcp 127.0.0.1:5432: connect: connection refused"))
+	fake.Advance(time.Second)
+	waitFor(t, "the database to be marked down", func() bool { return !monitor.Healthy() })
+
+	var contracts []types.Contract
+	if err := db.DB.Find(&contracts).Error; !errors.Is(err, ErrDatabaseUnavailable) {
+		t.Errorf("Expected ErrDatabaseUnavailable while down, got %v", err)
+	}
+
+	pool.setErr(nil)
+	fake.Advance(time.Second)
+	waitFor(t, "the database to recover", monitor.Healthy)
+
+	if err := db.DB.Find(&contracts).Error; err != nil {
+		t.Errorf("Expected queries to succeed after recovery, got %v", err)
+	}
+
+	pool.mu.Lock()
+	dropped := pool.dropped
+	pool.mu.Unlock()
+	if dropped == 0 {
+		t.Error("Expected the idle connections to be dropped")
+	}
+
+	waitFor(t, "the recovery to be logged", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(logged) == 2
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(logged[0], "connection lost") || !strings.Contains(logged[1], "recovered after 1s") {
+		t.Errorf("Expected the loss and recovery to be logged once each, got %q", logged)
+	}
+}
+
+func TestConnectionMonitor_ReportsConnectionErrors(t *testing.T) {
+	db := newDryRunDatabase(t)
+	monitor := NewConnectionMonitor(&fakePool{}, time.Second)
+	if err := db.DB.Use(monitor); err != nil {
+		t.Fatalf("Failed to register connection monitor: %v", err)
+	}
+
+	// Stand in for a connection that died with the server
+	err := db.DB.Callback().Query().After("gorm:query").Register("test:bad_conn", func(tx *gorm.DB) {
+		tx.AddError(driver.ErrBadConn)
+	})
+	if err != nil {
+		t.Fatalf("Failed to register failing callback: %v", err)
+	}
+
+	var contracts []types.Contract
+	if err := db.DB.Find(&contracts).Error; !errors.Is(err, ErrDatabaseUnavailable) {
+		t.Errorf("Expected the connection error as ErrDatabaseUnavailable, got %v", err)
+	}
+	if len(monitor.check) != 1 {
+		t.Error("Expected the connection error to trigger a check")
+	}
+}
+
+func TestConnectionMonitor_DefaultsUnsetInterval(t *testing.T) {
+	pool := &fakePool{err: errors.New("connection refused")}
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	monitor := &ConnectionMonitor{pool: pool, check: make(chan struct{}, 1), Clock: fake, Logf: func(string, ...interface{}) {}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.Run(ctx)
+	waitFor(t, "the monitor to start its ticker", func() bool { return fake.Tickers() == 1 })
+
+	fake.Advance(DefaultConnectionCheckInterval)
+	waitFor(t, "the database to be pinged after the default interval", func() bool { return !monitor.Healthy() })
+}
+
+func TestIsConnectionError(t *testing.T) {
+	for _, err := range []error{driver.ErrBadConn, errors.New("FATAL: terminating connection due to administrator command (SQLSTATE 57P01)"), fmt.Errorf("query: %w", errors.New("write: broken pipe"))} {
+		if !isConnectionError(err) {
+			t.Errorf("Expected %v to be a connection error", err)
+		}
+	}
+	if isConnectionError(errors.New(`ERROR: relation "events" does not exist (SQLSTATE 42P01)`)) {
+		t.Error("Expected a statement error not to be a connection error")
+	}
+}
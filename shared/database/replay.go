@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"gorm.io/gorm"
+
+	"chainpulse/shared/types"
+)
+
+// replayBatchSize is the number of stored events loaded per replay query
+const replayBatchSize = 500
+
+// ReplayEvents runs transform over the stored events of contract within [fromBlock, toBlock]
+// and saves the events it changed. It only reads the database, never the node.
+// Events are visited in ID order, so an event is transformed at most once per replay.
+// Replays are deterministic: a saved event keeps its block timestamp and creation time,
+// only its update time is bumped.
+func (d *Database) ReplayEvents(ctx context.Context, contract string, fromBlock, toBlock *big.Int, transform types.EventTransform) (*types.ReplayResult, error) {
+	query := d.DB.WithContext(ctx).Model(&types.IndexedEvent{}).
+		Where("LOWER(contract) = LOWER(?)", contract).
+		Where("block_number >= ? AND block_number <= ?", fromBlock, toBlock)
+
+	result := &types.ReplayResult{}
+	var lastID uint
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		var events []types.IndexedEvent
+		err := query.Session(&gorm.Session{}).Where("id > ?", lastID).Order("id ASC").Limit(replayBatchSize).Find(&events).Error
+		if err != nil {
+			return result, fmt.Errorf("failed to load events to replay: %v", err)
+		}
+		if len(events) == 0 {
+			return result, nil
+		}
+
+		for i := range events {
+			event := &events[i]
+			lastID = event.ID
+			result.Scanned++
+
+			changed, err := replayEvent(event, transform, time.Now())
+			if err != nil {
+				return result, fmt.Errorf("failed to transform event %d: %v", event.ID, err)
+			}
+			if !changed {
+				continue
+			}
+
+			if err := d.DB.WithContext(ctx).Save(event).Error; err != nil {
+				return result, fmt.Errorf("failed to save replayed event %d: %v", event.ID, err)
+			}
+			result.Updated++
+		}
+	}
+}
+
+// replayEvent runs transform over event. When the event changed, the block timestamp
+// and creation time it was stored with are restored, so a transform cannot move the
+// event in time, and its update time is set to now.
+func replayEvent(event *types.IndexedEvent, transform types.EventTransform, now time.Time) (bool, error) {
+	timestamp, createdAt := event.Timestamp, event.CreatedAt
+
+	changed, err := transform(event)
+	if err != nil || !changed {
+		return changed, err
+	}
+
+	event.Timestamp = timestamp
+	event.CreatedAt = createdAt
+	event.UpdatedAt = now
+	return true, nil
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"gorm.io/gorm"
+
+	"chainpulse/shared/types"
+)
+
+// RetentionPolicy defines when events are moved out of the hot table
+type RetentionPolicy struct {
+	MaxAge        time.Duration // archive events older than this, 0 disables
+	MaxBlockDepth int64         // archive events this many blocks behind the latest, 0 disables
+	BatchSize     int
+}
+
+// Enabled reports whether the policy has any retention boundary configured
+func (p RetentionPolicy) Enabled() bool {
+	return p.MaxAge > 0 || p.MaxBlockDepth > 0
+}
+
+// RetentionManager archives and deletes events past the retention boundary
+type RetentionManager struct {
+	db       *Database
+	archiver Archiver
+	policy   RetentionPolicy
+}
+
+// NewRetentionManager creates a new retention manager
+func NewRetentionManager(db *Database, archiver Archiver, policy RetentionPolicy) *RetentionManager {
+	if policy.BatchSize <= 0 {
+		policy.BatchSize = 1000
+	}
+
+	return &RetentionManager{
+		db:       db,
+		archiver: archiver,
+		policy:   policy,
+	}
+}
+
+// RunOnce archives every event past the retention boundary and removes it from the hot table.
+// It returns the number of archived events.
+func (rm *RetentionManager) RunOnce(ctx context.Context) (int64, error) {
+	if !rm.policy.Enabled() {
+		return 0, nil
+	}
+
+	query := rm.db.DB.WithContext(ctx).Model(&types.IndexedEvent{})
+
+	var cutoffTime time.Time
+	if rm.policy.MaxAge > 0 {
+		cutoffTime = time.Now().Add(-rm.policy.MaxAge)
+	}
+
+	var cutoffBlock *big.Int
+	if rm.policy.MaxBlockDepth > 0 {
+		stats, err := rm.db.GetStats()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get latest block: %v", err)
+		}
+		cutoffBlock = big.NewInt(stats.LatestBlock - rm.policy.MaxBlockDepth)
+	}
+
+	switch {
+	case !cutoffTime.IsZero() && cutoffBlock != nil:
+		query = query.Where("timestamp < ? OR block_number < ?", cutoffTime, cutoffBlock)
+	case !cutoffTime.IsZero():
+		query = query.Where("timestamp < ?", cutoffTime)
+	default:
+		query = query.Where("block_number < ?", cutoffBlock)
+	}
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		var events []types.IndexedEvent
+		if err := query.Session(&gorm.Session{}).Order("id ASC").Limit(rm.policy.BatchSize).Find(&events).Error; err != nil {
+			return total, fmt.Errorf("failed to load events to archive: %v", err)
+		}
+		if len(events) == 0 {
+			return total, nil
+		}
+
+		if err := rm.archive(ctx, events); err != nil {
+			return total, err
+		}
+
+		total += int64(len(events))
+	}
+}
+
+// archive archives events and deletes them from the hot table. An archiver writing to
+// the events database does both in one transaction; any other archiver archives first,
+// so events are only deleted once archived, and archives them again on the next run if
+// deleting them fails.
+func (rm *RetentionManager) archive(ctx context.Context, events []types.IndexedEvent) error {
+	ids := make([]uint, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	deleteArchived := func(tx *gorm.DB) error {
+		if err := tx.Where("id IN ?", ids).Delete(&types.IndexedEvent{}).Error; err != nil {
+			return fmt.Errorf("failed to delete archived events: %v", err)
+		}
+		return nil
+	}
+
+	if txArchiver, ok := rm.archiver.(TxArchiver); ok {
+		return rm.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := txArchiver.ArchiveTx(tx, events); err != nil {
+				return fmt.Errorf("failed to archive events with %s archiver: %v", rm.archiver.Name(), err)
+			}
+			return deleteArchived(tx)
+		})
+	}
+
+	if err := rm.archiver.Archive(ctx, events); err != nil {
+		return fmt.Errorf("failed to archive events with %s archiver: %v", rm.archiver.Name(), err)
+	}
+	return deleteArchived(rm.db.DB.WithContext(ctx))
+}
+
+// Start runs the retention policy periodically until the context is cancelled
+func (rm *RetentionManager) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			archived, err := rm.RunOnce(ctx)
+			if err != nil {
+				fmt.Printf("Retention run failed: %v\n", err)
+				continue
+			}
+			if archived > 0 {
+				fmt.Printf("Archived %d events with %s archiver\n", archived, rm.archiver.Name())
+			}
+		}
+	}
+}
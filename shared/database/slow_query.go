@@ -0,0 +1,102 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"chainpulse/shared/metrics"
+
+	"gorm.io/gorm"
+)
+
+// queryStartKey holds the statement start time between the before and after callbacks
+const queryStartKey = "slow_query:start"
+
+// SlowQueryLogger is a GORM plugin timing every statement. Durations are recorded in
+// the database query duration histogram by operation and table, and statements
+// slower than Threshold are logged with their SQL.
+type SlowQueryLogger struct {
+	Threshold time.Duration // 0 disables logging, durations are still recorded
+	Metrics   *metrics.Metrics
+	// Logf prints slow queries, nil uses fmt.Printf
+	Logf func(format string, args ...interface{})
+}
+
+// NewSlowQueryLogger creates a slow query logger; m may be nil to only log
+func NewSlowQueryLogger(threshold time.Duration, m *metrics.Metrics) *SlowQueryLogger {
+	return &SlowQueryLogger{Threshold: threshold, Metrics: m}
+}
+
+// Name implements gorm.Plugin
+func (l *SlowQueryLogger) Name() string {
+	return "slow_query"
+}
+
+// Initialize implements gorm.Plugin, timing each kind of statement from before its
+// first callback to after its last one
+func (l *SlowQueryLogger) Initialize(db *gorm.DB) error {
+	type registrar interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}
+
+	cb := db.Callback()
+	hooks := []struct {
+		operation     string
+		before, after registrar
+	}{
+		{"create", cb.Create().Before("*"), cb.Create().After("*")},
+		{"query", cb.Query().Before("*"), cb.Query().After("*")},
+		{"update", cb.Update().Before("*"), cb.Update().After("*")},
+		{"delete", cb.Delete().Before("*"), cb.Delete().After("*")},
+		{"row", cb.Row().Before("*"), cb.Row().After("*")},
+		{"raw", cb.Raw().Before("*"), cb.Raw().After("*")},
+	}
+
+	for _, hook := range hooks {
+		if err := hook.before.Register("slow_query:before_"+hook.operation, l.start); err != nil {
+			return err
+		}
+		if err := hook.after.Register("slow_query:after_"+hook.operation, l.finish(hook.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *SlowQueryLogger) start(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+// finish records the duration of a statement of the given operation and logs it if slow
+func (l *SlowQueryLogger) finish(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		duration := time.Since(start)
+
+		if l.Metrics != nil {
+			l.Metrics.RecordDatabaseQueryDuration(operation, db.Statement.Table, duration.Seconds())
+		}
+
+		if l.Threshold > 0 && duration >= l.Threshold {
+			// Only the SQL with placeholders is logged, bound values may hold user data
+			format, args := "Slow %s query on %q took %v: %s\n", []interface{}{operation, db.Statement.Table, duration, db.Statement.SQL.String()}
+			if l.Logf != nil {
+				l.Logf(format, args...)
+			} else {
+				fmt.Printf(format, args...)
+			}
+		}
+	}
+}
+
+// EnableSlowQueryLogger times every statement run on d, see SlowQueryLogger
+func (d *Database) EnableSlowQueryLogger(threshold time.Duration, m *metrics.Metrics) error {
+	return d.DB.Use(NewSlowQueryLogger(threshold, m))
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"chainpulse/shared/metrics"
+	"chainpulse/shared/types"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+)
+
+func newQueryMetrics() *metrics.Metrics {
+	return &metrics.Metrics{
+		DatabaseQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_database_query_duration_seconds"}, []string{"query_type", "table"}),
+	}
+}
+
+// newSlowQueryDatabase returns a dry run database timed by a slow query logger whose
+// log lines are collected in the returned slice
+func newSlowQueryDatabase(t *testing.T, threshold time.Duration, m *metrics.Metrics) (*Database, *[]string) {
+	db := newDryRunDatabase(t)
+
+	var logged []string
+	logger := NewSlowQueryLogger(threshold, m)
+	logger.Logf = func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+	if err := db.DB.Use(logger); err != nil {
+		t.Fatalf("Failed to register slow query logger: %v", err)
+	}
+	return db, &logged
+}
+
+func TestSlowQueryLogger_LogsSlowQuery(t *testing.T) {
+	m := newQueryMetrics()
+	db, logged := newSlowQueryDatabase(t, 50*time.Millisecond, m)
+
+	// Stand in for a slow database: dry run statements are never sent
+	err := db.DB.Callback().Query().After("gorm:query").Register("test:slow", func(*gorm.DB) {
+		time.Sleep(100 * time.Millisecond)
+	})
+	if err != nil {
+		t.Fatalf("Failed to register slow callback: %v", err)
+	}
+
+	var contracts []types.Contract
+	if err := db.DB.Where("type = ?", "ERC20").Find(&contracts).Error; err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(*logged) != 1 {
+		t.Fatalf("Expected 1 slow query log line, got %d", len(*logged))
+	}
+	line := (*logged)[0]
+	if !strings.Contains(line, "SELECT") || !strings.Contains(line, "contracts") {
+		t.Errorf("Expected the log line to contain the SQL, got %q", line)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(m.DatabaseQueryDuration)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	if len(families) != 1 || len(families[0].GetMetric()) != 1 {
+		t.Fatalf("Expected 1 recorded query series, got %v", families)
+	}
+	series := families[0].GetMetric()[0]
+	labels := map[string]string{}
+	for _, label := range series.GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	if labels["query_type"] != "query" || labels["table"] != "contracts" {
+		t.Errorf("Expected query_type=query and table=contracts, got %v", labels)
+	}
+	histogram := series.GetHistogram()
+	if histogram.GetSampleCount() != 1 {
+		t.Errorf("Expected 1 recorded query, got %d", histogram.GetSampleCount())
+	}
+	if histogram.GetSampleSum() < 0.1 {
+		t.Errorf("Expected a recorded duration of at least 100ms, got %vs", histogram.GetSampleSum())
+	}
+}
+
+func TestSlowQueryLogger_FastQueryNotLogged(t *testing.T) {
+	m := newQueryMetrics()
+	db, logged := newSlowQueryDatabase(t, time.Second, m)
+
+	var contract types.Contract
+	if err := db.DB.Where("address = ?", "0xfast").Find(&contract).Error; err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(*logged) != 0 {
+		t.Errorf("Expected no slow query log lines, got %v", *logged)
+	}
+	if got := testutil.CollectAndCount(m.DatabaseQueryDuration); got != 1 {
+		t.Errorf("Expected fast queries to be recorded too, got %d series", got)
+	}
+}
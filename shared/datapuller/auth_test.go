@@ -0,0 +1,57 @@
+package datapuller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	plugins "chainpulse/shared/datapuller/plugins"
+)
+
+func TestHTTPPuller_Auth(t *testing.T) {
+	var received *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.Write([]byte(`{"number": 1}`))
+	}))
+	defer server.Close()
+
+	puller := NewHTTPPuller(&DataSourceConfig{
+		URL:  server.URL,
+		Auth: plugins.Auth{Type: plugins.AuthQuery, Name: "key", Value: "secret"},
+	})
+	defer puller.Close()
+
+	if _, err := puller.PullLatest(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if received.URL.Query().Get("key") != "secret" {
+		t.Errorf("Expected key secret, got %q", received.URL.Query().Get("key"))
+	}
+	if received.Header.Get("Authorization") != "" {
+		t.Errorf("Expected no Authorization header, got %q", received.Header.Get("Authorization"))
+	}
+}
+
+func TestHTTPPuller_APIKey(t *testing.T) {
+	var received *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.Write([]byte(`{"number": 1}`))
+	}))
+	defer server.Close()
+
+	puller := NewHTTPPuller(&DataSourceConfig{URL: server.URL, APIKey: "secret"})
+	defer puller.Close()
+
+	if _, err := puller.PullLatest(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if received.Header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("Expected Bearer secret, got %q", received.Header.Get("Authorization"))
+	}
+	if received.Header.Get("X-API-Key") != "secret" {
+		t.Errorf("Expected X-API-Key secret, got %q", received.Header.Get("X-API-Key"))
+	}
+}
@@ -4,9 +4,14 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/ethereum/go-ethereum/core/types"
+	"chainpulse/shared/types"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 )
 
 // BlockchainDataPuller 区块链数据拉取器
@@ -45,7 +50,7 @@ func (bdp *BlockchainDataPuller) GetGlobalMetrics() (int64, int64, int64, time.D
 }
 
 // PullBlocks 拉取区块数据
-func (bdp *BlockchainDataPuller) PullBlocks(ctx context.Context, startBlock, endBlock *big.Int) ([]*types.Block, error) {
+func (bdp *BlockchainDataPuller) PullBlocks(ctx context.Context, startBlock, endBlock *big.Int) ([]*ethtypes.Block, error) {
 	filters := map[string]interface{}{
 		"start_block": startBlock.String(),
 		"end_block":   endBlock.String(),
@@ -56,7 +61,7 @@ func (bdp *BlockchainDataPuller) PullBlocks(ctx context.Context, startBlock, end
 		return nil, err
 	}
 
-	blocks := make([]*types.Block, 0, len(data))
+	blocks := make([]*ethtypes.Block, 0, len(data))
 	for _, item := range data {
 		// 这里需要根据实际API返回的数据格式进行转换
 		// 由于类型转换复杂，我们返回一个错误提示
@@ -66,28 +71,6 @@ func (bdp *BlockchainDataPuller) PullBlocks(ctx context.Context, startBlock, end
 	return blocks, nil
 }
 
-// PullTransactions 拉取交易数据
-func (bdp *BlockchainDataPuller) PullTransactions(ctx context.Context, startBlock, endBlock *big.Int) ([]*types.Transaction, error) {
-	filters := map[string]interface{}{
-		"start_block": startBlock.String(),
-		"end_block":   endBlock.String(),
-		"data_type":   "transaction",
-	}
-
-	data, err := bdp.PullWithFilters(ctx, filters)
-	if err != nil {
-		return nil, err
-	}
-
-	transactions := make([]*types.Transaction, 0, len(data))
-	for _, item := range data {
-		// 这里需要根据实际API返回的数据格式进行转换
-		return nil, fmt.Errorf("transaction conversion not implemented for this data source")
-	}
-
-	return transactions, nil
-}
-
 // PullEvents 拉取事件数据
 func (bdp *BlockchainDataPuller) PullEvents(ctx context.Context, startBlock, endBlock *big.Int, eventType string) ([]interface{}, error) {
 	filters := map[string]interface{}{
@@ -119,7 +102,7 @@ func (bdp *BlockchainDataPuller) PullContractData(ctx context.Context, contractA
 }
 
 // PullRealTimeBlocks 实时拉取新区块
-func (bdp *BlockchainDataPuller) PullRealTimeBlocks(ctx context.Context, handler func(*types.Block) error) error {
+func (bdp *BlockchainDataPuller) PullRealTimeBlocks(ctx context.Context, handler func(*ethtypes.Block) error) error {
 	return bdp.PullRealTime(ctx, func(data interface{}) error {
 		// 这里需要将接口数据转换为区块类型
 		// 由于转换复杂，我们简单地将数据传递给处理函数
@@ -127,21 +110,13 @@ func (bdp *BlockchainDataPuller) PullRealTimeBlocks(ctx context.Context, handler
 	})
 }
 
-// PullRealTimeTransactions 实时拉取新交易
-func (bdp *BlockchainDataPuller) PullRealTimeTransactions(ctx context.Context, handler func(*types.Transaction) error) error {
-	return bdp.PullRealTime(ctx, func(data interface{}) error {
-		// 这里需要将接口数据转换为交易类型
-		return handler(nil) // 需要实际实现转换逻辑
-	})
-}
-
 // PullRealTimeEvents 实时拉取新事件
 func (bdp *BlockchainDataPuller) PullRealTimeEvents(ctx context.Context, handler func(interface{}) error) error {
 	return bdp.PullRealTime(ctx, handler)
 }
 
 // convertToBlock 将外部API数据转换为内部Block格式
-func convertToBlock(data map[string]interface{}) (*types.Block, error) {
+func convertToBlock(data map[string]interface{}) (*ethtypes.Block, error) {
 	// 从外部API数据中提取字段
 	// 不同的区块链API返回的格式可能不同，这里以常见的格式为例
 	blockNumberHex, ok := data["number"].(string)
@@ -170,8 +145,8 @@ func convertToBlock(data map[string]interface{}) (*types.Block, error) {
 	return nil, nil // 返回nil作为占位符，实际实现需要更复杂的处理
 }
 
-// convertToTransaction 将外部API数据转换为内部Transaction格式
-func convertToTransaction(data map[string]interface{}) (*types.Transaction, error) {
+// convertToTransaction 将JSON-RPC返回的交易对象转换为PulledTransaction，同时支持legacy和EIP-1559交易
+func convertToTransaction(data map[string]interface{}) (*types.PulledTransaction, error) {
 	// 从外部API数据中提取字段
 	txHash, ok := data["hash"].(string)
 	if !ok {
@@ -183,10 +158,85 @@ func convertToTransaction(data map[string]interface{}) (*types.Transaction, erro
 		return nil, fmt.Errorf("invalid transaction hash format")
 	}
 
-	// 注意：go-ethereum的types.Transaction不能直接构造
-	// 这里我们只是示意如何处理外部数据
+	tx := &types.PulledTransaction{
+		Hash: txHash,
+		Type: types.LegacyTxType,
+	}
 
-	return nil, nil // 返回nil作为占位符，实际实现需要更复杂的处理
+	// 旧节点不返回type字段，此时视为legacy交易
+	if _, exists := data["type"]; exists {
+		txType, err := hexUint64Field(data, "type")
+		if err != nil {
+			return nil, err
+		}
+		if txType > 0xff {
+			return nil, fmt.Errorf("invalid transaction type: %d", txType)
+		}
+		tx.Type = uint8(txType)
+	}
+
+	tx.From, _ = data["from"].(string)
+	tx.To, _ = data["to"].(string) // 合约创建交易的to为null
+	tx.Input, _ = data["input"].(string)
+
+	var err error
+	if tx.Value, err = hexBigField(data, "value"); err != nil {
+		return nil, err
+	}
+	if tx.Gas, err = hexUint64Field(data, "gas"); err != nil {
+		return nil, err
+	}
+	if tx.Nonce, err = hexUint64Field(data, "nonce"); err != nil {
+		return nil, err
+	}
+
+	// legacy交易必须携带gasPrice；EIP-1559交易的gasPrice是实际成交价格，待打包时可能缺失
+	if _, exists := data["gasPrice"]; exists || !tx.IsDynamicFee() {
+		if tx.GasPrice, err = hexBigField(data, "gasPrice"); err != nil {
+			return nil, err
+		}
+	}
+
+	if tx.IsDynamicFee() {
+		if tx.MaxFeePerGas, err = hexBigField(data, "maxFeePerGas"); err != nil {
+			return nil, err
+		}
+		if tx.MaxPriorityFeePerGas, err = hexBigField(data, "maxPriorityFeePerGas"); err != nil {
+			return nil, err
+		}
+	}
+
+	return tx, nil
+}
+
+// hexBigField 解析JSON-RPC中十六进制编码的数量字段
+func hexBigField(data map[string]interface{}, key string) (*big.Int, error) {
+	str, ok := data[key].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid %s", key)
+	}
+
+	value, err := hexutil.DecodeBig(str)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", key, err)
+	}
+
+	return value, nil
+}
+
+// hexUint64Field 解析JSON-RPC中十六进制编码的uint64字段
+func hexUint64Field(data map[string]interface{}, key string) (uint64, error) {
+	str, ok := data[key].(string)
+	if !ok {
+		return 0, fmt.Errorf("missing or invalid %s", key)
+	}
+
+	value, err := hexutil.DecodeUint64(str)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", key, err)
+	}
+
+	return value, nil
 }
 
 // convertToIndexedEvent 将外部API数据转换为内部IndexedEvent格式
@@ -231,6 +281,8 @@ func convertToIndexedEvent(data map[string]interface{}) (*types.IndexedEvent, er
 			} else {
 				return nil, fmt.Errorf("invalid event name")
 			}
+		} else if event, ok := data["event_name"].(string); ok {
+			eventName = event
 		} else {
 			eventName = "Unknown" // 默认事件名
 		}
@@ -274,11 +326,21 @@ func convertToIndexedEvent(data map[string]interface{}) (*types.IndexedEvent, er
 
 	// 处理时间戳
 	timestamp := time.Now()
-	if ts, exists := data["timeStamp"]; exists {
+	if ts, exists := data["timestamp"]; exists {
+		// RFC3339字符串或Unix秒数
+		if tsStr, ok := ts.(string); ok {
+			if parsed, err := time.Parse(time.RFC3339, tsStr); err == nil {
+				timestamp = parsed
+			}
+		} else if tsFloat, ok := ts.(float64); ok {
+			timestamp = time.Unix(int64(tsFloat), 0)
+		}
+	} else if ts, exists := data["timeStamp"]; exists {
+		// 区块浏览器API以十进制字符串返回Unix秒数
 		if tsStr, ok := ts.(string); ok {
 			if tsInt, err := time.Parse("2006-01-02T15:04:05Z", tsStr); err == nil {
 				timestamp = tsInt
-			} else if tsFloat, ok := ts.(float64); ok {
+			} else if tsFloat, err := strconv.ParseFloat(tsStr, 64); err == nil {
 				timestamp = time.Unix(int64(tsFloat), 0)
 			}
 		} else if tsFloat, ok := ts.(float64); ok {
@@ -316,6 +378,14 @@ func convertToIndexedEvent(data map[string]interface{}) (*types.IndexedEvent, er
 		}
 	}
 
+	// 事件签名哈希为第一个topic
+	topic0 := ""
+	if topics, ok := data["topics"].([]interface{}); ok && len(topics) > 0 {
+		if t, ok := topics[0].(string); ok {
+			topic0 = strings.ToLower(t)
+		}
+	}
+
 	value := ""
 	if v, exists := data["value"]; exists {
 		if vStr, ok := v.(string); ok {
@@ -329,6 +399,7 @@ func convertToIndexedEvent(data map[string]interface{}) (*types.IndexedEvent, er
 		BlockNumber: blockNumber,
 		TxHash:      txHash,
 		EventName:   eventName,
+		Topic0:      topic0,
 		Contract:    contractAddr,
 		From:        from,
 		To:          to,
@@ -341,7 +412,7 @@ func convertToIndexedEvent(data map[string]interface{}) (*types.IndexedEvent, er
 }
 
 // PullBlocks 拉取区块数据
-func (bdp *BlockchainDataPuller) PullBlocks(ctx context.Context, startBlock, endBlock *big.Int) ([]*types.Block, error) {
+func (bdp *BlockchainDataPuller) PullBlocks(ctx context.Context, startBlock, endBlock *big.Int) ([]*ethtypes.Block, error) {
 	filters := map[string]interface{}{
 		"start_block": startBlock.String(),
 		"end_block":   endBlock.String(),
@@ -355,7 +426,7 @@ func (bdp *BlockchainDataPuller) PullBlocks(ctx context.Context, startBlock, end
 	// 对于区块数据，我们实际上返回的是IndexedEvent格式
 	// 因为go-ethereum的types.Block不能直接构造
 	// 这里我们返回空切片，实际应用中应使用PullEvents方法
-	blocks := make([]*types.Block, 0, len(data))
+	blocks := make([]*ethtypes.Block, 0, len(data))
 	for _, item := range data {
 		// 外部API数据可能是map[string]interface{}格式
 		if blockData, ok := item.(map[string]interface{}); ok {
@@ -369,7 +440,7 @@ func (bdp *BlockchainDataPuller) PullBlocks(ctx context.Context, startBlock, end
 }
 
 // PullTransactions 拉取交易数据
-func (bdp *BlockchainDataPuller) PullTransactions(ctx context.Context, startBlock, endBlock *big.Int) ([]*types.Transaction, error) {
+func (bdp *BlockchainDataPuller) PullTransactions(ctx context.Context, startBlock, endBlock *big.Int) ([]*types.PulledTransaction, error) {
 	filters := map[string]interface{}{
 		"start_block": startBlock.String(),
 		"end_block":   endBlock.String(),
@@ -381,22 +452,26 @@ func (bdp *BlockchainDataPuller) PullTransactions(ctx context.Context, startBloc
 		return nil, err
 	}
 
-	// 对于交易数据，我们也返回IndexedEvent格式
-	transactions := make([]*types.Transaction, 0, len(data))
+	transactions := make([]*types.PulledTransaction, 0, len(data))
 	for _, item := range data {
-		// 外部API数据可能是map[string]interface{}格式
-		if txData, ok := item.(map[string]interface{}); ok {
-			// 这里只是示意，实际实现需要根据API返回格式处理
-			_ = txData
+		// 外部API数据应为JSON-RPC交易对象格式
+		txData, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected transaction data type: %T", item)
+		}
+
+		tx, err := convertToTransaction(txData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert transaction: %v", err)
 		}
-		// 由于不能直接构造types.Transaction，我们返回空
+		transactions = append(transactions, tx)
 	}
 
 	return transactions, nil
 }
 
 // PullRealTimeBlocks 实时拉取新区块
-func (bdp *BlockchainDataPuller) PullRealTimeBlocks(ctx context.Context, handler func(*types.Block) error) error {
+func (bdp *BlockchainDataPuller) PullRealTimeBlocks(ctx context.Context, handler func(*ethtypes.Block) error) error {
 	return bdp.PullRealTime(ctx, func(data interface{}) error {
 		// 这里需要将接口数据转换为区块类型
 		// 由于go-ethereum的types.Block不能直接构造，我们转换为IndexedEvent
@@ -410,14 +485,22 @@ func (bdp *BlockchainDataPuller) PullRealTimeBlocks(ctx context.Context, handler
 }
 
 // PullRealTimeTransactions 实时拉取新交易
-func (bdp *BlockchainDataPuller) PullRealTimeTransactions(ctx context.Context, handler func(*types.Transaction) error) error {
+func (bdp *BlockchainDataPuller) PullRealTimeTransactions(ctx context.Context, handler func(*types.PulledTransaction) error) error {
 	return bdp.PullRealTime(ctx, func(data interface{}) error {
-		// 这里需要将接口数据转换为交易类型
-		if txData, ok := data.(map[string]interface{}); ok {
-			// 虽然我们不能构造types.Transaction，但可以转换为IndexedEvent
-			_ = txData
+		txData, ok := data.(map[string]interface{})
+		if !ok {
+			fmt.Printf("Unexpected real-time transaction data type: %T\n", data)
+			return nil
 		}
-		return handler(nil) // 返回nil，因为无法构造Transaction
+
+		tx, err := convertToTransaction(txData)
+		if err != nil {
+			// 如果转换失败，记录错误但继续处理其他数据
+			fmt.Printf("Failed to convert external transaction data: %v\n", err)
+			return nil
+		}
+
+		return handler(tx)
 	})
 }
 
@@ -0,0 +1,148 @@
+package datapuller
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"chainpulse/shared/types"
+)
+
+func TestConvertToTransaction_Legacy(t *testing.T) {
+	data := map[string]interface{}{
+		"hash":     "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b",
+		"from":     "0xa7d9ddbe1f17865597fbd27ec712455208b6b76d",
+		"to":       "0xf02c1c8e6114b1dbe8937a39260b5b0a374432bb",
+		"value":    "0xf3dbb76162000",
+		"gas":      "0xc350",
+		"gasPrice": "0x4a817c800",
+		"nonce":    "0x15",
+		"input":    "0x68656c6c6f21",
+		"type":     "0x0",
+	}
+
+	tx, err := convertToTransaction(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if tx.Type != types.LegacyTxType {
+		t.Errorf("Expected type %d, got %d", types.LegacyTxType, tx.Type)
+	}
+	if tx.IsDynamicFee() {
+		t.Error("Expected legacy transaction not to be dynamic fee")
+	}
+	if tx.Gas != 50000 {
+		t.Errorf("Expected gas 50000, got %d", tx.Gas)
+	}
+	if tx.GasPrice == nil || tx.GasPrice.Cmp(big.NewInt(20000000000)) != 0 {
+		t.Errorf("Expected gas price 20000000000, got %v", tx.GasPrice)
+	}
+	if tx.MaxFeePerGas != nil || tx.MaxPriorityFeePerGas != nil {
+		t.Errorf("Expected no EIP-1559 fee fields, got %v and %v", tx.MaxFeePerGas, tx.MaxPriorityFeePerGas)
+	}
+	if tx.Value.Cmp(big.NewInt(4290000000000000)) != 0 {
+		t.Errorf("Expected value 4290000000000000, got %s", tx.Value)
+	}
+	if tx.Nonce != 21 {
+		t.Errorf("Expected nonce 21, got %d", tx.Nonce)
+	}
+	if tx.To != "0xf02c1c8e6114b1dbe8937a39260b5b0a374432bb" {
+		t.Errorf("Expected to address to be decoded, got %s", tx.To)
+	}
+	if tx.Input != "0x68656c6c6f21" {
+		t.Errorf("Expected input 0x68656c6c6f21, got %s", tx.Input)
+	}
+}
+
+func TestConvertToTransaction_DynamicFee(t *testing.T) {
+	data := map[string]interface{}{
+		"hash":                 "0x3f6a2bc9e4d87a4e7c6b0f24a4a7b3d5f0e8a9c1b2d3e4f5a6b7c8d9e0f1a2b3",
+		"from":                 "0xa7d9ddbe1f17865597fbd27ec712455208b6b76d",
+		"to":                   nil, // contract creation
+		"value":                "0x0",
+		"gas":                  "0x5208",
+		"gasPrice":             "0x3b9aca0e",
+		"maxFeePerGas":         "0x77359400",
+		"maxPriorityFeePerGas": "0x3b9aca00",
+		"nonce":                "0x1",
+		"input":                "0x",
+		"type":                 "0x2",
+	}
+
+	tx, err := convertToTransaction(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if tx.Type != types.DynamicFeeTxType {
+		t.Errorf("Expected type %d, got %d", types.DynamicFeeTxType, tx.Type)
+	}
+	if !tx.IsDynamicFee() {
+		t.Error("Expected type-2 transaction to be dynamic fee")
+	}
+	if tx.MaxFeePerGas == nil || tx.MaxFeePerGas.Cmp(big.NewInt(2000000000)) != 0 {
+		t.Errorf("Expected max fee per gas 2000000000, got %v", tx.MaxFeePerGas)
+	}
+	if tx.MaxPriorityFeePerGas == nil || tx.MaxPriorityFeePerGas.Cmp(big.NewInt(1000000000)) != 0 {
+		t.Errorf("Expected max priority fee per gas 1000000000, got %v", tx.MaxPriorityFeePerGas)
+	}
+	if tx.GasPrice == nil || tx.GasPrice.Cmp(big.NewInt(1000000014)) != 0 {
+		t.Errorf("Expected effective gas price 1000000014, got %v", tx.GasPrice)
+	}
+	if tx.Gas != 21000 {
+		t.Errorf("Expected gas 21000, got %d", tx.Gas)
+	}
+	if tx.To != "" {
+		t.Errorf("Expected empty to address for contract creation, got %s", tx.To)
+	}
+
+	// A type-2 transaction without fee caps is malformed
+	delete(data, "maxFeePerGas")
+	if _, err := convertToTransaction(data); err == nil {
+		t.Error("Expected error for type-2 transaction missing maxFeePerGas")
+	}
+}
+
+func TestConvertToIndexedEvent_EventNameAlias(t *testing.T) {
+	event, err := convertToIndexedEvent(map[string]interface{}{
+		"block_number": float64(100),
+		"txHash":       "0xabc",
+		"event_name":   "Transfer",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if event.EventName != "Transfer" {
+		t.Errorf("Expected event name Transfer, got %s", event.EventName)
+	}
+}
+
+func TestConvertToIndexedEvent_RFC3339Timestamp(t *testing.T) {
+	event, err := convertToIndexedEvent(map[string]interface{}{
+		"blockNumber":     "0x64",
+		"transactionHash": "0xabc",
+		"timestamp":       "2024-03-01T12:30:00+02:00",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+	if !event.Timestamp.Equal(expected) {
+		t.Errorf("Expected timestamp %v, got %v", expected, event.Timestamp)
+	}
+}
+
+func TestConvertToIndexedEvent_UnixTimeStampString(t *testing.T) {
+	event, err := convertToIndexedEvent(map[string]interface{}{
+		"blockNumber":     "100",
+		"transactionHash": "0xabc",
+		"timeStamp":       "1709296200",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if event.Timestamp.Unix() != 1709296200 {
+		t.Errorf("Expected timestamp 1709296200, got %d", event.Timestamp.Unix())
+	}
+}
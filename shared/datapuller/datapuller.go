@@ -3,6 +3,9 @@ package datapuller
 import (
 	"context"
 	"time"
+
+	plugins "chainpulse/shared/datapuller/plugins"
+	"chainpulse/shared/utils"
 )
 
 // DataPuller 定义数据拉取服务接口
@@ -31,11 +34,18 @@ type DataPuller interface {
 
 // DataSourceConfig 数据源配置
 type DataSourceConfig struct {
-	URL           string
-	APIKey        string
+	URL string
+	// APIKey 未配置 Auth 时使用的 API 密钥，同时作为 Bearer 令牌和 X-API-Key 请求头发送
+	APIKey string
+	// Auth 请求认证方式，零值不认证
+	Auth          plugins.Auth
 	Timeout       time.Duration
 	RetryAttempts int
 	RetryDelay    time.Duration
+	// HTTPPool HTTP 连接复用配置，零值使用默认值
+	HTTPPool utils.HTTPPoolConfig
+	// WSCompression WebSocket permessage-deflate 压缩配置，零值不压缩
+	WSCompression plugins.WSCompressionConfig
 }
 
 // DataType 数据类型枚举
@@ -0,0 +1,387 @@
+package datapuller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 端点池默认配置
+const (
+	DefaultEndpointFailureThreshold = 3                // 连续失败多少次后端点被视为不健康
+	DefaultEndpointCooldown         = 30 * time.Second // 不健康端点暂停使用的时长
+	endpointErrorRateAlpha          = 0.2              // 错误率指数移动平均的平滑系数
+)
+
+// EndpointStatus 端点的健康状态
+type EndpointStatus struct {
+	URL       string
+	Weight    int
+	ErrorRate float64 // 近期请求的错误率（指数移动平均）
+	Healthy   bool
+	Requests  int64
+	Failures  int64
+}
+
+// poolEndpoint 池中的单个端点
+type poolEndpoint struct {
+	url            string
+	weight         int
+	plugin         Plugin
+	currentWeight  float64 // 平滑加权轮询的当前权重
+	errorRate      float64
+	consecutive    int // 连续失败次数
+	unhealthyUntil time.Time
+	requests       int64
+	failures       int64
+}
+
+// EndpointPool 同一协议下多个端点的插件池。请求按权重在健康端点间平滑轮询，
+// 端点的有效权重随其错误率降低；连续失败达到阈值的端点在冷却期内不再被选中，
+// 单次请求失败时依次尝试其他端点，使不稳定的主端点自动让位于健康的备用端点。
+type EndpointPool struct {
+	protocol         string
+	factory          func() Plugin
+	name             string
+	endpoints        []*poolEndpoint
+	failureThreshold int
+	cooldown         time.Duration
+	mu               sync.Mutex
+	now              func() time.Time
+}
+
+// NewEndpointPool 创建端点池，factory 为每个端点创建插件
+func NewEndpointPool(protocol string, factory func() Plugin) *EndpointPool {
+	return &EndpointPool{
+		protocol:         protocol,
+		factory:          factory,
+		name:             protocol,
+		failureThreshold: DefaultEndpointFailureThreshold,
+		cooldown:         DefaultEndpointCooldown,
+		now:              time.Now,
+	}
+}
+
+// ParseEndpoints 解析逗号分隔的端点列表，每项为 "url" 或 "url=weight"，
+// 返回可作为协议配置中 endpoints 的端点配置
+func ParseEndpoints(spec string) ([]map[string]interface{}, error) {
+	var endpoints []map[string]interface{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		url, weight := item, 1
+		// URL 的查询参数中也可能含有 "="，只有末尾为整数时才视为权重
+		if i := strings.LastIndex(item, "="); i > 0 {
+			if w, err := strconv.Atoi(item[i+1:]); err == nil {
+				if w <= 0 {
+					return nil, fmt.Errorf("invalid weight in endpoint %q: must be positive", item)
+				}
+				url, weight = item[:i], w
+			}
+		}
+		endpoints = append(endpoints, map[string]interface{}{"url": url, "weight": weight})
+	}
+	return endpoints, nil
+}
+
+// hasEndpoints 判断协议配置是否包含多个端点
+func hasEndpoints(config map[string]interface{}) bool {
+	_, ok := config["endpoints"]
+	return ok
+}
+
+// endpointConfigs 解析配置中的端点列表。每个端点的配置覆盖协议的公共配置，
+// 因此端点之间可以使用不同的 url、address 或 apiKey
+func endpointConfigs(config map[string]interface{}) ([]map[string]interface{}, error) {
+	var entries []map[string]interface{}
+	switch endpoints := config["endpoints"].(type) {
+	case []map[string]interface{}:
+		entries = endpoints
+	case []interface{}:
+		for _, endpoint := range endpoints {
+			entry, ok := endpoint.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid endpoint config: %v", endpoint)
+			}
+			entries = append(entries, entry)
+		}
+	default:
+		return nil, fmt.Errorf("invalid endpoints config: %v", config["endpoints"])
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no endpoints configured")
+	}
+
+	configs := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		merged := make(map[string]interface{}, len(config)+len(entry))
+		for key, value := range config {
+			if key != "endpoints" {
+				merged[key] = value
+			}
+		}
+		for key, value := range entry {
+			merged[key] = value
+		}
+		configs = append(configs, merged)
+	}
+	return configs, nil
+}
+
+// Name 返回插件名称
+func (ep *EndpointPool) Name() string {
+	return ep.name
+}
+
+// Protocol 返回协议类型
+func (ep *EndpointPool) Protocol() string {
+	return ep.protocol
+}
+
+// Initialize 为配置中的每个端点创建并初始化插件
+func (ep *EndpointPool) Initialize(config map[string]interface{}) error {
+	configs, err := endpointConfigs(config)
+	if err != nil {
+		return err
+	}
+
+	if threshold, ok := config["failureThreshold"].(int); ok && threshold > 0 {
+		ep.failureThreshold = threshold
+	}
+	if cooldown, ok := config["cooldown"].(time.Duration); ok && cooldown > 0 {
+		ep.cooldown = cooldown
+	}
+
+	var endpoints []*poolEndpoint
+	for i, endpointConfig := range configs {
+		plugin := ep.factory()
+		if err := plugin.Initialize(endpointConfig); err != nil {
+			for _, initialized := range endpoints {
+				initialized.plugin.Close()
+			}
+			return fmt.Errorf("failed to initialize endpoint %d: %v", i, err)
+		}
+
+		weight := 1
+		if w, ok := endpointConfig["weight"].(int); ok && w > 0 {
+			weight = w
+		}
+		url, _ := endpointConfig["url"].(string)
+		if url == "" {
+			url, _ = endpointConfig["address"].(string)
+		}
+
+		endpoints = append(endpoints, &poolEndpoint{url: url, weight: weight, plugin: plugin})
+	}
+
+	ep.mu.Lock()
+	ep.name = endpoints[0].plugin.Name()
+	ep.endpoints = endpoints
+	ep.mu.Unlock()
+
+	return nil
+}
+
+// healthy 判断端点当前是否可用，需持有锁
+func (ep *EndpointPool) healthy(endpoint *poolEndpoint, now time.Time) bool {
+	return !now.Before(endpoint.unhealthyUntil)
+}
+
+// order 返回本次请求尝试端点的顺序：先按平滑加权轮询选出的健康端点，
+// 再按错误率从低到高尝试其余端点
+func (ep *EndpointPool) order() []*poolEndpoint {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	now := ep.now()
+	var selected *poolEndpoint
+	total := 0.0
+	for _, endpoint := range ep.endpoints {
+		if !ep.healthy(endpoint, now) {
+			continue
+		}
+		// 错误率越高有效权重越低，但不为零，以便端点恢复后重新获得流量
+		effective := float64(endpoint.weight) * (1 - endpoint.errorRate)
+		if effective < 0.01 {
+			effective = 0.01
+		}
+		endpoint.currentWeight += effective
+		total += effective
+		if selected == nil || endpoint.currentWeight > selected.currentWeight {
+			selected = endpoint
+		}
+	}
+	if selected != nil {
+		selected.currentWeight -= total
+	}
+
+	order := make([]*poolEndpoint, 0, len(ep.endpoints))
+	if selected != nil {
+		order = append(order, selected)
+	}
+	rest := make([]*poolEndpoint, 0, len(ep.endpoints))
+	for _, endpoint := range ep.endpoints {
+		if endpoint != selected {
+			rest = append(rest, endpoint)
+		}
+	}
+	// 健康端点优先，其次错误率低的端点
+	sort.SliceStable(rest, func(i, j int) bool {
+		return ep.less(rest[i], rest[j], now)
+	})
+	return append(order, rest...)
+}
+
+// less 判断端点 a 是否应在 b 之前尝试，需持有锁
+func (ep *EndpointPool) less(a, b *poolEndpoint, now time.Time) bool {
+	aHealthy, bHealthy := ep.healthy(a, now), ep.healthy(b, now)
+	if aHealthy != bHealthy {
+		return aHealthy
+	}
+	return a.errorRate < b.errorRate
+}
+
+// record 记录端点的请求结果，更新错误率和健康状态
+func (ep *EndpointPool) record(endpoint *poolEndpoint, err error) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	endpoint.requests++
+	if err == nil {
+		endpoint.errorRate *= 1 - endpointErrorRateAlpha
+		endpoint.consecutive = 0
+		endpoint.unhealthyUntil = time.Time{}
+		return
+	}
+
+	endpoint.failures++
+	endpoint.errorRate = endpoint.errorRate*(1-endpointErrorRateAlpha) + endpointErrorRateAlpha
+	endpoint.consecutive++
+	if endpoint.consecutive >= ep.failureThreshold {
+		endpoint.unhealthyUntil = ep.now().Add(ep.cooldown)
+	}
+}
+
+// do 依次在端点上执行操作，直到成功或所有端点都失败。上下文取消导致的失败不计入端点错误
+func (ep *EndpointPool) do(ctx context.Context, operation func(Plugin) error) error {
+	endpoints := ep.order()
+	if len(endpoints) == 0 {
+		return fmt.Errorf("no endpoints available for protocol %s", ep.protocol)
+	}
+
+	var lastErr error
+	for _, endpoint := range endpoints {
+		err := operation(endpoint.plugin)
+		if err != nil && ctx.Err() != nil {
+			return err
+		}
+		ep.record(endpoint, err)
+		if err == nil {
+			return nil
+		}
+		lastErr = fmt.Errorf("endpoint %s: %v", endpoint.url, err)
+	}
+	return lastErr
+}
+
+// PullRealTime 拉取实时数据
+func (ep *EndpointPool) PullRealTime(ctx context.Context, handler func(interface{}) error) error {
+	return ep.do(ctx, func(p Plugin) error {
+		return p.PullRealTime(ctx, handler)
+	})
+}
+
+// PullRealTimeEvents 拉取实时事件数据
+func (ep *EndpointPool) PullRealTimeEvents(ctx context.Context, handler func(interface{}) error) error {
+	return ep.do(ctx, func(p Plugin) error {
+		return p.PullRealTimeEvents(ctx, handler)
+	})
+}
+
+// PullBatch 拉取批量数据
+func (ep *EndpointPool) PullBatch(ctx context.Context, start, end time.Time) ([]interface{}, error) {
+	var result []interface{}
+	err := ep.do(ctx, func(p Plugin) error {
+		var pullErr error
+		result, pullErr = p.PullBatch(ctx, start, end)
+		return pullErr
+	})
+	return result, err
+}
+
+// PullLatest 拉取最新数据
+func (ep *EndpointPool) PullLatest(ctx context.Context) (interface{}, error) {
+	var result interface{}
+	err := ep.do(ctx, func(p Plugin) error {
+		var pullErr error
+		result, pullErr = p.PullLatest(ctx)
+		return pullErr
+	})
+	return result, err
+}
+
+// PullWithFilters 拉取带过滤条件的数据
+func (ep *EndpointPool) PullWithFilters(ctx context.Context, filters map[string]interface{}) ([]interface{}, error) {
+	var result []interface{}
+	err := ep.do(ctx, func(p Plugin) error {
+		var pullErr error
+		result, pullErr = p.PullWithFilters(ctx, filters)
+		return pullErr
+	})
+	return result, err
+}
+
+// PullHistorical 拉取历史数据
+func (ep *EndpointPool) PullHistorical(ctx context.Context, start, end time.Time, filters map[string]interface{}) ([]interface{}, error) {
+	var result []interface{}
+	err := ep.do(ctx, func(p Plugin) error {
+		var pullErr error
+		result, pullErr = p.PullHistorical(ctx, start, end, filters)
+		return pullErr
+	})
+	return result, err
+}
+
+// EndpointStatuses 返回各端点的健康状态
+func (ep *EndpointPool) EndpointStatuses() []EndpointStatus {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	now := ep.now()
+	statuses := make([]EndpointStatus, 0, len(ep.endpoints))
+	for _, endpoint := range ep.endpoints {
+		statuses = append(statuses, EndpointStatus{
+			URL:       endpoint.url,
+			Weight:    endpoint.weight,
+			ErrorRate: endpoint.errorRate,
+			Healthy:   ep.healthy(endpoint, now),
+			Requests:  endpoint.requests,
+			Failures:  endpoint.failures,
+		})
+	}
+	return statuses
+}
+
+// Close 关闭所有端点的插件
+func (ep *EndpointPool) Close() error {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	var errors []error
+	for _, endpoint := range ep.endpoints {
+		if err := endpoint.plugin.Close(); err != nil {
+			errors = append(errors, fmt.Errorf("error closing endpoint %s: %v", endpoint.url, err))
+		}
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("errors occurred while closing endpoints: %v", errors)
+	}
+	return nil
+}
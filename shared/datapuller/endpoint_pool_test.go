@@ -0,0 +1,150 @@
+package datapuller
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyPlugin serves its URL and fails while failing is set, counting its calls
+type flakyPlugin struct {
+	fakePlugin
+	failing int32
+	calls   int32
+}
+
+func (f *flakyPlugin) PullLatest(ctx context.Context) (interface{}, error) {
+	atomic.AddInt32(&f.calls, 1)
+	if atomic.LoadInt32(&f.failing) == 1 {
+		return nil, errors.New("endpoint unavailable")
+	}
+	return f.url, nil
+}
+
+// newTestPool creates a pool over flaky plugins for the given endpoints, keyed by URL
+func newTestPool(t *testing.T, endpoints []map[string]interface{}) (*EndpointPool, map[string]*flakyPlugin, *time.Time) {
+	plugins := make(map[string]*flakyPlugin)
+	pool := NewEndpointPool("https-jsonrpc", func() Plugin {
+		p := &flakyPlugin{fakePlugin: fakePlugin{name: "fake-pool"}}
+		return p
+	})
+	now := time.Unix(1700000000, 0)
+	pool.now = func() time.Time { return now }
+
+	if err := pool.Initialize(map[string]interface{}{"endpoints": endpoints, "failureThreshold": 3}); err != nil {
+		t.Fatalf("Failed to initialize pool: %v", err)
+	}
+	for _, endpoint := range pool.endpoints {
+		plugins[endpoint.url] = endpoint.plugin.(*flakyPlugin)
+	}
+	return pool, plugins, &now
+}
+
+func TestEndpointPool_WeightedSelection(t *testing.T) {
+	pool, plugins, _ := newTestPool(t, []map[string]interface{}{
+		{"url": "https://primary", "weight": 3},
+		{"url": "https://secondary", "weight": 1},
+	})
+
+	for i := 0; i < 8; i++ {
+		if _, err := pool.PullLatest(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if calls := atomic.LoadInt32(&plugins["https://primary"].calls); calls != 6 {
+		t.Errorf("Expected 6 calls to the primary, got %d", calls)
+	}
+	if calls := atomic.LoadInt32(&plugins["https://secondary"].calls); calls != 2 {
+		t.Errorf("Expected 2 calls to the secondary, got %d", calls)
+	}
+}
+
+func TestEndpointPool_FailingEndpointYieldsToHealthy(t *testing.T) {
+	pool, plugins, now := newTestPool(t, []map[string]interface{}{
+		{"url": "https://primary", "weight": 3},
+		{"url": "https://secondary", "weight": 1},
+	})
+	primary, secondary := plugins["https://primary"], plugins["https://secondary"]
+	atomic.StoreInt32(&primary.failing, 1)
+
+	// Every request still succeeds, failing over to the secondary
+	for i := 0; i < 10; i++ {
+		result, err := pool.PullLatest(context.Background())
+		if err != nil {
+			t.Fatalf("Expected failover to succeed, got %v", err)
+		}
+		if result != "https://secondary" {
+			t.Errorf("Expected data from the secondary, got %v", result)
+		}
+	}
+
+	// After the failure threshold the primary is no longer tried at all
+	if calls := atomic.LoadInt32(&primary.calls); calls != 3 {
+		t.Errorf("Expected the primary to be tried 3 times, got %d", calls)
+	}
+	if calls := atomic.LoadInt32(&secondary.calls); calls != 10 {
+		t.Errorf("Expected all 10 requests to reach the secondary, got %d", calls)
+	}
+
+	statuses := pool.EndpointStatuses()
+	if statuses[0].Healthy || statuses[0].Failures != 3 || statuses[0].ErrorRate <= 0 {
+		t.Errorf("Expected the primary to be unhealthy with 3 failures, got %+v", statuses[0])
+	}
+	if !statuses[1].Healthy || statuses[1].ErrorRate != 0 {
+		t.Errorf("Expected the secondary to be healthy, got %+v", statuses[1])
+	}
+
+	// Once recovered and past the cooldown, the primary gets traffic again
+	atomic.StoreInt32(&primary.failing, 0)
+	*now = now.Add(DefaultEndpointCooldown)
+	for i := 0; i < 10; i++ {
+		pool.PullLatest(context.Background())
+	}
+	if calls := atomic.LoadInt32(&primary.calls); calls <= 3 {
+		t.Error("Expected the recovered primary to receive requests again")
+	}
+	if !pool.EndpointStatuses()[0].Healthy {
+		t.Error("Expected the recovered primary to be healthy")
+	}
+}
+
+func TestEndpointPool_AllEndpointsFailing(t *testing.T) {
+	pool, plugins, _ := newTestPool(t, []map[string]interface{}{
+		{"url": "https://primary"},
+		{"url": "https://secondary"},
+	})
+	for _, plugin := range plugins {
+		atomic.StoreInt32(&plugin.failing, 1)
+	}
+
+	if _, err := pool.PullLatest(context.Background()); err == nil {
+		t.Error("Expected an error when every endpoint fails")
+	}
+}
+
+func TestParseEndpoints(t *testing.T) {
+	endpoints, err := ParseEndpoints("https://primary=3, https://secondary, https://rpc.example/?key=abc")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(endpoints) != 3 {
+		t.Fatalf("Expected 3 endpoints, got %d", len(endpoints))
+	}
+	if endpoints[0]["url"] != "https://primary" || endpoints[0]["weight"] != 3 {
+		t.Errorf("Expected https://primary with weight 3, got %v", endpoints[0])
+	}
+	if endpoints[1]["weight"] != 1 {
+		t.Errorf("Expected the default weight 1, got %v", endpoints[1]["weight"])
+	}
+	// Query parameters are not mistaken for weights
+	if endpoints[2]["url"] != "https://rpc.example/?key=abc" {
+		t.Errorf("Expected the URL with its query, got %v", endpoints[2]["url"])
+	}
+
+	if _, err := ParseEndpoints("https://primary=0"); err == nil {
+		t.Error("Expected an error for a zero weight")
+	}
+}
@@ -0,0 +1,55 @@
+package datapuller
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultHistoricalPage 流式历史拉取每页默认的时间跨度
+const DefaultHistoricalPage = 6 * time.Hour
+
+// SetHistoricalPage 设置流式历史拉取每页的时间跨度，0 使用 DefaultHistoricalPage
+func (mpp *MultiProtocolPuller) SetHistoricalPage(page time.Duration) {
+	mpp.mu.Lock()
+	defer mpp.mu.Unlock()
+	mpp.historicalPage = page
+}
+
+// PullHistoricalStream 按时间分页拉取 start 到 end 的历史数据，逐条交给 handler 处理，
+// 内存中只保留一页数据。返回续传游标：游标之前的页都已完整交给 handler。
+// 拉取或 handler 失败时返回当时的游标，以游标作为 start 再次调用即可从中断的页继续，
+// 中断页中已处理的数据会再次交给 handler
+func (mpp *MultiProtocolPuller) PullHistoricalStream(ctx context.Context, start, end time.Time, filters map[string]interface{}, handler func(interface{}) error) (time.Time, error) {
+	mpp.mu.RLock()
+	page := mpp.historicalPage
+	mpp.mu.RUnlock()
+	if page <= 0 {
+		page = DefaultHistoricalPage
+	}
+
+	cursor := start
+	for cursor.Before(end) {
+		if err := ctx.Err(); err != nil {
+			return cursor, err
+		}
+
+		pageEnd := cursor.Add(page)
+		if pageEnd.After(end) {
+			pageEnd = end
+		}
+
+		data, err := mpp.PullHistorical(ctx, cursor, pageEnd, filters)
+		if err != nil {
+			return cursor, fmt.Errorf("failed to pull historical page from %s: %v", cursor.Format(time.RFC3339), err)
+		}
+		for _, item := range data {
+			if err := handler(item); err != nil {
+				return cursor, err
+			}
+		}
+		cursor = pageEnd
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,126 @@
+package datapuller
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pagedPlugin serves one historical item every 10 minutes of the pulled range, counting its pulls
+type pagedPlugin struct {
+	*fakePlugin
+	pulls int32
+}
+
+func (p *pagedPlugin) PullHistorical(ctx context.Context, start, end time.Time, filters map[string]interface{}) ([]interface{}, error) {
+	atomic.AddInt32(&p.pulls, 1)
+	var data []interface{}
+	for t := start; t.Before(end); t = t.Add(10 * time.Minute) {
+		data = append(data, t)
+	}
+	return data, nil
+}
+
+func newPagedPuller(t *testing.T, name string) (*BlockchainDataPuller, *pagedPlugin) {
+	originalFactories := pluginFactories
+	t.Cleanup(func() { pluginFactories = originalFactories })
+
+	plugin := &pagedPlugin{fakePlugin: newFakePlugin(name)}
+	pluginFactories = map[string]func() Plugin{
+		"https-jsonrpc": func() Plugin { return plugin },
+	}
+
+	puller := NewBlockchainDataPuller()
+	if err := puller.Initialize(map[string]map[string]interface{}{"https-jsonrpc": {}}); err != nil {
+		t.Fatalf("Expected no error initializing puller, got %v", err)
+	}
+	t.Cleanup(func() { GlobalRegistry.Unregister(name) })
+	puller.SetHistoricalPage(time.Hour)
+	return puller, plugin
+}
+
+func TestPullHistoricalStream_DeliversPageByPage(t *testing.T) {
+	puller, plugin := newPagedPuller(t, "fake-paged-stream")
+	start := time.Unix(1700000000, 0)
+	end := start.Add(10 * time.Hour)
+
+	var delivered []time.Time
+	var pullsAtFirstItem int32
+	cursor, err := puller.PullHistoricalStream(context.Background(), start, end, nil, func(data interface{}) error {
+		if len(delivered) == 0 {
+			pullsAtFirstItem = atomic.LoadInt32(&plugin.pulls)
+		}
+		delivered = append(delivered, data.(time.Time))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !cursor.Equal(end) {
+		t.Errorf("Expected the cursor at the end %v, got %v", end, cursor)
+	}
+
+	// Items are handed over as soon as their page is pulled
+	if pullsAtFirstItem != 1 {
+		t.Errorf("Expected the first item after 1 page pull, got %d", pullsAtFirstItem)
+	}
+	if pulls := atomic.LoadInt32(&plugin.pulls); pulls != 10 {
+		t.Errorf("Expected 10 page pulls, got %d", pulls)
+	}
+	if len(delivered) != 60 {
+		t.Fatalf("Expected 60 items, got %d", len(delivered))
+	}
+	for i, item := range delivered {
+		if expected := start.Add(time.Duration(i) * 10 * time.Minute); !item.Equal(expected) {
+			t.Fatalf("Expected item %d at %v, got %v", i, expected, item)
+		}
+	}
+}
+
+func TestPullHistoricalStream_ResumesFromCursor(t *testing.T) {
+	puller, plugin := newPagedPuller(t, "fake-paged-resume")
+	start := time.Unix(1700000000, 0)
+	end := start.Add(10 * time.Hour)
+
+	// The handler fails in the third page
+	failAt := start.Add(2*time.Hour + 30*time.Minute)
+	var delivered int
+	cursor, err := puller.PullHistoricalStream(context.Background(), start, end, nil, func(data interface{}) error {
+		if data.(time.Time).Equal(failAt) {
+			return errors.New("sink unavailable")
+		}
+		delivered++
+		return nil
+	})
+	if err == nil {
+		t.Fatal("Expected the handler error to be returned")
+	}
+	if expected := start.Add(2 * time.Hour); !cursor.Equal(expected) {
+		t.Fatalf("Expected the cursor at the interrupted page %v, got %v", expected, cursor)
+	}
+	if delivered != 15 {
+		t.Errorf("Expected 15 items before the failure, got %d", delivered)
+	}
+
+	// Resuming pulls only the pages from the cursor on
+	atomic.StoreInt32(&plugin.pulls, 0)
+	var resumed []time.Time
+	cursor, err = puller.PullHistoricalStream(context.Background(), cursor, end, nil, func(data interface{}) error {
+		resumed = append(resumed, data.(time.Time))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !cursor.Equal(end) {
+		t.Errorf("Expected the cursor at the end %v, got %v", end, cursor)
+	}
+	if pulls := atomic.LoadInt32(&plugin.pulls); pulls != 8 {
+		t.Errorf("Expected 8 page pulls after resuming, got %d", pulls)
+	}
+	if len(resumed) != 48 || !resumed[0].Equal(start.Add(2*time.Hour)) {
+		t.Errorf("Expected 48 items from %v, got %d", start.Add(2*time.Hour), len(resumed))
+	}
+}
@@ -2,29 +2,34 @@ package datapuller
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
+
+	plugins "chainpulse/shared/datapuller/plugins"
+	"chainpulse/shared/json"
+	"chainpulse/shared/utils"
 )
 
+// defaultThrottleDelay 服务商返回 429 但没有 Retry-After 且未配置 RetryDelay 时的等待时间
+const defaultThrottleDelay = time.Second
+
 // HTTPPuller HTTP REST API数据拉取器
 type HTTPPuller struct {
 	config *DataSourceConfig
 	client *http.Client
+
+	mu        sync.Mutex
+	notBefore time.Time // 被限流后，所有请求在此之前都不发送
 }
 
-// NewHTTPPuller 创建HTTP数据拉取器
+// NewHTTPPuller 创建HTTP数据拉取器，所有请求共用一个连接池以复用到同一主机的连接
 func NewHTTPPuller(config *DataSourceConfig) *HTTPPuller {
-	client := &http.Client{
-		Timeout: config.Timeout,
-	}
-
 	return &HTTPPuller{
 		config: config,
-		client: client,
+		client: utils.NewHTTPClient(config.Timeout, config.HTTPPool),
 	}
 }
 
@@ -49,37 +54,30 @@ func (hp *HTTPPuller) PullBatch(ctx context.Context, start, end time.Time) ([]in
 			return nil, fmt.Errorf("failed to create request: %v", err)
 		}
 
-		// 添加API密钥到请求头
-		if hp.config.APIKey != "" {
-			req.Header.Set("Authorization", "Bearer "+hp.config.APIKey)
-			req.Header.Set("X-API-Key", hp.config.APIKey)
-		}
+		// 按数据源配置的方式认证
+		hp.authenticate(req)
 
 		req.Header.Set("Content-Type", "application/json")
 
 		// 发送请求
-		resp, err := hp.client.Do(req)
+		resp, err := hp.do(req)
 		if err != nil {
-			return nil, fmt.Errorf("failed to send request: %v", err)
+			return nil, err
 		}
 
-		defer resp.Body.Close()
-
 		// 检查响应状态
 		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
 			return nil, fmt.Errorf("request failed with status: %d", resp.StatusCode)
 		}
 
-		// 读取响应体
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response body: %v", err)
-		}
-
-		// 解析响应数据
+		// 流式解析响应数据，不将整个响应体读入内存。每页读完即关闭响应体，
+		// 不在循环中 defer
 		var pageData []interface{}
-		if err := json.Unmarshal(body, &pageData); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+		err = json.NewDecoder(resp.Body).Decode(&pageData)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode response: %v", err)
 		}
 
 		// 如果没有更多数据，退出循环
@@ -110,18 +108,15 @@ func (hp *HTTPPuller) PullLatest(ctx context.Context) (interface{}, error) {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 
-	// 添加API密钥到请求头
-	if hp.config.APIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+hp.config.APIKey)
-		req.Header.Set("X-API-Key", hp.config.APIKey)
-	}
+	// 按数据源配置的方式认证
+	hp.authenticate(req)
 
 	req.Header.Set("Content-Type", "application/json")
 
 	// 发送请求
-	resp, err := hp.client.Do(req)
+	resp, err := hp.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
+		return nil, err
 	}
 
 	defer resp.Body.Close()
@@ -131,16 +126,10 @@ func (hp *HTTPPuller) PullLatest(ctx context.Context) (interface{}, error) {
 		return nil, fmt.Errorf("request failed with status: %d", resp.StatusCode)
 	}
 
-	// 读取响应体
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
-	}
-
-	// 解析响应数据
+	// 流式解析响应数据，不将整个响应体读入内存
 	var data interface{}
-	if err := json.Unmarshal(body, &data); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
 	}
 
 	return data, nil
@@ -165,18 +154,15 @@ func (hp *HTTPPuller) PullWithFilters(ctx context.Context, filters map[string]in
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 
-	// 添加API密钥到请求头
-	if hp.config.APIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+hp.config.APIKey)
-		req.Header.Set("X-API-Key", hp.config.APIKey)
-	}
+	// 按数据源配置的方式认证
+	hp.authenticate(req)
 
 	req.Header.Set("Content-Type", "application/json")
 
 	// 发送请求
-	resp, err := hp.client.Do(req)
+	resp, err := hp.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
+		return nil, err
 	}
 
 	defer resp.Body.Close()
@@ -186,16 +172,10 @@ func (hp *HTTPPuller) PullWithFilters(ctx context.Context, filters map[string]in
 		return nil, fmt.Errorf("request failed with status: %d", resp.StatusCode)
 	}
 
-	// 读取响应体
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
-	}
-
-	// 解析响应数据
+	// 流式解析响应数据，不将整个响应体读入内存
 	var data []interface{}
-	if err := json.Unmarshal(body, &data); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
 	}
 
 	return data, nil
@@ -272,7 +252,81 @@ func (hp *HTTPPuller) PullHistorical(ctx context.Context, start, end time.Time,
 
 // Close 关闭HTTP拉取器
 func (hp *HTTPPuller) Close() error {
-	// HTTP客户端不需要特殊关闭，但我们可以重置它
+	// 关闭连接池中的空闲连接
+	if hp.client != nil {
+		hp.client.CloseIdleConnections()
+	}
 	hp.client = nil
 	return nil
 }
+
+// authenticate 按数据源配置的方式认证请求。未配置 Auth 时兼容原来的 APIKey，
+// 同时发送 Bearer 令牌和 X-API-Key 请求头
+func (hp *HTTPPuller) authenticate(req *http.Request) {
+	if hp.config.Auth.IsZero() && hp.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+hp.config.APIKey)
+		req.Header.Set(plugins.DefaultAuthHeader, hp.config.APIKey)
+		return
+	}
+	hp.config.Auth.Apply(req)
+}
+
+// do 发送请求。服务商返回 429 时按 Retry-After 等待后重试，缺少时等待 RetryDelay，
+// 最多重试 RetryAttempts 次。限流期限对拉取器的所有请求生效
+func (hp *HTTPPuller) do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := hp.waitNotBefore(req.Context()); err != nil {
+			return nil, err
+		}
+
+		resp, err := hp.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %v", err)
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= hp.config.RetryAttempts {
+			return resp, nil
+		}
+
+		// 重试前关闭限流响应
+		resp.Body.Close()
+		hp.throttle(resp)
+	}
+}
+
+// throttle 记录一次被限流的请求并推迟限流期限
+func (hp *HTTPPuller) throttle(resp *http.Response) {
+	now := time.Now()
+	delay, ok := plugins.ParseRetryAfter(resp.Header.Get("Retry-After"), now)
+	if !ok {
+		delay = hp.config.RetryDelay
+		if delay <= 0 {
+			delay = defaultThrottleDelay
+		}
+	}
+	GlobalMetricsCollector.RecordThrottled("http", delay)
+
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+	if deadline := now.Add(delay); deadline.After(hp.notBefore) {
+		hp.notBefore = deadline
+	}
+}
+
+// waitNotBefore 等待到限流期限，上下文取消时返回错误
+func (hp *HTTPPuller) waitNotBefore(ctx context.Context) error {
+	hp.mu.Lock()
+	wait := time.Until(hp.notBefore)
+	hp.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
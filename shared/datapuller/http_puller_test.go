@@ -0,0 +1,50 @@
+package datapuller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPPuller_RetryAfter(t *testing.T) {
+	var arrivals []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrivals = append(arrivals, time.Now())
+		if len(arrivals) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"number": 1}`))
+	}))
+	defer server.Close()
+
+	puller := NewHTTPPuller(&DataSourceConfig{URL: server.URL, RetryAttempts: 1, RetryDelay: time.Millisecond})
+	defer puller.Close()
+
+	if _, err := puller.PullLatest(context.Background()); err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+	if len(arrivals) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(arrivals))
+	}
+	if wait := arrivals[1].Sub(arrivals[0]); wait < time.Second {
+		t.Errorf("Expected at least 1s before the retry, got %v", wait)
+	}
+}
+
+func TestHTTPPuller_ThrottledWithoutRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	puller := NewHTTPPuller(&DataSourceConfig{URL: server.URL})
+	defer puller.Close()
+
+	if _, err := puller.PullLatest(context.Background()); err == nil {
+		t.Error("Expected an error for a throttled request without retries")
+	}
+}
@@ -23,16 +23,18 @@ type MetricsCollector struct {
 
 // PluginMetrics 插件特定指标
 type PluginMetrics struct {
-	Name              string
-	TotalRequests     int64
-	TotalErrors       int64
-	TotalSuccess      int64
-	AvgResponseTime   time.Duration
-	TotalResponseTime time.Duration
-	RequestCount      int64
-	LastRequestTime   time.Time
-	LastErrorTime     time.Time
-	LastError         string
+	Name              string        `json:"name"`
+	TotalRequests     int64         `json:"total_requests"`
+	TotalErrors       int64         `json:"total_errors"`
+	TotalSuccess      int64         `json:"total_success"`
+	AvgResponseTime   time.Duration `json:"avg_response_time_ns"`
+	TotalResponseTime time.Duration `json:"total_response_time_ns"`
+	RequestCount      int64         `json:"request_count"`
+	LastRequestTime   time.Time     `json:"last_request_time"`
+	LastErrorTime     time.Time     `json:"last_error_time"`
+	LastError         string        `json:"last_error"`
+	ThrottledCount    int64         `json:"throttled_count"`   // 被服务商限流的请求数
+	ThrottledTime     time.Duration `json:"throttled_time_ns"` // 限流要求等待的总时间
 }
 
 // NewMetricsCollector 创建新的指标收集器
@@ -83,6 +85,23 @@ func (mc *MetricsCollector) RecordRequest(pluginName string, duration time.Durat
 	}
 }
 
+// RecordThrottled 记录一次被服务商限流的请求及其要求等待的时间
+func (mc *MetricsCollector) RecordThrottled(pluginName string, wait time.Duration) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	pluginMetric, exists := mc.pluginMetrics[pluginName]
+	if !exists {
+		pluginMetric = &PluginMetrics{
+			Name: pluginName,
+		}
+		mc.pluginMetrics[pluginName] = pluginMetric
+	}
+
+	pluginMetric.ThrottledCount++
+	pluginMetric.ThrottledTime += wait
+}
+
 // GetPluginMetrics 获取插件指标
 func (mc *MetricsCollector) GetPluginMetrics(pluginName string) (*PluginMetrics, error) {
 	mc.mu.RLock()
@@ -109,6 +128,19 @@ func (mc *MetricsCollector) GetAllMetrics() map[string]*PluginMetrics {
 	return result
 }
 
+// SnapshotMetrics 获取所有插件指标的副本，不受之后的请求记录影响
+func (mc *MetricsCollector) SnapshotMetrics() map[string]PluginMetrics {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	result := make(map[string]PluginMetrics, len(mc.pluginMetrics))
+	for name, metric := range mc.pluginMetrics {
+		result[name] = *metric
+	}
+
+	return result
+}
+
 // GetGlobalMetrics 获取全局指标
 func (mc *MetricsCollector) GetGlobalMetrics() (int64, int64, int64, time.Duration) {
 	mc.mu.RLock()
@@ -137,6 +169,8 @@ func (mc *MetricsCollector) ResetMetrics() {
 		pluginMetric.TotalResponseTime = 0
 		pluginMetric.RequestCount = 0
 		pluginMetric.AvgResponseTime = 0
+		pluginMetric.ThrottledCount = 0
+		pluginMetric.ThrottledTime = 0
 	}
 }
 
@@ -5,20 +5,37 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	plugins "chainpulse/shared/datapuller/plugins"
 )
 
 // MultiProtocolPuller 多协议数据拉取器
 type MultiProtocolPuller struct {
 	pullers     map[string]Plugin
+	reloaded    map[string]chan struct{}
 	mu          sync.RWMutex
 	retryConfig *RetryConfig
 	metrics     *MetricsCollector
+	// historicalPage 流式历史拉取每页的时间跨度，0 使用 DefaultHistoricalPage
+	historicalPage time.Duration
+}
+
+// pluginFactories 协议到插件构造函数的映射
+var pluginFactories = map[string]func() Plugin{
+	"https-jsonrpc": func() Plugin {
+		plugin := plugins.NewHTTPSJSONRPCPlugin()
+		plugin.SetThrottleRecorder(GlobalMetricsCollector)
+		return plugin
+	},
+	"websocket-jsonrpc": func() Plugin { return plugins.NewWebSocketJSONRPCPlugin() },
+	"grpc":              func() Plugin { return plugins.NewGRPCPlugin() },
 }
 
 // NewMultiProtocolPuller 创建多协议拉取器
 func NewMultiProtocolPuller() *MultiProtocolPuller {
 	return &MultiProtocolPuller{
 		pullers:     make(map[string]Plugin),
+		reloaded:    make(map[string]chan struct{}),
 		retryConfig: DefaultRetryConfig,
 		metrics:     GlobalMetricsCollector,
 	}
@@ -26,31 +43,20 @@ func NewMultiProtocolPuller() *MultiProtocolPuller {
 
 // Initialize 初始化多协议拉取器，根据配置加载插件
 func (mpp *MultiProtocolPuller) Initialize(configs map[string]map[string]interface{}) error {
+	mpp.mu.Lock()
+	defer mpp.mu.Unlock()
+
 	// Clear existing pullers
 	mpp.pullers = make(map[string]Plugin)
+	mpp.reloaded = make(map[string]chan struct{})
 
 	// Initialize and register plugins based on configuration
 	for protocol, config := range configs {
-		var plugin Plugin
-
-		// Create appropriate plugin based on protocol
-		switch protocol {
-		case "https-jsonrpc":
-			plugin = NewHTTPSJSONRPCPlugin()
-		case "websocket-jsonrpc":
-			plugin = NewWebSocketJSONRPCPlugin()
-		case "grpc":
-			plugin = NewGRPCPlugin()
-		default:
-			return fmt.Errorf("unsupported protocol: %s", protocol)
+		plugin, err := mpp.newPlugin(protocol, config)
+		if err != nil {
+			return err
 		}
 
-		// Wrap plugin with retry wrapper
-		plugin = NewRetryWrapper(plugin, mpp.retryConfig)
-
-		// Wrap plugin with metrics wrapper
-		plugin = WithMetrics(plugin, mpp.metrics)
-
 		// Initialize and register the plugin
 		if err := InitializeAndRegisterPlugin(plugin, config); err != nil {
 			return fmt.Errorf("failed to initialize plugin for protocol %s: %v", protocol, err)
@@ -58,28 +64,135 @@ func (mpp *MultiProtocolPuller) Initialize(configs map[string]map[string]interfa
 
 		// Store the plugin
 		mpp.pullers[protocol] = plugin
+		mpp.reloaded[protocol] = make(chan struct{})
+	}
+
+	return nil
+}
+
+// newPlugin 根据协议创建插件，并包装重试和指标。配置包含 endpoints 时创建多端点的端点池
+func (mpp *MultiProtocolPuller) newPlugin(protocol string, config map[string]interface{}) (Plugin, error) {
+	factory, exists := pluginFactories[protocol]
+	if !exists {
+		return nil, fmt.Errorf("unsupported protocol: %s", protocol)
+	}
+
+	var plugin Plugin
+	if hasEndpoints(config) {
+		plugin = NewEndpointPool(protocol, factory)
+	} else {
+		plugin = factory()
+	}
+
+	// Wrap plugin with retry wrapper
+	plugin = NewRetryWrapper(plugin, mpp.retryConfig)
+
+	// Wrap plugin with metrics wrapper
+	plugin = WithMetrics(plugin, mpp.metrics)
+
+	return plugin, nil
+}
+
+// ReloadPlugin 使用新配置重载单个协议的插件，其他协议的插件不受影响
+func (mpp *MultiProtocolPuller) ReloadPlugin(protocol string, config map[string]interface{}) error {
+	plugin, err := mpp.newPlugin(protocol, config)
+	if err != nil {
+		return err
+	}
+
+	// 先初始化新插件，失败时旧插件继续服务
+	if err := plugin.Initialize(config); err != nil {
+		return fmt.Errorf("failed to initialize plugin for protocol %s: %v", protocol, err)
+	}
+
+	mpp.mu.Lock()
+	old, exists := mpp.pullers[protocol]
+	mpp.pullers[protocol] = plugin
+	if ch, ok := mpp.reloaded[protocol]; ok {
+		// 通知正在进行的实时拉取切换到新插件
+		close(ch)
+	}
+	mpp.reloaded[protocol] = make(chan struct{})
+	mpp.mu.Unlock()
+
+	GlobalRegistry.Replace(plugin)
+
+	if exists {
+		if err := old.Close(); err != nil {
+			return fmt.Errorf("error closing old %s plugin: %v", protocol, err)
+		}
 	}
 
 	return nil
 }
 
+// getPlugin 获取协议对应的插件
+func (mpp *MultiProtocolPuller) getPlugin(protocol string) (Plugin, bool) {
+	mpp.mu.RLock()
+	defer mpp.mu.RUnlock()
+
+	plugin, exists := mpp.pullers[protocol]
+	return plugin, exists
+}
+
+// pullRealTimeWithReload 在协议插件上执行实时拉取，插件被重载时在新插件上重新开始
+func (mpp *MultiProtocolPuller) pullRealTimeWithReload(ctx context.Context, protocol string, pull func(Plugin, context.Context) error) error {
+	for {
+		mpp.mu.RLock()
+		plugin, exists := mpp.pullers[protocol]
+		reloaded := mpp.reloaded[protocol]
+		mpp.mu.RUnlock()
+
+		if !exists {
+			return fmt.Errorf("no plugin available for protocol %s", protocol)
+		}
+
+		pullCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-reloaded:
+				cancel()
+			case <-pullCtx.Done():
+			}
+		}()
+
+		err := pull(plugin, pullCtx)
+		cancel()
+
+		select {
+		case <-reloaded:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// 插件已被重载，使用新插件继续拉取
+			continue
+		default:
+			return err
+		}
+	}
+}
+
 // PullRealTime 拉取实时数据（使用支持实时协议的插件，如WebSocket或gRPC）
 func (mpp *MultiProtocolPuller) PullRealTime(ctx context.Context, handler func(interface{}) error) error {
+	pull := func(p Plugin, c context.Context) error {
+		return p.PullRealTime(c, handler)
+	}
+
 	// Try WebSocket plugin first, then gRPC
-	if wsPlugin, exists := mpp.pullers["websocket-jsonrpc"]; exists {
-		if err := wsPlugin.PullRealTime(ctx, handler); err != nil {
+	if _, exists := mpp.getPlugin("websocket-jsonrpc"); exists {
+		if err := mpp.pullRealTimeWithReload(ctx, "websocket-jsonrpc", pull); err != nil {
 			fmt.Printf("Error pulling real-time data with WebSocket: %v\n", err)
 			// If WebSocket fails, try gRPC
-			if grpcPlugin, exists := mpp.pullers["grpc"]; exists {
-				return grpcPlugin.PullRealTime(ctx, handler)
+			if _, exists := mpp.getPlugin("grpc"); exists {
+				return mpp.pullRealTimeWithReload(ctx, "grpc", pull)
 			}
 			return fmt.Errorf("no real-time protocol plugin available after WebSocket failure: %v", err)
 		}
 		return nil
 	}
 
-	if grpcPlugin, exists := mpp.pullers["grpc"]; exists {
-		return grpcPlugin.PullRealTime(ctx, handler)
+	if _, exists := mpp.getPlugin("grpc"); exists {
+		return mpp.pullRealTimeWithReload(ctx, "grpc", pull)
 	}
 
 	return fmt.Errorf("no real-time protocol plugin available")
@@ -87,21 +200,25 @@ func (mpp *MultiProtocolPuller) PullRealTime(ctx context.Context, handler func(i
 
 // PullRealTimeEvents 拉取实时事件数据
 func (mpp *MultiProtocolPuller) PullRealTimeEvents(ctx context.Context, handler func(interface{}) error) error {
+	pull := func(p Plugin, c context.Context) error {
+		return p.PullRealTimeEvents(c, handler)
+	}
+
 	// Try WebSocket plugin first, then gRPC
-	if wsPlugin, exists := mpp.pullers["websocket-jsonrpc"]; exists {
-		if err := wsPlugin.PullRealTimeEvents(ctx, handler); err != nil {
+	if _, exists := mpp.getPlugin("websocket-jsonrpc"); exists {
+		if err := mpp.pullRealTimeWithReload(ctx, "websocket-jsonrpc", pull); err != nil {
 			fmt.Printf("Error pulling real-time events with WebSocket: %v\n", err)
 			// If WebSocket fails, try gRPC
-			if grpcPlugin, exists := mpp.pullers["grpc"]; exists {
-				return grpcPlugin.PullRealTimeEvents(ctx, handler)
+			if _, exists := mpp.getPlugin("grpc"); exists {
+				return mpp.pullRealTimeWithReload(ctx, "grpc", pull)
 			}
 			return fmt.Errorf("no real-time protocol plugin available after WebSocket failure: %v", err)
 		}
 		return nil
 	}
 
-	if grpcPlugin, exists := mpp.pullers["grpc"]; exists {
-		return grpcPlugin.PullRealTimeEvents(ctx, handler)
+	if _, exists := mpp.getPlugin("grpc"); exists {
+		return mpp.pullRealTimeWithReload(ctx, "grpc", pull)
 	}
 
 	return fmt.Errorf("no real-time protocol plugin available")
@@ -113,7 +230,7 @@ func (mpp *MultiProtocolPuller) PullBatch(ctx context.Context, start, end time.T
 	protocols := []string{"https-jsonrpc", "grpc", "websocket-jsonrpc"}
 
 	for _, protocol := range protocols {
-		if plugin, exists := mpp.pullers[protocol]; exists {
+		if plugin, exists := mpp.getPlugin(protocol); exists {
 			result, err := plugin.PullBatch(ctx, start, end)
 			if err == nil {
 				return result, nil
@@ -132,7 +249,7 @@ func (mpp *MultiProtocolPuller) PullLatest(ctx context.Context) (interface{}, er
 	protocols := []string{"https-jsonrpc", "grpc", "websocket-jsonrpc"}
 
 	for _, protocol := range protocols {
-		if plugin, exists := mpp.pullers[protocol]; exists {
+		if plugin, exists := mpp.getPlugin(protocol); exists {
 			result, err := plugin.PullLatest(ctx)
 			if err == nil {
 				return result, nil
@@ -151,7 +268,7 @@ func (mpp *MultiProtocolPuller) PullWithFilters(ctx context.Context, filters map
 	protocols := []string{"https-jsonrpc", "grpc", "websocket-jsonrpc"}
 
 	for _, protocol := range protocols {
-		if plugin, exists := mpp.pullers[protocol]; exists {
+		if plugin, exists := mpp.getPlugin(protocol); exists {
 			result, err := plugin.PullWithFilters(ctx, filters)
 			if err == nil {
 				return result, nil
@@ -170,7 +287,7 @@ func (mpp *MultiProtocolPuller) PullHistorical(ctx context.Context, start, end t
 	protocols := []string{"https-jsonrpc", "grpc", "websocket-jsonrpc"}
 
 	for _, protocol := range protocols {
-		if plugin, exists := mpp.pullers[protocol]; exists {
+		if plugin, exists := mpp.getPlugin(protocol); exists {
 			result, err := plugin.PullHistorical(ctx, start, end, filters)
 			if err == nil {
 				return result, nil
@@ -223,6 +340,9 @@ func (mpp *MultiProtocolPuller) Close() error {
 	var mu sync.Mutex
 	var errors []error
 
+	mpp.mu.RLock()
+	defer mpp.mu.RUnlock()
+
 	// Close all plugins
 	for protocol, plugin := range mpp.pullers {
 		wg.Add(1)
@@ -0,0 +1,163 @@
+package datapuller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakePlugin is a minimal in-memory plugin used to exercise MultiProtocolPuller
+type fakePlugin struct {
+	name   string
+	url    string
+	closed int32
+}
+
+func newFakePlugin(name string) *fakePlugin {
+	return &fakePlugin{name: name}
+}
+
+func (f *fakePlugin) Name() string     { return f.name }
+func (f *fakePlugin) Protocol() string { return f.name }
+
+func (f *fakePlugin) Initialize(config map[string]interface{}) error {
+	f.url, _ = config["url"].(string)
+	return nil
+}
+
+func (f *fakePlugin) PullRealTime(ctx context.Context, handler func(interface{}) error) error {
+	if err := handler(f.url); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (f *fakePlugin) PullRealTimeEvents(ctx context.Context, handler func(interface{}) error) error {
+	return f.PullRealTime(ctx, handler)
+}
+
+func (f *fakePlugin) PullBatch(ctx context.Context, start, end time.Time) ([]interface{}, error) {
+	return []interface{}{f.url}, nil
+}
+
+func (f *fakePlugin) PullLatest(ctx context.Context) (interface{}, error) {
+	if atomic.LoadInt32(&f.closed) == 1 {
+		return nil, errors.New("plugin closed")
+	}
+	return f.url, nil
+}
+
+func (f *fakePlugin) PullWithFilters(ctx context.Context, filters map[string]interface{}) ([]interface{}, error) {
+	return []interface{}{f.url}, nil
+}
+
+func (f *fakePlugin) PullHistorical(ctx context.Context, start, end time.Time, filters map[string]interface{}) ([]interface{}, error) {
+	return []interface{}{f.url}, nil
+}
+
+func (f *fakePlugin) Close() error {
+	atomic.StoreInt32(&f.closed, 1)
+	return nil
+}
+
+func TestMultiProtocolPuller_ReloadPlugin(t *testing.T) {
+	var mu sync.Mutex
+	var wsPlugins []*fakePlugin
+
+	originalFactories := pluginFactories
+	defer func() { pluginFactories = originalFactories }()
+
+	pluginFactories = map[string]func() Plugin{
+		"websocket-jsonrpc": func() Plugin {
+			p := newFakePlugin("fake-ws-reload")
+			mu.Lock()
+			wsPlugins = append(wsPlugins, p)
+			mu.Unlock()
+			return p
+		},
+		"https-jsonrpc": func() Plugin { return newFakePlugin("fake-https-reload") },
+	}
+
+	mpp := NewMultiProtocolPuller()
+	err := mpp.Initialize(map[string]map[string]interface{}{
+		"websocket-jsonrpc": {"url": "ws://old"},
+		"https-jsonrpc":     {"url": "https://stable"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error initializing puller, got %v", err)
+	}
+	defer GlobalRegistry.Unregister("fake-ws-reload")
+	defer GlobalRegistry.Unregister("fake-https-reload")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan interface{}, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- mpp.PullRealTime(ctx, func(data interface{}) error {
+			received <- data
+			return nil
+		})
+	}()
+
+	if data := <-received; data != "ws://old" {
+		t.Fatalf("Expected data from old plugin, got %v", data)
+	}
+
+	if err := mpp.ReloadPlugin("websocket-jsonrpc", map[string]interface{}{"url": "ws://new"}); err != nil {
+		t.Fatalf("Expected no error reloading plugin, got %v", err)
+	}
+
+	select {
+	case data := <-received:
+		if data != "ws://new" {
+			t.Errorf("Expected real-time pull to restart on new plugin, got %v", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected real-time pull to restart after reload")
+	}
+
+	mu.Lock()
+	if len(wsPlugins) != 2 {
+		t.Fatalf("Expected 2 websocket plugins to be created, got %d", len(wsPlugins))
+	}
+	if atomic.LoadInt32(&wsPlugins[0].closed) != 1 {
+		t.Error("Expected old plugin to be closed after reload")
+	}
+	if atomic.LoadInt32(&wsPlugins[1].closed) != 0 {
+		t.Error("Expected new plugin to remain open")
+	}
+	mu.Unlock()
+
+	// The other protocol keeps serving throughout the reload
+	latest, err := mpp.PullLatest(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error from unaffected plugin, got %v", err)
+	}
+	if latest != "https://stable" {
+		t.Errorf("Expected https://stable, got %v", latest)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected error after cancellation, got nil")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected PullRealTime to return after cancellation")
+	}
+}
+
+func TestMultiProtocolPuller_ReloadPluginUnsupportedProtocol(t *testing.T) {
+	mpp := NewMultiProtocolPuller()
+
+	if err := mpp.ReloadPlugin("carrier-pigeon", map[string]interface{}{}); err == nil {
+		t.Error("Expected error reloading unsupported protocol")
+	}
+}
@@ -109,6 +109,16 @@ func (r *PluginRegistry) Unregister(name string) error {
 	return nil
 }
 
+// Replace 注册或替换同名插件，返回被替换的旧插件（不会关闭旧插件）
+func (r *PluginRegistry) Replace(plugin Plugin) Plugin {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.plugins[plugin.Name()]
+	r.plugins[plugin.Name()] = plugin
+	return old
+}
+
 // InitializePlugin 初始化并注册插件
 func (r *PluginRegistry) InitializePlugin(plugin Plugin, config map[string]interface{}) error {
 	if err := plugin.Initialize(config); err != nil {
@@ -0,0 +1,132 @@
+package datapuller
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// AuthType 数据源认证方式
+type AuthType string
+
+const (
+	AuthBearer AuthType = "bearer" // Authorization: Bearer <value>
+	AuthBasic  AuthType = "basic"  // Authorization: Basic，name 为用户名，value 为密码
+	AuthHeader AuthType = "header" // 自定义请求头 <name>: <value>
+	AuthQuery  AuthType = "query"  // 查询参数 ?<name>=<value>
+)
+
+const (
+	// DefaultAuthHeader header 认证未指定 name 时使用的请求头
+	DefaultAuthHeader = "X-API-Key"
+	// DefaultAuthQueryParam query 认证未指定 name 时使用的查询参数
+	DefaultAuthQueryParam = "apiKey"
+)
+
+// Auth 数据源认证配置，零值不认证。未指定 Type 时按 bearer 处理
+type Auth struct {
+	Type  AuthType
+	Name  string // 请求头名、查询参数名或 basic 用户名
+	Value string // 令牌、密钥或 basic 密码
+}
+
+// BearerAuth 返回使用 Bearer 令牌的认证配置，兼容原来的 apiKey 配置
+func BearerAuth(token string) Auth {
+	return Auth{Type: AuthBearer, Value: token}
+}
+
+// IsZero 判断是否未配置认证
+func (a Auth) IsZero() bool {
+	return a.Value == "" && a.Name == ""
+}
+
+// Validate 校验认证方式
+func (a Auth) Validate() error {
+	switch a.Type {
+	case "", AuthBearer, AuthBasic, AuthHeader, AuthQuery:
+		return nil
+	default:
+		return fmt.Errorf("unsupported auth type: %s", a.Type)
+	}
+}
+
+// Apply 将认证信息写入请求头或请求 URL
+func (a Auth) Apply(req *http.Request) {
+	a.apply(req.Header, req.URL)
+}
+
+// DialTarget 返回 WebSocket 拨号使用的 URL 和请求头，query 认证写入 URL，其余写入请求头
+func (a Auth) DialTarget(rawURL string, headers map[string]string) (string, http.Header, error) {
+	header := http.Header{}
+	for key, value := range headers {
+		header.Set(key, value)
+	}
+	if a.IsZero() {
+		return rawURL, header, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid url: %v", err)
+	}
+	a.apply(header, u)
+	return u.String(), header, nil
+}
+
+// apply 按认证方式设置请求头或查询参数
+func (a Auth) apply(header http.Header, u *url.URL) {
+	if a.IsZero() {
+		return
+	}
+
+	switch a.Type {
+	case AuthBasic:
+		credentials := base64.StdEncoding.EncodeToString([]byte(a.Name + ":" + a.Value))
+		header.Set("Authorization", "Basic "+credentials)
+	case AuthHeader:
+		header.Set(a.nameOr(DefaultAuthHeader), a.Value)
+	case AuthQuery:
+		query := u.Query()
+		query.Set(a.nameOr(DefaultAuthQueryParam), a.Value)
+		u.RawQuery = query.Encode()
+	default:
+		header.Set("Authorization", "Bearer "+a.Value)
+	}
+}
+
+// nameOr 返回配置的名称，未配置时返回 fallback
+func (a Auth) nameOr(fallback string) string {
+	if a.Name == "" {
+		return fallback
+	}
+	return a.Name
+}
+
+// parseAuth 解析插件配置中的认证信息。auth 为 {type, name, value}，
+// 未配置 auth 时兼容原来的 apiKey，作为 Bearer 令牌发送
+func parseAuth(config map[string]interface{}) (Auth, error) {
+	var auth Auth
+	switch raw := config["auth"].(type) {
+	case nil:
+		if apiKey, ok := config["apiKey"].(string); ok {
+			auth = BearerAuth(apiKey)
+		}
+	case Auth:
+		auth = raw
+	case map[string]interface{}:
+		authType, _ := raw["type"].(string)
+		name, _ := raw["name"].(string)
+		value, _ := raw["value"].(string)
+		auth = Auth{Type: AuthType(authType), Name: name, Value: value}
+	case map[string]string:
+		auth = Auth{Type: AuthType(raw["type"]), Name: raw["name"], Value: raw["value"]}
+	default:
+		return Auth{}, fmt.Errorf("invalid auth config: %v", raw)
+	}
+
+	if err := auth.Validate(); err != nil {
+		return Auth{}, err
+	}
+	return auth, nil
+}
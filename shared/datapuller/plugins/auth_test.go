@@ -0,0 +1,91 @@
+package datapuller
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAuth_Apply(t *testing.T) {
+	tests := []struct {
+		name   string
+		auth   Auth
+		header string
+		value  string
+		query  string
+	}{
+		{"none", Auth{}, "Authorization", "", "page=1"},
+		{"bearer", BearerAuth("secret"), "Authorization", "Bearer secret", "page=1"},
+		{"default type", Auth{Value: "secret"}, "Authorization", "Bearer secret", "page=1"},
+		{"basic", Auth{Type: AuthBasic, Name: "user", Value: "pass"}, "Authorization", "Basic dXNlcjpwYXNz", "page=1"},
+		{"header", Auth{Type: AuthHeader, Name: "X-Provider-Key", Value: "secret"}, "X-Provider-Key", "secret", "page=1"},
+		{"default header", Auth{Type: AuthHeader, Value: "secret"}, DefaultAuthHeader, "secret", "page=1"},
+		{"query", Auth{Type: AuthQuery, Name: "key", Value: "a b"}, "Authorization", "", "key=a+b&page=1"},
+		{"default query", Auth{Type: AuthQuery, Value: "secret"}, "Authorization", "", "apiKey=secret&page=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "https://node.example/v1?page=1", nil)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			tt.auth.Apply(req)
+
+			if got := req.Header.Get(tt.header); got != tt.value {
+				t.Errorf("Expected %s header %q, got %q", tt.header, tt.value, got)
+			}
+			if req.URL.RawQuery != tt.query {
+				t.Errorf("Expected query %s, got %s", tt.query, req.URL.RawQuery)
+			}
+		})
+	}
+}
+
+func TestAuth_DialTarget(t *testing.T) {
+	target, header, err := Auth{Type: AuthQuery, Name: "key", Value: "secret"}.DialTarget("wss://node.example/ws", map[string]string{"X-Client": "chainpulse"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if target != "wss://node.example/ws?key=secret" {
+		t.Errorf("Expected wss://node.example/ws?key=secret, got %s", target)
+	}
+	if header.Get("X-Client") != "chainpulse" {
+		t.Errorf("Expected custom header chainpulse, got %q", header.Get("X-Client"))
+	}
+
+	target, header, err = Auth{Type: AuthBasic, Name: "user", Value: "pass"}.DialTarget("wss://node.example/ws", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if target != "wss://node.example/ws" {
+		t.Errorf("Expected wss://node.example/ws, got %s", target)
+	}
+	if header.Get("Authorization") != "Basic dXNlcjpwYXNz" {
+		t.Errorf("Expected basic credentials, got %q", header.Get("Authorization"))
+	}
+}
+
+func TestParseAuth(t *testing.T) {
+	auth, err := parseAuth(map[string]interface{}{"apiKey": "secret"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if auth != BearerAuth("secret") {
+		t.Errorf("Expected apiKey as bearer token, got %+v", auth)
+	}
+
+	auth, err = parseAuth(map[string]interface{}{
+		"apiKey": "ignored",
+		"auth":   map[string]interface{}{"type": "header", "name": "X-Token", "value": "secret"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if auth != (Auth{Type: AuthHeader, Name: "X-Token", Value: "secret"}) {
+		t.Errorf("Expected header auth, got %+v", auth)
+	}
+
+	if _, err := parseAuth(map[string]interface{}{"auth": map[string]interface{}{"type": "digest"}}); err == nil {
+		t.Error("Expected an error for an unsupported auth type")
+	}
+}
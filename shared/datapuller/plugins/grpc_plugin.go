@@ -14,22 +14,23 @@ import (
 
 // GRPCPlugin gRPC 插件
 type GRPCPlugin struct {
-	name      string
-	address   string
-	conn      *grpc.ClientConn
-	client    pb.IndexerServiceClient
-	stream    pb.IndexerService_StreamEventsClient
-	mu        sync.RWMutex
-	ctx       context.Context
-	cancel    context.CancelFunc
-	reconnect bool
+	name          string
+	address       string
+	conn          *grpc.ClientConn
+	client        pb.IndexerServiceClient
+	mu            sync.RWMutex
+	ctx           context.Context
+	cancel        context.CancelFunc
+	autoReconnect bool
+	// lastSequence 最后收到的流事件序号，重连后从该序号之后继续
+	lastSequence uint64
 }
 
 // NewGRPCPlugin 创建 gRPC 插件
 func NewGRPCPlugin() *GRPCPlugin {
 	return &GRPCPlugin{
-		name:      "grpc",
-		reconnect: true,
+		name:          "grpc",
+		autoReconnect: true,
 	}
 }
 
@@ -95,41 +96,28 @@ func (p *GRPCPlugin) reconnect() error {
 	return p.connect()
 }
 
-// PullRealTime 拉取实时数据
+// PullRealTime 拉取实时数据。流断开后自动重连，并从最后收到的事件之后继续，
+// 断线期间的事件会被补发且只处理一次
 func (p *GRPCPlugin) PullRealTime(ctx context.Context, handler func(interface{}) error) error {
-	stream, err := p.client.StreamEvents(ctx, &pb.Empty{})
-	if err != nil {
-		return fmt.Errorf("failed to create stream: %v", err)
+	p.mu.Lock()
+	stream := &resumableEventStream{
+		client:       p.client,
+		reconnect:    p.autoReconnect,
+		retryDelay:   streamRetryDelay,
+		lastSequence: p.lastSequence,
 	}
+	p.mu.Unlock()
 
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			event, err := stream.Recv()
-			if err != nil {
-				// 尝试重连
-				if p.reconnect {
-					time.Sleep(1 * time.Second)
-					stream, err = p.client.StreamEvents(ctx, &pb.Empty{})
-					if err != nil {
-						time.Sleep(5 * time.Second) // 等待更长时间再试
-						continue
-					}
-					continue
-				}
-				return err
-			}
+	err := stream.Run(ctx, func(event *pb.Event) error {
+		return handler(p.grpcEventToInternal(event))
+	})
 
-			// 将 gRPC 事件转换为内部格式
-			internalEvent := p.grpcEventToInternal(event)
+	// 保存序号，下次拉取时同样从断点继续
+	p.mu.Lock()
+	p.lastSequence = stream.lastSequence
+	p.mu.Unlock()
 
-			if err := handler(internalEvent); err != nil {
-				return err
-			}
-		}
-	}
+	return err
 }
 
 // PullRealTimeEvents 拉取实时事件数据
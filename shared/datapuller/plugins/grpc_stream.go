@@ -0,0 +1,92 @@
+package datapuller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	pb "chainpulse/services/api/grpc"
+
+	"google.golang.org/grpc"
+)
+
+// streamRetryDelay 事件流断开后重连前的等待时间
+const streamRetryDelay = time.Second
+
+// eventStreamClient 打开事件流的 gRPC 客户端
+type eventStreamClient interface {
+	StreamEvents(ctx context.Context, in *pb.StreamEventsRequest, opts ...grpc.CallOption) (pb.IndexerService_StreamEventsClient, error)
+}
+
+// handlerError 标记处理函数返回的错误，此类错误不触发重连
+type handlerError struct {
+	err error
+}
+
+func (e *handlerError) Error() string { return e.err.Error() }
+
+func (e *handlerError) Unwrap() error { return e.err }
+
+// resumableEventStream 记录最后收到的事件序号，断开后重新打开的流从该序号之后继续。
+// 服务端只保留有限数量的最近事件，超出范围的事件无法补发，此时记录序号缺口
+type resumableEventStream struct {
+	client       eventStreamClient
+	reconnect    bool
+	retryDelay   time.Duration
+	lastSequence uint64
+}
+
+// Run 接收事件并交给 handler 处理，直到 ctx 取消、handler 返回错误，
+// 或者在不重连时流断开
+func (s *resumableEventStream) Run(ctx context.Context, handler func(*pb.Event) error) error {
+	for {
+		err := s.receive(ctx, handler)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if herr, ok := err.(*handlerError); ok {
+			return herr.err
+		}
+		if !s.reconnect {
+			return err
+		}
+
+		log.Printf("gRPC event stream interrupted after sequence %d, reconnecting: %v", s.lastSequence, err)
+		select {
+		case <-time.After(s.retryDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// receive 打开一个从最后序号之后开始的流，并处理其中的事件直到流结束
+func (s *resumableEventStream) receive(ctx context.Context, handler func(*pb.Event) error) error {
+	stream, err := s.client.StreamEvents(ctx, &pb.StreamEventsRequest{AfterSequence: s.lastSequence})
+	if err != nil {
+		return fmt.Errorf("failed to create stream: %v", err)
+	}
+
+	for {
+		streamed, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		sequence := streamed.GetSequence()
+		// 重连后补发的事件可能已经处理过
+		if sequence <= s.lastSequence {
+			continue
+		}
+		if s.lastSequence != 0 && sequence > s.lastSequence+1 {
+			log.Printf("gRPC event stream skipped %d events after sequence %d, beyond the server's resume lookback", sequence-s.lastSequence-1, s.lastSequence)
+		}
+
+		if err := handler(streamed.GetEvent()); err != nil {
+			return &handlerError{err: err}
+		}
+		s.lastSequence = sequence
+	}
+}
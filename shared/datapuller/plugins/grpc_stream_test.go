@@ -0,0 +1,187 @@
+package datapuller
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	pb "chainpulse/services/api/grpc"
+	"chainpulse/shared/types"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeEventStream serves a subscription of a pb.EventStream until it is broken
+type fakeEventStream struct {
+	grpc.ClientStream
+	ctx         context.Context
+	backlog     []*pb.StreamedEvent
+	events      <-chan *pb.StreamedEvent
+	unsubscribe func()
+	broken      chan struct{}
+}
+
+func (s *fakeEventStream) Recv() (*pb.StreamedEvent, error) {
+	if len(s.backlog) > 0 {
+		event := s.backlog[0]
+		s.backlog = s.backlog[1:]
+		return event, nil
+	}
+
+	select {
+	case event, ok := <-s.events:
+		if !ok {
+			return nil, status.Error(codes.ResourceExhausted, "fell behind")
+		}
+		return event, nil
+	case <-s.broken:
+		s.unsubscribe()
+		return nil, status.Error(codes.Unavailable, "transport is closing")
+	case <-s.ctx.Done():
+		s.unsubscribe()
+		return nil, s.ctx.Err()
+	}
+}
+
+// fakeStreamClient opens streams on a pb.EventStream. Every stream after the first waits
+// for reopen, and resumes overlap events early to simulate redelivery.
+type fakeStreamClient struct {
+	events  *pb.EventStream
+	overlap uint64
+	reopen  chan struct{}
+	opened  chan *fakeEventStream
+
+	mu       sync.Mutex
+	requests []uint64
+}
+
+func (c *fakeStreamClient) StreamEvents(ctx context.Context, in *pb.StreamEventsRequest, opts ...grpc.CallOption) (pb.IndexerService_StreamEventsClient, error) {
+	c.mu.Lock()
+	c.requests = append(c.requests, in.GetAfterSequence())
+	reopened := len(c.requests) > 1
+	c.mu.Unlock()
+
+	if reopened {
+		select {
+		case <-c.reopen:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	after := in.GetAfterSequence()
+	if after > c.overlap {
+		after -= c.overlap
+	}
+	backlog, events, unsubscribe := c.events.Subscribe(after)
+
+	stream := &fakeEventStream{ctx: ctx, backlog: backlog, events: events, unsubscribe: unsubscribe, broken: make(chan struct{})}
+	c.opened <- stream
+	return stream, nil
+}
+
+func publishStreamEvent(events *pb.EventStream, txHash string) uint64 {
+	return events.Publish(types.IndexedEvent{BlockNumber: big.NewInt(1), TxHash: txHash, EventName: "Transfer"})
+}
+
+func TestResumableEventStream_ResumesAfterDisconnect(t *testing.T) {
+	events := pb.NewEventStream(100)
+	client := &fakeStreamClient{events: events, overlap: 1, reopen: make(chan struct{}), opened: make(chan *fakeEventStream, 2)}
+	stream := &resumableEventStream{client: client, reconnect: true, retryDelay: time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- stream.Run(ctx, func(event *pb.Event) error {
+			received <- event.TxHash
+			return nil
+		})
+	}()
+
+	expectEvents := func(txHashes ...string) {
+		t.Helper()
+		for _, want := range txHashes {
+			select {
+			case got := <-received:
+				if got != want {
+					t.Fatalf("Expected event %s, got %s", want, got)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Expected event %s", want)
+			}
+		}
+	}
+
+	first := <-client.opened
+	publishStreamEvent(events, "0x1")
+	lastReceived := publishStreamEvent(events, "0x2")
+	expectEvents("0x1", "0x2")
+
+	// Events published while disconnected are missed by the live stream
+	close(first.broken)
+	publishStreamEvent(events, "0x3")
+	publishStreamEvent(events, "0x4")
+	client.reopen <- struct{}{}
+	<-client.opened
+
+	publishStreamEvent(events, "0x5")
+	expectEvents("0x3", "0x4", "0x5")
+
+	// 0x2 was redelivered on resume but is handled only once
+	select {
+	case txHash := <-received:
+		t.Fatalf("Expected each event exactly once, got %s again", txHash)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	client.mu.Lock()
+	requests := append([]uint64(nil), client.requests...)
+	client.mu.Unlock()
+	if len(requests) != 2 || requests[0] != 0 || requests[1] != lastReceived {
+		t.Errorf("Expected streams after sequences [0 %d], got %v", lastReceived, requests)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestResumableEventStream_HandlerErrorStops(t *testing.T) {
+	events := pb.NewEventStream(100)
+	client := &fakeStreamClient{events: events, reopen: make(chan struct{}), opened: make(chan *fakeEventStream, 1)}
+	stream := &resumableEventStream{client: client, reconnect: true, retryDelay: time.Millisecond}
+
+	handlerErr := errors.New("cannot handle event")
+	done := make(chan error, 1)
+	go func() {
+		done <- stream.Run(context.Background(), func(event *pb.Event) error {
+			return handlerErr
+		})
+	}()
+
+	<-client.opened
+	publishStreamEvent(events, "0x1")
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, handlerErr) {
+			t.Errorf("Expected the handler error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Run to return the handler error")
+	}
+
+	// The failed event is not recorded as received
+	if stream.lastSequence != 0 {
+		t.Errorf("Expected last sequence 0, got %d", stream.lastSequence)
+	}
+}
@@ -3,23 +3,34 @@ package datapuller
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"math/big"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/utils"
 )
 
 // HTTPSJSONRPCPlugin HTTPS JSONRPC 插件
 type HTTPSJSONRPCPlugin struct {
 	name       string
 	url        string
-	apiKey     string
+	auth       Auth
 	headers    map[string]string
 	client     *http.Client
 	batchSize  int
 	retryCount int
+	backoff    *RetryConfig
+	pool       utils.HTTPPoolConfig
+	allowed    *methodAllowList
+	metrics    ThrottleRecorder // 记录被限流的请求，为空时不记录
+
+	mu        sync.Mutex
+	notBefore time.Time // 被限流后，所有调用在此之前都不发送请求
 }
 
 // NewHTTPSJSONRPCPlugin 创建 HTTPS JSONRPC 插件
@@ -29,9 +40,16 @@ func NewHTTPSJSONRPCPlugin() *HTTPSJSONRPCPlugin {
 		headers:    make(map[string]string),
 		batchSize:  100,
 		retryCount: 3,
+		backoff:    DefaultRetryConfig,
+		allowed:    newMethodAllowList(DefaultAllowedMethods),
 	}
 }
 
+// SetThrottleRecorder 设置记录被限流请求的指标收集器
+func (p *HTTPSJSONRPCPlugin) SetThrottleRecorder(recorder ThrottleRecorder) {
+	p.metrics = recorder
+}
+
 // Name 返回插件名称
 func (p *HTTPSJSONRPCPlugin) Name() string {
 	return p.name
@@ -51,9 +69,12 @@ func (p *HTTPSJSONRPCPlugin) Initialize(config map[string]interface{}) error {
 		return fmt.Errorf("missing required 'url' configuration")
 	}
 
-	if apiKey, ok := config["apiKey"].(string); ok {
-		p.apiKey = apiKey
+	// 认证方式，未配置 auth 时 apiKey 作为 Bearer 令牌发送
+	auth, err := parseAuth(config)
+	if err != nil {
+		return err
 	}
+	p.auth = auth
 
 	if headers, ok := config["headers"].(map[string]string); ok {
 		p.headers = headers
@@ -67,11 +88,36 @@ func (p *HTTPSJSONRPCPlugin) Initialize(config map[string]interface{}) error {
 		p.retryCount = retryCount
 	}
 
-	// 创建 HTTP 客户端
-	p.client = &http.Client{
-		Timeout: 30 * time.Second,
+	// 重试退避，未配置的项使用默认的指数退避
+	backoff, err := parseRetryConfig(config)
+	if err != nil {
+		return err
+	}
+	p.backoff = backoff
+
+	// 方法白名单，未配置时只允许读取链上数据的 eth 方法
+	allowed, err := parseAllowedMethods(config)
+	if err != nil {
+		return err
+	}
+	p.allowed = allowed
+
+	// 连接池配置，未设置的项使用默认值
+	if maxIdleConns, ok := config["maxIdleConns"].(int); ok {
+		p.pool.MaxIdleConns = maxIdleConns
+	}
+
+	if maxIdleConnsPerHost, ok := config["maxIdleConnsPerHost"].(int); ok {
+		p.pool.MaxIdleConnsPerHost = maxIdleConnsPerHost
 	}
 
+	if idleConnTimeout, ok := config["idleConnTimeout"].(time.Duration); ok {
+		p.pool.IdleConnTimeout = idleConnTimeout
+	}
+
+	// 创建 HTTP 客户端，所有调用复用同一个连接池
+	p.client = utils.NewHTTPClient(30*time.Second, p.pool)
+
 	return nil
 }
 
@@ -99,6 +145,11 @@ type JSONRPCError struct {
 
 // callJSONRPC 调用 JSONRPC 方法
 func (p *HTTPSJSONRPCPlugin) callJSONRPC(ctx context.Context, method string, params []interface{}) (interface{}, error) {
+	// 在发送前拒绝白名单之外的方法
+	if err := p.allowed.check(method); err != nil {
+		return nil, err
+	}
+
 	request := JSONRPCRequest{
 		JSONRPC: "2.0",
 		Method:  method,
@@ -118,53 +169,126 @@ func (p *HTTPSJSONRPCPlugin) callJSONRPC(ctx context.Context, method string, par
 
 	// 设置请求头
 	req.Header.Set("Content-Type", "application/json")
-	if p.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+p.apiKey)
-	}
-
 	for key, value := range p.headers {
 		req.Header.Set(key, value)
 	}
+	p.auth.Apply(req)
 
 	// 重试机制
 	var lastErr error
+	throttled := false // 上一次尝试被限流时，等待限流期限而不是指数退避
 	for i := 0; i < p.retryCount; i++ {
 		if i > 0 {
-			time.Sleep(time.Duration(i) * time.Second) // 指数退避
-		}
+			// 指数退避，上下文取消后不再重试
+			if !throttled {
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(p.backoff.ComputeBackoff(i - 1)):
+				}
+			}
+			throttled = false
 
-		resp, err := p.client.Do(req)
-		if err != nil {
-			lastErr = err
-			continue
+			// 请求体已在上一次尝试中读完
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, fmt.Errorf("failed to reset request body: %v", err)
+			}
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			lastErr = fmt.Errorf("request failed with status: %d", resp.StatusCode)
-			continue
+		// 每次尝试前等待限流期限，包括其他调用触发的限流
+		if err := p.waitNotBefore(ctx); err != nil {
+			return nil, err
 		}
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			lastErr = fmt.Errorf("failed to read response body: %v", err)
-			continue
+		result, retry, err := p.attempt(req, i)
+		if err == nil {
+			return result, nil
 		}
-
-		var jsonResp JSONRPCResponse
-		if err := json.Unmarshal(body, &jsonResp); err != nil {
-			lastErr = fmt.Errorf("failed to unmarshal response: %v", err)
-			continue
+		if !retry {
+			return nil, err
 		}
+		lastErr = err
+		throttled = errors.Is(err, errThrottled)
+	}
+
+	return nil, fmt.Errorf("failed after %d retries: %v", p.retryCount, lastErr)
+}
+
+// errThrottled 表示请求被服务商限流
+var errThrottled = errors.New("request rate limited")
+
+// attempt 发送一次请求，返回前关闭响应体，重试前不会留下未关闭的连接。
+// retry 表示失败可以重试；被限流时返回 errThrottled 并推迟限流期限
+func (p *HTTPSJSONRPCPlugin) attempt(req *http.Request, i int) (interface{}, bool, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		p.throttle(resp, i)
+		return nil, true, fmt.Errorf("%w with status: %d", errThrottled, resp.StatusCode)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, true, fmt.Errorf("request failed with status: %d", resp.StatusCode)
+	}
+
+	// 流式解析响应数据，不将整个响应体读入内存
+	var jsonResp JSONRPCResponse
+	if err := json.NewDecoder(resp.Body).Decode(&jsonResp); err != nil {
+		return nil, true, fmt.Errorf("failed to decode response: %v", err)
+	}
 
-		if jsonResp.Error != nil {
-			return nil, fmt.Errorf("JSONRPC error: code=%d, message=%s", jsonResp.Error.Code, jsonResp.Error.Message)
+	if jsonResp.Error != nil {
+		if isRateLimitError(jsonResp.Error) {
+			p.throttle(resp, i)
+			return nil, true, fmt.Errorf("%w: code=%d, message=%s", errThrottled, jsonResp.Error.Code, jsonResp.Error.Message)
 		}
+		return nil, false, fmt.Errorf("JSONRPC error: code=%d, message=%s", jsonResp.Error.Code, jsonResp.Error.Message)
+	}
 
-		return jsonResp.Result, nil
+	return jsonResp.Result, false, nil
+}
+
+// throttle 记录一次被限流的请求，并推迟插件的限流期限：第 attempt 次尝试后需要等待的
+// 时间优先使用 Retry-After 响应头，缺少时使用正常的指数退避。期限对插件的所有调用生效，
+// 避免并发的调用在等待期间继续请求
+func (p *HTTPSJSONRPCPlugin) throttle(resp *http.Response, attempt int) {
+	now := time.Now()
+	delay, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), now)
+	if !ok {
+		delay = p.backoff.ComputeBackoff(attempt)
+	}
+	if p.metrics != nil {
+		p.metrics.RecordThrottled(p.name, delay)
 	}
 
-	return nil, fmt.Errorf("failed after %d retries: %v", p.retryCount, lastErr)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if deadline := now.Add(delay); deadline.After(p.notBefore) {
+		p.notBefore = deadline
+	}
+}
+
+// waitNotBefore 等待到限流期限，上下文取消时返回错误
+func (p *HTTPSJSONRPCPlugin) waitNotBefore(ctx context.Context) error {
+	p.mu.Lock()
+	wait := time.Until(p.notBefore)
+	p.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
 // PullRealTime 拉取实时数据
@@ -224,25 +348,59 @@ func (p *HTTPSJSONRPCPlugin) PullRealTimeEvents(ctx context.Context, handler fun
 
 			lastBlockNumber = blockNumber
 
-			// 获取区块中的交易
-			transactions, ok := blockData["transactions"].([]interface{})
-			if !ok {
-				continue
+			// 处理区块中每个交易的事件
+			for _, txData := range p.blockTransactions(ctx, blockData) {
+				if err := handler(txData); err != nil {
+					fmt.Printf("Error handling transaction: %v\n", err)
+				}
 			}
+		}
+	}
+}
 
-			// 处理每个交易的事件
-			for _, tx := range transactions {
-				if txData, ok := tx.(map[string]interface{}); ok {
-					if err := handler(txData); err != nil {
-						fmt.Printf("Error handling transaction: %v\n", err)
-					}
-				}
+// blockTransactions 返回区块中的交易。fullTx=true 的响应包含交易对象，直接返回；
+// fullTx=false 或轻节点的响应只包含交易哈希，逐个拉取交易回执代替；
+// 缺少 transactions 字段时返回空
+func (p *HTTPSJSONRPCPlugin) blockTransactions(ctx context.Context, blockData map[string]interface{}) []map[string]interface{} {
+	raw, exists := blockData["transactions"]
+	if !exists || raw == nil {
+		fmt.Printf("Block %v has no transactions field, skipping its transactions\n", blockData["number"])
+		return nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		fmt.Printf("Block %v has invalid transactions field of type %T\n", blockData["number"], raw)
+		return nil
+	}
+
+	transactions := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		switch tx := item.(type) {
+		case map[string]interface{}:
+			transactions = append(transactions, tx)
+		case string:
+			// 只有交易哈希，拉取回执获取交易的地址和日志
+			result, err := p.callJSONRPC(ctx, "eth_getTransactionReceipt", []interface{}{tx})
+			if err != nil {
+				fmt.Printf("Error getting receipt of transaction %s: %v\n", tx, err)
+				continue
 			}
+			receipt, ok := result.(map[string]interface{})
+			if !ok {
+				// 节点尚未返回回执
+				fmt.Printf("No receipt for transaction %s\n", tx)
+				continue
+			}
+			transactions = append(transactions, receipt)
+		default:
+			fmt.Printf("Skipping transaction of unexpected type %T in block %v\n", item, blockData["number"])
 		}
 	}
+	return transactions
 }
 
-// PullBatch 拉取批量数据
+// PullBatch 拉取时间戳在 [start, end) 内的区块。区块范围按区块时间戳二分查找确定，
+// 因此相邻的时间段不会重复或遗漏区块；任一区块拉取失败时返回错误，以便调用方从该时间段重试
 func (p *HTTPSJSONRPCPlugin) PullBatch(ctx context.Context, start, end time.Time) ([]interface{}, error) {
 	var allData []interface{}
 
@@ -257,30 +415,33 @@ func (p *HTTPSJSONRPCPlugin) PullBatch(ctx context.Context, start, end time.Time
 		return nil, fmt.Errorf("invalid block number format")
 	}
 
-	currentBlock := hexToInt(currentBlockHex)
-
-	// 根据时间范围计算区块范围（简化实现）
-	// 实际应用中需要根据区块时间戳来计算
-	endBlock := currentBlock
-	startBlock := currentBlock - 100 // 假设每3秒一个区块，100个区块约5分钟
+	currentBlock, err := hexToBlockNumber(currentBlockHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse current block number: %v", err)
+	}
 
-	if startBlock < 0 {
-		startBlock = 0
+	// 根据区块时间戳计算区块范围
+	startBlock, err := p.firstBlockAt(ctx, start, currentBlock)
+	if err != nil {
+		return nil, err
+	}
+	endBlock, err := p.firstBlockAt(ctx, end, currentBlock)
+	if err != nil {
+		return nil, err
 	}
 
-	// 批量获取区块数据
-	for blockNum := startBlock; blockNum <= endBlock; blockNum++ {
+	// 批量获取区块数据，endBlock 是第一个不早于 end 的区块，不包含在内
+	for blockNum := startBlock; blockNum < endBlock; blockNum++ {
 		blockHex := intToHex(blockNum)
 		result, err := p.callJSONRPC(ctx, "eth_getBlockByNumber", []interface{}{blockHex, true})
 		if err != nil {
-			fmt.Printf("Error getting block %s: %v\n", blockHex, err)
-			continue
+			return nil, fmt.Errorf("failed to get block %s: %v", blockHex, err)
 		}
 
 		allData = append(allData, result)
 
 		// 添加小延迟以避免过于频繁的请求
-		if blockNum%p.batchSize == 0 {
+		if blockNum%int64(p.batchSize) == 0 {
 			time.Sleep(100 * time.Millisecond)
 		}
 	}
@@ -288,6 +449,47 @@ func (p *HTTPSJSONRPCPlugin) PullBatch(ctx context.Context, start, end time.Time
 	return allData, nil
 }
 
+// firstBlockAt 二分查找 0 到 latest 之间第一个时间戳不早于 t 的区块；
+// 所有区块都早于 t 时返回 latest+1
+func (p *HTTPSJSONRPCPlugin) firstBlockAt(ctx context.Context, t time.Time, latest int64) (int64, error) {
+	low, high := int64(0), latest+1
+	for low < high {
+		mid := low + (high-low)/2
+		timestamp, err := p.blockTimestamp(ctx, mid)
+		if err != nil {
+			return 0, err
+		}
+		if timestamp.Before(t) {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+	return low, nil
+}
+
+// blockTimestamp 返回区块的时间戳，只拉取区块头不拉取交易
+func (p *HTTPSJSONRPCPlugin) blockTimestamp(ctx context.Context, number int64) (time.Time, error) {
+	blockHex := intToHex(number)
+	result, err := p.callJSONRPC(ctx, "eth_getBlockByNumber", []interface{}{blockHex, false})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get block %s: %v", blockHex, err)
+	}
+	block, ok := result.(map[string]interface{})
+	if !ok {
+		return time.Time{}, fmt.Errorf("block %s not found", blockHex)
+	}
+	timestampHex, ok := block["timestamp"].(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("block %s has no timestamp", blockHex)
+	}
+	timestamp, err := hexToBlockNumber(timestampHex)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse timestamp of block %s: %v", blockHex, err)
+	}
+	return time.Unix(timestamp, 0), nil
+}
+
 // PullLatest 拉取最新数据
 func (p *HTTPSJSONRPCPlugin) PullLatest(ctx context.Context) (interface{}, error) {
 	return p.callJSONRPC(ctx, "eth_getBlockByNumber", []interface{}{"latest", true})
@@ -314,13 +516,9 @@ func (p *HTTPSJSONRPCPlugin) PullWithFilters(ctx context.Context, filters map[st
 	}
 
 	// 获取交易数据并应用过滤器
-	if transactions, ok := blockData["transactions"].([]interface{}); ok {
-		for _, tx := range transactions {
-			if txData, ok := tx.(map[string]interface{}); ok {
-				if matchesFilters(txData, filters) {
-					results = append(results, txData)
-				}
-			}
+	for _, txData := range p.blockTransactions(ctx, blockData) {
+		if matchesFilters(txData, filters) {
+			results = append(results, txData)
 		}
 	}
 
@@ -360,10 +558,29 @@ func (p *HTTPSJSONRPCPlugin) Close() error {
 }
 
 // 辅助函数
-func hexToInt(hex string) int64 {
-	var result int64
-	fmt.Sscanf(hex, "0x%x", &result)
-	return result
+// hexToInt 解析十六进制数量，"0x" 前缀可选；数值不受 int64 范围限制，无法解析时返回错误
+func hexToInt(hex string) (*big.Int, error) {
+	trimmed := strings.TrimPrefix(strings.TrimPrefix(hex, "0x"), "0X")
+	if trimmed == "" {
+		return nil, fmt.Errorf("invalid hex quantity %q", hex)
+	}
+	result, ok := new(big.Int).SetString(trimmed, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid hex quantity %q", hex)
+	}
+	return result, nil
+}
+
+// hexToBlockNumber 解析十六进制区块号，超出 int64 范围时返回错误
+func hexToBlockNumber(hex string) (int64, error) {
+	number, err := hexToInt(hex)
+	if err != nil {
+		return 0, err
+	}
+	if !number.IsInt64() {
+		return 0, fmt.Errorf("block number %s out of range", number.String())
+	}
+	return number.Int64(), nil
 }
 
 func intToHex(num int64) string {
@@ -0,0 +1,524 @@
+package datapuller
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"chainpulse/shared/json"
+)
+
+// newJSONRPCServer answers every JSONRPC request with blockNumber and counts the
+// connections dialed to it
+func newJSONRPCServer(t *testing.T) (*httptest.Server, *int32) {
+	var dials int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(JSONRPCResponse{JSONRPC: "2.0", Result: "0x10", ID: 1})
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&dials, 1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &dials
+}
+
+func TestHTTPSJSONRPCPlugin_ReusesConnections(t *testing.T) {
+	server, dials := newJSONRPCServer(t)
+
+	plugin := NewHTTPSJSONRPCPlugin()
+	if err := plugin.Initialize(map[string]interface{}{"url": server.URL}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer plugin.Close()
+
+	for i := 0; i < 20; i++ {
+		if _, err := plugin.callJSONRPC(context.Background(), "eth_blockNumber", []interface{}{}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(dials); got != 1 {
+		t.Errorf("Expected sequential calls to reuse 1 connection, got %d dials", got)
+	}
+}
+
+func TestHTTPSJSONRPCPlugin_PoolConfig(t *testing.T) {
+	plugin := NewHTTPSJSONRPCPlugin()
+	err := plugin.Initialize(map[string]interface{}{
+		"url":                 "http://localhost:8545",
+		"maxIdleConnsPerHost": 8,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	transport, ok := plugin.client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected an *http.Transport, got %T", plugin.client.Transport)
+	}
+	if transport.MaxIdleConnsPerHost != 8 {
+		t.Errorf("Expected 8 idle connections per host, got %d", transport.MaxIdleConnsPerHost)
+	}
+	if transport.MaxIdleConns == 0 || transport.IdleConnTimeout == 0 {
+		t.Errorf("Expected defaults for unset pool settings, got %d idle connections and %v timeout", transport.MaxIdleConns, transport.IdleConnTimeout)
+	}
+}
+
+func TestHTTPSJSONRPCPlugin_Auth(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		json.NewEncoder(w).Encode(JSONRPCResponse{JSONRPC: "2.0", Result: "0x10", ID: 1})
+	}))
+	defer server.Close()
+
+	plugin := NewHTTPSJSONRPCPlugin()
+	err := plugin.Initialize(map[string]interface{}{
+		"url":  server.URL,
+		"auth": map[string]interface{}{"type": "basic", "name": "user", "value": "pass"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer plugin.Close()
+
+	if _, err := plugin.callJSONRPC(context.Background(), "eth_blockNumber", []interface{}{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if received.Get("Authorization") != "Basic dXNlcjpwYXNz" {
+		t.Errorf("Expected basic credentials, got %q", received.Get("Authorization"))
+	}
+}
+
+func BenchmarkHTTPSJSONRPCPlugin_SequentialCalls(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(JSONRPCResponse{JSONRPC: "2.0", Result: "0x10", ID: 1})
+	}))
+	defer server.Close()
+
+	plugin := NewHTTPSJSONRPCPlugin()
+	if err := plugin.Initialize(map[string]interface{}{"url": server.URL}); err != nil {
+		b.Fatal(err)
+	}
+	defer plugin.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := plugin.callJSONRPC(context.Background(), "eth_blockNumber", []interface{}{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestHTTPSJSONRPCPlugin_DisallowedMethod(t *testing.T) {
+	server, dials := newJSONRPCServer(t)
+
+	plugin := NewHTTPSJSONRPCPlugin()
+	if err := plugin.Initialize(map[string]interface{}{"url": server.URL}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer plugin.Close()
+
+	// debug_* is not in the default eth-read allow-list
+	_, err := plugin.callJSONRPC(context.Background(), "debug_traceTransaction", []interface{}{"0xabc"})
+	if !errors.Is(err, ErrMethodNotAllowed) {
+		t.Errorf("Expected ErrMethodNotAllowed, got %v", err)
+	}
+	if got := atomic.LoadInt32(dials); got != 0 {
+		t.Errorf("Expected no network call for a disallowed method, got %d dials", got)
+	}
+
+	result, err := plugin.callJSONRPC(context.Background(), "eth_blockNumber", []interface{}{})
+	if err != nil {
+		t.Fatalf("Expected allowed method to succeed, got %v", err)
+	}
+	if result != "0x10" {
+		t.Errorf("Expected result 0x10, got %v", result)
+	}
+}
+
+func TestHTTPSJSONRPCPlugin_ConfiguredAllowList(t *testing.T) {
+	server, _ := newJSONRPCServer(t)
+
+	plugin := NewHTTPSJSONRPCPlugin()
+	err := plugin.Initialize(map[string]interface{}{
+		"url":            server.URL,
+		"allowedMethods": []interface{}{"eth_blockNumber", "debug_*"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer plugin.Close()
+
+	for _, method := range []string{"eth_blockNumber", "debug_traceTransaction"} {
+		if _, err := plugin.callJSONRPC(context.Background(), method, []interface{}{}); err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", method, err)
+		}
+	}
+
+	// Only the configured methods are allowed, not the defaults
+	if _, err := plugin.callJSONRPC(context.Background(), "eth_getLogs", []interface{}{}); !errors.Is(err, ErrMethodNotAllowed) {
+		t.Errorf("Expected ErrMethodNotAllowed for eth_getLogs, got %v", err)
+	}
+
+	if err := plugin.Initialize(map[string]interface{}{"url": server.URL, "allowedMethods": 42}); err == nil {
+		t.Error("Expected error for an invalid allow-list")
+	}
+}
+
+func TestHTTPSJSONRPCPlugin_BlockTransactions(t *testing.T) {
+	var receipts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req JSONRPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "eth_getTransactionReceipt" {
+			t.Errorf("Expected a receipt request, got %+v: %v", req, err)
+		}
+		atomic.AddInt32(&receipts, 1)
+		json.NewEncoder(w).Encode(JSONRPCResponse{JSONRPC: "2.0", Result: map[string]interface{}{
+			"transactionHash": req.Params[0],
+			"logs":            []interface{}{},
+		}, ID: 1})
+	}))
+	defer server.Close()
+
+	plugin := NewHTTPSJSONRPCPlugin()
+	if err := plugin.Initialize(map[string]interface{}{"url": server.URL}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer plugin.Close()
+	ctx := context.Background()
+
+	// fullTx=true: the transaction objects are used as they are
+	full := plugin.blockTransactions(ctx, map[string]interface{}{
+		"number":       "0x10",
+		"transactions": []interface{}{map[string]interface{}{"hash": "0xaa"}, map[string]interface{}{"hash": "0xbb"}},
+	})
+	if len(full) != 2 || full[0]["hash"] != "0xaa" || full[1]["hash"] != "0xbb" {
+		t.Errorf("Expected the 2 transaction objects, got %v", full)
+	}
+	if got := atomic.LoadInt32(&receipts); got != 0 {
+		t.Errorf("Expected no receipt requests for full transactions, got %d", got)
+	}
+
+	// fullTx=false: the receipt of every hash is fetched
+	hashes := plugin.blockTransactions(ctx, map[string]interface{}{
+		"number":       "0x11",
+		"transactions": []interface{}{"0xcc", "0xdd"},
+	})
+	if len(hashes) != 2 || hashes[0]["transactionHash"] != "0xcc" || hashes[1]["transactionHash"] != "0xdd" {
+		t.Errorf("Expected the receipts of 0xcc and 0xdd, got %v", hashes)
+	}
+	if got := atomic.LoadInt32(&receipts); got != 2 {
+		t.Errorf("Expected 2 receipt requests, got %d", got)
+	}
+
+	// Light clients may omit the transactions
+	if missing := plugin.blockTransactions(ctx, map[string]interface{}{"number": "0x12"}); len(missing) != 0 {
+		t.Errorf("Expected no transactions, got %v", missing)
+	}
+}
+
+// chainServer serves a chain of blocks numbered 0 to head, mined every 12 seconds from
+// genesis, counting the full blocks fetched
+func chainServer(t *testing.T, head int64, genesis time.Time) (*httptest.Server, *int32) {
+	var fullBlocks int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req JSONRPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Expected a JSONRPC request, got %v", err)
+		}
+		var result interface{}
+		switch req.Method {
+		case "eth_blockNumber":
+			result = intToHex(head)
+		case "eth_getBlockByNumber":
+			number, err := hexToBlockNumber(req.Params[0].(string))
+			if err != nil || number > head {
+				break
+			}
+			if req.Params[1] == true {
+				atomic.AddInt32(&fullBlocks, 1)
+			}
+			result = map[string]interface{}{
+				"number":    intToHex(number),
+				"timestamp": intToHex(genesis.Unix() + 12*number),
+			}
+		}
+		json.NewEncoder(w).Encode(JSONRPCResponse{JSONRPC: "2.0", Result: result, ID: req.ID})
+	}))
+	t.Cleanup(server.Close)
+	return server, &fullBlocks
+}
+
+func TestHTTPSJSONRPCPlugin_PullBatchHonoursTimeRange(t *testing.T) {
+	genesis := time.Unix(1700000000, 0)
+	server, fullBlocks := chainServer(t, 999, genesis)
+
+	plugin := NewHTTPSJSONRPCPlugin()
+	if err := plugin.Initialize(map[string]interface{}{"url": server.URL}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer plugin.Close()
+
+	// Consecutive pages, as pulled by PullHistoricalStream, return every block once
+	var numbers []string
+	start := genesis.Add(100 * 12 * time.Second)
+	for page := 0; page < 3; page++ {
+		pageStart := start.Add(time.Duration(page) * time.Minute)
+		data, err := plugin.PullHistorical(context.Background(), pageStart, pageStart.Add(time.Minute), nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, item := range data {
+			numbers = append(numbers, item.(map[string]interface{})["number"].(string))
+		}
+	}
+
+	// 3 minutes of 12 second blocks from block 100
+	if len(numbers) != 15 {
+		t.Fatalf("Expected 15 blocks, got %d: %v", len(numbers), numbers)
+	}
+	for i, number := range numbers {
+		if expected := intToHex(int64(100 + i)); number != expected {
+			t.Errorf("Expected block %s at %d, got %s", expected, i, number)
+		}
+	}
+	if got := atomic.LoadInt32(fullBlocks); got != 15 {
+		t.Errorf("Expected only the 15 blocks in range to be fetched, got %d", got)
+	}
+
+	// A range after the head has no blocks yet
+	data, err := plugin.PullBatch(context.Background(), genesis.Add(time.Hour*24), genesis.Add(time.Hour*25))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(data) != 0 {
+		t.Errorf("Expected no blocks after the head, got %d", len(data))
+	}
+}
+
+// throttleRecorder records the throttled requests of every plugin
+type throttleRecorder struct {
+	count int
+	wait  time.Duration
+}
+
+func (r *throttleRecorder) RecordThrottled(pluginName string, wait time.Duration) {
+	r.count++
+	r.wait += wait
+}
+
+// throttlingServer answers the first request with throttle, then every request with
+// blockNumber, recording when each request arrived
+func throttlingServer(t *testing.T, throttle http.HandlerFunc) (*httptest.Server, *[]time.Time) {
+	var arrivals []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrivals = append(arrivals, time.Now())
+		if len(arrivals) == 1 {
+			throttle(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(JSONRPCResponse{JSONRPC: "2.0", Result: "0x10", ID: 1})
+	}))
+	t.Cleanup(server.Close)
+	return server, &arrivals
+}
+
+func TestHTTPSJSONRPCPlugin_RetryAfter(t *testing.T) {
+	server, arrivals := throttlingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	plugin := NewHTTPSJSONRPCPlugin()
+	if err := plugin.Initialize(map[string]interface{}{"url": server.URL}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer plugin.Close()
+	// The normal backoff would retry right away
+	plugin.backoff = &RetryConfig{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffMultiplier: 1}
+	recorder := &throttleRecorder{}
+	plugin.SetThrottleRecorder(recorder)
+
+	result, err := plugin.callJSONRPC(context.Background(), "eth_blockNumber", []interface{}{})
+	if err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+	if result != "0x10" {
+		t.Errorf("Expected result 0x10, got %v", result)
+	}
+
+	if len(*arrivals) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(*arrivals))
+	}
+	if wait := (*arrivals)[1].Sub((*arrivals)[0]); wait < 2*time.Second {
+		t.Errorf("Expected at least 2s before the retry, got %v", wait)
+	}
+
+	if recorder.count != 1 || recorder.wait != 2*time.Second {
+		t.Errorf("Expected 1 throttled request waiting 2s, got %d waiting %v", recorder.count, recorder.wait)
+	}
+}
+
+func TestHTTPSJSONRPCPlugin_RetryAfterDelaysOtherCalls(t *testing.T) {
+	server, arrivals := throttlingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	plugin := NewHTTPSJSONRPCPlugin()
+	if err := plugin.Initialize(map[string]interface{}{"url": server.URL}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer plugin.Close()
+	plugin.retryCount = 1
+
+	// The throttled call gives up, the next call still waits out the Retry-After
+	if _, err := plugin.callJSONRPC(context.Background(), "eth_blockNumber", []interface{}{}); err == nil {
+		t.Fatal("Expected the throttled call to fail")
+	}
+	if _, err := plugin.callJSONRPC(context.Background(), "eth_blockNumber", []interface{}{}); err != nil {
+		t.Fatalf("Expected the next call to succeed, got %v", err)
+	}
+
+	if len(*arrivals) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(*arrivals))
+	}
+	if wait := (*arrivals)[1].Sub((*arrivals)[0]); wait < time.Second {
+		t.Errorf("Expected at least 1s before the next request, got %v", wait)
+	}
+}
+
+func TestHTTPSJSONRPCPlugin_RateLimitErrorCode(t *testing.T) {
+	server, arrivals := throttlingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(JSONRPCResponse{JSONRPC: "2.0", Error: &JSONRPCError{Code: -32005, Message: "limit exceeded"}, ID: 1})
+	})
+
+	plugin := NewHTTPSJSONRPCPlugin()
+	if err := plugin.Initialize(map[string]interface{}{"url": server.URL}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer plugin.Close()
+	plugin.backoff = &RetryConfig{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffMultiplier: 1}
+	recorder := &throttleRecorder{}
+	plugin.SetThrottleRecorder(recorder)
+
+	// Rate limit errors are retried, other JSONRPC errors are returned right away
+	if _, err := plugin.callJSONRPC(context.Background(), "eth_blockNumber", []interface{}{}); err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+	if len(*arrivals) != 2 {
+		t.Errorf("Expected 2 requests, got %d", len(*arrivals))
+	}
+	if recorder.count != 1 {
+		t.Errorf("Expected 1 throttled request, got %d", recorder.count)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 11, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"2", 2 * time.Second, true},
+		{" 0 ", 0, true},
+		{"Wed, 01 Nov 2023 12:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 01 Nov 2023 11:59:00 GMT", 0, true}, // already passed
+		{"86400", maxRetryAfter, true},
+		{"", 0, false},
+		{"-1", 0, false},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		delay, ok := ParseRetryAfter(tt.value, now)
+		if ok != tt.ok || delay != tt.expected {
+			t.Errorf("Expected %v (%t) for %q, got %v (%t)", tt.expected, tt.ok, tt.value, delay, ok)
+		}
+	}
+}
+
+func TestHexToInt(t *testing.T) {
+	tests := []struct {
+		hex      string
+		expected string
+	}{
+		{"0x10d4f", "68943"},
+		{"10d4f", "68943"},
+		{"0x0", "0"},
+		{"0x10000000000000000", "18446744073709551616"}, // exceeds int64
+	}
+
+	for _, tt := range tests {
+		result, err := hexToInt(tt.hex)
+		if err != nil {
+			t.Errorf("Expected %s to parse, got error: %v", tt.hex, err)
+			continue
+		}
+		if result.String() != tt.expected {
+			t.Errorf("Expected %s for %s, got %s", tt.expected, tt.hex, result.String())
+		}
+	}
+
+	for _, hex := range []string{"", "0x", "0xzz", "latest"} {
+		if _, err := hexToInt(hex); err == nil {
+			t.Errorf("Expected an error for %q", hex)
+		}
+	}
+}
+
+func TestHexToBlockNumber_OutOfRange(t *testing.T) {
+	if _, err := hexToBlockNumber("0x10000000000000000"); err == nil {
+		t.Error("Expected an error for a block number exceeding int64")
+	}
+	if number, err := hexToBlockNumber("0x10d4f"); err != nil || number != 68943 {
+		t.Errorf("Expected 68943, got %d: %v", number, err)
+	}
+}
+
+func TestHTTPSJSONRPCPlugin_BackoffConfig(t *testing.T) {
+	plugin := NewHTTPSJSONRPCPlugin()
+	err := plugin.Initialize(map[string]interface{}{
+		"url":               "https://node.example",
+		"baseDelay":         50 * time.Millisecond,
+		"maxDelay":          time.Second,
+		"backoffMultiplier": 3.0,
+		"enableJitter":      false,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer plugin.Close()
+
+	expected := []time.Duration{50 * time.Millisecond, 150 * time.Millisecond, 450 * time.Millisecond, time.Second}
+	for attempt, want := range expected {
+		if got := plugin.backoff.ComputeBackoff(attempt); got != want {
+			t.Errorf("Expected backoff %v for attempt %d, got %v", want, attempt, got)
+		}
+	}
+
+	// Unset options keep the defaults
+	plugin = NewHTTPSJSONRPCPlugin()
+	if err := plugin.Initialize(map[string]interface{}{"url": "https://node.example"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer plugin.Close()
+	if plugin.backoff.BaseDelay != DefaultRetryConfig.BaseDelay || plugin.backoff.MaxDelay != DefaultRetryConfig.MaxDelay ||
+		plugin.backoff.BackoffMultiplier != DefaultRetryConfig.BackoffMultiplier || plugin.backoff.EnableJitter != DefaultRetryConfig.EnableJitter {
+		t.Errorf("Expected the default backoff, got %+v", plugin.backoff)
+	}
+
+	if err := NewHTTPSJSONRPCPlugin().Initialize(map[string]interface{}{"url": "https://node.example", "baseDelay": -time.Second}); err == nil {
+		t.Error("Expected an error for a negative base delay")
+	}
+}
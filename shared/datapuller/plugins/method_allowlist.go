@@ -0,0 +1,80 @@
+package datapuller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMethodNotAllowed 调用了白名单之外的 JSONRPC 方法
+var ErrMethodNotAllowed = errors.New("JSONRPC method not allowed")
+
+// DefaultAllowedMethods 未配置 allowedMethods 时允许的方法，只包含读取链上数据的 eth 方法，
+// 不允许发送交易和 debug_*、admin_* 等方法
+var DefaultAllowedMethods = []string{
+	"eth_blockNumber",
+	"eth_chainId",
+	"eth_getBlockByNumber",
+	"eth_getBlockByHash",
+	"eth_getTransactionByHash",
+	"eth_getTransactionReceipt",
+	"eth_getLogs",
+	"eth_getBalance",
+	"eth_getCode",
+	"eth_call",
+	"eth_subscribe",
+	"eth_unsubscribe",
+}
+
+// methodAllowList JSONRPC 方法白名单，以 * 结尾的条目按前缀匹配，例如 "eth_*"
+type methodAllowList struct {
+	methods  map[string]bool
+	prefixes []string
+}
+
+func newMethodAllowList(methods []string) *methodAllowList {
+	l := &methodAllowList{methods: make(map[string]bool)}
+	for _, method := range methods {
+		if prefix, ok := strings.CutSuffix(method, "*"); ok {
+			l.prefixes = append(l.prefixes, prefix)
+		} else {
+			l.methods[method] = true
+		}
+	}
+	return l
+}
+
+// check 方法不在白名单中时返回 ErrMethodNotAllowed
+func (l *methodAllowList) check(method string) error {
+	if l.methods[method] {
+		return nil
+	}
+	for _, prefix := range l.prefixes {
+		if strings.HasPrefix(method, prefix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrMethodNotAllowed, method)
+}
+
+// parseAllowedMethods 从插件配置的 allowedMethods 读取白名单，未配置时使用 DefaultAllowedMethods
+func parseAllowedMethods(config map[string]interface{}) (*methodAllowList, error) {
+	switch methods := config["allowedMethods"].(type) {
+	case nil:
+		return newMethodAllowList(DefaultAllowedMethods), nil
+	case []string:
+		return newMethodAllowList(methods), nil
+	case []interface{}:
+		names := make([]string, 0, len(methods))
+		for _, method := range methods {
+			name, ok := method.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid 'allowedMethods' entry: %v", method)
+			}
+			names = append(names, name)
+		}
+		return newMethodAllowList(names), nil
+	default:
+		return nil, fmt.Errorf("invalid 'allowedMethods' configuration: %v", methods)
+	}
+}
@@ -0,0 +1,58 @@
+package datapuller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRetryAfter 限制 Retry-After 要求的等待时间，避免异常的响应头长时间阻塞拉取
+const maxRetryAfter = 5 * time.Minute
+
+// rateLimitErrorCodes 表示请求被限流的 JSONRPC 错误码：-32005 为常见节点服务商的
+// "limit exceeded"，部分服务商直接在错误中返回 429
+var rateLimitErrorCodes = map[int]bool{
+	-32005: true,
+	429:    true,
+}
+
+// ThrottleRecorder 记录被服务商限流的请求及其要求等待的时间，
+// datapuller.MetricsCollector 实现了该接口
+type ThrottleRecorder interface {
+	RecordThrottled(pluginName string, wait time.Duration)
+}
+
+// isRateLimitError 判断 JSONRPC 错误是否表示请求被限流
+func isRateLimitError(err *JSONRPCError) bool {
+	return err != nil && rateLimitErrorCodes[err.Code]
+}
+
+// ParseRetryAfter 解析 Retry-After 响应头，支持秒数和 HTTP 日期两种格式，返回距 now
+// 需要等待的时间，不超过 maxRetryAfter；缺少或无法解析时返回 false
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = date.Sub(now)
+	} else {
+		return 0, false
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return delay, true
+}
@@ -0,0 +1,87 @@
+package datapuller
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryConfig 重试配置
+type RetryConfig struct {
+	MaxRetries        int            // 最大重试次数
+	BaseDelay         time.Duration  // 基础延迟时间
+	MaxDelay          time.Duration  // 最大延迟时间
+	BackoffMultiplier float64        // 退避乘数
+	EnableJitter      bool           // 是否启用完全抖动
+	random            func() float64 // 抖动的随机数来源，返回 [0, 1)，为空时使用 math/rand
+}
+
+// 默认重试配置
+var DefaultRetryConfig = &RetryConfig{
+	MaxRetries:        3,
+	BaseDelay:         time.Second,
+	MaxDelay:          30 * time.Second,
+	BackoffMultiplier: 2.0,
+	EnableJitter:      true,
+}
+
+// ComputeBackoff 计算第 attempt 次失败（从 0 开始）后的重试等待时间：BaseDelay 按
+// BackoffMultiplier 指数增长，不超过 MaxDelay。启用抖动时使用完全抖动策略，在
+// [0, 退避时间) 内均匀取值，避免大量客户端在同一时刻重试
+func (c *RetryConfig) ComputeBackoff(attempt int) time.Duration {
+	if c.BaseDelay <= 0 {
+		return 0
+	}
+
+	multiplier := c.BackoffMultiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(c.BaseDelay) * math.Pow(multiplier, float64(attempt))
+
+	// 限制最大延迟时间，未设置时也不能超出 time.Duration 的范围
+	if c.MaxDelay > 0 && delay > float64(c.MaxDelay) {
+		delay = float64(c.MaxDelay)
+	}
+	if delay > math.MaxInt64 {
+		delay = math.MaxInt64
+	}
+
+	// 完全抖动
+	if c.EnableJitter {
+		random := c.random
+		if random == nil {
+			random = rand.Float64
+		}
+		delay *= random()
+	}
+
+	return time.Duration(delay)
+}
+
+// parseRetryConfig 解析插件配置中的退避参数，未配置的项使用 DefaultRetryConfig
+func parseRetryConfig(config map[string]interface{}) (*RetryConfig, error) {
+	retry := *DefaultRetryConfig
+
+	if baseDelay, ok := config["baseDelay"].(time.Duration); ok {
+		retry.BaseDelay = baseDelay
+	}
+
+	if maxDelay, ok := config["maxDelay"].(time.Duration); ok {
+		retry.MaxDelay = maxDelay
+	}
+
+	if multiplier, ok := config["backoffMultiplier"].(float64); ok {
+		retry.BackoffMultiplier = multiplier
+	}
+
+	if enableJitter, ok := config["enableJitter"].(bool); ok {
+		retry.EnableJitter = enableJitter
+	}
+
+	if retry.BaseDelay < 0 || retry.MaxDelay < 0 {
+		return nil, fmt.Errorf("invalid backoff delays: base %v, max %v", retry.BaseDelay, retry.MaxDelay)
+	}
+	return &retry, nil
+}
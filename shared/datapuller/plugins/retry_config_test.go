@@ -0,0 +1,61 @@
+package datapuller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeBackoff_Exponential(t *testing.T) {
+	config := &RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, BackoffMultiplier: 2}
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for attempt, want := range expected {
+		if got := config.ComputeBackoff(attempt); got != want {
+			t.Errorf("Expected backoff %v for attempt %d, got %v", want, attempt, got)
+		}
+	}
+
+	// Far past the cap the delay neither overflows nor exceeds it
+	if got := config.ComputeBackoff(1000); got != time.Second {
+		t.Errorf("Expected the capped backoff %v, got %v", time.Second, got)
+	}
+
+	// A multiplier below 1 never shrinks the delay
+	constant := &RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, BackoffMultiplier: 0}
+	if got := constant.ComputeBackoff(3); got != 100*time.Millisecond {
+		t.Errorf("Expected the base delay, got %v", got)
+	}
+}
+
+func TestComputeBackoff_FullJitter(t *testing.T) {
+	randoms := []float64{0, 0.5, 0.999}
+	next := 0
+	config := &RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, BackoffMultiplier: 2, EnableJitter: true}
+	config.random = func() float64 {
+		r := randoms[next%len(randoms)]
+		next++
+		return r
+	}
+
+	// The jittered delay scales the exponential one by the random value
+	if got := config.ComputeBackoff(2); got != 0 {
+		t.Errorf("Expected no delay for random 0, got %v", got)
+	}
+	if got := config.ComputeBackoff(2); got != 200*time.Millisecond {
+		t.Errorf("Expected half of 400ms for random 0.5, got %v", got)
+	}
+	if got := config.ComputeBackoff(10); got >= time.Second || got < 990*time.Millisecond {
+		t.Errorf("Expected just under the 1s cap, got %v", got)
+	}
+
+	// With the default random source every delay stays within [0, backoff)
+	config.random = nil
+	for attempt := 0; attempt < 8; attempt++ {
+		ceiling := (&RetryConfig{BaseDelay: config.BaseDelay, MaxDelay: config.MaxDelay, BackoffMultiplier: 2}).ComputeBackoff(attempt)
+		for i := 0; i < 100; i++ {
+			if got := config.ComputeBackoff(attempt); got < 0 || got >= ceiling {
+				t.Fatalf("Expected a delay in [0, %v) for attempt %d, got %v", ceiling, attempt, got)
+			}
+		}
+	}
+}
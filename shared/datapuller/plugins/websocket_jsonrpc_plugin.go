@@ -2,13 +2,13 @@ package datapuller
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
 	"sync"
 	"time"
 
+	"chainpulse/shared/json"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -16,13 +16,15 @@ import (
 type WebSocketJSONRPCPlugin struct {
 	name          string
 	url           string
-	apiKey        string
+	auth          Auth
 	headers       map[string]string
 	conn          *websocket.Conn
 	subscriptions map[string]chan interface{}
 	mu            sync.RWMutex
 	ctx           context.Context
 	cancel        context.CancelFunc
+	allowed       *methodAllowList
+	compression   WSCompressionConfig
 }
 
 // NewWebSocketJSONRPCPlugin 创建 WebSocket JSONRPC 插件
@@ -31,6 +33,7 @@ func NewWebSocketJSONRPCPlugin() *WebSocketJSONRPCPlugin {
 		name:          "websocket-jsonrpc",
 		headers:       make(map[string]string),
 		subscriptions: make(map[string]chan interface{}),
+		allowed:       newMethodAllowList(DefaultAllowedMethods),
 	}
 }
 
@@ -53,14 +56,37 @@ func (p *WebSocketJSONRPCPlugin) Initialize(config map[string]interface{}) error
 		return fmt.Errorf("missing required 'url' configuration")
 	}
 
-	if apiKey, ok := config["apiKey"].(string); ok {
-		p.apiKey = apiKey
+	// 认证方式，未配置 auth 时 apiKey 作为 Bearer 令牌发送
+	auth, err := parseAuth(config)
+	if err != nil {
+		return err
 	}
+	p.auth = auth
 
 	if headers, ok := config["headers"].(map[string]string); ok {
 		p.headers = headers
 	}
 
+	// permessage-deflate 压缩，大的日志通知压缩后传输，小于阈值的请求不压缩
+	if compression, ok := config["compression"].(bool); ok {
+		p.compression.Enabled = compression
+	}
+
+	if threshold, ok := config["compressionThreshold"].(int); ok {
+		p.compression.Threshold = threshold
+	}
+
+	if level, ok := config["compressionLevel"].(int); ok {
+		p.compression.Level = level
+	}
+
+	// 方法白名单，未配置时只允许读取链上数据的 eth 方法
+	allowed, err := parseAllowedMethods(config)
+	if err != nil {
+		return err
+	}
+	p.allowed = allowed
+
 	// 创建上下文
 	p.ctx, p.cancel = context.WithCancel(context.Background())
 
@@ -78,23 +104,22 @@ func (p *WebSocketJSONRPCPlugin) Initialize(config map[string]interface{}) error
 // connect 连接到 WebSocket 服务器
 func (p *WebSocketJSONRPCPlugin) connect() error {
 	// 创建 WebSocket 连接
-	dialer := websocket.DefaultDialer
-	dialer.HandshakeTimeout = 10 * time.Second
+	dialer := p.compression.Dialer(10 * time.Second)
 
-	// 创建 HTTP 请求头
-	header := http.Header{}
-	if p.apiKey != "" {
-		header.Set("Authorization", "Bearer "+p.apiKey)
-	}
-
-	for key, value := range p.headers {
-		header.Set(key, value)
+	// 创建 HTTP 请求头，query 认证写入 URL
+	target, header, err := p.auth.DialTarget(p.url, p.headers)
+	if err != nil {
+		return err
 	}
 
-	conn, _, err := dialer.Dial(p.url, header)
+	conn, _, err := dialer.Dial(target, header)
 	if err != nil {
 		return fmt.Errorf("failed to dial WebSocket: %v", err)
 	}
+	if err := p.compression.Configure(conn); err != nil {
+		conn.Close()
+		return fmt.Errorf("invalid compression level: %v", err)
+	}
 
 	p.conn = conn
 	return nil
@@ -182,6 +207,11 @@ func (p *WebSocketJSONRPCPlugin) unsubscribe(subscriptionID string) {
 
 // sendJSONRPC 发送 JSONRPC 请求
 func (p *WebSocketJSONRPCPlugin) sendJSONRPC(method string, params []interface{}) error {
+	// 在发送前拒绝白名单之外的方法
+	if err := p.allowed.check(method); err != nil {
+		return err
+	}
+
 	request := JSONRPCRequest{
 		JSONRPC: "2.0",
 		Method:  method,
@@ -194,7 +224,7 @@ func (p *WebSocketJSONRPCPlugin) sendJSONRPC(method string, params []interface{}
 		return fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	return p.conn.WriteMessage(websocket.TextMessage, requestBytes)
+	return p.compression.writeMessage(p.conn, websocket.TextMessage, requestBytes)
 }
 
 // PullRealTime 拉取实时数据
@@ -266,7 +296,10 @@ func (p *WebSocketJSONRPCPlugin) PullBatch(ctx context.Context, start, end time.
 		return nil, fmt.Errorf("invalid block number format")
 	}
 
-	currentBlock := hexToInt(currentBlockHex)
+	currentBlock, err := hexToBlockNumber(currentBlockHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse current block number: %v", err)
+	}
 
 	// 获取最近的区块数据
 	for i := 0; i < 10; i++ { // 获取最近10个区块
@@ -290,6 +323,11 @@ func (p *WebSocketJSONRPCPlugin) PullBatch(ctx context.Context, start, end time.
 
 // callJSONRPCSync 同步调用 JSONRPC (用于批量操作)
 func (p *WebSocketJSONRPCPlugin) callJSONRPCSync(method string, params []interface{}) (interface{}, error) {
+	// 在发送前拒绝白名单之外的方法
+	if err := p.allowed.check(method); err != nil {
+		return nil, err
+	}
+
 	request := JSONRPCRequest{
 		JSONRPC: "2.0",
 		Method:  method,
@@ -302,7 +340,7 @@ func (p *WebSocketJSONRPCPlugin) callJSONRPCSync(method string, params []interfa
 		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	if err := p.conn.WriteMessage(websocket.TextMessage, requestBytes); err != nil {
+	if err := p.compression.writeMessage(p.conn, websocket.TextMessage, requestBytes); err != nil {
 		return nil, fmt.Errorf("failed to send request: %v", err)
 	}
 
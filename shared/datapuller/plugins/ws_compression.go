@@ -0,0 +1,52 @@
+package datapuller
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultCompressionThreshold 默认压缩阈值。更小的消息压缩后几乎不变小，却要付出 CPU 和 flate 缓冲区的开销，因此不压缩发送
+const DefaultCompressionThreshold = 1024
+
+// WSCompressionConfig WebSocket permessage-deflate 压缩配置，零值不启用压缩。
+// 压缩需要服务端同样支持，协商失败时连接照常建立，消息不压缩
+type WSCompressionConfig struct {
+	Enabled   bool
+	Threshold int // 压缩发送的最小消息字节数，0 使用 DefaultCompressionThreshold
+	Level     int // flate 压缩级别（1-9），0 使用默认级别
+}
+
+// threshold 返回压缩发送的最小消息字节数
+func (c WSCompressionConfig) threshold() int {
+	if c.Threshold <= 0 {
+		return DefaultCompressionThreshold
+	}
+	return c.Threshold
+}
+
+// Dialer 返回按配置协商压缩的拨号器，handshakeTimeout 为 0 时使用默认握手超时。
+// 返回的是副本，不修改共享的 websocket.DefaultDialer
+func (c WSCompressionConfig) Dialer(handshakeTimeout time.Duration) *websocket.Dialer {
+	dialer := *websocket.DefaultDialer
+	if handshakeTimeout > 0 {
+		dialer.HandshakeTimeout = handshakeTimeout
+	}
+	dialer.EnableCompression = c.Enabled
+	return &dialer
+}
+
+// Configure 设置新连接的压缩级别
+func (c WSCompressionConfig) Configure(conn *websocket.Conn) error {
+	if !c.Enabled || c.Level == 0 {
+		return nil
+	}
+	return conn.SetCompressionLevel(c.Level)
+}
+
+// writeMessage 写入一条消息，小于阈值的消息不压缩。
+// 未协商压缩的连接上 EnableWriteCompression 不起作用
+func (c WSCompressionConfig) writeMessage(conn *websocket.Conn, messageType int, data []byte) error {
+	conn.EnableWriteCompression(c.Enabled && len(data) >= c.threshold())
+	return conn.WriteMessage(messageType, data)
+}
@@ -0,0 +1,155 @@
+package datapuller
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// countingConn counts the bytes read from the connection
+type countingConn struct {
+	net.Conn
+	read *int64
+}
+
+func (c countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddInt64(c.read, int64(n))
+	return n, err
+}
+
+type countingListener struct {
+	net.Listener
+	read *int64
+}
+
+func (l countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return countingConn{Conn: conn, read: l.read}, nil
+}
+
+// newEchoServer starts a WebSocket server that supports compression and echoes every message,
+// counting the bytes it reads off the wire
+func newEchoServer(t *testing.T) (*httptest.Server, *int64) {
+	var read int64
+	upgrader := websocket.Upgrader{EnableCompression: true}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}))
+	server.Listener = countingListener{Listener: server.Listener, read: &read}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &read
+}
+
+// echo sends data over conn and checks that it comes back unchanged
+func echo(t *testing.T, compression WSCompressionConfig, conn *websocket.Conn, data []byte) {
+	if err := compression.writeMessage(conn, websocket.TextMessage, data); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+	_, reply, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	if string(reply) != string(data) {
+		t.Errorf("Expected the %d byte message to round-trip, got %d bytes", len(data), len(reply))
+	}
+}
+
+func TestWSCompression_NegotiatesAndRoundTrips(t *testing.T) {
+	large := []byte(strings.Repeat(`{"jsonrpc":"2.0","method":"eth_subscription","params":{}}`, 1000))
+	tiny := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`)
+
+	for _, enabled := range []bool{true, false} {
+		server, read := newEchoServer(t)
+		compression := WSCompressionConfig{Enabled: enabled, Level: 6}
+
+		conn, resp, err := compression.Dialer(0).Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("Failed to dial: %v", err)
+		}
+		if err := compression.Configure(conn); err != nil {
+			t.Fatalf("Failed to configure compression: %v", err)
+		}
+
+		negotiated := strings.Contains(resp.Header.Get("Sec-Websocket-Extensions"), "permessage-deflate")
+		if negotiated != enabled {
+			t.Errorf("Expected permessage-deflate negotiated to be %v, got %v", enabled, negotiated)
+		}
+
+		before := atomic.LoadInt64(read)
+		echo(t, compression, conn, large)
+		sent := atomic.LoadInt64(read) - before
+		if enabled && sent >= int64(len(large))/10 {
+			t.Errorf("Expected the %d byte message to be sent compressed, got %d bytes on the wire", len(large), sent)
+		}
+		if !enabled && sent < int64(len(large)) {
+			t.Errorf("Expected the %d byte message to be sent uncompressed, got %d bytes on the wire", len(large), sent)
+		}
+
+		// Messages below the threshold are sent as is
+		before = atomic.LoadInt64(read)
+		echo(t, compression, conn, tiny)
+		if sent := atomic.LoadInt64(read) - before; sent < int64(len(tiny)) {
+			t.Errorf("Expected the %d byte message to be sent uncompressed, got %d bytes on the wire", len(tiny), sent)
+		}
+
+		conn.Close()
+	}
+}
+
+func TestWebSocketJSONRPCPlugin_NegotiatesConfiguredCompression(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		offered := make(chan bool, 1)
+		upgrader := websocket.Upgrader{EnableCompression: true}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			offered <- strings.Contains(r.Header.Get("Sec-Websocket-Extensions"), "permessage-deflate")
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}))
+
+		plugin := NewWebSocketJSONRPCPlugin()
+		err := plugin.Initialize(map[string]interface{}{
+			"url":         "ws" + strings.TrimPrefix(server.URL, "http"),
+			"compression": enabled,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got := <-offered; got != enabled {
+			t.Errorf("Expected permessage-deflate offered to be %v, got %v", enabled, got)
+		}
+
+		plugin.Close()
+		server.Close()
+	}
+}
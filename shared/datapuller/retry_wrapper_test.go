@@ -0,0 +1,34 @@
+package datapuller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryWrapper_UsesBackoff(t *testing.T) {
+	var delays []time.Duration
+	last := time.Now()
+	attempts := 0
+	wrapper := NewRetryWrapper(nil, &RetryConfig{MaxRetries: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond, BackoffMultiplier: 2})
+
+	err := wrapper.executeWithRetry(context.Background(), func() error {
+		now := time.Now()
+		if attempts > 0 {
+			delays = append(delays, now.Sub(last))
+		}
+		last = now
+		attempts++
+		return errors.New("unavailable")
+	})
+	if err == nil || attempts != 4 {
+		t.Fatalf("Expected 4 failed attempts, got %d and %v", attempts, err)
+	}
+
+	for i, min := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 20 * time.Millisecond} {
+		if delays[i] < min {
+			t.Errorf("Expected retry %d after at least %v, got %v", i+1, min, delays[i])
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package datapuller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/mq"
+	"chainpulse/shared/types"
+)
+
+// Sink 拉取到的事件的输出目标
+type Sink interface {
+	// Write 写入一个事件
+	Write(ctx context.Context, event *types.IndexedEvent) error
+}
+
+// toIndexedEvent 将拉取到的数据转换为IndexedEvent，支持IndexedEvent和外部API的map格式
+func toIndexedEvent(data interface{}) (*types.IndexedEvent, error) {
+	switch v := data.(type) {
+	case *types.IndexedEvent:
+		return v, nil
+	case map[string]interface{}:
+		event, err := convertToIndexedEvent(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert external data: %v", err)
+		}
+		return event, nil
+	default:
+		return nil, fmt.Errorf("unsupported data format: %T", data)
+	}
+}
+
+// SinkHandler 返回将拉取到的数据转换为事件并写入sink的处理函数
+func SinkHandler(ctx context.Context, sink Sink) func(interface{}) error {
+	return func(data interface{}) error {
+		event, err := toIndexedEvent(data)
+		if err != nil {
+			return err
+		}
+		return sink.Write(ctx, event)
+	}
+}
+
+// PullRealTimeEventsToSink 实时拉取新事件并写入sink。无法转换的数据记录错误后跳过，
+// 以免中断实时拉取；写入sink失败时返回错误
+func (bdp *BlockchainDataPuller) PullRealTimeEventsToSink(ctx context.Context, sink Sink) error {
+	return bdp.PullRealTimeEvents(ctx, func(data interface{}) error {
+		event, err := toIndexedEvent(data)
+		if err != nil {
+			fmt.Printf("Skipping external event data: %v\n", err)
+			return nil
+		}
+		return sink.Write(ctx, event)
+	})
+}
+
+// PullHistoricalToSink 按页流式拉取历史数据并写入sink，返回成功写入的事件数。
+// 单条数据转换或写入失败时记录错误并继续处理其他数据
+func (bdp *BlockchainDataPuller) PullHistoricalToSink(ctx context.Context, start, end time.Time, filters map[string]interface{}, sink Sink) (int, error) {
+	handler := SinkHandler(ctx, sink)
+	written := 0
+	_, err := bdp.PullHistoricalStream(ctx, start, end, filters, func(data interface{}) error {
+		if err := handler(data); err != nil {
+			fmt.Printf("Failed to write historical data to sink: %v\n", err)
+			return nil
+		}
+		written++
+		return nil
+	})
+	if err != nil {
+		return written, fmt.Errorf("failed to pull historical data: %v", err)
+	}
+
+	return written, nil
+}
+
+// FileSink 以JSON Lines格式将事件追加写入文件
+type FileSink struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// NewFileSink 创建文件输出，文件不存在时自动创建
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sink file: %v", err)
+	}
+
+	return &FileSink{
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+// Write 将事件写为一行JSON
+func (s *FileSink) Write(ctx context.Context, event *types.IndexedEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.encoder.Encode(event); err != nil {
+		return fmt.Errorf("failed to write event to file: %v", err)
+	}
+	return nil
+}
+
+// Close 关闭文件
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// MQSink 将事件发布到消息队列（如Kafka）的主题
+type MQSink struct {
+	queue mq.MessageQueue
+	topic string
+}
+
+// NewMQSink 创建消息队列输出
+func NewMQSink(queue mq.MessageQueue, topic string) *MQSink {
+	return &MQSink{queue: queue, topic: topic}
+}
+
+// Write 将事件发布到主题
+func (s *MQSink) Write(ctx context.Context, event *types.IndexedEvent) error {
+	if err := s.queue.PublishContext(ctx, s.topic, event); err != nil {
+		return fmt.Errorf("failed to publish event to %s: %v", s.topic, err)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package datapuller
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/types"
+)
+
+// recordingSink records the events written to it
+type recordingSink struct {
+	mu     sync.Mutex
+	events []*types.IndexedEvent
+	fail   bool
+}
+
+func (s *recordingSink) Write(ctx context.Context, event *types.IndexedEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("sink unavailable")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *recordingSink) received() []*types.IndexedEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*types.IndexedEvent(nil), s.events...)
+}
+
+// eventPlugin serves fixed external event data for real-time and historical pulls
+type eventPlugin struct {
+	*fakePlugin
+	data []interface{}
+}
+
+func (p *eventPlugin) PullRealTime(ctx context.Context, handler func(interface{}) error) error {
+	for _, data := range p.data {
+		if err := handler(data); err != nil {
+			return err
+		}
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (p *eventPlugin) PullHistorical(ctx context.Context, start, end time.Time, filters map[string]interface{}) ([]interface{}, error) {
+	return p.data, nil
+}
+
+var externalEventData = []interface{}{
+	map[string]interface{}{
+		"blockNumber":     "0x64",
+		"transactionHash": "0xabc",
+		"eventName":       "Transfer",
+		"address":         "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D",
+		"tokenId":         "7",
+	},
+	map[string]interface{}{"transactionHash": "0xmissing-block"},
+	"unsupported",
+	map[string]interface{}{
+		"block_number": float64(101),
+		"txHash":       "0xdef",
+		"event":        "Transfer",
+		"contract":     "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48",
+		"value":        "1000",
+	},
+}
+
+// newEventPuller returns a puller whose plugins serve externalEventData
+func newEventPuller(t *testing.T, name string) *BlockchainDataPuller {
+	originalFactories := pluginFactories
+	t.Cleanup(func() { pluginFactories = originalFactories })
+
+	pluginFactories = map[string]func() Plugin{
+		"websocket-jsonrpc": func() Plugin {
+			return &eventPlugin{fakePlugin: newFakePlugin(name + "-ws"), data: externalEventData}
+		},
+		"https-jsonrpc": func() Plugin {
+			return &eventPlugin{fakePlugin: newFakePlugin(name + "-https"), data: externalEventData}
+		},
+	}
+
+	puller := NewBlockchainDataPuller()
+	err := puller.Initialize(map[string]map[string]interface{}{
+		"websocket-jsonrpc": {},
+		"https-jsonrpc":     {},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error initializing puller, got %v", err)
+	}
+	t.Cleanup(func() {
+		GlobalRegistry.Unregister(name + "-ws")
+		GlobalRegistry.Unregister(name + "-https")
+	})
+	return puller
+}
+
+func assertExternalEvents(t *testing.T, events []*types.IndexedEvent) {
+	t.Helper()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0].TxHash != "0xabc" || events[0].BlockNumber.Int64() != 100 || events[0].TokenID != "7" {
+		t.Errorf("Unexpected first event %+v", events[0])
+	}
+	if events[1].TxHash != "0xdef" || events[1].BlockNumber.Int64() != 101 || events[1].Value != "1000" {
+		t.Errorf("Unexpected second event %+v", events[1])
+	}
+}
+
+func TestPullHistoricalToSink(t *testing.T) {
+	puller := newEventPuller(t, "fake-sink-historical")
+	sink := &recordingSink{}
+
+	written, err := puller.PullHistoricalToSink(context.Background(), time.Now().Add(-time.Hour), time.Now(), nil, sink)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Data that cannot be converted is skipped
+	if written != 2 {
+		t.Errorf("Expected 2 events written, got %d", written)
+	}
+	assertExternalEvents(t, sink.received())
+}
+
+func TestPullRealTimeEventsToSink(t *testing.T) {
+	puller := newEventPuller(t, "fake-sink-realtime")
+	sink := &recordingSink{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- puller.PullRealTimeEventsToSink(ctx, sink)
+	}()
+
+	deadline := time.After(2 * time.Second)
+	for len(sink.received()) < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("Expected 2 events in the sink, got %d", len(sink.received()))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected real-time pull to return after cancellation")
+	}
+	assertExternalEvents(t, sink.received())
+}
+
+func TestSinkHandler(t *testing.T) {
+	sink := &recordingSink{}
+	handler := SinkHandler(context.Background(), sink)
+
+	event := &types.IndexedEvent{TxHash: "0x1"}
+	if err := handler(event); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if events := sink.received(); len(events) != 1 || events[0] != event {
+		t.Errorf("Expected the event to be written as is, got %v", events)
+	}
+
+	if err := handler(42); err == nil {
+		t.Error("Expected error for unsupported data")
+	}
+
+	sink.fail = true
+	if err := handler(event); err == nil {
+		t.Error("Expected sink error to be returned")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, txHash := range []string{"0x1", "0x2"} {
+		if err := sink.Write(context.Background(), &types.IndexedEvent{TxHash: txHash, EventName: "Transfer"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Expected no error closing sink, got %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var txHashes []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event types.IndexedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Expected a JSON event per line, got %q: %v", scanner.Text(), err)
+		}
+		txHashes = append(txHashes, event.TxHash)
+	}
+	if len(txHashes) != 2 || txHashes[0] != "0x1" || txHashes[1] != "0x2" {
+		t.Errorf("Expected events 0x1 and 0x2, got %v", txHashes)
+	}
+}
@@ -2,23 +2,26 @@ package datapuller
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"sync"
 	"time"
 
+	plugins "chainpulse/shared/datapuller/plugins"
+	"chainpulse/shared/json"
+
 	"github.com/gorilla/websocket"
 )
 
 // WebSocketPuller WebSocket实时数据拉取器
 type WebSocketPuller struct {
-	config     *DataSourceConfig
-	conn       *websocket.Conn
-	handler    func(interface{}) error
-	mu         sync.Mutex
-	ctx        context.Context
-	cancelFunc context.CancelFunc
+	config      *DataSourceConfig
+	conn        *websocket.Conn
+	handler     func(interface{}) error
+	mu          sync.Mutex
+	ctx         context.Context
+	cancelFunc  context.CancelFunc
+	compression plugins.WSCompressionConfig // 拨号时协商的压缩配置，取自 config.WSCompression
 }
 
 // NewWebSocketPuller 创建WebSocket数据拉取器
@@ -26,19 +29,28 @@ func NewWebSocketPuller(config *DataSourceConfig) *WebSocketPuller {
 	ctx, cancelFunc := context.WithCancel(context.Background())
 
 	return &WebSocketPuller{
-		config:     config,
-		ctx:        ctx,
-		cancelFunc: cancelFunc,
+		config:      config,
+		ctx:         ctx,
+		cancelFunc:  cancelFunc,
+		compression: config.WSCompression,
 	}
 }
 
 // PullRealTime 拉取实时数据
 func (wsp *WebSocketPuller) PullRealTime(ctx context.Context, handler func(interface{}) error) error {
 	// 连接到WebSocket服务器
-	conn, _, err := websocket.DefaultDialer.Dial(wsp.config.URL, nil)
+	target, header, err := wsp.config.Auth.DialTarget(wsp.config.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to WebSocket: %v", err)
+	}
+	conn, _, err := wsp.compression.Dialer(0).Dial(target, header)
 	if err != nil {
 		return fmt.Errorf("failed to connect to WebSocket: %v", err)
 	}
+	if err := wsp.compression.Configure(conn); err != nil {
+		conn.Close()
+		return fmt.Errorf("invalid compression level: %v", err)
+	}
 
 	wsp.conn = conn
 	wsp.handler = handler
@@ -0,0 +1,66 @@
+package datapuller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	plugins "chainpulse/shared/datapuller/plugins"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocketPuller_NegotiatesConfiguredCompression(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		offered := make(chan bool, 1)
+		upgrader := websocket.Upgrader{EnableCompression: true}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			offered <- strings.Contains(r.Header.Get("Sec-Websocket-Extensions"), "permessage-deflate")
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"block":1}`))
+			conn.ReadMessage()
+		}))
+
+		puller := NewWebSocketPuller(&DataSourceConfig{
+			URL:           "ws" + strings.TrimPrefix(server.URL, "http"),
+			Timeout:       5 * time.Second,
+			WSCompression: plugins.WSCompressionConfig{Enabled: enabled},
+		})
+
+		received := make(chan interface{}, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- puller.PullRealTime(ctx, func(data interface{}) error {
+				received <- data
+				cancel()
+				return nil
+			})
+		}()
+
+		select {
+		case data := <-received:
+			if data.(map[string]interface{})["block"] != float64(1) {
+				t.Errorf("Expected the message to be received, got %v", data)
+			}
+		case err := <-done:
+			t.Fatalf("Expected a message, got %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected a message before the timeout")
+		}
+		if got := <-offered; got != enabled {
+			t.Errorf("Expected permessage-deflate offered to be %v, got %v", enabled, got)
+		}
+
+		puller.Close()
+		<-done
+		server.Close()
+	}
+}
@@ -0,0 +1,148 @@
+package eventbus
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"chainpulse/shared/types"
+)
+
+// DefaultQueueSize is the number of events a subscriber may lag behind before further
+// events are dropped for it
+const DefaultQueueSize = 1024
+
+// Handler handles an event published on the bus
+type Handler func(event types.IndexedEvent)
+
+// SubscriberStats is a snapshot of the deliveries to one subscriber
+type SubscriberStats struct {
+	Name      string
+	Delivered uint64 // events handled
+	Dropped   uint64 // events dropped because the subscriber's queue was full
+	Queued    int    // events waiting to be handled
+}
+
+// EventBus fans out newly indexed events to in-process subscribers such as streaming
+// endpoints and notifiers. Each subscriber has a bounded queue drained by its own
+// goroutine, so a slow subscriber blocks neither the publisher nor the other
+// subscribers; events that do not fit its queue are dropped for it and counted.
+type EventBus struct {
+	mu          sync.RWMutex
+	queueSize   int
+	subscribers map[*subscriber]struct{}
+	closed      bool
+	wg          sync.WaitGroup
+}
+
+type subscriber struct {
+	name      string
+	queue     chan types.IndexedEvent
+	handler   Handler
+	delivered uint64
+	dropped   uint64
+}
+
+// NewEventBus creates an event bus whose subscribers queue up to queueSize events;
+// queueSize <= 0 uses DefaultQueueSize
+func NewEventBus(queueSize int) *EventBus {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	return &EventBus{
+		queueSize:   queueSize,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Subscribe calls handler with every event published from now on, one event at a time
+// and in publish order. name identifies the subscriber in Stats and logs. The returned
+// function unsubscribes; events already queued are still handled.
+func (b *EventBus) Subscribe(name string, handler Handler) (unsubscribe func()) {
+	sub := &subscriber{
+		name:    name,
+		queue:   make(chan types.IndexedEvent, b.queueSize),
+		handler: handler,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(sub.queue)
+		return func() {}
+	}
+	b.subscribers[sub] = struct{}{}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for event := range sub.queue {
+			sub.handler(event)
+			atomic.AddUint64(&sub.delivered, 1)
+		}
+	}()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[sub]; ok {
+			delete(b.subscribers, sub)
+			close(sub.queue)
+		}
+	}
+}
+
+// Publish queues event for every subscriber without blocking. A subscriber whose queue
+// is full misses the event.
+func (b *EventBus) Publish(event types.IndexedEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.queue <- event:
+		default:
+			if dropped := atomic.AddUint64(&sub.dropped, 1); dropped == 1 || dropped%1000 == 0 {
+				log.Printf("Event bus subscriber %s is falling behind, %d events dropped", sub.name, dropped)
+			}
+		}
+	}
+}
+
+// Stats returns the deliveries of the current subscribers, ordered by name
+func (b *EventBus) Stats() []SubscriberStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	stats := make([]SubscriberStats, 0, len(b.subscribers))
+	for sub := range b.subscribers {
+		stats = append(stats, SubscriberStats{
+			Name:      sub.name,
+			Delivered: atomic.LoadUint64(&sub.delivered),
+			Dropped:   atomic.LoadUint64(&sub.dropped),
+			Queued:    len(sub.queue),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// Close stops publishing, lets the subscribers handle their queued events and waits
+// for them
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for sub := range b.subscribers {
+			delete(b.subscribers, sub)
+			close(sub.queue)
+		}
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
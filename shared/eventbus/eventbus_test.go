@@ -0,0 +1,101 @@
+package eventbus
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"chainpulse/shared/types"
+)
+
+func TestEventBus_DeliversEventToEverySubscriber(t *testing.T) {
+	bus := NewEventBus(0)
+
+	var mu sync.Mutex
+	received := make(map[string][]string)
+	for _, name := range []string{"websocket", "webhook", "balance"} {
+		name := name
+		bus.Subscribe(name, func(event types.IndexedEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			received[name] = append(received[name], event.TxHash)
+		})
+	}
+
+	bus.Publish(types.IndexedEvent{TxHash: "0xabc"})
+	bus.Close()
+
+	for _, name := range []string{"websocket", "webhook", "balance"} {
+		if events := received[name]; len(events) != 1 || events[0] != "0xabc" {
+			t.Errorf("Expected %s to receive [0xabc], got %v", name, events)
+		}
+	}
+}
+
+func TestEventBus_DropsEventsForSlowSubscriber(t *testing.T) {
+	bus := NewEventBus(2)
+	defer bus.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	handling := make(chan struct{}, 10)
+	bus.Subscribe("slow", func(event types.IndexedEvent) {
+		handling <- struct{}{}
+		<-release
+	})
+	fast := make(chan string, 10)
+	bus.Subscribe("fast", func(event types.IndexedEvent) {
+		fast <- event.TxHash
+	})
+
+	// The slow subscriber holds the first event and queues the next two, while the fast
+	// one handles each event before the next is published
+	for i, txHash := range []string{"0x1", "0x2", "0x3", "0x4", "0x5"} {
+		bus.Publish(types.IndexedEvent{TxHash: txHash})
+		if i == 0 {
+			<-handling
+		}
+		select {
+		case <-fast:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the fast subscriber to receive %s", txHash)
+		}
+	}
+
+	stats := bus.Stats()
+	if len(stats) != 2 || stats[1].Name != "slow" {
+		t.Fatalf("Expected stats of 2 subscribers, got %+v", stats)
+	}
+	if stats[1].Dropped != 2 {
+		t.Errorf("Expected 2 events dropped for the slow subscriber, got %d", stats[1].Dropped)
+	}
+	if stats[0].Dropped != 0 {
+		t.Errorf("Expected no events dropped for the fast subscriber, got %d", stats[0].Dropped)
+	}
+}
+
+func TestEventBus_Unsubscribe(t *testing.T) {
+	bus := NewEventBus(0)
+	defer bus.Close()
+
+	received := make(chan string, 10)
+	unsubscribe := bus.Subscribe("websocket", func(event types.IndexedEvent) {
+		received <- event.TxHash
+	})
+
+	bus.Publish(types.IndexedEvent{TxHash: "0x1"})
+	unsubscribe()
+	bus.Publish(types.IndexedEvent{TxHash: "0x2"})
+
+	if txHash := <-received; txHash != "0x1" {
+		t.Errorf("Expected 0x1, got %s", txHash)
+	}
+	select {
+	case txHash := <-received:
+		t.Errorf("Expected no events after unsubscribing, got %s", txHash)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if stats := bus.Stats(); len(stats) != 0 {
+		t.Errorf("Expected no subscribers, got %+v", stats)
+	}
+}
@@ -0,0 +1,94 @@
+package explorer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"chainpulse/shared/json"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// ErrContractNotVerified is returned for contracts whose source code the explorer has not
+// verified, so it has no ABI for them
+var ErrContractNotVerified = errors.New("contract source code not verified")
+
+// Client fetches contract ABIs from an Etherscan-style block explorer API
+type Client struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewClient creates a client of the explorer API at baseURL, e.g.
+// https://api.etherscan.io/api, authenticating with apiKey
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// apiResponse is the envelope of every explorer API response. Status is "1" on success;
+// otherwise Result holds the error message.
+type apiResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Result  string `json:"result"`
+}
+
+// FetchABI returns the JSON ABI of the verified contract at address. It returns
+// ErrContractNotVerified if the explorer has no verified source for it.
+func (c *Client) FetchABI(ctx context.Context, address string) (string, error) {
+	query := url.Values{}
+	query.Set("module", "contract")
+	query.Set("action", "getabi")
+	query.Set("address", address)
+	if c.apiKey != "" {
+		query.Set("apikey", c.apiKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create explorer request: %v", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// The request URL carries the API key, so only the cause is reported
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return "", fmt.Errorf("failed to fetch ABI of %s: %v", address, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("explorer returned status %d for the ABI of %s", resp.StatusCode, address)
+	}
+
+	var response apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode explorer response: %v", err)
+	}
+	if response.Status != "1" {
+		// Rate limits and invalid keys are reported the same way, only the message differs
+		if strings.Contains(strings.ToLower(response.Result), "not verified") {
+			return "", ErrContractNotVerified
+		}
+		return "", fmt.Errorf("explorer failed to return the ABI of %s: %s", address, response.Result)
+	}
+
+	// Only store ABIs the decoder can use
+	if _, err := abi.JSON(strings.NewReader(response.Result)); err != nil {
+		return "", fmt.Errorf("explorer returned an invalid ABI for %s: %v", address, err)
+	}
+	return response.Result, nil
+}
@@ -0,0 +1,90 @@
+package explorer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chainpulse/shared/json"
+)
+
+const transferABI = `[{"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"value","type":"uint256"}],"name":"Transfer","type":"event"}]`
+
+// explorerServer answers getabi requests with result for every address
+func explorerServer(t *testing.T, status, result string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("module") != "contract" || query.Get("action") != "getabi" || query.Get("address") == "" {
+			t.Errorf("Expected a getabi request, got %s", r.URL.RawQuery)
+		}
+		if query.Get("apikey") != "test-key" {
+			t.Errorf("Expected the API key, got %q", query.Get("apikey"))
+		}
+		json.NewEncoder(w).Encode(apiResponse{Status: status, Message: "OK", Result: result})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_FetchABI(t *testing.T) {
+	server := explorerServer(t, "1", transferABI)
+
+	contractABI, err := NewClient(server.URL, "test-key").FetchABI(context.Background(), "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if contractABI != transferABI {
+		t.Errorf("Expected the explorer ABI, got %s", contractABI)
+	}
+}
+
+func TestClient_FetchABIUnverified(t *testing.T) {
+	server := explorerServer(t, "0", "Contract source code not verified")
+
+	_, err := NewClient(server.URL, "test-key").FetchABI(context.Background(), "0x0000000000000000000000000000000000000001")
+	if !errors.Is(err, ErrContractNotVerified) {
+		t.Errorf("Expected ErrContractNotVerified, got %v", err)
+	}
+}
+
+func TestClient_FetchABIErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		result string
+	}{
+		{"rate limited", "0", "Max rate limit reached"},
+		{"invalid ABI", "1", "not an ABI"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := explorerServer(t, tt.status, tt.result)
+
+			_, err := NewClient(server.URL, "test-key").FetchABI(context.Background(), "0x0000000000000000000000000000000000000001")
+			if err == nil || errors.Is(err, ErrContractNotVerified) {
+				t.Errorf("Expected a fetch error, got %v", err)
+			}
+			if err != nil && tt.status == "0" && !strings.Contains(err.Error(), tt.result) {
+				t.Errorf("Expected the explorer message in %v", err)
+			}
+		})
+	}
+}
+
+func TestClient_FetchABIHidesAPIKey(t *testing.T) {
+	server := explorerServer(t, "1", transferABI)
+	server.Close()
+
+	_, err := NewClient(server.URL, "secret-key").FetchABI(context.Background(), "0x0000000000000000000000000000000000000001")
+	if err == nil {
+		t.Fatal("Expected an error from the closed explorer")
+	}
+	if strings.Contains(err.Error(), "secret-key") {
+		t.Errorf("Expected the API key to be left out of the error, got %v", err)
+	}
+}
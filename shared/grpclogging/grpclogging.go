@@ -0,0 +1,120 @@
+package grpclogging
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"chainpulse/shared/requestid"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultRedactedKeys are the metadata keys whose values are never logged
+var DefaultRedactedKeys = []string{"authorization", "cookie", "x-api-key"}
+
+// redacted replaces the values of redacted metadata keys in log lines
+const redacted = "[REDACTED]"
+
+// Logger is the subset of the shared loggers the interceptor writes to
+type Logger interface {
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// Interceptor logs every gRPC call with its method, status code, duration, peer,
+// request ID and incoming metadata. Values of the redacted metadata keys, such as
+// auth tokens, are replaced before logging. Chain it after the request ID
+// interceptor and before the auth interceptor, so rejected calls are logged too.
+type Interceptor struct {
+	logger Logger
+	redact map[string]bool
+}
+
+// NewInterceptor creates an interceptor logging to logger, redacting the values of
+// the metadata keys in redactKeys (case-insensitive). Nil redactKeys uses
+// DefaultRedactedKeys.
+func NewInterceptor(logger Logger, redactKeys []string) *Interceptor {
+	if redactKeys == nil {
+		redactKeys = DefaultRedactedKeys
+	}
+	redact := make(map[string]bool, len(redactKeys))
+	for _, key := range redactKeys {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			redact[key] = true
+		}
+	}
+	return &Interceptor{logger: logger, redact: redact}
+}
+
+// Unary logs unary calls
+func (i *Interceptor) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	i.log(ctx, info.FullMethod, err, time.Since(start))
+	return resp, err
+}
+
+// Stream logs stream calls once they end
+func (i *Interceptor) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	i.log(ss.Context(), info.FullMethod, err, time.Since(start))
+	return err
+}
+
+// log writes the entry of one call, at error level for server faults and warning
+// level for other failures
+func (i *Interceptor) log(ctx context.Context, method string, err error, duration time.Duration) {
+	code := status.Code(err)
+
+	addr := "unknown"
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := requestid.FromContext(ctx)
+	if id == "" {
+		if values := md.Get(requestid.MetadataKey); len(values) > 0 {
+			id = values[0]
+		}
+	}
+
+	const format = "gRPC %s status=%s duration=%s peer=%s request_id=%s metadata=%s"
+	args := []interface{}{method, code.String(), duration, addr, id, i.formatMetadata(md)}
+	switch code {
+	case codes.OK:
+		i.logger.Info(format, args...)
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unimplemented:
+		i.logger.Error(format+" error=%v", append(args, err)...)
+	default:
+		i.logger.Warn(format+" error=%v", append(args, err)...)
+	}
+}
+
+// formatMetadata formats md as key=value pairs sorted by key, with the values of
+// redacted keys replaced
+func (i *Interceptor) formatMetadata(md metadata.MD) string {
+	keys := make([]string, 0, len(md))
+	for key := range md {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := strings.Join(md[key], ",")
+		if i.redact[strings.ToLower(key)] {
+			value = redacted
+		}
+		pairs = append(pairs, key+"="+value)
+	}
+	return "{" + strings.Join(pairs, " ") + "}"
+}
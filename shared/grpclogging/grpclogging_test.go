@@ -0,0 +1,176 @@
+package grpclogging
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"chainpulse/shared/requestid"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// captureLogger records formatted log lines by level
+type captureLogger struct {
+	lines []string
+}
+
+func (c *captureLogger) Info(msg string, args ...interface{}) {
+	c.lines = append(c.lines, "INFO "+fmt.Sprintf(msg, args...))
+}
+
+func (c *captureLogger) Warn(msg string, args ...interface{}) {
+	c.lines = append(c.lines, "WARN "+fmt.Sprintf(msg, args...))
+}
+
+func (c *captureLogger) Error(msg string, args ...interface{}) {
+	c.lines = append(c.lines, "ERROR "+fmt.Sprintf(msg, args...))
+}
+
+func incomingContext() context.Context {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"authorization", "Bearer secret-token",
+		"x-request-id", "req-123",
+		"user-agent", "grpc-go",
+	))
+	return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 7), Port: 5000}})
+}
+
+func TestInterceptor_Unary(t *testing.T) {
+	logger := &captureLogger{}
+	interceptor := NewInterceptor(logger, nil)
+	info := &grpc.UnaryServerInfo{FullMethod: "/event.EventService/GetEvent"}
+
+	resp, err := interceptor.Unary(incomingContext(), "request", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "response", nil
+	})
+	if err != nil || resp != "response" {
+		t.Fatalf("Expected the handler response, got %v: %v", resp, err)
+	}
+
+	if len(logger.lines) != 1 {
+		t.Fatalf("Expected 1 log line, got %d: %v", len(logger.lines), logger.lines)
+	}
+	line := logger.lines[0]
+	for _, expected := range []string{"INFO", "/event.EventService/GetEvent", "status=OK", "peer=10.0.0.7:5000", "request_id=req-123", "user-agent=grpc-go"} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("Expected %q in log line, got %s", expected, line)
+		}
+	}
+	if strings.Contains(line, "secret-token") || !strings.Contains(line, "authorization="+redacted) {
+		t.Errorf("Expected the auth token to be redacted, got %s", line)
+	}
+}
+
+func TestInterceptor_UnaryFailure(t *testing.T) {
+	logger := &captureLogger{}
+	interceptor := NewInterceptor(logger, []string{"Authorization", "User-Agent"})
+	info := &grpc.UnaryServerInfo{FullMethod: "/event.EventService/GetEvent"}
+
+	// The request ID assigned by the request ID interceptor takes precedence
+	ctx := requestid.NewContext(incomingContext(), "assigned-id")
+	_, err := interceptor.Unary(ctx, "request", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected the handler error, got %v", err)
+	}
+
+	line := logger.lines[0]
+	for _, expected := range []string{"WARN", "status=Unauthenticated", "request_id=assigned-id", "user-agent=" + redacted, "invalid token"} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("Expected %q in log line, got %s", expected, line)
+		}
+	}
+	if strings.Contains(line, "secret-token") {
+		t.Errorf("Expected the auth token to be redacted, got %s", line)
+	}
+}
+
+// contextStream is a server stream carrying a context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestInterceptor_Stream(t *testing.T) {
+	logger := &captureLogger{}
+	interceptor := NewInterceptor(logger, nil)
+	info := &grpc.StreamServerInfo{FullMethod: "/event.EventService/StreamEvents"}
+
+	err := interceptor.Stream(nil, &contextStream{ctx: incomingContext()}, info, func(srv interface{}, ss grpc.ServerStream) error {
+		return status.Error(codes.Internal, "store unavailable")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("Expected the handler error, got %v", err)
+	}
+
+	if len(logger.lines) != 1 || !strings.HasPrefix(logger.lines[0], "ERROR") || !strings.Contains(logger.lines[0], "status=Internal") {
+		t.Errorf("Expected an error log line with status Internal, got %v", logger.lines)
+	}
+}
+
+// tokenAuth rejects calls without the expected bearer token, like the auth interceptor
+func tokenAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) == 0 || values[0] != "Bearer valid-token" {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return handler(ctx, req)
+}
+
+func TestInterceptor_ChainedWithAuth(t *testing.T) {
+	logger := &captureLogger{}
+	interceptor := NewInterceptor(logger, nil)
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(requestid.UnaryServerInterceptor, interceptor.Unary, tokenAuth))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	for _, token := range []string{"Bearer valid-token", "Bearer stolen-token"} {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", token, "x-request-id", "req-456")
+		client.Check(ctx, &healthpb.HealthCheckRequest{})
+	}
+
+	// Calls rejected by auth are logged too, and no token reaches the log
+	if len(logger.lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %d: %v", len(logger.lines), logger.lines)
+	}
+	if !strings.Contains(logger.lines[0], "status=OK") || !strings.Contains(logger.lines[1], "status=Unauthenticated") {
+		t.Errorf("Expected an OK and an Unauthenticated call, got %v", logger.lines)
+	}
+	for _, line := range logger.lines {
+		if !strings.Contains(line, "/grpc.health.v1.Health/Check") || !strings.Contains(line, "request_id=req-456") {
+			t.Errorf("Expected the method and request ID in log line, got %s", line)
+		}
+		if strings.Contains(line, "valid-token") || strings.Contains(line, "stolen-token") {
+			t.Errorf("Expected the auth token to be redacted, got %s", line)
+		}
+	}
+}
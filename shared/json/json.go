@@ -0,0 +1,57 @@
+// Package json is the JSON library of chainpulse. It exposes the subset of the
+// encoding/json API the code base uses, backed by github.com/goccy/go-json, so every
+// package encodes and decodes JSON the same way and the implementation can be swapped
+// in one place.
+//
+// Prefer NewDecoder and NewEncoder over Unmarshal and Marshal for request and response
+// bodies and other payloads of unbounded size, so they are not held in memory whole.
+package json
+
+import (
+	"io"
+
+	gojson "github.com/goccy/go-json"
+)
+
+// Decoder reads and decodes JSON values from an input stream
+type Decoder = gojson.Decoder
+
+// Encoder writes JSON values to an output stream
+type Encoder = gojson.Encoder
+
+// RawMessage is a raw encoded JSON value, decoded later or encoded as is
+type RawMessage = gojson.RawMessage
+
+// Number is a JSON number literal, kept as its text
+type Number = gojson.Number
+
+// Marshal returns the JSON encoding of v
+func Marshal(v interface{}) ([]byte, error) {
+	return gojson.Marshal(v)
+}
+
+// MarshalIndent is like Marshal but indents the output
+func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	return gojson.MarshalIndent(v, prefix, indent)
+}
+
+// Unmarshal decodes the JSON-encoded data into v
+func Unmarshal(data []byte, v interface{}) error {
+	return gojson.Unmarshal(data, v)
+}
+
+// Valid reports whether data is a valid JSON encoding
+func Valid(data []byte) bool {
+	return gojson.Valid(data)
+}
+
+// NewDecoder returns a decoder reading from r. A stream of values is decoded one value
+// at a time, without reading the whole stream first.
+func NewDecoder(r io.Reader) *Decoder {
+	return gojson.NewDecoder(r)
+}
+
+// NewEncoder returns an encoder writing to w
+func NewEncoder(w io.Writer) *Encoder {
+	return gojson.NewEncoder(w)
+}
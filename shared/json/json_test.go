@@ -0,0 +1,54 @@
+package json
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type sample struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	data, err := Marshal(sample{Name: "transfer", Count: 3})
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	if string(data) != `{"name":"transfer","count":3}` {
+		t.Errorf("Expected the encoding/json output, got %s", data)
+	}
+
+	var decoded sample
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if decoded != (sample{Name: "transfer", Count: 3}) {
+		t.Errorf("Expected the original value, got %+v", decoded)
+	}
+}
+
+func TestDecoderStreams(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader(`{"name":"a","count":1} {"name":"b","count":2}`))
+
+	var names []string
+	for decoder.More() {
+		var s sample
+		if err := decoder.Decode(&s); err != nil {
+			t.Fatalf("Failed to decode: %v", err)
+		}
+		names = append(names, s.Name)
+	}
+	if strings.Join(names, ",") != "a,b" {
+		t.Errorf("Expected a,b, got %v", names)
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(sample{Name: "c"}); err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	if buf.String() != "{\"name\":\"c\",\"count\":0}\n" {
+		t.Errorf("Expected a newline-terminated value, got %q", buf.String())
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 
+	"chainpulse/shared/requestid"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -87,11 +89,16 @@ func (zl *ZapLogger) WithFields(fields map[string]interface{}) Logger {
 	}
 }
 
-// WithTrace adds trace context to the logger
+// WithTrace adds the request ID from the context to every log line
 func (zl *ZapLogger) WithTrace(ctx context.Context) Logger {
-	// In a real implementation, this would extract trace information from context
-	// For now, we'll just return the logger as is
-	return zl
+	id := requestid.FromContext(ctx)
+	if id == "" {
+		return zl
+	}
+
+	return &ZapLogger{
+		sugaredLogger: zl.sugaredLogger.With("request_id", id),
+	}
 }
 
 // Sync flushes any buffered log entries
@@ -103,6 +110,7 @@ func (zl *ZapLogger) Sync() error {
 type StdLogger struct {
 	logger  *log.Logger
 	debugMode bool
+	prefix    string
 }
 
 // NewStdLogger creates a new standard logger
@@ -115,23 +123,23 @@ func NewStdLogger(debugMode bool) Logger {
 
 // Info logs an info message
 func (sl *StdLogger) Info(msg string, args ...interface{}) {
-	sl.logger.Printf("[INFO] "+msg, args...)
+	sl.logger.Printf("[INFO] "+sl.prefix+msg, args...)
 }
 
 // Error logs an error message
 func (sl *StdLogger) Error(msg string, args ...interface{}) {
-	sl.logger.Printf("[ERROR] "+msg, args...)
+	sl.logger.Printf("[ERROR] "+sl.prefix+msg, args...)
 }
 
 // Warn logs a warning message
 func (sl *StdLogger) Warn(msg string, args ...interface{}) {
-	sl.logger.Printf("[WARN] "+msg, args...)
+	sl.logger.Printf("[WARN] "+sl.prefix+msg, args...)
 }
 
 // Debug logs a debug message
 func (sl *StdLogger) Debug(msg string, args ...interface{}) {
 	if sl.debugMode {
-		sl.logger.Printf("[DEBUG] "+msg, args...)
+		sl.logger.Printf("[DEBUG] "+sl.prefix+msg, args...)
 	}
 }
 
@@ -140,9 +148,18 @@ func (sl *StdLogger) WithFields(fields map[string]interface{}) Logger {
 	return sl
 }
 
-// WithTrace adds trace context to the logger (stub implementation)
+// WithTrace adds the request ID from the context to every log line
 func (sl *StdLogger) WithTrace(ctx context.Context) Logger {
-	return sl
+	id := requestid.FromContext(ctx)
+	if id == "" {
+		return sl
+	}
+
+	return &StdLogger{
+		logger:    sl.logger,
+		debugMode: sl.debugMode,
+		prefix:    sl.prefix + "[request_id=" + id + "] ",
+	}
 }
 
 // Sync is a no-op for the standard logger
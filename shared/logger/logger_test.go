@@ -1,7 +1,14 @@
 package logger
 
 import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"chainpulse/shared/requestid"
 )
 
 func TestNewLogger(t *testing.T) {
@@ -95,4 +102,46 @@ func TestLoggerSync(t *testing.T) {
 	if err != nil {
 		t.Errorf("Expected no error during sync, got %v", err)
 	}
-}
\ No newline at end of file
+}
+
+func TestLoggerWithTrace_RequestIDFromHeader(t *testing.T) {
+	var buf bytes.Buffer
+	appLogger := &StdLogger{logger: log.New(&buf, "", 0)}
+
+	handler := requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		appLogger.WithTrace(r.Context()).Info("handling %s", r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/events", nil)
+	req.Header.Set(requestid.Header, "client-req-42")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(requestid.Header); got != "client-req-42" {
+		t.Errorf("Expected response header %s to be client-req-42, got %q", requestid.Header, got)
+	}
+	if !strings.Contains(buf.String(), "request_id=client-req-42") {
+		t.Errorf("Expected log output to contain the request ID, got %q", buf.String())
+	}
+}
+
+func TestLoggerWithTrace_GeneratesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	appLogger := &StdLogger{logger: log.New(&buf, "", 0)}
+
+	handler := requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		appLogger.WithTrace(r.Context()).Info("handling request")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	id := rec.Header().Get(requestid.Header)
+	if id == "" {
+		t.Fatal("Expected a generated request ID in the response header")
+	}
+	if !strings.Contains(buf.String(), "request_id="+id) {
+		t.Errorf("Expected log output to contain generated request ID %s, got %q", id, buf.String())
+	}
+}
@@ -13,6 +13,8 @@ type Metrics struct {
 	EventsIndexedTotal      prometheus.Counter
 	EventsCacheHitsTotal    prometheus.Counter
 	EventsCacheMissesTotal  prometheus.Counter
+	RPCLimiterWait          prometheus.Histogram
+	SpamEventsDroppedTotal  *prometheus.CounterVec
 	
 	// API metrics
 	APIRequestsTotal        *prometheus.CounterVec
@@ -23,6 +25,16 @@ type Metrics struct {
 	DatabaseQueryDuration   *prometheus.HistogramVec
 	DatabaseConnections     prometheus.Gauge
 	
+	// Batch processor metrics
+	BatchBufferSize         prometheus.Gauge
+	BatchFlushesTotal       prometheus.Counter
+	BatchFlushedEventsTotal prometheus.Counter
+	BatchFlushDuration      prometheus.Histogram
+	
+	// Event latency metrics
+	EventChainLatency       prometheus.Histogram
+	EventProcessingLatency  prometheus.Histogram
+	
 	// Error metrics
 	ErrorsTotal             *prometheus.CounterVec
 }
@@ -51,6 +63,15 @@ func NewMetrics() *Metrics {
 			Name: "chainpulse_events_cache_misses_total",
 			Help: "Total number of cache misses for events",
 		}),
+		RPCLimiterWait: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name: "chainpulse_rpc_limiter_wait_seconds",
+			Help: "Time node RPC calls waited for the global RPC rate limit in seconds",
+			Buckets: prometheus.DefBuckets,
+		}),
+		SpamEventsDroppedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "chainpulse_spam_events_dropped_total",
+			Help: "Total number of events dropped as spam before storage",
+		}, []string{"reason"}),
 		
 		// API metrics
 		APIRequestsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
@@ -78,6 +99,37 @@ func NewMetrics() *Metrics {
 			Help: "Number of active database connections",
 		}),
 		
+		// Batch processor metrics
+		BatchBufferSize: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "chainpulse_batch_buffer_size",
+			Help: "Number of events waiting in the batch processor to be flushed",
+		}),
+		BatchFlushesTotal: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "chainpulse_batch_flushes_total",
+			Help: "Total number of batch processor flushes",
+		}),
+		BatchFlushedEventsTotal: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "chainpulse_batch_flushed_events_total",
+			Help: "Total number of events written by batch processor flushes",
+		}),
+		BatchFlushDuration: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name: "chainpulse_batch_flush_duration_seconds",
+			Help: "Batch processor flush duration in seconds",
+			Buckets: prometheus.DefBuckets,
+		}),
+		
+		// Event latency metrics
+		EventChainLatency: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name: "chainpulse_event_chain_latency_seconds",
+			Help: "Time from the block timestamp of a subscribed event until it is stored in seconds",
+			Buckets: []float64{1, 2, 5, 10, 15, 30, 60, 120, 300, 600},
+		}),
+		EventProcessingLatency: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name: "chainpulse_event_processing_latency_seconds",
+			Help: "Time from receiving a subscribed event until it is stored in seconds",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}),
+		
 		// Error metrics
 		ErrorsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
 			Name: "chainpulse_errors_total",
@@ -113,6 +165,16 @@ func (m *Metrics) IncrementCacheMiss() {
 	m.EventsCacheMissesTotal.Inc()
 }
 
+// RecordRPCLimiterWait records how long a node RPC call waited for the rate limit
+func (m *Metrics) RecordRPCLimiterWait(seconds float64) {
+	m.RPCLimiterWait.Observe(seconds)
+}
+
+// IncrementSpamDropped increments the dropped spam counter for reason
+func (m *Metrics) IncrementSpamDropped(reason string) {
+	m.SpamEventsDroppedTotal.WithLabelValues(reason).Inc()
+}
+
 // RecordAPIRequest records an API request
 func (m *Metrics) RecordAPIRequest(method, endpoint, status string) {
 	m.APIRequestsTotal.WithLabelValues(method, endpoint, status).Inc()
@@ -138,6 +200,25 @@ func (m *Metrics) SetDatabaseConnections(count float64) {
 	m.DatabaseConnections.Set(count)
 }
 
+// AddBatchBufferSize adjusts the batch buffer size gauge by delta
+func (m *Metrics) AddBatchBufferSize(delta float64) {
+	m.BatchBufferSize.Add(delta)
+}
+
+// RecordBatchFlush records a batch processor flush of events that took duration seconds
+func (m *Metrics) RecordBatchFlush(events int, duration float64) {
+	m.BatchFlushesTotal.Inc()
+	m.BatchFlushedEventsTotal.Add(float64(events))
+	m.BatchFlushDuration.Observe(duration)
+}
+
+// RecordEventLatency records how long a subscribed event took to be stored: chainSeconds
+// since its block timestamp and processingSeconds since the indexer received it
+func (m *Metrics) RecordEventLatency(chainSeconds, processingSeconds float64) {
+	m.EventChainLatency.Observe(chainSeconds)
+	m.EventProcessingLatency.Observe(processingSeconds)
+}
+
 // IncrementError increments the error counter
 func (m *Metrics) IncrementError(component, errorType string) {
 	m.ErrorsTotal.WithLabelValues(component, errorType).Inc()
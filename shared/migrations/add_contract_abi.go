@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// AddContractABIMigration adds the ABI of contracts, fetched from a block explorer for
+// verified contracts, so their logs decode without a manual upload
+type AddContractABIMigration struct{}
+
+// Up adds the abi column
+func (m *AddContractABIMigration) Up(db *gorm.DB) error {
+	err := db.Exec("ALTER TABLE contracts ADD COLUMN IF NOT EXISTS abi TEXT NOT NULL DEFAULT ''").Error
+	if err != nil {
+		return fmt.Errorf("failed to add abi column: %v", err)
+	}
+	return nil
+}
+
+// Down removes the abi column
+func (m *AddContractABIMigration) Down(db *gorm.DB) error {
+	err := db.Exec("ALTER TABLE contracts DROP COLUMN IF EXISTS abi").Error
+	if err != nil {
+		return fmt.Errorf("failed to drop abi column: %v", err)
+	}
+	return nil
+}
+
+// Version returns the migration version
+func (m *AddContractABIMigration) Version() string {
+	return "202311010008"
+}
+
+// Description returns the migration description
+func (m *AddContractABIMigration) Description() string {
+	return "Add ABI to contracts"
+}
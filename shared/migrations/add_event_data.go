@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// AddEventDataMigration adds the column holding the decoded event parameters
+type AddEventDataMigration struct{}
+
+// Up adds the data column
+func (m *AddEventDataMigration) Up(db *gorm.DB) error {
+	err := db.Exec("ALTER TABLE events ADD COLUMN IF NOT EXISTS data JSONB").Error
+	if err != nil {
+		return fmt.Errorf("failed to add data column: %v", err)
+	}
+
+	return nil
+}
+
+// Down removes the data column
+func (m *AddEventDataMigration) Down(db *gorm.DB) error {
+	err := db.Exec("ALTER TABLE events DROP COLUMN IF EXISTS data").Error
+	if err != nil {
+		return fmt.Errorf("failed to drop data column: %v", err)
+	}
+
+	return nil
+}
+
+// Version returns the migration version
+func (m *AddEventDataMigration) Version() string {
+	return "202311010004"
+}
+
+// Description returns the migration description
+func (m *AddEventDataMigration) Description() string {
+	return "Add decoded event data to events"
+}
@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// AddEventTopic0Migration adds the event signature hash so events can be queried by
+// signature across contracts
+type AddEventTopic0Migration struct{}
+
+// Up adds the topic0 column and an index for signature lookups over a block range
+func (m *AddEventTopic0Migration) Up(db *gorm.DB) error {
+	err := db.Exec("ALTER TABLE events ADD COLUMN IF NOT EXISTS topic0 VARCHAR(66) NOT NULL DEFAULT ''").Error
+	if err != nil {
+		return fmt.Errorf("failed to add topic0 column: %v", err)
+	}
+
+	err = db.Exec("CREATE INDEX IF NOT EXISTS idx_events_topic0_block_number ON events (topic0, block_number)").Error
+	if err != nil {
+		return fmt.Errorf("failed to create topic0 index: %v", err)
+	}
+
+	return nil
+}
+
+// Down removes the topic0 column and its index
+func (m *AddEventTopic0Migration) Down(db *gorm.DB) error {
+	err := db.Exec("DROP INDEX IF EXISTS idx_events_topic0_block_number").Error
+	if err != nil {
+		return fmt.Errorf("failed to drop topic0 index: %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE events DROP COLUMN IF EXISTS topic0").Error
+	if err != nil {
+		return fmt.Errorf("failed to drop topic0 column: %v", err)
+	}
+
+	return nil
+}
+
+// Version returns the migration version
+func (m *AddEventTopic0Migration) Version() string {
+	return "202311010006"
+}
+
+// Description returns the migration description
+func (m *AddEventTopic0Migration) Description() string {
+	return "Add event signature hash to events"
+}
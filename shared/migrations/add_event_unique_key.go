@@ -0,0 +1,79 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// AddEventUniqueKeyMigration makes (tx_hash, log_index) unique so a redelivered event
+// cannot be stored twice
+type AddEventUniqueKeyMigration struct{}
+
+// Up removes duplicate events, keeping the first stored copy, and adds the unique index
+func (m *AddEventUniqueKeyMigration) Up(db *gorm.DB) error {
+	if err := removeDuplicateEvents(db, "events"); err != nil {
+		return err
+	}
+
+	err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_events_tx_hash_log_index_unique ON events (tx_hash, log_index)").Error
+	if err != nil {
+		return fmt.Errorf("failed to create tx-hash-log-index unique index: %v", err)
+	}
+
+	return nil
+}
+
+// removeDuplicateEvents deletes the copies of events stored more than once in table.
+// Events stored before log indexes were recorded all have log index 0, so events sharing
+// a transaction and log index are only duplicates if the rest of their content matches too.
+// Distinct events left at the same position are not guessed at: removeDuplicateEvents fails
+// and they have to be reindexed before the unique index can be added.
+func removeDuplicateEvents(db *gorm.DB, table string) error {
+	err := db.Exec(fmt.Sprintf(`DELETE FROM %[1]s a USING %[1]s b
+		WHERE a.tx_hash = b.tx_hash AND a.log_index = b.log_index AND a.id > b.id
+		AND a.block_number IS NOT DISTINCT FROM b.block_number
+		AND a.contract IS NOT DISTINCT FROM b.contract
+		AND a.event_name IS NOT DISTINCT FROM b.event_name
+		AND a."from" IS NOT DISTINCT FROM b."from"
+		AND a."to" IS NOT DISTINCT FROM b."to"
+		AND a.token_id IS NOT DISTINCT FROM b.token_id
+		AND a.value IS NOT DISTINCT FROM b.value
+		AND a.data IS NOT DISTINCT FROM b.data`, table)).Error
+	if err != nil {
+		return fmt.Errorf("failed to remove duplicate events: %v", err)
+	}
+
+	var collisions int64
+	err = db.Raw(fmt.Sprintf(`SELECT COUNT(*) FROM (
+		SELECT tx_hash, log_index FROM %s GROUP BY tx_hash, log_index HAVING COUNT(*) > 1
+	) AS collisions`, table)).Scan(&collisions).Error
+	if err != nil {
+		return fmt.Errorf("failed to count events at the same log index: %v", err)
+	}
+	if collisions > 0 {
+		return fmt.Errorf("%d transactions have distinct events at the same log index, stored before log indexes were recorded; reindex them before adding the unique key", collisions)
+	}
+
+	return nil
+}
+
+// Down removes the unique index; removed duplicates are not restored
+func (m *AddEventUniqueKeyMigration) Down(db *gorm.DB) error {
+	err := db.Exec("DROP INDEX IF EXISTS idx_events_tx_hash_log_index_unique").Error
+	if err != nil {
+		return fmt.Errorf("failed to drop tx-hash-log-index unique index: %v", err)
+	}
+
+	return nil
+}
+
+// Version returns the migration version
+func (m *AddEventUniqueKeyMigration) Version() string {
+	return "202311010005"
+}
+
+// Description returns the migration description
+func (m *AddEventUniqueKeyMigration) Description() string {
+	return "Add unique key to events"
+}
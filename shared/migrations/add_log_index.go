@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// AddLogIndexMigration adds the log index column so a transaction can have one row per log
+type AddLogIndexMigration struct{}
+
+// Up adds the log_index column and an index for per-transaction lookups
+func (m *AddLogIndexMigration) Up(db *gorm.DB) error {
+	err := db.Exec("ALTER TABLE events ADD COLUMN IF NOT EXISTS log_index BIGINT NOT NULL DEFAULT 0").Error
+	if err != nil {
+		return fmt.Errorf("failed to add log_index column: %v", err)
+	}
+
+	err = db.Exec("CREATE INDEX IF NOT EXISTS idx_events_tx_hash_log_index ON events (tx_hash, log_index)").Error
+	if err != nil {
+		return fmt.Errorf("failed to create tx-hash-log-index index: %v", err)
+	}
+
+	return nil
+}
+
+// Down removes the log_index column and its index
+func (m *AddLogIndexMigration) Down(db *gorm.DB) error {
+	err := db.Exec("DROP INDEX IF EXISTS idx_events_tx_hash_log_index").Error
+	if err != nil {
+		return fmt.Errorf("failed to drop tx-hash-log-index index: %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE events DROP COLUMN IF EXISTS log_index").Error
+	if err != nil {
+		return fmt.Errorf("failed to drop log_index column: %v", err)
+	}
+
+	return nil
+}
+
+// Version returns the migration version
+func (m *AddLogIndexMigration) Version() string {
+	return "202311010003"
+}
+
+// Description returns the migration description
+func (m *AddLogIndexMigration) Description() string {
+	return "Add log index to events"
+}
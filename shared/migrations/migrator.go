@@ -3,6 +3,8 @@ package migrations
 import (
 	"fmt"
 	"log"
+	"sort"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -15,6 +17,21 @@ type Migration interface {
 	Description() string
 }
 
+// SchemaMigration records an applied migration in the schema_migrations table
+type SchemaMigration struct {
+	Version     string    `gorm:"primaryKey;size:32"`
+	Description string    `gorm:"not null;default:''"`
+	AppliedAt   time.Time `gorm:"not null"`
+}
+
+// TableName returns the name of the migration tracking table
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// legacyMigrationsTable is the tracking table of earlier versions of the migrator
+const legacyMigrationsTable = "migration_records"
+
 // Migrator manages database migrations
 type Migrator struct {
 	DB         *gorm.DB
@@ -34,125 +51,143 @@ func (m *Migrator) AddMigration(migration Migration) {
 	m.Migrations = append(m.Migrations, migration)
 }
 
-// RunMigrations runs all pending migrations
+// sortedMigrations returns the migrations ordered by version
+func (m *Migrator) sortedMigrations() []Migration {
+	sorted := make([]Migration, len(m.Migrations))
+	copy(sorted, m.Migrations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Version() < sorted[j].Version()
+	})
+	return sorted
+}
+
+// RunMigrations applies the migrations not applied yet, in version order. Each
+// migration runs in its own transaction together with its record in
+// schema_migrations, so a failed migration leaves no partial changes and is retried
+// on the next run, while the migrations before it stay applied. Running again once
+// all are applied does nothing.
 func (m *Migrator) RunMigrations() error {
-	// Create the migrations table if it doesn't exist
-	err := m.createMigrationsTable()
-	if err != nil {
+	if err := m.createMigrationsTable(); err != nil {
 		return fmt.Errorf("failed to create migrations table: %v", err)
 	}
 
-	// Get already run migrations
-	runMigrations, err := m.getRunMigrations()
+	applied, err := m.AppliedVersions()
 	if err != nil {
-		return fmt.Errorf("failed to get run migrations: %v", err)
+		return fmt.Errorf("failed to get applied migrations: %v", err)
 	}
 
-	// Run pending migrations
-	for _, migration := range m.Migrations {
-		if !m.isMigrationRun(runMigrations, migration.Version()) {
-			log.Printf("Running migration: %s - %s", migration.Version(), migration.Description())
-			
-			err := migration.Up(m.DB)
-			if err != nil {
-				return fmt.Errorf("failed to run migration %s: %v", migration.Version(), err)
-			}
+	for _, migration := range m.sortedMigrations() {
+		if applied[migration.Version()] {
+			continue
+		}
 
-			err = m.recordMigration(migration.Version())
-			if err != nil {
-				return fmt.Errorf("failed to record migration %s: %v", migration.Version(), err)
-			}
+		log.Printf("Running migration: %s - %s", migration.Version(), migration.Description())
 
-			log.Printf("Migration %s completed successfully", migration.Version())
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			record := SchemaMigration{
+				Version:     migration.Version(),
+				Description: migration.Description(),
+				AppliedAt:   time.Now().UTC(),
+			}
+			if err := tx.Create(&record).Error; err != nil {
+				return fmt.Errorf("failed to record migration: %v", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to run migration %s: %v", migration.Version(), err)
 		}
-	}
 
-	return nil
-}
-
-// createMigrationsTable creates the table to track migrations
-func (m *Migrator) createMigrationsTable() error {
-	type MigrationRecord struct {
-		ID        uint   `gorm:"primaryKey"`
-		Version   string `gorm:"uniqueIndex;not null"`
-		CreatedAt string `gorm:"autoCreateTime"`
+		log.Printf("Migration %s completed successfully", migration.Version())
 	}
 
-	return m.DB.AutoMigrate(&MigrationRecord{})
+	return nil
 }
 
-// getRunMigrations gets the list of already run migrations
-func (m *Migrator) getRunMigrations() ([]string, error) {
-	type MigrationRecord struct {
-		Version string
+// RollbackLast rolls back the most recently applied migration, in a transaction
+// together with the removal of its record. It returns the rolled back version, or
+// an empty string if no migration is applied.
+func (m *Migrator) RollbackLast() (string, error) {
+	if err := m.createMigrationsTable(); err != nil {
+		return "", fmt.Errorf("failed to create migrations table: %v", err)
 	}
 
-	var records []MigrationRecord
-	err := m.DB.Model(&MigrationRecord{}).Find(&records).Error
+	var last SchemaMigration
+	err := m.DB.Order("version DESC").Limit(1).Find(&last).Error
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("failed to get the last applied migration: %v", err)
 	}
-
-	versions := make([]string, len(records))
-	for i, record := range records {
-		versions[i] = record.Version
+	if last.Version == "" {
+		return "", nil
 	}
 
-	return versions, nil
-}
-
-// isMigrationRun checks if a migration has already been run
-func (m *Migrator) isMigrationRun(runMigrations []string, version string) bool {
-	for _, migration := range runMigrations {
-		if migration == version {
-			return true
+	var target Migration
+	for _, migration := range m.Migrations {
+		if migration.Version() == last.Version {
+			target = migration
+			break
 		}
 	}
-	return false
-}
-
-// recordMigration records a migration as run
-func (m *Migrator) recordMigration(version string) error {
-	type MigrationRecord struct {
-		ID      uint   `gorm:"primaryKey"`
-		Version string `gorm:"uniqueIndex;not null"`
+	if target == nil {
+		return "", fmt.Errorf("migration %s is applied but not registered", last.Version)
 	}
 
-	record := MigrationRecord{Version: version}
-	return m.DB.Create(&record).Error
-}
+	log.Printf("Rolling back migration: %s - %s", target.Version(), target.Description())
 
-// rollbackMigration rolls back a migration
-func (m *Migrator) rollbackMigration(version string) error {
-	// Find the migration to rollback
-	var targetMigration Migration
-	for _, migration := range m.Migrations {
-		if migration.Version() == version {
-			targetMigration = migration
-			break
+	err = m.DB.Transaction(func(tx *gorm.DB) error {
+		if err := target.Down(tx); err != nil {
+			return err
 		}
+		if err := tx.Where("version = ?", last.Version).Delete(&SchemaMigration{}).Error; err != nil {
+			return fmt.Errorf("failed to remove migration record: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to roll back migration %s: %v", last.Version, err)
 	}
 
-	if targetMigration == nil {
-		return fmt.Errorf("migration %s not found", version)
+	log.Printf("Migration %s rolled back successfully", last.Version)
+	return last.Version, nil
+}
+
+// AppliedVersions returns the versions of the applied migrations
+func (m *Migrator) AppliedVersions() (map[string]bool, error) {
+	var records []SchemaMigration
+	if err := m.DB.Find(&records).Error; err != nil {
+		return nil, err
 	}
 
-	log.Printf("Rolling back migration: %s", version)
-	
-	err := targetMigration.Down(m.DB)
-	if err != nil {
-		return fmt.Errorf("failed to rollback migration %s: %v", version, err)
+	applied := make(map[string]bool, len(records))
+	for _, record := range records {
+		applied[record.Version] = true
 	}
+	return applied, nil
+}
 
-	// Remove the migration record
-	err = m.DB.Where("version = ?", version).Delete(&struct {
-		ID      uint   `gorm:"primaryKey"`
-		Version string `gorm:"uniqueIndex;not null"`
-	}{}).Error
-	if err != nil {
-		return fmt.Errorf("failed to remove migration record %s: %v", version, err)
+// createMigrationsTable creates the schema_migrations table. Databases migrated by
+// earlier versions of the migrator keep their history: the versions recorded in the
+// legacy table are moved over, so those migrations are not applied again.
+func (m *Migrator) createMigrationsTable() error {
+	if err := m.DB.AutoMigrate(&SchemaMigration{}); err != nil {
+		return err
 	}
 
-	log.Printf("Migration %s rolled back successfully", version)
-	return nil
-}
\ No newline at end of file
+	if !m.DB.Migrator().HasTable(legacyMigrationsTable) {
+		return nil
+	}
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(
+			"INSERT INTO schema_migrations (version, description, applied_at) " +
+				"SELECT version, '', NOW() FROM " + legacyMigrationsTable + " " +
+				"ON CONFLICT (version) DO NOTHING",
+		).Error
+		if err != nil {
+			return fmt.Errorf("failed to copy legacy migration records: %v", err)
+		}
+		return tx.Migrator().DropTable(legacyMigrationsTable)
+	})
+}
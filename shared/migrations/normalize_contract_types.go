@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// canonicalContractTypes are the canonical contract types of types.ContractTypes. They are
+// listed here so migrations do not depend on the types package.
+var canonicalContractTypes = []string{"ERC20", "ERC721", "ERC1155"}
+
+// NormalizeContractTypesMigration rewrites contract types stored in variant spellings,
+// such as "erc-721", to their canonical types so filters on the type match every row
+type NormalizeContractTypesMigration struct{}
+
+// Up rewrites every spelling that normalizes to a canonical type
+func (m *NormalizeContractTypesMigration) Up(db *gorm.DB) error {
+	for _, contractType := range canonicalContractTypes {
+		// The SQL counterpart of types.ParseContractType
+		err := db.Exec(`UPDATE contracts SET type = ?
+			WHERE type <> ? AND regexp_replace(regexp_replace(upper(type), '[-_ ]', '', 'g'), '^EIP', 'ERC') = ?`,
+			contractType, contractType, contractType).Error
+		if err != nil {
+			return fmt.Errorf("failed to normalize %s contract types: %v", contractType, err)
+		}
+	}
+	return nil
+}
+
+// Down does nothing, the original spellings are not kept
+func (m *NormalizeContractTypesMigration) Down(db *gorm.DB) error {
+	return nil
+}
+
+// Version returns the migration version
+func (m *NormalizeContractTypesMigration) Version() string {
+	return "202311010007"
+}
+
+// Description returns the migration description
+func (m *NormalizeContractTypesMigration) Description() string {
+	return "Normalize contract types to their canonical spelling"
+}
@@ -0,0 +1,233 @@
+package mq
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultMaxMessageSize is the largest message published as-is, just under
+// Kafka's default broker limit (message.max.bytes) of about 1MB
+const DefaultMaxMessageSize = 1000000
+
+// ContentTypeChunk marks a message carrying one chunk of a larger framed message
+const ContentTypeChunk = "application/x-chainpulse-chunk"
+
+// Header keys carried by chunk messages
+const (
+	ChunkIDHeader    = "x-chunk-id"
+	ChunkIndexHeader = "x-chunk-index"
+	ChunkCountHeader = "x-chunk-count"
+)
+
+// chunkTTL bounds how long an incomplete chunked message is kept waiting for its remaining chunks
+const chunkTTL = 5 * time.Minute
+
+// maxMessageSizeFromConfig returns the limit selected by the "max_message_size" plugin
+// configuration key. 0 disables chunking.
+func maxMessageSizeFromConfig(config map[string]interface{}) (int, error) {
+	sizeInterface, exists := config["max_message_size"]
+	if !exists {
+		return DefaultMaxMessageSize, nil
+	}
+
+	var size int
+	switch v := sizeInterface.(type) {
+	case int:
+		size = v
+	case int64:
+		size = int(v)
+	case float64:
+		size = int(v)
+	default:
+		return 0, fmt.Errorf("invalid max_message_size configuration type: %T", sizeInterface)
+	}
+
+	if size < 0 {
+		return 0, fmt.Errorf("max_message_size must not be negative")
+	}
+	return size, nil
+}
+
+// SplitMessage splits a framed message larger than maxSize into chunk messages of at most
+// maxSize bytes each, to be published in order and reassembled by a Reassembler.
+// Messages within the limit, or any message when maxSize is 0, are returned unchanged.
+func SplitMessage(message []byte, maxSize int) ([][]byte, error) {
+	if maxSize <= 0 || len(message) <= maxSize {
+		return [][]byte{message}, nil
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate chunk ID: %w", err)
+	}
+
+	// The header block size depends on the digits of index and count, so size
+	// chunks for the largest header: a count with as many digits as the message length
+	maxDigits := strconv.Itoa(len(message))
+	overhead := len(chunkHeader(hex.EncodeToString(id), maxDigits, maxDigits))
+	chunkSize := maxSize - overhead
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("max message size %d too small to hold chunk headers", maxSize)
+	}
+
+	count := (len(message) + chunkSize - 1) / chunkSize
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * chunkSize
+		if end > len(message) {
+			end = len(message)
+		}
+		chunk := chunkHeader(hex.EncodeToString(id), strconv.Itoa(i), strconv.Itoa(count))
+		chunks = append(chunks, append(chunk, message[i*chunkSize:end]...))
+	}
+
+	return chunks, nil
+}
+
+// chunkHeader returns the envelope header block of a chunk message
+func chunkHeader(id, index, count string) []byte {
+	headers := [][2]string{
+		{ContentTypeHeader, ContentTypeChunk},
+		{ChunkIDHeader, id},
+		{ChunkIndexHeader, index},
+		{ChunkCountHeader, count},
+	}
+
+	header := append([]byte{}, envelopeMagic...)
+	header = append(header, byte(len(headers)))
+	for _, h := range headers {
+		header = append(header, byte(len(h[0])))
+		header = append(header, h[0]...)
+		header = append(header, byte(len(h[1])))
+		header = append(header, h[1]...)
+	}
+	return header
+}
+
+// splitForPublish splits an encoded message that exceeds maxSize, logging and
+// recording the oversized payload for the plugin
+func splitForPublish(pluginName, topic string, data []byte, maxSize int, collector *MetricsCollector) ([][]byte, error) {
+	chunks, err := SplitMessage(data, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chunks) > 1 {
+		log.Printf("Message of %d bytes on topic %s exceeds max size %d, publishing as %d chunks", len(data), topic, maxSize, len(chunks))
+		if collector != nil {
+			collector.RecordOversizedMessage(pluginName)
+		}
+	}
+
+	return chunks, nil
+}
+
+// pendingMessage collects the chunks of one message until all have arrived
+type pendingMessage struct {
+	chunks    [][]byte
+	received  int
+	firstSeen time.Time
+}
+
+// Reassembler rebuilds messages split by SplitMessage. Chunks may arrive in any
+// order and interleaved with other messages; it is safe for concurrent use.
+type Reassembler struct {
+	mu      sync.Mutex
+	pending map[string]*pendingMessage
+	ttl     time.Duration
+}
+
+// NewReassembler creates a new reassembler
+func NewReassembler() *Reassembler {
+	return &Reassembler{
+		pending: make(map[string]*pendingMessage),
+		ttl:     chunkTTL,
+	}
+}
+
+// Add processes a consumed message. Messages that are not chunks are returned
+// as-is with complete set; for chunks, complete is set once the last one arrives
+// and the reassembled message is returned.
+func (r *Reassembler) Add(message []byte) (reassembled []byte, complete bool, err error) {
+	if !bytes.HasPrefix(message, envelopeMagic) {
+		return message, true, nil
+	}
+
+	headers, payload, err := DecodeEnvelope(message)
+	if err != nil {
+		return nil, false, err
+	}
+	if headers[ContentTypeHeader] != ContentTypeChunk {
+		return message, true, nil
+	}
+
+	id := headers[ChunkIDHeader]
+	index, err := strconv.Atoi(headers[ChunkIndexHeader])
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid chunk index: %w", err)
+	}
+	count, err := strconv.Atoi(headers[ChunkCountHeader])
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid chunk count: %w", err)
+	}
+	if id == "" || count <= 0 || index < 0 || index >= count {
+		return nil, false, fmt.Errorf("invalid chunk %d/%d of message %q", index, count, id)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.evictExpired()
+
+	pending, exists := r.pending[id]
+	if !exists {
+		pending = &pendingMessage{chunks: make([][]byte, count), firstSeen: time.Now()}
+		r.pending[id] = pending
+	}
+	if len(pending.chunks) != count {
+		return nil, false, fmt.Errorf("chunk count mismatch for message %s: expected %d, got %d", id, len(pending.chunks), count)
+	}
+
+	// Redelivered chunks are ignored
+	if pending.chunks[index] == nil {
+		pending.chunks[index] = append([]byte{}, payload...)
+		pending.received++
+	}
+
+	if pending.received < count {
+		return nil, false, nil
+	}
+
+	delete(r.pending, id)
+	return bytes.Join(pending.chunks, nil), true, nil
+}
+
+// evictExpired drops incomplete messages whose chunks stopped arriving. Callers must hold r.mu.
+func (r *Reassembler) evictExpired() {
+	for id, pending := range r.pending {
+		if time.Since(pending.firstSeen) > r.ttl {
+			log.Printf("Dropping incomplete chunked message %s: received %d of %d chunks", id, pending.received, len(pending.chunks))
+			delete(r.pending, id)
+		}
+	}
+}
+
+// Handler wraps handler so that it only sees complete messages
+func (r *Reassembler) Handler(handler MessageHandler) MessageHandler {
+	return func(message []byte) error {
+		reassembled, complete, err := r.Add(message)
+		if err != nil {
+			return fmt.Errorf("failed to reassemble message: %w", err)
+		}
+		if !complete {
+			return nil
+		}
+		return handler(reassembled)
+	}
+}
@@ -0,0 +1,108 @@
+package mq
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitMessage_OversizedEventIsReassembled(t *testing.T) {
+	event := map[string]string{
+		"tx_hash": "0xlarge",
+		"data":    strings.Repeat("ab", 2048),
+	}
+	message, err := Encode(MsgpackCodec{}, event)
+	if err != nil {
+		t.Fatalf("Failed to encode event: %v", err)
+	}
+
+	const maxSize = 512
+	collector := NewMetricsCollector()
+	chunks, err := splitForPublish("memory", "blockchain.raw.events", message, maxSize, collector)
+	if err != nil {
+		t.Fatalf("Failed to split message: %v", err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("Expected message of %d bytes to be split, got %d chunk(s)", len(message), len(chunks))
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > maxSize {
+			t.Errorf("Expected chunk %d to be at most %d bytes, got %d", i, maxSize, len(chunk))
+		}
+	}
+
+	metrics, exists := collector.GetPluginMetrics("memory")
+	if !exists || metrics.OversizedMessages != 1 {
+		t.Errorf("Expected 1 oversized message recorded, got %+v", metrics)
+	}
+
+	small, _ := Encode(JSONCodec{}, map[string]string{"tx_hash": "0xsmall"})
+
+	var received []map[string]string
+	handler := NewReassembler().Handler(func(message []byte) error {
+		var payload map[string]string
+		if err := Decode(message, &payload); err != nil {
+			return err
+		}
+		received = append(received, payload)
+		return nil
+	})
+
+	// Deliver the chunks in reverse, with an unrelated message and a redelivered chunk in between
+	for i := len(chunks) - 1; i >= 0; i-- {
+		if err := handler(chunks[i]); err != nil {
+			t.Fatalf("Failed to handle chunk %d: %v", i, err)
+		}
+		if i == len(chunks)-1 {
+			if err := handler(small); err != nil {
+				t.Fatalf("Failed to handle message: %v", err)
+			}
+			if err := handler(chunks[i]); err != nil {
+				t.Fatalf("Failed to handle redelivered chunk %d: %v", i, err)
+			}
+		}
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(received))
+	}
+	if received[0]["tx_hash"] != "0xsmall" {
+		t.Errorf("Expected 0xsmall, got %s", received[0]["tx_hash"])
+	}
+	if received[1]["tx_hash"] != "0xlarge" || received[1]["data"] != event["data"] {
+		t.Errorf("Expected reassembled event 0xlarge, got %s with %d bytes of data", received[1]["tx_hash"], len(received[1]["data"]))
+	}
+}
+
+func TestSplitMessage_WithinLimitIsUnchanged(t *testing.T) {
+	message, _ := Encode(JSONCodec{}, map[string]string{"tx_hash": "0x1"})
+
+	for _, maxSize := range []int{0, len(message)} {
+		chunks, err := SplitMessage(message, maxSize)
+		if err != nil {
+			t.Fatalf("Failed to split message: %v", err)
+		}
+		if len(chunks) != 1 || string(chunks[0]) != string(message) {
+			t.Errorf("Expected message unchanged with max size %d, got %d chunk(s)", maxSize, len(chunks))
+		}
+	}
+
+	if _, err := SplitMessage(append(message, message...), 16); err == nil {
+		t.Error("Expected error for a max size too small to hold chunk headers")
+	}
+}
+
+func TestMaxMessageSizeFromConfig(t *testing.T) {
+	size, err := maxMessageSizeFromConfig(map[string]interface{}{})
+	if err != nil || size != DefaultMaxMessageSize {
+		t.Errorf("Expected default %d, got %d (%v)", DefaultMaxMessageSize, size, err)
+	}
+
+	size, err = maxMessageSizeFromConfig(map[string]interface{}{"max_message_size": float64(2048)})
+	if err != nil || size != 2048 {
+		t.Errorf("Expected 2048, got %d (%v)", size, err)
+	}
+
+	if _, err := maxMessageSizeFromConfig(map[string]interface{}{"max_message_size": "big"}); err == nil {
+		t.Error("Expected error for a non-numeric max_message_size")
+	}
+}
@@ -0,0 +1,336 @@
+package mq
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+
+	"chainpulse/shared/json"
+	"chainpulse/shared/requestid"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Content types written into the message header for each codec
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeMsgpack  = "application/msgpack"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// Header keys carried in the message envelope (and as native Kafka headers)
+const (
+	ContentTypeHeader = "content-type"
+	RequestIDHeader   = "x-request-id"
+	MessageIDHeader   = "x-message-id" // dedup ID set with WithMessageID
+)
+
+// Codec encodes and decodes message payloads
+type Codec interface {
+	Name() string
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// ProtoMarshaler is implemented by types that encode themselves with the protobuf wire format
+type ProtoMarshaler interface {
+	MarshalProto() ([]byte, error)
+}
+
+// ProtoUnmarshaler is implemented by types that decode themselves from the protobuf wire format
+type ProtoUnmarshaler interface {
+	UnmarshalProto(data []byte) error
+}
+
+// JSONCodec encodes messages as JSON
+type JSONCodec struct{}
+
+// Name returns the codec name
+func (JSONCodec) Name() string { return "json" }
+
+// ContentType returns the codec content type
+func (JSONCodec) ContentType() string { return ContentTypeJSON }
+
+// Marshal encodes v as JSON
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes JSON data into v
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// MsgpackCodec encodes messages as msgpack, using the json struct tags for field names
+type MsgpackCodec struct{}
+
+// Name returns the codec name
+func (MsgpackCodec) Name() string { return "msgpack" }
+
+// ContentType returns the codec content type
+func (MsgpackCodec) ContentType() string { return ContentTypeMsgpack }
+
+// Marshal encodes v as msgpack
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes msgpack data into v
+func (MsgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// ProtobufCodec encodes messages with the protobuf wire format.
+// Values must be proto.Message or implement ProtoMarshaler/ProtoUnmarshaler.
+type ProtobufCodec struct{}
+
+// Name returns the codec name
+func (ProtobufCodec) Name() string { return "protobuf" }
+
+// ContentType returns the codec content type
+func (ProtobufCodec) ContentType() string { return ContentTypeProtobuf }
+
+// Marshal encodes v with the protobuf wire format
+func (ProtobufCodec) Marshal(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case proto.Message:
+		return proto.Marshal(m)
+	case ProtoMarshaler:
+		return m.MarshalProto()
+	default:
+		return nil, fmt.Errorf("type %T does not support protobuf encoding", v)
+	}
+}
+
+// Unmarshal decodes protobuf data into v
+func (ProtobufCodec) Unmarshal(data []byte, v interface{}) error {
+	switch m := v.(type) {
+	case proto.Message:
+		return proto.Unmarshal(data, m)
+	case ProtoUnmarshaler:
+		return m.UnmarshalProto(data)
+	default:
+		return fmt.Errorf("type %T does not support protobuf decoding", v)
+	}
+}
+
+// codecs holds the available codecs by name
+var codecs = map[string]Codec{
+	"json":     JSONCodec{},
+	"msgpack":  MsgpackCodec{},
+	"protobuf": ProtobufCodec{},
+}
+
+// GetCodec returns the codec with the given name, defaulting to JSON when name is empty
+func GetCodec(name string) (Codec, error) {
+	if name == "" {
+		return JSONCodec{}, nil
+	}
+
+	codec, exists := codecs[name]
+	if !exists {
+		return nil, fmt.Errorf("unsupported codec: %s", name)
+	}
+
+	return codec, nil
+}
+
+// CheckCodec returns an error unless the codec named name exists and can encode and
+// decode every message, given as a value of each type a service publishes. Services call
+// it at startup, so a codec that cannot carry their messages is rejected before any
+// message is published rather than failing every publish.
+func CheckCodec(name string, messages ...interface{}) error {
+	codec, err := GetCodec(name)
+	if err != nil {
+		return err
+	}
+	if _, ok := codec.(ProtobufCodec); !ok {
+		return nil
+	}
+
+	for _, message := range messages {
+		if !supportsProtobuf(message) {
+			return fmt.Errorf("codec %s does not support %T messages", codec.Name(), message)
+		}
+	}
+	return nil
+}
+
+// supportsProtobuf reports whether ProtobufCodec can encode message and decode it back
+// into a value of its type
+func supportsProtobuf(message interface{}) bool {
+	target := message
+	if t := reflect.TypeOf(message); t != nil && t.Kind() != reflect.Ptr {
+		target = reflect.New(t).Interface()
+	}
+
+	_, encodes := message.(proto.Message)
+	if _, ok := message.(ProtoMarshaler); ok {
+		encodes = true
+	}
+	_, decodes := target.(proto.Message)
+	if _, ok := target.(ProtoUnmarshaler); ok {
+		decodes = true
+	}
+	return encodes && decodes
+}
+
+// codecForContentType returns the codec that produced the given content type
+func codecForContentType(contentType string) (Codec, error) {
+	for _, codec := range codecs {
+		if codec.ContentType() == contentType {
+			return codec, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported content type: %s", contentType)
+}
+
+// codecFromConfig returns the codec selected by the "codec" plugin configuration key
+func codecFromConfig(config map[string]interface{}) (Codec, error) {
+	codecInterface, exists := config["codec"]
+	if !exists {
+		return JSONCodec{}, nil
+	}
+
+	name, ok := codecInterface.(string)
+	if !ok {
+		return nil, fmt.Errorf("codec must be a string")
+	}
+
+	return GetCodec(name)
+}
+
+// envelopeMagic marks a message framed with a header block. JSON payloads never
+// start with a NUL byte, so unframed messages from older publishers are still
+// recognised and treated as JSON.
+var envelopeMagic = []byte{0x00, 'c', 't'}
+
+// Encode marshals v with the codec and frames it with a content-type header
+func Encode(codec Codec, v interface{}) ([]byte, error) {
+	return EncodeWithHeaders(codec, v, nil)
+}
+
+// EncodeWithHeaders marshals v with the codec and frames it with the given headers
+// plus the content type:
+// magic | header count (1 byte) | (key length (1 byte) | key | value length (1 byte) | value)... | payload
+func EncodeWithHeaders(codec Codec, v interface{}, headers map[string]string) ([]byte, error) {
+	payload, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	all := map[string]string{ContentTypeHeader: codec.ContentType()}
+	for key, value := range headers {
+		if key != ContentTypeHeader {
+			all[key] = value
+		}
+	}
+	if len(all) > 0xff {
+		return nil, fmt.Errorf("too many message headers: %d", len(all))
+	}
+
+	message := append([]byte{}, envelopeMagic...)
+	message = append(message, byte(len(all)))
+	for key, value := range all {
+		if len(key) > 0xff || len(value) > 0xff {
+			return nil, fmt.Errorf("message header %s too long", key)
+		}
+		message = append(message, byte(len(key)))
+		message = append(message, key...)
+		message = append(message, byte(len(value)))
+		message = append(message, value...)
+	}
+	message = append(message, payload...)
+	return message, nil
+}
+
+// DecodeEnvelope splits a framed message into its headers and payload.
+// Unframed messages are reported as JSON with no other headers.
+func DecodeEnvelope(message []byte) (map[string]string, []byte, error) {
+	if !bytes.HasPrefix(message, envelopeMagic) {
+		return map[string]string{ContentTypeHeader: ContentTypeJSON}, message, nil
+	}
+
+	rest := message[len(envelopeMagic):]
+	if len(rest) < 1 {
+		return nil, nil, fmt.Errorf("truncated message header")
+	}
+	count := int(rest[0])
+	rest = rest[1:]
+
+	headers := make(map[string]string, count)
+	for i := 0; i < count; i++ {
+		key, remaining, err := readHeaderField(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		value, remaining, err := readHeaderField(remaining)
+		if err != nil {
+			return nil, nil, err
+		}
+		headers[key] = value
+		rest = remaining
+	}
+
+	return headers, rest, nil
+}
+
+// readHeaderField reads a length-prefixed header field
+func readHeaderField(data []byte) (string, []byte, error) {
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return "", nil, fmt.Errorf("truncated message header")
+	}
+	return string(data[1 : 1+int(data[0])]), data[1+int(data[0]):], nil
+}
+
+// Decode unmarshals a consumed message into v using the codec named by its content-type header
+func Decode(message []byte, v interface{}) error {
+	headers, payload, err := DecodeEnvelope(message)
+	if err != nil {
+		return err
+	}
+
+	codec, err := codecForContentType(headers[ContentTypeHeader])
+	if err != nil {
+		return err
+	}
+
+	return codec.Unmarshal(payload, v)
+}
+
+// headersFromContext returns the envelope headers propagated from ctx
+func headersFromContext(ctx context.Context) map[string]string {
+	var headers map[string]string
+	if id := requestid.FromContext(ctx); id != "" {
+		headers = map[string]string{RequestIDHeader: id}
+	}
+	if id := MessageIDFromContext(ctx); id != "" {
+		if headers == nil {
+			headers = make(map[string]string, 1)
+		}
+		headers[MessageIDHeader] = id
+	}
+	return headers
+}
+
+// ContextFromMessage returns a copy of ctx carrying the request ID of a consumed
+// message, so processing it can be correlated with the request that triggered it
+func ContextFromMessage(ctx context.Context, message []byte) context.Context {
+	headers, _, err := DecodeEnvelope(message)
+	if err != nil || headers[RequestIDHeader] == "" {
+		return ctx
+	}
+	return requestid.NewContext(ctx, headers[RequestIDHeader])
+}
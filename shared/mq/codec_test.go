@@ -0,0 +1,171 @@
+package mq
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"chainpulse/shared/requestid"
+	"chainpulse/shared/types"
+)
+
+func TestCodecs_RoundTripIndexedEvent(t *testing.T) {
+	event := types.IndexedEvent{
+		ID:          42,
+		BlockNumber: big.NewInt(18000000),
+		TxHash:      "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b",
+		LogIndex:    7,
+		EventName:   "Transfer",
+		Contract:    "0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D",
+		From:        "0x0000000000000000000000000000000000000001",
+		To:          "0x0000000000000000000000000000000000000002",
+		TokenID:     "1234",
+		Value:       "1000000000000000000",
+		Data:        map[string]interface{}{"value": "1000000000000000000", "memo": "0x01"},
+		Timestamp:   time.Unix(1700000000, 0),
+	}
+
+	for _, name := range []string{"json", "msgpack", "protobuf"} {
+		t.Run(name, func(t *testing.T) {
+			codec, err := GetCodec(name)
+			if err != nil {
+				t.Fatalf("Expected codec %s, got error: %v", name, err)
+			}
+
+			message, err := Encode(codec, &event)
+			if err != nil {
+				t.Fatalf("Failed to encode event: %v", err)
+			}
+
+			headers, _, err := DecodeEnvelope(message)
+			if err != nil {
+				t.Fatalf("Failed to read message header: %v", err)
+			}
+			if headers[ContentTypeHeader] != codec.ContentType() {
+				t.Errorf("Expected content type %s, got %s", codec.ContentType(), headers[ContentTypeHeader])
+			}
+
+			var decoded types.IndexedEvent
+			if err := Decode(message, &decoded); err != nil {
+				t.Fatalf("Failed to decode event: %v", err)
+			}
+
+			if decoded.ID != event.ID {
+				t.Errorf("Expected ID %d, got %d", event.ID, decoded.ID)
+			}
+			if decoded.BlockNumber == nil || decoded.BlockNumber.Cmp(event.BlockNumber) != 0 {
+				t.Errorf("Expected block number %s, got %v", event.BlockNumber, decoded.BlockNumber)
+			}
+			if decoded.TxHash != event.TxHash {
+				t.Errorf("Expected tx hash %s, got %s", event.TxHash, decoded.TxHash)
+			}
+			if decoded.LogIndex != event.LogIndex {
+				t.Errorf("Expected log index %d, got %d", event.LogIndex, decoded.LogIndex)
+			}
+			if decoded.EventName != event.EventName || decoded.Contract != event.Contract {
+				t.Errorf("Expected %s on %s, got %s on %s", event.EventName, event.Contract, decoded.EventName, decoded.Contract)
+			}
+			if decoded.From != event.From || decoded.To != event.To {
+				t.Errorf("Expected %s -> %s, got %s -> %s", event.From, event.To, decoded.From, decoded.To)
+			}
+			if decoded.TokenID != event.TokenID || decoded.Value != event.Value {
+				t.Errorf("Expected token %s value %s, got token %s value %s", event.TokenID, event.Value, decoded.TokenID, decoded.Value)
+			}
+			if decoded.Data["value"] != event.Data["value"] || decoded.Data["memo"] != event.Data["memo"] {
+				t.Errorf("Expected data %v, got %v", event.Data, decoded.Data)
+			}
+			if !decoded.Timestamp.Equal(event.Timestamp) {
+				t.Errorf("Expected timestamp %v, got %v", event.Timestamp, decoded.Timestamp)
+			}
+			if decoded.SchemaVersion != types.CurrentEventSchemaVersion {
+				t.Errorf("Expected schema version %d, got %d", types.CurrentEventSchemaVersion, decoded.SchemaVersion)
+			}
+		})
+	}
+}
+
+func TestDecode_UnframedMessageIsJSON(t *testing.T) {
+	var decoded types.IndexedEvent
+	if err := Decode([]byte(`{"tx_hash":"0x1","event_name":"Transfer"}`), &decoded); err != nil {
+		t.Fatalf("Expected legacy JSON message to decode, got %v", err)
+	}
+	if decoded.TxHash != "0x1" {
+		t.Errorf("Expected tx hash 0x1, got %s", decoded.TxHash)
+	}
+}
+
+func TestGetCodec_Unsupported(t *testing.T) {
+	if _, err := GetCodec("xml"); err == nil {
+		t.Error("Expected error for unsupported codec")
+	}
+}
+
+func TestCheckCodec_RejectsUnsupportedMessages(t *testing.T) {
+	type plainMessage struct {
+		Name string `json:"name"`
+	}
+
+	for _, name := range []string{"json", "msgpack"} {
+		if err := CheckCodec(name, plainMessage{}, DeadLetterMessage{}); err != nil {
+			t.Errorf("Expected %s to support every message, got %v", name, err)
+		}
+	}
+
+	if err := CheckCodec("protobuf", DeadLetterMessage{}, &types.IndexedEvent{}); err != nil {
+		t.Errorf("Expected protobuf to support dead letters and events, got %v", err)
+	}
+	if err := CheckCodec("protobuf", DeadLetterMessage{}, plainMessage{}); err == nil {
+		t.Error("Expected protobuf to be rejected for a message without a protobuf encoding")
+	}
+	if err := CheckCodec("avro"); err == nil {
+		t.Error("Expected an unknown codec to be rejected")
+	}
+}
+
+func TestProtobufCodec_RoundTripDeadLetter(t *testing.T) {
+	deadLetter := DeadLetterMessage{
+		Topic:    "chainpulse.raw-events",
+		Error:    "handler failed",
+		Attempts: 3,
+		Payload:  []byte{0x00, 'c', 't', 0x01},
+		FailedAt: time.Unix(1700000000, 123),
+	}
+
+	message, err := Encode(ProtobufCodec{}, deadLetter)
+	if err != nil {
+		t.Fatalf("Failed to encode dead letter: %v", err)
+	}
+	var decoded DeadLetterMessage
+	if err := Decode(message, &decoded); err != nil {
+		t.Fatalf("Failed to decode dead letter: %v", err)
+	}
+
+	if decoded.Topic != deadLetter.Topic || decoded.Error != deadLetter.Error || decoded.Attempts != 3 {
+		t.Errorf("Expected %+v, got %+v", deadLetter, decoded)
+	}
+	if string(decoded.Payload) != string(deadLetter.Payload) || !decoded.FailedAt.Equal(deadLetter.FailedAt) {
+		t.Errorf("Expected payload %x failed at %v, got %x failed at %v", deadLetter.Payload, deadLetter.FailedAt, decoded.Payload, decoded.FailedAt)
+	}
+}
+
+func TestEncodeWithHeaders_PropagatesRequestID(t *testing.T) {
+	ctx := requestid.NewContext(context.Background(), "req-123")
+
+	message, err := EncodeWithHeaders(MsgpackCodec{}, &types.IndexedEvent{TxHash: "0x1"}, headersFromContext(ctx))
+	if err != nil {
+		t.Fatalf("Failed to encode event: %v", err)
+	}
+
+	if id := requestid.FromContext(ContextFromMessage(context.Background(), message)); id != "req-123" {
+		t.Errorf("Expected request ID req-123, got %q", id)
+	}
+
+	var decoded types.IndexedEvent
+	if err := Decode(message, &decoded); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if decoded.TxHash != "0x1" {
+		t.Errorf("Expected tx hash 0x1, got %s", decoded.TxHash)
+	}
+}
@@ -0,0 +1,213 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultDedupTTL is how long a delivered message ID is remembered. A retried publish
+// arrives within seconds, so an hour covers retries with room to spare.
+const DefaultDedupTTL = time.Hour
+
+// messageIDKey is the context key of the message ID
+type messageIDKey struct{}
+
+// WithMessageID returns a copy of ctx carrying the dedup ID of the message about to be
+// published. Publishing the same message again with the same ID, e.g. when a publish is
+// retried after a timeout although the first attempt succeeded, delivers it only once to
+// consumers using DedupHandler.
+func WithMessageID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, messageIDKey{}, id)
+}
+
+// MessageIDFromContext returns the message ID carried by ctx, or "" if none
+func MessageIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(messageIDKey{}).(string)
+	return id
+}
+
+// EventMessageID returns the dedup ID of the message of a log: one log of one chain is
+// one message however often it is published
+func EventMessageID(chainID, txHash string, logIndex uint) string {
+	return fmt.Sprintf("%s:%s:%d", chainID, strings.ToLower(txHash), logIndex)
+}
+
+// MessageID returns the message ID of a consumed message, or "" if it was published
+// without one
+func MessageID(message []byte) string {
+	headers, _, err := DecodeEnvelope(message)
+	if err != nil {
+		return ""
+	}
+	return headers[MessageIDHeader]
+}
+
+// DedupLease is how long a message is claimed while it is handled. A consumer that
+// crashes while handling a message holds it for no longer than the lease, after which the
+// redelivered message is handled again.
+const DedupLease = 5 * time.Minute
+
+// dedupSweepInterval is how often MemoryDedupStore drops expired IDs
+const dedupSweepInterval = time.Minute
+
+// DedupStore remembers the IDs of messages being handled and of delivered messages
+type DedupStore interface {
+	// Claim claims id for DedupLease while its message is handled and reports whether it
+	// was neither delivered nor claimed by another consumer
+	Claim(ctx context.Context, id string) (bool, error)
+	// Deliver records id as delivered once its message was handled
+	Deliver(ctx context.Context, id string) error
+	// Release forgets id, so a message whose handling failed is handled when redelivered
+	Release(ctx context.Context, id string) error
+}
+
+// DedupHandler wraps handler so that a message whose ID was already delivered or is being
+// handled is skipped. The ID is only recorded as delivered once handler succeeds, and is
+// released if it fails. Messages without an ID are always handled. A nil store returns
+// handler as is. If the store is unavailable the message is handled, preferring a
+// duplicate to a loss.
+func DedupHandler(store DedupStore, handler MessageHandler) MessageHandler {
+	if store == nil {
+		return handler
+	}
+
+	return func(message []byte) error {
+		id := MessageID(message)
+		if id == "" {
+			return handler(message)
+		}
+
+		ctx := context.Background()
+		first, err := store.Claim(ctx, id)
+		if err != nil {
+			log.Printf("Failed to check message %s for duplicates: %v", id, err)
+			return handler(message)
+		}
+		if !first {
+			log.Printf("Skipping duplicate message %s", id)
+			return nil
+		}
+
+		if err := handler(message); err != nil {
+			if releaseErr := store.Release(ctx, id); releaseErr != nil {
+				log.Printf("Failed to release message %s: %v", id, releaseErr)
+			}
+			return err
+		}
+		if err := store.Deliver(ctx, id); err != nil {
+			// The claim expires with its lease, after which a redelivery is handled again
+			log.Printf("Failed to record message %s as delivered: %v", id, err)
+		}
+		return nil
+	}
+}
+
+// MemoryDedupStore keeps claimed and delivered message IDs in memory, delivered ones for
+// ttl. It only deduplicates the messages of one consumer process; use RedisDedupStore to
+// share them.
+type MemoryDedupStore struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	expires   map[string]time.Time
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryDedupStore creates an in-memory dedup store; ttl <= 0 uses DefaultDedupTTL
+func NewMemoryDedupStore(ttl time.Duration) *MemoryDedupStore {
+	if ttl <= 0 {
+		ttl = DefaultDedupTTL
+	}
+	return &MemoryDedupStore{
+		ttl:     ttl,
+		expires: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Claim claims id for DedupLease and reports whether it was neither delivered within the
+// ttl nor claimed within the lease
+func (s *MemoryDedupStore) Claim(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if expires, ok := s.expires[id]; ok && now.Before(expires) {
+		return false, nil
+	}
+
+	s.sweep(now)
+	s.expires[id] = now.Add(DedupLease)
+	return true, nil
+}
+
+// Deliver records id as delivered for the ttl
+func (s *MemoryDedupStore) Deliver(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expires[id] = s.now().Add(s.ttl)
+	return nil
+}
+
+// Release forgets id
+func (s *MemoryDedupStore) Release(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.expires, id)
+	return nil
+}
+
+// sweep drops expired IDs so the store does not grow with every message ever seen. It
+// scans the IDs at most once per dedupSweepInterval, so claims stay cheap under load.
+func (s *MemoryDedupStore) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	for key, expires := range s.expires {
+		if !now.Before(expires) {
+			delete(s.expires, key)
+		}
+	}
+	s.nextSweep = now.Add(dedupSweepInterval)
+}
+
+// RedisDedupStore keeps claimed and delivered message IDs in Redis, shared by every consumer
+type RedisDedupStore struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisDedupStore creates a dedup store on client; ttl <= 0 uses DefaultDedupTTL
+func NewRedisDedupStore(client *redis.Client, ttl time.Duration) *RedisDedupStore {
+	if ttl <= 0 {
+		ttl = DefaultDedupTTL
+	}
+	return &RedisDedupStore{
+		client: client,
+		prefix: "mq:delivered:",
+		ttl:    ttl,
+	}
+}
+
+// Claim claims id for DedupLease with SET NX and reports whether it was neither delivered
+// nor claimed before
+func (s *RedisDedupStore) Claim(ctx context.Context, id string) (bool, error) {
+	return s.client.SetNX(ctx, s.prefix+id, 1, DedupLease).Result()
+}
+
+// Deliver records id as delivered for the ttl, replacing its claim
+func (s *RedisDedupStore) Deliver(ctx context.Context, id string) error {
+	return s.client.Set(ctx, s.prefix+id, 1, s.ttl).Err()
+}
+
+// Release forgets id
+func (s *RedisDedupStore) Release(ctx context.Context, id string) error {
+	return s.client.Del(ctx, s.prefix+id).Err()
+}
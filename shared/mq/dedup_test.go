@@ -0,0 +1,148 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// encodingQueue frames published messages like the broker plugins and delivers them on Consume
+type encodingQueue struct {
+	memoryQueue
+}
+
+func (q *encodingQueue) PublishContext(ctx context.Context, topic string, message interface{}) error {
+	data, err := EncodeWithHeaders(JSONCodec{}, message, headersFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.messages = append(q.messages, data)
+	return nil
+}
+
+func TestDedupHandler_DeliversKeyedMessageOnce(t *testing.T) {
+	queue := &encodingQueue{}
+	ctx := WithMessageID(context.Background(), EventMessageID("1", "0xABC", 3))
+
+	// The publish is retried although the first attempt reached the broker
+	for i := 0; i < 2; i++ {
+		if err := queue.PublishContext(ctx, "blockchain.raw.events", map[string]string{"tx_hash": "0xabc"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	// A message without an ID is never deduplicated
+	if err := queue.PublishContext(context.Background(), "blockchain.raw.events", map[string]string{"tx_hash": "0xdef"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var delivered []string
+	handler := DedupHandler(NewMemoryDedupStore(time.Minute), func(message []byte) error {
+		var event map[string]string
+		if err := Decode(message, &event); err != nil {
+			return err
+		}
+		delivered = append(delivered, event["tx_hash"])
+		return nil
+	})
+	if err := queue.Consume(context.Background(), "blockchain.raw.events", handler); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(delivered) != 2 || delivered[0] != "0xabc" || delivered[1] != "0xdef" {
+		t.Errorf("Expected deliveries [0xabc 0xdef], got %v", delivered)
+	}
+	if id := MessageID(queue.messages[0]); id != "1:0xabc:3" {
+		t.Errorf("Expected message ID 1:0xabc:3, got %q", id)
+	}
+}
+
+func TestDedupHandler_FailedMessageIsRedelivered(t *testing.T) {
+	message, _ := EncodeWithHeaders(JSONCodec{}, "event", map[string]string{MessageIDHeader: "1:0xabc:0"})
+
+	calls := 0
+	handler := DedupHandler(NewMemoryDedupStore(time.Minute), func([]byte) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database unavailable")
+		}
+		return nil
+	})
+
+	if err := handler(message); err == nil {
+		t.Fatal("Expected the first delivery to fail")
+	}
+	if err := handler(message); err != nil {
+		t.Fatalf("Expected the redelivery to succeed, got %v", err)
+	}
+	if err := handler(message); err != nil {
+		t.Fatalf("Expected the duplicate to be skipped, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 handler calls, got %d", calls)
+	}
+}
+
+func TestMemoryDedupStore_Expires(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryDedupStore(time.Minute)
+	store.now = func() time.Time { return now }
+
+	if first, _ := store.Claim(context.Background(), "id"); !first {
+		t.Fatal("Expected the first claim to succeed")
+	}
+	if err := store.Deliver(context.Background(), "id"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if first, _ := store.Claim(context.Background(), "id"); first {
+		t.Error("Expected a second claim within the ttl to fail")
+	}
+
+	now = now.Add(time.Minute)
+	if first, _ := store.Claim(context.Background(), "id"); !first {
+		t.Error("Expected a claim after the ttl to succeed")
+	}
+}
+
+func TestMemoryDedupStore_ClaimLeaseExpires(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryDedupStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	// The consumer handling the message crashes before it is delivered
+	if first, _ := store.Claim(context.Background(), "id"); !first {
+		t.Fatal("Expected the first claim to succeed")
+	}
+	if first, _ := store.Claim(context.Background(), "id"); first {
+		t.Error("Expected a claim within the lease to fail")
+	}
+
+	now = now.Add(DedupLease)
+	if first, _ := store.Claim(context.Background(), "id"); !first {
+		t.Error("Expected a claim after the lease to succeed")
+	}
+}
+
+func TestMemoryDedupStore_SweepsExpiredIDs(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryDedupStore(10 * time.Second)
+	store.now = func() time.Time { return now }
+
+	store.Claim(context.Background(), "a")
+	store.Deliver(context.Background(), "a")
+
+	// Claims between sweeps don't scan the IDs
+	now = now.Add(30 * time.Second)
+	store.Claim(context.Background(), "b")
+	if len(store.expires) != 2 {
+		t.Errorf("Expected no sweep within the sweep interval, got %d IDs", len(store.expires))
+	}
+
+	now = now.Add(dedupSweepInterval)
+	store.Claim(context.Background(), "c")
+	if _, ok := store.expires["a"]; ok || len(store.expires) != 2 {
+		t.Errorf("Expected only the expired ID to be dropped, got %v", store.expires)
+	}
+}
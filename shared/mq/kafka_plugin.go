@@ -2,9 +2,9 @@ package mq
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -15,12 +15,17 @@ type KafkaPlugin struct {
 	writer           *kafka.Writer
 	reader           *kafka.Reader
 	metricsCollector *MetricsCollector
+	codec            Codec
+	maxMessageSize   int
 	config           KafkaConfig
 }
 
 // NewKafkaPlugin creates a new Kafka plugin instance
 func NewKafkaPlugin() *KafkaPlugin {
-	return &KafkaPlugin{}
+	return &KafkaPlugin{
+		codec:          JSONCodec{},
+		maxMessageSize: DefaultMaxMessageSize,
+	}
 }
 
 // Initialize initializes the Kafka plugin with configuration
@@ -51,15 +56,29 @@ func (k *KafkaPlugin) Initialize(config map[string]interface{}) error {
 		return fmt.Errorf("at least one broker is required for Kafka plugin")
 	}
 
+	codec, err := codecFromConfig(config)
+	if err != nil {
+		return fmt.Errorf("invalid codec configuration for Kafka plugin: %w", err)
+	}
+
+	maxMessageSize, err := maxMessageSizeFromConfig(config)
+	if err != nil {
+		return fmt.Errorf("invalid max message size configuration for Kafka plugin: %w", err)
+	}
+
 	k.config = KafkaConfig{
 		Brokers: brokers,
 	}
+	k.codec = codec
+	k.maxMessageSize = maxMessageSize
 
-	// Create Kafka writer with configuration
+	// Create Kafka writer with configuration. Chunks of an oversized message share
+	// a key, so the hash balancer keeps them on one partition, in order, for a
+	// single consumer to reassemble; unkeyed messages are spread round-robin.
 	k.writer = &kafka.Writer{
 		Addr:                   kafka.TCP(brokers...),
 		AllowAutoTopicCreation: true,
-		Balancer:               &kafka.LeastBytes{},
+		Balancer:               &kafka.Hash{},
 		WriteBackoffMin:        100 * time.Millisecond,
 		WriteBackoffMax:        1 * time.Second,
 		MaxAttempts:            5,
@@ -83,9 +102,15 @@ func (k *KafkaPlugin) SetMetricsCollector(collector *MetricsCollector) {
 
 // Publish sends a message to the specified topic
 func (k *KafkaPlugin) Publish(topic string, message interface{}) error {
+	return k.PublishContext(context.Background(), topic, message)
+}
+
+// PublishContext sends a message to the specified topic, propagating the request ID in ctx
+func (k *KafkaPlugin) PublishContext(ctx context.Context, topic string, message interface{}) error {
 	startTime := time.Now()
 
-	data, err := json.Marshal(message)
+	headers := headersFromContext(ctx)
+	data, err := EncodeWithHeaders(k.codec, message, headers)
 	if err != nil {
 		if k.metricsCollector != nil {
 			k.metricsCollector.RecordRequest("kafka", time.Since(startTime), err)
@@ -93,13 +118,46 @@ func (k *KafkaPlugin) Publish(topic string, message interface{}) error {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	msg := kafka.Message{
-		Topic: topic,
-		Value: data,
-		Time:  time.Now(),
+	chunks, err := splitForPublish("kafka", topic, data, k.maxMessageSize, k.metricsCollector)
+	if err != nil {
+		if k.metricsCollector != nil {
+			k.metricsCollector.RecordRequest("kafka", time.Since(startTime), err)
+		}
+		return fmt.Errorf("failed to split message: %w", err)
+	}
+
+	// kafka-go has no idempotent producer, so a retried publish can be written twice.
+	// Keying a message by its ID keeps both copies on one partition, in order, for the
+	// consumer's DedupHandler to drop the second.
+	var key []byte
+	if len(chunks) > 1 {
+		chunkHeaders, _, _ := DecodeEnvelope(chunks[0])
+		key = []byte(chunkHeaders[ChunkIDHeader])
+	} else if id, ok := headers[MessageIDHeader]; ok {
+		key = []byte(id)
 	}
 
-	err = k.writer.WriteMessages(context.Background(), msg)
+	msgs := make([]kafka.Message, 0, len(chunks))
+	for _, chunk := range chunks {
+		msg := kafka.Message{
+			Topic: topic,
+			Key:   key,
+			Value: chunk,
+			Headers: []kafka.Header{
+				{Key: ContentTypeHeader, Value: []byte(k.codec.ContentType())},
+			},
+			Time: time.Now(),
+		}
+		if id, ok := headers[RequestIDHeader]; ok {
+			msg.Headers = append(msg.Headers, kafka.Header{Key: RequestIDHeader, Value: []byte(id)})
+		}
+		if id, ok := headers[MessageIDHeader]; ok {
+			msg.Headers = append(msg.Headers, kafka.Header{Key: MessageIDHeader, Value: []byte(id)})
+		}
+		msgs = append(msgs, msg)
+	}
+
+	err = k.writer.WriteMessages(ctx, msgs...)
 
 	if k.metricsCollector != nil {
 		k.metricsCollector.RecordRequest("kafka", time.Since(startTime), err)
@@ -112,8 +170,31 @@ func (k *KafkaPlugin) Publish(topic string, message interface{}) error {
 	return nil
 }
 
-// Consume reads messages from the specified topic and handles them
+// kafkaConsumerWorkers is the number of messages Consume handles concurrently
+const kafkaConsumerWorkers = 10
+
+// Consume reads messages from the specified topic and handles them. Messages sharing a
+// Kafka message key are handled in order; messages without a key are spread over the workers.
 func (k *KafkaPlugin) Consume(ctx context.Context, topic string, handler MessageHandler) error {
+	return k.consume(ctx, topic, kafkaConsumerWorkers, func(m kafka.Message) string {
+		if len(m.Key) > 0 {
+			return string(m.Key)
+		}
+		return fmt.Sprintf("%d:%d", m.Partition, m.Offset)
+	}, handler)
+}
+
+// ConsumePartitioned reads messages from topic and handles them on workers partitioned by
+// key, so messages sharing a key are handled one at a time in the order they were read
+func (k *KafkaPlugin) ConsumePartitioned(ctx context.Context, topic string, workers int, key PartitionKeyFunc, handler MessageHandler) error {
+	return k.consume(ctx, topic, workers, func(m kafka.Message) string { return key(m.Value) }, handler)
+}
+
+// consume reads messages on one goroutine and hands each to the worker of its key. A
+// message is committed once it and every earlier message of its Kafka partition have been
+// handled; a message whose handler fails is never committed, so it and the messages after
+// it are redelivered after a restart. Messages still queued at shutdown are redelivered too.
+func (k *KafkaPlugin) consume(ctx context.Context, topic string, workers int, key func(kafka.Message) string, handler MessageHandler) error {
 	k.reader = kafka.NewReader(kafka.ReaderConfig{
 		Brokers:         k.config.Brokers,
 		Topic:           topic,
@@ -127,75 +208,101 @@ func (k *KafkaPlugin) Consume(ctx context.Context, topic string, handler Message
 
 	defer k.reader.Close()
 
-	// Create a worker pool for concurrent message processing
-	const numWorkers = 10
-	tasks := make(chan kafka.Message, numWorkers*2)
-
-	// Start worker goroutines
-	var workersDone int
-	workersDoneChan := make(chan bool, numWorkers)
-
-	for i := 0; i < numWorkers; i++ {
-		go func(workerID int) {
-			defer func() {
-				workersDone++
-				workersDoneChan <- true
-			}()
-
-			for msg := range tasks {
-				startTime := time.Now()
-				err := handler(msg.Value)
-
-				if k.metricsCollector != nil {
-					k.metricsCollector.RecordRequest("kafka", time.Since(startTime), err)
-				}
-
-				if err != nil {
-					log.Printf("Error handling message in worker %d: %v", workerID, err)
-					continue
-				}
-
-				// Commit the message after successful processing
-				if err := k.reader.CommitMessages(ctx, msg); err != nil {
-					log.Printf("Error committing message from worker %d: %v", workerID, err)
-				}
-			}
-		}(i)
+	handler = NewReassembler().Handler(handler)
+	timed := func(message []byte) error {
+		startTime := time.Now()
+		err := handler(message)
+		if k.metricsCollector != nil {
+			k.metricsCollector.RecordRequest("kafka", time.Since(startTime), err)
+		}
+		return err
 	}
 
+	partitioned := NewPartitionedHandler(workers, nil, timed)
+	tracker := newCommitTracker(func(m kafka.Message) error {
+		// Messages handled during shutdown are still committed
+		return k.reader.CommitMessages(context.Background(), m)
+	})
+
 	for {
-		select {
-		case <-ctx.Done():
-			close(tasks)
-			for i := 0; i < numWorkers; i++ {
-				<-workersDoneChan
+		m, err := k.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				partitioned.Close()
+				return ctx.Err()
 			}
-			return ctx.Err()
-		default:
-			m, err := k.reader.FetchMessage(ctx)
+			log.Printf("Error fetching message: %v", err)
+			continue
+		}
+
+		tracker.fetched(m)
+		message := m
+		partitioned.submit(key(message), message.Value, func(err error) {
 			if err != nil {
-				if ctx.Err() != nil {
-					close(tasks)
-					for i := 0; i < numWorkers; i++ {
-						<-workersDoneChan
-					}
-					return ctx.Err()
-				}
-				log.Printf("Error fetching message: %v", err)
-				continue
+				log.Printf("Error handling message at offset %d of partition %d, leaving it uncommitted: %v", message.Offset, message.Partition, err)
+				return
 			}
+			tracker.handled(message)
+		})
+	}
+}
 
-			select {
-			case tasks <- m:
-			case <-ctx.Done():
-				close(tasks)
-				for i := 0; i < numWorkers; i++ {
-					<-workersDoneChan
-				}
-				return ctx.Err()
-			}
-		}
+// commitTracker commits the offsets of messages handled out of order. Committing an offset
+// commits every earlier offset of its partition, so a partition is only committed up to
+// the message before its oldest unhandled one.
+type commitTracker struct {
+	mu      sync.Mutex
+	commit  func(kafka.Message) error
+	pending map[int][]kafka.Message // fetched messages not yet committed, in offset order
+	done    map[int]map[int64]bool  // offsets of pending messages that have been handled
+}
+
+func newCommitTracker(commit func(kafka.Message) error) *commitTracker {
+	return &commitTracker{
+		commit:  commit,
+		pending: make(map[int][]kafka.Message),
+		done:    make(map[int]map[int64]bool),
+	}
+}
+
+// fetched records m as read; messages of a partition must be recorded in offset order
+func (t *commitTracker) fetched(m kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending[m.Partition] = append(t.pending[m.Partition], m)
+	if t.done[m.Partition] == nil {
+		t.done[m.Partition] = make(map[int64]bool)
+	}
+}
+
+// handled records m as handled and commits its partition up to the last message before
+// the oldest unhandled one. Commits are serialized so offsets never move backwards.
+func (t *commitTracker) handled(m kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	done := t.done[m.Partition]
+	done[m.Offset] = true
+
+	pending := t.pending[m.Partition]
+	n := 0
+	for n < len(pending) && done[pending[n].Offset] {
+		n++
+	}
+	if n == 0 {
+		return
+	}
+
+	if err := t.commit(pending[n-1]); err != nil {
+		// Leave the messages pending so the next handled message commits them
+		log.Printf("Error committing offset %d of partition %d: %v", pending[n-1].Offset, m.Partition, err)
+		return
+	}
+	for _, committed := range pending[:n] {
+		delete(done, committed.Offset)
 	}
+	t.pending[m.Partition] = pending[n:]
 }
 
 // Close closes the Kafka connections
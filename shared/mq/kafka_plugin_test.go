@@ -0,0 +1,65 @@
+package mq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestCommitTracker_CommitsContiguousOffsets(t *testing.T) {
+	var commits []int64
+	tracker := newCommitTracker(func(m kafka.Message) error {
+		commits = append(commits, m.Offset)
+		return nil
+	})
+
+	messages := make([]kafka.Message, 4)
+	for i := range messages {
+		messages[i] = kafka.Message{Partition: 0, Offset: int64(10 + i)}
+		tracker.fetched(messages[i])
+	}
+	other := kafka.Message{Partition: 1, Offset: 3}
+	tracker.fetched(other)
+
+	// Offset 12 is handled before 10 and 11, committing it would commit them too
+	tracker.handled(messages[2])
+	if len(commits) != 0 {
+		t.Fatalf("Expected no commit while earlier messages are unhandled, got %v", commits)
+	}
+
+	tracker.handled(messages[0])
+	tracker.handled(messages[1])
+	if len(commits) != 2 || commits[0] != 10 || commits[1] != 12 {
+		t.Errorf("Expected commits of offsets 10 and 12, got %v", commits)
+	}
+
+	// Partitions are committed independently
+	tracker.handled(other)
+	if len(commits) != 3 || commits[2] != 3 {
+		t.Errorf("Expected a commit of offset 3 of partition 1, got %v", commits)
+	}
+}
+
+func TestCommitTracker_RetriesFailedCommit(t *testing.T) {
+	fail := true
+	var commits []int64
+	tracker := newCommitTracker(func(m kafka.Message) error {
+		if fail {
+			return errors.New("broker unavailable")
+		}
+		commits = append(commits, m.Offset)
+		return nil
+	})
+
+	first, second := kafka.Message{Offset: 1}, kafka.Message{Offset: 2}
+	tracker.fetched(first)
+	tracker.fetched(second)
+
+	tracker.handled(first)
+	fail = false
+	tracker.handled(second)
+	if len(commits) != 1 || commits[0] != 2 {
+		t.Errorf("Expected the next commit to cover offset 2, got %v", commits)
+	}
+}
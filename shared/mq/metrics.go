@@ -17,6 +17,7 @@ type PluginMetrics struct {
 	LastError         string
 	LastErrorTime     time.Time
 	LastRequestTime   time.Time
+	OversizedMessages int64 // messages published in chunks for exceeding the max message size
 }
 
 // MetricsCollector collects metrics for MQ plugins
@@ -84,6 +85,22 @@ func (mc *MetricsCollector) RecordRequest(pluginName string, duration time.Durat
 	}
 }
 
+// RecordOversizedMessage records a message that exceeded the max message size of the given plugin
+func (mc *MetricsCollector) RecordOversizedMessage(pluginName string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	pluginMetric, exists := mc.pluginMetrics[pluginName]
+	if !exists {
+		pluginMetric = &PluginMetrics{
+			Name: pluginName,
+		}
+		mc.pluginMetrics[pluginName] = pluginMetric
+	}
+
+	pluginMetric.OversizedMessages++
+}
+
 // GetGlobalMetrics returns global metrics
 func (mc *MetricsCollector) GetGlobalMetrics() (int64, int64, int64, time.Duration) {
 	mc.mu.Lock()
@@ -7,9 +7,12 @@ import (
 // MessageQueue interface defines the methods for message queue operations
 type MessageQueue interface {
 	Publish(topic string, message interface{}) error
+	// PublishContext publishes like Publish, propagating the request ID in ctx
+	PublishContext(ctx context.Context, topic string, message interface{}) error
 	Consume(ctx context.Context, topic string, handler MessageHandler) error
 	Close() error
 }
 
-// MessageHandler defines the function signature for handling messages
+// MessageHandler defines the function signature for handling messages.
+// The message carries a content-type header and should be decoded with Decode.
 type MessageHandler func(message []byte) error
@@ -0,0 +1,127 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+// partitionQueueSize is the number of messages each worker buffers before Handle blocks
+const partitionQueueSize = 64
+
+// ErrPartitionedHandlerClosed is reported for messages still queued when the handler is
+// closed. They were not handled, so they must not be committed.
+var ErrPartitionedHandlerClosed = errors.New("partitioned handler closed")
+
+// PartitionKeyFunc returns the key of a message; messages sharing a key are handled in order
+type PartitionKeyFunc func(message []byte) string
+
+// PartitionedConsumer is implemented by queues that can consume a topic on a fixed set of
+// workers partitioned by key, handling messages sharing a key one at a time in the order
+// they were read and committing a message only once it and every earlier message of its
+// partition have been handled
+type PartitionedConsumer interface {
+	ConsumePartitioned(ctx context.Context, topic string, workers int, key PartitionKeyFunc, handler MessageHandler) error
+}
+
+// ConsumePartitioned consumes topic from queue on workers partitioned by key. Partitioning
+// has to happen where messages are read, so queues that do not implement
+// PartitionedConsumer are rejected rather than consumed out of order.
+func ConsumePartitioned(ctx context.Context, queue MessageQueue, topic string, workers int, key PartitionKeyFunc, handler MessageHandler) error {
+	consumer, ok := queue.(PartitionedConsumer)
+	if !ok {
+		return fmt.Errorf("message queue %T does not support partitioned consumption", queue)
+	}
+	return consumer.ConsumePartitioned(ctx, topic, workers, key, handler)
+}
+
+// partitionedMessage is a queued message and the callback receiving its outcome
+type partitionedMessage struct {
+	message []byte
+	done    func(error)
+}
+
+// PartitionedHandler handles messages on a fixed set of workers. Each message is assigned
+// to a worker by a hash of its partition key, so messages sharing a key are handled one at
+// a time in the order they were submitted, while messages with different keys are
+// handled in parallel.
+type PartitionedHandler struct {
+	key     PartitionKeyFunc
+	handler MessageHandler
+	queues  []chan partitionedMessage
+	closed  int32
+	wg      sync.WaitGroup
+}
+
+// NewPartitionedHandler starts workers goroutines running handler; values below 1 are
+// treated as 1. Close must be called to stop them.
+func NewPartitionedHandler(workers int, key PartitionKeyFunc, handler MessageHandler) *PartitionedHandler {
+	if workers < 1 {
+		workers = 1
+	}
+
+	p := &PartitionedHandler{
+		key:     key,
+		handler: handler,
+		queues:  make([]chan partitionedMessage, workers),
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan partitionedMessage, partitionQueueSize)
+		p.wg.Add(1)
+		go p.work(i)
+	}
+	return p
+}
+
+// Handle handles message on the worker of its partition key and returns the handler's
+// error, blocking until it has been handled. It has the MessageHandler signature, but
+// only messages submitted by one goroutine keep their order; consumers reading
+// messages on one goroutine use Submit instead to handle them in parallel.
+func (p *PartitionedHandler) Handle(message []byte) error {
+	result := make(chan error, 1)
+	p.Submit(message, func(err error) { result <- err })
+	return <-result
+}
+
+// Submit queues message on the worker of its partition key, blocking while that worker's
+// queue is full, and calls done with the handler's error once it has been handled
+func (p *PartitionedHandler) Submit(message []byte, done func(error)) {
+	p.submit(p.key(message), message, done)
+}
+
+// submit queues message on the worker of key
+func (p *PartitionedHandler) submit(key string, message []byte, done func(error)) {
+	p.queues[p.partition(key)] <- partitionedMessage{message: message, done: done}
+}
+
+// Close stops accepting messages and waits for the messages being handled. Messages
+// still queued are not handled; their callbacks get ErrPartitionedHandlerClosed.
+// Submit must not be called after Close.
+func (p *PartitionedHandler) Close() {
+	atomic.StoreInt32(&p.closed, 1)
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
+
+// partition returns the worker of key
+func (p *PartitionedHandler) partition(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(p.queues)))
+}
+
+func (p *PartitionedHandler) work(worker int) {
+	defer p.wg.Done()
+	for queued := range p.queues[worker] {
+		if atomic.LoadInt32(&p.closed) == 1 {
+			queued.done(ErrPartitionedHandlerClosed)
+			continue
+		}
+		queued.done(p.handler(queued.message))
+	}
+}
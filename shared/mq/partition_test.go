@@ -0,0 +1,162 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// partitionMessage encodes a message as "<contract>:<sequence>"
+func partitionMessage(contract string, seq int) []byte {
+	return []byte(fmt.Sprintf("%s:%d", contract, seq))
+}
+
+func contractKey(message []byte) string {
+	return strings.SplitN(string(message), ":", 2)[0]
+}
+
+// consumeSlowly sends perContract messages for each contract through a partitioned
+// handler with the given workers, each taking delay to handle, and returns the
+// sequences handled per contract and the elapsed time
+func consumeSlowly(workers int, contracts []string, perContract int, delay time.Duration) (map[string][]int, time.Duration) {
+	var mu sync.Mutex
+	handled := make(map[string][]int)
+
+	p := NewPartitionedHandler(workers, contractKey, func(message []byte) error {
+		time.Sleep(delay)
+		parts := strings.SplitN(string(message), ":", 2)
+		contract := parts[0]
+		seq, _ := strconv.Atoi(parts[1])
+
+		mu.Lock()
+		handled[contract] = append(handled[contract], seq)
+		mu.Unlock()
+		return nil
+	})
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for seq := 0; seq < perContract; seq++ {
+		for _, contract := range contracts {
+			wg.Add(1)
+			p.Submit(partitionMessage(contract, seq), func(error) { wg.Done() })
+		}
+	}
+	wg.Wait()
+	p.Close()
+	return handled, time.Since(start)
+}
+
+func TestPartitionedHandler_OrderedPerKey(t *testing.T) {
+	contracts := []string{"0xaaa", "0xbbb", "0xccc", "0xddd", "0xeee", "0xfff"}
+	handled, _ := consumeSlowly(4, contracts, 50, 0)
+
+	for _, contract := range contracts {
+		sequences := handled[contract]
+		if len(sequences) != 50 {
+			t.Fatalf("Expected 50 messages for %s, got %d", contract, len(sequences))
+		}
+		for i, seq := range sequences {
+			if seq != i {
+				t.Fatalf("Expected messages of %s in order, got %v", contract, sequences)
+			}
+		}
+	}
+}
+
+func TestPartitionedHandler_ScalesWithWorkers(t *testing.T) {
+	// Enough contracts that they spread over every worker
+	var contracts []string
+	for i := 0; i < 32; i++ {
+		contracts = append(contracts, fmt.Sprintf("0x%040x", i))
+	}
+
+	_, sequential := consumeSlowly(1, contracts, 2, 5*time.Millisecond)
+	_, parallel := consumeSlowly(8, contracts, 2, 5*time.Millisecond)
+
+	// 64 messages of 5ms take at least 320ms on one worker
+	if parallel*2 > sequential {
+		t.Errorf("Expected 8 workers to be at least twice as fast as 1, took %v and %v", parallel, sequential)
+	}
+}
+
+func TestPartitionedHandler_SingleWorkerMinimum(t *testing.T) {
+	p := NewPartitionedHandler(0, contractKey, func([]byte) error { return nil })
+	defer p.Close()
+
+	if len(p.queues) != 1 {
+		t.Errorf("Expected 1 worker, got %d", len(p.queues))
+	}
+}
+
+func TestPartitionedHandler_HandleReturnsHandlerError(t *testing.T) {
+	failure := errors.New("store unavailable")
+	p := NewPartitionedHandler(2, contractKey, func(message []byte) error {
+		if contractKey(message) == "0xbad" {
+			return failure
+		}
+		return nil
+	})
+	defer p.Close()
+
+	// Handle returns once the message is handled, with its outcome
+	if err := p.Handle(partitionMessage("0xbad", 0)); !errors.Is(err, failure) {
+		t.Errorf("Expected the handler error, got %v", err)
+	}
+	if err := p.Handle(partitionMessage("0xaaa", 0)); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestPartitionedHandler_CloseSkipsQueuedMessages(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var handled int32
+	p := NewPartitionedHandler(1, contractKey, func([]byte) error {
+		if atomic.AddInt32(&handled, 1) == 1 {
+			close(started)
+			<-release
+		}
+		return nil
+	})
+
+	results := make(chan error, 2)
+	p.Submit(partitionMessage("0xaaa", 0), func(err error) { results <- err })
+	p.Submit(partitionMessage("0xaaa", 1), func(err error) { results <- err })
+	<-started
+
+	// The message being handled finishes, the queued one is left for redelivery
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	p.Close()
+
+	if err := <-results; err != nil {
+		t.Errorf("Expected the first message to be handled, got %v", err)
+	}
+	if err := <-results; !errors.Is(err, ErrPartitionedHandlerClosed) {
+		t.Errorf("Expected ErrPartitionedHandlerClosed for the queued message, got %v", err)
+	}
+	if got := atomic.LoadInt32(&handled); got != 1 {
+		t.Errorf("Expected 1 handled message, got %d", got)
+	}
+}
+
+// unpartitionedQueue is a MessageQueue that cannot partition consumption
+type unpartitionedQueue struct {
+	MessageQueue
+}
+
+func TestConsumePartitioned_RequiresPartitionedConsumer(t *testing.T) {
+	err := ConsumePartitioned(context.Background(), unpartitionedQueue{}, "events", 4, contractKey, func([]byte) error { return nil })
+	if err == nil {
+		t.Error("Expected an error for a queue that cannot partition consumption")
+	}
+}
@@ -120,6 +120,16 @@ func (mp *MultiProtocolMQ) Publish(topic string, message interface{}) error {
 	return plugin.Publish(topic, message)
 }
 
+// PublishContext sends a message using the default plugin, propagating the request ID in ctx
+func (mp *MultiProtocolMQ) PublishContext(ctx context.Context, topic string, message interface{}) error {
+	plugin, exists := mp.plugins[mp.defaultPlugin]
+	if !exists {
+		return fmt.Errorf("default plugin %s not found", mp.defaultPlugin)
+	}
+
+	return plugin.PublishContext(ctx, topic, message)
+}
+
 // PublishToPlugin sends a message using a specific plugin
 func (mp *MultiProtocolMQ) PublishToPlugin(pluginName, topic string, message interface{}) error {
 	plugin, exists := mp.plugins[pluginName]
@@ -140,6 +150,17 @@ func (mp *MultiProtocolMQ) Consume(ctx context.Context, topic string, handler Me
 	return plugin.Consume(ctx, topic, handler)
 }
 
+// ConsumePartitioned reads messages from the default plugin on workers partitioned by key.
+// It fails if the default plugin does not support partitioned consumption.
+func (mp *MultiProtocolMQ) ConsumePartitioned(ctx context.Context, topic string, workers int, key PartitionKeyFunc, handler MessageHandler) error {
+	plugin, exists := mp.plugins[mp.defaultPlugin]
+	if !exists {
+		return fmt.Errorf("default plugin %s not found", mp.defaultPlugin)
+	}
+
+	return ConsumePartitioned(ctx, plugin, topic, workers, key, handler)
+}
+
 // ConsumeFromPlugin reads messages from a specific plugin
 func (mp *MultiProtocolMQ) ConsumeFromPlugin(ctx context.Context, pluginName, topic string, handler MessageHandler) error {
 	plugin, exists := mp.plugins[pluginName]
@@ -2,7 +2,6 @@ package mq
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"time"
@@ -14,6 +13,8 @@ import (
 type RedisPlugin struct {
 	client           *redis.Client
 	metricsCollector *MetricsCollector
+	codec            Codec
+	maxMessageSize   int
 	config           RedisConfig
 }
 
@@ -26,7 +27,10 @@ type RedisConfig struct {
 
 // NewRedisPlugin creates a new Redis plugin instance
 func NewRedisPlugin() *RedisPlugin {
-	return &RedisPlugin{}
+	return &RedisPlugin{
+		codec:          JSONCodec{},
+		maxMessageSize: DefaultMaxMessageSize,
+	}
 }
 
 // Initialize initializes the Redis plugin with configuration
@@ -57,11 +61,23 @@ func (r *RedisPlugin) Initialize(config map[string]interface{}) error {
 		}
 	}
 
+	codec, err := codecFromConfig(config)
+	if err != nil {
+		return fmt.Errorf("invalid codec configuration for Redis plugin: %w", err)
+	}
+
+	maxMessageSize, err := maxMessageSizeFromConfig(config)
+	if err != nil {
+		return fmt.Errorf("invalid max message size configuration for Redis plugin: %w", err)
+	}
+
 	r.config = RedisConfig{
 		Addr:     addr,
 		Password: password,
 		DB:       db,
 	}
+	r.codec = codec
+	r.maxMessageSize = maxMessageSize
 
 	// Create Redis client
 	r.client = redis.NewClient(&redis.Options{
@@ -93,9 +109,15 @@ func (r *RedisPlugin) SetMetricsCollector(collector *MetricsCollector) {
 
 // Publish sends a message to the specified topic using Redis
 func (r *RedisPlugin) Publish(topic string, message interface{}) error {
+	return r.PublishContext(context.Background(), topic, message)
+}
+
+// PublishContext sends a message to the specified topic using Redis, propagating the request ID in ctx
+func (r *RedisPlugin) PublishContext(ctx context.Context, topic string, message interface{}) error {
 	startTime := time.Now()
 
-	data, err := json.Marshal(message)
+	headers := headersFromContext(ctx)
+	data, err := EncodeWithHeaders(r.codec, message, headers)
 	if err != nil {
 		if r.metricsCollector != nil {
 			r.metricsCollector.RecordRequest("redis", time.Since(startTime), err)
@@ -103,11 +125,30 @@ func (r *RedisPlugin) Publish(topic string, message interface{}) error {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	chunks, err := splitForPublish("redis", topic, data, r.maxMessageSize, r.metricsCollector)
+	if err != nil {
+		if r.metricsCollector != nil {
+			r.metricsCollector.RecordRequest("redis", time.Since(startTime), err)
+		}
+		return fmt.Errorf("failed to split message: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	// Use Redis list as a simple queue
-	err = r.client.LPush(ctx, topic, data).Err()
+	// Use Redis list as a simple queue; chunks are pushed in one call so they stay in order
+	values := make([]interface{}, len(chunks))
+	for i, chunk := range chunks {
+		values[i] = chunk
+	}
+	if id, ok := headers[MessageIDHeader]; ok {
+		// A message with an ID is pushed once: a retry after a publish whose reply was
+		// lost finds the ID already recorded and pushes nothing
+		args := append([]interface{}{DefaultDedupTTL.Milliseconds()}, values...)
+		err = publishOnceScript.Run(ctx, r.client, []string{publishedKey(topic, id), topic}, args...).Err()
+	} else {
+		err = r.client.LPush(ctx, topic, values...).Err()
+	}
 
 	if r.metricsCollector != nil {
 		r.metricsCollector.RecordRequest("redis", time.Since(startTime), err)
@@ -120,8 +161,24 @@ func (r *RedisPlugin) Publish(topic string, message interface{}) error {
 	return nil
 }
 
+// publishOnceScript pushes the chunks in ARGV[2:] onto the list KEYS[2] unless the message
+// ID key KEYS[1] exists, recording the ID for ARGV[1] milliseconds, in one atomic step
+var publishOnceScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], 1, "NX", "PX", ARGV[1]) then
+	return redis.call("LPUSH", KEYS[2], unpack(ARGV, 2))
+end
+return 0
+`)
+
+// publishedKey returns the key recording that the message id was published to topic
+func publishedKey(topic, id string) string {
+	return "mq:published:" + topic + ":" + id
+}
+
 // Consume reads messages from the specified topic and handles them using Redis
 func (r *RedisPlugin) Consume(ctx context.Context, topic string, handler MessageHandler) error {
+	handler = NewReassembler().Handler(handler)
+
 	// Create a worker pool for concurrent message processing
 	const numWorkers = 5
 	tasks := make(chan []byte, numWorkers*2)
@@ -0,0 +1,183 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// DeadLetterSuffix is appended to a topic to name its dead-letter topic
+const DeadLetterSuffix = ".dlq"
+
+// DefaultRetryPolicy retries a failing message twice, after 100ms and 200ms
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// RetryPolicy configures how a consumer retries a message whose handler fails
+type RetryPolicy struct {
+	// MaxAttempts is the number of handler calls per message, including the first.
+	// Values below 1 are treated as 1.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry; it doubles with every retry
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between retries
+	MaxDelay time.Duration
+}
+
+// delay returns the wait before retry number retry, counted from 1
+func (p RetryPolicy) delay(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// DeadLetterMessage is published to a dead-letter topic for a message whose handler
+// failed every attempt. Payload is the original message, headers included.
+type DeadLetterMessage struct {
+	Topic    string    `json:"topic"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	Payload  []byte    `json:"payload"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// Field numbers of a DeadLetterMessage encoded with the protobuf wire format
+const (
+	deadLetterFieldTopic    protowire.Number = 1
+	deadLetterFieldError    protowire.Number = 2
+	deadLetterFieldAttempts protowire.Number = 3
+	deadLetterFieldPayload  protowire.Number = 4
+	deadLetterFieldFailedAt protowire.Number = 5 // Unix nanoseconds
+)
+
+// MarshalProto encodes the dead letter with the protobuf wire format, so dead-letter
+// topics can be published with ProtobufCodec
+func (d DeadLetterMessage) MarshalProto() ([]byte, error) {
+	var b []byte
+	b = protowire.AppendTag(b, deadLetterFieldTopic, protowire.BytesType)
+	b = protowire.AppendString(b, d.Topic)
+	b = protowire.AppendTag(b, deadLetterFieldError, protowire.BytesType)
+	b = protowire.AppendString(b, d.Error)
+	b = protowire.AppendTag(b, deadLetterFieldAttempts, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(d.Attempts))
+	b = protowire.AppendTag(b, deadLetterFieldPayload, protowire.BytesType)
+	b = protowire.AppendBytes(b, d.Payload)
+	b = protowire.AppendTag(b, deadLetterFieldFailedAt, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(d.FailedAt.UnixNano()))
+	return b, nil
+}
+
+// UnmarshalProto decodes a dead letter produced by MarshalProto
+func (d *DeadLetterMessage) UnmarshalProto(data []byte) error {
+	*d = DeadLetterMessage{}
+
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("invalid dead letter tag: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		switch {
+		case typ == protowire.VarintType && (num == deadLetterFieldAttempts || num == deadLetterFieldFailedAt):
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return fmt.Errorf("invalid dead letter field %d: %v", num, protowire.ParseError(n))
+			}
+			if num == deadLetterFieldAttempts {
+				d.Attempts = int(v)
+			} else {
+				d.FailedAt = time.Unix(0, int64(v))
+			}
+			data = data[n:]
+		case typ == protowire.BytesType && num >= deadLetterFieldTopic && num <= deadLetterFieldPayload:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return fmt.Errorf("invalid dead letter field %d: %v", num, protowire.ParseError(n))
+			}
+			switch num {
+			case deadLetterFieldTopic:
+				d.Topic = string(v)
+			case deadLetterFieldError:
+				d.Error = string(v)
+			case deadLetterFieldPayload:
+				d.Payload = append([]byte(nil), v...)
+			}
+			data = data[n:]
+		default:
+			// Skip unknown fields for forward compatibility
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return fmt.Errorf("invalid dead letter field %d: %v", num, protowire.ParseError(n))
+			}
+			data = data[n:]
+		}
+	}
+
+	return nil
+}
+
+// DeadLetterTopic returns the dead-letter topic of topic
+func DeadLetterTopic(topic string) string {
+	return topic + DeadLetterSuffix
+}
+
+// RetryHandler wraps handler so that a failing message is retried with exponential backoff
+// and, once policy.MaxAttempts is exhausted, published to the dead-letter topic of topic on
+// queue. The wrapped handler only returns an error, leaving the message uncommitted, if
+// publishing to the dead-letter topic fails or ctx is cancelled while waiting to retry.
+func RetryHandler(ctx context.Context, queue MessageQueue, topic string, policy RetryPolicy, handler MessageHandler) MessageHandler {
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return func(message []byte) error {
+		var err error
+		for attempt := 1; attempt <= maxAttempts; attempt++ {
+			if attempt > 1 {
+				select {
+				case <-time.After(policy.delay(attempt - 1)):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			if err = handler(message); err == nil {
+				return nil
+			}
+			log.Printf("Handler failed for message on %s (attempt %d/%d): %v", topic, attempt, maxAttempts, err)
+		}
+
+		deadLetter := DeadLetterMessage{
+			Topic:    topic,
+			Error:    err.Error(),
+			Attempts: maxAttempts,
+			Payload:  message,
+			FailedAt: time.Now(),
+		}
+		if publishErr := queue.PublishContext(ctx, DeadLetterTopic(topic), deadLetter); publishErr != nil {
+			return fmt.Errorf("failed to dead-letter message after %d attempts: %w (handler error: %v)", maxAttempts, publishErr, err)
+		}
+
+		log.Printf("Message on %s moved to %s after %d attempts: %v", topic, DeadLetterTopic(topic), maxAttempts, err)
+		return nil
+	}
+}
+
+// ConsumeWithRetry consumes topic from queue, retrying failed messages according to policy
+// and dead-lettering those that still fail
+func ConsumeWithRetry(ctx context.Context, queue MessageQueue, topic string, policy RetryPolicy, handler MessageHandler) error {
+	return queue.Consume(ctx, topic, RetryHandler(ctx, queue, topic, policy, handler))
+}
@@ -0,0 +1,138 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryQueue delivers the queued messages to the consumer and records published messages
+type memoryQueue struct {
+	mu        sync.Mutex
+	messages  [][]byte
+	published map[string][]interface{}
+	consumed  []error
+}
+
+func (q *memoryQueue) Publish(topic string, message interface{}) error {
+	return q.PublishContext(context.Background(), topic, message)
+}
+
+func (q *memoryQueue) PublishContext(ctx context.Context, topic string, message interface{}) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.published == nil {
+		q.published = make(map[string][]interface{})
+	}
+	q.published[topic] = append(q.published[topic], message)
+	return nil
+}
+
+func (q *memoryQueue) Consume(ctx context.Context, topic string, handler MessageHandler) error {
+	for _, message := range q.messages {
+		q.consumed = append(q.consumed, handler(message))
+	}
+	return nil
+}
+
+func (q *memoryQueue) Close() error {
+	return nil
+}
+
+var fastRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestConsumeWithRetry_SucceedsAfterFailures(t *testing.T) {
+	message, _ := Encode(JSONCodec{}, map[string]string{"tx_hash": "0xflaky"})
+	queue := &memoryQueue{messages: [][]byte{message}}
+
+	attempts := 0
+	handler := func(data []byte) error {
+		attempts++
+		if attempts <= 2 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	}
+
+	if err := ConsumeWithRetry(context.Background(), queue, "blockchain.raw.events", fastRetryPolicy, handler); err != nil {
+		t.Fatalf("Failed to consume: %v", err)
+	}
+
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+	if queue.consumed[0] != nil {
+		t.Errorf("Expected the message to be handled, got %v", queue.consumed[0])
+	}
+	if len(queue.published) != 0 {
+		t.Errorf("Expected nothing dead-lettered, got %v", queue.published)
+	}
+}
+
+func TestConsumeWithRetry_DeadLettersAfterMaxAttempts(t *testing.T) {
+	message, _ := Encode(JSONCodec{}, map[string]string{"tx_hash": "0xpoison"})
+	queue := &memoryQueue{messages: [][]byte{message}}
+
+	attempts := 0
+	handler := func(data []byte) error {
+		attempts++
+		return errors.New("cannot process")
+	}
+
+	if err := ConsumeWithRetry(context.Background(), queue, "blockchain.raw.events", fastRetryPolicy, handler); err != nil {
+		t.Fatalf("Failed to consume: %v", err)
+	}
+
+	if attempts != fastRetryPolicy.MaxAttempts {
+		t.Errorf("Expected %d attempts, got %d", fastRetryPolicy.MaxAttempts, attempts)
+	}
+	// The dead-lettered message counts as handled, so it is committed
+	if queue.consumed[0] != nil {
+		t.Errorf("Expected the dead-lettered message to be handled, got %v", queue.consumed[0])
+	}
+
+	deadLetters := queue.published["blockchain.raw.events.dlq"]
+	if len(deadLetters) != 1 {
+		t.Fatalf("Expected 1 dead-lettered message, got %d", len(deadLetters))
+	}
+	deadLetter := deadLetters[0].(DeadLetterMessage)
+	if deadLetter.Topic != "blockchain.raw.events" || deadLetter.Attempts != 3 || deadLetter.Error != "cannot process" {
+		t.Errorf("Unexpected dead letter %+v", deadLetter)
+	}
+
+	var event map[string]string
+	if err := Decode(deadLetter.Payload, &event); err != nil || event["tx_hash"] != "0xpoison" {
+		t.Errorf("Expected the original message in the dead letter, got %v (%v)", event, err)
+	}
+}
+
+func TestRetryHandler_StopsWaitingWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	queue := &memoryQueue{}
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}
+
+	handler := RetryHandler(ctx, queue, "blockchain.raw.events", policy, func(data []byte) error {
+		cancel()
+		return errors.New("temporary failure")
+	})
+
+	if err := handler([]byte("message")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(queue.published) != 0 {
+		t.Errorf("Expected nothing dead-lettered, got %v", queue.published)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, want := range expected {
+		if got := policy.delay(i + 1); got != want {
+			t.Errorf("Expected retry %d delay %v, got %v", i+1, want, got)
+		}
+	}
+}
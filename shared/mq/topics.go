@@ -0,0 +1,45 @@
+package mq
+
+// Default topic names used by the pipeline services
+const (
+	DefaultRawEventsTopic       = "blockchain.raw.events"
+	DefaultProcessedEventsTopic = "blockchain.processed.events"
+	DefaultReorgEventsTopic     = "blockchain.reorg.events"
+)
+
+// TopicConfig holds the topic names used by the pipeline services. A non-empty
+// Prefix namespaces every topic, so isolated pipelines (e.g. staging and prod)
+// can share one broker. The zero value uses the default names without a prefix.
+type TopicConfig struct {
+	Prefix              string
+	RawEventsName       string
+	ProcessedEventsName string
+	ReorgEventsName     string
+}
+
+// topic returns the full topic name for name, including the prefix.
+// An empty name falls back to defaultName.
+func (c TopicConfig) topic(name, defaultName string) string {
+	if name == "" {
+		name = defaultName
+	}
+	if c.Prefix == "" {
+		return name
+	}
+	return c.Prefix + "." + name
+}
+
+// RawEvents returns the topic carrying raw blockchain events
+func (c TopicConfig) RawEvents() string {
+	return c.topic(c.RawEventsName, DefaultRawEventsTopic)
+}
+
+// ProcessedEvents returns the topic carrying processed events
+func (c TopicConfig) ProcessedEvents() string {
+	return c.topic(c.ProcessedEventsName, DefaultProcessedEventsTopic)
+}
+
+// ReorgEvents returns the topic carrying chain reorganization notifications
+func (c TopicConfig) ReorgEvents() string {
+	return c.topic(c.ReorgEventsName, DefaultReorgEventsTopic)
+}
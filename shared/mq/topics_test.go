@@ -0,0 +1,140 @@
+package mq
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryPlugin is an in-process MQPlugin that delivers published messages to consumers of the same topic
+type memoryPlugin struct {
+	mu        sync.Mutex
+	published []string
+	consumed  []string
+	topics    map[string]chan []byte
+}
+
+func newMemoryPlugin() *memoryPlugin {
+	return &memoryPlugin{topics: make(map[string]chan []byte)}
+}
+
+func (m *memoryPlugin) channel(topic string) chan []byte {
+	if _, exists := m.topics[topic]; !exists {
+		m.topics[topic] = make(chan []byte, 10)
+	}
+	return m.topics[topic]
+}
+
+func (m *memoryPlugin) Initialize(config map[string]interface{}) error { return nil }
+
+func (m *memoryPlugin) GetName() string { return "memory" }
+
+func (m *memoryPlugin) SetMetricsCollector(collector *MetricsCollector) {}
+
+func (m *memoryPlugin) Publish(topic string, message interface{}) error {
+	return m.PublishContext(context.Background(), topic, message)
+}
+
+func (m *memoryPlugin) PublishContext(ctx context.Context, topic string, message interface{}) error {
+	data, err := EncodeWithHeaders(JSONCodec{}, message, headersFromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.published = append(m.published, topic)
+	ch := m.channel(topic)
+	m.mu.Unlock()
+
+	ch <- data
+	return nil
+}
+
+func (m *memoryPlugin) Consume(ctx context.Context, topic string, handler MessageHandler) error {
+	m.mu.Lock()
+	m.consumed = append(m.consumed, topic)
+	ch := m.channel(topic)
+	m.mu.Unlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case data := <-ch:
+			if err := handler(data); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (m *memoryPlugin) Close() error { return nil }
+
+func TestTopicConfig_Defaults(t *testing.T) {
+	var topics TopicConfig
+
+	if topics.RawEvents() != "blockchain.raw.events" {
+		t.Errorf("Expected blockchain.raw.events, got %s", topics.RawEvents())
+	}
+	if topics.ProcessedEvents() != "blockchain.processed.events" {
+		t.Errorf("Expected blockchain.processed.events, got %s", topics.ProcessedEvents())
+	}
+	if topics.ReorgEvents() != "blockchain.reorg.events" {
+		t.Errorf("Expected blockchain.reorg.events, got %s", topics.ReorgEvents())
+	}
+}
+
+func TestTopicConfig_PrefixUsedByPublishAndConsume(t *testing.T) {
+	plugin := newMemoryPlugin()
+	if err := GlobalPluginRegistry.RegisterPlugin("memory", plugin); err != nil {
+		existing, _ := GlobalPluginRegistry.GetPlugin("memory")
+		plugin = existing.(*memoryPlugin)
+	}
+
+	mq := NewMultiProtocolMQ("memory")
+	if err := mq.Initialize(map[string]map[string]interface{}{"memory": {}}); err != nil {
+		t.Fatalf("Failed to initialize MQ: %v", err)
+	}
+
+	topics := TopicConfig{Prefix: "staging", ProcessedEventsName: "events.processed"}
+	if topics.RawEvents() != "staging.blockchain.raw.events" {
+		t.Errorf("Expected staging.blockchain.raw.events, got %s", topics.RawEvents())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan string, 1)
+	go mq.Consume(ctx, topics.ProcessedEvents(), func(message []byte) error {
+		var payload map[string]string
+		if err := Decode(message, &payload); err != nil {
+			return err
+		}
+		received <- payload["tx_hash"]
+		return nil
+	})
+
+	if err := mq.Publish(topics.ProcessedEvents(), map[string]string{"tx_hash": "0x1"}); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	select {
+	case txHash := <-received:
+		if txHash != "0x1" {
+			t.Errorf("Expected 0x1, got %s", txHash)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected message on the prefixed topic")
+	}
+
+	plugin.mu.Lock()
+	defer plugin.mu.Unlock()
+	expected := "staging.events.processed"
+	if len(plugin.published) == 0 || plugin.published[len(plugin.published)-1] != expected {
+		t.Errorf("Expected publish to %s, got %v", expected, plugin.published)
+	}
+	if len(plugin.consumed) == 0 || plugin.consumed[len(plugin.consumed)-1] != expected {
+		t.Errorf("Expected consume from %s, got %v", expected, plugin.consumed)
+	}
+}
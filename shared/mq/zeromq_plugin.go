@@ -2,7 +2,6 @@ package mq
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"time"
@@ -15,6 +14,8 @@ type ZeroMQPlugin struct {
 	publisher        zmq4.Socket
 	subscriber       zmq4.Socket
 	metricsCollector *MetricsCollector
+	codec            Codec
+	maxMessageSize   int
 	config           ZeroMQConfig
 }
 
@@ -26,7 +27,10 @@ type ZeroMQConfig struct {
 
 // NewZeroMQPlugin creates a new ZeroMQ plugin instance
 func NewZeroMQPlugin() *ZeroMQPlugin {
-	return &ZeroMQPlugin{}
+	return &ZeroMQPlugin{
+		codec:          JSONCodec{},
+		maxMessageSize: DefaultMaxMessageSize,
+	}
 }
 
 // Initialize initializes the ZeroMQ plugin with configuration
@@ -51,10 +55,22 @@ func (z *ZeroMQPlugin) Initialize(config map[string]interface{}) error {
 		return fmt.Errorf("subscribe_addr must be a string")
 	}
 
+	codec, err := codecFromConfig(config)
+	if err != nil {
+		return fmt.Errorf("invalid codec configuration for ZeroMQ plugin: %w", err)
+	}
+
+	maxMessageSize, err := maxMessageSizeFromConfig(config)
+	if err != nil {
+		return fmt.Errorf("invalid max message size configuration for ZeroMQ plugin: %w", err)
+	}
+
 	z.config = ZeroMQConfig{
 		PublishAddr:   publishAddr,
 		SubscribeAddr: subscribeAddr,
 	}
+	z.codec = codec
+	z.maxMessageSize = maxMessageSize
 
 	// Create publisher socket
 	z.publisher = zmq4.NewPub(context.Background())
@@ -77,9 +93,14 @@ func (z *ZeroMQPlugin) SetMetricsCollector(collector *MetricsCollector) {
 
 // Publish sends a message to the specified topic using ZeroMQ
 func (z *ZeroMQPlugin) Publish(topic string, message interface{}) error {
+	return z.PublishContext(context.Background(), topic, message)
+}
+
+// PublishContext sends a message to the specified topic using ZeroMQ, propagating the request ID in ctx
+func (z *ZeroMQPlugin) PublishContext(ctx context.Context, topic string, message interface{}) error {
 	startTime := time.Now()
 
-	data, err := json.Marshal(message)
+	data, err := EncodeWithHeaders(z.codec, message, headersFromContext(ctx))
 	if err != nil {
 		if z.metricsCollector != nil {
 			z.metricsCollector.RecordRequest("zeromq", time.Since(startTime), err)
@@ -95,10 +116,22 @@ func (z *ZeroMQPlugin) Publish(topic string, message interface{}) error {
 		return fmt.Errorf("failed to connect publisher: %w", err)
 	}
 
-	// Format message as topic:message
-	msg := zmq4.Msg{Frames: [][]byte{[]byte(topic + ":"), data}}
+	chunks, err := splitForPublish("zeromq", topic, data, z.maxMessageSize, z.metricsCollector)
+	if err != nil {
+		if z.metricsCollector != nil {
+			z.metricsCollector.RecordRequest("zeromq", time.Since(startTime), err)
+		}
+		return fmt.Errorf("failed to split message: %w", err)
+	}
 
-	err = z.publisher.Send(msg)
+	for _, chunk := range chunks {
+		// Format message as topic:message
+		msg := zmq4.Msg{Frames: [][]byte{[]byte(topic + ":"), chunk}}
+
+		if err = z.publisher.Send(msg); err != nil {
+			break
+		}
+	}
 
 	if z.metricsCollector != nil {
 		z.metricsCollector.RecordRequest("zeromq", time.Since(startTime), err)
@@ -113,6 +146,8 @@ func (z *ZeroMQPlugin) Publish(topic string, message interface{}) error {
 
 // Consume reads messages from the specified topic and handles them using ZeroMQ
 func (z *ZeroMQPlugin) Consume(ctx context.Context, topic string, handler MessageHandler) error {
+	handler = NewReassembler().Handler(handler)
+
 	// Connect subscriber
 	if err := z.subscriber.Dial(z.config.SubscribeAddr); err != nil {
 		return fmt.Errorf("failed to connect subscriber: %w", err)
@@ -0,0 +1,124 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Header is the HTTP header carrying the request ID
+const Header = "X-Request-ID"
+
+// MetadataKey is the gRPC metadata key carrying the request ID
+const MetadataKey = "x-request-id"
+
+// maxLength bounds client-supplied request IDs so they cannot bloat log lines
+const maxLength = 128
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or an empty string
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Generate returns a new random request ID
+func Generate() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// resolve returns the client-supplied ID when usable, or a newly generated one
+func resolve(id string) string {
+	if !valid(id) {
+		return Generate()
+	}
+	return id
+}
+
+// valid reports whether a client-supplied ID is safe to echo back and write to logs
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware reads X-Request-ID from the request (or generates one), stores it
+// in the request context and echoes it back in the response header
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := resolve(r.Header.Get(Header))
+
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// fromIncomingMetadata returns the request ID from gRPC metadata, or an empty string
+func fromIncomingMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(MetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// UnaryServerInterceptor reads the request ID from gRPC metadata (or generates one),
+// stores it in the context and returns it in the response header metadata
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	id := resolve(fromIncomingMetadata(ctx))
+	_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataKey, id))
+
+	return handler(NewContext(ctx, id), req)
+}
+
+// StreamServerInterceptor is the stream equivalent of UnaryServerInterceptor
+func StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id := resolve(fromIncomingMetadata(ss.Context()))
+	_ = ss.SetHeader(metadata.Pairs(MetadataKey, id))
+
+	return handler(srv, &wrappedStream{
+		ServerStream: ss,
+		ctx:          NewContext(ss.Context(), id),
+	})
+}
+
+// wrappedStream wraps the gRPC stream to use the context carrying the request ID
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context carrying the request ID
+func (w *wrappedStream) Context() context.Context {
+	return w.ctx
+}
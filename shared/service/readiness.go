@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// SyncReadiness tracks whether the indexer has resumed from its last processed block
+// and caught up with the chain head closely enough to serve traffic
+type SyncReadiness struct {
+	mu      sync.RWMutex
+	resumed bool
+	lag     int64
+	maxLag  int64
+}
+
+// NewSyncReadiness creates a readiness tracker that reports ready once the lag is at most maxLag blocks
+func NewSyncReadiness(maxLag int64) *SyncReadiness {
+	return &SyncReadiness{
+		lag:    -1, // unknown until the first update
+		maxLag: maxLag,
+	}
+}
+
+// MarkResumed records that the indexer has resumed from the last processed block
+func (r *SyncReadiness) MarkResumed() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.resumed = true
+}
+
+// UpdateLag records the distance between the chain head and the last processed block
+func (r *SyncReadiness) UpdateLag(head, processed *big.Int) {
+	if head == nil || processed == nil {
+		return
+	}
+
+	lag := new(big.Int).Sub(head, processed)
+	if lag.Sign() < 0 {
+		lag.SetInt64(0)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lag.IsInt64() {
+		r.lag = lag.Int64()
+	}
+}
+
+// Ready reports whether the indexer is ready, with a reason when it is not
+func (r *SyncReadiness) Ready() (bool, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.resumed {
+		return false, "indexer has not resumed from the last processed block"
+	}
+	if r.lag < 0 {
+		return false, "sync lag is not known yet"
+	}
+	if r.lag > r.maxLag {
+		return false, fmt.Sprintf("backfill in progress: %d blocks behind (max %d)", r.lag, r.maxLag)
+	}
+
+	return true, ""
+}
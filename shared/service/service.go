@@ -15,7 +15,14 @@ type IndexerService interface {
 	ProcessHistoricalEvents(ctx context.Context, contractAddresses []common.Address, fromBlock, toBlock *big.Int) error
 	GetEvents(filter *types.EventFilter) ([]types.IndexedEvent, error)
 	GetEventByID(id uint) (*types.IndexedEvent, error)
+	GetEventsByTxHash(txHash string) ([]types.IndexedEvent, error)
+	GetAddressActivity(address string, limitNum, offset int) ([]types.AddressActivity, error)
+	GetBalances(address, contract string) ([]types.TokenBalance, error)
+	GetLatestEvents(limitNum int) ([]types.IndexedEvent, error)
+	GetEventsAfterID(afterID uint, limitNum int) ([]types.IndexedEvent, error)
 	GetEventsByBlockRange(fromBlock, toBlock *big.Int) ([]types.IndexedEvent, error)
 	GetLastProcessedBlock() (*big.Int, error)
 	ResumeEvents(ctx context.Context, fromBlock, toBlock *big.Int) error
+	ReplayEvents(ctx context.Context, contract string, fromBlock, toBlock *big.Int, transform string) (*types.ReplayResult, error)
+	VerifyEvents(ctx context.Context, contract string, fromBlock, toBlock *big.Int, repair bool) (*types.ConsistencyReport, error)
 }
\ No newline at end of file
@@ -0,0 +1,35 @@
+package types
+
+import "strings"
+
+// Directions of an event relative to an address
+const (
+	DirectionIn   = "in"   // the address received
+	DirectionOut  = "out"  // the address sent
+	DirectionSelf = "self" // the address sent to itself
+)
+
+// AddressActivity is an event touching an address as sender or receiver
+type AddressActivity struct {
+	IndexedEvent
+	Direction string `json:"direction"`
+}
+
+// EventDirection returns the direction of event relative to address, or "" if the
+// address is neither its sender nor its receiver. Addresses are compared
+// case-insensitively, so checksummed and lowercase forms match.
+func EventDirection(event *IndexedEvent, address string) string {
+	from := strings.EqualFold(event.From, address)
+	to := strings.EqualFold(event.To, address)
+
+	switch {
+	case from && to:
+		return DirectionSelf
+	case from:
+		return DirectionOut
+	case to:
+		return DirectionIn
+	default:
+		return ""
+	}
+}
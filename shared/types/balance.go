@@ -0,0 +1,108 @@
+package types
+
+import (
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Names of the stored transfer events balances are derived from
+const (
+	NFTTransferEventName   = "NFTTransfer"
+	TokenTransferEventName = "TokenTransfer"
+)
+
+// TokenBalance is what an address holds of one token contract
+type TokenBalance struct {
+	Contract string       `json:"contract"`
+	Type     ContractType `json:"type"`
+	Balance  string       `json:"balance"`             // amount of an ERC-20 token, number of tokens owned of an ERC-721 contract
+	TokenIDs []string     `json:"token_ids,omitempty"` // ERC-721 tokens owned, in ascending order
+}
+
+// ComputeBalances returns the non-zero balances of address from the transfers it sent or
+// received, which must be in chain order. ERC-20 transfers add or subtract their value;
+// an ERC-721 token is owned once received until it is sent. Other events are ignored and
+// addresses are compared case-insensitively. Balances are ordered by contract.
+func ComputeBalances(address string, transfers []IndexedEvent) []TokenBalance {
+	amounts := make(map[string]*big.Int)
+	owned := make(map[string]map[string]bool)
+
+	for i := range transfers {
+		event := &transfers[i]
+		from := strings.EqualFold(event.From, address)
+		to := strings.EqualFold(event.To, address)
+		if !from && !to {
+			continue
+		}
+
+		switch event.EventName {
+		case TokenTransferEventName:
+			value, ok := new(big.Int).SetString(event.Value, 10)
+			if !ok {
+				continue
+			}
+			amount := amounts[event.Contract]
+			if amount == nil {
+				amount = new(big.Int)
+				amounts[event.Contract] = amount
+			}
+			if from {
+				amount.Sub(amount, value)
+			}
+			if to {
+				amount.Add(amount, value)
+			}
+		case NFTTransferEventName:
+			tokens := owned[event.Contract]
+			if tokens == nil {
+				tokens = make(map[string]bool)
+				owned[event.Contract] = tokens
+			}
+			if from {
+				delete(tokens, event.TokenID)
+			}
+			if to {
+				tokens[event.TokenID] = true
+			}
+		}
+	}
+
+	var balances []TokenBalance
+	for contract, amount := range amounts {
+		if amount.Sign() != 0 {
+			balances = append(balances, TokenBalance{Contract: contract, Type: ContractTypeERC20, Balance: amount.String()})
+		}
+	}
+	for contract, tokens := range owned {
+		if len(tokens) == 0 {
+			continue
+		}
+		balance := TokenBalance{Contract: contract, Type: ContractTypeERC721, Balance: strconv.Itoa(len(tokens))}
+		for tokenID := range tokens {
+			balance.TokenIDs = append(balance.TokenIDs, tokenID)
+		}
+		sort.Slice(balance.TokenIDs, func(i, j int) bool { return lessTokenID(balance.TokenIDs[i], balance.TokenIDs[j]) })
+		balances = append(balances, balance)
+	}
+
+	sort.Slice(balances, func(i, j int) bool {
+		if balances[i].Contract != balances[j].Contract {
+			return balances[i].Contract < balances[j].Contract
+		}
+		return balances[i].Type < balances[j].Type
+	})
+	return balances
+}
+
+// lessTokenID orders token IDs numerically, falling back to string order for IDs that
+// are not decimal numbers
+func lessTokenID(a, b string) bool {
+	x, okX := new(big.Int).SetString(a, 10)
+	y, okY := new(big.Int).SetString(b, 10)
+	if okX && okY {
+		return x.Cmp(y) < 0
+	}
+	return a < b
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComputeBalances_TransferSequence(t *testing.T) {
+	wallet := "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	other := "0x8617E340B3D01FA5F11F306F4090FD50E238070D"
+	token := "0x1111111111111111111111111111111111111111"
+	nft := "0x2222222222222222222222222222222222222222"
+	spent := "0x3333333333333333333333333333333333333333"
+
+	transfers := []IndexedEvent{
+		{Contract: token, EventName: TokenTransferEventName, From: other, To: wallet, Value: "1000"},
+		{Contract: token, EventName: TokenTransferEventName, From: wallet, To: other, Value: "300"},
+		{Contract: token, EventName: TokenTransferEventName, From: wallet, To: wallet, Value: "500"},
+		{Contract: nft, EventName: NFTTransferEventName, From: other, To: wallet, TokenID: "10"},
+		{Contract: nft, EventName: NFTTransferEventName, From: other, To: wallet, TokenID: "9"},
+		{Contract: nft, EventName: NFTTransferEventName, From: other, To: wallet, TokenID: "7"},
+		{Contract: nft, EventName: NFTTransferEventName, From: wallet, To: other, TokenID: "7"},
+		{Contract: nft, EventName: NFTTransferEventName, From: wallet, To: wallet, TokenID: "9"},
+		// Fully spent balances are not returned
+		{Contract: spent, EventName: TokenTransferEventName, From: other, To: wallet, Value: "50"},
+		{Contract: spent, EventName: TokenTransferEventName, From: wallet, To: other, Value: "50"},
+		// Transfers between others and other events are ignored
+		{Contract: token, EventName: TokenTransferEventName, From: other, To: other, Value: "99"},
+		{Contract: token, EventName: "Approval", From: other, To: wallet, Value: "7"},
+	}
+
+	// Addresses are matched case-insensitively
+	balances := ComputeBalances("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", transfers)
+
+	expected := []TokenBalance{
+		{Contract: token, Type: ContractTypeERC20, Balance: "700"},
+		{Contract: nft, Type: ContractTypeERC721, Balance: "2", TokenIDs: []string{"9", "10"}},
+	}
+	if !reflect.DeepEqual(balances, expected) {
+		t.Errorf("Expected balances %+v, got %+v", expected, balances)
+	}
+}
+
+func TestComputeBalances_NoTransfers(t *testing.T) {
+	if balances := ComputeBalances("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", nil); len(balances) != 0 {
+		t.Errorf("Expected no balances, got %+v", balances)
+	}
+}
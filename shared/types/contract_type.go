@@ -0,0 +1,47 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContractType is the token standard a contract implements, stored in its canonical
+// spelling so contracts of one standard are stored and filtered alike
+type ContractType string
+
+// Canonical contract types
+const (
+	ContractTypeERC20   ContractType = "ERC20"
+	ContractTypeERC721  ContractType = "ERC721"
+	ContractTypeERC1155 ContractType = "ERC1155"
+)
+
+// ContractTypes lists the canonical contract types
+var ContractTypes = []ContractType{ContractTypeERC20, ContractTypeERC721, ContractTypeERC1155}
+
+// ParseContractType normalizes a spelling such as "erc-721", "ERC_721" or "eip721" to its
+// canonical contract type, rejecting types that are not known
+func ParseContractType(s string) (ContractType, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(s))
+	normalized = strings.NewReplacer("-", "", "_", "", " ", "").Replace(normalized)
+	if strings.HasPrefix(normalized, "EIP") {
+		normalized = "ERC" + strings.TrimPrefix(normalized, "EIP")
+	}
+
+	for _, t := range ContractTypes {
+		if normalized == string(t) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown contract type: %q", s)
+}
+
+// Valid reports whether t is a canonical contract type
+func (t ContractType) Valid() bool {
+	for _, known := range ContractTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
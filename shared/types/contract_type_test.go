@@ -0,0 +1,45 @@
+package types
+
+import "testing"
+
+func TestParseContractType_NormalizesVariants(t *testing.T) {
+	tests := map[string]ContractType{
+		"ERC20":    ContractTypeERC20,
+		"erc20":    ContractTypeERC20,
+		"ERC-20":   ContractTypeERC20,
+		" erc_20 ": ContractTypeERC20,
+		"EIP-20":   ContractTypeERC20,
+		"ERC721":   ContractTypeERC721,
+		"erc-721":  ContractTypeERC721,
+		"Erc 721":  ContractTypeERC721,
+		"erc1155":  ContractTypeERC1155,
+		"ERC-1155": ContractTypeERC1155,
+		"eip_1155": ContractTypeERC1155,
+	}
+
+	for input, expected := range tests {
+		contractType, err := ParseContractType(input)
+		if err != nil {
+			t.Errorf("Expected %q to parse, got %v", input, err)
+			continue
+		}
+		if contractType != expected {
+			t.Errorf("Expected %q to normalize to %s, got %s", input, expected, contractType)
+		}
+		if !contractType.Valid() {
+			t.Errorf("Expected %s to be valid", contractType)
+		}
+	}
+}
+
+func TestParseContractType_RejectsUnknown(t *testing.T) {
+	for _, input := range []string{"", "ERC", "ERC777", "erc-7211", "NFT"} {
+		if contractType, err := ParseContractType(input); err == nil {
+			t.Errorf("Expected %q to be rejected, got %s", input, contractType)
+		}
+	}
+
+	if ContractType("erc-721").Valid() {
+		t.Error("Expected a non-canonical spelling to be invalid")
+	}
+}
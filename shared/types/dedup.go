@@ -0,0 +1,100 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"chainpulse/shared/json"
+)
+
+// Dedup key strategies selectable by name
+const (
+	DedupByLog     = "log"
+	DedupByTx      = "tx"
+	DedupByContent = "content"
+)
+
+// DedupKeyer decides which events are duplicates: events with the same key are stored
+// and processed once
+type DedupKeyer interface {
+	Key(event *IndexedEvent) string
+	// UniqueColumns are the events columns of the unique index matching the key, used as
+	// the insert conflict target. nil when no index matches, then only the key deduplicates.
+	UniqueColumns() []string
+}
+
+// LogDedupKeyer treats events of the same log as duplicates, keyed by
+// (chain ID, transaction hash, log index). It is the default keyer.
+type LogDedupKeyer struct {
+	ChainID string
+}
+
+func (k LogDedupKeyer) Key(event *IndexedEvent) string {
+	return fmt.Sprintf("%s:%s:%d", k.ChainID, strings.ToLower(event.TxHash), event.LogIndex)
+}
+
+func (k LogDedupKeyer) UniqueColumns() []string {
+	return []string{"tx_hash", "log_index"}
+}
+
+// TxDedupKeyer keeps one event per transaction, keyed by (chain ID, transaction hash)
+type TxDedupKeyer struct {
+	ChainID string
+}
+
+func (k TxDedupKeyer) Key(event *IndexedEvent) string {
+	return fmt.Sprintf("%s:%s", k.ChainID, strings.ToLower(event.TxHash))
+}
+
+func (k TxDedupKeyer) UniqueColumns() []string {
+	return nil
+}
+
+// ContentDedupKeyer treats events with the same content as duplicates wherever they
+// were emitted, e.g. the same external event delivered by several sources. The key is a
+// hash of the contract, event, parties, token, value and decoded parameters; the block,
+// transaction and log position are left out.
+type ContentDedupKeyer struct {
+	ChainID string
+}
+
+func (k ContentDedupKeyer) Key(event *IndexedEvent) string {
+	// Map keys are marshalled in sorted order, so equal parameters hash the same
+	data, _ := json.Marshal(event.Data)
+	content := strings.Join([]string{
+		strings.ToLower(event.Contract),
+		event.EventName,
+		strings.ToLower(event.Topic0),
+		strings.ToLower(event.From),
+		strings.ToLower(event.To),
+		event.TokenID,
+		event.Value,
+		string(data),
+	}, "\x00")
+	sum := sha256.Sum256([]byte(content))
+	return fmt.Sprintf("%s:%s", k.ChainID, hex.EncodeToString(sum[:]))
+}
+
+func (k ContentDedupKeyer) UniqueColumns() []string {
+	return nil
+}
+
+// DefaultDedupKeyer is used where no keyer is configured
+var DefaultDedupKeyer DedupKeyer = LogDedupKeyer{ChainID: "1"}
+
+// NewDedupKeyer returns the keyer of the named strategy for chainID, an empty strategy
+// selects DedupByLog
+func NewDedupKeyer(strategy, chainID string) (DedupKeyer, error) {
+	switch strategy {
+	case "", DedupByLog:
+		return LogDedupKeyer{ChainID: chainID}, nil
+	case DedupByTx:
+		return TxDedupKeyer{ChainID: chainID}, nil
+	case DedupByContent:
+		return ContentDedupKeyer{ChainID: chainID}, nil
+	default:
+		return nil, fmt.Errorf("unknown dedup key strategy: %s", strategy)
+	}
+}
@@ -0,0 +1,106 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+)
+
+// countUnique returns how many of events are kept when deduplicating by keyer
+func countUnique(keyer DedupKeyer, events []*IndexedEvent) int {
+	seen := make(map[string]bool)
+	for _, event := range events {
+		seen[keyer.Key(event)] = true
+	}
+	return len(seen)
+}
+
+func TestDedupKeyers_SwappingChangesDuplicates(t *testing.T) {
+	transfer := func(txHash string, logIndex uint, blockNumber int64) *IndexedEvent {
+		return &IndexedEvent{
+			BlockNumber: big.NewInt(blockNumber),
+			TxHash:      txHash,
+			LogIndex:    logIndex,
+			EventName:   "Transfer",
+			Contract:    "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48",
+			From:        "0x1111111111111111111111111111111111111111",
+			To:          "0x2222222222222222222222222222222222222222",
+			Value:       "1000",
+		}
+	}
+
+	events := []*IndexedEvent{
+		transfer("0xabc", 0, 100),
+		// Another log of the same transaction with the same content
+		transfer("0xabc", 1, 100),
+		// The same log redelivered, with a differently cased hash
+		transfer("0xABC", 0, 100),
+		// The same content from another transaction and block
+		transfer("0xdef", 0, 101),
+	}
+
+	tests := []struct {
+		keyer DedupKeyer
+		want  int
+	}{
+		{LogDedupKeyer{ChainID: "1"}, 3},
+		{TxDedupKeyer{ChainID: "1"}, 2},
+		{ContentDedupKeyer{ChainID: "1"}, 1},
+	}
+
+	for _, tt := range tests {
+		if got := countUnique(tt.keyer, events); got != tt.want {
+			t.Errorf("Expected %d unique events with %T, got %d", tt.want, tt.keyer, got)
+		}
+	}
+}
+
+func TestDedupKeyers_ChainID(t *testing.T) {
+	event := &IndexedEvent{TxHash: "0xabc", LogIndex: 0}
+
+	mainnet := LogDedupKeyer{ChainID: "1"}
+	polygon := LogDedupKeyer{ChainID: "137"}
+	if mainnet.Key(event) == polygon.Key(event) {
+		t.Error("Expected the same log on different chains to have different keys")
+	}
+}
+
+func TestContentDedupKeyer_Data(t *testing.T) {
+	keyer := ContentDedupKeyer{ChainID: "1"}
+
+	first := &IndexedEvent{EventName: "Approval", Data: map[string]interface{}{"owner": "0x1", "spender": "0x2"}}
+	sameData := &IndexedEvent{EventName: "Approval", Data: map[string]interface{}{"spender": "0x2", "owner": "0x1"}}
+	otherData := &IndexedEvent{EventName: "Approval", Data: map[string]interface{}{"owner": "0x1", "spender": "0x3"}}
+
+	if keyer.Key(first) != keyer.Key(sameData) {
+		t.Error("Expected events with equal parameters to have the same key")
+	}
+	if keyer.Key(first) == keyer.Key(otherData) {
+		t.Error("Expected events with different parameters to have different keys")
+	}
+}
+
+func TestNewDedupKeyer(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     DedupKeyer
+	}{
+		{"", LogDedupKeyer{ChainID: "5"}},
+		{DedupByLog, LogDedupKeyer{ChainID: "5"}},
+		{DedupByTx, TxDedupKeyer{ChainID: "5"}},
+		{DedupByContent, ContentDedupKeyer{ChainID: "5"}},
+	}
+
+	for _, tt := range tests {
+		keyer, err := NewDedupKeyer(tt.strategy, "5")
+		if err != nil {
+			t.Fatalf("Expected no error for strategy %q, got %v", tt.strategy, err)
+		}
+		if keyer != tt.want {
+			t.Errorf("Expected %#v for strategy %q, got %#v", tt.want, tt.strategy, keyer)
+		}
+	}
+
+	if _, err := NewDedupKeyer("block", "5"); err == nil {
+		t.Error("Expected error for an unknown strategy")
+	}
+}
@@ -0,0 +1,27 @@
+package types
+
+// IndexerDiagnostics is a snapshot of what the indexer is doing, to look into an indexer
+// that misbehaves
+type IndexerDiagnostics struct {
+	Subscriptions      []ContractSubscription `json:"subscriptions"`        // contracts with open event subscriptions
+	Chains             []ChainProgress        `json:"chains"`               // progress of each indexed chain
+	BatchBufferSize    int64                  `json:"batch_buffer_size"`    // events waiting for the next batch flush
+	BatchBufferedBytes int64                  `json:"batch_buffered_bytes"` // approximate size of the events waiting for the next batch flush
+	EventsInFlight     int64                  `json:"events_in_flight"`     // subscribed events being processed
+	SyncProcessing     bool                   `json:"sync_processing"`      // whether subscribed events are processed one at a time
+	Goroutines         int                    `json:"goroutines"`           // goroutines of the process
+}
+
+// ContractSubscription lists the events subscribed to for a contract
+type ContractSubscription struct {
+	Contract string   `json:"contract"`
+	Events   []string `json:"events"` // e.g. nft_transfer, token_transfer, approval, wrapped_native
+}
+
+// ChainProgress is how far the indexer got on a chain
+type ChainProgress struct {
+	ChainID            string `json:"chain_id"`
+	LastProcessedBlock uint64 `json:"last_processed_block"`
+	HeadBlock          uint64 `json:"head_block"`      // highest block seen, 0 until one is seen
+	Error              string `json:"error,omitempty"` // why the last processed block could not be read
+}
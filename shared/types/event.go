@@ -1,6 +1,8 @@
 package types
 
 import (
+	"context"
+	"errors"
 	"math/big"
 	"time"
 
@@ -9,37 +11,105 @@ import (
 
 type IndexedEvent struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
-	BlockNumber *big.Int  `json:"block_number" gorm:"index"`
-	TxHash      string    `json:"tx_hash" gorm:"index"`
+	SchemaVersion int     `json:"schema_version" gorm:"-"` // version of the encoding the event was decoded from, see CurrentEventSchemaVersion
+	BlockNumber *big.Int  `json:"block_number" gorm:"index;serializer:json;type:numeric"` // stored as a number by its JSON encoding, which GORM cannot map itself
+	TxHash      string    `json:"tx_hash" gorm:"index;uniqueIndex:,composite:tx_hash_log_index_unique"`
+	LogIndex    uint      `json:"log_index" gorm:"uniqueIndex:,composite:tx_hash_log_index_unique"` // position of the log within its block; the unique index is named per table so ArchivedEvent gets its own
 	EventName   string    `json:"event_name" gorm:"index"`
+	Topic0      string    `json:"topic0,omitempty" gorm:"index"` // event signature hash, the first topic of the log
 	Contract    string    `json:"contract" gorm:"index"`
-	From        string    `json:"from,omitempty"`
-	To          string    `json:"to,omitempty"`
+	From        string    `json:"from,omitempty" gorm:"index"`
+	To          string    `json:"to,omitempty" gorm:"index"`
 	TokenID     string    `json:"token_id,omitempty"`
 	Value       string    `json:"value,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty" gorm:"serializer:json;type:jsonb"` // decoded event parameters keyed by ABI input name
 	Timestamp   time.Time `json:"timestamp"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	ReceivedAt  time.Time `json:"-" gorm:"-"` // when the indexer received the event from its subscription, zero for backfilled events
+}
+
+// ArchivedEvent is an IndexedEvent moved out of the hot table by the retention policy
+type ArchivedEvent struct {
+	IndexedEvent
+	ArchivedAt time.Time `json:"archived_at" gorm:"index"`
 }
 
 type NFTTransferEvent struct {
 	BlockNumber *big.Int    `json:"block_number"`
 	TxHash      common.Hash `json:"tx_hash"`
+	LogIndex    uint        `json:"log_index"`
 	From        common.Address `json:"from"`
 	To          common.Address `json:"to"`
 	TokenID     *big.Int    `json:"token_id"`
 	Contract    common.Address `json:"contract"`
+	Data        map[string]interface{} `json:"data,omitempty"` // decoded with the contract's ABI, see EventProcessor.DecodeLog
 	Timestamp   time.Time   `json:"timestamp"`
+	ReceivedAt  time.Time   `json:"-"` // when the indexer received the event from its subscription
 }
 
 type TokenTransferEvent struct {
 	BlockNumber *big.Int    `json:"block_number"`
 	TxHash      common.Hash `json:"tx_hash"`
+	LogIndex    uint        `json:"log_index"`
 	From        common.Address `json:"from"`
 	To          common.Address `json:"to"`
 	Value       *big.Int    `json:"value"`
 	Contract    common.Address `json:"contract"`
+	Data        map[string]interface{} `json:"data,omitempty"` // decoded with the contract's ABI, see EventProcessor.DecodeLog
 	Timestamp   time.Time   `json:"timestamp"`
+	ReceivedAt  time.Time   `json:"-"` // when the indexer received the event from its subscription
+}
+
+// ApprovalEvent is an Approval(address,address,uint256) log: an ERC-20 allowance, or an
+// ERC-721 approval of a single token when the third parameter is indexed
+type ApprovalEvent struct {
+	BlockNumber *big.Int       `json:"block_number"`
+	TxHash      common.Hash    `json:"tx_hash"`
+	LogIndex    uint           `json:"log_index"`
+	Owner       common.Address `json:"owner"`
+	Spender     common.Address `json:"spender"`
+	Amount      *big.Int       `json:"amount,omitempty"`   // allowance of an ERC-20 approval, nil for ERC-721
+	TokenID     *big.Int       `json:"token_id,omitempty"` // token of an ERC-721 approval, nil for ERC-20
+	Contract    common.Address `json:"contract"`
+	Timestamp   time.Time      `json:"timestamp"`
+}
+
+// ApprovalForAllEvent is an ERC-721/ERC-1155 ApprovalForAll(address,address,bool) log
+// granting or revoking an operator's control over all of an owner's tokens
+type ApprovalForAllEvent struct {
+	BlockNumber *big.Int       `json:"block_number"`
+	TxHash      common.Hash    `json:"tx_hash"`
+	LogIndex    uint           `json:"log_index"`
+	Owner       common.Address `json:"owner"`
+	Operator    common.Address `json:"operator"`
+	Approved    bool           `json:"approved"`
+	Contract    common.Address `json:"contract"`
+	Timestamp   time.Time      `json:"timestamp"`
+}
+
+// DepositEvent is a wrapped-native token Deposit(address,uint256) log, emitted when an
+// account wraps native currency, e.g. ETH into WETH
+type DepositEvent struct {
+	BlockNumber *big.Int       `json:"block_number"`
+	TxHash      common.Hash    `json:"tx_hash"`
+	LogIndex    uint           `json:"log_index"`
+	Account     common.Address `json:"account"` // receiver of the wrapped tokens
+	Amount      *big.Int       `json:"amount"`
+	Contract    common.Address `json:"contract"`
+	Timestamp   time.Time      `json:"timestamp"`
+}
+
+// WithdrawalEvent is a wrapped-native token Withdrawal(address,uint256) log, emitted when
+// an account unwraps tokens back into native currency
+type WithdrawalEvent struct {
+	BlockNumber *big.Int       `json:"block_number"`
+	TxHash      common.Hash    `json:"tx_hash"`
+	LogIndex    uint           `json:"log_index"`
+	Account     common.Address `json:"account"` // owner of the unwrapped tokens
+	Amount      *big.Int       `json:"amount"`
+	Contract    common.Address `json:"contract"`
+	Timestamp   time.Time      `json:"timestamp"`
 }
 
 type EventFilter struct {
@@ -47,7 +117,14 @@ type EventFilter struct {
 	Contract    string `json:"contract"`
 	FromBlock   *big.Int `json:"from_block"`
 	ToBlock     *big.Int `json:"to_block"`
+	TokenID     string `json:"token_id"`
+	MinValue    *big.Int `json:"min_value"` // inclusive, events without a numeric value never match a value range
+	MaxValue    *big.Int `json:"max_value"` // inclusive
+	FromTime    *time.Time `json:"from_time"` // inclusive, compared with the block timestamp
+	ToTime      *time.Time `json:"to_time"`   // inclusive
 	Limit       int    `json:"limit"`
+	Offset      int    `json:"offset"`
+	Fields      []string `json:"fields"` // event fields to load, see EventFields; empty for all
 }
 
 type RawEvent struct {
@@ -79,6 +156,55 @@ type LastProcessedBlock struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// BlockHeader is the hash stored for a recently processed block. Headers are kept for the
+// blocks within the maximum reorg depth, so a reorg can be traced to its exact fork point.
+type BlockHeader struct {
+	BlockNumber uint64    `json:"block_number" gorm:"primaryKey;autoIncrement:false"`
+	BlockHash   string    `json:"block_hash" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BackfillProgress is the highest block up to which the historical events of a contract
+// have been fully processed, so an interrupted backfill resumes after it
+type BackfillProgress struct {
+	Contract    string    `json:"contract" gorm:"primaryKey"`
+	ChainID     string    `json:"chain_id" gorm:"primaryKey"`
+	BlockNumber uint64    `json:"block_number" gorm:"not null"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName stores backfill progress in the backfill_progress table
+func (BackfillProgress) TableName() string {
+	return "backfill_progress"
+}
+
+// BackfillRange is a block range over which the historical events of a contract have been
+// fully processed. Overlapping and adjacent ranges are merged when saved, so a backfill can
+// skip every range completed earlier, in any order.
+type BackfillRange struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Contract  string    `json:"contract" gorm:"index:idx_backfill_ranges_contract"`
+	ChainID   string    `json:"chain_id" gorm:"index:idx_backfill_ranges_contract"`
+	FromBlock uint64    `json:"from_block" gorm:"not null"`
+	ToBlock   uint64    `json:"to_block" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName stores completed backfill ranges in the backfill_ranges table
+func (BackfillRange) TableName() string {
+	return "backfill_ranges"
+}
+
+// ReorgEvent is published to the reorg topic when processed blocks leave the canonical chain
+type ReorgEvent struct {
+	Type          string    `json:"type"`
+	FromBlock     *big.Int  `json:"from_block"` // lowest block whose hash changed
+	ToBlock       *big.Int  `json:"to_block"`   // highest processed block affected
+	OldHash       string    `json:"old_hash"`   // processed hash at FromBlock
+	NewHash       string    `json:"new_hash"`   // canonical hash at FromBlock, empty if the block no longer exists
+	DetectionTime time.Time `json:"detection_time"`
+}
+
 type ProcessedEvent struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	EventKey  string    `json:"event_key" gorm:"index;unique"`
@@ -98,7 +224,9 @@ type Contract struct {
 	Address   string    `json:"address" gorm:"index;unique"`
 	Name      string    `json:"name,omitempty"`
 	Symbol    string    `json:"symbol,omitempty"`
-	Type      string    `json:"type,omitempty"` // ERC20, ERC721, ERC1155, etc.
+	Type      ContractType `json:"type,omitempty"` // canonical standard, empty when unknown
+	ABI       string    `json:"abi,omitempty" gorm:"type:text;not null;default:''"` // JSON ABI its logs are decoded with, empty to decode them with the generic ABI
+	Active    bool      `json:"active" gorm:"not null;default:true"` // paused contracts are left out of subscriptions and backfills
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -107,4 +235,52 @@ type Stats struct {
 	TotalEvents    int64 `json:"total_events"`
 	TotalContracts int64 `json:"total_contracts"`
 	LatestBlock    int64 `json:"latest_block"`
-}
\ No newline at end of file
+}
+
+// EventTransform rewrites a stored event in place when events are replayed,
+// e.g. to re-derive fields after a decoder fix. It reports whether the event changed.
+type EventTransform func(event *IndexedEvent) (bool, error)
+
+// EventTransformer enriches or filters an event before it is stored, e.g. looking up
+// prices or labeling addresses. It returns the event to store and whether to keep it;
+// an event that is not kept is dropped.
+type EventTransformer interface {
+	Transform(ctx context.Context, event *IndexedEvent) (*IndexedEvent, bool, error)
+}
+
+// EventTransformerFunc adapts a function to an EventTransformer
+type EventTransformerFunc func(ctx context.Context, event *IndexedEvent) (*IndexedEvent, bool, error)
+
+// Transform calls f
+func (f EventTransformerFunc) Transform(ctx context.Context, event *IndexedEvent) (*IndexedEvent, bool, error) {
+	return f(ctx, event)
+}
+
+// ErrUnknownReplayTransform is returned when a replay names a transform that is not registered
+var ErrUnknownReplayTransform = errors.New("unknown replay transform")
+
+// ReplayResult summarises a replay of stored events
+type ReplayResult struct {
+	Scanned int64 `json:"scanned"` // events read in the replay scope
+	Updated int64 `json:"updated"` // events changed by the transform and saved
+}
+
+// EventRef identifies an event by its position on chain
+type EventRef struct {
+	BlockNumber uint64 `json:"block_number"`
+	TxHash      string `json:"tx_hash"`
+	LogIndex    uint   `json:"log_index"`
+}
+
+// ConsistencyReport lists the discrepancies between the stored events of a contract and
+// the logs the node reports for the same block range
+type ConsistencyReport struct {
+	Contract     string     `json:"contract"`
+	FromBlock    uint64     `json:"from_block"`
+	ToBlock      uint64     `json:"to_block"`
+	NodeEvents   int        `json:"node_events"`   // logs reported by the node
+	StoredEvents int        `json:"stored_events"` // events found in the database
+	Missing      []EventRef `json:"missing"`       // logs on the node that are not stored
+	Extra        []EventRef `json:"extra"`         // stored events the node has no log for
+	Repaired     int        `json:"repaired"`      // missing events re-indexed by the check
+}
@@ -0,0 +1,67 @@
+package types
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// EventCursor is the position of an event in the listing order of events, newest first
+// by block number, log index and ID. A listing resumed after a cursor neither repeats
+// nor skips events when new ones are indexed meanwhile, unlike one resumed at an offset.
+type EventCursor struct {
+	BlockNumber *big.Int
+	LogIndex    uint
+	ID          uint
+}
+
+// ErrInvalidEventCursor is returned when a token is not one returned by EventCursor.Encode
+var ErrInvalidEventCursor = errors.New("invalid event cursor")
+
+// CursorOf returns the position of event
+func CursorOf(event IndexedEvent) EventCursor {
+	blockNumber := event.BlockNumber
+	if blockNumber == nil {
+		blockNumber = new(big.Int)
+	}
+	return EventCursor{BlockNumber: blockNumber, LogIndex: event.LogIndex, ID: event.ID}
+}
+
+// Encode returns the cursor as an opaque, URL-safe token
+func (c EventCursor) Encode() string {
+	blockNumber := c.BlockNumber
+	if blockNumber == nil {
+		blockNumber = new(big.Int)
+	}
+	raw := fmt.Sprintf("%s:%d:%d", blockNumber.String(), c.LogIndex, c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeEventCursor parses a token returned by EventCursor.Encode
+func DecodeEventCursor(token string) (EventCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return EventCursor{}, fmt.Errorf("%w: %v", ErrInvalidEventCursor, err)
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return EventCursor{}, ErrInvalidEventCursor
+	}
+	blockNumber, ok := new(big.Int).SetString(parts[0], 10)
+	if !ok || blockNumber.Sign() < 0 {
+		return EventCursor{}, ErrInvalidEventCursor
+	}
+	logIndex, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return EventCursor{}, ErrInvalidEventCursor
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return EventCursor{}, ErrInvalidEventCursor
+	}
+	return EventCursor{BlockNumber: blockNumber, LogIndex: uint(logIndex), ID: uint(id)}, nil
+}
@@ -0,0 +1,32 @@
+package types
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestEventCursor_EncodeRoundTrip(t *testing.T) {
+	cursor := CursorOf(IndexedEvent{ID: 981, BlockNumber: big.NewInt(18000000), LogIndex: 7})
+
+	decoded, err := DecodeEventCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if decoded.BlockNumber.Cmp(big.NewInt(18000000)) != 0 || decoded.LogIndex != 7 || decoded.ID != 981 {
+		t.Errorf("Expected cursor 18000000/7/981, got %s/%d/%d", decoded.BlockNumber, decoded.LogIndex, decoded.ID)
+	}
+
+	// An event without a block number sorts as block 0
+	if decoded, err := DecodeEventCursor(CursorOf(IndexedEvent{ID: 1}).Encode()); err != nil || decoded.BlockNumber.Sign() != 0 {
+		t.Errorf("Expected block 0, got %v, %v", decoded.BlockNumber, err)
+	}
+}
+
+func TestDecodeEventCursor_RejectsInvalidTokens(t *testing.T) {
+	for _, token := range []string{"", "not base64!", "MToy", "LTE6Mjoz", "MTpYOjM"} {
+		if _, err := DecodeEventCursor(token); !errors.Is(err, ErrInvalidEventCursor) {
+			t.Errorf("Expected token %q to be invalid, got %v", token, err)
+		}
+	}
+}
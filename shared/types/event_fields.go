@@ -0,0 +1,104 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"chainpulse/shared/json"
+)
+
+// EventFields maps the fields of an encoded IndexedEvent that can be selected to the
+// columns storing them
+var EventFields = map[string]string{
+	"id":           "id",
+	"block_number": "block_number",
+	"tx_hash":      "tx_hash",
+	"log_index":    "log_index",
+	"event_name":   "event_name",
+	"topic0":       "topic0",
+	"contract":     "contract",
+	"from":         "from",
+	"to":           "to",
+	"token_id":     "token_id",
+	"value":        "value",
+	"data":         "data",
+	"timestamp":    "timestamp",
+	"created_at":   "created_at",
+	"updated_at":   "updated_at",
+}
+
+// ParseEventFields parses a comma-separated list of event fields, as named in the encoded
+// event or in camel case (txHash for tx_hash). It returns the field names without
+// duplicates, or an error naming the first unknown field.
+func ParseEventFields(list string) ([]string, error) {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		field := snakeCase(name)
+		if _, ok := EventFields[field]; !ok {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields selected")
+	}
+	return fields, nil
+}
+
+// EventColumns returns the columns storing fields, for selecting only them
+func EventColumns(fields []string) []string {
+	columns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		columns = append(columns, EventFields[field])
+	}
+	return columns
+}
+
+// ProjectEvents encodes events with only the given fields. Fields an event omits when
+// empty, like the value of an NFT transfer, stay omitted.
+func ProjectEvents(events []IndexedEvent, fields []string) ([]map[string]json.RawMessage, error) {
+	projected := make([]map[string]json.RawMessage, 0, len(events))
+	for i := range events {
+		data, err := json.Marshal(events[i])
+		if err != nil {
+			return nil, err
+		}
+		var encoded map[string]json.RawMessage
+		if err := json.Unmarshal(data, &encoded); err != nil {
+			return nil, err
+		}
+
+		event := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := encoded[field]; ok {
+				event[field] = value
+			}
+		}
+		projected = append(projected, event)
+	}
+	return projected, nil
+}
+
+// snakeCase converts a camel case name to snake case, leaving snake case names unchanged
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
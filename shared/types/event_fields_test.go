@@ -0,0 +1,49 @@
+package types
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestParseEventFields(t *testing.T) {
+	fields, err := ParseEventFields(" txHash, value,block_number,tx_hash ")
+	if err != nil {
+		t.Fatalf("Expected the fields to parse, got %v", err)
+	}
+	expected := []string{"tx_hash", "value", "block_number"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected fields %v, got %v", expected, fields)
+	}
+	if columns := EventColumns(fields); !reflect.DeepEqual(columns, expected) {
+		t.Errorf("Expected columns %v, got %v", expected, columns)
+	}
+
+	for _, list := range []string{"tx_hash,gasPrice", "", " , "} {
+		if fields, err := ParseEventFields(list); err == nil {
+			t.Errorf("Expected %q to be rejected, got %v", list, fields)
+		}
+	}
+}
+
+func TestProjectEvents(t *testing.T) {
+	events := []IndexedEvent{
+		{ID: 1, BlockNumber: big.NewInt(100), TxHash: "0x1", Value: "1000", Contract: "0xabc"},
+		{ID: 2, BlockNumber: big.NewInt(101), TxHash: "0x2", TokenID: "7", Contract: "0xdef"},
+	}
+
+	projected, err := ProjectEvents(events, []string{"tx_hash", "value"})
+	if err != nil {
+		t.Fatalf("Expected the events to be projected, got %v", err)
+	}
+	if len(projected) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(projected))
+	}
+	if len(projected[0]) != 2 || string(projected[0]["tx_hash"]) != `"0x1"` || string(projected[0]["value"]) != `"1000"` {
+		t.Errorf("Expected the tx hash and value, got %v", projected[0])
+	}
+	// The value of an NFT transfer is empty and omitted
+	if len(projected[1]) != 1 || string(projected[1]["tx_hash"]) != `"0x2"` {
+		t.Errorf("Expected only the tx hash, got %v", projected[1])
+	}
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Event ID strategies selectable by name
+const (
+	IDByAutoIncrement = "auto"
+	IDByHash          = "hash"
+)
+
+// EventIDGenerator assigns events their ID before they are stored, so an event has the
+// same identity wherever and however often it is derived
+type EventIDGenerator interface {
+	ID(event *IndexedEvent) uint
+}
+
+// HashIDGenerator derives the ID from a hash of (chain ID, transaction hash, log index),
+// the position of the event on chain. IDs are positive and fit a signed 64-bit column.
+type HashIDGenerator struct {
+	ChainID string
+}
+
+func (g HashIDGenerator) ID(event *IndexedEvent) uint {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%d", g.ChainID, strings.ToLower(event.TxHash), event.LogIndex)))
+	id := binary.BigEndian.Uint64(sum[:8]) & (1<<63 - 1)
+	if id == 0 {
+		id = 1
+	}
+	return uint(id)
+}
+
+// NewEventIDGenerator returns the generator of the named strategy for chainID. An empty
+// strategy or IDByAutoIncrement returns nil, leaving the database to assign IDs.
+func NewEventIDGenerator(strategy, chainID string) (EventIDGenerator, error) {
+	switch strategy {
+	case "", IDByAutoIncrement:
+		return nil, nil
+	case IDByHash:
+		return HashIDGenerator{ChainID: chainID}, nil
+	default:
+		return nil, fmt.Errorf("unknown event ID strategy: %s", strategy)
+	}
+}
@@ -0,0 +1,72 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestHashIDGenerator_StableAcrossRederivation(t *testing.T) {
+	generator := HashIDGenerator{ChainID: "1"}
+
+	// The same log decoded twice, once before and once after enrichment and a redelivery
+	decoded := &IndexedEvent{
+		BlockNumber: big.NewInt(18000000),
+		TxHash:      "0xABC",
+		LogIndex:    4,
+		EventName:   "Transfer",
+		Value:       "1000",
+		Timestamp:   time.Unix(1700000000, 0),
+	}
+	rederived := &IndexedEvent{
+		BlockNumber: big.NewInt(18000000),
+		TxHash:      "0xabc",
+		LogIndex:    4,
+		EventName:   "Transfer",
+		Value:       "1000",
+		Data:        map[string]interface{}{"fromLabel": "Binance 14"},
+		Timestamp:   time.Unix(1700000000, 0),
+		CreatedAt:   time.Now(),
+	}
+
+	id := generator.ID(decoded)
+	if id == 0 {
+		t.Fatal("Expected a non-zero ID")
+	}
+	if id > 1<<63-1 {
+		t.Errorf("Expected the ID to fit a signed 64-bit column, got %d", id)
+	}
+	if rederived := generator.ID(rederived); rederived != id {
+		t.Errorf("Expected the re-derived event to keep ID %d, got %d", id, rederived)
+	}
+
+	otherLog := *decoded
+	otherLog.LogIndex = 5
+	if generator.ID(&otherLog) == id {
+		t.Error("Expected another log of the transaction to get another ID")
+	}
+	if (HashIDGenerator{ChainID: "137"}).ID(decoded) == id {
+		t.Error("Expected the same log on another chain to get another ID")
+	}
+}
+
+func TestNewEventIDGenerator(t *testing.T) {
+	for _, strategy := range []string{"", IDByAutoIncrement} {
+		generator, err := NewEventIDGenerator(strategy, "1")
+		if err != nil || generator != nil {
+			t.Errorf("Expected no generator for %q, got %v and %v", strategy, generator, err)
+		}
+	}
+
+	generator, err := NewEventIDGenerator(IDByHash, "1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := generator.(HashIDGenerator); !ok {
+		t.Errorf("Expected a HashIDGenerator, got %T", generator)
+	}
+
+	if _, err := NewEventIDGenerator("ulid", "1"); err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+}
@@ -0,0 +1,173 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"chainpulse/shared/json"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the Event message in proto/indexer.proto
+const (
+	eventFieldID          protowire.Number = 1
+	eventFieldBlockNumber protowire.Number = 2
+	eventFieldTxHash      protowire.Number = 3
+	eventFieldEventName   protowire.Number = 4
+	eventFieldContract    protowire.Number = 5
+	eventFieldFrom        protowire.Number = 6
+	eventFieldTo          protowire.Number = 7
+	eventFieldTokenID     protowire.Number = 8
+	eventFieldValue       protowire.Number = 9
+	eventFieldTimestamp   protowire.Number = 10
+	eventFieldLogIndex    protowire.Number = 11
+	eventFieldData        protowire.Number = 12
+	eventFieldSchema      protowire.Number = 13
+)
+
+// MarshalProto encodes the event with the wire format of the Event message in proto/indexer.proto.
+// The timestamp is encoded as unix seconds and the decoded parameters as a JSON object.
+func (e *IndexedEvent) MarshalProto() ([]byte, error) {
+	var b []byte
+
+	if e.ID != 0 {
+		b = protowire.AppendTag(b, eventFieldID, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(e.ID))
+	}
+	if e.BlockNumber != nil {
+		b = appendProtoString(b, eventFieldBlockNumber, e.BlockNumber.String())
+	}
+	b = appendProtoString(b, eventFieldTxHash, e.TxHash)
+	b = appendProtoString(b, eventFieldEventName, e.EventName)
+	b = appendProtoString(b, eventFieldContract, e.Contract)
+	b = appendProtoString(b, eventFieldFrom, e.From)
+	b = appendProtoString(b, eventFieldTo, e.To)
+	b = appendProtoString(b, eventFieldTokenID, e.TokenID)
+	b = appendProtoString(b, eventFieldValue, e.Value)
+	if !e.Timestamp.IsZero() {
+		b = protowire.AppendTag(b, eventFieldTimestamp, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(e.Timestamp.Unix()))
+	}
+	if e.LogIndex != 0 {
+		b = protowire.AppendTag(b, eventFieldLogIndex, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(e.LogIndex))
+	}
+	if len(e.Data) > 0 {
+		data, err := json.Marshal(e.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode event data: %v", err)
+		}
+		b = appendProtoString(b, eventFieldData, string(data))
+	}
+	b = protowire.AppendTag(b, eventFieldSchema, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(e.versioned().SchemaVersion))
+
+	return b, nil
+}
+
+// UnmarshalProto decodes an Event message produced by MarshalProto
+func (e *IndexedEvent) UnmarshalProto(data []byte) error {
+	*e = IndexedEvent{}
+
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("invalid event tag: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		switch {
+		case num == eventFieldID && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return fmt.Errorf("invalid event id: %v", protowire.ParseError(n))
+			}
+			e.ID = uint(v)
+			data = data[n:]
+		case num == eventFieldTimestamp && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return fmt.Errorf("invalid event timestamp: %v", protowire.ParseError(n))
+			}
+			e.Timestamp = time.Unix(int64(v), 0)
+			data = data[n:]
+		case num == eventFieldLogIndex && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return fmt.Errorf("invalid event log index: %v", protowire.ParseError(n))
+			}
+			e.LogIndex = uint(v)
+			data = data[n:]
+		case num == eventFieldSchema && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return fmt.Errorf("invalid event schema version: %v", protowire.ParseError(n))
+			}
+			e.SchemaVersion = int(v)
+			data = data[n:]
+		case num == eventFieldData && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return fmt.Errorf("invalid event data: %v", protowire.ParseError(n))
+			}
+			if err := json.Unmarshal(v, &e.Data); err != nil {
+				return fmt.Errorf("invalid event data: %v", err)
+			}
+			data = data[n:]
+		case num >= eventFieldBlockNumber && num <= eventFieldValue && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return fmt.Errorf("invalid event field %d: %v", num, protowire.ParseError(n))
+			}
+			if err := e.setProtoString(num, v); err != nil {
+				return err
+			}
+			data = data[n:]
+		default:
+			// Skip unknown fields for forward compatibility
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return fmt.Errorf("invalid event field %d: %v", num, protowire.ParseError(n))
+			}
+			data = data[n:]
+		}
+	}
+
+	return nil
+}
+
+func (e *IndexedEvent) setProtoString(num protowire.Number, v string) error {
+	switch num {
+	case eventFieldBlockNumber:
+		blockNumber, ok := new(big.Int).SetString(v, 10)
+		if !ok {
+			return fmt.Errorf("invalid block number: %s", v)
+		}
+		e.BlockNumber = blockNumber
+	case eventFieldTxHash:
+		e.TxHash = v
+	case eventFieldEventName:
+		e.EventName = v
+	case eventFieldContract:
+		e.Contract = v
+	case eventFieldFrom:
+		e.From = v
+	case eventFieldTo:
+		e.To = v
+	case eventFieldTokenID:
+		e.TokenID = v
+	case eventFieldValue:
+		e.Value = v
+	}
+	return nil
+}
+
+func appendProtoString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
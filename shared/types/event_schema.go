@@ -0,0 +1,168 @@
+package types
+
+import (
+	"time"
+
+	"chainpulse/shared/json"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Schema versions of encoded events. Every encoded event carries its version as
+// schema_version. Bump CurrentEventSchemaVersion whenever IndexedEvent gains a field,
+// and have UpgradeSchema fill the new field for events encoded with older versions.
+const (
+	// EventSchemaV1 has the block, transaction, contract, parties, token ID and value
+	EventSchemaV1 = 1
+	// EventSchemaV2 adds the log index, the signature topic and the decoded parameters
+	EventSchemaV2 = 2
+
+	CurrentEventSchemaVersion = EventSchemaV2
+)
+
+// transferTopic0 is the signature topic of ERC-20 and ERC-721 Transfer events
+var transferTopic0 = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")).Hex()
+
+// indexedEvent is an IndexedEvent without its encoding methods, for the default encoding
+type indexedEvent IndexedEvent
+
+// versioned returns the event stamped with the current schema version, unless it keeps
+// the version of an older encoding it was decoded from
+func (e IndexedEvent) versioned() IndexedEvent {
+	if e.SchemaVersion == 0 {
+		e.SchemaVersion = CurrentEventSchemaVersion
+	}
+	return e
+}
+
+// UpgradeSchema fills the fields missing from an event decoded from an older schema
+// version, as far as they can be derived from the fields it has. Decoding leaves events
+// as encoded; consumers reading events written by older code, such as cached or queued
+// ones, call it after decoding. Only events carrying an older version are upgraded: one
+// without a version, like a request body, is not known to be legacy and is left as is.
+// SchemaVersion keeps the decoded version, so consumers can tell the fields that were
+// not encoded from zero values; the log index of a version 1 event, for one, is unknown.
+func (e *IndexedEvent) UpgradeSchema() {
+	if e.SchemaVersion < EventSchemaV1 || e.SchemaVersion >= CurrentEventSchemaVersion {
+		return
+	}
+
+	if e.SchemaVersion < EventSchemaV2 {
+		if e.Topic0 == "" && e.EventName == "Transfer" {
+			e.Topic0 = transferTopic0
+		}
+		if e.Data == nil {
+			// The decoded parameters of the transfers version 1 covered, by ABI input name
+			data := make(map[string]interface{})
+			for key, value := range map[string]string{"from": e.From, "to": e.To, "tokenId": e.TokenID, "value": e.Value} {
+				if value != "" {
+					data[key] = value
+				}
+			}
+			if len(data) > 0 {
+				e.Data = data
+			}
+		}
+	}
+}
+
+// MarshalJSON encodes the event with its schema version
+func (e IndexedEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(indexedEvent(e.versioned()))
+}
+
+// EncodeMsgpack encodes the event with its schema version
+func (e IndexedEvent) EncodeMsgpack(enc *msgpack.Encoder) error {
+	return enc.Encode(indexedEvent(e.versioned()))
+}
+
+// The types embedding IndexedEvent encode their own fields alongside it, rather than
+// inheriting the event's encoding methods and losing them
+
+// archivedEvent and addressActivity are the encodings of ArchivedEvent and AddressActivity
+type archivedEvent struct {
+	indexedEvent
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+type addressActivity struct {
+	indexedEvent
+	Direction string `json:"direction"`
+}
+
+func (a ArchivedEvent) encoding() archivedEvent {
+	return archivedEvent{indexedEvent(a.IndexedEvent.versioned()), a.ArchivedAt}
+}
+
+func (a *ArchivedEvent) decoded(decoded archivedEvent) {
+	a.IndexedEvent, a.ArchivedAt = IndexedEvent(decoded.indexedEvent), decoded.ArchivedAt
+}
+
+// MarshalJSON encodes the archived event with its schema version
+func (a ArchivedEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.encoding())
+}
+
+// UnmarshalJSON decodes an archived event of any schema version
+func (a *ArchivedEvent) UnmarshalJSON(data []byte) error {
+	var decoded archivedEvent
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	a.decoded(decoded)
+	return nil
+}
+
+// EncodeMsgpack encodes the archived event with its schema version
+func (a ArchivedEvent) EncodeMsgpack(enc *msgpack.Encoder) error {
+	return enc.Encode(a.encoding())
+}
+
+// DecodeMsgpack decodes an archived event of any schema version
+func (a *ArchivedEvent) DecodeMsgpack(dec *msgpack.Decoder) error {
+	var decoded archivedEvent
+	if err := dec.Decode(&decoded); err != nil {
+		return err
+	}
+	a.decoded(decoded)
+	return nil
+}
+
+func (a AddressActivity) encoding() addressActivity {
+	return addressActivity{indexedEvent(a.IndexedEvent.versioned()), a.Direction}
+}
+
+func (a *AddressActivity) decoded(decoded addressActivity) {
+	a.IndexedEvent, a.Direction = IndexedEvent(decoded.indexedEvent), decoded.Direction
+}
+
+// MarshalJSON encodes the activity with the schema version of its event
+func (a AddressActivity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.encoding())
+}
+
+// UnmarshalJSON decodes an activity of any schema version
+func (a *AddressActivity) UnmarshalJSON(data []byte) error {
+	var decoded addressActivity
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	a.decoded(decoded)
+	return nil
+}
+
+// EncodeMsgpack encodes the activity with the schema version of its event
+func (a AddressActivity) EncodeMsgpack(enc *msgpack.Encoder) error {
+	return enc.Encode(a.encoding())
+}
+
+// DecodeMsgpack decodes an activity of any schema version
+func (a *AddressActivity) DecodeMsgpack(dec *msgpack.Decoder) error {
+	var decoded addressActivity
+	if err := dec.Decode(&decoded); err != nil {
+		return err
+	}
+	a.decoded(decoded)
+	return nil
+}
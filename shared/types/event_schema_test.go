@@ -0,0 +1,172 @@
+package types
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"chainpulse/shared/json"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// v1EventJSON is a transfer as encoded with schema version 1
+const v1EventJSON = `{"schema_version":1,"id":7,"block_number":18000000,"tx_hash":"0xabc","event_name":"Transfer","contract":"0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48","from":"0x1111111111111111111111111111111111111111","to":"0x2222222222222222222222222222222222222222","value":"1000","timestamp":"2023-11-14T22:13:20Z"}`
+
+func TestIndexedEvent_UpgradeV1JSON(t *testing.T) {
+	var event IndexedEvent
+	if err := json.Unmarshal([]byte(v1EventJSON), &event); err != nil {
+		t.Fatalf("Failed to decode v1 event: %v", err)
+	}
+
+	// Decoding leaves the event as encoded
+	if event.Topic0 != "" || event.Data != nil {
+		t.Errorf("Expected no fields to be derived on decode, got topic %q and data %v", event.Topic0, event.Data)
+	}
+
+	event.UpgradeSchema()
+	if event.SchemaVersion != EventSchemaV1 {
+		t.Errorf("Expected schema version %d, got %d", EventSchemaV1, event.SchemaVersion)
+	}
+	if event.ID != 7 || event.BlockNumber.Cmp(big.NewInt(18000000)) != 0 || event.Value != "1000" {
+		t.Errorf("Expected the v1 fields to be decoded, got %+v", event)
+	}
+
+	// Fields added in v2 are derived where possible
+	if event.Topic0 != "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef" {
+		t.Errorf("Expected the Transfer signature topic, got %q", event.Topic0)
+	}
+	if event.Data["from"] != event.From || event.Data["to"] != event.To || event.Data["value"] != "1000" {
+		t.Errorf("Expected the decoded parameters from the v1 fields, got %v", event.Data)
+	}
+	if _, ok := event.Data["tokenId"]; ok {
+		t.Error("Expected no token ID parameter for an event without one")
+	}
+	if event.LogIndex != 0 {
+		t.Errorf("Expected an unknown log index to stay 0, got %d", event.LogIndex)
+	}
+
+	// Re-encoding keeps the version, the log index is still not known
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Failed to encode event: %v", err)
+	}
+	if !strings.Contains(string(data), `"schema_version":1`) {
+		t.Errorf("Expected the v1 schema version to be kept, got %s", data)
+	}
+}
+
+func TestIndexedEvent_UnversionedIsNotUpgraded(t *testing.T) {
+	// A request body or other payload without a version is not known to be legacy
+	var event IndexedEvent
+	if err := json.Unmarshal([]byte(`{"tx_hash":"0xabc","event_name":"Transfer","from":"0x1","to":"0x2","value":"5"}`), &event); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+
+	event.UpgradeSchema()
+	if event.SchemaVersion != 0 {
+		t.Errorf("Expected no schema version, got %d", event.SchemaVersion)
+	}
+	if event.Topic0 != "" || event.Data != nil {
+		t.Errorf("Expected the event to be left as decoded, got topic %q and data %v", event.Topic0, event.Data)
+	}
+}
+
+func TestIndexedEvent_EncodesCurrentVersion(t *testing.T) {
+	event := IndexedEvent{TxHash: "0xabc", LogIndex: 3, EventName: "Approval", Data: map[string]interface{}{"owner": "0x1"}}
+
+	data, err := json.Marshal(&event)
+	if err != nil {
+		t.Fatalf("Failed to encode event: %v", err)
+	}
+	if !strings.Contains(string(data), `"schema_version":2`) {
+		t.Errorf("Expected the current schema version, got %s", data)
+	}
+
+	var decoded IndexedEvent
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if decoded.SchemaVersion != CurrentEventSchemaVersion || decoded.LogIndex != 3 {
+		t.Errorf("Expected version %d with log index 3, got version %d with log index %d", CurrentEventSchemaVersion, decoded.SchemaVersion, decoded.LogIndex)
+	}
+	decoded.UpgradeSchema()
+	if len(decoded.Data) != 1 || decoded.Topic0 != "" {
+		t.Errorf("Expected a current event to be left as encoded, got data %v and topic %q", decoded.Data, decoded.Topic0)
+	}
+
+	// Events from newer code decode with the fields this version knows
+	var newer IndexedEvent
+	if err := json.Unmarshal([]byte(`{"schema_version":3,"tx_hash":"0xdef","block_hash":"0x01"}`), &newer); err != nil {
+		t.Fatalf("Failed to decode a newer event: %v", err)
+	}
+	if newer.SchemaVersion != 3 || newer.TxHash != "0xdef" {
+		t.Errorf("Expected version 3 with its tx hash, got version %d with %q", newer.SchemaVersion, newer.TxHash)
+	}
+}
+
+func TestIndexedEvent_UpgradeV1Proto(t *testing.T) {
+	// Written before the log index and data fields existed
+	var b []byte
+	b = appendProtoString(b, eventFieldTxHash, "0xabc")
+	b = appendProtoString(b, eventFieldEventName, "Transfer")
+	b = appendProtoString(b, eventFieldTokenID, "42")
+	b = protowire.AppendTag(b, eventFieldSchema, protowire.VarintType)
+	b = protowire.AppendVarint(b, EventSchemaV1)
+
+	var event IndexedEvent
+	if err := event.UnmarshalProto(b); err != nil {
+		t.Fatalf("Failed to decode v1 event: %v", err)
+	}
+	event.UpgradeSchema()
+	if event.SchemaVersion != EventSchemaV1 || event.Data["tokenId"] != "42" {
+		t.Errorf("Expected an upgraded v1 event, got version %d with data %v", event.SchemaVersion, event.Data)
+	}
+
+	encoded, err := (&IndexedEvent{TxHash: "0xabc"}).MarshalProto()
+	if err != nil {
+		t.Fatalf("Failed to encode event: %v", err)
+	}
+	var decoded IndexedEvent
+	if err := decoded.UnmarshalProto(encoded); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if decoded.SchemaVersion != CurrentEventSchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", CurrentEventSchemaVersion, decoded.SchemaVersion)
+	}
+}
+
+func TestEmbeddedEvents_KeepTheirFields(t *testing.T) {
+	activity := AddressActivity{IndexedEvent: IndexedEvent{TxHash: "0xabc"}, Direction: DirectionIn}
+	data, err := json.Marshal(activity)
+	if err != nil {
+		t.Fatalf("Failed to encode activity: %v", err)
+	}
+	var decodedActivity AddressActivity
+	if err := json.Unmarshal(data, &decodedActivity); err != nil {
+		t.Fatalf("Failed to decode activity: %v", err)
+	}
+	if decodedActivity.Direction != DirectionIn || decodedActivity.TxHash != "0xabc" || decodedActivity.SchemaVersion != CurrentEventSchemaVersion {
+		t.Errorf("Expected the activity and its event, got %+v", decodedActivity)
+	}
+
+	archived := ArchivedEvent{IndexedEvent: IndexedEvent{TxHash: "0xabc"}, ArchivedAt: time.Unix(1700000000, 0).UTC()}
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(&archived); err != nil {
+		t.Fatalf("Failed to encode archived event: %v", err)
+	}
+	dec := msgpack.NewDecoder(&buf)
+	dec.SetCustomStructTag("json")
+	var decodedArchived ArchivedEvent
+	if err := dec.Decode(&decodedArchived); err != nil {
+		t.Fatalf("Failed to decode archived event: %v", err)
+	}
+	if !decodedArchived.ArchivedAt.Equal(archived.ArchivedAt) || decodedArchived.TxHash != "0xabc" || decodedArchived.SchemaVersion != CurrentEventSchemaVersion {
+		t.Errorf("Expected the archived event and its archive time, got %+v", decodedArchived)
+	}
+}
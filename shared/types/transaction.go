@@ -0,0 +1,32 @@
+package types
+
+import (
+	"math/big"
+)
+
+// Transaction envelope types as reported in the JSON-RPC "type" field
+const (
+	LegacyTxType     uint8 = 0x0
+	AccessListTxType uint8 = 0x1
+	DynamicFeeTxType uint8 = 0x2
+)
+
+// PulledTransaction is a transaction decoded from a JSON-RPC transaction object
+type PulledTransaction struct {
+	Hash                 string   `json:"hash"`
+	Type                 uint8    `json:"type"`
+	From                 string   `json:"from"`
+	To                   string   `json:"to,omitempty"` // empty for contract creation
+	Value                *big.Int `json:"value"`
+	Gas                  uint64   `json:"gas"`
+	GasPrice             *big.Int `json:"gas_price,omitempty"`
+	MaxFeePerGas         *big.Int `json:"max_fee_per_gas,omitempty"`          // EIP-1559 only
+	MaxPriorityFeePerGas *big.Int `json:"max_priority_fee_per_gas,omitempty"` // EIP-1559 only
+	Nonce                uint64   `json:"nonce"`
+	Input                string   `json:"input"`
+}
+
+// IsDynamicFee reports whether the transaction carries EIP-1559 fee fields
+func (tx *PulledTransaction) IsDynamicFee() bool {
+	return tx.Type >= DynamicFeeTxType
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Connection pool defaults for clients sending many requests to a few hosts. The
+// default transport keeps only 2 idle connections per host, so under load most
+// requests dial a new connection and leave the old one in TIME_WAIT.
+const (
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 32
+	DefaultIdleConnTimeout     = 90 * time.Second
+)
+
+// HTTPPoolConfig tunes the idle connections an HTTP client keeps for reuse. Zero
+// values use the defaults above.
+type HTTPPoolConfig struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+}
+
+// NewHTTPTransport returns a transport reusing connections as configured by pool
+func NewHTTPTransport(pool HTTPPoolConfig) *http.Transport {
+	if pool.MaxIdleConns <= 0 {
+		pool.MaxIdleConns = DefaultMaxIdleConns
+	}
+	if pool.MaxIdleConnsPerHost <= 0 {
+		pool.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+	if pool.IdleConnTimeout <= 0 {
+		pool.IdleConnTimeout = DefaultIdleConnTimeout
+	}
+
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          pool.MaxIdleConns,
+		MaxIdleConnsPerHost:   pool.MaxIdleConnsPerHost,
+		IdleConnTimeout:       pool.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// NewHTTPClient returns a client with the given timeout on a pooled transport. The
+// client should be reused for all requests so its connections are reused too.
+func NewHTTPClient(timeout time.Duration, pool HTTPPoolConfig) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: NewHTTPTransport(pool),
+	}
+}